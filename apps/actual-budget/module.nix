@@ -16,7 +16,8 @@ mkBloudApp {
   network = "host";
   dataDir = "/data";
 
-  # Load secrets from env file: ACTUAL_OPENID_CLIENT_SECRET
+  # Load host-dependent SSO settings from env file; the client secret is the
+  # oauth-client-secret systemd credential (bloud.apps.actual-budget.credentials)
   envFile = "${secretsDir}/actual-budget.env";
 
   # Depend on Authentik when SSO is enabled
@@ -32,7 +33,7 @@ mkBloudApp {
       default = "actual-budget-client";
       description = "OpenID Connect client ID";
     };
-    # openidClientSecret loaded from actual-budget.env as ACTUAL_OPENID_CLIENT_SECRET
+    # openidClientSecret loaded from the oauth-client-secret credential as ACTUAL_OPENID_CLIENT_SECRET
   };
 
   environment = cfg: {
@@ -41,7 +42,7 @@ mkBloudApp {
     # Skip Actual Budget's own login - use Authentik only
     ACTUAL_OPENID_ENFORCE = "true";
     # Host-dependent SSO env vars (ACTUAL_OPENID_DISCOVERY_URL, ACTUAL_OPENID_CLIENT_ID,
-    # ACTUAL_OPENID_CLIENT_SECRET, ACTUAL_OPENID_SERVER_HOSTNAME) are written to the env file
+    # ACTUAL_OPENID_SERVER_HOSTNAME) are written to the env file
     # at runtime by the host-agent prestart hook, using detected local IPs.
   };
 }
@@ -16,7 +16,8 @@ mkBloudApp {
   # AFFiNE needs to write to container paths, don't use keep-id
   userns = null;
 
-  # Load secrets from env file: DATABASE_URL, OAUTH_OIDC_CLIENT_SECRET
+  # Load secrets from env file: DATABASE_URL. The client secret is the
+  # oauth-client-secret systemd credential (bloud.apps.affine.credentials)
   envFile = "${secretsDir}/affine.env";
 
   options = {
@@ -24,7 +25,7 @@ mkBloudApp {
       default = "affine-client";
       description = "OpenID Connect client ID";
     };
-    # openidClientSecret loaded from affine.env as OAUTH_OIDC_CLIENT_SECRET
+    # openidClientSecret loaded from the oauth-client-secret credential as OAUTH_OIDC_CLIENT_SECRET
   };
 
   # Volumes for persistent data
@@ -47,8 +48,8 @@ mkBloudApp {
     OAUTH_OIDC_CLAIM_MAP_ID = "sub";
     OAUTH_OIDC_CLAIM_MAP_EMAIL = "email";
     OAUTH_OIDC_CLAIM_MAP_NAME = "name";
-    # Host-dependent SSO env vars (OAUTH_OIDC_ISSUER, OAUTH_OIDC_CLIENT_ID,
-    # OAUTH_OIDC_CLIENT_SECRET) are written to the env file at runtime
+    # Host-dependent SSO env vars (OAUTH_OIDC_ISSUER, OAUTH_OIDC_CLIENT_ID)
+    # are written to the env file at runtime
     # by the host-agent prestart hook, using detected local IPs.
   };
 
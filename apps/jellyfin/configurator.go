@@ -11,6 +11,8 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	authentikClient "codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
@@ -27,6 +29,14 @@ const (
 	// Note: Jellyfin uses GUIDs without dashes in the API
 	ldapPluginID = "958aad6637844d2ab89aa7b6fab6e25c"
 
+	// apiKeyFile stores the API key used by the provisioner, relative to the data path.
+	// It is created during LDAP setup, before the bootstrap admin is deleted.
+	apiKeyFile = "bloud-api-key"
+
+	// ldapPluginConfigFile is where the LDAP-Auth plugin persists its configuration,
+	// relative to <data>/config/plugins/configurations
+	ldapPluginConfigFile = "LDAP-Auth.xml"
+
 	// Default LDAP configuration for Authentik
 	// Use container name since Jellyfin and LDAP outpost are on the same network
 	defaultLDAPHost     = "apps-authentik-ldap"
@@ -163,13 +173,13 @@ func (c *Configurator) PostStart(ctx context.Context, state *configurator.AppSta
 	}
 
 	// 2. Configure media libraries
-	if err := c.configureLibraries(ctx); err != nil {
+	if err := c.configureLibraries(ctx, state.DataPath, state.Settings, false); err != nil {
 		return fmt.Errorf("failed to configure libraries: %w", err)
 	}
 
 	// 3. Configure LDAP if SSO integration is enabled
 	if _, hasSSO := state.Integrations["sso"]; hasSSO {
-		if err := c.configureLDAP(ctx); err != nil {
+		if err := c.configureLDAP(ctx, state.DataPath); err != nil {
 			return fmt.Errorf("failed to configure LDAP: %w", err)
 		}
 	}
@@ -431,10 +441,29 @@ type VirtualFolder struct {
 	ItemId         string   `json:"ItemId"`
 }
 
-// configureLibraries sets up the default media libraries
-func (c *Configurator) configureLibraries(ctx context.Context) error {
-	// Authenticate first
-	token, err := c.authenticate(ctx, bootstrapUsername, bootstrapPassword)
+// library is a media library managed through the moviePaths and showPaths settings
+type library struct {
+	name           string
+	collectionType string
+	settingKey     string
+	defaultPath    string
+}
+
+var libraries = []library{
+	{"Movies", "movies", "moviePaths", "/movies"},
+	{"Shows", "shows", "showPaths", "/shows"},
+}
+
+// Configure applies the library path settings. Unlike PostStart, folders
+// that are no longer listed are removed from the library.
+func (c *Configurator) Configure(ctx context.Context, state *configurator.AppState, settings map[string]any) error {
+	return c.configureLibraries(ctx, state.DataPath, settings, true)
+}
+
+// configureLibraries creates the media libraries and adds the folders from
+// settings to them. With prune, folders not in settings are removed.
+func (c *Configurator) configureLibraries(ctx context.Context, dataPath string, settings map[string]any, prune bool) error {
+	token, err := c.accessToken(ctx, dataPath)
 	if err != nil {
 		return fmt.Errorf("authenticating: %w", err)
 	}
@@ -445,37 +474,62 @@ func (c *Configurator) configureLibraries(ctx context.Context) error {
 		return fmt.Errorf("getting libraries: %w", err)
 	}
 
-	// Create a map of existing library names
-	existingNames := make(map[string]bool)
+	existing := make(map[string]VirtualFolder)
 	for _, lib := range existingLibraries {
-		existingNames[lib.Name] = true
-	}
-
-	// Define libraries to create
-	libraries := []struct {
-		name           string
-		collectionType string
-		path           string
-	}{
-		{"Movies", "movies", "/movies"},
-		{"Shows", "shows", "/shows"},
+		existing[lib.Name] = lib
 	}
 
 	for _, lib := range libraries {
-		if existingNames[lib.name] {
-			log.Printf("Jellyfin: Library '%s' already exists", lib.name)
-			continue
+		paths := []string{lib.defaultPath}
+		if v, ok := settings[lib.settingKey].([]string); ok && len(v) > 0 {
+			paths = v
+		}
+
+		folder, ok := existing[lib.name]
+		if !ok {
+			log.Printf("Jellyfin: Creating library '%s' at %s", lib.name, paths[0])
+			if err := c.addVirtualFolder(ctx, token, lib.name, lib.collectionType, paths[0]); err != nil {
+				return fmt.Errorf("creating library %s: %w", lib.name, err)
+			}
+			folder = VirtualFolder{Name: lib.name, Locations: paths[:1]}
 		}
 
-		log.Printf("Jellyfin: Creating library '%s' at %s", lib.name, lib.path)
-		if err := c.addVirtualFolder(ctx, token, lib.name, lib.collectionType, lib.path); err != nil {
-			return fmt.Errorf("creating library %s: %w", lib.name, err)
+		for _, path := range paths {
+			if slices.Contains(folder.Locations, path) {
+				continue
+			}
+			log.Printf("Jellyfin: Adding %s to library '%s'", path, lib.name)
+			if err := c.addMediaPath(ctx, token, lib.name, path); err != nil {
+				return fmt.Errorf("adding %s to library %s: %w", path, lib.name, err)
+			}
+		}
+
+		if !prune {
+			continue
+		}
+		for _, path := range folder.Locations {
+			if slices.Contains(paths, path) {
+				continue
+			}
+			log.Printf("Jellyfin: Removing %s from library '%s'", path, lib.name)
+			if err := c.removeMediaPath(ctx, token, lib.name, path); err != nil {
+				return fmt.Errorf("removing %s from library %s: %w", path, lib.name, err)
+			}
 		}
 	}
 
 	return nil
 }
 
+// accessToken returns the provisioning API key once LDAP setup has created
+// it (the bootstrap admin is deleted then), or logs in as the bootstrap admin
+func (c *Configurator) accessToken(ctx context.Context, dataPath string) (string, error) {
+	if data, err := os.ReadFile(filepath.Join(dataPath, apiKeyFile)); err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	return c.authenticate(ctx, bootstrapUsername, bootstrapPassword)
+}
+
 // getVirtualFolders returns all configured libraries
 func (c *Configurator) getVirtualFolders(ctx context.Context, token string) ([]VirtualFolder, error) {
 	url := c.getBaseURL() + "/Library/VirtualFolders"
@@ -531,6 +585,49 @@ func (c *Configurator) addVirtualFolder(ctx context.Context, token, name, collec
 	return nil
 }
 
+// addMediaPath adds a folder to an existing library
+func (c *Configurator) addMediaPath(ctx context.Context, token, name, path string) error {
+	body, _ := json.Marshal(map[string]any{
+		"Name":     name,
+		"PathInfo": map[string]string{"Path": path},
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", c.getBaseURL()+"/Library/VirtualFolders/Paths?refreshLibrary=false", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.doLibraryRequest(req, token)
+}
+
+// removeMediaPath removes a folder from a library; the media itself is untouched
+func (c *Configurator) removeMediaPath(ctx context.Context, token, name, path string) error {
+	reqURL := fmt.Sprintf("%s/Library/VirtualFolders/Paths?name=%s&path=%s&refreshLibrary=false",
+		c.getBaseURL(), url.QueryEscape(name), url.QueryEscape(path))
+	req, err := http.NewRequestWithContext(ctx, "DELETE", reqURL, nil)
+	if err != nil {
+		return err
+	}
+	return c.doLibraryRequest(req, token)
+}
+
+// doLibraryRequest sends an authenticated library request that returns no content
+func (c *Configurator) doLibraryRequest(req *http.Request, token string) error {
+	req.Header.Set("X-Emby-Authorization", fmt.Sprintf(`MediaBrowser Client="Bloud", Device="Host-Agent", DeviceId="bloud-host-agent", Version="1.0.0", Token="%s"`, token))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
 // LDAPConfig represents the LDAP plugin configuration
 type LDAPConfig struct {
 	LdapServer                     string   `json:"LdapServer"`
@@ -563,7 +660,7 @@ type LDAPConfig struct {
 }
 
 // configureLDAP configures the LDAP plugin to use Authentik
-func (c *Configurator) configureLDAP(ctx context.Context) error {
+func (c *Configurator) configureLDAP(ctx context.Context, dataPath string) error {
 	// First, authenticate to get an access token
 	token, err := c.authenticate(ctx, bootstrapUsername, bootstrapPassword)
 	if err != nil {
@@ -626,6 +723,12 @@ func (c *Configurator) configureLDAP(ctx context.Context) error {
 
 	log.Println("Jellyfin: LDAP configured successfully")
 
+	// Mint an API key for user provisioning while we still have an admin session
+	if err := c.ensureAPIKey(ctx, token, dataPath); err != nil {
+		log.Printf("Jellyfin: Warning - failed to create provisioning API key: %v", err)
+		// Don't fail - only user provisioning depends on the key
+	}
+
 	// Delete bootstrap admin after LDAP is configured
 	if err := c.deleteBootstrapAdmin(ctx, token); err != nil {
 		log.Printf("Jellyfin: Warning - failed to delete bootstrap admin: %v", err)
@@ -635,6 +738,95 @@ func (c *Configurator) configureLDAP(ctx context.Context) error {
 	return nil
 }
 
+// UpdateLDAPBindPassword rewrites the bind password in the LDAP plugin's config file.
+// The bootstrap admin is gone once LDAP is configured, so the API can't be used here;
+// the file is read by the plugin when Jellyfin restarts.
+func (c *Configurator) UpdateLDAPBindPassword(ctx context.Context, state *configurator.AppState, password string) error {
+	configPath := filepath.Join(state.DataPath, "config", "plugins", "configurations", ldapPluginConfigFile)
+
+	cfg, err := xmlutil.OpenExisting(configPath)
+	if os.IsNotExist(err) {
+		log.Println("Jellyfin: LDAP plugin not configured, skipping bind password update")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg.SetElement("LdapBindPassword", password)
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	log.Println("Jellyfin: Updated LDAP bind password")
+	return nil
+}
+
+// ensureAPIKey creates a Jellyfin API key for Bloud and writes it to the data directory
+func (c *Configurator) ensureAPIKey(ctx context.Context, token, dataPath string) error {
+	keyPath := filepath.Join(dataPath, apiKeyFile)
+	if _, err := os.Stat(keyPath); err == nil {
+		return nil
+	}
+
+	url := c.getBaseURL() + "/Auth/Keys?app=Bloud"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Emby-Authorization", fmt.Sprintf(`MediaBrowser Client="Bloud", Device="Host-Agent", DeviceId="bloud-host-agent", Version="1.0.0", Token="%s"`, token))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("creating API key: unexpected status %d", resp.StatusCode)
+	}
+
+	// The create endpoint doesn't return the key, so look it up
+	req, err = http.NewRequestWithContext(ctx, "GET", c.getBaseURL()+"/Auth/Keys", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Emby-Authorization", fmt.Sprintf(`MediaBrowser Client="Bloud", Device="Host-Agent", DeviceId="bloud-host-agent", Version="1.0.0", Token="%s"`, token))
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("listing API keys: unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var keys struct {
+		Items []struct {
+			AccessToken string `json:"AccessToken"`
+			AppName     string `json:"AppName"`
+		} `json:"Items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		return err
+	}
+
+	for _, key := range keys.Items {
+		if key.AppName == "Bloud" {
+			if err := os.WriteFile(keyPath, []byte(key.AccessToken), 0600); err != nil {
+				return fmt.Errorf("writing API key: %w", err)
+			}
+			log.Println("Jellyfin: Created provisioning API key")
+			return nil
+		}
+	}
+
+	return fmt.Errorf("API key not found after creation")
+}
+
 // AuthResponse represents the authentication response
 type AuthResponse struct {
 	AccessToken string `json:"AccessToken"`
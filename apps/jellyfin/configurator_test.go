@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -660,6 +661,63 @@ func TestConfigurator_PostStart_WizardAlreadyComplete(t *testing.T) {
 	}
 }
 
+func TestConfigurator_Configure_SyncsLibraryPaths(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("X-Emby-Authorization"), `Token="test-api-key"`) {
+			t.Errorf("expected the API key, got %s", r.Header.Get("X-Emby-Authorization"))
+		}
+
+		switch r.URL.Path {
+		case "/Library/VirtualFolders":
+			json.NewEncoder(w).Encode([]VirtualFolder{
+				{Name: "Movies", Locations: []string{"/movies", "/old-movies"}},
+				{Name: "Shows", Locations: []string{"/shows"}},
+			})
+
+		case "/Library/VirtualFolders/Paths":
+			if r.Method == http.MethodPost {
+				var body struct {
+					Name     string
+					PathInfo struct{ Path string }
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				calls = append(calls, "add "+body.Name+" "+body.PathInfo.Path)
+			} else {
+				calls = append(calls, "remove "+r.URL.Query().Get("name")+" "+r.URL.Query().Get("path"))
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			t.Errorf("Unexpected endpoint called: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dataPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataPath, apiKeyFile), []byte("test-api-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewConfigurator(8096, "http://localhost:9001", "test-token")
+	c.baseURL = server.URL
+
+	state := &configurator.AppState{Name: "jellyfin", DataPath: dataPath}
+	settings := map[string]any{
+		"moviePaths": []string{"/movies", "/movies/4k"},
+		"showPaths":  []string{"/shows"},
+	}
+	if err := c.Configure(context.Background(), state, settings); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	want := []string{"add Movies /movies/4k", "remove Movies /old-movies"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
 func TestConfigurator_ConfigureLDAP_AlreadyConfigured(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -690,7 +748,7 @@ func TestConfigurator_ConfigureLDAP_AlreadyConfigured(t *testing.T) {
 	c := NewConfigurator(8096, "http://localhost:9001", "test-token")
 	c.baseURL = server.URL
 
-	err := c.configureLDAP(context.Background())
+	err := c.configureLDAP(context.Background(), t.TempDir())
 	if err != nil {
 		t.Fatalf("configureLDAP() error = %v", err)
 	}
@@ -717,8 +775,62 @@ func TestConfigurator_ConfigureLDAP_PluginNotInstalled(t *testing.T) {
 	c.baseURL = server.URL
 
 	// Should not error when plugin is not installed
-	err := c.configureLDAP(context.Background())
+	err := c.configureLDAP(context.Background(), t.TempDir())
 	if err != nil {
 		t.Fatalf("configureLDAP() should not error when plugin not installed: %v", err)
 	}
 }
+
+func TestConfigurator_UpdateLDAPBindPassword(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataPath := filepath.Join(tmpDir, "jellyfin")
+	pluginDir := filepath.Join(dataPath, "config", "plugins", "configurations")
+
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	existing := `<?xml version="1.0" encoding="utf-8"?>
+<PluginConfiguration>
+  <LdapServer>apps-authentik-ldap</LdapServer>
+  <LdapBindUser>cn=ldap-service,ou=users,dc=ldap,dc=goauthentik,dc=io</LdapBindUser>
+  <LdapBindPassword>old-password</LdapBindPassword>
+</PluginConfiguration>`
+	configPath := filepath.Join(pluginDir, "LDAP-Auth.xml")
+	if err := os.WriteFile(configPath, []byte(existing), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	c := NewConfigurator(8096, "http://localhost:9001", "test-token")
+	state := &configurator.AppState{Name: "jellyfin", DataPath: dataPath}
+	if err := c.UpdateLDAPBindPassword(context.Background(), state, "new-password"); err != nil {
+		t.Fatalf("UpdateLDAPBindPassword() error = %v", err)
+	}
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	contentStr := string(content)
+	if !strings.Contains(contentStr, "<LdapBindPassword>new-password</LdapBindPassword>") {
+		t.Error("UpdateLDAPBindPassword() should replace the bind password")
+	}
+	if !strings.Contains(contentStr, "<LdapServer>apps-authentik-ldap</LdapServer>") {
+		t.Error("UpdateLDAPBindPassword() should preserve other settings")
+	}
+}
+
+func TestConfigurator_UpdateLDAPBindPassword_NotConfigured(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c := NewConfigurator(8096, "http://localhost:9001", "test-token")
+	state := &configurator.AppState{Name: "jellyfin", DataPath: filepath.Join(tmpDir, "jellyfin")}
+	if err := c.UpdateLDAPBindPassword(context.Background(), state, "new-password"); err != nil {
+		t.Errorf("UpdateLDAPBindPassword() error = %v, want nil", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "jellyfin", "config", "plugins", "configurations", "LDAP-Auth.xml")); !os.IsNotExist(err) {
+		t.Error("UpdateLDAPBindPassword() should not create the plugin config")
+	}
+}
@@ -0,0 +1,206 @@
+package jellyfin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/provisioner"
+)
+
+// ldapAuthProviderID is the authentication provider the LDAP-Auth plugin registers.
+// Provisioned users are assigned to it so they log in with their Authentik password.
+const ldapAuthProviderID = "Jellyfin.Plugin.LDAP_Auth.LdapAuthenticationProviderPlugin"
+
+// Provisioner mirrors Authentik users into Jellyfin's user table
+type Provisioner struct {
+	Port     int
+	baseURL  string // Override for testing; if empty, uses localhost:Port
+	dataPath string
+}
+
+// NewProvisioner creates a new Jellyfin provisioner.
+// dataPath is Jellyfin's data directory, where the configurator stores the API key.
+func NewProvisioner(port int, dataPath string) *Provisioner {
+	if port == 0 {
+		port = 8096
+	}
+	return &Provisioner{
+		Port:     port,
+		dataPath: dataPath,
+	}
+}
+
+// getBaseURL returns the base URL for API calls
+func (p *Provisioner) getBaseURL() string {
+	if p.baseURL != "" {
+		return p.baseURL
+	}
+	return fmt.Sprintf("http://localhost:%d", p.Port)
+}
+
+func (p *Provisioner) Name() string {
+	return "jellyfin"
+}
+
+// provisionedUser is a Jellyfin user including its policy.
+// The policy is kept as a raw map so updates round-trip fields we don't model.
+type provisionedUser struct {
+	ID     string         `json:"Id"`
+	Name   string         `json:"Name"`
+	Policy map[string]any `json:"Policy"`
+}
+
+// ListUsers returns all Jellyfin users
+func (p *Provisioner) ListUsers(ctx context.Context) ([]provisioner.User, error) {
+	users, err := p.getUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]provisioner.User, 0, len(users))
+	for _, user := range users {
+		disabled, _ := user.Policy["IsDisabled"].(bool)
+		result = append(result, provisioner.User{
+			Username: user.Name,
+			Active:   !disabled,
+		})
+	}
+	return result, nil
+}
+
+// CreateUser creates a Jellyfin user that authenticates through LDAP
+func (p *Provisioner) CreateUser(ctx context.Context, user provisioner.User) error {
+	body, _ := json.Marshal(map[string]string{"Name": user.Username})
+
+	resp, err := p.do(ctx, "POST", "/Users/New", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("creating user: unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var created provisionedUser
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return err
+	}
+	if created.Policy == nil {
+		created.Policy = make(map[string]any)
+	}
+
+	created.Policy["AuthenticationProviderId"] = ldapAuthProviderID
+	created.Policy["IsDisabled"] = !user.Active
+	if err := p.setPolicy(ctx, created.ID, created.Policy); err != nil {
+		return fmt.Errorf("setting policy: %w", err)
+	}
+
+	log.Printf("Jellyfin: Provisioned user %s", user.Username)
+	return nil
+}
+
+// SetUserDisabled updates the IsDisabled flag on a user's policy
+func (p *Provisioner) SetUserDisabled(ctx context.Context, username string, disabled bool) error {
+	users, err := p.getUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if !strings.EqualFold(user.Name, username) {
+			continue
+		}
+		if user.Policy == nil {
+			user.Policy = make(map[string]any)
+		}
+		user.Policy["IsDisabled"] = disabled
+		if err := p.setPolicy(ctx, user.ID, user.Policy); err != nil {
+			return fmt.Errorf("setting policy: %w", err)
+		}
+		log.Printf("Jellyfin: Set user %s disabled=%t", username, disabled)
+		return nil
+	}
+
+	return fmt.Errorf("user %s not found", username)
+}
+
+// getUsers fetches all users with their policies
+func (p *Provisioner) getUsers(ctx context.Context) ([]provisionedUser, error) {
+	resp, err := p.do(ctx, "GET", "/Users", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var users []provisionedUser
+	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// setPolicy replaces a user's policy
+func (p *Provisioner) setPolicy(ctx context.Context, userID string, policy map[string]any) error {
+	body, _ := json.Marshal(policy)
+
+	resp, err := p.do(ctx, "POST", fmt.Sprintf("/Users/%s/Policy", userID), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// do sends an authenticated request using the API key created by the configurator
+func (p *Provisioner) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	apiKey, err := p.apiKey()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.getBaseURL()+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Emby-Authorization", fmt.Sprintf(`MediaBrowser Client="Bloud", Device="Host-Agent", DeviceId="bloud-host-agent", Version="1.0.0", Token="%s"`, apiKey))
+
+	return http.DefaultClient.Do(req)
+}
+
+// apiKey reads the API key written during LDAP setup.
+// Installs configured before the key existed have no admin left to mint one,
+// so they report ErrNotReady rather than failing every sync.
+func (p *Provisioner) apiKey() (string, error) {
+	data, err := os.ReadFile(filepath.Join(p.dataPath, apiKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", provisioner.ErrNotReady
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading API key: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
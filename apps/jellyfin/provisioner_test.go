@@ -0,0 +1,149 @@
+package jellyfin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/provisioner"
+)
+
+// testProvisioner creates a provisioner with an API key on disk pointed at server
+func testProvisioner(t *testing.T, server *httptest.Server) *Provisioner {
+	dataPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataPath, apiKeyFile), []byte("test-api-key\n"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	p := NewProvisioner(8096, dataPath)
+	p.baseURL = server.URL
+	return p
+}
+
+func TestProvisioner_ListUsers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("X-Emby-Authorization"), `Token="test-api-key"`) {
+			t.Errorf("missing API key in auth header: %s", r.Header.Get("X-Emby-Authorization"))
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"Id": "1", "Name": "alice", "Policy": map[string]any{"IsDisabled": false}},
+			{"Id": "2", "Name": "bob", "Policy": map[string]any{"IsDisabled": true}},
+		})
+	}))
+	defer server.Close()
+
+	p := testProvisioner(t, server)
+	users, err := p.ListUsers(context.Background())
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("ListUsers() returned %d users, want 2", len(users))
+	}
+	if users[0].Username != "alice" || !users[0].Active {
+		t.Errorf("unexpected first user: %+v", users[0])
+	}
+	if users[1].Username != "bob" || users[1].Active {
+		t.Errorf("unexpected second user: %+v", users[1])
+	}
+}
+
+func TestProvisioner_ListUsers_NoAPIKey(t *testing.T) {
+	p := NewProvisioner(8096, t.TempDir())
+
+	_, err := p.ListUsers(context.Background())
+	if !errors.Is(err, provisioner.ErrNotReady) {
+		t.Errorf("ListUsers() error = %v, want ErrNotReady", err)
+	}
+}
+
+func TestProvisioner_CreateUser(t *testing.T) {
+	var createdName string
+	var policy map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Users/New":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			createdName = body["Name"]
+			json.NewEncoder(w).Encode(map[string]any{
+				"Id":   "abc",
+				"Name": body["Name"],
+				"Policy": map[string]any{
+					"IsDisabled":              false,
+					"PasswordResetProviderId": "default-reset",
+				},
+			})
+		case "/Users/abc/Policy":
+			json.NewDecoder(r.Body).Decode(&policy)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected endpoint: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	p := testProvisioner(t, server)
+	err := p.CreateUser(context.Background(), provisioner.User{Username: "alice", Active: true})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	if createdName != "alice" {
+		t.Errorf("created user name = %q, want alice", createdName)
+	}
+	if policy["AuthenticationProviderId"] != ldapAuthProviderID {
+		t.Errorf("AuthenticationProviderId = %v, want LDAP provider", policy["AuthenticationProviderId"])
+	}
+	if policy["PasswordResetProviderId"] != "default-reset" {
+		t.Error("CreateUser() should preserve existing policy fields")
+	}
+}
+
+func TestProvisioner_SetUserDisabled(t *testing.T) {
+	var policy map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Users":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"Id": "abc", "Name": "Alice", "Policy": map[string]any{"IsDisabled": false}},
+			})
+		case "/Users/abc/Policy":
+			json.NewDecoder(r.Body).Decode(&policy)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected endpoint: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	p := testProvisioner(t, server)
+	if err := p.SetUserDisabled(context.Background(), "alice", true); err != nil {
+		t.Fatalf("SetUserDisabled() error = %v", err)
+	}
+
+	if policy["IsDisabled"] != true {
+		t.Errorf("IsDisabled = %v, want true", policy["IsDisabled"])
+	}
+}
+
+func TestProvisioner_SetUserDisabled_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{})
+	}))
+	defer server.Close()
+
+	p := testProvisioner(t, server)
+	if err := p.SetUserDisabled(context.Background(), "ghost", true); err == nil {
+		t.Error("SetUserDisabled() expected error for missing user")
+	}
+}
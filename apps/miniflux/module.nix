@@ -13,7 +13,8 @@ mkBloudApp {
   network = "host";
   database = "miniflux";
 
-  # Load secrets from env file: DATABASE_URL, OAUTH_CLIENT_SECRET
+  # Load secrets from env file: DATABASE_URL. The client secret is the
+  # oauth-client-secret systemd credential (bloud.apps.miniflux.credentials)
   envFile = "${secretsDir}/miniflux.env";
 
   options = {
@@ -21,7 +22,7 @@ mkBloudApp {
       default = "miniflux-client";
       description = "OpenID Connect client ID";
     };
-    # openidClientSecret loaded from miniflux.env as OAUTH_CLIENT_SECRET
+    # openidClientSecret loaded from the oauth-client-secret credential as OAUTH2_CLIENT_SECRET
   };
 
   environment = cfg: {
@@ -33,8 +34,9 @@ mkBloudApp {
     # SSO via Authentik - users created via OAuth, no local admin needed
     DISABLE_LOCAL_AUTH = "true";
     # Host-dependent SSO env vars (OAUTH2_OIDC_DISCOVERY_ENDPOINT, OAUTH2_REDIRECT_URL,
-    # OAUTH2_CLIENT_ID, OAUTH2_CLIENT_SECRET, etc.) are written to the env file at runtime
+    # OAUTH2_CLIENT_ID, etc.) are written to the env file at runtime
     # by the host-agent prestart hook, using detected local IPs for dynamic host support.
+    # POLLING_FREQUENCY comes from the pollingFrequency setting in metadata.yaml.
   };
 
   # All runtime configuration handled by Go configurator:
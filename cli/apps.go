@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/apiclient"
+	"codeberg.org/d-buckner/bloud/cli/vm"
+)
+
+const (
+	// Test VM from lima/test-nixos.yaml.template, run beside the dev VM
+	testVMName    = "bloud-test"
+	testAPIPort   = 3001
+	devAPIPort    = 3000
+	appsDescWidth = 50
+)
+
+// hostAgentAPI reaches a running host-agent from inside its VM, like
+// install and uninstall do, so it works without port forwarding: client is
+// the generated API client over vmTransport, and streams are curled
+type hostAgentAPI struct {
+	client *apiclient.Client
+	exec   func(cmd string) (string, error)
+	pipe   func(cmd string, stdout io.Writer) error // for streams; nil when unsupported
+	port   int
+}
+
+func newHostAgentAPI(exec func(cmd string) (string, error), pipe func(cmd string, stdout io.Writer) error, port int) *hostAgentAPI {
+	client := apiclient.New(fmt.Sprintf("http://localhost:%d", port))
+	client.HTTPClient = &http.Client{Transport: vmTransport{exec: exec}}
+	return &hostAgentAPI{client: client, exec: exec, pipe: pipe, port: port}
+}
+
+// vmTransport is an http.RoundTripper that sends each request with curl
+// where exec runs commands, in the VM or on the native host
+type vmTransport struct {
+	exec func(cmd string) (string, error)
+}
+
+func (t vmTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	curl := "curl -s -X " + req.Method + ` -w '\n%{http_code}'`
+	for key, values := range req.Header {
+		for _, v := range values {
+			curl += " -H " + shellQuote(key+": "+v)
+		}
+	}
+	curl += " " + shellQuote(req.URL.String())
+
+	// The body goes through base64 so it survives the shell unquoted
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > 0 {
+			curl = fmt.Sprintf("echo %s | base64 -d | %s --data-binary @-", base64.StdEncoding.EncodeToString(body), curl)
+		}
+	}
+
+	output, err := t.exec(curl)
+	if err != nil {
+		return nil, fmt.Errorf("host-agent not reachable: %w", err)
+	}
+
+	// Last line is the HTTP status code
+	output = strings.TrimSpace(output)
+	body, httpCode := "", output
+	if idx := strings.LastIndex(output, "\n"); idx >= 0 {
+		body, httpCode = output[:idx], output[idx+1:]
+	}
+	code, err := strconv.Atoi(httpCode)
+	if err != nil || code == 0 {
+		return nil, fmt.Errorf("host-agent not reachable (HTTP status %q)", httpCode)
+	}
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode: code,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// resolveHostAgentAPI finds the host-agent to talk to: the Proxmox VM in
+// Proxmox mode, otherwise the dev VM (or the test VM with test set) or the
+// native host
+func resolveHostAgentAPI(test bool) (*hostAgentAPI, error) {
+	if isPVEMode() {
+		cfg := getPVEConfig()
+		if !pveVMIsRunning(cfg) {
+			return nil, fmt.Errorf("VM is not running. Start with: ./bloud start [iso]")
+		}
+		ip := getVMIP(cfg)
+		if ip == "" {
+			return nil, fmt.Errorf("could not get VM IP")
+		}
+		return newHostAgentAPI(
+			func(cmd string) (string, error) { return vmExec(ip, cmd) },
+			func(cmd string, stdout io.Writer) error { return vmExecPipe(ip, cmd, nil, stdout) },
+			devAPIPort,
+		), nil
+	}
+
+	if vm.IsNative() {
+		if test {
+			return nil, fmt.Errorf("--test needs the Lima test VM")
+		}
+		return newHostAgentAPI(
+			vm.LocalExec,
+			func(cmd string, stdout io.Writer) error { return vm.LocalExecPipe(cmd, nil, stdout) },
+			devAPIPort,
+		), nil
+	}
+
+	vmName, port := devVMName, devAPIPort
+	if test {
+		vmName, port = testVMName, testAPIPort
+	}
+	if !vm.IsRunning(vmName) {
+		return nil, fmt.Errorf("VM %s is not running. Start with: ./bloud start", vmName)
+	}
+	return newHostAgentAPI(
+		func(cmd string) (string, error) { return vm.Exec(vmName, cmd) },
+		func(cmd string, stdout io.Writer) error { return vm.ExecPipe(vmName, cmd, nil, stdout) },
+		port,
+	), nil
+}
+
+// cmdApps handles "./bloud apps list|info|plan <app>"
+func cmdApps(args []string, test, remove bool) int {
+	sub := args[0]
+	if sub != "list" && len(args) < 2 {
+		errorf("Usage: ./bloud apps %s <app>", sub)
+		return exitUsage
+	}
+
+	api, err := resolveHostAgentAPI(test)
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	switch sub {
+	case "list":
+		return appsList(api)
+	case "info":
+		return appsInfo(api, args[1])
+	case "plan":
+		return appsPlan(api, args[1], remove)
+	default:
+		errorf("Unknown apps command: %s (use list, info or plan)", sub)
+		return exitUsage
+	}
+}
+
+func appsList(api *hostAgentAPI) int {
+	ctx := context.Background()
+	catalog, err := api.client.ListApps(ctx, nil)
+	if err != nil {
+		errorf("Failed to list catalog: %v", err)
+		return 1
+	}
+	installed, err := api.client.ListInstalledApps(ctx, nil)
+	if err != nil {
+		errorf("Failed to list installed apps: %v", err)
+		return 1
+	}
+
+	if jsonOutput {
+		return printJSON(map[string]any{"catalog": catalog.Apps, "installed": installed})
+	}
+
+	status := make(map[string]string, len(installed))
+	for _, app := range installed {
+		status[app.Name] = app.Status
+	}
+
+	sort.Slice(catalog.Apps, func(i, j int) bool { return catalog.Apps[i].Name < catalog.Apps[j].Name })
+
+	fmt.Println()
+	fmt.Printf("  %-16s %-16s %-12s %s\n", "NAME", "CATEGORY", "STATUS", "DESCRIPTION")
+	for _, app := range catalog.Apps {
+		fmt.Printf("  %-16s %-16s %s %s\n", app.Name, app.Category, colorStatus(status[app.Name], 12), truncate(app.Description, appsDescWidth))
+	}
+
+	// System apps aren't in the catalog listing, but show up once installed
+	var system []apiclient.InstalledApp
+	for _, app := range installed {
+		if app.IsSystem {
+			system = append(system, app)
+		}
+	}
+	if len(system) > 0 {
+		sort.Slice(system, func(i, j int) bool { return system[i].Name < system[j].Name })
+		fmt.Println()
+		fmt.Printf("  %-16s %-16s %s\n", "SYSTEM", "", "STATUS")
+		for _, app := range system {
+			fmt.Printf("  %-16s %-16s %s\n", app.Name, "", colorStatus(app.Status, 12))
+		}
+	}
+	fmt.Println()
+	return 0
+}
+
+func appsInfo(api *hostAgentAPI, name string) int {
+	ctx := context.Background()
+	app, err := api.client.GetAppMetadata(ctx, name)
+	if err != nil {
+		errorf("Failed to get %s: %v", name, err)
+		return 1
+	}
+	installed, err := api.client.ListInstalledApps(ctx, url.Values{"q": {name}})
+	if err != nil {
+		errorf("Failed to list installed apps: %v", err)
+		return 1
+	}
+	var record *apiclient.InstalledApp
+	for i := range installed {
+		if installed[i].Name == name {
+			record = &installed[i]
+		}
+	}
+
+	if jsonOutput {
+		return printJSON(map[string]any{"app": app, "installed": record})
+	}
+
+	fmt.Println()
+	fmt.Printf("  %s%s%s (%s)\n", colorCyan, app.DisplayName, colorReset, app.Name)
+	if app.Description != "" {
+		fmt.Printf("  %s\n", app.Description)
+	}
+	fmt.Println()
+	fmt.Printf("  %-14s %s\n", "Category:", app.Category)
+	fmt.Printf("  %-14s %s\n", "Version:", app.Version)
+	if app.Port != 0 {
+		fmt.Printf("  %-14s %d\n", "Port:", app.Port)
+	}
+	if app.SSO.Strategy != "" {
+		fmt.Printf("  %-14s %s\n", "SSO:", app.SSO.Strategy)
+	}
+	if len(app.Dependencies) > 0 {
+		fmt.Printf("  %-14s %s\n", "Depends on:", strings.Join(app.Dependencies, ", "))
+	}
+	if app.Resources.MinRam != 0 || app.Resources.MinDisk != 0 {
+		fmt.Printf("  %-14s %d MB RAM, %d GB disk\n", "Needs:", app.Resources.MinRam, app.Resources.MinDisk)
+	}
+	if len(app.Tags) > 0 {
+		fmt.Printf("  %-14s %s\n", "Tags:", strings.Join(app.Tags, ", "))
+	}
+
+	fmt.Println()
+	if record == nil {
+		fmt.Printf("  %-14s not installed\n", "Status:")
+		fmt.Println()
+		return 0
+	}
+	fmt.Printf("  %-14s %s\n", "Status:", colorStatus(record.Status, 0))
+	fmt.Printf("  %-14s %s\n", "Installed:", record.InstalledAt.Local().Format("2006-01-02 15:04"))
+	if record.Image != "" {
+		fmt.Printf("  %-14s %s\n", "Image:", record.Image)
+	}
+	if len(record.IntegrationConfig) > 0 {
+		keys := make([]string, 0, len(record.IntegrationConfig))
+		for k := range record.IntegrationConfig {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Printf("  %-14s", "Integrations:")
+		for i, k := range keys {
+			if i > 0 {
+				fmt.Printf("  %-14s", "")
+			}
+			fmt.Printf(" %s → %s\n", k, record.IntegrationConfig[k])
+		}
+	}
+	fmt.Println()
+	return 0
+}
+
+func appsPlan(api *hostAgentAPI, name string, remove bool) int {
+	if remove {
+		plan, err := api.client.PlanRemove(context.Background(), name)
+		if err != nil {
+			errorf("Failed to plan removal of %s: %v", name, err)
+			return 1
+		}
+		if jsonOutput {
+			return printJSON(plan)
+		}
+
+		fmt.Println()
+		if plan.CanRemove {
+			fmt.Printf("  %s%s can be removed%s\n", colorGreen, name, colorReset)
+		} else {
+			fmt.Printf("  %s%s can't be removed%s\n", colorRed, name, colorReset)
+		}
+		printPlanList("Blocked by", plan.Blockers)
+		printPlanList("Will unconfigure", plan.WillUnconfigure)
+		fmt.Println()
+		return 0
+	}
+
+	plan, err := api.client.PlanInstall(context.Background(), name)
+	if err != nil {
+		errorf("Failed to plan install of %s: %v", name, err)
+		return 1
+	}
+	if jsonOutput {
+		return printJSON(plan)
+	}
+
+	fmt.Println()
+	if plan.CanInstall {
+		fmt.Printf("  %s%s can be installed%s\n", colorGreen, name, colorReset)
+	} else {
+		fmt.Printf("  %s%s can't be installed%s\n", colorRed, name, colorReset)
+	}
+	printPlanList("Blocked by", plan.Blockers)
+
+	if len(plan.Choices) > 0 {
+		fmt.Println()
+		fmt.Println("  Integrations to choose:")
+		for _, c := range plan.Choices {
+			var options []string
+			for _, o := range c.Installed {
+				options = append(options, o.App+" (installed)")
+			}
+			for _, o := range c.Available {
+				options = append(options, o.App)
+			}
+			required := ""
+			if c.Required {
+				required = " (required)"
+			}
+			fmt.Printf("    %s%s: %s", c.Integration, required, strings.Join(options, ", "))
+			if c.Recommended != "" {
+				fmt.Printf(" — recommended: %s", c.Recommended)
+			}
+			fmt.Println()
+		}
+	}
+
+	printPlanTasks("Configured automatically", plan.AutoConfig)
+	printPlanTasks("Existing apps that will integrate with it", plan.Dependents)
+	fmt.Println()
+	return 0
+}
+
+func printPlanList(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("  %s:\n", title)
+	for _, item := range items {
+		fmt.Printf("    %s\n", item)
+	}
+}
+
+func printPlanTasks(title string, tasks []apiclient.ConfigTask) {
+	if len(tasks) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("  %s:\n", title)
+	for _, t := range tasks {
+		fmt.Printf("    %s ← %s (%s)\n", t.Target, t.Source, t.Integration)
+	}
+}
+
+// colorStatus colors an installed app's status, padded to width
+func colorStatus(status string, width int) string {
+	color := colorYellow
+	switch status {
+	case "":
+		status, color = "-", ""
+	case "running":
+		color = colorGreen
+	case "failed", "error":
+		color = colorRed
+	}
+	padded := fmt.Sprintf("%-*s", width, status)
+	if color == "" {
+		return padded
+	}
+	return color + padded + colorReset
+}
+
+func truncate(s string, width int) string {
+	if len([]rune(s)) <= width {
+		return s
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
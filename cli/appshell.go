@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// cmdAppShell handles "./bloud shell --app <name> [cmd...]": it finds the
+// app's podman container in the environment and runs podman exec -it there,
+// opening sh when no command is given
+func cmdAppShell(appName string, args []string) int {
+	if !appNamePattern.MatchString(appName) {
+		errorf("Invalid app name: %s", appName)
+		return exitUsage
+	}
+
+	run, err := resolveBackupTarget()
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+	var out bytes.Buffer
+	if err := run("podman ps --format '{{.Names}}'", nil, &out); err != nil {
+		errorf("Failed to list containers: %v", err)
+		return 1
+	}
+	container, err := appContainer(appName, strings.Fields(out.String()))
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	command := "sh"
+	if len(args) > 0 {
+		command = strings.Join(args, " ")
+	}
+	podmanExec := []string{"podman", "exec", "-it", container, command}
+	if isPVEMode() {
+		return cmdShellPVE(podmanExec)
+	}
+	return cmdShell(podmanExec)
+}
+
+// appContainer picks an app's container from the running ones. Containers
+// are named after the app, some with an "apps-" prefix; apps with several
+// containers (apps-authentik-server, apps-authentik-worker) need the full
+// name when the prefix is ambiguous.
+func appContainer(appName string, running []string) (string, error) {
+	var prefixed []string
+	for _, name := range running {
+		if name == appName || name == "apps-"+appName {
+			return name, nil
+		}
+		if strings.HasPrefix(name, appName+"-") || strings.HasPrefix(name, "apps-"+appName+"-") {
+			prefixed = append(prefixed, name)
+		}
+	}
+
+	switch len(prefixed) {
+	case 0:
+		return "", fmt.Errorf("no running container for %s (is it installed and started?)", appName)
+	case 1:
+		return prefixed[0], nil
+	default:
+		return "", fmt.Errorf("%s has several containers, pick one with --app: %s", appName, strings.Join(prefixed, ", "))
+	}
+}
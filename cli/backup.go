@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
+)
+
+const (
+	// postgresContainer and postgresUser match apps/postgres/module.nix
+	postgresContainer = "apps-postgres"
+	postgresUser      = "apps"
+
+	// backupDumpName is the database dump's name inside a backup archive
+	backupDumpName = "postgres.sql"
+)
+
+// pipeExec runs a command on the target with stdin and stdout attached
+type pipeExec func(cmd string, stdin io.Reader, stdout io.Writer) error
+
+// backupScript dumps every database while PostgreSQL stays online, then
+// streams a gzipped tar of the dump and the data dir to stdout. The raw
+// PostgreSQL files are left out since the dump replaces them, as are the
+// host-agent's own database backups.
+var backupScript = fmt.Sprintf(`set -euo pipefail
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+podman exec %[1]s pg_dumpall -U %[2]s --clean --if-exists > "$tmp/%[3]s"
+tar -czf - -C "$tmp" %[3]s -C %[4]s --exclude=./%[1]s --exclude=./backups .
+`, postgresContainer, postgresUser, backupDumpName, nativeDataDir)
+
+// restoreScript unpacks an archive from stdin, stops the app containers that
+// use the database, replays the dump, copies the data dir back and restarts
+// the apps. The host-agent must be stopped first (stopHint says how) so
+// nothing holds the databases open.
+func restoreScript(stopHint string) string {
+	return fmt.Sprintf(`set -euo pipefail
+if curl -sf -o /dev/null --max-time 2 http://localhost:%[5]d/api/v1/health; then
+  echo "host-agent is running; stop it before restoring: %[6]s" >&2
+  exit 1
+fi
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+tar -xzf - -C "$tmp"
+if [ ! -f "$tmp/%[3]s" ]; then
+  echo "not a bloud backup: %[3]s is missing" >&2
+  exit 1
+fi
+apps=$(systemctl --user list-units 'podman-*.service' --state=active --plain --no-legend | awk '{print $1}' | grep -v '^podman-%[1]s.service$' || true)
+if [ -n "$apps" ]; then systemctl --user stop $apps; fi
+podman exec -i %[1]s psql -U %[2]s -d postgres -q < "$tmp/%[3]s" > /dev/null
+rm "$tmp/%[3]s"
+cp -a "$tmp/." %[4]s/
+if [ -n "$apps" ]; then systemctl --user start $apps; fi
+`, postgresContainer, postgresUser, backupDumpName, nativeDataDir, devAPIPort, stopHint)
+}
+
+// resolveBackupTarget finds where the environment's data lives: the Proxmox
+// VM, the native host or the Lima dev VM
+func resolveBackupTarget() (pipeExec, error) {
+	if isPVEMode() {
+		cfg := getPVEConfig()
+		if !pveVMIsRunning(cfg) {
+			return nil, fmt.Errorf("VM is not running. Start with: ./bloud start [iso]")
+		}
+		ip := getVMIP(cfg)
+		if ip == "" {
+			return nil, fmt.Errorf("could not get VM IP")
+		}
+		return func(cmd string, stdin io.Reader, stdout io.Writer) error {
+			return vmExecPipe(ip, cmd, stdin, stdout)
+		}, nil
+	}
+
+	if !vm.IsNative() && !vm.IsRunning(devVMName) {
+		return nil, fmt.Errorf("VM is not running. Start with: ./bloud start")
+	}
+	return func(cmd string, stdin io.Reader, stdout io.Writer) error {
+		return vm.RunPipe(devVMName, cmd, stdin, stdout)
+	}, nil
+}
+
+// cmdBackup handles "./bloud backup [archive]": it captures the data dir and
+// a dump of every app database into a local .tar.gz
+func cmdBackup(args []string) int {
+	archive := fmt.Sprintf("bloud-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+	if len(args) > 0 {
+		archive = args[0]
+	}
+
+	run, err := resolveBackupTarget()
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	// Write next to the archive and rename, so a failed backup never
+	// leaves a truncated archive behind
+	tmp, err := os.CreateTemp(filepath.Dir(archive), ".bloud-backup-*")
+	if err != nil {
+		errorf("Failed to create archive: %v", err)
+		return 1
+	}
+	defer os.Remove(tmp.Name())
+
+	log("Backing up databases and data directory...")
+	err = run(backupScript, nil, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		errorf("Backup failed: %v", err)
+		return 1
+	}
+	if err := os.Rename(tmp.Name(), archive); err != nil {
+		errorf("Failed to save archive: %v", err)
+		return 1
+	}
+
+	info, err := os.Stat(archive)
+	if err != nil {
+		errorf("Failed to stat archive: %v", err)
+		return 1
+	}
+	log(fmt.Sprintf("Backup written to %s (%s)", archive, formatBytes(info.Size())))
+	return 0
+}
+
+// cmdRestore handles "./bloud restore <archive>": it replays a backup into
+// the environment, replacing its databases and data dir contents
+func cmdRestore(args []string) int {
+	archive := args[0]
+
+	f, err := os.Open(archive)
+	if err != nil {
+		errorf("Failed to open archive: %v", err)
+		return 1
+	}
+	defer f.Close()
+
+	run, err := resolveBackupTarget()
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	stopHint := "./bloud stop"
+	if isPVEMode() {
+		stopHint = "./bloud shell sudo systemctl stop bloud-host-agent"
+	}
+
+	log(fmt.Sprintf("Restoring %s...", archive))
+	if err := run(restoreScript(stopHint), f, os.Stdout); err != nil {
+		errorf("Restore failed: %v", err)
+		return 1
+	}
+
+	log("Restore complete. Start the host-agent again to pick up the restored state.")
+	return 0
+}
+
+// formatBytes renders a size in the largest whole unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %s", float64(n)/float64(div), strings.Split("KiB MiB GiB TiB", " ")[exp])
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// cmdVerifyCatalog checks apps/*/metadata.yaml with the host-agent's
+// catalog.Verify, built from the checkout so the CLI and the host-agent
+// always agree on what a valid catalog is. Problems are printed as
+// apps/<app>/metadata.yaml:<line>: <message>, relative to the project root.
+func cmdVerifyCatalog() int {
+	root, err := getProjectRoot()
+	if err != nil {
+		errorf("Could not find project root: %v", err)
+		return 1
+	}
+
+	tmpDir, err := os.MkdirTemp("", "bloud-verify-catalog-")
+	if err != nil {
+		errorf("Failed to create temp dir: %v", err)
+		return 1
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bin := filepath.Join(tmpDir, "verify-catalog")
+	build := localExec("go", "build", "-o", bin, "./cmd/verify-catalog")
+	build.Dir = filepath.Join(root, "services", "host-agent")
+	build.Stdout = os.Stderr
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		errorf("Failed to build verify-catalog: %v", err)
+		return 1
+	}
+
+	verify := localExec(bin, "apps")
+	verify.Dir = root
+	verify.Stdout = os.Stdout
+	verify.Stderr = os.Stderr
+	if err := verify.Run(); err != nil {
+		return 1
+	}
+	log("Catalog OK")
+	return 0
+}
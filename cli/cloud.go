@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const cloudRequestTimeout = 30 * time.Second
+
+// cloudServer is what ./bloud deploy asks a provider to create: a stock
+// Debian server that is then kexec'd into the Bloud installer
+type cloudServer struct {
+	name       string
+	serverType string
+	region     string
+}
+
+// cloudProvider creates servers through a provider's API
+type cloudProvider interface {
+	// ensureSSHKey returns the ID of the account's copy of publicKey,
+	// uploading it under name when it isn't there yet
+	ensureSSHKey(name, publicKey string) (int64, error)
+	// createServer creates a Debian server that accepts keyID as root
+	createServer(s cloudServer, keyID int64) (int64, error)
+	// serverAddress returns the server's public IPv4 and whether it is up
+	serverAddress(id int64) (ip string, running bool, err error)
+}
+
+// cloudProviderInfo describes a provider ./bloud deploy supports
+type cloudProviderInfo struct {
+	title      string
+	tokenEnv   string
+	baseURL    string
+	serverType string // default; needs enough memory to hold the installer in RAM
+	region     string // default
+	newClient  func(api cloudAPI) cloudProvider
+}
+
+var cloudProviders = map[string]cloudProviderInfo{
+	"hetzner": {
+		title:      "Hetzner Cloud",
+		tokenEnv:   "HCLOUD_TOKEN",
+		baseURL:    "https://api.hetzner.cloud/v1",
+		serverType: "cx32",
+		region:     "nbg1",
+		newClient:  func(api cloudAPI) cloudProvider { return hetznerCloud{api} },
+	},
+	"do": {
+		title:      "DigitalOcean",
+		tokenEnv:   "DIGITALOCEAN_TOKEN",
+		baseURL:    "https://api.digitalocean.com/v2",
+		serverType: "s-4vcpu-8gb",
+		region:     "fra1",
+		newClient:  func(api cloudAPI) cloudProvider { return digitalOcean{api} },
+	},
+}
+
+// cloudProviderNames lists the providers for usage messages
+func cloudProviderNames() []string {
+	names := make([]string, 0, len(cloudProviders))
+	for name := range cloudProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCloudProvider builds a client for the named provider, with the API
+// token from the provider's usual environment variable
+func newCloudProvider(name string) (cloudProvider, cloudProviderInfo, error) {
+	info, ok := cloudProviders[name]
+	if !ok {
+		return nil, info, fmt.Errorf("unknown provider %q (use %s)", name, strings.Join(cloudProviderNames(), " or "))
+	}
+	token := os.Getenv(info.tokenEnv)
+	if token == "" {
+		return nil, info, fmt.Errorf("%s is not set (create an API token in the %s console)", info.tokenEnv, info.title)
+	}
+	return info.newClient(cloudAPI{baseURL: info.baseURL, token: token}), info, nil
+}
+
+// cloudAPI is a JSON API authenticated with a bearer token
+type cloudAPI struct {
+	baseURL string
+	token   string
+}
+
+// call sends body (when non-nil) as JSON and decodes a 2xx response into v
+// (when non-nil), surfacing the API's error message otherwise
+func (a cloudAPI) call(method, path string, body, v any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, a.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: cloudRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return cloudError(resp.StatusCode, data)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// cloudError extracts the message from Hetzner's {"error":{"message"}} or
+// DigitalOcean's {"message"} error bodies
+func cloudError(code int, body []byte) error {
+	var apiErr struct {
+		Message string `json:"message"`
+		Error   struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	msg := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &apiErr) == nil {
+		if apiErr.Error.Message != "" {
+			msg = apiErr.Error.Message
+		} else if apiErr.Message != "" {
+			msg = apiErr.Message
+		}
+	}
+	return fmt.Errorf("HTTP %d: %s", code, msg)
+}
+
+// sameSSHKey compares public keys by type and key material, ignoring the
+// comment
+func sameSSHKey(a, b string) bool {
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	return len(fa) >= 2 && len(fb) >= 2 && fa[0] == fb[0] && fa[1] == fb[1]
+}
+
+// cloudSSHKey is an SSH key as both providers list it
+type cloudSSHKey struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key"`
+}
+
+// ensureCloudSSHKey finds publicKey among the account's keys at listPath or
+// uploads it to createPath; both providers use the same key shape
+func ensureCloudSSHKey(api cloudAPI, listPath, createPath, name, publicKey string) (int64, error) {
+	var list struct {
+		SSHKeys []cloudSSHKey `json:"ssh_keys"`
+	}
+	if err := api.call("GET", listPath, nil, &list); err != nil {
+		return 0, fmt.Errorf("failed to list SSH keys: %w", err)
+	}
+	for _, key := range list.SSHKeys {
+		if sameSSHKey(key.PublicKey, publicKey) {
+			return key.ID, nil
+		}
+	}
+
+	var created struct {
+		SSHKey cloudSSHKey `json:"ssh_key"`
+	}
+	body := map[string]string{"name": name, "public_key": publicKey}
+	if err := api.call("POST", createPath, body, &created); err != nil {
+		return 0, fmt.Errorf("failed to upload SSH key: %w", err)
+	}
+	return created.SSHKey.ID, nil
+}
+
+// hetznerCloud talks to the Hetzner Cloud API
+type hetznerCloud struct {
+	api cloudAPI
+}
+
+func (h hetznerCloud) ensureSSHKey(name, publicKey string) (int64, error) {
+	return ensureCloudSSHKey(h.api, "/ssh_keys?per_page=50", "/ssh_keys", name, publicKey)
+}
+
+func (h hetznerCloud) createServer(s cloudServer, keyID int64) (int64, error) {
+	body := map[string]any{
+		"name":        s.name,
+		"server_type": s.serverType,
+		"location":    s.region,
+		"image":       "debian-12",
+		"ssh_keys":    []int64{keyID},
+	}
+	var resp struct {
+		Server struct {
+			ID int64 `json:"id"`
+		} `json:"server"`
+	}
+	if err := h.api.call("POST", "/servers", body, &resp); err != nil {
+		return 0, fmt.Errorf("failed to create server: %w", err)
+	}
+	return resp.Server.ID, nil
+}
+
+func (h hetznerCloud) serverAddress(id int64) (string, bool, error) {
+	var resp struct {
+		Server struct {
+			Status    string `json:"status"`
+			PublicNet struct {
+				IPv4 struct {
+					IP string `json:"ip"`
+				} `json:"ipv4"`
+			} `json:"public_net"`
+		} `json:"server"`
+	}
+	if err := h.api.call("GET", fmt.Sprintf("/servers/%d", id), nil, &resp); err != nil {
+		return "", false, err
+	}
+	return resp.Server.PublicNet.IPv4.IP, resp.Server.Status == "running", nil
+}
+
+// digitalOcean talks to the DigitalOcean API
+type digitalOcean struct {
+	api cloudAPI
+}
+
+func (d digitalOcean) ensureSSHKey(name, publicKey string) (int64, error) {
+	return ensureCloudSSHKey(d.api, "/account/keys?per_page=200", "/account/keys", name, publicKey)
+}
+
+func (d digitalOcean) createServer(s cloudServer, keyID int64) (int64, error) {
+	body := map[string]any{
+		"name":     s.name,
+		"size":     s.serverType,
+		"region":   s.region,
+		"image":    "debian-12-x64",
+		"ssh_keys": []int64{keyID},
+	}
+	var resp struct {
+		Droplet struct {
+			ID int64 `json:"id"`
+		} `json:"droplet"`
+	}
+	if err := d.api.call("POST", "/droplets", body, &resp); err != nil {
+		return 0, fmt.Errorf("failed to create droplet: %w", err)
+	}
+	return resp.Droplet.ID, nil
+}
+
+func (d digitalOcean) serverAddress(id int64) (string, bool, error) {
+	var resp struct {
+		Droplet struct {
+			Status   string `json:"status"`
+			Networks struct {
+				V4 []struct {
+					IPAddress string `json:"ip_address"`
+					Type      string `json:"type"`
+				} `json:"v4"`
+			} `json:"networks"`
+		} `json:"droplet"`
+	}
+	if err := d.api.call("GET", fmt.Sprintf("/droplets/%d", id), nil, &resp); err != nil {
+		return "", false, err
+	}
+	for _, n := range resp.Droplet.Networks.V4 {
+		if n.Type == "public" {
+			return n.IPAddress, resp.Droplet.Status == "active", nil
+		}
+	}
+	return "", false, nil
+}
@@ -0,0 +1,603 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
+)
+
+// Exit codes shared by every command
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2 // bad command, flag or arguments
+)
+
+// mode is a set of backends a command works with
+type mode int
+
+const (
+	modeLima mode = 1 << iota
+	modeNative
+	modePVE
+
+	devModes = modeLima | modeNative
+	allModes = devModes | modePVE
+)
+
+// currentMode is the backend this invocation talks to
+func currentMode() mode {
+	switch {
+	case isPVEMode():
+		return modePVE
+	case vm.IsNative():
+		return modeNative
+	default:
+		return modeLima
+	}
+}
+
+// command is a ./bloud subcommand. setup defines the command's flags on fs
+// and returns the function that runs it with the remaining positional args.
+type command struct {
+	name    string
+	args    string // positional args synopsis, e.g. "<app>"
+	summary string
+	help    string // extra text for ./bloud help <command>
+	modes   mode
+
+	minArgs, maxArgs int // maxArgs < 0 means unlimited
+
+	json    bool // honors --json
+	remote  bool // works against --host
+	noVM    bool // runs before the Lima SSH check
+	rawArgs bool // flags end at the first positional arg, which stays verbatim
+
+	setup func(fs *flag.FlagSet) func(args []string) int
+}
+
+// commands is the registry, in the order usage lists them. It is filled in
+// init since help and completion read it back.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name: "setup", summary: "Check prerequisites and prepare the VM image or NixOS config",
+			modes: devModes, noVM: true,
+			setup: noFlags(func([]string) int { return cmdSetup() }),
+		},
+		{
+			name: "start", summary: "Start the dev environment (auto-starts the VM if needed)",
+			modes: devModes,
+			setup: noFlags(func([]string) int { return cmdStart() }),
+		},
+		{
+			name: "start", args: "[iso]", summary: "Deploy ISO → create VM → boot → check (VM stays running)",
+			help:  "The ISO is a local path or URL; the latest GitHub release is used by default.",
+			modes: modePVE, maxArgs: 1,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				var opts pveStartOptions
+				fs.BoolVar(&opts.build, "build", false, "Build the ISO (on this host with Nix and KVM, else on the build VM) instead of downloading")
+				fs.BoolVar(&opts.skipDeploy, "skip-deploy", false, "Reuse the existing VM (skip ISO upload and VM create)")
+				fs.StringVar(&opts.host, "pve-host", "", "Proxmox SSH `host` to use instead of BLOUD_PVE_HOST")
+				fs.StringVar(&opts.vmid, "vmid", "", "VM `id` to use instead of BLOUD_PVE_VMID")
+				fs.DurationVar(&opts.checkTimeout, "timeout", pveDefaultCheckTimeout, "Time limit for each attempt of a health check")
+				return func(args []string) int {
+					if opts.checkTimeout <= 0 {
+						errorf("--timeout must be positive")
+						return exitUsage
+					}
+					if len(args) > 0 {
+						opts.isoSource = args[0]
+					}
+					return cmdStartPVE(opts)
+				}
+			},
+		},
+		{
+			name: "stop", summary: "Stop dev services, or the Proxmox VM",
+			modes: allModes,
+			setup: noFlags(func([]string) int {
+				if isPVEMode() {
+					return cmdStopPVE()
+				}
+				return cmdStop()
+			}),
+		},
+		{
+			name: "status", summary: "Show environment and service status",
+			modes: allModes, json: true, remote: true,
+			setup: noFlags(func([]string) int {
+				if isPVEMode() {
+					return cmdStatusPVE()
+				}
+				return cmdStatus()
+			}),
+		},
+		{
+			name: "watch", summary: "Show a live dashboard of status, health and app events",
+			help:  "Redraws every interval until Ctrl-C. App status changes come from the\nhost-agent's app events stream, so they appear as they happen.",
+			modes: allModes,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				interval := fs.Duration("interval", defaultWatchInterval, "Time between redraws")
+				return func([]string) int { return cmdWatch(*interval) }
+			},
+		},
+		{
+			name: "services", summary: "Show podman service status",
+			modes: devModes, json: true,
+			setup: noFlags(func([]string) int { return cmdServices() }),
+		},
+		{
+			name: "logs", args: "[app]", summary: "Stream dev service logs, or show an app's journal",
+			modes: allModes, maxArgs: 1, remote: true,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				follow := fs.Bool("follow", false, "Keep printing new journal lines (with an app)")
+				alias(fs, "f", "follow")
+				lines := fs.Int("lines", appLogLines, "Show the last `n` journal lines (with an app)")
+				alias(fs, "n", "lines")
+				return func(args []string) int {
+					switch {
+					case len(args) > 0:
+						if *lines < 0 {
+							errorf("Invalid line count: %d", *lines)
+							return exitUsage
+						}
+						return cmdAppLogs(args[0], *follow, *lines)
+					case isPVEMode():
+						return cmdLogsPVE()
+					default:
+						return cmdLogs()
+					}
+				}
+			},
+		},
+		{
+			name: "attach", summary: "Attach to the tmux session (Ctrl-B D to detach)",
+			modes: devModes,
+			setup: noFlags(func([]string) int { return cmdAttach() }),
+		},
+		{
+			name: "shell", args: "[cmd...]", summary: "Open a shell in the VM, or run a command",
+			help:  "With --app, the shell or command runs inside the app's container via podman exec -it.",
+			modes: allModes, maxArgs: -1, rawArgs: true,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				app := fs.String("app", "", "Run in the `app`'s container instead of the VM")
+				return func(args []string) int {
+					if *app != "" {
+						return cmdAppShell(*app, args)
+					}
+					if isPVEMode() {
+						return cmdShellPVE(args)
+					}
+					return cmdShell(args)
+				}
+			},
+		},
+		{
+			name: "rebuild", summary: "Rebuild the NixOS configuration",
+			modes: devModes,
+			setup: noFlags(func([]string) int { return cmdRebuild() }),
+		},
+		{
+			name: "doctor", summary: "Diagnose the environment (attach output to bug reports)",
+			modes: allModes, json: true, noVM: true,
+			setup: noFlags(func([]string) int { return cmdDoctor() }),
+		},
+		{
+			name: "install", args: "<app>", summary: "Install an app",
+			modes: allModes, minArgs: 1, maxArgs: 1, remote: true,
+			setup: noFlags(func(args []string) int {
+				if isPVEMode() {
+					return cmdInstallPVE(args)
+				}
+				return cmdInstall(args)
+			}),
+		},
+		{
+			name: "uninstall", args: "<app>", summary: "Uninstall an app",
+			modes: allModes, minArgs: 1, maxArgs: 1, remote: true,
+			setup: noFlags(func(args []string) int {
+				if isPVEMode() {
+					return cmdUninstallPVE(args)
+				}
+				return cmdUninstall(args)
+			}),
+		},
+		{
+			name: "apps", args: "list|info|plan [app]", summary: "Query the catalog and install state",
+			help: "Subcommands:\n" +
+				"  list         List catalog apps and their install status\n" +
+				"  info <app>   Show an app's metadata and install state\n" +
+				"  plan <app>   Show what installing (or with --remove, removing) an app does",
+			modes: allModes, minArgs: 1, maxArgs: 2, json: true,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				test := fs.Bool("test", false, fmt.Sprintf("Query the test VM (%s, port %d)", testVMName, testAPIPort))
+				remove := fs.Bool("remove", false, "With plan, show what removing the app does")
+				return func(args []string) int { return cmdApps(args, *test, *remove) }
+			},
+		},
+		{
+			name: "test", args: "run [suite...]|matrix|start|stop", summary: "Run end-to-end scenarios against the test VM",
+			help: "Subcommands:\n" +
+				"  run [suite...]   Boot the test VM, run tests/scenarios/<suite>.yaml (default: all),\n" +
+				"                   print a summary and tear the VM down; exits 1 if a scenario fails\n" +
+				"  matrix           Install each app combination from --apps-file on a snapshot of\n" +
+				"                   the fresh test VM and report which ones reach running\n" +
+				"  start            Boot the test VM and its environment (ports 3001/5174/8081)\n" +
+				"  stop             Destroy the test VM",
+			modes: modeLima, minArgs: 1, maxArgs: -1, json: true,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				keep := fs.Bool("keep", false, "With run or matrix, leave the test VM running afterwards")
+				appsFile := fs.String("apps-file", "", "With matrix, the YAML `file` of app combinations (default "+testMatrixFile+")")
+				return func(args []string) int { return cmdTest(args, *keep, *appsFile) }
+			},
+		},
+		{
+			name: "forward", args: "<app|port> [local-port]", summary: "Forward a VM port (or an app's catalog port) to localhost",
+			modes: allModes, minArgs: 1, maxArgs: 2,
+			setup: noFlags(cmdForward),
+		},
+		{
+			name: "backup", args: "[archive]", summary: "Save the data dir and all databases to a local .tar.gz",
+			modes: allModes, maxArgs: 1,
+			setup: noFlags(cmdBackup),
+		},
+		{
+			name: "restore", args: "<archive>", summary: "Replay a backup (stop the host-agent first)",
+			modes: allModes, minArgs: 1, maxArgs: 1,
+			setup: noFlags(cmdRestore),
+		},
+		{
+			name: "snapshot", args: "create|restore|list|delete [name]", summary: "Checkpoint the dev VM, or roll it back to a checkpoint",
+			help: "Subcommands:\n" +
+				"  create [name]    Snapshot the dev VM (default name: snap-<timestamp>)\n" +
+				"  restore <name>   Roll the dev VM back to a snapshot\n" +
+				"  list             List snapshots\n" +
+				"  delete <name>    Delete a snapshot\n\n" +
+				"Lima snapshots a stopped VM, so a running VM is stopped and the dev\n" +
+				"environment started again afterwards.",
+			modes: modeLima, minArgs: 1, maxArgs: 2,
+			setup: noFlags(cmdSnapshot),
+		},
+		{
+			name: "depgraph", summary: "Generate the app dependency graph (Mermaid, DOT or JSON) from app metadata",
+			help:  "Render DOT with Graphviz: ./bloud depgraph --format dot | dot -Tsvg > deps.svg",
+			modes: devModes, json: true,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				format := fs.String("format", "mermaid", "Output `format`: "+strings.Join(depGraphFormats, ", "))
+				live := fs.Bool("live", false, "Color apps by install status from the running host-agent")
+				test := fs.Bool("test", false, "With --live, ask the test VM's host-agent")
+				return func([]string) int { return cmdDepGraph(*format, *live, *test) }
+			},
+		},
+		{
+			name: "installer", args: "[stop]", summary: "Start the installer UI in mock mode (http://localhost:5174)",
+			modes: devModes, maxArgs: 1,
+			setup: noFlags(func(args []string) int {
+				if len(args) == 0 {
+					return cmdInstaller()
+				}
+				if args[0] != "stop" {
+					errorf("Unknown installer command: %s (use stop)", args[0])
+					return exitUsage
+				}
+				return cmdInstallerStop()
+			}),
+		},
+		{
+			name: "destroy", summary: "Destroy the VM completely",
+			modes: allModes,
+			setup: noFlags(func([]string) int {
+				if isPVEMode() {
+					return cmdDestroyPVE()
+				}
+				return cmdDestroy()
+			}),
+		},
+		{
+			name: "checks", summary: "Run health checks against the running VM, in parallel",
+			modes: modePVE, json: true,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				timeout := fs.Duration("timeout", pveDefaultCheckTimeout, "Time limit for each attempt of a check")
+				return func([]string) int {
+					if *timeout <= 0 {
+						errorf("--timeout must be positive")
+						return exitUsage
+					}
+					return cmdChecksPVE(*timeout)
+				}
+			},
+		},
+		{
+			name: "setup-builder", summary: "Provision or update the ISO build VM (VMID " + pveDefaultBuildVMID + ")",
+			modes: modePVE,
+			setup: noFlags(func([]string) int { return cmdSetupBuilderPVE() }),
+		},
+		{
+			name: "destroy-builder", summary: "Destroy the ISO build VM",
+			modes: modePVE,
+			setup: noFlags(func([]string) int { return cmdDestroyBuilderPVE() }),
+		},
+		{
+			name: "deploy", args: "hetzner|do", summary: "Provision a cloud server and install Bloud on it",
+			help: "Creates a Debian server through the provider's API (token in HCLOUD_TOKEN or\n" +
+				"DIGITALOCEAN_TOKEN), kexecs it into the Bloud installer, installs to its disk\n" +
+				"and waits for the host-agent. The SSH key is authorized for root during the\n" +
+				"install and for the bloud user afterwards, whose password login is locked.",
+			modes: allModes, minArgs: 1, maxArgs: 1, noVM: true,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				var opts deployOptions
+				fs.StringVar(&opts.name, "name", "bloud", "Server name")
+				fs.StringVar(&opts.serverType, "type", "", "Server type or droplet size (default cx32 / s-4vcpu-8gb)")
+				fs.StringVar(&opts.region, "region", "", "Location or region (default nbg1 / fra1)")
+				fs.StringVar(&opts.sshKey, "ssh-key", "", "SSH public key `file` to authorize (default ~/.ssh/id_ed25519.pub)")
+				fs.StringVar(&opts.kexec, "kexec", "", "Installer kexec tarball, URL or `path` (default: latest release)")
+				fs.StringVar(&opts.secrets, "secrets", "", "secrets.json `file` to install instead of generating new secrets")
+				return func(args []string) int { return cmdDeploy(args[0], opts) }
+			},
+		},
+		{
+			name: "verify-catalog", summary: "Strictly validate apps/*/metadata.yaml (for CI)",
+			help: "Loads every app through the host-agent's catalog checks plus stricter ones:\n" +
+				"unknown fields, apps with a port but no health check, unknown SSO strategies,\n" +
+				"port collisions and dependencies or integrations naming missing apps. Prints\n" +
+				"file:line: message per problem and exits 1 if there are any. Needs Go.",
+			modes: allModes, noVM: true,
+			setup: noFlags(func([]string) int { return cmdVerifyCatalog() }),
+		},
+		{
+			name: "update", summary: "Show, check for or roll back system updates",
+			help: "Shows the automatic update policy, the last update and any newer system\n" +
+				"found, following an update that is under way until it settles. Updates are\n" +
+				"applied in the maintenance window; offline bundles are uploaded as in\n" +
+				"scripts/make-update-bundle.sh.",
+			// Talks to the host-agent API itself, locally or with --host, so
+			// it keeps its flags either way
+			modes: allModes, json: true, remote: true, noVM: true,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				check := fs.Bool("check", false, "Look for a newer system first")
+				rollback := fs.Bool("rollback", false, "Switch back to the system the last update replaced")
+				return func([]string) int { return cmdUpdate(*check, *rollback) }
+			},
+		},
+		{
+			name: "self-update", summary: "Replace this CLI with the latest release build",
+			help: "Downloads the build for this OS and architecture from the latest GitHub\n" +
+				"release, verifies it against the release's SHA-256 checksums and renames it\n" +
+				"over the running binary. The checksums must be signed by the Bloud release\n" +
+				"key built into this CLI.",
+			modes: allModes, noVM: true,
+			setup: func(fs *flag.FlagSet) func([]string) int {
+				check := fs.Bool("check", false, "Only report whether an update is available")
+				force := fs.Bool("force", false, "Replace a CLI built from source")
+				return func([]string) int { return cmdSelfUpdate(*check, *force) }
+			},
+		},
+		{
+			name: "config", args: "[list|get|set|unset|path] [key] [value]", summary: "Show or change ~/.config/bloud/config.yaml",
+			modes: allModes, maxArgs: 3, noVM: true, rawArgs: true,
+			setup: noFlags(cmdConfig),
+		},
+		{
+			name: "completion", args: "bash|zsh|fish", summary: "Print a shell completion script",
+			modes: allModes, minArgs: 1, maxArgs: 1, noVM: true,
+			setup: noFlags(cmdCompletion),
+		},
+		{
+			name: "help", args: "[command]", summary: "Show usage, or a command's flags",
+			modes: allModes, maxArgs: 1, noVM: true,
+			setup: noFlags(func(args []string) int {
+				if len(args) == 0 {
+					printUsage()
+					return exitOK
+				}
+				c := lookupCommand(args[0])
+				if c == nil {
+					errorf("Unknown command: %s", args[0])
+					return exitUsage
+				}
+				fs := newFlagSet(c)
+				c.setup(fs)
+				printCommandHelp(os.Stdout, c, fs)
+				return exitOK
+			}),
+		},
+		{
+			// Used by the completion scripts
+			name: "__apps", modes: allModes, noVM: true,
+			setup: noFlags(func([]string) int { return cmdCompleteApps() }),
+		},
+	}
+}
+
+// noFlags adapts a run function for a command without flags
+func noFlags(run func(args []string) int) func(*flag.FlagSet) func([]string) int {
+	return func(*flag.FlagSet) func([]string) int { return run }
+}
+
+// alias registers short as another name for the flag long
+func alias(fs *flag.FlagSet, short, long string) {
+	fs.Var(fs.Lookup(long).Value, short, "alias for --"+long)
+}
+
+// hidden reports whether a command is left out of usage and completion
+func (c *command) hidden() bool {
+	return strings.HasPrefix(c.name, "__")
+}
+
+// lookupCommand finds a command by name, preferring the variant for the
+// current mode
+func lookupCommand(name string) *command {
+	var found *command
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if c.modes&currentMode() != 0 {
+			return c
+		}
+		if found == nil {
+			found = c
+		}
+	}
+	return found
+}
+
+// modeError explains why a command does not run in the current mode
+func modeError(c *command) string {
+	switch {
+	case c.modes == modePVE:
+		return fmt.Sprintf("'%s' is only available in Proxmox mode (set BLOUD_PVE_HOST)", c.name)
+	case currentMode() == modePVE:
+		return fmt.Sprintf("'%s' is not available in Proxmox mode", c.name)
+	default:
+		return fmt.Sprintf("'%s' is not available on native NixOS", c.name)
+	}
+}
+
+func newFlagSet(c *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	return fs
+}
+
+// parseCommand parses a command's flags and checks its argument count. It
+// returns the function to run and its positional args, or a nil function and
+// the exit code when the command should not run (after --help, or on a usage
+// error).
+func parseCommand(c *command, args []string) (func([]string) int, []string, int) {
+	fs := newFlagSet(c)
+	run := c.setup(fs)
+
+	positional, err := parseFlags(fs, args, !c.rawArgs)
+	if errors.Is(err, flag.ErrHelp) {
+		printCommandHelp(os.Stdout, c, fs)
+		return nil, nil, exitOK
+	}
+	if err != nil {
+		errorf("%v (see ./bloud help %s)", err, c.name)
+		return nil, nil, exitUsage
+	}
+
+	if len(positional) < c.minArgs || (c.maxArgs >= 0 && len(positional) > c.maxArgs) {
+		errorf("Usage: ./bloud %s", synopsis(c, fs))
+		return nil, nil, exitUsage
+	}
+	return run, positional, exitOK
+}
+
+// parseFlags parses flags anywhere among args when interspersed is set, so
+// "./bloud logs app -f" works, and returns the positional args. "--" ends
+// the flags either way.
+func parseFlags(fs *flag.FlagSet, args []string, interspersed bool) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		terminated := len(rest) < len(args) && args[len(args)-len(rest)-1] == "--"
+		if !interspersed || terminated || len(rest) == 0 {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// synopsis is a command's one-line usage
+func synopsis(c *command, fs *flag.FlagSet) string {
+	parts := []string{c.name}
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		parts = append(parts, "[flags]")
+	}
+	if c.args != "" {
+		parts = append(parts, c.args)
+	}
+	return strings.Join(parts, " ")
+}
+
+// printCommandHelp prints a command's usage, summary and flags
+func printCommandHelp(w io.Writer, c *command, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: ./bloud %s\n\n%s\n", synopsis(c, fs), c.summary)
+	if c.help != "" {
+		fmt.Fprintf(w, "\n%s\n", c.help)
+	}
+	if c.modes != allModes {
+		fmt.Fprintf(w, "\nAvailable in: %s\n", modeNames(c.modes))
+	}
+
+	var lines []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Usage, "alias for --") {
+			return
+		}
+		name := "--" + f.Name
+		fs.VisitAll(func(a *flag.Flag) {
+			if a.Usage == "alias for --"+f.Name {
+				name = "-" + a.Name + ", " + name
+			}
+		})
+		arg, usage := flag.UnquoteUsage(f)
+		if arg != "" {
+			name += " <" + arg + ">"
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		lines = append(lines, fmt.Sprintf("  %-22s %s", name, usage))
+	})
+	if len(lines) > 0 {
+		fmt.Fprintf(w, "\nFlags:\n%s\n", strings.Join(lines, "\n"))
+	}
+	fmt.Fprintln(w, "\nGlobal flags (--json, --env, --host, --verbose, --quiet): see ./bloud help")
+}
+
+func modeNames(m mode) string {
+	var names []string
+	if m&modeLima != 0 {
+		names = append(names, "Lima")
+	}
+	if m&modeNative != 0 {
+		names = append(names, "native NixOS")
+	}
+	if m&modePVE != 0 {
+		names = append(names, "Proxmox")
+	}
+	return strings.Join(names, ", ")
+}
+
+// commandFlags lists the long flags of every variant of a command, for
+// shell completion
+func commandFlags(name string) []string {
+	seen := map[string]bool{}
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		fs := newFlagSet(c)
+		c.setup(fs)
+		fs.VisitAll(func(f *flag.Flag) {
+			if len(f.Name) > 1 {
+				seen["--"+f.Name] = true
+			}
+		})
+	}
+	flags := make([]string, 0, len(seen))
+	for f := range seen {
+		flags = append(flags, f)
+	}
+	sort.Strings(flags)
+	return flags
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// completionCommands are the top-level commands offered by shell
+// completion: every visible command in the registry, once
+func completionCommands() []*command {
+	var list []*command
+	seen := map[string]bool{}
+	for _, c := range commands {
+		if !c.hidden() && !seen[c.name] {
+			seen[c.name] = true
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// completionSubcommands are the subcommands of commands that take them
+var completionSubcommands = map[string][]string{
+	"apps":       {"list", "info", "plan"},
+	"installer":  {"stop"},
+	"completion": {"bash", "zsh", "fish"},
+	"config":     {"list", "get", "set", "unset", "path"},
+	"test":       {"run", "matrix", "start", "stop"},
+	"snapshot":   {"create", "restore", "list", "delete"},
+	"deploy":     {"hetzner", "do"},
+}
+
+// completionFlags are the flags of each command that has any
+func completionFlags() map[string][]string {
+	flags := map[string][]string{}
+	for _, c := range completionCommands() {
+		if f := commandFlags(c.name); len(f) > 0 {
+			flags[c.name] = f
+		}
+	}
+	return flags
+}
+
+// cmdCompletion handles "./bloud completion bash|zsh|fish"
+func cmdCompletion(args []string) int {
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print(zshCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	default:
+		errorf("Unsupported shell: %s (use bash, zsh or fish)", args[0])
+		return exitUsage
+	}
+	return 0
+}
+
+// cmdCompleteApps prints app names for shell completion, one per line: from
+// the running host-agent's catalog when it answers, otherwise from the
+// apps/*/metadata.yaml files in the checkout. Errors are silent, since the
+// output goes straight into the shell's completion list.
+func cmdCompleteApps() int {
+	var names []string
+
+	if api, err := resolveHostAgentAPI(false); err == nil {
+		if catalog, err := api.client.ListApps(context.Background(), nil); err == nil {
+			for _, app := range catalog.Apps {
+				names = append(names, app.Name)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		if root, err := getProjectRoot(); err == nil {
+			if apps, err := loadAppMetadata(filepath.Join(root, "apps")); err == nil {
+				for name, app := range apps {
+					if !app.IsSystem {
+						names = append(names, name)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return 0
+}
+
+func completionCommandNames() string {
+	var names []string
+	for _, c := range completionCommands() {
+		names = append(names, c.name)
+	}
+	return strings.Join(names, " ")
+}
+
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString(`# bash completion for ./bloud
+# Load with: source <(./bloud completion bash)
+_bloud() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local cmd="${COMP_WORDS[1]}"
+
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "` + completionCommandNames() + ` --json --host --env --verbose --quiet" -- "$cur"))
+        return
+    fi
+
+    if [[ "$prev" == "--app" ]]; then
+        COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" __apps 2>/dev/null)" -- "$cur"))
+        return
+    fi
+
+    if [[ "$cur" == -* ]]; then
+        case "$cmd" in
+`)
+	flags := completionFlags()
+	for _, cmd := range sortedKeys(flags) {
+		fmt.Fprintf(&b, "            %s) COMPREPLY=($(compgen -W \"%s --json\" -- \"$cur\")) ;;\n", cmd, strings.Join(flags[cmd], " "))
+	}
+	b.WriteString(`            *) COMPREPLY=($(compgen -W "--json" -- "$cur")) ;;
+        esac
+        return
+    fi
+
+    case "$cmd" in
+        install|uninstall|logs|forward)
+            [[ $COMP_CWORD -eq 2 ]] && COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" __apps 2>/dev/null)" -- "$cur"))
+            ;;
+        apps)
+            if [[ $COMP_CWORD -eq 2 ]]; then
+                COMPREPLY=($(compgen -W "` + strings.Join(completionSubcommands["apps"], " ") + `" -- "$cur"))
+            elif [[ $COMP_CWORD -eq 3 && "$prev" != "list" ]]; then
+                COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" __apps 2>/dev/null)" -- "$cur"))
+            fi
+            ;;
+`)
+	for _, cmd := range []string{"installer", "completion", "config", "test", "snapshot", "deploy"} {
+		fmt.Fprintf(&b, "        %s)\n            [[ $COMP_CWORD -eq 2 ]] && COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n            ;;\n", cmd, strings.Join(completionSubcommands[cmd], " "))
+	}
+	b.WriteString(`    esac
+}
+complete -o default -F _bloud bloud ./bloud
+`)
+	return b.String()
+}
+
+func zshCompletion() string {
+	var b strings.Builder
+	b.WriteString(`#compdef bloud
+# zsh completion for ./bloud
+# Load with: source <(./bloud completion zsh)
+_bloud() {
+    local -a commands
+    commands=(
+`)
+	for _, c := range completionCommands() {
+		fmt.Fprintf(&b, "        '%s:%s'\n", c.name, strings.NewReplacer("'", `'\''`, ":", `\:`).Replace(c.summary))
+	}
+	b.WriteString(`    )
+
+    if (( CURRENT == 2 )); then
+        _describe 'command' commands
+        return
+    fi
+
+    if [[ "$words[CURRENT-1]" == --app ]]; then
+        compadd -- ${(f)"$("$words[1]" __apps 2>/dev/null)"}
+        return
+    fi
+
+    if [[ "$PREFIX" == -* ]]; then
+        case "$words[2]" in
+`)
+	flags := completionFlags()
+	for _, cmd := range sortedKeys(flags) {
+		fmt.Fprintf(&b, "            %s) compadd -- %s --json ;;\n", cmd, strings.Join(flags[cmd], " "))
+	}
+	b.WriteString(`            *) compadd -- --json --host --env --verbose --quiet ;;
+        esac
+        return
+    fi
+
+    case "$words[2]" in
+        backup|restore)
+            _files
+            ;;
+        install|uninstall|logs|forward)
+            (( CURRENT == 3 )) && compadd -- ${(f)"$("$words[1]" __apps 2>/dev/null)"}
+            ;;
+        apps)
+            if (( CURRENT == 3 )); then
+                compadd -- ` + strings.Join(completionSubcommands["apps"], " ") + `
+            elif (( CURRENT == 4 )) && [[ "$words[3]" != "list" ]]; then
+                compadd -- ${(f)"$("$words[1]" __apps 2>/dev/null)"}
+            fi
+            ;;
+`)
+	for _, cmd := range []string{"installer", "completion", "config", "test", "snapshot", "deploy"} {
+		fmt.Fprintf(&b, "        %s)\n            (( CURRENT == 3 )) && compadd -- %s\n            ;;\n", cmd, strings.Join(completionSubcommands[cmd], " "))
+	}
+	b.WriteString(`    esac
+}
+compdef _bloud bloud ./bloud
+`)
+	return b.String()
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString(`# fish completion for ./bloud
+# Load with: ./bloud completion fish | source
+complete -c bloud -f
+complete -c bloud -l json -d 'Print JSON instead of text'
+complete -c bloud -l host -r -d 'Manage a Bloud appliance over its API'
+complete -c bloud -l env -r -d 'Use a named environment profile'
+complete -c bloud -l verbose -d 'Print every command run'
+complete -c bloud -l quiet -d 'Only print results, warnings and errors'
+`)
+	for _, c := range completionCommands() {
+		fmt.Fprintf(&b, "complete -c bloud -n '__fish_use_subcommand' -a %s -d '%s'\n", c.name, strings.ReplaceAll(c.summary, "'", `\'`))
+	}
+	b.WriteString(`complete -c bloud -n '__fish_seen_subcommand_from backup restore' -F
+complete -c bloud -n '__fish_seen_subcommand_from install uninstall logs forward' -a '(eval (commandline -opc)[1] __apps 2>/dev/null)'
+complete -c bloud -n '__fish_seen_subcommand_from apps; and not __fish_seen_subcommand_from list info plan' -a '` + strings.Join(completionSubcommands["apps"], " ") + `'
+complete -c bloud -n '__fish_seen_subcommand_from info plan' -a '(eval (commandline -opc)[1] __apps 2>/dev/null)'
+complete -c bloud -n '__fish_seen_subcommand_from shell' -l app -r -a '(eval (commandline -opc)[1] __apps 2>/dev/null)'
+`)
+	for _, cmd := range []string{"installer", "completion", "config", "test", "snapshot", "deploy"} {
+		fmt.Fprintf(&b, "complete -c bloud -n '__fish_seen_subcommand_from %s' -a '%s'\n", cmd, strings.Join(completionSubcommands[cmd], " "))
+	}
+	flags := completionFlags()
+	for _, cmd := range sortedKeys(flags) {
+		for _, flag := range flags[cmd] {
+			fmt.Fprintf(&b, "complete -c bloud -n '__fish_seen_subcommand_from %s' -l %s\n", cmd, strings.TrimPrefix(flag, "--"))
+		}
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
+	"gopkg.in/yaml.v3"
+)
+
+// envName is the active environment profile, set by the global --env flag
+// or BLOUD_ENV. Empty means the default dev VM.
+var envName string
+
+// cliConfig is the CLI's own configuration, read from config.yaml in the
+// user config directory (~/.config/bloud/config.yaml on Linux). Settings are
+// defaults: the environment and the project's .env take precedence.
+type cliConfig struct {
+	PVEHost      string                `yaml:"pveHost,omitempty"`
+	PVEVMID      string                `yaml:"pveVMID,omitempty"`
+	PVEBuildVMID string                `yaml:"pveBuildVMID,omitempty"`
+	VMBackend    string                `yaml:"vmBackend,omitempty"`
+	DefaultEnv   string                `yaml:"defaultEnv,omitempty"`
+	Color        string                `yaml:"color,omitempty"`
+	Tokens       map[string]string     `yaml:"tokens,omitempty"` // API tokens by --host value
+	Envs         map[string]envProfile `yaml:"envs,omitempty"`
+}
+
+// configSetting is a scalar config key and the environment variable it
+// stands in for
+type configSetting struct {
+	key, env, desc string
+	field          func(c *cliConfig) *string
+}
+
+var configSettings = []configSetting{
+	{"pveHost", "BLOUD_PVE_HOST", "Proxmox SSH target; switches to Proxmox mode", func(c *cliConfig) *string { return &c.PVEHost }},
+	{"pveVMID", "BLOUD_PVE_VMID", "Proxmox test VM ID (default " + pveDefaultVMID + ")", func(c *cliConfig) *string { return &c.PVEVMID }},
+	{"pveBuildVMID", "BLOUD_PVE_BUILD_VMID", "Proxmox ISO build VM ID (default " + pveDefaultBuildVMID + ")", func(c *cliConfig) *string { return &c.PVEBuildVMID }},
+	{"vmBackend", "BLOUD_VM_BACKEND", "lima or libvirt (Linux)", func(c *cliConfig) *string { return &c.VMBackend }},
+	{"defaultEnv", "BLOUD_ENV", "Profile used when --env is not given", func(c *cliConfig) *string { return &c.DefaultEnv }},
+	{"color", "BLOUD_COLOR", "auto, always or never", func(c *cliConfig) *string { return &c.Color }},
+}
+
+// userConfig is the loaded CLI config, empty until applyCLIConfig runs
+var userConfig = &cliConfig{}
+
+// envProfile describes a named environment, so several isolated stacks can
+// run side by side. Unset fields fall back to the defaults, except VM, which
+// defaults to bloud-<name> so profiles never share the dev VM by accident.
+type envProfile struct {
+	VM         string `yaml:"vm,omitempty"`         // Lima VM name
+	LimaConfig string `yaml:"limaConfig,omitempty"` // Lima config, relative to the project root
+	PortOffset int    `yaml:"portOffset,omitempty"` // added to every forwarded host port
+	PVEHost    string `yaml:"pveHost,omitempty"`    // switches the profile to Proxmox mode
+	PVEVMID    string `yaml:"pveVMID,omitempty"`
+}
+
+// cliConfigPath returns where the CLI config file lives
+func cliConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bloud", "config.yaml"), nil
+}
+
+// loadCLIConfig reads the CLI config file. A missing file is an empty config.
+func loadCLIConfig() (*cliConfig, error) {
+	cfg := &cliConfig{}
+	path, err := cliConfigPath()
+	if err != nil {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// applyEnvProfile switches the CLI to the named profile: its VM name,
+// Lima config, forwarded ports and Proxmox target
+func applyEnvProfile(name string) error {
+	cfg := userConfig
+	profile, ok := cfg.Envs[name]
+	if !ok {
+		path, _ := cliConfigPath()
+		return fmt.Errorf("unknown environment %q (profiles: %s, defined under envs: in %s)", name, envNames(cfg), path)
+	}
+
+	if profile.PVEHost != "" {
+		os.Setenv("BLOUD_PVE_HOST", profile.PVEHost)
+		if profile.PVEVMID != "" {
+			os.Setenv("BLOUD_PVE_VMID", profile.PVEVMID)
+		}
+		return nil
+	}
+
+	// A Lima profile wins over a Proxmox host set in .env
+	os.Unsetenv("BLOUD_PVE_HOST")
+	devVMName = profile.VM
+	if devVMName == "" {
+		devVMName = "bloud-" + name
+	}
+	if profile.LimaConfig != "" {
+		devLimaConfig = profile.LimaConfig
+	}
+	for i := range devPorts {
+		devPorts[i].LocalPort += profile.PortOffset
+	}
+	return nil
+}
+
+// envNames lists the configured profiles for error messages
+func envNames(cfg *cliConfig) string {
+	if len(cfg.Envs) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(cfg.Envs))
+	for name := range cfg.Envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// applyCLIConfig loads the CLI config and exports its settings as the
+// BLOUD_* variables they replace, unless already set
+func applyCLIConfig() error {
+	cfg, err := loadCLIConfig()
+	if err != nil {
+		return err
+	}
+	userConfig = cfg
+
+	for _, setting := range configSettings {
+		if value := *setting.field(cfg); value != "" && os.Getenv(setting.env) == "" {
+			os.Setenv(setting.env, value)
+		}
+	}
+	if vmid := os.Getenv("BLOUD_PVE_BUILD_VMID"); vmid != "" {
+		pveBuildVMID = vmid
+	}
+	return nil
+}
+
+// saveCLIConfig writes the config file, readable only by the user since it
+// can hold API tokens
+func saveCLIConfig(cfg *cliConfig) error {
+	path, err := cliConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// configField resolves a key to its value in cfg: a setting or tokens.<host>
+func configField(cfg *cliConfig, key string) (*string, error) {
+	if host, ok := strings.CutPrefix(key, "tokens."); ok && host != "" {
+		value := cfg.Tokens[host]
+		return &value, nil
+	}
+	for _, setting := range configSettings {
+		if setting.key == key {
+			return setting.field(cfg), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown config key %q (see ./bloud config list)", key)
+}
+
+// cmdConfig handles "./bloud config [list|get|set|unset|path]"
+func cmdConfig(args []string) int {
+	sub := "list"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	// Re-read the file so an invalid one is reported rather than overwritten
+	cfg, err := loadCLIConfig()
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	switch sub {
+	case "list":
+		return configList(cfg)
+	case "path":
+		path, err := cliConfigPath()
+		if err != nil {
+			errorf("%v", err)
+			return 1
+		}
+		fmt.Println(path)
+		return 0
+	case "get":
+		if len(args) != 2 {
+			errorf("Usage: ./bloud config get <key>")
+			return exitUsage
+		}
+		value, err := configField(cfg, args[1])
+		if err != nil {
+			errorf("%v", err)
+			return 1
+		}
+		if *value != "" {
+			fmt.Println(*value)
+		}
+		return 0
+	case "set", "unset":
+		if (sub == "set" && len(args) != 3) || (sub == "unset" && len(args) != 2) {
+			errorf("Usage: ./bloud config set <key> <value> | unset <key>")
+			return exitUsage
+		}
+		value := ""
+		if sub == "set" {
+			value = args[2]
+		}
+		if err := setConfigValue(cfg, args[1], value); err != nil {
+			errorf("%v", err)
+			return 1
+		}
+		if err := saveCLIConfig(cfg); err != nil {
+			errorf("%v", err)
+			return 1
+		}
+		return 0
+	default:
+		errorf("Unknown config command: %s (use list, get, set, unset or path)", sub)
+		return exitUsage
+	}
+}
+
+// setConfigValue validates and stores a value; empty removes it
+func setConfigValue(cfg *cliConfig, key, value string) error {
+	if host, ok := strings.CutPrefix(key, "tokens."); ok && host != "" {
+		if value == "" {
+			delete(cfg.Tokens, host)
+			return nil
+		}
+		if cfg.Tokens == nil {
+			cfg.Tokens = map[string]string{}
+		}
+		cfg.Tokens[host] = value
+		return nil
+	}
+
+	field, err := configField(cfg, key)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "color":
+		if value != "" && value != "auto" && value != "always" && value != "never" {
+			return fmt.Errorf("color must be auto, always or never")
+		}
+	case "vmBackend":
+		if value != "" && value != string(vm.BackendLima) && value != string(vm.BackendLibvirt) {
+			return fmt.Errorf("vmBackend must be lima or libvirt")
+		}
+	case "defaultEnv":
+		if _, ok := cfg.Envs[value]; value != "" && !ok {
+			return fmt.Errorf("unknown environment %q (profiles: %s)", value, envNames(cfg))
+		}
+	}
+	*field = value
+	return nil
+}
+
+func configList(cfg *cliConfig) int {
+	path, _ := cliConfigPath()
+	fmt.Printf("%s# %s%s\n", colorCyan, path, colorReset)
+	for _, setting := range configSettings {
+		value := *setting.field(cfg)
+		if value == "" {
+			value = "-"
+		}
+		fmt.Printf("%-14s %-28s %s (%s)\n", setting.key, value, setting.desc, setting.env)
+	}
+	hosts := make([]string, 0, len(cfg.Tokens))
+	for host := range cfg.Tokens {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		fmt.Printf("%-14s %s\n", "tokens."+host, maskToken(cfg.Tokens[host]))
+	}
+	if len(cfg.Envs) > 0 {
+		fmt.Printf("%-14s %s\n", "envs", envNames(cfg))
+	}
+	return 0
+}
+
+// maskToken shows enough of a token to tell tokens apart
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return "********"
+	}
+	return token[:4] + "…" + token[len(token)-4:]
+}
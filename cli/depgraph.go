@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -16,6 +18,7 @@ type AppMetadata struct {
 	DisplayName  string                 `yaml:"displayName"`
 	Category     string                 `yaml:"category"`
 	IsSystem     bool                   `yaml:"isSystem"`
+	Port         int                    `yaml:"port"`
 	Integrations map[string]Integration `yaml:"integrations"`
 	SSO          SSOConfig              `yaml:"sso"`
 }
@@ -37,7 +40,21 @@ type CompatibleApp struct {
 	Default bool   `yaml:"default"`
 }
 
-func cmdDepGraph() int {
+// depGraphFormats are the outputs ./bloud depgraph --format accepts
+var depGraphFormats = []string{"mermaid", "dot", "json"}
+
+// cmdDepGraph prints the app dependency graph as Mermaid, Graphviz DOT or
+// JSON. With live set, nodes are colored by their install status on the
+// running host-agent (the test VM's with test set).
+func cmdDepGraph(format string, live, test bool) int {
+	if jsonOutput {
+		format = "json"
+	}
+	if !slices.Contains(depGraphFormats, format) {
+		errorf("Unknown format: %s (use %s)", format, strings.Join(depGraphFormats, ", "))
+		return exitUsage
+	}
+
 	root, err := getProjectRoot()
 	if err != nil {
 		errorf("Could not find project root: %v", err)
@@ -51,11 +68,61 @@ func cmdDepGraph() int {
 		return 1
 	}
 
-	mermaid := generateMermaid(apps)
-	fmt.Println(mermaid)
+	graph := buildDepGraph(apps)
+	if live {
+		status, err := fetchInstallStatus(test)
+		if err != nil {
+			errorf("%v", err)
+			return 1
+		}
+		graph.Status = status
+	}
+
+	switch format {
+	case "json":
+		return printJSON(graph)
+	case "dot":
+		fmt.Print(generateDOT(graph))
+	default:
+		fmt.Println(generateMermaid(graph))
+	}
 	return 0
 }
 
+// fetchInstallStatus asks the running host-agent for each installed app's
+// status. The host-agent itself counts as running once it answers.
+func fetchInstallStatus(test bool) (map[string]string, error) {
+	api, err := resolveHostAgentAPI(test)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := api.client.ListInstalledApps(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get installed apps: %w", err)
+	}
+
+	status := map[string]string{"host-agent": "running"}
+	for _, app := range installed {
+		status[app.Name] = app.Status
+	}
+	return status, nil
+}
+
+// depGraphColor groups an install status into the fill colors the graphs
+// use; empty means not installed
+func depGraphColor(status string) string {
+	switch status {
+	case "":
+		return ""
+	case "running":
+		return "#b7e4c7"
+	case "error", "failed":
+		return "#f4a6a6"
+	default: // installing, starting, stopping...
+		return "#ffe08a"
+	}
+}
+
 func loadAppMetadata(appsDir string) (map[string]*AppMetadata, error) {
 	apps := make(map[string]*AppMetadata)
 
@@ -94,34 +161,54 @@ func loadAppMetadata(appsDir string) (map[string]*AppMetadata, error) {
 	return apps, nil
 }
 
-func generateMermaid(apps map[string]*AppMetadata) string {
-	var sb strings.Builder
+// depGraph is the dependency graph drawn by ./bloud depgraph
+type depGraph struct {
+	Apps       []string  `json:"apps"`
+	SystemApps []string  `json:"systemApps"`
+	Edges      []depEdge `json:"edges"`
 
-	sb.WriteString("```mermaid\n")
-	sb.WriteString("flowchart TD\n")
+	// Status maps installed apps to their install status; set with --live
+	Status map[string]string `json:"status,omitempty"`
+}
+
+// depEdge is one dependency: From uses To for the integration Label
+// (or the implicit "sso", "routing" and host-agent "database" edges)
+type depEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Label    string `json:"label"`
+	Required bool   `json:"required"`
+}
+
+func buildDepGraph(apps map[string]*AppMetadata) depGraph {
+	graph := depGraph{Apps: []string{}, SystemApps: []string{"host-agent"}}
 
 	// Collect all apps and sort for consistent output
 	var appNames []string
 	var systemApps []string
-	var userApps []string
 	for name := range apps {
 		appNames = append(appNames, name)
 		if apps[name].IsSystem {
 			systemApps = append(systemApps, name)
 		} else {
-			userApps = append(userApps, name)
+			graph.Apps = append(graph.Apps, name)
 		}
 	}
 	sort.Strings(appNames)
 	sort.Strings(systemApps)
-	sort.Strings(userApps)
+	sort.Strings(graph.Apps)
+	graph.SystemApps = append(graph.SystemApps, systemApps...)
 
 	// Track which edges we've added to avoid duplicates
 	edges := make(map[string]bool)
-	// Track apps that appear in edges
-	appsInEdges := make(map[string]bool)
-	// Collect edges to write after subgraph
-	var edgeLines []string
+	addEdge := func(edge depEdge) {
+		edgeKey := fmt.Sprintf("%s->%s", edge.From, edge.To)
+		if edges[edgeKey] {
+			return
+		}
+		edges[edgeKey] = true
+		graph.Edges = append(graph.Edges, edge)
+	}
 
 	// Generate edges for each app's integrations
 	for _, appName := range appNames {
@@ -138,19 +225,7 @@ func generateMermaid(apps map[string]*AppMetadata) string {
 			integration := app.Integrations[intName]
 			for _, compat := range integration.Compatible {
 				// Edge: app depends on compatible app
-				edgeKey := fmt.Sprintf("%s->%s", appName, compat.App)
-				if edges[edgeKey] {
-					continue
-				}
-				edges[edgeKey] = true
-				appsInEdges[appName] = true
-				appsInEdges[compat.App] = true
-
-				label := intName
-				if integration.Required {
-					label += "*"
-				}
-				edgeLines = append(edgeLines, fmt.Sprintf("    %s -->|%s| %s", appName, label, compat.App))
+				addEdge(depEdge{From: appName, To: compat.App, Label: intName, Required: integration.Required})
 			}
 		}
 	}
@@ -159,13 +234,7 @@ func generateMermaid(apps map[string]*AppMetadata) string {
 	for _, appName := range appNames {
 		app := apps[appName]
 		if app.SSO.Strategy == "forward-auth" || app.SSO.Strategy == "native-oidc" {
-			edgeKey := fmt.Sprintf("%s->authentik", appName)
-			if !edges[edgeKey] {
-				edges[edgeKey] = true
-				appsInEdges[appName] = true
-				appsInEdges["authentik"] = true
-				edgeLines = append(edgeLines, fmt.Sprintf("    %s -->|sso| authentik", appName))
-			}
+			addEdge(depEdge{From: appName, To: "authentik", Label: "sso"})
 		}
 	}
 
@@ -175,19 +244,25 @@ func generateMermaid(apps map[string]*AppMetadata) string {
 		if appName == "traefik" || app.Category == "infrastructure" {
 			continue
 		}
-		edgeKey := fmt.Sprintf("%s->traefik", appName)
-		if !edges[edgeKey] {
-			edges[edgeKey] = true
-			appsInEdges[appName] = true
-			appsInEdges["traefik"] = true
-			edgeLines = append(edgeLines, fmt.Sprintf("    %s -->|routing| traefik", appName))
-		}
+		addEdge(depEdge{From: appName, To: "traefik", Label: "routing"})
 	}
 
+	// Add host-agent's database dependency
+	graph.Edges = append(graph.Edges, depEdge{From: "host-agent", To: "postgres", Label: "database", Required: true})
+
+	return graph
+}
+
+func generateMermaid(graph depGraph) string {
+	var sb strings.Builder
+
+	sb.WriteString("```mermaid\n")
+	sb.WriteString("flowchart TD\n")
+
 	// Write user apps subgraph (above system)
-	if len(userApps) > 0 {
+	if len(graph.Apps) > 0 {
 		sb.WriteString("    subgraph Apps\n")
-		for _, appName := range userApps {
+		for _, appName := range graph.Apps {
 			sb.WriteString(fmt.Sprintf("        %s\n", appName))
 		}
 		sb.WriteString("    end\n")
@@ -195,22 +270,70 @@ func generateMermaid(apps map[string]*AppMetadata) string {
 
 	// Write system apps subgraph (includes host-agent)
 	sb.WriteString("    subgraph System\n")
-	sb.WriteString("        host-agent\n")
-	for _, appName := range systemApps {
+	for _, appName := range graph.SystemApps {
 		sb.WriteString(fmt.Sprintf("        %s\n", appName))
 	}
 	sb.WriteString("    end\n")
 
-	// Add host-agent's database dependency
-	edgeLines = append(edgeLines, "    host-agent -->|database*| postgres")
-
 	// Write all edges
-	for _, edge := range edgeLines {
-		sb.WriteString(edge + "\n")
+	for _, edge := range graph.Edges {
+		label := edge.Label
+		if edge.Required {
+			label += "*"
+		}
+		sb.WriteString(fmt.Sprintf("    %s -->|%s| %s\n", edge.From, label, edge.To))
+	}
+
+	// Color installed apps by status
+	for _, appName := range append(slices.Clone(graph.Apps), graph.SystemApps...) {
+		if color := depGraphColor(graph.Status[appName]); color != "" {
+			sb.WriteString(fmt.Sprintf("    style %s fill:%s\n", appName, color))
+		}
 	}
 
 	sb.WriteString("```\n")
 	sb.WriteString("\n_* = required integration_\n")
+	if graph.Status != nil {
+		sb.WriteString("_green = running, yellow = in progress, red = error, uncolored = not installed_\n")
+	}
+
+	return sb.String()
+}
+
+// generateDOT renders the graph for Graphviz: ./bloud depgraph --format dot | dot -Tsvg
+func generateDOT(graph depGraph) string {
+	var sb strings.Builder
+
+	sb.WriteString("digraph bloud {\n")
+	sb.WriteString("    rankdir=TB;\n")
+	sb.WriteString("    node [shape=box, style=\"rounded,filled\", fillcolor=white, fontname=Helvetica];\n")
+	sb.WriteString("    edge [fontname=Helvetica, fontsize=10];\n")
+
+	writeCluster := func(name, label string, apps []string) {
+		sb.WriteString(fmt.Sprintf("    subgraph cluster_%s {\n        label=%q;\n", name, label))
+		for _, appName := range apps {
+			attrs := ""
+			if color := depGraphColor(graph.Status[appName]); color != "" {
+				attrs = fmt.Sprintf(" [fillcolor=%q, tooltip=%q]", color, graph.Status[appName])
+			}
+			sb.WriteString(fmt.Sprintf("        %q%s;\n", appName, attrs))
+		}
+		sb.WriteString("    }\n")
+	}
+	if len(graph.Apps) > 0 {
+		writeCluster("apps", "Apps", graph.Apps)
+	}
+	writeCluster("system", "System", graph.SystemApps)
+
+	// Required integrations are solid, optional ones dashed
+	for _, edge := range graph.Edges {
+		style := "dashed"
+		if edge.Required {
+			style = "solid"
+		}
+		sb.WriteString(fmt.Sprintf("    %q -> %q [label=%q, style=%s];\n", edge.From, edge.To, edge.Label, style))
+	}
 
+	sb.WriteString("}\n")
 	return sb.String()
 }
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
+)
+
+const (
+	cloudKexecAsset     = "bloud-kexec.tar.gz"
+	cloudKexecDir       = "/root/bloud-kexec"
+	cloudBootTimeout    = 5 * time.Minute  // server created until root SSH works
+	cloudKexecTimeout   = 5 * time.Minute  // kexec until the installer answers
+	cloudServiceTimeout = 15 * time.Minute // reboot until the host-agent answers
+	cloudPollInterval   = 5 * time.Second
+)
+
+// deployOptions are the flags of ./bloud deploy
+type deployOptions struct {
+	name       string
+	serverType string
+	region     string
+	sshKey     string // public key file
+	kexec      string // installer kexec tarball, URL or local path
+	secrets    string // secrets.json to install instead of generating one
+}
+
+// cloudSSH runs commands as root on a server being deployed, with key auth
+// only. Host keys change when the server kexecs, so they aren't recorded.
+type cloudSSH struct {
+	ip       string
+	identity string // private key, empty for the SSH agent and defaults
+}
+
+func (c cloudSSH) args(cmd string) []string {
+	args := []string{
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "BatchMode=yes",
+		"-o", "ConnectTimeout=5",
+		"-o", "LogLevel=ERROR",
+	}
+	if c.identity != "" {
+		args = append(args, "-i", c.identity)
+	}
+	return append(args, "root@"+c.ip, cmd)
+}
+
+func (c cloudSSH) exec(cmd string) (string, error) {
+	output, err := vm.Command("ssh", c.args(cmd)...).CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+func (c cloudSSH) stream(cmd string) error {
+	ssh := vm.Command("ssh", c.args(cmd)...)
+	ssh.Stdout = os.Stdout
+	ssh.Stderr = os.Stderr
+	return ssh.Run()
+}
+
+func (c cloudSSH) pipe(cmd string, stdin io.Reader) error {
+	ssh := vm.Command("ssh", c.args(cmd)...)
+	ssh.Stdin = stdin
+	ssh.Stdout = os.Stdout
+	ssh.Stderr = os.Stderr
+	return ssh.Run()
+}
+
+// waitFor polls check over SSH until it succeeds, or gives up after timeout
+func (c cloudSSH) waitFor(check string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, err := c.exec(check); err == nil {
+			return true
+		}
+		time.Sleep(cloudPollInterval)
+	}
+	return false
+}
+
+// cmdDeploy handles "./bloud deploy hetzner|do": it creates a Debian server
+// through the provider's API, kexecs it into the Bloud installer, installs
+// to its disk with the same installer API the ISO uses, authorizes the
+// deploy key for the bloud user, optionally installs existing secrets, and
+// waits for the host-agent to come up after the reboot.
+func cmdDeploy(providerName string, opts deployOptions) int {
+	if _, ok := cloudProviders[providerName]; !ok {
+		errorf("Unknown provider: %s (use %s)", providerName, strings.Join(cloudProviderNames(), " or "))
+		return exitUsage
+	}
+	provider, info, err := newCloudProvider(providerName)
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+	if opts.serverType == "" {
+		opts.serverType = info.serverType
+	}
+	if opts.region == "" {
+		opts.region = info.region
+	}
+
+	keyPath, publicKey, err := readDeployKey(opts.sshKey)
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+	if opts.secrets != "" {
+		if _, err := os.Stat(opts.secrets); err != nil {
+			errorf("Secrets file: %v", err)
+			return 1
+		}
+	}
+	if opts.kexec == "" {
+		if opts.kexec, err = latestKexecURL(); err != nil {
+			errorf("%v", err)
+			return 1
+		}
+	}
+
+	log(fmt.Sprintf("Registering SSH key %s with %s...", keyPath, info.title))
+	keyID, err := provider.ensureSSHKey("bloud-deploy", publicKey)
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	log(fmt.Sprintf("Creating %s server %s (%s in %s)...", info.title, opts.name, opts.serverType, opts.region))
+	serverID, err := provider.createServer(cloudServer{name: opts.name, serverType: opts.serverType, region: opts.region}, keyID)
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	// From here on a failure leaves a billed server behind, so say which
+	failed := func(format string, args ...any) int {
+		errorf(format, args...)
+		fmt.Fprintf(os.Stderr, "\nServer %d was left running for debugging; delete it in the %s console when done.\n", serverID, info.title)
+		return 1
+	}
+
+	ip, err := waitForCloudServer(provider, serverID)
+	if err != nil {
+		return failed("%v", err)
+	}
+	ssh := cloudSSH{ip: ip, identity: strings.TrimSuffix(keyPath, ".pub")}
+	if _, err := os.Stat(ssh.identity); err != nil {
+		ssh.identity = ""
+	}
+
+	log(fmt.Sprintf("Waiting for SSH on %s...", ip))
+	if !ssh.waitFor("true", cloudBootTimeout) {
+		return failed("SSH to root@%s not available after %s", ip, cloudBootTimeout)
+	}
+
+	if err := bootCloudInstaller(ssh, opts.kexec, publicKey); err != nil {
+		return failed("%v", err)
+	}
+
+	if code := driveInstaller(ssh.exec, ssh.stream); code != 0 {
+		return failed("Installation failed")
+	}
+
+	log("Authorizing SSH key for the bloud user...")
+	if err := prepareCloudInstall(ssh, opts.secrets); err != nil {
+		return failed("%v", err)
+	}
+
+	log("Rebooting into Bloud...")
+	_, _ = ssh.exec(`curl -sf -X POST -H "X-Setup-Token: $(cat ` + pveInstallerToken + `)" ` + pveInstallerAPI + "/reboot")
+
+	log(fmt.Sprintf("Waiting for the host-agent (timeout: %s)...", cloudServiceTimeout))
+	if !waitForCloudHostAgent(ip, cloudServiceTimeout) {
+		return failed("host-agent at http://%s:3000 did not come up within %s (check: ssh bloud@%s journalctl --user -u bloud-host-agent)", ip, cloudServiceTimeout, ip)
+	}
+
+	fmt.Println()
+	fmt.Printf("%s✓ Bloud is running on %s%s (server %d)\n", colorGreen, info.title, colorReset, serverID)
+	fmt.Println()
+	fmt.Printf("  Web UI:  http://%s\n", ip)
+	fmt.Printf("  API:     http://%s:3000/api/v1\n", ip)
+	fmt.Printf("  SSH:     ssh bloud@%s\n", ip)
+	fmt.Println()
+	fmt.Println("  Password login for bloud is locked; set one with: sudo passwd bloud")
+	fmt.Println("  Finish setup in the web UI before sharing the address.")
+	fmt.Println()
+	return 0
+}
+
+// readDeployKey reads the public key to authorize, defaulting to the first
+// of the usual keys in ~/.ssh
+func readDeployKey(path string) (string, string, error) {
+	candidates := []string{path}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		candidates = nil
+		for _, name := range []string{"id_ed25519.pub", "id_ecdsa.pub", "id_rsa.pub"} {
+			candidates = append(candidates, filepath.Join(home, ".ssh", name))
+		}
+	}
+
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		key := strings.TrimSpace(string(data))
+		if len(strings.Fields(key)) < 2 {
+			return "", "", fmt.Errorf("%s is not an SSH public key", candidate)
+		}
+		return candidate, key, nil
+	}
+	if path != "" {
+		return "", "", fmt.Errorf("cannot read SSH key %s", path)
+	}
+	return "", "", fmt.Errorf("no SSH public key in ~/.ssh (create one with ssh-keygen -t ed25519, or pass --ssh-key)")
+}
+
+// latestKexecURL finds the installer kexec tarball in the latest release
+func latestKexecURL() (string, error) {
+	log("Finding latest GitHub release...")
+	out, err := vm.Command("gh", "release", "view", "latest", "--json", "assets",
+		"--jq", fmt.Sprintf(`.assets[] | select(.name == "%s") | .url`, cloudKexecAsset),
+	).Output()
+	url := strings.TrimSpace(string(out))
+	if err != nil || url == "" {
+		return "", fmt.Errorf("no --kexec given and no %s in the latest GitHub release (build one with: nix build .#packages.x86_64-linux.kexec)", cloudKexecAsset)
+	}
+	return url, nil
+}
+
+// waitForCloudServer polls the provider until the server is running with
+// a public address
+func waitForCloudServer(provider cloudProvider, id int64) (string, error) {
+	log("Waiting for the server to start...")
+	deadline := time.Now().Add(cloudBootTimeout)
+	for time.Now().Before(deadline) {
+		ip, running, err := provider.serverAddress(id)
+		if err != nil {
+			return "", fmt.Errorf("failed to get server %d: %w", id, err)
+		}
+		if running && ip != "" {
+			log(fmt.Sprintf("Server is up at %s", ip))
+			return ip, nil
+		}
+		time.Sleep(cloudPollInterval)
+	}
+	return "", fmt.Errorf("server %d not running after %s", id, cloudBootTimeout)
+}
+
+// bootCloudInstaller copies the installer kexec tarball to the server and
+// kexecs into it, passing the deploy key on the kernel command line since
+// the installer only accepts that key, then waits for the installer API
+func bootCloudInstaller(ssh cloudSSH, source, publicKey string) error {
+	log("Installing kexec-tools...")
+	if err := ssh.stream("DEBIAN_FRONTEND=noninteractive apt-get -qq update && DEBIAN_FRONTEND=noninteractive apt-get -qq install -y kexec-tools curl >/dev/null"); err != nil {
+		return fmt.Errorf("failed to install kexec-tools: %w", err)
+	}
+
+	unpack := fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s && tar -xz -C %[1]s", cloudKexecDir)
+	if strings.HasPrefix(source, "http") {
+		log(fmt.Sprintf("Downloading installer on the server: %s", source))
+		if err := ssh.stream(fmt.Sprintf("curl -fsSL '%s' | (%s)", source, unpack)); err != nil {
+			return fmt.Errorf("failed to download installer: %w", err)
+		}
+	} else {
+		log(fmt.Sprintf("Uploading installer %s...", source))
+		f, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := ssh.pipe(unpack, f); err != nil {
+			return fmt.Errorf("failed to upload installer: %w", err)
+		}
+	}
+
+	log("Booting the installer with kexec...")
+	key := base64.StdEncoding.EncodeToString([]byte(publicKey))
+	boot := fmt.Sprintf(
+		`sed -i 's|--command-line "|--command-line "bloud.authorized_key=%s |' %s/kexec-boot && `+
+			`nohup setsid %s/kexec-boot >/root/kexec.log 2>&1 &`,
+		key, cloudKexecDir, cloudKexecDir,
+	)
+	if _, err := ssh.exec(boot); err != nil {
+		return fmt.Errorf("failed to kexec: %w", err)
+	}
+
+	// Wait for Debian's SSH to go away before waiting for the installer's
+	for i := 0; i < 60; i++ {
+		if _, err := ssh.exec("true"); err != nil {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+
+	log(fmt.Sprintf("Waiting for the installer (timeout: %s)...", cloudKexecTimeout))
+	if !ssh.waitFor("curl -sf "+pveInstallerAPI+"/health", cloudKexecTimeout) {
+		return fmt.Errorf("installer not reachable after %s (see /root/kexec.log if Debian is still running)", cloudKexecTimeout)
+	}
+	return nil
+}
+
+// prepareCloudInstall finishes the installed system before its first boot:
+// the deploy key is authorized for bloud, whose default password is locked
+// since the server is on a public address, and the given secrets replace
+// the ones init-secrets would generate
+func prepareCloudInstall(ssh cloudSSH, secrets string) error {
+	const home = "/mnt/home/bloud"
+	setup := strings.Join([]string{
+		fmt.Sprintf("mkdir -p %[1]s/.ssh %[1]s/.local/share/bloud", home),
+		fmt.Sprintf("cp /root/.ssh/authorized_keys %s/.ssh/authorized_keys", home),
+		fmt.Sprintf("chmod 700 %[1]s/.ssh && chmod 600 %[1]s/.ssh/authorized_keys", home),
+	}, " && ")
+	if _, err := ssh.exec(setup); err != nil {
+		return fmt.Errorf("failed to authorize SSH key: %w", err)
+	}
+
+	if secrets != "" {
+		log(fmt.Sprintf("Installing secrets from %s...", secrets))
+		f, err := os.Open(secrets)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		path := home + "/.local/share/bloud/secrets.json"
+		if err := ssh.pipe(fmt.Sprintf("cat > %[1]s && chmod 600 %[1]s", path), f); err != nil {
+			return fmt.Errorf("failed to install secrets: %w", err)
+		}
+	}
+
+	finish := "nixos-enter --root /mnt -c 'chown -R bloud:users /home/bloud/.ssh /home/bloud/.local && passwd -l bloud'"
+	if out, err := ssh.exec(finish); err != nil {
+		return fmt.Errorf("failed to set up the bloud user: %v\n%s", err, out)
+	}
+	return nil
+}
+
+// waitForCloudHostAgent polls the host-agent's health endpoint over the
+// public address until it answers
+func waitForCloudHostAgent(ip string, timeout time.Duration) bool {
+	client := &http.Client{Timeout: cloudPollInterval}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(fmt.Sprintf("http://%s:3000/api/health", ip))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return true
+			}
+		}
+		time.Sleep(cloudPollInterval)
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -14,15 +15,23 @@ import (
 
 // localExec runs a command on the host machine (not in VM)
 func localExec(name string, args ...string) *exec.Cmd {
-	return exec.Command(name, args...)
+	return vm.Command(name, args...)
 }
 
 const (
-	devVMName      = "bloud"
 	devTmuxSession = "bloud-dev"
 	devProjectInVM = "/home/bloud.linux/bloud"
 )
 
+// appNamePattern matches catalog app names, which are also podman unit names
+var appNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// The dev VM and its Lima config; an --env profile can override both
+var (
+	devVMName     = "bloud"
+	devLimaConfig = filepath.Join("lima", "nixos.yaml")
+)
+
 var devPorts = []vm.PortForward{
 	{LocalPort: 3000, RemotePort: 3000},
 	{LocalPort: 5173, RemotePort: 5173},
@@ -69,7 +78,7 @@ func getDevConfigPath() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(root, "lima", "nixos.yaml"), nil
+	return filepath.Join(root, devLimaConfig), nil
 }
 
 func cmdStart() int {
@@ -89,7 +98,7 @@ func cmdStartLima() int {
 		return 1
 	}
 
-	configPath := filepath.Join(projectRoot, "lima", "nixos.yaml")
+	configPath := filepath.Join(projectRoot, devLimaConfig)
 
 	// Run pre-flight checks before attempting to start
 	preflightResult := vm.RunPreflightChecks(projectRoot)
@@ -163,33 +172,105 @@ func cmdStop() int {
 	return 0
 }
 
-func cmdStatus() int {
+// devStatus is what ./bloud status reports for the Lima VM or native host
+type devStatus struct {
+	Backend     string            `json:"backend"`
+	VM          string            `json:"vm,omitempty"` // running, stopped or not_created; Lima only
+	Session     bool              `json:"session"`
+	HostAgent   bool              `json:"hostAgent"`
+	WebUI       bool              `json:"webUI"`
+	PortForward *bool             `json:"portForward,omitempty"` // Lima only
+	Containers  []containerStatus `json:"containers"`
+}
+
+type containerStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Ports  string `json:"ports"`
+}
+
+// collectDevStatus checks the dev environment, stopping at the first layer
+// that isn't up
+func collectDevStatus() devStatus {
+	status := devStatus{Backend: "lima", Containers: []containerStatus{}}
+	run := func(cmd string) (string, error) { return vm.Exec(devVMName, cmd) }
+
 	if vm.IsNative() {
-		return cmdStatusNative()
+		status.Backend = "native"
+		run = vm.LocalExec
+	} else {
+		switch vm.GetStatus(devVMName) {
+		case vm.StatusRunning:
+			status.VM = "running"
+		case vm.StatusStopped:
+			status.VM = "stopped"
+			return status
+		default:
+			status.VM = "not_created"
+			return status
+		}
+	}
+
+	output, err := run(fmt.Sprintf("tmux has-session -t %s 2>/dev/null && echo running || echo stopped", devTmuxSession))
+	status.Session = err == nil && strings.TrimSpace(output) == "running"
+	if !status.Session {
+		return status
+	}
+
+	output, _ = run("curl -s http://localhost:3000/api/health 2>/dev/null")
+	status.HostAgent = strings.Contains(output, "ok")
+
+	output, _ = run("curl -s http://localhost:8080 2>/dev/null")
+	status.WebUI = strings.Contains(output, "html")
+
+	if !vm.IsNative() {
+		forwarding := isPortForwardingRunning(devPorts[0].LocalPort)
+		status.PortForward = &forwarding
+	}
+
+	output, _ = run(`podman ps --format "{{.Names}}\t{{.Status}}\t{{.Ports}}"`)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		for len(fields) < 3 {
+			fields = append(fields, "")
+		}
+		status.Containers = append(status.Containers, containerStatus{Name: fields[0], Status: fields[1], Ports: fields[2]})
+	}
+
+	return status
+}
+
+func cmdStatus() int {
+	status := collectDevStatus()
+	if jsonOutput {
+		return printJSON(status)
 	}
 
 	fmt.Println()
 
-	// Check VM status
-	status := vm.GetStatus(devVMName)
-	switch status {
-	case vm.StatusRunning:
-		fmt.Printf("  VM:           %sRunning%s\n", colorGreen, colorReset)
-	case vm.StatusStopped:
-		fmt.Printf("  VM:           %sStopped%s\n", colorYellow, colorReset)
-		fmt.Println()
-		fmt.Println("  Run './bloud start' to start the dev environment")
-		return 0
-	default:
-		fmt.Printf("  VM:           %sNot created%s\n", colorRed, colorReset)
-		fmt.Println()
-		fmt.Println("  Run './bloud start' to create and start the dev environment")
-		return 0
+	if status.Backend == "native" {
+		fmt.Printf("  Runtime:      %sNative NixOS%s\n", colorGreen, colorReset)
+	} else {
+		switch status.VM {
+		case "running":
+			fmt.Printf("  VM:           %sRunning%s\n", colorGreen, colorReset)
+		case "stopped":
+			fmt.Printf("  VM:           %sStopped%s\n", colorYellow, colorReset)
+			fmt.Println()
+			fmt.Println("  Run './bloud start' to start the dev environment")
+			return 0
+		default:
+			fmt.Printf("  VM:           %sNot created%s\n", colorRed, colorReset)
+			fmt.Println()
+			fmt.Println("  Run './bloud start' to create and start the dev environment")
+			return 0
+		}
 	}
 
-	// Check tmux session
-	output, err := vm.Exec(devVMName, fmt.Sprintf("tmux has-session -t %s 2>/dev/null && echo running || echo stopped", devTmuxSession))
-	if err == nil && strings.TrimSpace(output) == "running" {
+	if status.Session {
 		fmt.Printf("  Tmux Session: %sRunning%s\n", colorGreen, colorReset)
 	} else {
 		fmt.Printf("  Tmux Session: %sNot running%s\n", colorRed, colorReset)
@@ -198,37 +279,35 @@ func cmdStatus() int {
 		return 0
 	}
 
-	// Check host-agent
-	output, _ = vm.Exec(devVMName, `curl -s http://localhost:3000/api/health 2>/dev/null`)
-	if strings.Contains(output, "ok") {
+	if status.HostAgent {
 		fmt.Printf("  Host Agent:   %sRunning%s (http://localhost:3000)\n", colorGreen, colorReset)
 	} else {
 		fmt.Printf("  Host Agent:   %sStarting...%s\n", colorYellow, colorReset)
 	}
 
-	// Check web UI
-	output, _ = vm.Exec(devVMName, `curl -s http://localhost:8080 2>/dev/null`)
-	if strings.Contains(output, "html") {
+	if status.WebUI {
 		fmt.Printf("  Web UI:       %sRunning%s (http://localhost:8080)\n", colorGreen, colorReset)
 	} else {
 		fmt.Printf("  Web UI:       %sStarting...%s\n", colorYellow, colorReset)
 	}
 
-	// Check port forwarding
-	if isPortForwardingRunning(devPorts[0].LocalPort) {
-		fmt.Printf("  Port Forward: %sActive%s\n", colorGreen, colorReset)
-	} else {
-		fmt.Printf("  Port Forward: %sNot running%s\n", colorRed, colorReset)
+	if status.PortForward != nil {
+		if *status.PortForward {
+			fmt.Printf("  Port Forward: %sActive%s\n", colorGreen, colorReset)
+		} else {
+			fmt.Printf("  Port Forward: %sNot running%s\n", colorRed, colorReset)
+		}
 	}
 
-	// Check podman containers
 	fmt.Println()
 	log("Podman containers:")
-	output, _ = vm.Exec(devVMName, `podman ps --format "table {{.Names}}\t{{.Status}}\t{{.Ports}}"`)
-	if strings.TrimSpace(output) == "" {
+	if len(status.Containers) == 0 {
 		fmt.Println("  (none running)")
-	} else {
-		fmt.Println(output)
+		return 0
+	}
+	fmt.Printf("%-40s %-30s %s\n", "NAMES", "STATUS", "PORTS")
+	for _, c := range status.Containers {
+		fmt.Printf("%-40s %-30s %s\n", c.Name, c.Status, c.Ports)
 	}
 
 	return 0
@@ -265,6 +344,52 @@ func cmdLogs() int {
 	return 0
 }
 
+// appLogLines is how much of an app's journal ./bloud logs <app> shows
+const appLogLines = 100
+
+// cmdAppLogs handles "./bloud logs <app> [-f] [-n lines]": it prints the
+// app's podman unit journal from the VM or native host
+func cmdAppLogs(appName string, follow bool, lines int) int {
+	if !appNamePattern.MatchString(appName) {
+		errorf("Invalid app name: %s", appName)
+		return 1
+	}
+
+	// Same unit the host-agent's log stream reads
+	cmd := fmt.Sprintf("journalctl --user -u podman-%s.service -n %d --no-pager -o short-iso", appName, lines)
+	if follow {
+		cmd += " --follow"
+	}
+
+	var err error
+	if isPVEMode() {
+		cfg := getPVEConfig()
+		if !pveVMIsRunning(cfg) {
+			errorf("VM is not running. Start with: ./bloud start [iso]")
+			return 1
+		}
+		ip := getVMIP(cfg)
+		if ip == "" {
+			errorf("Could not get VM IP (is the guest agent running?)")
+			return 1
+		}
+		err = vmExecStream(ip, cmd)
+	} else {
+		if !vm.IsNative() && !vm.IsRunning(devVMName) {
+			errorf("VM is not running. Start with: ./bloud start")
+			return 1
+		}
+		err = vm.RunStream(devVMName, cmd)
+	}
+
+	// Following ends with Ctrl-C, which is not a failure
+	if err != nil && !follow {
+		errorf("Failed to read logs for %s: %v", appName, err)
+		return 1
+	}
+	return 0
+}
+
 func cmdAttach() int {
 	if vm.IsNative() {
 		return cmdAttachNative()
@@ -335,10 +460,10 @@ func cmdRebuild() int {
 		return 1
 	}
 
-	// Initialize secrets before rebuild to ensure NixOS can read them
-	log("Ensuring secrets are initialized...")
-	if _, err := vm.Exec(devVMName, "/tmp/host-agent init-secrets /home/bloud/.local/share/bloud"); err != nil {
-		warn(fmt.Sprintf("Failed to initialize secrets: %v (continuing anyway)", err))
+	// Initialize and verify secrets before rebuild to ensure NixOS can read them
+	log("Verifying secrets...")
+	if !secretsVerified(vm.Exec(devVMName, "/tmp/host-agent init-secrets --verify /home/bloud/.local/share/bloud")) {
+		return 1
 	}
 
 	log("Rebuilding NixOS configuration...")
@@ -352,8 +477,13 @@ func cmdRebuild() int {
 }
 
 func cmdServices() int {
+	listCmd := "systemctl --user list-units 'podman-*' --all --no-pager"
+	if jsonOutput {
+		listCmd += " --output=json"
+	}
+
 	if vm.IsNative() {
-		output, err := vm.LocalExec("systemctl --user list-units 'podman-*' --all --no-pager")
+		output, err := vm.LocalExec(listCmd)
 		if err != nil {
 			errorf("Failed to get services: %v", err)
 			return 1
@@ -367,7 +497,7 @@ func cmdServices() int {
 		return 1
 	}
 
-	output, err := vm.Exec(devVMName, "systemctl --user list-units 'podman-*' --all --no-pager")
+	output, err := vm.Exec(devVMName, listCmd)
 	if err != nil {
 		errorf("Failed to get services: %v", err)
 		return 1
@@ -412,21 +542,11 @@ func isPortForwardingRunning(port int) bool {
 }
 
 func cmdInstall(args []string) int {
-	if len(args) < 1 {
-		errorf("Usage: ./bloud install <app-name>")
-		return 1
-	}
-
 	appName := args[0]
 	return installApp(devVMName, 3000, appName)
 }
 
 func cmdUninstall(args []string) int {
-	if len(args) < 1 {
-		errorf("Usage: ./bloud uninstall <app-name>")
-		return 1
-	}
-
 	appName := args[0]
 	return uninstallApp(devVMName, 3000, appName)
 }
@@ -441,7 +561,7 @@ func installApp(vmName string, apiPort int, appName string) int {
 	log(fmt.Sprintf("Installing %s...", appName))
 
 	// Call the host-agent API
-	curlCmd := fmt.Sprintf(`curl -s -X POST -w "\n%%{http_code}" http://localhost:%d/api/apps/%s/install`, apiPort, appName)
+	curlCmd := fmt.Sprintf(`curl -s -X POST -w "\n%%{http_code}" http://localhost:%d/api/v1/apps/%s/install`, apiPort, appName)
 	output, err := vm.Run(vmName, curlCmd)
 	if err != nil {
 		errorf("Failed to call install API: %v", err)
@@ -478,7 +598,7 @@ func uninstallApp(vmName string, apiPort int, appName string) int {
 	log(fmt.Sprintf("Uninstalling %s...", appName))
 
 	// Call the host-agent API
-	curlCmd := fmt.Sprintf(`curl -s -X POST -w "\n%%{http_code}" http://localhost:%d/api/apps/%s/uninstall`, apiPort, appName)
+	curlCmd := fmt.Sprintf(`curl -s -X POST -w "\n%%{http_code}" http://localhost:%d/api/v1/apps/%s/uninstall`, apiPort, appName)
 	output, err := vm.Run(vmName, curlCmd)
 	if err != nil {
 		errorf("Failed to call uninstall API: %v", err)
@@ -721,50 +841,6 @@ func cmdStopNative() int {
 	return 0
 }
 
-func cmdStatusNative() int {
-	fmt.Println()
-	fmt.Printf("  Runtime:      %sNative NixOS%s\n", colorGreen, colorReset)
-
-	// Check tmux session
-	output, _ := vm.LocalExec(fmt.Sprintf("tmux has-session -t %s 2>/dev/null && echo running || echo stopped", devTmuxSession))
-	if strings.TrimSpace(output) == "running" {
-		fmt.Printf("  Tmux Session: %sRunning%s\n", colorGreen, colorReset)
-	} else {
-		fmt.Printf("  Tmux Session: %sNot running%s\n", colorRed, colorReset)
-		fmt.Println()
-		fmt.Println("  Run './bloud start' to start the dev environment")
-		return 0
-	}
-
-	// Check host-agent
-	output, _ = vm.LocalExec("curl -s http://localhost:3000/api/health 2>/dev/null")
-	if strings.Contains(output, "ok") {
-		fmt.Printf("  Host Agent:   %sRunning%s (http://localhost:3000)\n", colorGreen, colorReset)
-	} else {
-		fmt.Printf("  Host Agent:   %sStarting...%s\n", colorYellow, colorReset)
-	}
-
-	// Check web UI
-	output, _ = vm.LocalExec("curl -s http://localhost:8080 2>/dev/null")
-	if strings.Contains(output, "html") {
-		fmt.Printf("  Web UI:       %sRunning%s (http://localhost:8080)\n", colorGreen, colorReset)
-	} else {
-		fmt.Printf("  Web UI:       %sStarting...%s\n", colorYellow, colorReset)
-	}
-
-	// Check podman containers
-	fmt.Println()
-	log("Podman containers:")
-	output, _ = vm.LocalExec(`podman ps --format "table {{.Names}}\t{{.Status}}\t{{.Ports}}"`)
-	if strings.TrimSpace(output) == "" {
-		fmt.Println("  (none running)")
-	} else {
-		fmt.Println(output)
-	}
-
-	return 0
-}
-
 func cmdLogsNative() int {
 	// Check if tmux session exists
 	output, _ := vm.LocalExec(fmt.Sprintf("tmux has-session -t %s 2>/dev/null && echo running || echo stopped", devTmuxSession))
@@ -811,10 +887,10 @@ func cmdRebuildNative() int {
 		return 1
 	}
 
-	// Initialize secrets before rebuild
-	log("Ensuring secrets are initialized...")
-	if _, err := vm.LocalExec(fmt.Sprintf("/tmp/host-agent init-secrets %s", nativeDataDir)); err != nil {
-		warn(fmt.Sprintf("Failed to initialize secrets: %v (continuing anyway)", err))
+	// Initialize and verify secrets before rebuild
+	log("Verifying secrets...")
+	if !secretsVerified(vm.LocalExec(fmt.Sprintf("/tmp/host-agent init-secrets --verify %s", nativeDataDir))) {
+		return 1
 	}
 
 	log("Rebuilding NixOS configuration...")
@@ -826,3 +902,23 @@ func cmdRebuildNative() int {
 
 	return 0
 }
+
+// secretsVerified reports the result of "init-secrets --verify". Repairs are
+// shown; anything it couldn't fix stops the rebuild, since services would
+// come up with missing or mismatched secrets.
+func secretsVerified(output string, err error) bool {
+	output = strings.TrimSpace(output)
+	if err != nil {
+		if output != "" {
+			fmt.Println(output)
+		}
+		errorf("Secrets need attention before rebuilding: %v", err)
+		return false
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "repaired") {
+			warn(line)
+		}
+	}
+	return true
+}
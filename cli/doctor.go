@@ -0,0 +1,519 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
+)
+
+// Free space on the data directory's filesystem below which doctor warns or fails
+const (
+	doctorDiskWarnGB = 10
+	doctorDiskFailGB = 2
+)
+
+type doctorStatus int
+
+const (
+	doctorPass doctorStatus = iota
+	doctorWarn
+	doctorFail
+	doctorSkip
+)
+
+func (s doctorStatus) String() string {
+	switch s {
+	case doctorPass:
+		return "pass"
+	case doctorWarn:
+		return "warn"
+	case doctorFail:
+		return "fail"
+	default:
+		return "skip"
+	}
+}
+
+// doctorResult is the outcome of one doctor check
+type doctorResult struct {
+	name   string
+	status doctorStatus
+	detail string
+	fix    string
+}
+
+// doctorReport is what ./bloud doctor prints with --json
+type doctorReport struct {
+	Backend string            `json:"backend"`
+	Host    string            `json:"host"`
+	Commit  string            `json:"commit,omitempty"`
+	Passed  int               `json:"passed"`
+	Warned  int               `json:"warned"`
+	Failed  int               `json:"failed"`
+	Checks  []doctorCheckJSON `json:"checks"`
+}
+
+type doctorCheckJSON struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // pass, warn, fail or skip
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// doctorTarget is the machine the dev environment runs on: the Lima VM, the
+// local NixOS host or the Proxmox test VM
+type doctorTarget struct {
+	backend   string
+	agentPath string
+	// exec runs a command on the target; nil when it can't be reached
+	exec func(cmd string) (string, error)
+}
+
+// cmdDoctor checks everything a working environment depends on and prints
+// pass/fail with a suggested fix for each, so the output can be attached to
+// bug reports as is.
+func cmdDoctor() int {
+	projectRoot, _ := getProjectRoot()
+	target, vmResult := doctorResolveTarget()
+
+	var commit string
+	if projectRoot != "" {
+		if out, err := vm.LocalExec(fmt.Sprintf("git -C %s rev-parse --short HEAD 2>/dev/null", projectRoot)); err == nil {
+			commit = strings.TrimSpace(out)
+		}
+	}
+
+	results := []doctorResult{
+		doctorPrerequisites(projectRoot),
+		vmResult,
+		doctorPorts(),
+		doctorDNS(target),
+		doctorMDNS(target),
+		doctorHostAgent(target),
+		doctorAuthentik(target),
+		doctorDiskSpace(target),
+		doctorSecrets(target),
+	}
+
+	var passed, warned, failed int
+	for _, r := range results {
+		switch r.status {
+		case doctorPass:
+			passed++
+		case doctorWarn:
+			warned++
+		case doctorFail:
+			failed++
+		}
+	}
+
+	exitCode := 0
+	if failed > 0 {
+		exitCode = 1
+	}
+
+	if jsonOutput {
+		report := doctorReport{
+			Backend: target.backend,
+			Host:    doctorHost(),
+			Commit:  commit,
+			Passed:  passed,
+			Warned:  warned,
+			Failed:  failed,
+			Checks:  make([]doctorCheckJSON, 0, len(results)),
+		}
+		for _, r := range results {
+			report.Checks = append(report.Checks, doctorCheckJSON{Name: r.name, Status: r.status.String(), Detail: r.detail, Fix: r.fix})
+		}
+		if printJSON(report) != 0 {
+			return 1
+		}
+		return exitCode
+	}
+
+	fmt.Println()
+	fmt.Printf("%s╭─────────────────────────────────────╮%s\n", colorCyan, colorReset)
+	fmt.Printf("%s│            Bloud Doctor             │%s\n", colorCyan, colorReset)
+	fmt.Printf("%s╰─────────────────────────────────────╯%s\n", colorCyan, colorReset)
+	fmt.Println()
+
+	fmt.Printf("  Backend:  %s\n", target.backend)
+	fmt.Printf("  Host:     %s\n", doctorHost())
+	if commit != "" {
+		fmt.Printf("  Commit:   %s\n", commit)
+	}
+	fmt.Println()
+
+	for _, r := range results {
+		printDoctorResult(r)
+	}
+
+	fmt.Println()
+	fmt.Println("════════════════════════════════════════════════════════════")
+	fmt.Printf("  %s%d passed%s, %s%d warnings%s, %s%d failed%s\n",
+		colorGreen, passed, colorReset,
+		colorYellow, warned, colorReset,
+		colorRed, failed, colorReset,
+	)
+	fmt.Println("════════════════════════════════════════════════════════════")
+	fmt.Println()
+
+	return exitCode
+}
+
+// doctorHost describes the machine the CLI runs on
+func doctorHost() string {
+	host := runtime.GOOS + "/" + runtime.GOARCH
+	if vm.IsWSL() {
+		host += " (WSL2)"
+	}
+	return host
+}
+
+func printDoctorResult(r doctorResult) {
+	var mark string
+	switch r.status {
+	case doctorPass:
+		mark = colorGreen + "✓" + colorReset
+	case doctorWarn:
+		mark = colorYellow + "!" + colorReset
+	case doctorFail:
+		mark = colorRed + "✗" + colorReset
+	default:
+		mark = "-"
+	}
+	fmt.Printf("  %s %-20s %s\n", mark, r.name, r.detail)
+	if r.fix != "" && (r.status == doctorWarn || r.status == doctorFail) {
+		fmt.Printf("    %sFix:%s %s\n", colorCyan, colorReset, r.fix)
+	}
+}
+
+// doctorResolveTarget works out where the dev environment runs and whether
+// it is reachable, which is the VM check's result
+func doctorResolveTarget() (doctorTarget, doctorResult) {
+	result := doctorResult{name: "VM"}
+
+	if isPVEMode() {
+		cfg := getPVEConfig()
+		target := doctorTarget{backend: fmt.Sprintf("Proxmox (%s, VMID %s)", cfg.Host, cfg.VMID)}
+		switch {
+		case !pveVMExists(cfg):
+			result.status, result.detail = doctorFail, "not created"
+			result.fix = "./bloud start [iso]"
+		case !pveVMIsRunning(cfg):
+			result.status, result.detail = doctorFail, "stopped"
+			result.fix = "./bloud start --skip-deploy"
+		default:
+			ip := getVMIP(cfg)
+			if ip == "" {
+				result.status, result.detail = doctorFail, "running, but its IP is unknown"
+				result.fix = "check the QEMU guest agent is running in the VM"
+				break
+			}
+			result.status, result.detail = doctorPass, "running at "+ip
+			target.exec = func(cmd string) (string, error) { return vmExec(ip, cmd) }
+			// The installed system runs the packaged binary, not a dev build
+			target.agentPath = `$(systemctl show bloud-host-agent.service -p ExecStart --value | sed -n 's/.*path=\([^ ;]*\).*/\1/p')`
+		}
+		return target, result
+	}
+
+	if vm.IsNative() {
+		result.status, result.detail = doctorSkip, "not used on native NixOS"
+		return doctorTarget{backend: "Native NixOS", agentPath: "/tmp/host-agent", exec: vm.LocalExec}, result
+	}
+
+	target := doctorTarget{backend: "Lima VM", agentPath: "/tmp/host-agent"}
+	if vm.GetBackend() == vm.BackendLibvirt {
+		target.backend = "libvirt VM"
+	}
+	switch vm.GetStatus(devVMName) {
+	case vm.StatusRunning:
+		result.status, result.detail = doctorPass, "running"
+		target.exec = func(cmd string) (string, error) { return vm.Exec(devVMName, cmd) }
+	case vm.StatusStopped:
+		result.status, result.detail = doctorFail, "stopped"
+		result.fix = "./bloud start"
+	default:
+		result.status, result.detail = doctorFail, "not created"
+		result.fix = "./bloud setup"
+	}
+	return target, result
+}
+
+func doctorPrerequisites(projectRoot string) doctorResult {
+	result := doctorResult{name: "Prerequisites"}
+
+	var preflight *vm.PreflightResult
+	switch {
+	case isPVEMode():
+		preflight = &vm.PreflightResult{}
+		if !checkCommand("ssh") {
+			preflight.AddError("ssh", "ssh is not installed", "install an OpenSSH client", "")
+		}
+	case vm.IsNative():
+		preflight = vm.RunNativePreflightChecks()
+	default:
+		if projectRoot == "" {
+			result.status, result.detail = doctorFail, "project root not found"
+			result.fix = "run ./bloud from the repository checkout"
+			return result
+		}
+		preflight = vm.RunPreflightChecks(projectRoot)
+	}
+
+	if !preflight.HasErrors() {
+		result.status, result.detail = doctorPass, "installed"
+		return result
+	}
+
+	var messages, fixes []string
+	for _, e := range preflight.Errors {
+		messages = append(messages, strings.ReplaceAll(e.Message, "\n   ", ""))
+		if e.FixCommand != "" {
+			fixes = append(fixes, e.FixCommand)
+		} else if e.Check == "vm-image" {
+			fixes = append(fixes, "./bloud setup")
+		}
+	}
+	result.status = doctorFail
+	result.detail = strings.Join(messages, "; ")
+	result.fix = strings.Join(fixes, "; ")
+	return result
+}
+
+// doctorPorts looks for other processes holding the ports the dev
+// environment forwards or listens on
+func doctorPorts() doctorResult {
+	result := doctorResult{name: "Ports"}
+
+	if isPVEMode() {
+		result.status, result.detail = doctorSkip, "not forwarded in Proxmox mode"
+		return result
+	}
+
+	ports := make([]int, 0, len(devPorts))
+	if vm.IsNative() {
+		// The dev servers hold these themselves while the session is up
+		if out, _ := vm.LocalExec(fmt.Sprintf("tmux has-session -t %s 2>/dev/null && echo running", devTmuxSession)); strings.TrimSpace(out) == "running" {
+			result.status, result.detail = doctorSkip, "dev session is running"
+			return result
+		}
+		ports = append(ports, 3000, 5173)
+	} else {
+		if isPortForwardingRunning(devPorts[0].LocalPort) {
+			result.status, result.detail = doctorPass, "forwarded to the VM"
+			return result
+		}
+		for _, p := range devPorts {
+			ports = append(ports, p.LocalPort)
+		}
+	}
+
+	var busy []string
+	for _, port := range ports {
+		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			busy = append(busy, strconv.Itoa(port))
+			continue
+		}
+		ln.Close()
+	}
+
+	if len(busy) == 0 {
+		result.status, result.detail = doctorPass, "free"
+		return result
+	}
+	result.status = doctorFail
+	result.detail = fmt.Sprintf("in use by another process: %s", strings.Join(busy, ", "))
+	result.fix = fmt.Sprintf("stop the process holding each port (lsof -i :%s)", busy[0])
+	return result
+}
+
+func doctorDNS(target doctorTarget) doctorResult {
+	result := doctorResult{name: "DNS"}
+	if target.exec == nil {
+		result.status, result.detail = doctorSkip, "target not reachable"
+		return result
+	}
+
+	// Image pulls are the first thing to break without DNS
+	if _, err := target.exec("getent hosts registry-1.docker.io"); err != nil {
+		result.status, result.detail = doctorFail, "cannot resolve registry-1.docker.io"
+		result.fix = "check /etc/resolv.conf and the network on the " + target.backend
+		return result
+	}
+	result.status, result.detail = doctorPass, "resolves registry-1.docker.io"
+	return result
+}
+
+func doctorMDNS(target doctorTarget) doctorResult {
+	result := doctorResult{name: "mDNS"}
+
+	if !isPVEMode() && !vm.IsNative() {
+		result.status, result.detail = doctorSkip, "not used in the Lima VM"
+		return result
+	}
+	if target.exec == nil {
+		result.status, result.detail = doctorSkip, "target not reachable"
+		return result
+	}
+
+	if _, err := target.exec("systemctl is-active avahi-daemon.service"); err != nil {
+		result.status, result.detail = doctorFail, "avahi-daemon is not running"
+		result.fix = "systemctl status avahi-daemon.service"
+		return result
+	}
+
+	// The appliance is reached as bloud.local from other machines on the LAN
+	if isPVEMode() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if _, err := net.DefaultResolver.LookupHost(ctx, "bloud.local"); err != nil {
+			result.status, result.detail = doctorWarn, "avahi-daemon is running, but bloud.local doesn't resolve from this machine"
+			result.fix = "enable mDNS resolution on this machine (nss-mdns, or Bonjour on macOS)"
+			return result
+		}
+	}
+
+	result.status, result.detail = doctorPass, "avahi-daemon is running"
+	return result
+}
+
+func doctorHostAgent(target doctorTarget) doctorResult {
+	result := doctorResult{name: "Host agent"}
+	if target.exec == nil {
+		result.status, result.detail = doctorSkip, "target not reachable"
+		return result
+	}
+
+	out, _ := target.exec("curl -sf http://localhost:3000/api/health 2>/dev/null")
+	if !strings.Contains(out, "ok") {
+		result.status, result.detail = doctorFail, "http://localhost:3000/api/health is not responding"
+		if isPVEMode() {
+			result.fix = "./bloud logs, or: ./bloud shell systemctl status bloud-host-agent"
+		} else {
+			result.fix = "./bloud start, then check ./bloud logs"
+		}
+		return result
+	}
+	result.status, result.detail = doctorPass, "healthy"
+	return result
+}
+
+func doctorAuthentik(target doctorTarget) doctorResult {
+	result := doctorResult{name: "Authentik"}
+	if target.exec == nil {
+		result.status, result.detail = doctorSkip, "target not reachable"
+		return result
+	}
+
+	if _, err := target.exec("systemctl --user cat podman-apps-authentik-server.service >/dev/null 2>&1"); err != nil {
+		result.status, result.detail = doctorSkip, "not installed"
+		return result
+	}
+
+	out, _ := target.exec(`curl -s -o /dev/null -w "%{http_code}" http://localhost:9001/-/health/live/ 2>/dev/null`)
+	code := strings.TrimSpace(out)
+	if code != "200" && code != "204" {
+		result.status = doctorFail
+		result.detail = "http://localhost:9001 is not responding"
+		if code != "" && code != "000" {
+			result.detail = fmt.Sprintf("http://localhost:9001 returned HTTP %s", code)
+		}
+		result.fix = "./bloud shell journalctl --user -u podman-apps-authentik-server -n 50"
+		return result
+	}
+	result.status, result.detail = doctorPass, "healthy"
+	return result
+}
+
+func doctorDiskSpace(target doctorTarget) doctorResult {
+	result := doctorResult{name: "Disk space"}
+	if target.exec == nil {
+		result.status, result.detail = doctorSkip, "target not reachable"
+		return result
+	}
+
+	out, err := target.exec(fmt.Sprintf("df -Pk %s 2>/dev/null || df -Pk /home/bloud", nativeDataDir))
+	if err != nil {
+		result.status, result.detail = doctorWarn, "could not run df"
+		return result
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 6 {
+		result.status, result.detail = doctorWarn, "could not parse df output"
+		return result
+	}
+	availKB, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		result.status, result.detail = doctorWarn, "could not parse df output"
+		return result
+	}
+
+	availGB := float64(availKB) / (1024 * 1024)
+	result.detail = fmt.Sprintf("%.1f GB free on %s", availGB, fields[5])
+	switch {
+	case availGB < doctorDiskFailGB:
+		result.status = doctorFail
+		result.fix = "free space, e.g. podman image prune -a and nix-collect-garbage -d"
+	case availGB < doctorDiskWarnGB:
+		result.status = doctorWarn
+		result.fix = "free space, e.g. podman image prune -a and nix-collect-garbage -d"
+	default:
+		result.status = doctorPass
+	}
+	return result
+}
+
+// doctorSecrets runs the same verification as ./bloud rebuild. It repairs
+// what it safely can (e.g. stale env files), so repairs show as warnings.
+func doctorSecrets(target doctorTarget) doctorResult {
+	result := doctorResult{name: "Secrets"}
+	if target.exec == nil {
+		result.status, result.detail = doctorSkip, "target not reachable"
+		return result
+	}
+
+	out, err := target.exec(fmt.Sprintf("%s init-secrets --verify %s 2>&1", target.agentPath, nativeDataDir))
+	out = strings.TrimSpace(out)
+
+	var repaired, unfixed []string
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "repaired"):
+			repaired = append(repaired, strings.TrimSpace(strings.TrimPrefix(line, "repaired")))
+		case strings.HasPrefix(line, "UNFIXED"), strings.HasPrefix(line, "FAILED"):
+			unfixed = append(unfixed, strings.TrimSpace(line))
+		}
+	}
+
+	switch {
+	case err != nil && len(unfixed) == 0:
+		result.status, result.detail = doctorFail, "verification did not run: "+lastLine(out)
+		result.fix = "./bloud setup builds the host agent and initializes secrets"
+	case err != nil:
+		result.status, result.detail = doctorFail, strings.Join(unfixed, "; ")
+		result.fix = "follow the \"to fix\" hints from: host-agent init-secrets --verify"
+	case len(repaired) > 0:
+		result.status, result.detail = doctorWarn, "repaired: "+strings.Join(repaired, "; ")
+	default:
+		result.status, result.detail = doctorPass, "verified"
+	}
+	return result
+}
+
+func lastLine(s string) string {
+	if s == "" {
+		return "no output"
+	}
+	lines := strings.Split(s, "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
+)
+
+// cmdForward handles "./bloud forward <app|port> [local-port]": it forwards a
+// host port to a port in the Lima or Proxmox VM until interrupted. An app
+// name is resolved to its port through the catalog.
+func cmdForward(args []string) int {
+	if vm.IsNative() {
+		errorf("Nothing to forward on native NixOS: app ports are already local")
+		return 1
+	}
+
+	remotePort, err := strconv.Atoi(args[0])
+	if err != nil {
+		remotePort, err = lookupAppPort(args[0])
+		if err != nil {
+			errorf("%v", err)
+			return 1
+		}
+	}
+	localPort := remotePort
+	if len(args) > 1 {
+		if localPort, err = strconv.Atoi(args[1]); err != nil {
+			errorf("Invalid local port: %s", args[1])
+			return exitUsage
+		}
+	}
+	if !validPort(remotePort) || !validPort(localPort) {
+		errorf("Ports must be between 1 and 65535")
+		return 1
+	}
+
+	if waitForLocalPort(localPort, 0) {
+		errorf("localhost:%d is already in use; pass another local port: ./bloud forward %s <local-port>", localPort, args[0])
+		return 1
+	}
+
+	forward := vm.PortForward{LocalPort: localPort, RemotePort: remotePort}
+
+	var stop func()
+	if isPVEMode() {
+		stop, err = startPVEPortForward(forward)
+	} else {
+		if !vm.IsRunning(devVMName) {
+			errorf("VM is not running. Start with: ./bloud start")
+			return 1
+		}
+		stop, err = vm.StartPortForwarding(devVMName, []vm.PortForward{forward})
+	}
+	if err != nil {
+		errorf("Failed to forward port: %v", err)
+		return 1
+	}
+
+	// ssh exits on its own when the forward fails (ExitOnForwardFailure), so
+	// make sure the local port actually answers before reporting success
+	if !waitForLocalPort(localPort, 10*time.Second) {
+		stop()
+		errorf("Port forward did not come up on localhost:%d", localPort)
+		return 1
+	}
+
+	log(fmt.Sprintf("Forwarding http://localhost:%d -> VM port %d (Ctrl-C to stop)", localPort, remotePort))
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	stop()
+	fmt.Println()
+	log("Port forward stopped")
+	return 0
+}
+
+// lookupAppPort finds an app's port in the running host-agent's catalog,
+// falling back to its metadata.yaml in the checkout
+func lookupAppPort(name string) (int, error) {
+	if api, err := resolveHostAgentAPI(false); err == nil {
+		if app, err := api.client.GetAppMetadata(context.Background(), name); err == nil && app.Port > 0 {
+			return app.Port, nil
+		}
+	}
+
+	root, err := getProjectRoot()
+	if err != nil {
+		return 0, err
+	}
+	apps, err := loadAppMetadata(filepath.Join(root, "apps"))
+	if err != nil {
+		return 0, err
+	}
+	app, ok := apps[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown app: %s", name)
+	}
+	if app.Port == 0 {
+		return 0, fmt.Errorf("%s does not expose a port", name)
+	}
+	return app.Port, nil
+}
+
+// startPVEPortForward runs an SSH tunnel to the Proxmox VM in the background
+func startPVEPortForward(forward vm.PortForward) (func(), error) {
+	cfg := getPVEConfig()
+	if !pveVMIsRunning(cfg) {
+		return nil, fmt.Errorf("VM is not running. Start with: ./bloud start [iso]")
+	}
+	ip := getVMIP(cfg)
+	if ip == "" {
+		return nil, fmt.Errorf("could not get VM IP (is the guest agent running?)")
+	}
+
+	c := vm.Command("sshpass", "-p", pveVMSSHPass,
+		"ssh",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "LogLevel=ERROR",
+		"-o", "ServerAliveInterval=60",
+		"-o", "ExitOnForwardFailure=yes",
+		"-N",
+		"-L", fmt.Sprintf("%d:localhost:%d", forward.LocalPort, forward.RemotePort),
+		pveVMSSHUser+"@"+ip,
+	)
+	c.Stderr = os.Stderr
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	return func() {
+		_ = c.Process.Signal(syscall.SIGTERM)
+		_ = c.Wait()
+	}, nil
+}
+
+// waitForLocalPort polls until something accepts connections on port,
+// trying once when timeout is zero
+func waitForLocalPort(port int, timeout time.Duration) bool {
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	deadline := time.Now().Add(timeout)
+	for {
+		if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+			conn.Close()
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
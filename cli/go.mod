@@ -2,4 +2,10 @@ module codeberg.org/d-buckner/bloud/cli
 
 go 1.24.0
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	codeberg.org/d-buckner/bloud-v3/services/host-agent v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+// The generated host-agent API client (pkg/apiclient), from this checkout
+replace codeberg.org/d-buckner/bloud-v3/services/host-agent => ../services/host-agent
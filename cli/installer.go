@@ -23,6 +23,7 @@ func cmdInstaller() int {
 		fmt.Println()
 		fmt.Println("  http://localhost:5174     Installer UI")
 		fmt.Println("  http://localhost:3001     Installer API (mock)")
+		fmt.Println("  MOCK-CODE                 Setup code")
 		fmt.Println()
 		fmt.Printf("  ./bloud installer stop   Stop the installer dev server\n")
 		fmt.Printf("  tmux attach -t %s   Attach to session\n", installerTmuxSession)
@@ -61,6 +62,7 @@ func cmdInstaller() int {
 	fmt.Println()
 	fmt.Println("  http://localhost:5174     Installer UI")
 	fmt.Println("  http://localhost:3001     Installer API (mock)")
+	fmt.Println("  MOCK-CODE                 Setup code")
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Printf("  tmux attach -t %s   View both processes (Ctrl-B D to detach)\n", installerTmuxSession)
@@ -3,14 +3,17 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"codeberg.org/d-buckner/bloud/cli/vm"
 )
 
-const (
+// Terminal colors; setupColor clears them when color is off
+var (
 	colorRed    = "\033[0;31m"
 	colorGreen  = "\033[0;32m"
 	colorYellow = "\033[1;33m"
@@ -18,6 +21,23 @@ const (
 	colorReset  = "\033[0m"
 )
 
+// setupColor applies the color preference from BLOUD_COLOR (or the config
+// file's color key): always, never, or auto, which colors only terminals
+// and honors NO_COLOR
+func setupColor() {
+	switch os.Getenv("BLOUD_COLOR") {
+	case "always":
+		return
+	case "never":
+	default:
+		info, err := os.Stdout.Stat()
+		if os.Getenv("NO_COLOR") == "" && err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			return
+		}
+	}
+	colorRed, colorGreen, colorYellow, colorCyan, colorReset = "", "", "", "", ""
+}
+
 // loadDotEnv reads a .env file from the project root and sets any variables
 // not already present in the environment. This lets users configure BLOUD_PVE_HOST
 // and other settings without needing to export them from their shell profile.
@@ -57,160 +77,151 @@ func loadDotEnv() {
 
 func main() {
 	loadDotEnv()
+	configErr := applyCLIConfig()
+	setupColor()
+	if configErr != nil {
+		warn(fmt.Sprintf("Ignoring CLI config: %v", configErr))
+	}
 	vm.DetectRuntime()
+	if err := vm.SetBackend(os.Getenv("BLOUD_VM_BACKEND")); err != nil {
+		errorf("BLOUD_VM_BACKEND: %v", err)
+		os.Exit(exitUsage)
+	}
 
-	if len(os.Args) < 2 {
+	argv := parseGlobalFlags(os.Args[1:])
+	if len(argv) < 1 {
 		printUsage()
-		os.Exit(0)
+		os.Exit(exitOK)
+	}
+
+	name := argv[0]
+	if name == "--help" || name == "-h" {
+		name = "help"
+	}
+	c := lookupCommand(name)
+	if c == nil {
+		errorf("Unknown command: %s (see ./bloud help)", name)
+		os.Exit(exitUsage)
+	}
+
+	// Flags are parsed first so --help works without a VM or a valid profile
+	run, args, code := parseCommand(c, argv[1:])
+	if run == nil {
+		os.Exit(code)
+	}
+
+	// config edits the file the other commands read, so it runs even when a
+	// profile or the file itself is broken
+	if c.name == "config" {
+		os.Exit(run(args))
+	}
+
+	if envName != "" {
+		if vm.IsNative() {
+			errorf("--env needs Lima or Proxmox; native NixOS has a single environment")
+			os.Exit(exitUsage)
+		}
+		if err := applyEnvProfile(envName); err != nil {
+			errorf("%v", err)
+			os.Exit(exitFailure)
+		}
+	}
+
+	if jsonOutput && !c.json {
+		errorf("--json is not supported by '%s'", c.name)
+		os.Exit(exitUsage)
+	}
+
+	// A remote appliance is reached over its API, not through a local VM
+	if remoteHost != "" && !c.noVM {
+		if !c.remote {
+			errorf("'%s' does not work with --host (supported: install, uninstall, status, logs, update)", c.name)
+			os.Exit(exitUsage)
+		}
+		os.Exit(cmdRemote(c.name, args))
 	}
 
-	cmd := os.Args[1]
-	args := os.Args[2:]
+	if c.modes&currentMode() == 0 {
+		errorf("%s", modeError(c))
+		os.Exit(exitUsage)
+	}
 
-	// Handle setup command before any other checks
-	if cmd == "setup" {
-		os.Exit(cmdSetup())
+	// setup, doctor, completion and help must work whether or not a VM is
+	// reachable
+	if c.noVM {
+		os.Exit(run(args))
 	}
 
 	// For Lima mode, ensure SSH is available
 	if !vm.IsNative() && !isPVEMode() {
+		if runtime.GOOS == "windows" {
+			errorf("The dev VM does not run on Windows directly; run ./bloud inside a WSL2 distro")
+			os.Exit(exitFailure)
+		}
 		if err := vm.EnsureSSHAvailable(); err != nil {
 			fmt.Fprintf(os.Stderr, "%sError:%s %v\n", colorRed, colorReset, err)
 			fmt.Fprintf(os.Stderr, "\nRun './bloud setup' to check all prerequisites.\n")
-			os.Exit(1)
+			os.Exit(exitFailure)
 		}
 	}
 
-	var exitCode int
+	os.Exit(run(args))
+}
 
-	switch cmd {
-	case "start":
-		if isPVEMode() {
-			exitCode = cmdStartPVE(args)
-		} else {
-			exitCode = cmdStart()
-		}
-	case "stop":
-		if isPVEMode() {
-			exitCode = cmdStopPVE()
-		} else {
-			exitCode = cmdStop()
-		}
-	case "status":
-		if isPVEMode() {
-			exitCode = cmdStatusPVE()
-		} else {
-			exitCode = cmdStatus()
-		}
-	case "logs":
-		if isPVEMode() {
-			exitCode = cmdLogsPVE()
-		} else {
-			exitCode = cmdLogs()
-		}
-	case "shell":
-		if isPVEMode() {
-			exitCode = cmdShellPVE(args)
-		} else {
-			exitCode = cmdShell(args)
-		}
-	case "install":
-		if isPVEMode() {
-			exitCode = cmdInstallPVE(args)
-		} else {
-			exitCode = cmdInstall(args)
-		}
-	case "uninstall":
-		if isPVEMode() {
-			exitCode = cmdUninstallPVE(args)
-		} else {
-			exitCode = cmdUninstall(args)
-		}
-	case "destroy":
-		if isPVEMode() {
-			exitCode = cmdDestroyPVE()
-		} else {
-			exitCode = cmdDestroy()
-		}
-	case "checks":
-		if isPVEMode() {
-			exitCode = cmdChecksPVE()
-		} else {
-			fmt.Fprintf(os.Stderr, "%sError:%s 'checks' is only available in Proxmox mode (set BLOUD_PVE_HOST)\n", colorRed, colorReset)
-			exitCode = 1
+// printUsage lists the commands available in the current mode
+func printUsage() {
+	fmt.Println("Bloud CLI")
+	fmt.Println()
+
+	switch currentMode() {
+	case modePVE:
+		fmt.Printf("  Backend: %sProxmox%s (%s)\n", colorCyan, colorReset, os.Getenv("BLOUD_PVE_HOST"))
+	case modeNative:
+		fmt.Println("  Backend: Native NixOS")
+	default:
+		backend := "Lima"
+		if vm.GetBackend() == vm.BackendLibvirt {
+			backend = "libvirt"
 		}
-	case "setup-builder":
-		if isPVEMode() {
-			exitCode = cmdSetupBuilderPVE()
-		} else {
-			fmt.Fprintf(os.Stderr, "%sError:%s 'setup-builder' is only available in Proxmox mode (set BLOUD_PVE_HOST)\n", colorRed, colorReset)
-			exitCode = 1
+		fmt.Printf("  Backend: %s VM (persistent, ports 8080/3000/5173)\n", backend)
+	}
+	fmt.Println()
+	fmt.Println("Usage: ./bloud [global flags] <command> [flags] [args]")
+	fmt.Println()
+
+	fmt.Println("Commands:")
+	for _, c := range commands {
+		if c.hidden() || c.modes&currentMode() == 0 {
+			continue
 		}
-	case "destroy-builder":
-		if isPVEMode() {
-			exitCode = cmdDestroyBuilderPVE()
-		} else {
-			fmt.Fprintf(os.Stderr, "%sError:%s 'destroy-builder' is only available in Proxmox mode (set BLOUD_PVE_HOST)\n", colorRed, colorReset)
-			exitCode = 1
+		usage := c.name
+		if c.args != "" {
+			usage += " " + c.args
 		}
-	// Lima-only commands
-	case "services":
-		exitCode = cmdServices()
-	case "attach":
-		exitCode = cmdAttach()
-	case "rebuild":
-		exitCode = cmdRebuild()
-	case "depgraph":
-		exitCode = cmdDepGraph()
-	case "installer":
-		if len(args) > 0 && args[0] == "stop" {
-			exitCode = cmdInstallerStop()
+		if len(usage) > 24 {
+			fmt.Printf("  %s\n  %-24s %s\n", usage, "", c.summary)
 		} else {
-			exitCode = cmdInstaller()
+			fmt.Printf("  %-24s %s\n", usage, c.summary)
 		}
-
-	case "help", "--help", "-h":
-		printUsage()
-		exitCode = 0
-
-	default:
-		fmt.Fprintf(os.Stderr, "%sError:%s Unknown command: %s\n", colorRed, colorReset, cmd)
-		printUsage()
-		exitCode = 1
 	}
-
-	os.Exit(exitCode)
-}
-
-func printUsage() {
-	fmt.Println("Bloud CLI")
+	fmt.Println()
+	fmt.Println("Global flags:")
+	fmt.Println("  --json                   Print JSON instead of text (status, services, depgraph, checks, apps, doctor)")
+	fmt.Println("  --env <name>             Use a named environment profile from ~/.config/bloud/config.yaml")
+	fmt.Println("                           (own VM name, Lima config and port offset; also BLOUD_ENV)")
+	fmt.Println("  --host <host>            Manage a Bloud appliance over its API (install, uninstall, status, logs);")
+	fmt.Println("                           also BLOUD_HOST, with an API token in BLOUD_TOKEN")
+	fmt.Println("  --verbose                Print every command run on the host, VM or Proxmox node")
+	fmt.Println("  --quiet                  Only print results, warnings and errors")
+	fmt.Println()
+	fmt.Println("Run './bloud help <command>' (or <command> --help) for a command's flags.")
+	fmt.Println("Exit status: 0 on success, 1 on failure, 2 on a usage error.")
 	fmt.Println()
 
 	if isPVEMode() {
-		fmt.Printf("  Backend: %sProxmox%s (%s)\n", colorCyan, colorReset, os.Getenv("BLOUD_PVE_HOST"))
-		fmt.Println()
-		fmt.Println("Usage: ./bloud <command> [args]")
-		fmt.Println()
-		fmt.Println("Commands:")
-		fmt.Println("  start [iso] [flags]   Deploy ISO → create VM → boot → check (VM stays running)")
-		fmt.Println("    --build             Build ISO locally via build VM instead of downloading")
-		fmt.Println("    --skip-deploy       Reuse existing VM (skip ISO upload + VM create)")
-		fmt.Println("    --pve-host <host>   Override Proxmox SSH target")
-		fmt.Println("    --vmid <id>         Override VM ID")
-		fmt.Println("  stop                  Stop VM")
-		fmt.Println("  destroy               Destroy VM completely")
-		fmt.Println("  status                Show VM and service status")
-		fmt.Println("  logs                  Stream VM journalctl")
-		fmt.Println("  shell [cmd]           SSH into VM")
-		fmt.Println("  checks                Run health checks against running VM")
-		fmt.Println("  install <app>         Install an app via API")
-		fmt.Println("  uninstall <app>       Uninstall an app via API")
-		fmt.Println("  setup-builder         Provision or update the ISO build VM (VMID 9998)")
-		fmt.Println("  destroy-builder       Destroy the ISO build VM")
-		fmt.Println()
 		fmt.Println("Environment:")
-		fmt.Println("  BLOUD_PVE_HOST        Proxmox SSH target (e.g. root@192.168.0.62)")
-		fmt.Println("  BLOUD_PVE_VMID        VM ID (default: 9999)")
+		fmt.Println("  BLOUD_PVE_HOST           Proxmox SSH target (e.g. root@192.168.0.62)")
+		fmt.Println("  BLOUD_PVE_VMID           VM ID (default: " + pveDefaultVMID + ")")
 		fmt.Println()
 		fmt.Println("Examples:")
 		fmt.Println("  ./bloud start                         # test latest GitHub release")
@@ -220,63 +231,30 @@ func printUsage() {
 		return
 	}
 
-	if vm.IsNative() {
-		fmt.Println("  Backend: Native NixOS")
-	} else {
-		fmt.Println("  Backend: Lima VM")
-	}
-
-	fmt.Println()
-	fmt.Println("Usage: ./bloud <command> [args]")
-	fmt.Println()
-	fmt.Println("Setup:")
-	if vm.IsNative() {
-		fmt.Println("  setup           Check prerequisites and apply NixOS configuration")
-	} else {
-		fmt.Println("  setup           Check prerequisites and download VM image")
-	}
-	fmt.Println()
-
-	if vm.IsNative() {
-		fmt.Println("Commands:")
-		fmt.Println("  start           Start dev environment")
-	} else {
-		fmt.Println("Commands (persistent Lima VM, ports 8080/3000/5173):")
-		fmt.Println("  start           Start dev environment (auto-starts VM if needed)")
-	}
-	fmt.Println("  stop            Stop dev services")
-	fmt.Println("  status          Show dev environment status")
-	fmt.Println("  services        Show podman service status")
-	fmt.Println("  logs            Show logs from dev services")
-	fmt.Println("  attach          Attach to tmux session (Ctrl-B D to detach)")
-	if vm.IsNative() {
-		fmt.Println("  shell [cmd]     Run a command (or open a shell)")
-	} else {
-		fmt.Println("  shell [cmd]     Shell into VM (or run a command)")
-	}
-	fmt.Println("  rebuild         Rebuild NixOS configuration")
-	fmt.Println("  install <app>   Install an app")
-	fmt.Println("  uninstall <app> Uninstall an app")
-	fmt.Println("  depgraph        Generate Mermaid dependency graph from app metadata")
-	fmt.Println("  installer       Start installer UI in mock mode (http://localhost:5174)")
-	fmt.Println("  installer stop  Stop the installer dev server")
-	if !vm.IsNative() {
-		fmt.Println("  destroy         Destroy the dev VM completely")
-	}
-	fmt.Println()
 	fmt.Println("URLs (after start):")
 	fmt.Println("  http://localhost:8080     Web UI (via Traefik)")
 	fmt.Println("  http://localhost:3000     Go API")
 	fmt.Println()
-	fmt.Println("Proxmox mode: set BLOUD_PVE_HOST to switch to ISO testing against Proxmox")
+	fmt.Println("Proxmox mode: set BLOUD_PVE_HOST (or ./bloud config set pveHost) to switch to ISO testing against Proxmox")
 }
 
 func log(msg string) {
-	fmt.Printf("%s==>%s %s\n", colorGreen, colorReset, msg)
+	if quiet {
+		return
+	}
+	fmt.Fprintf(progressOut(), "%s==>%s %s\n", colorGreen, colorReset, msg)
 }
 
 func warn(msg string) {
-	fmt.Printf("%sWarning:%s %s\n", colorYellow, colorReset, msg)
+	fmt.Fprintf(progressOut(), "%sWarning:%s %s\n", colorYellow, colorReset, msg)
+}
+
+// progressOut is where log and warn write: stderr when stdout carries JSON
+func progressOut() io.Writer {
+	if jsonOutput {
+		return os.Stderr
+	}
+	return os.Stdout
 }
 
 func errorf(format string, args ...any) {
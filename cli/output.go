@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
+)
+
+// jsonOutput is set by the global --json flag. Commands that support it
+// print a single JSON document to stdout instead of colored text; progress
+// messages from log and warn go to stderr so stdout stays parseable.
+var jsonOutput bool
+
+// quiet is set by the global --quiet flag and silences log's progress
+// messages; results, warnings and errors still print
+var quiet bool
+
+// parseGlobalFlags removes global flags from the arguments and records them.
+// They may appear anywhere, except after the command name of a command that
+// takes its arguments verbatim (shell, config).
+func parseGlobalFlags(args []string) []string {
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case len(rest) > 0 && isRawCommand(rest[0]):
+			rest = append(rest, arg)
+		case arg == "--json":
+			jsonOutput = true
+		case arg == "--verbose":
+			vm.Verbose = true
+		case arg == "--quiet":
+			quiet = true
+		case arg == "--host" && i+1 < len(args):
+			remoteHost = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--host="):
+			remoteHost = strings.TrimPrefix(arg, "--host=")
+		case arg == "--env" && i+1 < len(args):
+			envName = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--env="):
+			envName = strings.TrimPrefix(arg, "--env=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if remoteHost == "" {
+		remoteHost = os.Getenv("BLOUD_HOST")
+	}
+	if envName == "" {
+		envName = os.Getenv("BLOUD_ENV")
+	}
+	return rest
+}
+
+// isRawCommand reports whether the named command takes its args verbatim
+func isRawCommand(name string) bool {
+	c := lookupCommand(name)
+	return c != nil && c.rawArgs
+}
+
+// printJSON writes v to stdout as indented JSON and returns the exit code
+func printJSON(v any) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		errorf("Failed to encode JSON: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -3,11 +3,18 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
 )
 
 const (
@@ -25,6 +32,7 @@ const (
 	// Installer ISO — live system runs bloud-installer on port 3001 as root (empty password)
 	pveInstallerPort    = "3001"
 	pveInstallerAPI     = "http://localhost:" + pveInstallerPort + "/api"
+	pveInstallerToken   = "/run/bloud-installer/setup-token" // root-only; guards install and reboot
 	pveInstallTimeout   = 600
 
 	// Disk provisioned for the test VM so the installer has a target
@@ -33,15 +41,15 @@ const (
 
 	// Build VM — persistent Ubuntu VM (VMID 9998) used to build ISOs locally.
 	// Ubuntu cloud image + Nix daemon: no manual OS install required.
-	pveBuildVMID      = "9998"
-	pveBuildVMName    = "bloud-builder"
-	pveBuildMemory    = 8192
-	pveBuildCores     = 4
-	pveBuildDisk      = "40G"
-	pveBuildDir       = "/root/bloud"
-	pveBuildImageURL  = "https://cloud-images.ubuntu.com/noble/current/noble-server-cloudimg-amd64.img"
-	pveBuildImageFile = "noble-server-cloudimg-amd64.img"
-	pveBuildKeyPath   = ".bloud/builder_rsa" // relative to $HOME
+	pveDefaultBuildVMID = "9998"
+	pveBuildVMName      = "bloud-builder"
+	pveBuildMemory      = 8192
+	pveBuildCores       = 4
+	pveBuildDisk        = "40G"
+	pveBuildDir         = "/root/bloud"
+	pveBuildImageURL    = "https://cloud-images.ubuntu.com/noble/current/noble-server-cloudimg-amd64.img"
+	pveBuildImageFile   = "noble-server-cloudimg-amd64.img"
+	pveBuildKeyPath     = ".bloud/builder_rsa" // relative to $HOME
 )
 
 type pveConfig struct {
@@ -51,6 +59,9 @@ type pveConfig struct {
 	Cores  int
 }
 
+// pveBuildVMID is the ISO build VM, overridable with BLOUD_PVE_BUILD_VMID
+var pveBuildVMID = pveDefaultBuildVMID
+
 func isPVEMode() bool {
 	return os.Getenv("BLOUD_PVE_HOST") != ""
 }
@@ -71,7 +82,7 @@ func getPVEConfig() pveConfig {
 // ── SSH helpers ────────────────────────────────────────────────────────────────
 
 func pveExec(cfg pveConfig, cmd string) (string, error) {
-	c := exec.Command("ssh",
+	c := vm.Command("ssh",
 		"-o", "ConnectTimeout=10",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -84,7 +95,7 @@ func pveExec(cfg pveConfig, cmd string) (string, error) {
 }
 
 func pveExecStream(cfg pveConfig, cmd string) error {
-	c := exec.Command("ssh",
+	c := vm.Command("ssh",
 		"-o", "ConnectTimeout=10",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -98,7 +109,22 @@ func pveExecStream(cfg pveConfig, cmd string) error {
 }
 
 func vmExec(ip, cmd string) (string, error) {
-	c := exec.Command("sshpass", "-p", pveVMSSHPass,
+	c := vm.Command("sshpass", "-p", pveVMSSHPass,
+		"ssh",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "ConnectTimeout=5",
+		"-o", "LogLevel=ERROR",
+		pveVMSSHUser+"@"+ip,
+		cmd,
+	)
+	output, err := c.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// vmExecContext is vmExec, with the SSH session killed when ctx is done
+func vmExecContext(ctx context.Context, ip, cmd string) (string, error) {
+	c := vm.CommandContext(ctx, "sshpass", "-p", pveVMSSHPass,
 		"ssh",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -112,7 +138,7 @@ func vmExec(ip, cmd string) (string, error) {
 }
 
 func vmExecStream(ip, cmd string) error {
-	c := exec.Command("sshpass", "-p", pveVMSSHPass,
+	c := vm.Command("sshpass", "-p", pveVMSSHPass,
 		"ssh",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -126,6 +152,24 @@ func vmExecStream(ip, cmd string) error {
 	return c.Run()
 }
 
+// vmExecPipe runs a command in the VM with stdin and stdout connected to the
+// given reader and writer, for streaming files in and out
+func vmExecPipe(ip, cmd string, stdin io.Reader, stdout io.Writer) error {
+	c := vm.Command("sshpass", "-p", pveVMSSHPass,
+		"ssh",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "ConnectTimeout=5",
+		"-o", "LogLevel=ERROR",
+		pveVMSSHUser+"@"+ip,
+		cmd,
+	)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
+
 func vmInteractive(ip, cmd string) error {
 	args := []string{
 		"-p", pveVMSSHPass,
@@ -139,7 +183,7 @@ func vmInteractive(ip, cmd string) error {
 	if cmd != "" {
 		args = append(args, cmd)
 	}
-	c := exec.Command("sshpass", args...)
+	c := vm.Command("sshpass", args...)
 	c.Stdin = os.Stdin
 	c.Stdout = os.Stdout
 	c.Stderr = os.Stderr
@@ -150,7 +194,7 @@ func vmInteractive(ip, cmd string) error {
 // The live installer ISO runs as root with an empty password (no bloud user).
 
 func isoExecStream(ip, cmd string) error {
-	c := exec.Command("sshpass", "-p", "",
+	c := vm.Command("sshpass", "-p", "",
 		"ssh",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -165,7 +209,7 @@ func isoExecStream(ip, cmd string) error {
 }
 
 func isoExec(ip, cmd string) (string, error) {
-	c := exec.Command("sshpass", "-p", "",
+	c := vm.Command("sshpass", "-p", "",
 		"ssh",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -185,7 +229,7 @@ func waitForISOReady(cfg pveConfig) string {
 	for i := 0; i < pveBootTimeout; i++ {
 		ip := getVMIP(cfg)
 		if ip != "" {
-			c := exec.Command("sshpass", "-p", "",
+			c := vm.Command("sshpass", "-p", "",
 				"ssh",
 				"-o", "StrictHostKeyChecking=no",
 				"-o", "UserKnownHostsFile=/dev/null",
@@ -207,11 +251,12 @@ func waitForISOReady(cfg pveConfig) string {
 	return ""
 }
 
-// runInstaller drives the installer API: auto-selects disk, triggers install,
-// polls until complete, updates Proxmox boot order to disk, then reboots.
-func runInstaller(cfg pveConfig, ip string) int {
+// driveInstaller runs an installation through the installer API of a live
+// installer environment, reached through exec and stream: it auto-selects
+// the disk, starts the install and follows it until it completes or fails.
+func driveInstaller(exec func(cmd string) (string, error), stream func(cmd string) error) int {
 	log("Getting disk info from installer...")
-	disk, err := isoExec(ip, "curl -sf "+pveInstallerAPI+"/disks | jq -r '.autoSelected'")
+	disk, err := exec("curl -sf "+pveInstallerAPI+"/disks | jq -r '.autoSelected'")
 	if err != nil || disk == "" || disk == "null" {
 		errorf("Failed to get auto-selected disk (got %q): %v", disk, err)
 		return 1
@@ -220,8 +265,8 @@ func runInstaller(cfg pveConfig, ip string) int {
 
 	log("Starting installation...")
 	installBody := fmt.Sprintf(`{"disk":"%s","encryption":false}`, disk)
-	out, err := isoExec(ip, fmt.Sprintf(
-		`curl -sf -X POST -H 'Content-Type: application/json' -d '%s' `+pveInstallerAPI+`/install`,
+	out, err := exec(fmt.Sprintf(
+		`curl -sf -X POST -H 'Content-Type: application/json' -H "X-Setup-Token: $(cat `+pveInstallerToken+`)" -d '%s' `+pveInstallerAPI+`/install`,
 		installBody,
 	))
 	if err != nil {
@@ -234,7 +279,7 @@ func runInstaller(cfg pveConfig, ip string) int {
 	// Stream SSE events from /api/progress in the background.
 	// The server closes the stream on complete or failed, so curl exits naturally.
 	go func() {
-		_ = isoExecStream(ip, fmt.Sprintf(
+		_ = stream(fmt.Sprintf(
 			`curl -N -sf %s/progress | while IFS= read -r line; do`+
 				`  if [[ "$line" == data:* ]]; then`+
 				`    json="${line#data: }";`+
@@ -249,17 +294,17 @@ func runInstaller(cfg pveConfig, ip string) int {
 
 	var lastPhase string
 	for i := 0; i < pveInstallTimeout; i += 2 {
-		phase, _ := isoExec(ip, "curl -sf "+pveInstallerAPI+"/status | jq -r '.phase'")
+		phase, _ := exec("curl -sf "+pveInstallerAPI+"/status | jq -r '.phase'")
 		if phase != lastPhase && phase != "" && phase != "null" {
 			lastPhase = phase
 		}
 		switch phase {
 		case "complete":
 			log("Installation complete")
-			goto installDone
+			return 0
 		case "failed":
 			// Fetch the last message for diagnostics
-			msg, _ := isoExec(ip, "curl -sf "+pveInstallerAPI+"/status | jq -r '.lastMessage // empty'")
+			msg, _ := exec("curl -sf "+pveInstallerAPI+"/status | jq -r '.lastMessage // empty'")
 			if msg != "" && msg != "null" {
 				fmt.Printf("  Last error: %s\n", msg)
 			}
@@ -273,8 +318,17 @@ func runInstaller(cfg pveConfig, ip string) int {
 	}
 	errorf("Timeout waiting for installation to complete (%ds)", pveInstallTimeout)
 	return 1
+}
+
+// runInstaller drives the installer API: auto-selects disk, triggers install,
+// polls until complete, updates Proxmox boot order to disk, then reboots.
+func runInstaller(cfg pveConfig, ip string) int {
+	exec := func(cmd string) (string, error) { return isoExec(ip, cmd) }
+	stream := func(cmd string) error { return isoExecStream(ip, cmd) }
+	if code := driveInstaller(exec, stream); code != 0 {
+		return code
+	}
 
-installDone:
 	// Update boot order and eject ISO in the config file.
 	log("Updating VM boot order to disk...")
 	if _, err := pveExec(cfg, fmt.Sprintf("qm set %s --boot 'order=sata0' --ide2 none,media=cdrom", cfg.VMID)); err != nil {
@@ -338,7 +392,7 @@ func waitForPVEVMReady(cfg pveConfig) string {
 	for i := 0; i < pveBootTimeout; i++ {
 		ip := getVMIP(cfg)
 		if ip != "" {
-			c := exec.Command("sshpass", "-p", pveVMSSHPass,
+			c := vm.Command("sshpass", "-p", pveVMSSHPass,
 				"ssh",
 				"-o", "StrictHostKeyChecking=no",
 				"-o", "UserKnownHostsFile=/dev/null",
@@ -390,36 +444,154 @@ func pveCleanOldVMs(cfg pveConfig) {
 // ── Health checks ──────────────────────────────────────────────────────────────
 
 type pveCheck struct {
-	name string
-	cmd  string
+	name    string
+	cmd     string
+	retries int // extra attempts for checks that race service startup
 }
 
 var pveChecks = []pveCheck{
-	{"bloud-pull-images completed", `systemctl --user show bloud-pull-images.service -p ActiveState --value | grep -qE 'active|inactive'`},
-	{"bloud-apps target is active", `systemctl --user is-active bloud-apps.target`},
-	{"host-agent service is active", `systemctl is-active bloud-host-agent.service`},
-	{"host-agent API responds", `curl -sf http://localhost:3000/api/health`},
-	{"traefik routes to host-agent", `curl -sf http://localhost:8080/api/health`},
-	{"web UI is served", `curl -sf http://localhost:8080/ | grep -q html`},
-	{"podman containers are running", `podman ps --format '{{.Names}}' | grep -q apps`},
-	{"mDNS is active", `systemctl is-active avahi-daemon.service`},
+	{"bloud-pull-images completed", `systemctl --user show bloud-pull-images.service -p ActiveState --value | grep -qE 'active|inactive'`, 0},
+	{"bloud-apps target is active", `systemctl --user is-active bloud-apps.target`, 0},
+	{"host-agent service is active", `systemctl is-active bloud-host-agent.service`, 0},
+	{"host-agent API responds", `curl -sf http://localhost:3000/api/health`, 2},
+	{"traefik routes to host-agent", `curl -sf http://localhost:8080/api/health`, 2},
+	{"web UI is served", `curl -sf http://localhost:8080/ | grep -q html`, 2},
+	{"podman containers are running", `podman ps --format '{{.Names}}' | grep -q apps`, 1},
+	{"mDNS is active", `systemctl is-active avahi-daemon.service`, 0},
 }
 
-func runPVEChecks(ip string) (passed, failed int) {
+const (
+	// pveDefaultCheckTimeout bounds one attempt of a health check
+	pveDefaultCheckTimeout = 30 * time.Second
+
+	// pveCheckRetryDelay is the pause before retrying a failed check
+	pveCheckRetryDelay = 3 * time.Second
+
+	// pveSlowestChecks is how many of the slowest checks the summary lists
+	pveSlowestChecks = 3
+)
+
+// pveCheckResult is the outcome of one health check
+type pveCheckResult struct {
+	Name     string  `json:"name"`
+	Passed   bool    `json:"passed"`
+	Attempts int     `json:"attempts"`
+	Seconds  float64 `json:"seconds"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// pveChecksReport is what ./bloud checks prints with --json
+type pveChecksReport struct {
+	IP      string           `json:"ip"`
+	Passed  int              `json:"passed"`
+	Failed  int              `json:"failed"`
+	Seconds float64          `json:"seconds"`
+	Checks  []pveCheckResult `json:"checks"`
+}
+
+func runPVEChecks(ip string, timeout time.Duration) (passed, failed int) {
+	report := collectPVEChecks(ip, timeout)
+	return report.Passed, report.Failed
+}
+
+// collectPVEChecks runs the health checks concurrently, each attempt
+// bounded by timeout, and prints the results with their durations unless
+// the report is going to be printed as JSON
+func collectPVEChecks(ip string, timeout time.Duration) pveChecksReport {
+	if !jsonOutput {
+		fmt.Println()
+		log(fmt.Sprintf("Running %d health checks...", len(pveChecks)))
+	}
+
+	start := time.Now()
+	results := make([]pveCheckResult, len(pveChecks))
+	var wg sync.WaitGroup
+	for i, c := range pveChecks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runPVECheck(ip, c, timeout)
+		}()
+	}
+	wg.Wait()
+
+	report := pveChecksReport{IP: ip, Checks: results, Seconds: time.Since(start).Seconds()}
+	for _, r := range results {
+		if r.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+
+	if !jsonOutput {
+		printPVECheckResults(report)
+	}
+	return report
+}
+
+// runPVECheck runs one check over SSH, retrying it up to c.retries times
+func runPVECheck(ip string, c pveCheck, timeout time.Duration) pveCheckResult {
+	result := pveCheckResult{Name: c.name}
+	start := time.Now()
+	for {
+		result.Attempts++
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		out, err := vmExecContext(ctx, ip, c.cmd)
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		cancel()
+
+		if err == nil {
+			result.Passed = true
+			result.Error = ""
+			break
+		}
+		result.Error = err.Error()
+		if out != "" {
+			result.Error = out
+		}
+		if result.Attempts > c.retries {
+			break
+		}
+		time.Sleep(pveCheckRetryDelay)
+	}
+	result.Seconds = time.Since(start).Seconds()
+	return result
+}
+
+func printPVECheckResults(report pveChecksReport) {
 	fmt.Println()
-	log("Running health checks...")
+	for _, r := range report.Checks {
+		status := colorGreen + "PASS" + colorReset
+		if !r.Passed {
+			status = colorRed + "FAIL" + colorReset
+		}
+		attempts := ""
+		if r.Attempts > 1 {
+			attempts = fmt.Sprintf(", %d attempts", r.Attempts)
+		}
+		fmt.Printf("  %s %-32s %5.1fs%s\n", status, r.Name, r.Seconds, attempts)
+		if !r.Passed && r.Error != "" {
+			fmt.Printf("       %s\n", r.Error)
+		}
+	}
+
+	slowest := slices.Clone(report.Checks)
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].Seconds > slowest[j].Seconds })
+	if len(slowest) > pveSlowestChecks {
+		slowest = slowest[:pveSlowestChecks]
+	}
 	fmt.Println()
-	for _, c := range pveChecks {
-		fmt.Printf("  Checking %s... ", c.name)
-		if _, err := vmExec(ip, c.cmd); err == nil {
-			fmt.Printf("%sPASS%s\n", colorGreen, colorReset)
-			passed++
-		} else {
-			fmt.Printf("%sFAIL%s\n", colorRed, colorReset)
-			failed++
+	fmt.Printf("  Checks took %.1fs. Slowest:", report.Seconds)
+	for i, r := range slowest {
+		if i > 0 {
+			fmt.Print(",")
 		}
+		fmt.Printf(" %s (%.1fs)", r.Name, r.Seconds)
 	}
-	return
+	fmt.Println()
 }
 
 func printPVEResults(ip string, passed, failed int) {
@@ -442,7 +614,7 @@ func printPVEResults(ip string, passed, failed int) {
 func doDeploy(cfg pveConfig, isoSource string) int {
 	if isoSource == "" {
 		log("Finding latest GitHub release...")
-		out, err := exec.Command("gh", "release", "view", "--json", "assets",
+		out, err := vm.Command("gh", "release", "view", "--json", "assets",
 			"--jq", `[.assets[] | select(.name | endswith(".iso"))] | last | .url`,
 		).Output()
 		if err != nil || strings.TrimSpace(string(out)) == "" {
@@ -461,7 +633,7 @@ func doDeploy(cfg pveConfig, isoSource string) int {
 		}
 	} else {
 		log("Copying ISO to Proxmox...")
-		c := exec.Command("scp", isoSource, cfg.Host+":"+pveISOStorage+"/"+pveISOFilename)
+		c := vm.Command("scp", isoSource, cfg.Host+":"+pveISOStorage+"/"+pveISOFilename)
 		c.Stdout = os.Stdout
 		c.Stderr = os.Stderr
 		if err := c.Run(); err != nil {
@@ -499,13 +671,13 @@ func ensureBuilderKey() error {
 	if err := os.MkdirAll(filepath.Dir(privKey), 0700); err != nil {
 		return err
 	}
-	return exec.Command("ssh-keygen", "-t", "ed25519", "-f", privKey, "-N", "", "-C", "bloud-builder").Run()
+	return vm.Command("ssh-keygen", "-t", "ed25519", "-f", privKey, "-N", "", "-C", "bloud-builder").Run()
 }
 
 // builderExec runs a command on the build VM as root using the SSH keypair.
 func builderExec(ip, cmd string) (string, error) {
 	privKey, _ := builderKeyPaths()
-	c := exec.Command("ssh",
+	c := vm.Command("ssh",
 		"-i", privKey,
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -520,7 +692,7 @@ func builderExec(ip, cmd string) (string, error) {
 
 func builderExecStream(ip, cmd string) error {
 	privKey, _ := builderKeyPaths()
-	c := exec.Command("ssh",
+	c := vm.Command("ssh",
 		"-i", privKey,
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -540,7 +712,7 @@ func waitForBuilderSSH(bc pveConfig) string {
 	for i := 0; i < pveBootTimeout; i++ {
 		ip := getVMIP(bc)
 		if ip != "" {
-			c := exec.Command("ssh",
+			c := vm.Command("ssh",
 				"-i", privKey,
 				"-o", "StrictHostKeyChecking=no",
 				"-o", "UserKnownHostsFile=/dev/null",
@@ -657,7 +829,7 @@ func createBuilderVM(cfg pveConfig) int {
 
 	log("Configuring root SSH key access...")
 	// Upload public key to Proxmox, then pass it to cloud-init
-	scpKey := exec.Command("scp",
+	scpKey := vm.Command("scp",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
 		"-o", "LogLevel=ERROR",
@@ -728,6 +900,41 @@ echo 'Provisioning complete.'`
 	return 0
 }
 
+// isoSyncExcludes keeps build output, dependencies and VM images out of
+// the copy of the checkout an ISO is built from
+var isoSyncExcludes = []string{
+	"--exclude=build/",
+	"--exclude=node_modules/",
+	"--exclude=.direnv/",
+	"--exclude=lima/imgs/",
+}
+
+// isoBuildScript builds the host-agent and frontend into build/, stages
+// them for Nix and builds the ISO in a checkout at dir. The build
+// directory's git index is changed, so dir is never the user's checkout.
+func isoBuildScript(dir string) string {
+	return fmt.Sprintf(`set -e
+export PATH="$PATH:/usr/local/go/bin:/nix/var/nix/profiles/default/bin"
+cd %s
+mkdir -p build
+
+echo '==> Building Go binary...'
+cd services/host-agent
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o ../../build/host-agent ./cmd/host-agent
+cd ../..
+
+echo '==> Building frontend...'
+npm ci --prefer-offline
+npm run build --workspace=services/host-agent/web
+cp -r services/host-agent/web/build build/frontend
+
+echo '==> Staging artifacts for Nix...'
+git add -f build/
+
+echo '==> Building ISO...'
+nix build .#packages.x86_64-linux.iso --no-link`, dir)
+}
+
 // doBuild rsyncs source to the build VM, builds the ISO, then copies it
 // Mac→Proxmox ISO storage — replacing the normal ISO download step.
 func doBuild(cfg pveConfig) int {
@@ -762,14 +969,11 @@ func doBuild(cfg pveConfig) int {
 	privKey, _ := builderKeyPaths()
 
 	log("Syncing source to build VM...")
-	rsync := exec.Command("rsync", "-av", "--delete",
-		"--exclude=build/",
-		"--exclude=node_modules/",
-		"--exclude=.direnv/",
+	rsync := vm.Command("rsync", append(append([]string{"-av", "--delete"}, isoSyncExcludes...),
 		"-e", fmt.Sprintf("ssh -i %s -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o LogLevel=ERROR", privKey),
 		root+"/",
 		"root@"+ip+":"+pveBuildDir+"/",
-	)
+	)...)
 	rsync.Stdout = os.Stdout
 	rsync.Stderr = os.Stderr
 	if err := rsync.Run(); err != nil {
@@ -778,26 +982,7 @@ func doBuild(cfg pveConfig) int {
 	}
 
 	log("Building ISO (first build may take 15-30 minutes)...")
-	buildScript := fmt.Sprintf(`set -e
-export PATH="$PATH:/usr/local/go/bin:/nix/var/nix/profiles/default/bin"
-cd %s
-mkdir -p build
-
-echo '==> Building Go binary...'
-cd services/host-agent
-CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -o ../../build/host-agent ./cmd/host-agent
-cd ../..
-
-echo '==> Building frontend...'
-npm ci --prefer-offline
-npm run build --workspace=services/host-agent/web
-cp -r services/host-agent/web/build build/frontend
-
-echo '==> Staging artifacts for Nix...'
-git add -f build/
-
-echo '==> Building ISO...'
-nix build .#packages.x86_64-linux.iso --no-link`, pveBuildDir)
+	buildScript := isoBuildScript(pveBuildDir)
 
 	if err := builderExecStream(ip, buildScript); err != nil {
 		errorf("ISO build failed: %v", err)
@@ -824,7 +1009,7 @@ nix build .#packages.x86_64-linux.iso --no-link`, pveBuildDir)
 	// Copy ISO: build VM → Mac → Proxmox
 	localISO := "/tmp/bloud-built.iso"
 	log("Downloading ISO from build VM...")
-	scpDown := exec.Command("scp",
+	scpDown := vm.Command("scp",
 		"-i", privKey,
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -840,8 +1025,89 @@ nix build .#packages.x86_64-linux.iso --no-link`, pveBuildDir)
 	}
 	defer os.Remove(localISO)
 
+	return uploadISO(cfg, localISO)
+}
+
+// localISOBuildDir is where start --build copies the checkout to build the
+// ISO on this host, mirroring pveBuildDir on the build VM
+func localISOBuildDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "bloud", "iso-build")
+}
+
+// checkLocalISOBuild reports why this host can't build the x86_64 ISO
+// itself, or nil when it can: Linux on x86_64 with Nix, KVM and the
+// tools the build script uses
+func checkLocalISOBuild() error {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		return fmt.Errorf("not an x86_64 Linux host")
+	}
+	for _, tool := range []string{"nix", "go", "npm", "git", "rsync"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("%s is not installed", tool)
+		}
+	}
+	kvm, err := os.OpenFile("/dev/kvm", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("/dev/kvm is not accessible")
+	}
+	kvm.Close()
+	return nil
+}
+
+// doBuildLocal builds the ISO on this host with the build VM's script, in
+// a copy of the checkout so staging build/ leaves the user's git index
+// alone, then uploads it to Proxmox like doBuild
+func doBuildLocal(cfg pveConfig) int {
+	root, err := getProjectRoot()
+	if err != nil {
+		errorf("Could not find project root: %v", err)
+		return 1
+	}
+
+	dir := localISOBuildDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		errorf("Failed to create %s: %v", dir, err)
+		return 1
+	}
+
+	log(fmt.Sprintf("Syncing source to %s...", dir))
+	rsync := vm.Command("rsync", append(append([]string{"-a", "--delete"}, isoSyncExcludes...), root+"/", dir+"/")...)
+	rsync.Stdout = os.Stdout
+	rsync.Stderr = os.Stderr
+	if err := rsync.Run(); err != nil {
+		errorf("Failed to sync source: %v", err)
+		return 1
+	}
+
+	log("Building ISO locally (first build may take 15-30 minutes)...")
+	if err := vm.LocalExecStream(isoBuildScript(dir)); err != nil {
+		errorf("ISO build failed: %v", err)
+		return 1
+	}
+
+	// Get the store path from cache (instant — build already done above)
+	out, err := vm.LocalExec(fmt.Sprintf("cd '%s' && nix build .#packages.x86_64-linux.iso --no-link --print-out-paths", dir))
+	storePath := strings.TrimSpace(out)
+	if err != nil || storePath == "" {
+		errorf("Failed to get ISO store path: %v", err)
+		return 1
+	}
+	isos, _ := filepath.Glob(filepath.Join(storePath, "iso", "*.iso"))
+	if len(isos) == 0 {
+		errorf("Could not find .iso file in %s/iso", storePath)
+		return 1
+	}
+
+	log(fmt.Sprintf("ISO built: %s", isos[0]))
+	return uploadISO(cfg, isos[0])
+}
+
+// uploadISO copies a locally built ISO to Proxmox's ISO storage, where
+// doDeploy would otherwise have downloaded it
+func uploadISO(cfg pveConfig, localISO string) int {
 	log("Uploading ISO to Proxmox...")
-	scpUp := exec.Command("scp",
+	scpUp := vm.Command("scp",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
 		"-o", "LogLevel=ERROR",
@@ -897,37 +1163,29 @@ func cmdSetupBuilderPVE() int {
 
 // ── Commands ───────────────────────────────────────────────────────────────────
 
+// pveStartOptions are the flags of ./bloud start in Proxmox mode
+type pveStartOptions struct {
+	build      bool
+	skipDeploy bool
+	host       string // overrides BLOUD_PVE_HOST
+	vmid       string // overrides BLOUD_PVE_VMID
+	isoSource  string
+
+	checkTimeout time.Duration // per attempt of each health check
+}
+
 // cmdStartPVE is the main ISO test lifecycle:
 // deploy ISO → clean old VMs → create VM → boot → wait for services → checks
 // VM stays running after checks. Flags: --skip-deploy (reuse existing VM)
-func cmdStartPVE(args []string) int {
+func cmdStartPVE(opts pveStartOptions) int {
 	cfg := getPVEConfig()
-	build := false
-	skipDeploy := false
-	isoSource := ""
-
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "--build":
-			build = true
-		case "--skip-deploy":
-			skipDeploy = true
-		case "--pve-host":
-			if i+1 < len(args) {
-				cfg.Host = args[i+1]
-				i++
-			}
-		case "--vmid":
-			if i+1 < len(args) {
-				cfg.VMID = args[i+1]
-				i++
-			}
-		default:
-			if !strings.HasPrefix(args[i], "-") {
-				isoSource = args[i]
-			}
-		}
+	if opts.host != "" {
+		cfg.Host = opts.host
+	}
+	if opts.vmid != "" {
+		cfg.VMID = opts.vmid
 	}
+	build, skipDeploy, isoSource := opts.build, opts.skipDeploy, opts.isoSource
 
 	printVMInfo := func() {
 		fmt.Printf("  VM is running. To tear down: ./bloud destroy\n")
@@ -937,7 +1195,12 @@ func cmdStartPVE(args []string) int {
 
 	if !skipDeploy {
 		if build {
-			if code := doBuild(cfg); code != 0 {
+			buildISO := doBuildLocal
+			if err := checkLocalISOBuild(); err != nil {
+				log(fmt.Sprintf("Building on the build VM (can't build locally: %v)", err))
+				buildISO = doBuild
+			}
+			if code := buildISO(cfg); code != 0 {
 				return code
 			}
 		} else {
@@ -997,7 +1260,7 @@ func cmdStartPVE(args []string) int {
 
 	ctx, cancelJournal := context.WithCancel(context.Background())
 	go func() {
-		c := exec.CommandContext(ctx, "sshpass", "-p", pveVMSSHPass,
+		c := vm.CommandContext(ctx, "sshpass", "-p", pveVMSSHPass,
 			"ssh",
 			"-o", "StrictHostKeyChecking=no",
 			"-o", "UserKnownHostsFile=/dev/null",
@@ -1032,7 +1295,7 @@ func cmdStartPVE(args []string) int {
 		warn("Timeout waiting for services — running checks anyway")
 	}
 
-	passed, failed := runPVEChecks(vmIP)
+	passed, failed := runPVEChecks(vmIP, opts.checkTimeout)
 
 	// Extra diagnostics
 	fmt.Println()
@@ -1085,21 +1348,70 @@ func cmdDestroyBuilderPVE() int {
 	return 0
 }
 
+// pveStatus is what ./bloud status reports in Proxmox mode
+type pveStatus struct {
+	Backend   string            `json:"backend"`
+	Host      string            `json:"host"`
+	VMID      string            `json:"vmid"`
+	VM        string            `json:"vm"` // running, stopped or not_created
+	IP        string            `json:"ip,omitempty"`
+	Services  map[string]string `json:"services,omitempty"` // unit -> ActiveState
+	HostAgent bool              `json:"hostAgent"`
+}
+
+func collectPVEStatus(cfg pveConfig) pveStatus {
+	status := pveStatus{Backend: "proxmox", Host: cfg.Host, VMID: cfg.VMID}
+
+	switch {
+	case !pveVMExists(cfg):
+		status.VM = "not_created"
+		return status
+	case !pveVMIsRunning(cfg):
+		status.VM = "stopped"
+		return status
+	}
+	status.VM = "running"
+
+	status.IP = getVMIP(cfg)
+	if status.IP == "" {
+		return status
+	}
+
+	status.Services = make(map[string]string)
+	for _, name := range []string{"bloud-host-agent", "bloud-apps.target", "avahi-daemon"} {
+		scope := "--user"
+		if name == "bloud-host-agent" || name == "avahi-daemon" {
+			scope = ""
+		}
+		out, _ := vmExec(status.IP, fmt.Sprintf("systemctl %s is-active %s.service 2>/dev/null || systemctl %s is-active %s 2>/dev/null", scope, name, scope, name))
+		status.Services[name] = strings.TrimSpace(out)
+	}
+
+	out, _ := vmExec(status.IP, "curl -sf http://localhost:3000/api/health 2>/dev/null")
+	status.HostAgent = strings.Contains(out, "ok")
+
+	return status
+}
+
 func cmdStatusPVE() int {
 	cfg := getPVEConfig()
+	status := collectPVEStatus(cfg)
+	if jsonOutput {
+		return printJSON(status)
+	}
+
 	fmt.Println()
 	fmt.Printf("  Backend:  %sProxmox%s (%s)\n", colorCyan, colorReset, cfg.Host)
 	fmt.Printf("  VMID:     %s\n", cfg.VMID)
 	fmt.Println()
 
-	if !pveVMExists(cfg) {
+	switch status.VM {
+	case "not_created":
 		fmt.Printf("  VM:       %sNot created%s\n", colorRed, colorReset)
 		fmt.Println()
 		fmt.Println("  Run './bloud start [iso]' to deploy and boot a VM")
 		return 0
-	}
-
-	if !pveVMIsRunning(cfg) {
+	case "stopped":
 		fmt.Printf("  VM:       %sStopped%s\n", colorYellow, colorReset)
 		fmt.Println()
 		fmt.Println("  Run './bloud start --skip-deploy' to boot the existing VM")
@@ -1108,24 +1420,18 @@ func cmdStatusPVE() int {
 
 	fmt.Printf("  VM:       %sRunning%s\n", colorGreen, colorReset)
 
-	ip := getVMIP(cfg)
-	if ip == "" {
+	if status.IP == "" {
 		fmt.Printf("  IP:       %sUnknown (no guest agent?)\n%s", colorYellow, colorReset)
 	} else {
-		fmt.Printf("  IP:       %s%s%s\n", colorGreen, ip, colorReset)
+		fmt.Printf("  IP:       %s%s%s\n", colorGreen, status.IP, colorReset)
 	}
 
-	if ip != "" {
+	if status.IP != "" {
 		fmt.Println()
 		log("Service status:")
 
 		for _, name := range []string{"bloud-host-agent", "bloud-apps.target", "avahi-daemon"} {
-			scope := "--user"
-			if name == "bloud-host-agent" || name == "avahi-daemon" {
-				scope = ""
-			}
-			out, _ := vmExec(ip, fmt.Sprintf("systemctl %s is-active %s.service 2>/dev/null || systemctl %s is-active %s 2>/dev/null", scope, name, scope, name))
-			state := strings.TrimSpace(out)
+			state := status.Services[name]
 			color := colorRed
 			if state == "active" {
 				color = colorGreen
@@ -1133,8 +1439,7 @@ func cmdStatusPVE() int {
 			fmt.Printf("  %-30s %s%s%s\n", name, color, state, colorReset)
 		}
 
-		out, _ := vmExec(ip, "curl -sf http://localhost:3000/api/health 2>/dev/null")
-		if strings.Contains(out, "ok") {
+		if status.HostAgent {
 			fmt.Printf("  %-30s %srunning%s\n", "host-agent API", colorGreen, colorReset)
 		} else {
 			fmt.Printf("  %-30s %snot responding%s\n", "host-agent API", colorYellow, colorReset)
@@ -1184,7 +1489,7 @@ func cmdShellPVE(args []string) int {
 	return 0
 }
 
-func cmdChecksPVE() int {
+func cmdChecksPVE(timeout time.Duration) int {
 	cfg := getPVEConfig()
 	if !pveVMIsRunning(cfg) {
 		errorf("VM is not running. Start with: ./bloud start [iso]")
@@ -1195,19 +1500,19 @@ func cmdChecksPVE() int {
 		errorf("Could not get VM IP (is the guest agent running?)")
 		return 1
 	}
-	passed, failed := runPVEChecks(ip)
-	printPVEResults(ip, passed, failed)
-	if failed > 0 {
+	report := collectPVEChecks(ip, timeout)
+	if jsonOutput {
+		printJSON(report)
+	} else {
+		printPVEResults(ip, report.Passed, report.Failed)
+	}
+	if report.Failed > 0 {
 		return 1
 	}
 	return 0
 }
 
 func cmdInstallPVE(args []string) int {
-	if len(args) < 1 {
-		errorf("Usage: ./bloud install <app-name>")
-		return 1
-	}
 	cfg := getPVEConfig()
 	if !pveVMIsRunning(cfg) {
 		errorf("VM is not running. Start with: ./bloud start [iso]")
@@ -1221,7 +1526,7 @@ func cmdInstallPVE(args []string) int {
 	appName := args[0]
 	log(fmt.Sprintf("Installing %s...", appName))
 	out, err := vmExec(ip, fmt.Sprintf(
-		`curl -s -X POST -w "\n%%{http_code}" http://localhost:3000/api/apps/%s/install`, appName,
+		`curl -s -X POST -w "\n%%{http_code}" http://localhost:3000/api/v1/apps/%s/install`, appName,
 	))
 	if err != nil {
 		errorf("Failed to call install API: %v", err)
@@ -1240,10 +1545,6 @@ func cmdInstallPVE(args []string) int {
 }
 
 func cmdUninstallPVE(args []string) int {
-	if len(args) < 1 {
-		errorf("Usage: ./bloud uninstall <app-name>")
-		return 1
-	}
 	cfg := getPVEConfig()
 	if !pveVMIsRunning(cfg) {
 		errorf("VM is not running. Start with: ./bloud start [iso]")
@@ -1257,7 +1558,7 @@ func cmdUninstallPVE(args []string) int {
 	appName := args[0]
 	log(fmt.Sprintf("Uninstalling %s...", appName))
 	out, err := vmExec(ip, fmt.Sprintf(
-		`curl -s -X POST -w "\n%%{http_code}" http://localhost:3000/api/apps/%s/uninstall`, appName,
+		`curl -s -X POST -w "\n%%{http_code}" http://localhost:3000/api/v1/apps/%s/uninstall`, appName,
 	))
 	if err != nil {
 		errorf("Failed to call uninstall API: %v", err)
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/apiclient"
+)
+
+const remoteRequestTimeout = 30 * time.Second
+
+// remoteHost is set by the global --host flag, falling back to BLOUD_HOST.
+// When set, install, uninstall, status and logs talk to that appliance's API
+// over the network instead of a local Lima or Proxmox VM.
+var remoteHost string
+
+// remoteAPI calls a Bloud appliance's host-agent API directly with the
+// generated API client, authenticating with a personal access token from
+// POST /api/v1/auth/tokens
+type remoteAPI struct {
+	client   *apiclient.Client
+	hasToken bool
+}
+
+// newRemoteAPI builds a client for host, which may be a bare hostname
+// (bloud.local) or a URL (https://bloud.example.com). The token comes from
+// BLOUD_TOKEN or the config file's tokens.<host>, so it stays out of shell
+// history.
+func newRemoteAPI(host string) (*remoteAPI, error) {
+	token := os.Getenv("BLOUD_TOKEN")
+	if token == "" {
+		token = userConfig.Tokens[host]
+	}
+
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid host %q", host)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q (use http or https)", u.Scheme)
+	}
+
+	// Requests are bounded by their context instead: installs and log
+	// streams have no timeout
+	client := apiclient.New(u.String())
+	client.HTTPClient = &http.Client{}
+	if token != "" {
+		client.SetToken(token)
+	}
+	return &remoteAPI{client: client, hasToken: token != ""}, nil
+}
+
+// remoteContext bounds a request that should answer promptly
+func remoteContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), remoteRequestTimeout)
+}
+
+// remoteError explains a failed request, with a hint for the
+// authentication failures a missing or weak token causes
+func (a *remoteAPI) remoteError(err error) error {
+	var apiErr *apiclient.Error
+	if !errors.As(err, &apiErr) {
+		return fmt.Errorf("%s not reachable: %w", remoteHost, err)
+	}
+	msg := apiErr.Message
+	if msg == "" {
+		msg = strings.TrimSpace(string(apiErr.Body))
+	}
+
+	code := apiErr.StatusCode
+	switch code {
+	case http.StatusUnauthorized:
+		if !a.hasToken {
+			return fmt.Errorf("HTTP %d: %s (set BLOUD_TOKEN or ./bloud config set tokens.%s <token>)", code, msg, remoteHost)
+		}
+		return fmt.Errorf("HTTP %d: %s (check the API token)", code, msg)
+	case http.StatusForbidden:
+		return fmt.Errorf("HTTP %d: %s (installing and removing apps needs a token with the write and admin scopes)", code, msg)
+	}
+	return fmt.Errorf("HTTP %d: %s", code, msg)
+}
+
+// cmdRemote runs a command marked remote in the registry against the
+// appliance named by --host or BLOUD_HOST
+func cmdRemote(cmd string, args []string) int {
+	api, err := newRemoteAPI(remoteHost)
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	switch cmd {
+	case "install", "uninstall":
+		return remoteInstall(api, cmd, args[0])
+	case "status":
+		return remoteStatus(api)
+	default:
+		if len(args) < 1 {
+			errorf("Usage: ./bloud --host <host> logs <app>")
+			return exitUsage
+		}
+		return remoteLogs(api, args[0])
+	}
+}
+
+// remoteInstall calls the install or uninstall endpoint for an app
+func remoteInstall(api *remoteAPI, action, appName string) int {
+	verb := "Installing"
+	if action == "uninstall" {
+		verb = "Uninstalling"
+	}
+	log(fmt.Sprintf("%s %s on %s...", verb, appName, remoteHost))
+
+	// No timeout: the request returns once the NixOS rebuild finishes
+	var result any
+	var err error
+	if action == "install" {
+		result, err = api.client.InstallApp(context.Background(), appName, apiclient.InstallAppRequest{})
+	} else {
+		result, err = api.client.UninstallApp(context.Background(), appName, apiclient.UninstallAppRequest{})
+	}
+	if err != nil {
+		errorf("%s failed: %v", strings.ToUpper(action[:1])+action[1:], api.remoteError(err))
+		return 1
+	}
+
+	log(fmt.Sprintf("Successfully %sed %s", action, appName))
+	data, _ := json.Marshal(result)
+	fmt.Println(string(data))
+	return 0
+}
+
+// remoteStatusReport is the JSON form of "./bloud --host <host> status"
+type remoteStatusReport struct {
+	Host   string                          `json:"host"`
+	System *apiclient.Stats                `json:"system,omitempty"`
+	Health apiclient.HealthSummaryResponse `json:"health"`
+}
+
+// fetchHealthSummary reads the health summary, which answers 503 with a
+// full body when a critical check fails
+func fetchHealthSummary(ctx context.Context, client *apiclient.Client) (*apiclient.HealthSummaryResponse, error) {
+	summary, err := client.GetHealthSummary(ctx)
+	var apiErr *apiclient.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable {
+		var down apiclient.HealthSummaryResponse
+		if json.Unmarshal(apiErr.Body, &down) == nil && down.Status != "" {
+			return &down, nil
+		}
+	}
+	return summary, err
+}
+
+// remoteStatus shows the appliance's health summary and resource usage
+func remoteStatus(api *remoteAPI) int {
+	report := remoteStatusReport{Host: remoteHost}
+
+	ctx, cancel := remoteContext()
+	defer cancel()
+	health, err := fetchHealthSummary(ctx, api.client)
+	if err != nil {
+		errorf("Failed to get status: %v", api.remoteError(err))
+		return 1
+	}
+	report.Health = *health
+	if stats, err := api.client.GetSystemStatus(ctx); err == nil {
+		report.System = stats
+	}
+
+	if jsonOutput {
+		printJSON(report)
+	} else {
+		printRemoteStatus(report)
+	}
+
+	if report.Health.Status == "down" {
+		return 1
+	}
+	return 0
+}
+
+func printRemoteStatus(report remoteStatusReport) {
+	fmt.Printf("=== %s ===\n", report.Host)
+	fmt.Printf("Status: %s\n", colorHealth(report.Health.Status, 0))
+	if report.System != nil {
+		fmt.Printf("CPU: %d%%  Memory: %d%%  Disk: %d%%\n", report.System.CPU, report.System.Memory, report.System.Disk)
+	}
+	fmt.Println()
+
+	fmt.Println("Checks:")
+	for _, check := range report.Health.Checks {
+		detail := check.Detail
+		if check.Error != "" {
+			detail = check.Error
+		}
+		fmt.Printf("  %-16s %s %s\n", check.Name, colorHealth(check.Status, 8), detail)
+	}
+	fmt.Println()
+
+	if len(report.Health.Apps) == 0 {
+		fmt.Println("No apps installed")
+		return
+	}
+	fmt.Println("Apps:")
+	for _, app := range report.Health.Apps {
+		health := "-"
+		if app.LastCheck != nil {
+			health = colorHealth("healthy", 0)
+			if !app.LastCheck.Healthy {
+				health = colorHealth("unhealthy", 0)
+			}
+		}
+		fmt.Printf("  %-20s %s %s\n", app.Name, colorStatus(app.Status, 12), health)
+	}
+}
+
+// colorHealth colors a health summary or check status, padded to width
+func colorHealth(status string, width int) string {
+	color := colorRed
+	switch status {
+	case "ok", "healthy":
+		color = colorGreen
+	case "degraded", "skipped":
+		color = colorYellow
+	}
+	return color + fmt.Sprintf("%-*s", width, status) + colorReset
+}
+
+// remoteLogs follows an app's logs from the appliance's log stream until
+// interrupted
+func remoteLogs(api *remoteAPI, appName string) int {
+	// No timeout: the stream stays open until interrupted
+	resp, err := api.client.StreamAppLogs(context.Background(), appName)
+	if err != nil {
+		errorf("Failed to stream logs: %v", api.remoteError(err))
+		return 1
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			fmt.Println(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errorf("Log stream ended: %v", err)
+		return 1
+	}
+	return 0
+}
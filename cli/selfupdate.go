@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// Release builds are published to the latest release by build-iso.yml,
+	// with one sha256sum line per binary in cliChecksumsAsset and its
+	// ssh-keygen -Y signature in cliSignatureAsset
+	cliReleaseURL     = "https://github.com/d-buckner/bloud/releases/latest/download"
+	cliChecksumsAsset = "bloud-cli.sha256"
+	cliSignatureAsset = cliChecksumsAsset + ".sig"
+	selfUpdateTimeout = 5 * time.Minute
+	devBuildVersion   = "dev"
+
+	// cliSigningKey is the public half of the CLI_SIGNING_KEY secret
+	// build-iso.yml signs the checksums with, in the cliSigningNamespace
+	cliSigningKey       = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDx8UZWiVU4Wz1Rt46wJMqWEwU4D4BtoH1fLD2sSACjH bloud-cli release"
+	cliSigningNamespace = "bloud-cli"
+)
+
+// version identifies the CLI build; release builds set it to their commit
+// with -ldflags "-X main.version=<sha>"
+var version = devBuildVersion
+
+// cliAssetName is the release binary for this OS and architecture
+func cliAssetName() string {
+	return fmt.Sprintf("bloud-%s-%s", runtime.GOOS, runtime.GOARCH)
+}
+
+// cmdSelfUpdate replaces the running binary with the latest release build
+// when its checksum differs. The release's checksums must carry a signature
+// from cliSigningKey, and the download is verified against them before it
+// is renamed over the binary, so a tampered, interrupted or corrupted
+// update leaves the old one in place.
+func cmdSelfUpdate(check, force bool) int {
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		errorf("Cannot find the running binary: %v", err)
+		return 1
+	}
+
+	log("Checking the latest release...")
+	sums, err := fetchCLIChecksums()
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+	asset := cliAssetName()
+	want, ok := sums[asset]
+	if !ok {
+		errorf("The latest release has no CLI build for %s/%s", runtime.GOOS, runtime.GOARCH)
+		return 1
+	}
+
+	current, err := fileSHA256(exe)
+	if err != nil {
+		errorf("Failed to read %s: %v", exe, err)
+		return 1
+	}
+	if current == want {
+		log(fmt.Sprintf("Already up to date (%s)", version))
+		return 0
+	}
+	if check {
+		log(fmt.Sprintf("An update is available (current: %s). Run: ./bloud self-update", version))
+		return 0
+	}
+
+	// A source build belongs to its checkout; npm run cli:build would
+	// overwrite the release binary again
+	if version == devBuildVersion && !force {
+		errorf("This CLI was built from source. Rebuild it with: npm run cli:build")
+		fmt.Println("  Or pass --force to replace it with the release build")
+		return 1
+	}
+
+	if err := replaceBinary(exe, cliReleaseURL+"/"+asset, want); err != nil {
+		errorf("Update failed: %v", err)
+		return 1
+	}
+	log(fmt.Sprintf("Updated %s", exe))
+	return 0
+}
+
+// fetchCLIChecksums downloads the release's sha256sum file as asset -> hash,
+// once its signature checks out against cliSigningKey
+func fetchCLIChecksums() (map[string]string, error) {
+	checksums, err := fetchReleaseAsset(cliChecksumsAsset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release checksums: %w", err)
+	}
+	signature, err := fetchReleaseAsset(cliSignatureAsset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the release checksums' signature: %w", err)
+	}
+	if err := verifySSHSignature(cliSigningKey, cliSigningNamespace, checksums, signature); err != nil {
+		return nil, fmt.Errorf("the release checksums aren't signed by the Bloud release key: %w", err)
+	}
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read release checksums: %w", err)
+	}
+	return sums, nil
+}
+
+// fetchReleaseAsset downloads a small asset of the latest release
+func fetchReleaseAsset(name string) ([]byte, error) {
+	client := &http.Client{Timeout: remoteRequestTimeout}
+	resp, err := client.Get(cliReleaseURL + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replaceBinary downloads url next to exe, checks it against sum and
+// renames it over exe, which is atomic within a directory
+func replaceBinary(exe, url, sum string) error {
+	client := &http.Client{Timeout: selfUpdateTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".bloud-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w", filepath.Dir(exe), err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	h := sha256.New()
+	_, err = copyWithProgress(io.MultiWriter(tmp, h), resp.Body, resp.ContentLength, "  Downloading")
+	fmt.Println()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != sum {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", got, sum)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), exe)
+}
@@ -135,25 +135,43 @@ func cmdSetupLima() int {
 	// Check each prerequisite
 	allGood := true
 
-	// 1. Check Lima
-	fmt.Print("  Checking Lima...              ")
-	if checkCommand("limactl") {
-		fmt.Printf("%s✓ installed%s\n", colorGreen, colorReset)
+	// 1. Check Lima, or libvirt when it is the VM backend
+	if vm.GetBackend() == vm.BackendLibvirt {
+		fmt.Print("  Checking libvirt...           ")
+		if !printSetupChecks(vm.RunLibvirtPreflightChecks(), "installed") {
+			allGood = false
+		}
 	} else {
-		fmt.Printf("%s✗ not installed%s\n", colorRed, colorReset)
-		printInstallHint("lima")
-		allGood = false
+		fmt.Print("  Checking Lima...              ")
+		if checkCommand("limactl") {
+			fmt.Printf("%s✓ installed%s\n", colorGreen, colorReset)
+		} else {
+			fmt.Printf("%s✗ not installed%s\n", colorRed, colorReset)
+			printInstallHint("lima")
+			allGood = false
+		}
 	}
 
-	// 2. Check sshpass (optional - only needed if SSH key auth fails)
-	fmt.Print("  Checking sshpass...           ")
-	if checkCommand("sshpass") {
-		fmt.Printf("%s✓ installed%s\n", colorGreen, colorReset)
-	} else {
-		fmt.Printf("%s○ not installed (optional)%s\n", colorYellow, colorReset)
-		fmt.Printf("     SSH key auth will be used instead. sshpass is only needed\n")
-		fmt.Printf("     as a fallback if SSH keys aren't configured.\n")
-		// Don't fail - sshpass is optional now
+	// On WSL2, QEMU needs nested virtualization
+	if vm.IsWSL() {
+		fmt.Print("  Checking WSL2...              ")
+		if !printSetupChecks(vm.RunWSLPreflightChecks(projectRoot), "ready") {
+			allGood = false
+		}
+	}
+
+	// 2. Check sshpass (optional - only needed if SSH key auth fails; the
+	// libvirt check above already requires it)
+	if vm.GetBackend() != vm.BackendLibvirt {
+		fmt.Print("  Checking sshpass...           ")
+		if checkCommand("sshpass") {
+			fmt.Printf("%s✓ installed%s\n", colorGreen, colorReset)
+		} else {
+			fmt.Printf("%s○ not installed (optional)%s\n", colorYellow, colorReset)
+			fmt.Printf("     SSH key auth will be used instead. sshpass is only needed\n")
+			fmt.Printf("     as a fallback if SSH keys aren't configured.\n")
+			// Don't fail - sshpass is optional now
+		}
 	}
 
 	// 3. Check VM image
@@ -213,7 +231,7 @@ func cmdSetupLima() int {
 		fmt.Println()
 		fmt.Println("  Creating VM (this may take a minute)...")
 
-		configPath := filepath.Join(projectRoot, "lima", "nixos.yaml")
+		configPath := filepath.Join(projectRoot, devLimaConfig)
 		if err := vm.Create(devVMName, configPath); err != nil {
 			errorf("Failed to create VM: %v", err)
 			return 1
@@ -268,6 +286,21 @@ func cmdSetupLima() int {
 	return 0
 }
 
+// printSetupChecks finishes a setup line from preflight results, listing
+// each failure with its fix, and reports whether all passed
+func printSetupChecks(result *vm.PreflightResult, okText string) bool {
+	if !result.HasErrors() {
+		fmt.Printf("%s✓ %s%s\n", colorGreen, okText, colorReset)
+		return true
+	}
+	fmt.Printf("%s✗ not %s%s\n", colorRed, okText, colorReset)
+	for _, e := range result.Errors {
+		fmt.Printf("     %s\n", e.Message)
+		fmt.Printf("     Fix: %s%s%s\n", colorCyan, e.FixCommand, colorReset)
+	}
+	return false
+}
+
 func checkCommand(name string) bool {
 	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
 		path := filepath.Join(dir, name)
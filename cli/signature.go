@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// sshsigMagic starts both an SSH signature and the data it signs, per
+// OpenSSH's PROTOCOL.sshsig
+const sshsigMagic = "SSHSIG"
+
+// verifySSHSignature checks an armored signature made with
+// ssh-keygen -Y sign -n namespace over message against an ssh-ed25519
+// public key in authorized_keys form
+func verifySSHSignature(publicKey, namespace string, message, armored []byte) error {
+	want, err := parseEd25519Key(publicKey)
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != "SSH SIGNATURE" {
+		return errors.New("not an SSH signature")
+	}
+	blob := block.Bytes
+	if !bytes.HasPrefix(blob, []byte(sshsigMagic)) {
+		return errors.New("not an SSH signature")
+	}
+	blob = blob[len(sshsigMagic):]
+	if len(blob) < 4 || binary.BigEndian.Uint32(blob) != 1 {
+		return errors.New("unsupported SSH signature version")
+	}
+	blob = blob[4:]
+
+	var keyBlob, sigNamespace, reserved, hashAlg, sigBlob []byte
+	for _, field := range []*[]byte{&keyBlob, &sigNamespace, &reserved, &hashAlg, &sigBlob} {
+		if *field, blob, err = readSSHString(blob); err != nil {
+			return err
+		}
+	}
+	key, err := parseEd25519Blob(keyBlob)
+	if err != nil {
+		return err
+	}
+	if !key.Equal(want) {
+		return errors.New("signed by an unknown key")
+	}
+	if string(sigNamespace) != namespace {
+		return fmt.Errorf("signature is for %q, not %q", sigNamespace, namespace)
+	}
+
+	var h hash.Hash
+	switch string(hashAlg) {
+	case "sha512":
+		h = sha512.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return fmt.Errorf("unsupported signature hash %q", hashAlg)
+	}
+	h.Write(message)
+
+	sigType, rest, err := readSSHString(sigBlob)
+	if err != nil {
+		return err
+	}
+	sig, _, err := readSSHString(rest)
+	if err != nil {
+		return err
+	}
+	if string(sigType) != "ssh-ed25519" {
+		return fmt.Errorf("unsupported signature type %q", sigType)
+	}
+
+	// The signature covers the magic, namespace, reserved field, hash
+	// algorithm and the message's hash
+	signed := []byte(sshsigMagic)
+	for _, field := range [][]byte{sigNamespace, reserved, hashAlg, h.Sum(nil)} {
+		signed = appendSSHString(signed, field)
+	}
+	if !ed25519.Verify(key, signed, sig) {
+		return errors.New("bad signature")
+	}
+	return nil
+}
+
+// parseEd25519Key parses an "ssh-ed25519 <base64> [comment]" public key
+func parseEd25519Key(publicKey string) (ed25519.PublicKey, error) {
+	fields := strings.Fields(publicKey)
+	if len(fields) < 2 || fields[0] != "ssh-ed25519" {
+		return nil, errors.New("not an ssh-ed25519 public key")
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return parseEd25519Blob(blob)
+}
+
+// parseEd25519Blob parses a public key in SSH wire format
+func parseEd25519Blob(blob []byte) (ed25519.PublicKey, error) {
+	keyType, rest, err := readSSHString(blob)
+	if err != nil {
+		return nil, err
+	}
+	key, _, err := readSSHString(rest)
+	if err != nil {
+		return nil, err
+	}
+	if string(keyType) != "ssh-ed25519" || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// readSSHString reads a length-prefixed string, returning it and the rest
+func readSSHString(b []byte) ([]byte, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("truncated SSH signature")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint64(len(b)-4) < uint64(n) {
+		return nil, nil, errors.New("truncated SSH signature")
+	}
+	return b[4 : 4+n], b[4+n:], nil
+}
+
+func appendSSHString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
+)
+
+// cmdSnapshot handles "./bloud snapshot create|restore|list|delete [name]"
+// for the Lima dev VM. Lima only snapshots a stopped VM, so a running VM is
+// stopped first and the dev environment started again afterwards.
+func cmdSnapshot(args []string) int {
+	sub := args[0]
+	if (sub == "restore" || sub == "delete") && len(args) < 2 {
+		errorf("Usage: ./bloud snapshot %s <name>", sub)
+		return exitUsage
+	}
+	if !vm.Exists(devVMName) {
+		errorf("VM %s does not exist. Create it with: ./bloud start", devVMName)
+		return 1
+	}
+
+	switch sub {
+	case "list":
+		return snapshotList()
+	case "create":
+		name := fmt.Sprintf("snap-%s", time.Now().Format("20060102-150405"))
+		if len(args) > 1 {
+			name = args[1]
+		}
+		if vm.SnapshotExists(devVMName, name) {
+			errorf("Snapshot %s already exists", name)
+			return 1
+		}
+		return withVMStopped(fmt.Sprintf("Creating snapshot %s...", name), func() error {
+			return vm.SnapshotCreate(devVMName, name)
+		})
+	case "restore":
+		name := args[1]
+		if !vm.SnapshotExists(devVMName, name) {
+			errorf("No snapshot named %s (see ./bloud snapshot list)", name)
+			return 1
+		}
+		return withVMStopped(fmt.Sprintf("Restoring snapshot %s...", name), func() error {
+			return vm.SnapshotApply(devVMName, name)
+		})
+	case "delete":
+		if err := vm.SnapshotDelete(devVMName, args[1]); err != nil {
+			errorf("%v", err)
+			return 1
+		}
+		log(fmt.Sprintf("Deleted snapshot %s", args[1]))
+		return 0
+	default:
+		errorf("Unknown snapshot command: %s (use create, restore, list or delete)", sub)
+		return exitUsage
+	}
+}
+
+func snapshotList() int {
+	snapshots, err := vm.SnapshotList(devVMName)
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots. Create one with: ./bloud snapshot create [name]")
+		return 0
+	}
+	for _, name := range snapshots {
+		fmt.Println(name)
+	}
+	return 0
+}
+
+// withVMStopped runs fn against the stopped dev VM, stopping it first and
+// restarting the dev environment afterwards if it was running
+func withVMStopped(msg string, fn func() error) int {
+	wasRunning := vm.IsRunning(devVMName)
+	if wasRunning {
+		cmdStop()
+		log("Stopping VM...")
+		if err := vm.Stop(devVMName); err != nil {
+			errorf("Failed to stop VM: %v", err)
+			return 1
+		}
+	}
+
+	log(msg)
+	if err := fn(); err != nil {
+		errorf("%v", err)
+		if wasRunning {
+			warn("The VM was left stopped. Start it again with: ./bloud start")
+		}
+		return 1
+	}
+	log("Done")
+
+	if wasRunning {
+		return cmdStartLima()
+	}
+	return 0
+}
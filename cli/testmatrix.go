@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud/cli/vm"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// testMatrixFile is the default --apps-file
+	testMatrixFile = "tests/matrix.yaml"
+
+	// testMatrixSnapshot is the clean test VM every combination starts from
+	testMatrixSnapshot = "matrix-base"
+)
+
+// testMatrix is an --apps-file: sets of apps installed side by side
+type testMatrix struct {
+	Description  string            `yaml:"description"`
+	Combinations []testCombination `yaml:"combinations"`
+}
+
+type testCombination struct {
+	Name string   `yaml:"name"`
+	Apps []string `yaml:"apps"`
+	// Timeout for each app to reach running (a Go duration, default 5m)
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+func loadTestMatrix(path string) (testMatrix, error) {
+	var matrix testMatrix
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return matrix, err
+	}
+	if err := yaml.Unmarshal(data, &matrix); err != nil {
+		return matrix, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if len(matrix.Combinations) == 0 {
+		return matrix, fmt.Errorf("%s: no combinations", path)
+	}
+	seen := make(map[string]bool)
+	for i, c := range matrix.Combinations {
+		if c.Name == "" {
+			return matrix, fmt.Errorf("%s: combination %d has no name", path, i+1)
+		}
+		if seen[c.Name] {
+			return matrix, fmt.Errorf("%s: duplicate combination %q", path, c.Name)
+		}
+		seen[c.Name] = true
+		if len(c.Apps) == 0 {
+			return matrix, fmt.Errorf("%s: %s: no apps", path, c.Name)
+		}
+		for _, app := range c.Apps {
+			if !appNamePattern.MatchString(app) {
+				return matrix, fmt.Errorf("%s: %s: invalid app name %q", path, c.Name, app)
+			}
+		}
+		if c.Timeout != "" {
+			if _, err := time.ParseDuration(c.Timeout); err != nil {
+				return matrix, fmt.Errorf("%s: %s: invalid timeout %q", path, c.Name, c.Timeout)
+			}
+		}
+	}
+	return matrix, nil
+}
+
+// cmdTestMatrix installs each combination from appsFile on the test VM,
+// restoring a snapshot of the freshly started VM in between, and reports
+// which combinations came up. The VM is torn down afterwards unless keep
+// is set or it was already running.
+func cmdTestMatrix(appsFile string, keep bool) int {
+	root, err := getProjectRoot()
+	if err != nil {
+		errorf("Could not find project root: %v", err)
+		return 1
+	}
+	if appsFile == "" {
+		appsFile = filepath.Join(root, testMatrixFile)
+	}
+
+	matrix, err := loadTestMatrix(appsFile)
+	if err != nil {
+		errorf("%v", err)
+		return exitUsage
+	}
+	suite := strings.TrimSuffix(filepath.Base(appsFile), filepath.Ext(appsFile))
+
+	booted := !vm.IsRunning(testVMName)
+	if err := startTestVM(); err != nil {
+		errorf("%v", err)
+		return 1
+	}
+	if booted && !keep {
+		defer func() {
+			if err := destroyTestVM(); err != nil {
+				warn(fmt.Sprintf("Failed to tear down the test VM: %v", err))
+			}
+		}()
+	}
+
+	if !booted {
+		warn("The test VM was already running; its current state is the baseline for every combination")
+	}
+	if err := resetTestVM("Snapshotting the test VM...", func() error {
+		if vm.SnapshotExists(testVMName, testMatrixSnapshot) {
+			if err := vm.SnapshotDelete(testVMName, testMatrixSnapshot); err != nil {
+				return err
+			}
+		}
+		return vm.SnapshotCreate(testVMName, testMatrixSnapshot)
+	}); err != nil {
+		errorf("%v", err)
+		return 1
+	}
+	defer func() {
+		if err := vm.SnapshotDelete(testVMName, testMatrixSnapshot); err != nil && vm.Exists(testVMName) {
+			warn(fmt.Sprintf("Failed to delete snapshot %s: %v", testMatrixSnapshot, err))
+		}
+	}()
+
+	var report testReport
+	for i, c := range matrix.Combinations {
+		if i > 0 {
+			if err := resetTestVM("Restoring the test VM snapshot...", func() error {
+				return vm.SnapshotApply(testVMName, testMatrixSnapshot)
+			}); err != nil {
+				errorf("%v", err)
+				return 1
+			}
+		}
+
+		log(fmt.Sprintf("%s: %s (%s)", suite, c.Name, strings.Join(c.Apps, ", ")))
+		result := runTestCombination(suite, c)
+		report.Results = append(report.Results, result)
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+
+	if jsonOutput {
+		printJSON(report)
+	} else {
+		printTestReport(report)
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// resetTestVM runs fn against the stopped test VM and brings the test
+// environment back up; Lima only snapshots stopped VMs
+func resetTestVM(msg string, fn func() error) error {
+	_ = vm.KillPortForwarding(testVMName, testPorts[0].LocalPort)
+	if err := vm.Stop(testVMName); err != nil {
+		return fmt.Errorf("failed to stop test VM: %w", err)
+	}
+	log(msg)
+	if err := fn(); err != nil {
+		return err
+	}
+	return startTestVM()
+}
+
+// runTestCombination installs every app in the set, then waits for each to
+// reach running, as a scenario of install and expect steps
+func runTestCombination(suite string, c testCombination) testResult {
+	scenario := testScenario{Name: c.Name}
+	for _, app := range c.Apps {
+		scenario.Steps = append(scenario.Steps, testStep{Install: app})
+	}
+	for _, app := range c.Apps {
+		scenario.Steps = append(scenario.Steps, testStep{Expect: &testExpect{App: app, Status: "running", Timeout: c.Timeout}})
+	}
+
+	api, err := resolveHostAgentAPI(true)
+	if err != nil {
+		return testResult{Suite: suite, Scenario: c.Name, Step: 1, Error: err.Error()}
+	}
+	return runTestScenario(api, suite, scenario)
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/apiclient"
+	"codeberg.org/d-buckner/bloud/cli/vm"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// testLimaTemplate is rendered into the test VM's Lima config
+	testLimaTemplate = "lima/test-nixos.yaml.template"
+
+	// testScenariosDir holds the end-to-end suites, one YAML file each
+	testScenariosDir = "tests/scenarios"
+
+	// testAPITimeout covers the first host-agent build in a fresh test VM
+	testAPITimeout = 10 * time.Minute
+
+	// testExpectTimeout is how long an expect step waits by default
+	testExpectTimeout = 5 * time.Minute
+)
+
+// testPorts are the test VM's forwarded ports (dev port + 1 or 8081)
+var testPorts = []vm.PortForward{
+	{LocalPort: testAPIPort, RemotePort: testAPIPort},
+	{LocalPort: 5174, RemotePort: 5174},
+	{LocalPort: 8081, RemotePort: 8081},
+}
+
+// testSuite is a tests/scenarios/<suite>.yaml file
+type testSuite struct {
+	Name        string         `yaml:"-"`
+	Description string         `yaml:"description"`
+	Scenarios   []testScenario `yaml:"scenarios"`
+}
+
+// testScenario is a sequence of steps run against the test VM's API. A
+// scenario should leave the VM as it found it, since the next one shares it.
+type testScenario struct {
+	Name  string     `yaml:"name"`
+	Steps []testStep `yaml:"steps"`
+}
+
+// testStep does exactly one thing: install an app, uninstall one, or wait
+// for an app to reach an expected state
+type testStep struct {
+	Install   string      `yaml:"install,omitempty"`
+	Uninstall string      `yaml:"uninstall,omitempty"`
+	Expect    *testExpect `yaml:"expect,omitempty"`
+}
+
+// testExpect passes once the app's install record matches, polling until
+// Timeout (a Go duration, default 5m)
+type testExpect struct {
+	App       string `yaml:"app"`
+	Status    string `yaml:"status,omitempty"`    // e.g. running
+	Installed *bool  `yaml:"installed,omitempty"` // false: the app is gone
+	Timeout   string `yaml:"timeout,omitempty"`
+}
+
+// testResult is one scenario's outcome in the run summary
+type testResult struct {
+	Suite    string  `json:"suite"`
+	Scenario string  `json:"scenario"`
+	Passed   bool    `json:"passed"`
+	Step     int     `json:"step,omitempty"` // 1-based failing step
+	Error    string  `json:"error,omitempty"`
+	Seconds  float64 `json:"seconds"`
+}
+
+// testReport is the JSON form of "./bloud test run"
+type testReport struct {
+	Results []testResult `json:"results"`
+	Passed  int          `json:"passed"`
+	Failed  int          `json:"failed"`
+}
+
+// cmdTest handles "./bloud test run [suite...]|matrix|start|stop"
+func cmdTest(args []string, keep bool, appsFile string) int {
+	switch args[0] {
+	case "run":
+		return cmdTestRun(args[1:], keep)
+	case "matrix":
+		if len(args) > 1 {
+			errorf("Usage: ./bloud test matrix [--apps-file <file>]")
+			return exitUsage
+		}
+		return cmdTestMatrix(appsFile, keep)
+	case "start":
+		if err := startTestVM(); err != nil {
+			errorf("%v", err)
+			return 1
+		}
+		log(fmt.Sprintf("Test environment ready: http://localhost:%d (API), http://localhost:8081 (Traefik)", testAPIPort))
+		return 0
+	case "stop":
+		if err := destroyTestVM(); err != nil {
+			errorf("%v", err)
+			return 1
+		}
+		return 0
+	default:
+		errorf("Unknown test command: %s (use run, matrix, start or stop)", args[0])
+		return exitUsage
+	}
+}
+
+// cmdTestRun boots the test VM, runs the named suites (all of them by
+// default) and tears the VM down again unless keep is set or it was already
+// running
+func cmdTestRun(names []string, keep bool) int {
+	root, err := getProjectRoot()
+	if err != nil {
+		errorf("Could not find project root: %v", err)
+		return 1
+	}
+
+	// Load every suite first so a typo fails before a ten-minute boot
+	suites, err := loadTestSuites(filepath.Join(root, testScenariosDir), names)
+	if err != nil {
+		errorf("%v", err)
+		return exitUsage
+	}
+
+	booted := !vm.IsRunning(testVMName)
+	if err := startTestVM(); err != nil {
+		errorf("%v", err)
+		return 1
+	}
+	if booted && !keep {
+		defer func() {
+			if err := destroyTestVM(); err != nil {
+				warn(fmt.Sprintf("Failed to tear down the test VM: %v", err))
+			}
+		}()
+	}
+
+	api, err := resolveHostAgentAPI(true)
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	var report testReport
+	for _, suite := range suites {
+		for _, scenario := range suite.Scenarios {
+			log(fmt.Sprintf("%s: %s", suite.Name, scenario.Name))
+			result := runTestScenario(api, suite.Name, scenario)
+			report.Results = append(report.Results, result)
+			if result.Passed {
+				report.Passed++
+			} else {
+				report.Failed++
+			}
+		}
+	}
+
+	if jsonOutput {
+		printJSON(report)
+	} else {
+		printTestReport(report)
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadTestSuites reads the named suites from dir, or every suite when names
+// is empty
+func loadTestSuites(dir string, names []string) ([]testSuite, error) {
+	if len(names) == 0 {
+		files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			names = append(names, strings.TrimSuffix(filepath.Base(file), ".yaml"))
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no test suites in %s", dir)
+		}
+	}
+
+	suites := make([]testSuite, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name+".yaml"))
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("unknown test suite %q (suites live in %s)", name, testScenariosDir)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		suite := testSuite{Name: name}
+		if err := yaml.Unmarshal(data, &suite); err != nil {
+			return nil, fmt.Errorf("failed to parse suite %s: %w", name, err)
+		}
+		if err := validateTestSuite(suite); err != nil {
+			return nil, fmt.Errorf("suite %s: %w", name, err)
+		}
+		suites = append(suites, suite)
+	}
+	return suites, nil
+}
+
+func validateTestSuite(suite testSuite) error {
+	if len(suite.Scenarios) == 0 {
+		return fmt.Errorf("no scenarios")
+	}
+	for _, scenario := range suite.Scenarios {
+		if len(scenario.Steps) == 0 {
+			return fmt.Errorf("%s: no steps", scenario.Name)
+		}
+		for i, step := range scenario.Steps {
+			actions := 0
+			for _, set := range []bool{step.Install != "", step.Uninstall != "", step.Expect != nil} {
+				if set {
+					actions++
+				}
+			}
+			if actions != 1 {
+				return fmt.Errorf("%s step %d: use exactly one of install, uninstall or expect", scenario.Name, i+1)
+			}
+			app := step.Install + step.Uninstall
+			if e := step.Expect; e != nil {
+				app = e.App
+				if e.Timeout != "" {
+					if _, err := time.ParseDuration(e.Timeout); err != nil {
+						return fmt.Errorf("%s step %d: invalid timeout %q", scenario.Name, i+1, e.Timeout)
+					}
+				}
+			}
+			if !appNamePattern.MatchString(app) {
+				return fmt.Errorf("%s step %d: invalid app name %q", scenario.Name, i+1, app)
+			}
+		}
+	}
+	return nil
+}
+
+// runTestScenario runs a scenario's steps in order, stopping at the first
+// failure
+func runTestScenario(api *hostAgentAPI, suite string, scenario testScenario) testResult {
+	start := time.Now()
+	result := testResult{Suite: suite, Scenario: scenario.Name, Passed: true}
+	for i, step := range scenario.Steps {
+		if err := runTestStep(api, step); err != nil {
+			result.Passed = false
+			result.Step = i + 1
+			result.Error = err.Error()
+			break
+		}
+	}
+	result.Seconds = time.Since(start).Seconds()
+	return result
+}
+
+func runTestStep(api *hostAgentAPI, step testStep) error {
+	switch {
+	case step.Install != "":
+		if _, err := api.client.InstallApp(context.Background(), step.Install, apiclient.InstallAppRequest{}); err != nil {
+			return fmt.Errorf("install %s: %w", step.Install, err)
+		}
+	case step.Uninstall != "":
+		if _, err := api.client.UninstallApp(context.Background(), step.Uninstall, apiclient.UninstallAppRequest{}); err != nil {
+			return fmt.Errorf("uninstall %s: %w", step.Uninstall, err)
+		}
+	default:
+		return waitForExpectation(api, *step.Expect)
+	}
+	return nil
+}
+
+// waitForExpectation polls the installed apps until the expectation holds
+func waitForExpectation(api *hostAgentAPI, e testExpect) error {
+	timeout := testExpectTimeout
+	if e.Timeout != "" {
+		timeout, _ = time.ParseDuration(e.Timeout)
+	}
+	wantInstalled := e.Installed == nil || *e.Installed
+
+	deadline := time.Now().Add(timeout)
+	for {
+		installed, err := api.client.ListInstalledApps(context.Background(), nil)
+
+		got := "not installed"
+		if err == nil {
+			for _, app := range installed {
+				if app.Name == e.App {
+					got = app.Status
+				}
+			}
+			isInstalled := got != "not installed"
+			if isInstalled == wantInstalled && (!wantInstalled || e.Status == "" || got == e.Status) {
+				return nil
+			}
+		} else {
+			got = err.Error()
+		}
+
+		if time.Now().After(deadline) {
+			want := e.Status
+			if !wantInstalled {
+				want = "not installed"
+			} else if want == "" {
+				want = "installed"
+			}
+			return fmt.Errorf("expected %s to be %s, got %s after %s", e.App, want, got, timeout)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func printTestReport(report testReport) {
+	fmt.Println()
+	for _, r := range report.Results {
+		if r.Passed {
+			fmt.Printf("  %sPASS%s %s: %s (%.0fs)\n", colorGreen, colorReset, r.Suite, r.Scenario, r.Seconds)
+		} else {
+			fmt.Printf("  %sFAIL%s %s: %s (%.0fs)\n", colorRed, colorReset, r.Suite, r.Scenario, r.Seconds)
+			fmt.Printf("       step %d: %s\n", r.Step, r.Error)
+		}
+	}
+	fmt.Println()
+	if report.Failed > 0 {
+		fmt.Printf("%s%d passed, %d failed%s\n", colorRed, report.Passed, report.Failed, colorReset)
+	} else {
+		fmt.Printf("%s%d passed%s\n", colorGreen, report.Passed, colorReset)
+	}
+}
+
+// startTestVM creates or starts the bloud-test Lima VM from its template,
+// starts the test environment in it and waits for the API
+func startTestVM() error {
+	root, err := getProjectRoot()
+	if err != nil {
+		return fmt.Errorf("could not find project root: %w", err)
+	}
+
+	if preflight := vm.RunPreflightChecks(root); preflight.HasErrors() {
+		vm.PrintPreflightErrors(preflight)
+		return fmt.Errorf("pre-flight checks failed")
+	}
+
+	mainRepo := testMainRepo(root)
+	configPath, err := renderTestLimaConfig(root, mainRepo)
+	if err != nil {
+		return err
+	}
+
+	if err := vm.EnsureRunning(context.Background(), testVMName, configPath); err != nil {
+		return fmt.Errorf("failed to start test VM: %w", err)
+	}
+
+	// Mount tags follow the order of mounts in the template
+	log("Mounting shared directories...")
+	mounts := []vm.Mount{
+		{Tag: "mount0", MountPath: root},
+		{Tag: "mount1", MountPath: filepath.Join(mainRepo, ".git"), ReadOnly: true},
+		{Tag: "mount2", MountPath: "/tmp/lima"},
+	}
+	if err := vm.MountFilesystems(testVMName, mounts); err != nil {
+		warn(fmt.Sprintf("Mount warning: %v", err))
+	}
+
+	if !isPortForwardingRunning(testPorts[0].LocalPort) {
+		log("Starting port forwarding...")
+		if _, err := vm.StartPortForwarding(testVMName, testPorts); err != nil {
+			warn(fmt.Sprintf("Port forwarding warning: %v", err))
+		}
+	}
+
+	log("Starting test environment...")
+	if _, err := vm.Exec(testVMName, fmt.Sprintf("bash '%s/lima/start-test.sh' '%s'", root, root)); err != nil {
+		return fmt.Errorf("failed to start test environment: %w", err)
+	}
+
+	log("Waiting for the test API...")
+	api := newHostAgentAPI(func(cmd string) (string, error) { return vm.Exec(testVMName, cmd) }, nil, testAPIPort)
+	deadline := time.Now().Add(testAPITimeout)
+	for {
+		if _, err := api.client.Health(context.Background()); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("test API did not come up within %s (check: limactl shell %s tmux capture-pane -t %s -p)", testAPITimeout, testVMName, testVMName)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// destroyTestVM deletes the test VM and its port forwards
+func destroyTestVM() error {
+	if !vm.Exists(testVMName) {
+		return nil
+	}
+	_ = vm.KillPortForwarding(testVMName, testPorts[0].LocalPort)
+
+	log("Destroying test VM...")
+	if err := vm.Delete(testVMName); err != nil {
+		return fmt.Errorf("failed to destroy test VM: %w", err)
+	}
+	return nil
+}
+
+// testMainRepo is the checkout that owns .git and the VM image: root
+// itself, or the main worktree when root is a git worktree
+func testMainRepo(root string) string {
+	c := localExec("git", "rev-parse", "--path-format=absolute", "--git-common-dir")
+	c.Dir = root
+	out, err := c.Output()
+	if err != nil {
+		return root
+	}
+	return filepath.Dir(strings.TrimSpace(string(out)))
+}
+
+// renderTestLimaConfig fills in the template's paths and writes the result
+// next to the VM's scratch mount
+func renderTestLimaConfig(root, mainRepo string) (string, error) {
+	template, err := os.ReadFile(filepath.Join(root, testLimaTemplate))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", testLimaTemplate, err)
+	}
+	config := strings.NewReplacer(
+		"__PROJECT_ROOT__", root,
+		"__BLOUD_MAIN_REPO__", mainRepo,
+	).Replace(string(template))
+
+	dir := "/tmp/lima-test"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, testVMName+".yaml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
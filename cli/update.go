@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/apiclient"
+)
+
+const (
+	// updateCheckJob is the host-agent's scheduled update check, run on
+	// demand by --check
+	updateCheckJob = "update-check"
+	// updatePollInterval is how often an update in progress is checked on
+	updatePollInterval = 5 * time.Second
+	// updateFollowTimeout bounds following an update: the switch, and the
+	// host-agent's ten minutes of verification before it rolls back
+	updateFollowTimeout = 20 * time.Minute
+)
+
+// Update statuses the host-agent reports while a switch is under way
+var updateInProgress = map[string]bool{"applying": true, "verifying": true, "rolling-back": true}
+
+// cmdUpdate shows the system update status of the environment or the
+// --host appliance, following an update that is under way until it
+// settles. --check looks for a newer system first; --rollback switches
+// back to the system the last update replaced.
+func cmdUpdate(check, rollback bool) int {
+	if check && rollback {
+		errorf("--check and --rollback can't be combined")
+		return exitUsage
+	}
+	client, explain, err := resolveUpdateAPI()
+	if err != nil {
+		errorf("%v", err)
+		return 1
+	}
+
+	switch {
+	case check:
+		log("Checking for a newer system...")
+		if err := runUpdateCheck(client); err != nil {
+			errorf("Update check failed: %v", explain(err))
+			return 1
+		}
+	case rollback:
+		log("Rolling back the last update...")
+		if err := startRollback(client); err != nil {
+			errorf("Rollback failed: %v", explain(err))
+			return 1
+		}
+	}
+
+	resp, followed, err := followUpdate(client)
+	if err != nil {
+		errorf("%v", explain(err))
+		return 1
+	}
+	if jsonOutput {
+		printJSON(resp)
+	} else {
+		printUpdates(resp)
+	}
+
+	// An update or rollback watched to its end must have succeeded
+	switch status := resp.Status.Status; {
+	case rollback:
+		if status != "rolled-back" {
+			return 1
+		}
+	case followed:
+		if status != "applied" && status != "up-to-date" {
+			return 1
+		}
+	}
+	return 0
+}
+
+// resolveUpdateAPI picks the --host appliance when one is set, the
+// environment's host-agent otherwise, returning its API client and how to
+// explain the client's errors
+func resolveUpdateAPI() (*apiclient.Client, func(error) error, error) {
+	if remoteHost != "" {
+		api, err := newRemoteAPI(remoteHost)
+		if err != nil {
+			return nil, nil, err
+		}
+		return api.client, api.remoteError, nil
+	}
+	api, err := resolveHostAgentAPI(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return api.client, func(err error) error { return err }, nil
+}
+
+// runUpdateCheck runs the host-agent's update check now and waits for it.
+// A check already running is waited for instead.
+func runUpdateCheck(client *apiclient.Client) error {
+	before, err := updateCheckStatus(client)
+	if err != nil {
+		return err
+	}
+	if !before.Running {
+		if _, err := client.RunSchedule(context.Background(), updateCheckJob); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(updateFollowTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(updatePollInterval)
+		st, err := updateCheckStatus(client)
+		if err != nil {
+			return err
+		}
+		if st.Running || st.LastRun.IsZero() || !st.LastRun.After(before.LastRun) {
+			continue
+		}
+		if st.LastError != "" {
+			return errors.New(st.LastError)
+		}
+		return nil
+	}
+	return fmt.Errorf("the check didn't finish within %s", updateFollowTimeout)
+}
+
+// updateCheckStatus finds the update check among the scheduled jobs
+func updateCheckStatus(client *apiclient.Client) (apiclient.SchedulerStatus, error) {
+	resp, err := client.ListSchedules(context.Background())
+	if err != nil {
+		return apiclient.SchedulerStatus{}, err
+	}
+	for _, st := range resp.Schedules {
+		if st.Name == updateCheckJob {
+			return st, nil
+		}
+	}
+	return apiclient.SchedulerStatus{}, errors.New("this host-agent has no update check")
+}
+
+// startRollback asks for a rollback. The request returns once the switch
+// is done, but the switch usually restarts the host-agent and drops it, so
+// a failed request only counts when no rollback is under way.
+func startRollback(client *apiclient.Client) error {
+	_, postErr := client.RollbackUpdate(context.Background())
+	if postErr == nil {
+		return nil
+	}
+	resp, err := client.GetUpdates(context.Background())
+	if err != nil {
+		return postErr
+	}
+	if st := resp.Status.Status; st == "rolling-back" || st == "rolled-back" {
+		return nil
+	}
+	return postErr
+}
+
+// followUpdate returns the update status once no switch is under way, and
+// whether it waited for one. The host-agent restarts with the new system,
+// so errors are retried until the timeout.
+func followUpdate(client *apiclient.Client) (*apiclient.UpdatesResponse, bool, error) {
+	deadline := time.Now().Add(updateFollowTimeout)
+	last := ""
+	for {
+		resp, err := client.GetUpdates(context.Background())
+		switch {
+		case err == nil && !updateInProgress[resp.Status.Status]:
+			return resp, last != "", nil
+		case time.Now().After(deadline):
+			if err != nil {
+				return nil, true, fmt.Errorf("failed to get the update status: %w", err)
+			}
+			return resp, true, fmt.Errorf("the update is still %s after %s", resp.Status.Status, updateFollowTimeout)
+		case err == nil && resp.Status.Status != last:
+			log(fmt.Sprintf("Update %s...", resp.Status.Status))
+			last = resp.Status.Status
+		}
+		time.Sleep(updatePollInterval)
+	}
+}
+
+func printUpdates(resp *apiclient.UpdatesResponse) {
+	policy := resp.Policy
+	switch policy.Mode {
+	case "", "off":
+		fmt.Println("Automatic updates: off")
+	default:
+		window := policy.Window.Start + "-" + policy.Window.End
+		if len(policy.Window.Days) > 0 {
+			window += " " + strings.Join(policy.Window.Days, ",")
+		}
+		fmt.Printf("Automatic updates: %s, %s channel, window %s\n", policy.Mode, policy.Channel, window)
+	}
+	st := resp.Status
+	if !st.NextWindow.IsZero() {
+		fmt.Printf("Next window:       %s\n", st.NextWindow.Local().Format("2006-01-02 15:04"))
+	}
+	if st.Status != "" {
+		line := colorUpdateStatus(st.Status)
+		if !st.LastAttemptAt.IsZero() {
+			line += " at " + st.LastAttemptAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("Last update:       %s\n", line)
+		if st.ToSystem != "" {
+			fmt.Printf("  %s -> %s\n", path.Base(st.FromSystem), path.Base(st.ToSystem))
+		}
+		if st.Error != "" {
+			fmt.Printf("  %s%s%s\n", colorRed, st.Error, colorReset)
+		}
+	}
+
+	if st.Available == nil {
+		fmt.Println("Available:         none (the running system is the latest)")
+		return
+	}
+	available := path.Base(st.Available.System)
+	if st.Available.Channel != "" {
+		available += " on the " + st.Available.Channel + " channel"
+	}
+	fmt.Printf("Available:         %s%s%s\n", colorGreen, available, colorReset)
+	fmt.Printf("  found %s, last checked %s\n",
+		st.Available.FoundAt.Local().Format("2006-01-02 15:04"), st.Available.CheckedAt.Local().Format("2006-01-02 15:04"))
+}
+
+// colorUpdateStatus colors an update status by outcome
+func colorUpdateStatus(status string) string {
+	color := colorYellow
+	switch status {
+	case "applied", "up-to-date":
+		color = colorGreen
+	case "failed", "rolled-back":
+		color = colorRed
+	}
+	return color + status + colorReset
+}
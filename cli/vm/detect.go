@@ -1,8 +1,10 @@
 package vm
 
 import (
+	"fmt"
 	"os"
 	"runtime"
+	"strings"
 )
 
 // RuntimeMode represents the detected execution environment
@@ -15,9 +17,45 @@ const (
 
 var detectedMode = ModeLima
 
-// DetectRuntime detects whether we're running on native NixOS or need Lima
+// Backend is the hypervisor layer behind the dev and test VMs in Lima mode
+type Backend string
+
+const (
+	BackendLima    Backend = "lima"    // limactl, on macOS and Linux
+	BackendLibvirt Backend = "libvirt" // virsh and virt-install, on Linux
+)
+
+var vmBackend = BackendLima
+
+// SetBackend selects the VM backend by name; empty keeps Lima
+func SetBackend(name string) error {
+	switch Backend(name) {
+	case "", BackendLima:
+		vmBackend = BackendLima
+	case BackendLibvirt:
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("the libvirt backend needs a Linux host")
+		}
+		vmBackend = BackendLibvirt
+	default:
+		return fmt.Errorf("unknown VM backend %q (use lima or libvirt)", name)
+	}
+	return nil
+}
+
+// GetBackend returns the selected VM backend
+func GetBackend() Backend {
+	return vmBackend
+}
+
+var detectedWSL bool
+
+// DetectRuntime detects whether we're running on native NixOS or need Lima.
+// NixOS-WSL counts as native NixOS; other WSL2 distros run the Lima VM on
+// QEMU through nested virtualization.
 func DetectRuntime() {
 	if runtime.GOOS == "linux" {
+		detectedWSL = detectWSL()
 		if _, err := os.Stat("/run/current-system"); err == nil {
 			detectedMode = ModeNative
 			return
@@ -31,6 +69,21 @@ func IsNative() bool {
 	return detectedMode == ModeNative
 }
 
+// IsWSL returns true if running inside a WSL2 distro on Windows
+func IsWSL() bool {
+	return detectedWSL
+}
+
+// detectWSL looks for the WSL interop handler, falling back to the
+// Microsoft kernel build string
+func detectWSL() bool {
+	if _, err := os.Stat("/proc/sys/fs/binfmt_misc/WSLInterop"); err == nil {
+		return true
+	}
+	version, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	return err == nil && strings.Contains(strings.ToLower(string(version)), "microsoft")
+}
+
 // GetMode returns the detected runtime mode
 func GetMode() RuntimeMode {
 	return detectedMode
@@ -1,11 +1,36 @@
 package vm
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
 )
 
+// Verbose makes Command echo every command it runs to stderr, set by the
+// CLI's global --verbose flag
+var Verbose bool
+
+// Command is exec.Command, traced when Verbose is set
+func Command(name string, args ...string) *exec.Cmd {
+	trace(name, args)
+	return exec.Command(name, args...)
+}
+
+// CommandContext is exec.CommandContext, traced when Verbose is set
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	trace(name, args)
+	return exec.CommandContext(ctx, name, args...)
+}
+
+func trace(name string, args []string) {
+	if Verbose {
+		fmt.Fprintf(os.Stderr, "+ %s %s\n", name, strings.Join(args, " "))
+	}
+}
+
 // Run executes a command either in the VM (via SSH) or locally, returning output
 func Run(vmName, command string) (string, error) {
 	if IsNative() {
@@ -22,6 +47,15 @@ func RunStream(vmName, command string) error {
 	return ExecStream(vmName, command)
 }
 
+// RunPipe executes a command with stdin and stdout connected to the given
+// reader and writer
+func RunPipe(vmName, command string, stdin io.Reader, stdout io.Writer) error {
+	if IsNative() {
+		return LocalExecPipe(command, stdin, stdout)
+	}
+	return ExecPipe(vmName, command, stdin, stdout)
+}
+
 // RunInteractive executes a command with full stdin/stdout/stderr attached
 func RunInteractive(vmName, command string) error {
 	if IsNative() {
@@ -32,7 +66,7 @@ func RunInteractive(vmName, command string) error {
 
 // LocalExec runs a command locally and returns the output
 func LocalExec(command string) (string, error) {
-	cmd := exec.Command("bash", "-c", command)
+	cmd := Command("bash", "-c", command)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return string(output), fmt.Errorf("command failed: %w", err)
@@ -42,18 +76,28 @@ func LocalExec(command string) (string, error) {
 
 // LocalExecStream runs a command locally with stdout/stderr piped to the terminal
 func LocalExecStream(command string) error {
-	cmd := exec.Command("bash", "-c", command)
+	cmd := Command("bash", "-c", command)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
+// LocalExecPipe runs a command locally with stdin and stdout connected to the
+// given reader and writer, and stderr piped to the terminal
+func LocalExecPipe(command string, stdin io.Reader, stdout io.Writer) error {
+	cmd := Command("bash", "-c", command)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 // LocalInteractive runs a command locally with full stdin/stdout/stderr attached
 func LocalInteractive(command string) error {
 	if command == "" {
 		command = "bash"
 	}
-	cmd := exec.Command("bash", "-c", command)
+	cmd := Command("bash", "-c", command)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -0,0 +1,288 @@
+package vm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The libvirt backend runs the same NixOS image as Lima on local QEMU,
+// driven by virsh and virt-install. It reads the VM's resources, image and
+// mounts from the Lima config so both backends share one definition, and
+// exposes the mounts under Lima's 9p tags (mount0, mount1, ...) so
+// MountFilesystems works unchanged. Guests sit on libvirt's default NAT
+// network and are reached over SSH at their own address.
+
+const (
+	libvirtDefaultURI  = "qemu:///system"
+	libvirtNetwork     = "default"
+	libvirtStopTimeout = time.Minute
+)
+
+// limaConfig is the part of a Lima config the libvirt backend uses
+type limaConfig struct {
+	CPUs   int    `yaml:"cpus"`
+	Memory string `yaml:"memory"`
+	Disk   string `yaml:"disk"`
+	Images []struct {
+		Location string `yaml:"location"`
+	} `yaml:"images"`
+	Mounts []struct {
+		Location string `yaml:"location"`
+		Writable bool   `yaml:"writable"`
+	} `yaml:"mounts"`
+}
+
+// libvirtURI is the libvirt connection, LIBVIRT_DEFAULT_URI or the system
+// instance, whose default network lets the host reach the guest
+func libvirtURI() string {
+	if uri := os.Getenv("LIBVIRT_DEFAULT_URI"); uri != "" {
+		return uri
+	}
+	return libvirtDefaultURI
+}
+
+func virsh(args ...string) *exec.Cmd {
+	return Command("virsh", append([]string{"--connect", libvirtURI()}, args...)...)
+}
+
+// libvirtDiskPath is the VM's qcow2 overlay on top of the NixOS image
+func libvirtDiskPath(vmName string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "bloud", "libvirt", vmName+".qcow2")
+}
+
+func libvirtStatus(vmName string) VMStatus {
+	output, err := virsh("domstate", vmName).Output()
+	if err != nil {
+		return StatusUnknown
+	}
+	if strings.TrimSpace(string(output)) == "running" {
+		return StatusRunning
+	}
+	return StatusStopped
+}
+
+// libvirtIP returns the guest's address from the default network's DHCP
+// leases, falling back to the host's ARP table
+func libvirtIP(vmName string) (string, error) {
+	for _, source := range []string{"lease", "arp"} {
+		output, err := virsh("domifaddr", vmName, "--source", source).Output()
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 4 && fields[2] == "ipv4" {
+				return strings.SplitN(fields[3], "/", 2)[0], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("VM %s has no IP address yet", vmName)
+}
+
+func libvirtStart(vmName string) error {
+	switch libvirtStatus(vmName) {
+	case StatusUnknown:
+		return fmt.Errorf("VM %s does not exist", vmName)
+	case StatusRunning:
+		return nil
+	}
+
+	if output, err := virsh("start", vmName).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to start VM: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// libvirtCreate defines and boots a VM from a Lima config: a qcow2 overlay
+// on the config's image, its CPUs and memory, and its mounts as 9p shares
+func libvirtCreate(vmName, configPath string) error {
+	if libvirtStatus(vmName) != StatusUnknown {
+		return fmt.Errorf("VM %s already exists", vmName)
+	}
+
+	cfg, err := readLimaConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Images) == 0 {
+		return fmt.Errorf("no image in %s", configPath)
+	}
+	image := expandHome(cfg.Images[0].Location)
+	memory, err := parseLimaSize(cfg.Memory, "4GiB")
+	if err != nil {
+		return fmt.Errorf("invalid memory in %s: %w", configPath, err)
+	}
+	diskSize, err := parseLimaSize(cfg.Disk, "100GiB")
+	if err != nil {
+		return fmt.Errorf("invalid disk in %s: %w", configPath, err)
+	}
+	cpus := cfg.CPUs
+	if cpus == 0 {
+		cpus = 4
+	}
+
+	format, err := imageFormat(image)
+	if err != nil {
+		return err
+	}
+	disk := libvirtDiskPath(vmName)
+	if err := os.MkdirAll(filepath.Dir(disk), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(disk), err)
+	}
+	cmd := Command("qemu-img", "create", "-f", "qcow2", "-b", image, "-F", format, disk, strconv.FormatInt(diskSize, 10))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create disk: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	args := []string{
+		"--connect", libvirtURI(),
+		"--name", vmName,
+		"--vcpus", strconv.Itoa(cpus),
+		"--memory", strconv.FormatInt(memory>>20, 10),
+		"--import",
+		"--disk", fmt.Sprintf("path=%s,format=qcow2,bus=virtio", disk),
+		"--network", fmt.Sprintf("network=%s,model=virtio", libvirtNetwork),
+		"--boot", "uefi",
+		"--os-variant", "linux2020",
+		"--graphics", "none",
+		"--noautoconsole",
+	}
+	for i, m := range cfg.Mounts {
+		share := fmt.Sprintf("source=%s,target=mount%d,accessmode=squash", expandHome(m.Location), i)
+		if !m.Writable {
+			share += ",readonly=on"
+		}
+		args = append(args, "--filesystem", share)
+	}
+
+	if output, err := Command("virt-install", args...).CombinedOutput(); err != nil {
+		_ = os.Remove(disk)
+		return fmt.Errorf("failed to create VM: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// libvirtStop shuts the guest down cleanly, forcing it off if it hangs
+func libvirtStop(vmName string) error {
+	if output, err := virsh("shutdown", vmName).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stop VM: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	deadline := time.Now().Add(libvirtStopTimeout)
+	for time.Now().Before(deadline) {
+		if libvirtStatus(vmName) != StatusRunning {
+			return nil
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	if output, err := virsh("destroy", vmName).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stop VM: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+func libvirtDelete(vmName string) error {
+	if output, err := virsh("undefine", vmName, "--nvram").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete VM: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	if err := os.Remove(libvirtDiskPath(vmName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete disk: %w", err)
+	}
+	return nil
+}
+
+// libvirtSnapshot creates (-c), applies (-a) or deletes (-d) an internal
+// snapshot of the stopped VM's disk
+func libvirtSnapshot(vmName, op, snapshotName string) error {
+	cmd := Command("qemu-img", "snapshot", op, snapshotName, libvirtDiskPath(vmName))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("snapshot failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// libvirtSnapshotList reads the snapshot tags from qemu-img's table, which
+// has two header lines and the tag in the second column
+func libvirtSnapshotList(vmName string) ([]string, error) {
+	output, err := Command("qemu-img", "snapshot", "-U", "-l", libvirtDiskPath(vmName)).Output()
+	if err != nil {
+		return []string{}, nil
+	}
+
+	var snapshots []string
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if i < 2 || len(fields) < 2 {
+			continue
+		}
+		snapshots = append(snapshots, fields[1])
+	}
+	return snapshots, nil
+}
+
+func readLimaConfig(configPath string) (*limaConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &limaConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+	return cfg, nil
+}
+
+// imageFormat asks qemu-img whether the image is raw or qcow2
+func imageFormat(image string) (string, error) {
+	output, err := Command("qemu-img", "info", "--output=json", image).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read image %s: %w", image, err)
+	}
+	var info struct {
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return "", fmt.Errorf("failed to read image %s: %w", image, err)
+	}
+	return info.Format, nil
+}
+
+// parseLimaSize converts a Lima size such as "4GiB" to bytes
+func parseLimaSize(size, fallback string) (int64, error) {
+	if size == "" {
+		size = fallback
+	}
+	units := []struct {
+		suffix string
+		shift  uint
+	}{{"GiB", 30}, {"MiB", 20}, {"G", 30}, {"M", 20}}
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(size, u.suffix); ok {
+			value, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return value << u.shift, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported size %q", size)
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~"); ok {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, rest)
+	}
+	return path
+}
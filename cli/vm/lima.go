@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
@@ -36,19 +37,19 @@ func detectSSHMethod(vmName string) sshMethod {
 		return cachedSSHMethod
 	}
 
-	port, err := GetSSHPort(vmName)
+	host, port, err := sshAddress(vmName)
 	if err != nil || port == 0 {
 		return sshMethodUnknown
 	}
 
 	// Try key-based SSH first (preferred - no sshpass needed)
-	if tryKeySSH(port) {
+	if tryKeySSH(host, port) {
 		cachedSSHMethod = sshMethodKey
 		return sshMethodKey
 	}
 
 	// Fall back to password auth if sshpass is available
-	if hasSshpass() && tryPasswordSSH(port) {
+	if hasSshpass() && tryPasswordSSH(host, port) {
 		cachedSSHMethod = sshMethodPassword
 		return sshMethodPassword
 	}
@@ -57,23 +58,23 @@ func detectSSHMethod(vmName string) sshMethod {
 }
 
 // tryKeySSH tests if key-based SSH works
-func tryKeySSH(port int) bool {
-	cmd := exec.Command("ssh",
+func tryKeySSH(host string, port int) bool {
+	cmd := Command("ssh",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
 		"-o", "BatchMode=yes", // Fail instead of prompting for password
 		"-o", "ConnectTimeout=2",
 		"-o", "LogLevel=ERROR",
 		"-p", strconv.Itoa(port),
-		vmUser+"@127.0.0.1",
+		vmUser+"@"+host,
 		"true",
 	)
 	return cmd.Run() == nil
 }
 
 // tryPasswordSSH tests if password-based SSH works
-func tryPasswordSSH(port int) bool {
-	cmd := exec.Command("sshpass", "-p", vmPassword,
+func tryPasswordSSH(host string, port int) bool {
+	cmd := Command("sshpass", "-p", vmPassword,
 		"ssh",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
@@ -82,7 +83,7 @@ func tryPasswordSSH(port int) bool {
 		"-o", "LogLevel=ERROR",
 		"-o", "ConnectTimeout=2",
 		"-p", strconv.Itoa(port),
-		vmUser+"@127.0.0.1",
+		vmUser+"@"+host,
 		"true",
 	)
 	return cmd.Run() == nil
@@ -130,7 +131,11 @@ type limaVM struct {
 
 // GetStatus returns the current status of a VM
 func GetStatus(vmName string) VMStatus {
-	cmd := exec.Command("limactl", "list", "--format", "json")
+	if vmBackend == BackendLibvirt {
+		return libvirtStatus(vmName)
+	}
+
+	cmd := Command("limactl", "list", "--format", "json")
 	output, err := cmd.Output()
 	if err != nil {
 		return StatusUnknown
@@ -176,7 +181,7 @@ func IsRunning(vmName string) bool {
 
 // GetSSHPort returns the SSH port for a running VM
 func GetSSHPort(vmName string) (int, error) {
-	cmd := exec.Command("limactl", "list", "--format", "json")
+	cmd := Command("limactl", "list", "--format", "json")
 	output, err := cmd.Output()
 	if err != nil {
 		return 0, fmt.Errorf("failed to list VMs: %w", err)
@@ -199,8 +204,23 @@ func GetSSHPort(vmName string) (int, error) {
 	return 0, fmt.Errorf("VM %s not found", vmName)
 }
 
+// sshAddress returns where a VM's SSH server is reached: Lima forwards it
+// to a local port, libvirt guests answer on their own address
+func sshAddress(vmName string) (string, int, error) {
+	if vmBackend == BackendLibvirt {
+		ip, err := libvirtIP(vmName)
+		return ip, 22, err
+	}
+	port, err := GetSSHPort(vmName)
+	return "127.0.0.1", port, err
+}
+
 // Start starts an existing stopped VM
 func Start(vmName string) error {
+	if vmBackend == BackendLibvirt {
+		return libvirtStart(vmName)
+	}
+
 	if !Exists(vmName) {
 		return fmt.Errorf("VM %s does not exist", vmName)
 	}
@@ -210,7 +230,7 @@ func Start(vmName string) error {
 	}
 
 	// Start in background - Lima's SSH check won't work with password auth
-	cmd := exec.Command("limactl", "start", vmName, "--tty=false")
+	cmd := Command("limactl", "start", vmName, "--tty=false")
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start VM: %w", err)
 	}
@@ -225,11 +245,15 @@ func Start(vmName string) error {
 
 // Create creates and starts a new VM from a config file
 func Create(vmName, configPath string) error {
+	if vmBackend == BackendLibvirt {
+		return libvirtCreate(vmName, configPath)
+	}
+
 	if Exists(vmName) {
 		return fmt.Errorf("VM %s already exists", vmName)
 	}
 
-	cmd := exec.Command("limactl", "start", "--name="+vmName, configPath, "--tty=false")
+	cmd := Command("limactl", "start", "--name="+vmName, configPath, "--tty=false")
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to create VM: %w", err)
 	}
@@ -248,7 +272,11 @@ func Stop(vmName string) error {
 		return nil // Already stopped
 	}
 
-	cmd := exec.Command("limactl", "stop", vmName)
+	if vmBackend == BackendLibvirt {
+		return libvirtStop(vmName)
+	}
+
+	cmd := Command("limactl", "stop", vmName)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to stop VM: %w", err)
 	}
@@ -269,7 +297,11 @@ func Delete(vmName string) error {
 		}
 	}
 
-	cmd := exec.Command("limactl", "delete", vmName, "--force")
+	if vmBackend == BackendLibvirt {
+		return libvirtDelete(vmName)
+	}
+
+	cmd := Command("limactl", "delete", vmName, "--force")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to delete VM: %w", err)
 	}
@@ -290,7 +322,7 @@ func WaitForSSH(ctx context.Context, vmName string, timeout time.Duration) error
 		default:
 		}
 
-		port, err := GetSSHPort(vmName)
+		host, port, err := sshAddress(vmName)
 		if err != nil || port == 0 {
 			fmt.Printf("\r  Waiting for SSH... (%d)", attempt)
 			time.Sleep(2 * time.Second)
@@ -298,7 +330,7 @@ func WaitForSSH(ctx context.Context, vmName string, timeout time.Duration) error
 		}
 
 		// Try to connect
-		if testSSH(port) {
+		if testSSH(host, port) {
 			fmt.Println()
 			return nil
 		}
@@ -312,26 +344,26 @@ func WaitForSSH(ctx context.Context, vmName string, timeout time.Duration) error
 }
 
 // testSSH attempts an SSH connection to verify it works
-func testSSH(port int) bool {
+func testSSH(host string, port int) bool {
 	// Try key-based first
-	if tryKeySSH(port) {
+	if tryKeySSH(host, port) {
 		return true
 	}
 	// Fall back to password if sshpass available
 	if hasSshpass() {
-		return tryPasswordSSH(port)
+		return tryPasswordSSH(host, port)
 	}
 	return false
 }
 
 // Exec runs a command in the VM and returns the output
 func Exec(vmName string, command string) (string, error) {
-	port, err := GetSSHPort(vmName)
+	host, port, err := sshAddress(vmName)
 	if err != nil {
 		return "", err
 	}
 
-	cmd := buildSSHCommand(vmName, port, false, command)
+	cmd := buildSSHCommand(vmName, host, port, false, command)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return string(output), fmt.Errorf("command failed: %w", err)
@@ -341,7 +373,7 @@ func Exec(vmName string, command string) (string, error) {
 }
 
 // buildSSHCommand creates an SSH command using the best available auth method
-func buildSSHCommand(vmName string, port int, interactive bool, command string) *exec.Cmd {
+func buildSSHCommand(vmName, host string, port int, interactive bool, command string) *exec.Cmd {
 	method := detectSSHMethod(vmName)
 
 	var args []string
@@ -371,7 +403,7 @@ func buildSSHCommand(vmName string, port int, interactive bool, command string)
 
 	sshArgs = append(sshArgs,
 		"-p", strconv.Itoa(port),
-		vmUser+"@127.0.0.1",
+		vmUser+"@"+host,
 	)
 
 	if command != "" {
@@ -380,29 +412,46 @@ func buildSSHCommand(vmName string, port int, interactive bool, command string)
 
 	if method == sshMethodPassword && hasSshpass() {
 		args = append(args, sshArgs...)
-		return exec.Command("sshpass", args...)
+		return Command("sshpass", args...)
 	}
 
-	return exec.Command("ssh", sshArgs...)
+	return Command("ssh", sshArgs...)
 }
 
 // ExecStream runs a command in the VM and streams output to stdout/stderr
 func ExecStream(vmName string, command string) error {
-	port, err := GetSSHPort(vmName)
+	host, port, err := sshAddress(vmName)
 	if err != nil {
 		return err
 	}
 
-	cmd := buildSSHCommand(vmName, port, false, command)
+	cmd := buildSSHCommand(vmName, host, port, false, command)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	return cmd.Run()
 }
 
+// ExecPipe runs a command in the VM with its stdin and stdout connected to
+// the given reader and writer, for streaming files in and out. Stderr goes
+// to the terminal.
+func ExecPipe(vmName string, command string, stdin io.Reader, stdout io.Writer) error {
+	host, port, err := sshAddress(vmName)
+	if err != nil {
+		return err
+	}
+
+	cmd := buildSSHCommand(vmName, host, port, false, command)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
 // InteractiveShell opens an interactive SSH session to the VM
 func InteractiveShell(vmName string, command string) error {
-	port, err := GetSSHPort(vmName)
+	host, port, err := sshAddress(vmName)
 	if err != nil {
 		return err
 	}
@@ -412,7 +461,7 @@ func InteractiveShell(vmName string, command string) error {
 		command = "bash"
 	}
 
-	cmd := buildSSHCommand(vmName, port, true, command)
+	cmd := buildSSHCommand(vmName, host, port, true, command)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -448,7 +497,7 @@ func MountFilesystems(vmName string, mounts []Mount) error {
 // StartPortForwarding starts SSH port forwarding in the background
 // Returns a function to stop the forwarding
 func StartPortForwarding(vmName string, ports []PortForward) (func(), error) {
-	port, err := GetSSHPort(vmName)
+	host, port, err := sshAddress(vmName)
 	if err != nil {
 		return nil, err
 	}
@@ -482,9 +531,9 @@ func StartPortForwarding(vmName string, ports []PortForward) (func(), error) {
 		args = append(args, "-L", fmt.Sprintf("%d:localhost:%d", p.LocalPort, p.RemotePort))
 	}
 
-	args = append(args, "-p", strconv.Itoa(port), vmUser+"@127.0.0.1")
+	args = append(args, "-p", strconv.Itoa(port), vmUser+"@"+host)
 
-	cmd := exec.Command(cmdName, args...)
+	cmd := Command(cmdName, args...)
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start port forwarding: %w", err)
 	}
@@ -503,7 +552,7 @@ func StartPortForwarding(vmName string, ports []PortForward) (func(), error) {
 func KillPortForwarding(vmName string, firstPort int) error {
 	// Use pkill to find and kill the SSH process
 	pattern := fmt.Sprintf("ssh.*-L %d:localhost:%d.*%s@", firstPort, firstPort, vmUser)
-	cmd := exec.Command("pkill", "-f", pattern)
+	cmd := Command("pkill", "-f", pattern)
 	_ = cmd.Run() // Ignore errors - process might not exist
 	return nil
 }
@@ -568,7 +617,11 @@ func SnapshotCreate(vmName, snapshotName string) error {
 		return fmt.Errorf("VM must be stopped before creating a snapshot")
 	}
 
-	cmd := exec.Command("limactl", "snapshot", "create", vmName, "--tag", snapshotName)
+	if vmBackend == BackendLibvirt {
+		return libvirtSnapshot(vmName, "-c", snapshotName)
+	}
+
+	cmd := Command("limactl", "snapshot", "create", vmName, "--tag", snapshotName)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to create snapshot: %s: %w", string(output), err)
@@ -584,7 +637,11 @@ func SnapshotApply(vmName, snapshotName string) error {
 		return fmt.Errorf("VM must be stopped before applying a snapshot")
 	}
 
-	cmd := exec.Command("limactl", "snapshot", "apply", vmName, "--tag", snapshotName)
+	if vmBackend == BackendLibvirt {
+		return libvirtSnapshot(vmName, "-a", snapshotName)
+	}
+
+	cmd := Command("limactl", "snapshot", "apply", vmName, "--tag", snapshotName)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to apply snapshot: %s: %w", string(output), err)
@@ -595,7 +652,11 @@ func SnapshotApply(vmName, snapshotName string) error {
 
 // SnapshotDelete deletes a snapshot from a VM
 func SnapshotDelete(vmName, snapshotName string) error {
-	cmd := exec.Command("limactl", "snapshot", "delete", vmName, "--tag", snapshotName)
+	if vmBackend == BackendLibvirt {
+		return libvirtSnapshot(vmName, "-d", snapshotName)
+	}
+
+	cmd := Command("limactl", "snapshot", "delete", vmName, "--tag", snapshotName)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to delete snapshot: %s: %w", string(output), err)
@@ -606,7 +667,11 @@ func SnapshotDelete(vmName, snapshotName string) error {
 
 // SnapshotList lists all snapshots for a VM
 func SnapshotList(vmName string) ([]string, error) {
-	cmd := exec.Command("limactl", "snapshot", "list", vmName, "--quiet")
+	if vmBackend == BackendLibvirt {
+		return libvirtSnapshotList(vmName)
+	}
+
+	cmd := Command("limactl", "snapshot", "list", vmName, "--quiet")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// No snapshots returns error, treat as empty list
@@ -43,8 +43,12 @@ func (r *PreflightResult) AddError(check, message, fixCommand, fixURL string) {
 func RunPreflightChecks(projectRoot string) *PreflightResult {
 	result := &PreflightResult{}
 
-	// Check Lima
-	checkLima(result)
+	// Check Lima, or the libvirt tools that replace it
+	if vmBackend == BackendLibvirt {
+		checkLibvirt(result)
+	} else {
+		checkLima(result)
+	}
 
 	// Check sshpass
 	checkSshpass(result)
@@ -52,6 +56,11 @@ func RunPreflightChecks(projectRoot string) *PreflightResult {
 	// Check VM image
 	checkVMImage(result, projectRoot)
 
+	// Check WSL2 nested virtualization and checkout location
+	if IsWSL() {
+		checkWSL(result, projectRoot)
+	}
+
 	return result
 }
 
@@ -74,12 +83,56 @@ func checkLima(result *PreflightResult) {
 	}
 }
 
+// checkLibvirt verifies the libvirt tools are installed. The image only
+// accepts password logins until a key is authorized, so sshpass is required
+// here rather than optional as with Lima, which injects the user's key.
+func checkLibvirt(result *PreflightResult) {
+	for _, tool := range []struct{ name, pkg string }{
+		{"virsh", "libvirt-clients"},
+		{"virt-install", "virtinst"},
+		{"qemu-img", "qemu-utils"},
+		{"sshpass", "sshpass"},
+	} {
+		if _, err := exec.LookPath(tool.name); err != nil {
+			result.AddError(
+				tool.name,
+				tool.name+" is not installed",
+				"sudo apt install "+tool.pkg,
+				"https://libvirt.org/docs.html",
+			)
+		}
+	}
+}
+
 // checkSshpass verifies sshpass is installed (optional - only needed for password auth fallback)
 func checkSshpass(result *PreflightResult) {
 	// sshpass is now optional - we prefer SSH key auth
 	// Only warn, don't add as error
 }
 
+// checkWSL verifies a WSL2 distro can run the Lima VM: QEMU needs /dev/kvm,
+// which WSL2 only exposes with nested virtualization enabled, and the
+// checkout must live in the distro's own filesystem because Lima's mounts
+// from /mnt/c are slow and lose file permissions
+func checkWSL(result *PreflightResult, projectRoot string) {
+	if _, err := os.Stat("/dev/kvm"); err != nil {
+		result.AddError(
+			"wsl-kvm",
+			"/dev/kvm is missing, so QEMU cannot use hardware virtualization",
+			`add "nestedVirtualization=true" under [wsl2] in %UserProfile%\.wslconfig, then run "wsl --shutdown"`,
+			"https://learn.microsoft.com/windows/wsl/wsl-config#wslconfig",
+		)
+	}
+	if strings.HasPrefix(projectRoot, "/mnt/") {
+		result.AddError(
+			"wsl-checkout",
+			fmt.Sprintf("Project checkout is on the Windows filesystem:\n    %s", projectRoot),
+			"clone the repository inside WSL, e.g. into ~/Projects/bloud",
+			"",
+		)
+	}
+}
+
 // checkVMImage verifies the VM image exists
 func checkVMImage(result *PreflightResult, projectRoot string) {
 	imagePath := GetImagePath(projectRoot)
@@ -161,6 +214,20 @@ func PrintImageBuildInstructions() {
 	fmt.Println()
 }
 
+// RunWSLPreflightChecks runs only the WSL2 checks, for ./bloud setup
+func RunWSLPreflightChecks(projectRoot string) *PreflightResult {
+	result := &PreflightResult{}
+	checkWSL(result, projectRoot)
+	return result
+}
+
+// RunLibvirtPreflightChecks runs only the libvirt tool checks, for ./bloud setup
+func RunLibvirtPreflightChecks() *PreflightResult {
+	result := &PreflightResult{}
+	checkLibvirt(result)
+	return result
+}
+
 // RunNativePreflightChecks runs preflight checks for native NixOS
 func RunNativePreflightChecks() *PreflightResult {
 	result := &PreflightResult{}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultWatchInterval = 2 * time.Second
+	watchEventLimit      = 10
+	watchRetryDelay      = 3 * time.Second
+)
+
+// ANSI sequences for redrawing the dashboard in place
+const (
+	ansiClear      = "\033[H\033[2J"
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+)
+
+// watchEvent is an app status change seen on the app events stream
+type watchEvent struct {
+	at   time.Time
+	app  string
+	from string // empty for a newly listed app
+	to   string // empty for an app that left the list
+}
+
+// appEventWatcher follows the host-agent's app events SSE stream and turns
+// each snapshot of the installed apps into status changes
+type appEventWatcher struct {
+	mu        sync.Mutex
+	connected bool
+	apps      map[string]string // name -> status from the latest snapshot
+	events    []watchEvent      // newest last
+}
+
+// run keeps the stream open, reconnecting whenever the host-agent is back
+func (w *appEventWatcher) run() {
+	for {
+		if api, err := resolveHostAgentAPI(false); err == nil && api.pipe != nil {
+			w.follow(api)
+		}
+		time.Sleep(watchRetryDelay)
+	}
+}
+
+func (w *appEventWatcher) follow(api *hostAgentAPI) {
+	r, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(api.pipe(fmt.Sprintf("curl -sN http://localhost:%d/api/v1/apps/events", api.port), pw))
+	}()
+	defer r.Close()
+
+	// Each event carries the whole app list, which can outgrow the default
+	// line limit
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var apps []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		}
+		if json.Unmarshal([]byte(data), &apps) != nil {
+			continue
+		}
+		snapshot := make(map[string]string, len(apps))
+		for _, app := range apps {
+			snapshot[app.Name] = app.Status
+		}
+		w.update(snapshot)
+	}
+
+	w.mu.Lock()
+	w.connected = false
+	w.mu.Unlock()
+}
+
+// update records the differences from the previous snapshot; the first one
+// only sets the baseline
+func (w *appEventWatcher) update(snapshot map[string]string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.connected = true
+	if w.apps != nil {
+		now := time.Now()
+		for _, name := range sortedKeys(snapshot) {
+			if prev, ok := w.apps[name]; !ok || prev != snapshot[name] {
+				w.events = append(w.events, watchEvent{at: now, app: name, from: prev, to: snapshot[name]})
+			}
+		}
+		for _, name := range sortedKeys(w.apps) {
+			if _, ok := snapshot[name]; !ok {
+				w.events = append(w.events, watchEvent{at: now, app: name, from: w.apps[name]})
+			}
+		}
+		if len(w.events) > watchEventLimit {
+			w.events = w.events[len(w.events)-watchEventLimit:]
+		}
+	}
+	w.apps = snapshot
+}
+
+// state returns a copy of what the dashboard shows
+func (w *appEventWatcher) state() (bool, map[string]string, []watchEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	apps := make(map[string]string, len(w.apps))
+	for name, status := range w.apps {
+		apps[name] = status
+	}
+	return w.connected, apps, append([]watchEvent(nil), w.events...)
+}
+
+// cmdWatch redraws a dashboard of the environment every interval until
+// interrupted: VM and service state, health checks, the operation queue and
+// recent app status changes
+func cmdWatch(interval time.Duration) int {
+	if interval <= 0 {
+		errorf("--interval must be positive")
+		return exitUsage
+	}
+
+	events := &appEventWatcher{}
+	go events.run()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+	fmt.Print(ansiHideCursor)
+	defer fmt.Print(ansiShowCursor)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Render off-screen first so the clear and redraw happen together
+		frame := renderWatchFrame(interval, events)
+		fmt.Print(ansiClear + frame)
+
+		select {
+		case <-sig:
+			fmt.Println()
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+func renderWatchFrame(interval time.Duration, events *appEventWatcher) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sBloud%s  every %s  %s  (Ctrl-C to stop)\n\n", colorCyan, colorReset, interval, time.Now().Format("15:04:05"))
+
+	var agentUp bool
+	if isPVEMode() {
+		agentUp = renderWatchPVE(&b)
+	} else {
+		agentUp = renderWatchDev(&b)
+	}
+	if !agentUp {
+		return b.String()
+	}
+
+	api, err := resolveHostAgentAPI(false)
+	if err != nil {
+		fmt.Fprintf(&b, "\n  %s%v%s\n", colorRed, err, colorReset)
+		return b.String()
+	}
+	health, err := fetchHealthSummary(context.Background(), api.client)
+	if err != nil {
+		fmt.Fprintf(&b, "\n  Health:       %s%v%s\n", colorRed, err, colorReset)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "\n  Health:       %s\n", colorHealth(health.Status, 0))
+	for _, check := range health.Checks {
+		detail := check.Detail
+		if check.Error != "" {
+			detail = check.Error
+		}
+		fmt.Fprintf(&b, "    %-16s %s %s\n", check.Name, colorHealth(check.Status, 8), detail)
+	}
+	fmt.Fprintf(&b, "  Queue depth:  %s\n", fetchQueueDepth(api))
+
+	connected, statuses, recent := events.state()
+
+	b.WriteString("\n  Apps\n")
+	if len(health.Apps) == 0 {
+		b.WriteString("    (none installed)\n")
+	}
+	for _, app := range health.Apps {
+		// The event stream is newer than the summary when it's connected
+		status := app.Status
+		if s, ok := statuses[app.Name]; ok {
+			status = s
+		}
+		check := "-"
+		if app.LastCheck != nil {
+			check = colorHealth("healthy", 0)
+			if !app.LastCheck.Healthy {
+				check = colorHealth("unhealthy", 0)
+			}
+		}
+		fmt.Fprintf(&b, "    %-20s %s %s\n", app.Name, colorStatus(status, 12), check)
+	}
+
+	b.WriteString("\n  Recent events")
+	if !connected {
+		fmt.Fprintf(&b, " %s(connecting...)%s", colorYellow, colorReset)
+	}
+	b.WriteString("\n")
+	if len(recent) == 0 {
+		b.WriteString("    (none yet)\n")
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		e := recent[i]
+		var change string
+		switch {
+		case e.from == "":
+			change = "added as " + colorStatus(e.to, 0)
+		case e.to == "":
+			change = "removed"
+		default:
+			change = e.from + " -> " + colorStatus(e.to, 0)
+		}
+		fmt.Fprintf(&b, "    %s  %-20s %s\n", e.at.Format("15:04:05"), e.app, change)
+	}
+	return b.String()
+}
+
+// renderWatchDev writes the Lima or native environment's state and reports
+// whether the host-agent is up
+func renderWatchDev(b *strings.Builder) bool {
+	status := collectDevStatus()
+
+	if status.Backend == "native" {
+		fmt.Fprintf(b, "  Runtime:      %sNative NixOS%s\n", colorGreen, colorReset)
+	} else {
+		switch status.VM {
+		case "running":
+			fmt.Fprintf(b, "  VM:           %sRunning%s\n", colorGreen, colorReset)
+		case "stopped":
+			fmt.Fprintf(b, "  VM:           %sStopped%s\n", colorYellow, colorReset)
+			return false
+		default:
+			fmt.Fprintf(b, "  VM:           %sNot created%s\n", colorRed, colorReset)
+			return false
+		}
+	}
+
+	if !status.Session {
+		fmt.Fprintf(b, "  Tmux Session: %sNot running%s\n", colorRed, colorReset)
+		return false
+	}
+	fmt.Fprintf(b, "  Tmux Session: %sRunning%s\n", colorGreen, colorReset)
+	fmt.Fprintf(b, "  Host Agent:   %s\n", upOrStarting(status.HostAgent))
+	fmt.Fprintf(b, "  Web UI:       %s\n", upOrStarting(status.WebUI))
+
+	b.WriteString("\n  Containers\n")
+	if len(status.Containers) == 0 {
+		b.WriteString("    (none running)\n")
+	}
+	for _, c := range status.Containers {
+		fmt.Fprintf(b, "    %-40s %s\n", c.Name, c.Status)
+	}
+	return status.HostAgent
+}
+
+// renderWatchPVE writes the Proxmox VM's state and reports whether the
+// host-agent is up
+func renderWatchPVE(b *strings.Builder) bool {
+	cfg := getPVEConfig()
+	status := collectPVEStatus(cfg)
+
+	switch status.VM {
+	case "not_created":
+		fmt.Fprintf(b, "  VM:           %sNot created%s (%s, VMID %s)\n", colorRed, colorReset, cfg.Host, cfg.VMID)
+		return false
+	case "stopped":
+		fmt.Fprintf(b, "  VM:           %sStopped%s (%s, VMID %s)\n", colorYellow, colorReset, cfg.Host, cfg.VMID)
+		return false
+	}
+	fmt.Fprintf(b, "  VM:           %sRunning%s (%s, VMID %s)\n", colorGreen, colorReset, cfg.Host, cfg.VMID)
+	if status.IP == "" {
+		fmt.Fprintf(b, "  IP:           %sUnknown (no guest agent?)%s\n", colorYellow, colorReset)
+		return false
+	}
+	fmt.Fprintf(b, "  IP:           %s\n", status.IP)
+
+	b.WriteString("\n  Services\n")
+	for _, name := range sortedKeys(status.Services) {
+		state := status.Services[name]
+		color := colorRed
+		if state == "active" {
+			color = colorGreen
+		}
+		fmt.Fprintf(b, "    %-30s %s%s%s\n", name, color, state, colorReset)
+	}
+	fmt.Fprintf(b, "    %-30s %s\n", "host-agent API", upOrStarting(status.HostAgent))
+	return status.HostAgent
+}
+
+func upOrStarting(up bool) string {
+	if up {
+		return colorGreen + "Running" + colorReset
+	}
+	return colorYellow + "Starting..." + colorReset
+}
+
+// fetchQueueDepth reads the install/uninstall queue gauge from the
+// Prometheus metrics
+func fetchQueueDepth(api *hostAgentAPI) string {
+	output, err := api.exec(fmt.Sprintf("curl -s http://localhost:%d/metrics", api.port))
+	if err != nil {
+		return "-"
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if value, ok := strings.CutPrefix(line, "bloud_operation_queue_depth "); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return "-"
+}
@@ -80,8 +80,27 @@
             {
               isoImage.storeContents = [
                 self.nixosConfigurations.bloud.config.system.build.toplevel
+                self.nixosConfigurations.bloud-encrypted.config.system.build.toplevel
               ];
               bloud.installer.systemPath = "${self.nixosConfigurations.bloud.config.system.build.toplevel}";
+              bloud.installer.encryptedSystemPath = "${self.nixosConfigurations.bloud-encrypted.config.system.build.toplevel}";
+            }
+          ];
+        };
+
+        # Installer as kexec kernel + initrd, for cloud servers (./bloud deploy)
+        # Build with: nix build .#packages.x86_64-linux.kexec
+        kexec = nixpkgs.lib.nixosSystem {
+          system = "x86_64-linux";
+          modules = [
+            ./nixos/kexec.nix
+            {
+              netboot.storeContents = [
+                self.nixosConfigurations.bloud.config.system.build.toplevel
+                self.nixosConfigurations.bloud-encrypted.config.system.build.toplevel
+              ];
+              bloud.installer.systemPath = "${self.nixosConfigurations.bloud.config.system.build.toplevel}";
+              bloud.installer.encryptedSystemPath = "${self.nixosConfigurations.bloud-encrypted.config.system.build.toplevel}";
             }
           ];
         };
@@ -93,6 +112,21 @@
           modules = [
             ./nixos/installed.nix
             ./nixos/bloud.nix
+            # The commit, for update release notes (nixos-version --json)
+            { system.configurationRevision = self.rev or null; }
+          ];
+        };
+
+        # Installed system for encrypted installs, unlocking the LUKS
+        # containers in the initrd until bloud-disks.nix takes over
+        bloud-encrypted = nixpkgs.lib.nixosSystem {
+          system = "x86_64-linux";
+          modules = [
+            ./nixos/installed.nix
+            ./nixos/bloud.nix
+            # The commit, for update release notes (nixos-version --json)
+            { system.configurationRevision = self.rev or null; }
+            ./nixos/encrypted.nix
           ];
         };
       };
@@ -113,6 +147,8 @@
         // (if system == "x86_64-linux" then {
           # Bootable appliance ISO
           iso = self.nixosConfigurations.iso.config.system.build.isoImage;
+          # Installer kernel, initrd and kexec-boot script for ./bloud deploy
+          kexec = self.nixosConfigurations.kexec.config.system.build.kexecTree;
         } else {})
       );
 
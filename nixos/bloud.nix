@@ -64,9 +64,108 @@ in
       default = [];
       description = "Container images to pull sequentially before starting apps (avoids parallel pull storms)";
     };
+
+    # Where the host agent keeps generated secrets. Env files for app
+    # services are still written to the data directory with every backend.
+    secrets = {
+      backend = lib.mkOption {
+        type = lib.types.enum [ "file" "sops" "vault" ];
+        default = "file";
+        description = "Secrets backend: secrets.json in the data directory, a sops-encrypted file, or HashiCorp Vault KV v2";
+      };
+
+      keyFile = lib.mkOption {
+        type = lib.types.nullOr lib.types.str;
+        default = null;
+        description = "Machine key sealing secrets.json with the file backend (create with `host-agent secrets-key generate`)";
+        example = "/etc/bloud/secrets.key";
+      };
+
+      keyCredential = lib.mkOption {
+        type = lib.types.nullOr lib.types.str;
+        default = null;
+        description = "Machine key sealed to the TPM with `systemd-creds encrypt --with-key=tpm2 --name=bloud-secrets-key`, used instead of keyFile";
+        example = "/etc/bloud/secrets-key.cred";
+      };
+
+      sopsFile = lib.mkOption {
+        type = lib.types.nullOr lib.types.str;
+        default = null;
+        description = "sops-encrypted JSON file holding the secrets (e.g. committed with the flake); must be writable by the bloud user";
+        example = "/home/bloud/bloud-config/secrets/bloud.sops.json";
+      };
+
+      sopsAgeKeyFile = lib.mkOption {
+        type = lib.types.nullOr lib.types.str;
+        default = null;
+        description = "Age key sops decrypts with (SOPS_AGE_KEY_FILE)";
+        example = "/var/lib/sops-nix/key.txt";
+      };
+
+      vaultAddr = lib.mkOption {
+        type = lib.types.nullOr lib.types.str;
+        default = null;
+        description = "Vault server address";
+        example = "https://vault.example.com:8200";
+      };
+
+      vaultPath = lib.mkOption {
+        type = lib.types.str;
+        default = "secret/bloud";
+        description = "KV v2 secret holding the secrets, as <mount>/<path>";
+      };
+
+      vaultTokenFile = lib.mkOption {
+        type = lib.types.nullOr lib.types.str;
+        default = null;
+        description = "File containing the Vault token (readable by the bloud user)";
+      };
+
+      environment = lib.mkOption {
+        type = lib.types.attrsOf lib.types.str;
+        internal = true;
+        readOnly = true;
+        default = lib.optionalAttrs (cfg.secrets.backend != "file") {
+          BLOUD_SECRETS_BACKEND = cfg.secrets.backend;
+        } // lib.optionalAttrs (cfg.secrets.backend == "file" && cfg.secrets.keyFile != null) {
+          BLOUD_SECRETS_KEY_FILE = cfg.secrets.keyFile;
+        } // lib.optionalAttrs (cfg.secrets.backend == "file" && cfg.secrets.keyCredential != null) {
+          BLOUD_SECRETS_KEY_CREDENTIAL = cfg.secrets.keyCredential;
+        } // lib.optionalAttrs (cfg.secrets.backend == "sops") ({
+          BLOUD_SECRETS_SOPS_FILE = toString cfg.secrets.sopsFile;
+          BLOUD_SECRETS_SOPS_BIN = "${pkgs.sops}/bin/sops";
+        } // lib.optionalAttrs (cfg.secrets.sopsAgeKeyFile != null) {
+          SOPS_AGE_KEY_FILE = cfg.secrets.sopsAgeKeyFile;
+        }) // lib.optionalAttrs (cfg.secrets.backend == "vault") {
+          VAULT_ADDR = toString cfg.secrets.vaultAddr;
+          BLOUD_SECRETS_VAULT_PATH = cfg.secrets.vaultPath;
+          BLOUD_SECRETS_VAULT_TOKEN_FILE = toString cfg.secrets.vaultTokenFile;
+        };
+        description = "Environment selecting the secrets backend, shared by the host agent and its hooks";
+      };
+    };
   };
 
   config = lib.mkIf cfg.enable {
+    assertions = [
+      {
+        assertion = cfg.secrets.keyFile == null || cfg.secrets.keyCredential == null;
+        message = "set only one of bloud.secrets.keyFile and bloud.secrets.keyCredential";
+      }
+      {
+        assertion = cfg.secrets.backend != "sops" || cfg.secrets.sopsFile != null;
+        message = "bloud.secrets.sopsFile is required for the sops backend";
+      }
+      {
+        assertion = cfg.secrets.backend != "vault" || (cfg.secrets.vaultAddr != null && cfg.secrets.vaultTokenFile != null);
+        message = "bloud.secrets.vaultAddr and bloud.secrets.vaultTokenFile are required for the vault backend";
+      }
+    ];
+
+    # init-secrets and app prestart hooks read secrets through the same backend
+    systemd.user.extraConfig = lib.concatStringsSep "\n"
+      (lib.mapAttrsToList (name: value: "DefaultEnvironment=\"${name}=${value}\"") cfg.secrets.environment);
+
     # Enable core infrastructure by default
     bloud.apps.postgres.enable = lib.mkDefault true;   # Shared database
     bloud.apps.redis.enable = lib.mkDefault true;      # Shared cache (used by Authentik)
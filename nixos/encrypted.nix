@@ -0,0 +1,28 @@
+# Bloud Installed System, encrypted
+#
+# Added to installed.nix for installs that chose full-disk encryption
+# (services/installer/internal/partition/encryption.go). The pre-built system
+# can't read the installer's bloud-disks.nix, which declares exactly the
+# containers that were created, so until the first rebuild this unlocks the
+# root container and whichever of the others exist.
+
+{ lib, ... }:
+
+{
+  # systemd asks for the passphrase once and tries it on each container; a
+  # key the installer sealed to the TPM unlocks them without asking.
+  boot.initrd.systemd.enable = true;
+  boot.initrd.luks.devices = {
+    cryptroot = {
+      device = "/dev/disk/by-label/nixos-crypt";
+      crypttabExtraOpts = [ "tpm2-device=auto" ];
+    };
+  } // lib.mapAttrs (name: label: {
+    device = "/dev/disk/by-label/${label}";
+    # Only there when the layout has a data partition or a mirror
+    crypttabExtraOpts = [ "nofail" "tpm2-device=auto" "x-systemd.device-timeout=5s" ];
+  }) {
+    cryptdata = "bloud-data-crypt";
+    cryptmirror = "bloud-mirror-crypt";
+  };
+}
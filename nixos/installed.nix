@@ -7,6 +7,19 @@
 #   Partition 1 → BIOS boot (1MiB–2MiB, bios_grub type, no filesystem)
 #   Partition 2 → label "ESP"   → /boot (FAT32, EFI)
 #   Partition 3 → label "nixos" → /     (ext4)
+# and, when the install request's layout asks for them:
+#   Partition 4 → label "bloud-data" → /home/bloud/.local/share/bloud, the data dir
+#                 (ext4 or btrfs, rest of the disk after rootGB)
+#                 or a member of ZFS pool "bloud" (dataset bloud/data)
+#   Last        → label "swap"       → swap (swapGB at the end of the disk)
+# A btrfs or ZFS data store can be mirrored onto a second disk. The installer
+# also writes /etc/nixos/bloud-disks.nix describing the exact layout.
+#
+# With encryption, partition 3, partition 4 and the mirror are LUKS2
+# containers (labels "nixos-crypt", "bloud-data-crypt", "bloud-mirror-crypt")
+# holding the filesystems above, and swap is keyed randomly at each boot.
+# Encrypted installs start from bloud-encrypted (this plus encrypted.nix),
+# and bloud-disks.nix declares exactly the containers that were created.
 
 { config, pkgs, lib, ... }:
 
@@ -14,6 +27,12 @@
   imports = [
     ./modules/host-agent.nix
   ]
+  # Disk layout written by the installer (requires nixos-rebuild --impure)
+  ++ lib.optional (builtins.pathExists /etc/nixos/bloud-disks.nix)
+       /etc/nixos/bloud-disks.nix
+  # Network settings from the installer, when any were given
+  ++ lib.optional (builtins.pathExists /etc/nixos/bloud-network.nix)
+       /etc/nixos/bloud-network.nix
   # Load app config generated by host-agent (requires nixos-rebuild --impure)
   ++ lib.optional (builtins.pathExists /home/bloud/.local/share/bloud/nix/apps.nix)
        /home/bloud/.local/share/bloud/nix/apps.nix;
@@ -49,9 +68,176 @@
     fsType = "vfat";
   };
 
+  # Optional partitions — nofail so default layouts without them still boot.
+  # bloud-disks.nix pins these down once the host-agent first rebuilds. The
+  # data store holds the data dir, so it's mounted before anything using it
+  # starts.
+  fileSystems."/home/${config.bloud.user}/.local/share/${config.bloud.dataDir}" = {
+    device = "/dev/disk/by-label/bloud-data";
+    fsType = "auto";
+    options = [ "nofail" "x-systemd.device-timeout=5s" ]
+      ++ map (unit: "x-systemd.before=${unit}") [ "bloud-user-services.service" "bloud-restore.service" "bloud-host-agent.service" ];
+  };
+
+  swapDevices = [{
+    device = "/dev/disk/by-label/swap";
+    options = [ "nofail" "x-systemd.device-timeout=5s" ];
+  }];
+
+  # Data store filesystems the installer can create
+  # (networking.hostId, which ZFS needs, is set below)
+  boot.supportedFilesystems = [ "btrfs" "zfs" ];
+
+  # The system installed from the ISO is pre-built, so it can't know about a
+  # ZFS data store until bloud-disks.nix is imported; import the pool if the
+  # installer created one.
+  systemd.services.bloud-data-pool = {
+    description = "Import the Bloud ZFS data pool";
+    wantedBy = [ "multi-user.target" ];
+    before = [ "bloud-user-services.service" ];
+    after = [ "zfs-import.target" ];
+    path = [ config.boot.zfs.package ];
+    serviceConfig = {
+      Type = "oneshot";
+      RemainAfterExit = true;
+    };
+    script = ''
+      zpool list bloud >/dev/null 2>&1 && exit 0
+      if zpool import | grep -q "pool: bloud$"; then
+        zpool import bloud
+      fi
+    '';
+  };
+
+  # Static address, VLAN and hostname from the installer. Like the ZFS pool
+  # above, the pre-built system applies them from /etc/bloud/network.env at
+  # boot; bloud-network.nix declares them properly and disables this.
+  systemd.services.bloud-network-setup = {
+    description = "Apply network settings from the Bloud installer";
+    wantedBy = [ "multi-user.target" ];
+    after = [ "network-pre.target" ];
+    before = [ "network-online.target" "avahi-daemon.service" ];
+    unitConfig.ConditionPathExists = "/etc/bloud/network.env";
+    path = with pkgs; [ iproute2 nettools openresolv ];
+    serviceConfig = {
+      Type = "oneshot";
+      RemainAfterExit = true;
+    };
+    script = ''
+      . /etc/bloud/network.env
+      if [ -n "$BLOUD_HOSTNAME" ]; then
+        hostname "$BLOUD_HOSTNAME"
+      fi
+      dev="$BLOUD_INTERFACE"
+      if [ -n "$BLOUD_VLAN" ]; then
+        dev="$BLOUD_INTERFACE.$BLOUD_VLAN"
+        ip link show "$dev" >/dev/null 2>&1 \
+          || ip link add link "$BLOUD_INTERFACE" name "$dev" type vlan id "$BLOUD_VLAN"
+        ip link set "$dev" up
+      fi
+      if [ -n "$BLOUD_ADDRESS" ]; then
+        ip link set "$dev" up
+        ip addr replace "$BLOUD_ADDRESS" dev "$dev"
+        ip route replace default via "$BLOUD_GATEWAY" dev "$dev"
+        for ns in $BLOUD_DNS; do echo "nameserver $ns"; done | resolvconf -a "$dev.bloud"
+      fi
+    '';
+  };
+
+  # Backup restored by the installer: it unpacked the data dir and left the
+  # database dump for this service to replay once PostgreSQL is up, before
+  # the host-agent opens the database. The rebuild brings back the apps
+  # listed in the restored nix/apps.nix.
+  systemd.services.bloud-restore = let
+    dataDir = "/home/${config.bloud.user}/.local/share/${config.bloud.dataDir}";
+  in {
+    description = "Replay the database from a restored Bloud backup";
+    wantedBy = [ "multi-user.target" ];
+    after = [ "bloud-user-services.service" ];
+    before = [ "bloud-host-agent.service" ];
+    requiredBy = [ "bloud-host-agent.service" ];
+    unitConfig.ConditionPathExists = "${dataDir}/restore/postgres.sql";
+    path = [ pkgs.podman "/run/wrappers" ];
+    serviceConfig = {
+      Type = "oneshot";
+      User = config.bloud.user;
+      Group = "users";
+    };
+    script = ''
+      for i in $(seq 1 300); do
+        podman exec apps-postgres psql -U apps -d postgres -c "SELECT 1" >/dev/null 2>&1 && break
+        sleep 1
+      done
+      # Stops at the first error, keeping the dump and failing the unit, so
+      # the host-agent doesn't start on a half-restored database. The dump
+      # recreates every role, which fails for the one replaying it.
+      if ! sed -e '/^DROP ROLE IF EXISTS apps;$/d' -e '/^CREATE ROLE apps;$/d' ${dataDir}/restore/postgres.sql \
+          | podman exec -i apps-postgres psql -U apps -d postgres -q -v ON_ERROR_STOP=1 >/dev/null; then
+        echo "Replaying the database failed; the dump is kept in ${dataDir}/restore" >&2
+        exit 1
+      fi
+      rm -r ${dataDir}/restore
+      sudo env _NIXOS_REBUILD_REEXEC=1 PATH=/run/current-system/sw/bin \
+        nixos-rebuild switch --flake ${config.bloud."host-agent".sourceDir}#bloud --impure \
+        || echo "Rebuild failed; restored apps start with the next rebuild" >&2
+    '';
+  };
+
+  # Configuration the pre-built system lacks until bloud-disks.nix is
+  # imported, such as encrypted swap and remote unlock in the initrd. The
+  # installer asks for this rebuild when it wrote some; it takes effect at
+  # the next boot.
+  systemd.services.bloud-rebuild-pending = {
+    description = "Rebuild with the Bloud installer's generated configuration";
+    wantedBy = [ "multi-user.target" ];
+    after = [ "bloud-restore.service" "network-online.target" ];
+    wants = [ "network-online.target" ];
+    unitConfig.ConditionPathExists = "/etc/bloud/rebuild-pending";
+    serviceConfig.Type = "oneshot";
+    script = ''
+      env _NIXOS_REBUILD_REEXEC=1 PATH=/run/current-system/sw/bin \
+        nixos-rebuild boot --flake ${config.bloud."host-agent".sourceDir}#bloud --impure
+      rm /etc/bloud/rebuild-pending
+    '';
+  };
+
+  # Admin account and initial apps from an installer answer file, set up
+  # through the host-agent's localhost API once Authentik is ready. The file
+  # holds the admin password and is removed once the admin exists.
+  systemd.services.bloud-provision = {
+    description = "Create the admin and install apps from the Bloud installer";
+    wantedBy = [ "multi-user.target" ];
+    after = [ "bloud-host-agent.service" ];
+    wants = [ "bloud-host-agent.service" ];
+    unitConfig.ConditionPathExists = "/etc/bloud/provision.json";
+    path = with pkgs; [ curl jq ];
+    serviceConfig.Type = "oneshot";
+    script = ''
+      api=http://localhost:${toString config.bloud."host-agent".port}/api/v1
+      file=/etc/bloud/provision.json
+      for i in $(seq 1 600); do
+        curl -sf "$api/setup/status" | jq -e .authentikReady >/dev/null && break
+        sleep 2
+      done
+
+      if jq -e .admin "$file" >/dev/null && curl -sf "$api/setup/status" | jq -e .setupRequired >/dev/null; then
+        jq .admin "$file" | curl -sf -X POST -H 'Content-Type: application/json' --data @- "$api/setup/create-user" >/dev/null
+      fi
+      jq 'del(.admin)' "$file" > "$file.tmp" && mv "$file.tmp" "$file"
+
+      for app in $(jq -r '.apps // [] | .[]' "$file"); do
+        echo "Installing $app"
+        curl -sf -X POST --max-time 1800 "$api/apps/$app/install" >/dev/null \
+          || echo "Failed to install $app; install it from the web UI" >&2
+      done
+      rm "$file"
+    '';
+  };
+
   # Network
   networking = {
     hostName = "bloud";
+    hostId = lib.mkDefault "b10d0001";
     useDHCP = true;
 
     firewall = {
@@ -150,6 +336,7 @@
   bloud."host-agent" = {
     enable = true;
     flakeTarget = "bloud";
+    mdnsApps = true;
   };
 
   # Postgres — host-agent uses this as its database
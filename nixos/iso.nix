@@ -1,7 +1,8 @@
 # Bloud Installer ISO
 #
 # Minimal bootable NixOS image that runs the Bloud installer service.
-# Boots to a terminal that directs users to http://bloud.local to set up.
+# Boots to a console installer on tty1; users can also set up from
+# http://bloud.local on another device.
 # After installation the machine reboots into the installed Bloud system.
 #
 # Build with: nix build .#packages.x86_64-linux.iso
@@ -29,6 +30,13 @@
     volumeID = "BLOUD";
     makeEfiBootable = true;
     makeUsbBootable = true;
+    # An answer file at the repo root makes the ISO install unattended
+    # (services/installer/internal/answers). Flakes only see tracked files,
+    # so it needs `git add -f bloud-install.yaml` like build/.
+    contents = lib.optional (builtins.pathExists ../bloud-install.yaml) {
+      source = ../bloud-install.yaml;
+      target = "/bloud-install.yaml";
+    };
   };
 
   # Boot loader
@@ -41,12 +49,18 @@
     options = [ "size=80%" "mode=755" ];
   };
 
+  # Data store filesystems the installer can create (partition/datastore.go).
+  # The ISO has its own host ID (below); the installer exports the pool
+  # when done.
+  boot.supportedFilesystems = [ "btrfs" "zfs" ];
+
   # QEMU guest agent for Proxmox/KVM testing
   services.qemuGuest.enable = true;
 
   # Network
   networking = {
     hostName = "bloud";
+    hostId = "4a9c3e01";
     useDHCP = true;
 
     firewall = {
@@ -98,8 +112,10 @@
   # Root password for SSH debug access on the installer
   users.users.root.initialHashedPassword = "";
 
-  # Terminal welcome banner shown before the login prompt
-  # Directs users to the web UI
+  # Terminal welcome banner shown before the login prompt on tty2 and up
+  # (tty1 runs the console installer). Directs users to the web UI; the
+  # installer appends this boot's setup code, which the web UI asks for.
+  services.getty.extraArgs = [ "--issue-file" "/etc/issue:/run/bloud-installer/issue" ];
   environment.etc."issue".text = ''
 
     ╔══════════════════════════════════════════════════╗
@@ -110,7 +126,8 @@
     ║                                                  ║
     ║      http://bloud.local                          ║
     ║                                                  ║
-    ║  to set up your server.                          ║
+    ║  to set up your server, or press Alt+F1 to set   ║
+    ║  up from this screen.                            ║
     ║                                                  ║
     ╚══════════════════════════════════════════════════╝
 
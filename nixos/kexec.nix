@@ -0,0 +1,84 @@
+# Bloud Installer (kexec)
+#
+# The installer environment from iso.nix as a kernel + initrd that a running
+# Linux machine can kexec into, for cloud servers that can't boot our ISO.
+# ./bloud deploy boots a stock Debian server, kexecs it into this and then
+# drives the installer API over SSH, as it does for the ISO on Proxmox.
+#
+# Unlike the ISO this is reachable on a public address, so SSH only accepts
+# the key passed on the kernel command line as bloud.authorized_key=<base64>.
+#
+# Build with: nix build .#packages.x86_64-linux.kexec
+# Boot with:  ./kexec-boot (from the build output, needs kexec-tools)
+
+{ config, pkgs, lib, modulesPath, ... }:
+
+{
+  imports = [
+    (modulesPath + "/installer/netboot/netboot.nix")
+    (modulesPath + "/profiles/qemu-guest.nix")
+    ./modules/installer.nix
+  ];
+
+  networking = {
+    hostName = "bloud";
+    useDHCP = true;
+
+    firewall = {
+      enable = true;
+      allowedTCPPorts = [ 22 ];
+    };
+  };
+
+  # Installer service, only reached through SSH
+  bloud.installer = {
+    enable = true;
+  };
+
+  services.openssh = {
+    enable = true;
+    settings = {
+      PermitRootLogin = "prohibit-password";
+      PasswordAuthentication = false;
+    };
+  };
+
+  # Install the deployer's key from the kernel command line before sshd starts
+  systemd.services.bloud-authorized-key = {
+    description = "Authorize the SSH key from the kernel command line";
+    wantedBy = [ "multi-user.target" ];
+    before = [ "sshd.service" ];
+    serviceConfig.Type = "oneshot";
+    script = ''
+      for arg in $(cat /proc/cmdline); do
+        case "$arg" in
+          bloud.authorized_key=*)
+            mkdir -p -m 700 /root/.ssh
+            echo "''${arg#bloud.authorized_key=}" | ${pkgs.coreutils}/bin/base64 -d > /root/.ssh/authorized_keys
+            chmod 600 /root/.ssh/authorized_keys
+            ;;
+        esac
+      done
+    '';
+  };
+
+  # Nix settings (needed for nixos-install to work during installation)
+  nix.settings = {
+    experimental-features = [ "nix-command" "flakes" ];
+    trusted-users = [ "root" ];
+  };
+
+  # Disk tools available in the installer environment
+  environment.systemPackages = with pkgs; [
+    parted
+    util-linux
+    dosfstools
+    e2fsprogs
+    cryptsetup    # LUKS encryption
+    vim
+    curl
+    jq
+  ];
+
+  system.stateVersion = "24.11";
+}
@@ -194,6 +194,12 @@ in
 {
   options.bloud.apps.${name} = {
     enable = lib.mkEnableOption description;
+    # Written by host-agent's generated apps.nix; see podman-service.nix
+    credentials = lib.mkOption {
+      type = lib.types.attrsOf lib.types.str;
+      default = {};
+      description = "Systemd credentials to load from the credentials directory, mapped to the env var the container reads each as";
+    };
   } // portOption // customOptions;
 
   config = lib.mkIf appCfg.enable (lib.mkMerge [
@@ -226,6 +232,11 @@ in
           # Bloud configurator hooks (uses dev path for now, will be packaged later)
           bloudAppName = name;
           bloudAgentPath = config.bloud.agentPath;
+          # Secrets written by host-agent to credentials/<app>/<id>
+          credentials = lib.mapAttrs (id: target: {
+            source = "${configPath}/credentials/${name}/${id}";
+            inherit target;
+          }) appCfg.credentials;
           inherit waitFor cmd;
         # Only add port mappings for non-host networking (host networking binds directly)
         } // lib.optionalAttrs (port != null && network != "host") {
@@ -8,8 +8,12 @@
 #             e.g. [{ container = "postgres"; command = "pg_isready -U user"; }]
 #   bloudAppName - if set, runs bloud-agent configure prestart/poststart hooks
 #   bloudAgentPath - path to bloud-agent binary (required if bloudAppName is set)
+#   credentials - attrset of credential ID -> { source, target } loaded with
+#                 LoadCredential= and passed to the container as env var
+#                 `target` via a podman secret, keeping the value out of the
+#                 unit, env file and `podman inspect`
 
-{ name, image, ports ? [], environment ? {}, volumes ? [], network ? null, dependsOn ? [], cmd ? [], userns ? null, waitFor ? [], extraAfter ? [], extraRequires ? [], bloudAppName ? null, bloudAgentPath ? null, envFile ? null, preStartScript ? null }:
+{ name, image, ports ? [], environment ? {}, volumes ? [], network ? null, dependsOn ? [], cmd ? [], userns ? null, waitFor ? [], extraAfter ? [], extraRequires ? [], bloudAppName ? null, bloudAgentPath ? null, envFile ? null, preStartScript ? null, credentials ? {} }:
 let
   # Generate health check script for each waitFor entry
   mkHealthCheck = { container, command, timeout ? 60 }: ''
@@ -34,6 +38,13 @@ let
     echo "Running bloud prestart for ${if bloudAppName != null then bloudAppName else name}..."
     ${if bloudAgentPath != null then bloudAgentPath else "/tmp/host-agent"} configure prestart ${if bloudAppName != null then bloudAppName else name}
   '';
+  # Podman secrets are created from $CREDENTIALS_DIRECTORY on each start so a
+  # rotated secret is picked up by a restart. A missing credential file falls
+  # back to the empty SetCredential= value rather than failing the unit.
+  hasCredentials = credentials != {};
+  credentialsScript = pkgs.writeShellScript "${name}-credentials" (lib.concatStrings (lib.mapAttrsToList (id: _: ''
+    ${pkgs.podman}/bin/podman secret create --replace ${name}-${id} "$CREDENTIALS_DIRECTORY/${id}" >/dev/null
+  '') credentials));
   poststartScript = pkgs.writeShellScript "${name}-bloud-poststart" ''
     echo "Running bloud poststart for ${if bloudAppName != null then bloudAppName else name}..."
     ${if bloudAgentPath != null then bloudAgentPath else "/tmp/host-agent"} configure poststart ${if bloudAppName != null then bloudAppName else name}
@@ -56,9 +67,13 @@ in
     Restart = "always";
     TimeoutStartSec = 900;
 
+    LoadCredential = lib.mapAttrsToList (id: c: "${id}:${c.source}") credentials;
+    SetCredential = lib.mapAttrsToList (id: _: "${id}:") credentials;
+
     ExecStartPre = [
       "-${pkgs.podman}/bin/podman rm -f ${name}"
     ] ++ lib.optional (waitFor != []) (pkgs.writeShellScript "${name}-wait-for-deps" healthCheckScript)
+      ++ lib.optional hasCredentials credentialsScript
       ++ lib.optional hasConfigurator prestartScript
       ++ lib.optional (preStartScript != null) preStartScript;
 
@@ -67,12 +82,13 @@ in
         portArgs = lib.concatMapStrings (p: " -p ${p}") ports;
         envArgs = lib.concatStrings (lib.mapAttrsToList (k: v: " -e ${k}=${lib.escapeShellArg v}") environment);
         envFileArg = if envFile != null then " --env-file=${envFile}" else "";
+        secretArgs = lib.concatStrings (lib.mapAttrsToList (id: c: " --secret ${name}-${id},type=env,target=${c.target}") credentials);
         volArgs = lib.concatMapStrings (v: " -v ${v}") volumes;
         netArg = if network != null then " --network=${network}" else "";
         usernsArg = if userns != null then " --userns=${userns}" else "";
         cmdArgs = lib.concatMapStrings (c: " ${lib.escapeShellArg c}") cmd;
       in
-      "${pkgs.podman}/bin/podman run --pull=missing --sdnotify=conmon --name=${name} --rm${portArgs}${envArgs}${envFileArg}${volArgs}${netArg}${usernsArg} ${image}${cmdArgs}";
+      "${pkgs.podman}/bin/podman run --pull=missing --sdnotify=conmon --name=${name} --rm${portArgs}${envArgs}${envFileArg}${secretArgs}${volArgs}${netArg}${usernsArg} ${image}${cmdArgs}";
 
     ExecStartPost = lib.optional hasConfigurator poststartScript;
 
@@ -38,6 +38,19 @@ in
       default = "${pkg}/share/bloud";
       description = "Path to the bloud source tree (apps, nixos modules, flake)";
     };
+
+    mdnsApps = lib.mkOption {
+      type = lib.types.bool;
+      default = false;
+      description = "Advertise installed apps over mDNS as <app>.local (needs services.avahi)";
+    };
+
+    updateKeys = lib.mkOption {
+      type = lib.types.listOf lib.types.str;
+      default = [ ];
+      example = [ "bloud-updates-1:AbCd...=" ];
+      description = "Public keys offline update bundles must be signed by (see scripts/make-update-bundle.sh); without any, uploads are rejected";
+    };
   };
 
   config = lib.mkIf cfg.enable {
@@ -65,7 +78,12 @@ in
         BLOUD_FLAKE_TARGET = cfg.flakeTarget;
         BLOUD_SSO_BASE_URL = bloudCfg.externalHost;
         BLOUD_SSO_AUTHENTIK_URL = bloudCfg.authentikExternalHost;
-      };
+        BLOUD_UPDATE_KEYS = lib.concatStringsSep " " cfg.updateKeys;
+        BLOUD_GIT = "${pkgs.git}/bin/git";
+      } // lib.optionalAttrs cfg.mdnsApps {
+        BLOUD_MDNS_AVAHI_PUBLISH = "${pkgs.avahi}/bin/avahi-publish";
+        BLOUD_MDNS_PORT = toString config.bloud.apps.traefik.port;
+      } // bloudCfg.secrets.environment;
 
       serviceConfig = {
         Type = "simple";
@@ -105,6 +123,15 @@ in
       };
     };
 
+    # Offline update bundles are imported with nix copy, which only accepts
+    # paths signed by a trusted key; the host agent then checks the system
+    # is signed by an update key rather than any trusted one
+    nix.settings.extra-trusted-public-keys = lib.mkIf (cfg.updateKeys != [ ]) cfg.updateKeys;
+
+    # The host agent publishes app records through avahi-daemon as the bloud
+    # user
+    services.avahi.publish.userServices = lib.mkIf cfg.mdnsApps true;
+
     # Allow user to run nixos-rebuild without password
     security.sudo.extraRules = [
       {
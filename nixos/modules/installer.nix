@@ -32,6 +32,18 @@ in
       default = "";
       description = "Pre-built NixOS system store path. When set, nixos-install uses --system instead of --flake, bypassing flake re-evaluation.";
     };
+
+    encryptedSystemPath = lib.mkOption {
+      type = lib.types.str;
+      default = "";
+      description = "Pre-built NixOS system store path for encrypted installs, used like systemPath";
+    };
+
+    console = lib.mkOption {
+      type = lib.types.bool;
+      default = true;
+      description = "Run the console installer on tty1, for machines with no other device on the network";
+    };
   };
 
   config = lib.mkIf cfg.enable {
@@ -43,12 +55,13 @@ in
 
       # NixOS `path` is the correct idiom for adding packages to a service PATH.
       # Setting PATH via `environment` causes Nix evaluation errors with store paths.
-      path = with pkgs; [ parted util-linux dosfstools e2fsprogs cryptsetup nix ];
+      path = with pkgs; [ parted util-linux dosfstools e2fsprogs btrfs-progs config.boot.zfs.package cryptsetup systemd openssh nix ];
 
       environment = {
         INSTALLER_PORT = toString cfg.port;
         INSTALLER_FLAKE_PATH = cfg.flakePath;
         INSTALLER_SYSTEM_PATH = cfg.systemPath;
+        INSTALLER_ENCRYPTED_SYSTEM_PATH = cfg.encryptedSystemPath;
       };
 
       serviceConfig = {
@@ -64,5 +77,32 @@ in
         StandardError = "journal";
       };
     };
+
+    # Console frontend: takes over tty1 in place of the login prompt and
+    # talks to the service above. Other ttys keep their logins.
+    systemd.services.bloud-installer-console = lib.mkIf cfg.console {
+      description = "Bloud Installer Console";
+      wantedBy = [ "multi-user.target" ];
+      after = [ "bloud-installer.service" "getty@tty1.service" ];
+      conflicts = [ "getty@tty1.service" ];
+
+      environment = {
+        INSTALLER_PORT = toString cfg.port;
+        TERM = "linux";
+      };
+
+      serviceConfig = {
+        Type = "simple";
+        ExecStart = "${pkg}/bin/bloud-installer console";
+        StandardInput = "tty";
+        StandardOutput = "tty";
+        StandardError = "journal";
+        TTYPath = "/dev/tty1";
+        TTYReset = true;
+        TTYVHangup = true;
+        Restart = "always";
+        RestartSec = "2s";
+      };
+    };
   };
 }
@@ -0,0 +1,50 @@
+#!/usr/bin/env bash
+# Build an offline update bundle, for hosts that can't reach the Bloud
+# repository. The bundle holds a NixOS system and its closure, signed with
+# a key in the host's bloud.host-agent.updateKeys; hosts without one reject
+# bundles.
+#
+# Usage: scripts/make-update-bundle.sh <flake-target> <secret-key-file> [output]
+#   e.g. scripts/make-update-bundle.sh bloud ./bloud-updates.sec
+#
+# Create a key pair with:
+#   nix key generate-secret --key-name bloud-updates-1 > bloud-updates.sec
+#   nix key convert-secret-to-public < bloud-updates.sec
+#
+# Upload it to the host with:
+#   curl -X POST -H "Authorization: Bearer <admin token>" -H "Content-Type: application/gzip" \
+#     --data-binary @bloud-update.tar.gz http://<host>/api/system/updates/upload
+
+set -euo pipefail
+
+if [ $# -lt 2 ]; then
+  echo "Usage: $0 <flake-target> <secret-key-file> [output]" >&2
+  exit 1
+fi
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+ROOT="$SCRIPT_DIR/.."
+TARGET="$1"
+KEY_FILE="$(realpath "$2")"
+OUTPUT="$(realpath "${3:-bloud-update.tar.gz}")"
+
+WORK="$(mktemp -d)"
+trap 'rm -rf "$WORK"' EXIT
+
+echo "==> Building system $TARGET..."
+SYSTEM="$(nix build --no-link --print-out-paths \
+  "$ROOT#nixosConfigurations.$TARGET.config.system.build.toplevel")"
+
+echo "==> Signing closure..."
+nix store sign --key-file "$KEY_FILE" --recursive "$SYSTEM"
+
+echo "==> Copying closure to bundle cache..."
+nix copy --to "file://$WORK/cache" "$SYSTEM"
+printf '{"system": "%s"}\n' "$SYSTEM" > "$WORK/bundle.json"
+
+echo "==> Packing bundle..."
+tar -C "$WORK" -czf "$OUTPUT" bundle.json cache
+
+echo ""
+echo "Done. Bundle for $SYSTEM at:"
+echo "  $OUTPUT"
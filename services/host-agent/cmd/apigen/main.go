@@ -0,0 +1,47 @@
+// Command apigen writes the host-agent OpenAPI document and the generated Go
+// client into pkg/apiclient. Run it via go generate after changing routes or
+// request/response types:
+//
+//	go generate ./pkg/apiclient
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/api"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/apigen"
+)
+
+func main() {
+	outDir := flag.String("out", ".", "output directory for openapi.json and client_gen.go")
+	pkg := flag.String("package", "apiclient", "package name of the generated client")
+	flag.Parse()
+
+	if err := run(*outDir, *pkg); err != nil {
+		fmt.Fprintf(os.Stderr, "apigen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(outDir, pkg string) error {
+	spec, err := api.OpenAPISpec()
+	if err != nil {
+		return fmt.Errorf("failed to build OpenAPI spec: %w", err)
+	}
+
+	client, err := apigen.Generate(spec, pkg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(outDir, "openapi.json"), append(spec, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write openapi.json: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "client_gen.go"), client, 0644); err != nil {
+		return fmt.Errorf("failed to write client_gen.go: %w", err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
 	"os"
@@ -14,6 +15,7 @@ import (
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/netutil"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/orchestrator"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/sso"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/configurator"
@@ -65,8 +67,9 @@ func runConfigure(args []string) int {
 			writeSSOEnvVars(appName, cfg, logger)
 		}
 
-		// If no configurator, we're done (prestart env files are already written)
-		if registry.Get(appName) == nil {
+		// If no configurator and no env-mapped settings, we're done
+		// (prestart env files are already written)
+		if registry.Get(appName) == nil && !hasEnvSettings(appName, cfg, logger) {
 			logger.Debug("no configurator registered, skipping", "app", appName)
 			return 0
 		}
@@ -92,9 +95,11 @@ func runConfigure(args []string) int {
 		return 1
 	}
 	defer database.Close()
+	recordSecretAccess(cfg.Secrets, database, logger)
 
-	// Create app store
+	// Create app and settings stores
 	appStore := store.NewAppStore(database)
+	settingsStore := store.NewSettingsStore(database)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
@@ -104,13 +109,13 @@ func runConfigure(args []string) int {
 
 	switch action {
 	case "prestart":
-		return runPreStart(ctx, args[1], registry, appStore, catalogCache, cfg.DataDir, cfg, logger)
+		return runPreStart(ctx, args[1], registry, appStore, settingsStore, catalogCache, cfg.DataDir, cfg, logger)
 
 	case "poststart":
-		return runPostStart(ctx, args[1], registry, appStore, catalogCache, cfg.DataDir, logger)
+		return runPostStart(ctx, args[1], registry, appStore, settingsStore, catalogCache, cfg.DataDir, logger)
 
 	case "reconcile":
-		return runReconcile(ctx, registry, appStore, catalogCache, cfg.DataDir, logger)
+		return runReconcile(ctx, registry, appStore, settingsStore, catalogCache, cfg.DataDir, logger)
 
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", action)
@@ -119,7 +124,7 @@ func runConfigure(args []string) int {
 	}
 }
 
-func runPreStart(ctx context.Context, appName string, registry *configurator.Registry, appStore *store.AppStore, catalogCache catalog.CacheInterface, dataDir string, appCfg *config.Config, logger *slog.Logger) int {
+func runPreStart(ctx context.Context, appName string, registry *configurator.Registry, appStore *store.AppStore, settingsStore *store.SettingsStore, catalogCache catalog.CacheInterface, dataDir string, appCfg *config.Config, logger *slog.Logger) int {
 	logger.Info("running prestart", "app", appName)
 
 	// Framework-level SSO wait: check if this app has SSO configured in the database.
@@ -143,6 +148,9 @@ func runPreStart(ctx context.Context, appName string, registry *configurator.Reg
 		}
 	}
 
+	// Settings mapped to env vars go into the env file regenerated above
+	writeSettingsEnvVars(appName, settingsStore, catalogCache, appCfg, logger)
+
 	cfg := registry.Get(appName)
 	if cfg == nil {
 		// No configurator for this app - that's OK, just succeed
@@ -150,7 +158,7 @@ func runPreStart(ctx context.Context, appName string, registry *configurator.Reg
 		return 0
 	}
 
-	state, err := buildAppState(appName, appStore, catalogCache, dataDir, logger)
+	state, err := buildAppState(appName, appStore, settingsStore, catalogCache, dataDir, logger)
 	if err != nil {
 		logger.Error("failed to build app state", "app", appName, "error", err)
 		return 1
@@ -165,7 +173,7 @@ func runPreStart(ctx context.Context, appName string, registry *configurator.Reg
 	return 0
 }
 
-func runPostStart(ctx context.Context, appName string, registry *configurator.Registry, appStore *store.AppStore, catalogCache catalog.CacheInterface, dataDir string, logger *slog.Logger) int {
+func runPostStart(ctx context.Context, appName string, registry *configurator.Registry, appStore *store.AppStore, settingsStore *store.SettingsStore, catalogCache catalog.CacheInterface, dataDir string, logger *slog.Logger) int {
 	logger.Info("running poststart", "app", appName)
 
 	cfg := registry.Get(appName)
@@ -182,7 +190,7 @@ func runPostStart(ctx context.Context, appName string, registry *configurator.Re
 		return 1
 	}
 
-	state, err := buildAppState(appName, appStore, catalogCache, dataDir, logger)
+	state, err := buildAppState(appName, appStore, settingsStore, catalogCache, dataDir, logger)
 	if err != nil {
 		logger.Error("failed to build app state", "app", appName, "error", err)
 		return 1
@@ -197,7 +205,7 @@ func runPostStart(ctx context.Context, appName string, registry *configurator.Re
 	return 0
 }
 
-func runReconcile(ctx context.Context, registry *configurator.Registry, appStore *store.AppStore, catalogCache catalog.CacheInterface, dataDir string, logger *slog.Logger) int {
+func runReconcile(ctx context.Context, registry *configurator.Registry, appStore *store.AppStore, settingsStore *store.SettingsStore, catalogCache catalog.CacheInterface, dataDir string, logger *slog.Logger) int {
 	logger.Info("running full reconciliation")
 
 	reconciler := orchestrator.NewReconciler(
@@ -208,6 +216,7 @@ func runReconcile(ctx context.Context, registry *configurator.Registry, appStore
 		logger,
 		orchestrator.DefaultReconcileConfig(),
 	)
+	reconciler.SetSettingsStore(settingsStore)
 
 	if err := reconciler.Reconcile(ctx); err != nil {
 		logger.Error("reconciliation failed", "error", err)
@@ -218,7 +227,7 @@ func runReconcile(ctx context.Context, registry *configurator.Registry, appStore
 	return 0
 }
 
-func buildAppState(appName string, appStore *store.AppStore, catalogCache catalog.CacheInterface, dataDir string, logger *slog.Logger) (*configurator.AppState, error) {
+func buildAppState(appName string, appStore *store.AppStore, settingsStore *store.SettingsStore, catalogCache catalog.CacheInterface, dataDir string, logger *slog.Logger) (*configurator.AppState, error) {
 	app, err := appStore.GetByName(appName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get app: %w", err)
@@ -235,7 +244,8 @@ func buildAppState(appName string, appStore *store.AppStore, catalogCache catalo
 		port = app.Port
 	}
 
-	// Load SSO config from catalog if available
+	// Load SSO config and settings from catalog if available
+	settings := make(map[string]any)
 	if catalogCache != nil {
 		if catalogApp, err := catalogCache.Get(appName); err == nil && catalogApp != nil {
 			if catalogApp.SSO.Strategy != "" {
@@ -244,6 +254,12 @@ func buildAppState(appName string, appStore *store.AppStore, catalogCache catalo
 					"app", appName,
 					"strategy", catalogApp.SSO.Strategy)
 			}
+
+			stored, err := settingsStore.Get(appName)
+			if err != nil {
+				logger.Warn("failed to load app settings, using defaults", "app", appName, "error", err)
+			}
+			settings = catalogApp.ResolveSettings(stored)
 		}
 	}
 
@@ -254,6 +270,9 @@ func buildAppState(appName string, appStore *store.AppStore, catalogCache catalo
 		Port:          port,
 		Integrations:  integrations,
 		Options:       make(map[string]any),
+		Settings:      settings,
+		// Set when run as ExecStartPre/ExecStartPost of the app's unit
+		CredentialsDir: os.Getenv("CREDENTIALS_DIRECTORY"),
 	}, nil
 }
 
@@ -302,3 +321,74 @@ func writeSSOEnvVars(appName string, cfg *config.Config, logger *slog.Logger) {
 
 	logger.Info("appended SSO env vars to env file", "app", appName, "vars", len(ssoVars))
 }
+
+// hasEnvSettings reports whether the app declares settings that are written
+// to its env file, which needs the database even without a configurator
+func hasEnvSettings(appName string, cfg *config.Config, logger *slog.Logger) bool {
+	allApps, err := catalog.NewLoader(cfg.AppsDir).LoadAll()
+	if err != nil {
+		logger.Warn("failed to load catalog for settings", "error", err)
+		return false
+	}
+	catalogApp, ok := allApps[appName]
+	if !ok {
+		return false
+	}
+	for _, setting := range catalogApp.Settings {
+		if setting.Env != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSettingsEnvVars appends env-mapped app settings to the app's env file
+func writeSettingsEnvVars(appName string, settingsStore *store.SettingsStore, catalogCache catalog.CacheInterface, cfg *config.Config, logger *slog.Logger) {
+	if cfg.Secrets == nil {
+		return
+	}
+
+	catalogApp, err := catalogCache.Get(appName)
+	if err != nil || catalogApp == nil {
+		return
+	}
+
+	stored, err := settingsStore.Get(appName)
+	if err != nil {
+		logger.Warn("failed to load app settings, using defaults", "app", appName, "error", err)
+	}
+
+	vars := catalogApp.SettingsEnv(catalogApp.ResolveSettings(stored))
+	if len(vars) == 0 {
+		return
+	}
+
+	if err := cfg.Secrets.AppendEnvVars(appName, vars); err != nil {
+		logger.Error("failed to append settings env vars", "app", appName, "error", err)
+		return
+	}
+
+	logger.Info("appended settings env vars to env file", "app", appName, "vars", len(vars))
+}
+
+// recordSecretAccess sends this command's secret reads and writes, including
+// those made before the database was opened, to the secret access log
+func recordSecretAccess(mgr *secrets.Manager, database *sql.DB, logger *slog.Logger) {
+	if mgr == nil {
+		return
+	}
+	accessLog := store.NewSecretAccessStore(database)
+	mgr.SetAccessRecorder(func(a secrets.Access) {
+		err := accessLog.Record(&store.SecretAccessEntry{
+			Component: a.Component,
+			Caller:    a.Caller,
+			Action:    a.Action,
+			App:       a.App,
+			Secret:    a.Secret,
+			CreatedAt: a.At,
+		})
+		if err != nil {
+			logger.Warn("failed to record secret access", "secret", a.Secret, "app", a.App, "error", err)
+		}
+	})
+}
@@ -6,29 +6,47 @@ import (
 	"path/filepath"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/sso"
 )
 
 // runInitSecrets handles the "init-secrets" subcommand
-// This generates the secrets.json file if it doesn't exist.
+// This generates the secrets (secrets.json with the default file backend)
+// if they don't exist.
 // Should be called BEFORE nixos-rebuild to ensure NixOS can read the secrets.
 //
 // Usage:
 //
-//	host-agent init-secrets [data-dir]
+//	host-agent init-secrets [--verify] [data-dir]
 //
 // If data-dir is not provided, defaults to ~/.local/share/bloud
+//
+// With --verify, existing secrets are also checked: every expected secret
+// exists and decrypts, OAuth client secrets match their derivation from the
+// host secret, env files are current, and files are private to their owner.
+// What can be repaired is, the rest is reported, and the exit status is 1 if
+// anything is left to fix.
 func runInitSecrets(args []string) int {
+	verify := false
+	var positional []string
+	for _, arg := range args {
+		switch arg {
+		case "--verify":
+			verify = true
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
 	// Determine data directory
 	dataDir := ""
-	if len(args) > 0 {
-		dataDir = args[0]
+	if len(positional) > 0 {
+		dataDir = positional[0]
 	} else {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
+		var err error
+		if dataDir, err = defaultDataDir(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: cannot determine home directory: %v\n", err)
 			return 1
 		}
-		dataDir = filepath.Join(homeDir, ".local", "share", "bloud")
 	}
 
 	// Ensure data directory exists
@@ -39,19 +57,90 @@ func runInitSecrets(args []string) int {
 
 	secretsPath := filepath.Join(dataDir, "secrets.json")
 
+	// Secrets are stored in the backend selected by BLOUD_SECRETS_BACKEND
+	mgr := secrets.NewManagerFromEnv(secretsPath)
+
+	if verify {
+		return verifySecrets(mgr)
+	}
+
 	// Check if secrets already exist
-	if _, err := os.Stat(secretsPath); err == nil {
-		fmt.Printf("Secrets already exist at %s\n", secretsPath)
+	exists, err := mgr.Exists()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read secrets from %s backend: %v\n", mgr.BackendName(), err)
+		return 1
+	}
+	if exists {
+		fmt.Printf("Secrets already exist in %s backend\n", mgr.BackendName())
 		return 0
 	}
 
 	// Generate new secrets
-	mgr := secrets.NewManager(secretsPath)
 	if err := mgr.Load(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: failed to generate secrets: %v\n", err)
 		return 1
 	}
 
-	fmt.Printf("Generated secrets at %s\n", secretsPath)
+	fmt.Printf("Generated secrets in %s backend (env files in %s)\n", mgr.BackendName(), dataDir)
 	return 0
 }
+
+// bloudOAuthSecretApp is where the host agent stores its own OAuth client
+// secret, which isn't derived per key version like app client secrets
+const bloudOAuthSecretApp = "bloud-oauth"
+
+// verifySecrets checks and repairs the secrets, printing what it found
+func verifySecrets(mgr *secrets.Manager) int {
+	report, err := mgr.Verify(secrets.VerifyOptions{
+		DeriveClientSecret: func(appName string, version int) string {
+			// The host agent's own login client keeps the secret it was created with
+			if appName == bloudOAuthSecretApp {
+				return ""
+			}
+			return sso.DeriveClientSecret(mgr.GetSSOHostSecret(), appName, version)
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to verify secrets in %s backend: %v\n", mgr.BackendName(), err)
+		return 1
+	}
+
+	for _, f := range report.Findings {
+		subject := f.Check
+		if f.App != "" {
+			subject += " (" + f.App + ")"
+		}
+		switch {
+		case f.Repaired:
+			fmt.Printf("repaired  %s: %s\n", subject, f.Detail)
+		case f.Error != "":
+			fmt.Printf("FAILED    %s: %s: repair failed: %s\n", subject, f.Detail, f.Error)
+		default:
+			fmt.Printf("UNFIXED   %s: %s\n", subject, f.Detail)
+			if f.Action != "" {
+				fmt.Printf("          to fix: %s\n", f.Action)
+			}
+		}
+	}
+
+	if !report.OK {
+		fmt.Fprintf(os.Stderr, "Secrets in %s backend need attention\n", mgr.BackendName())
+		return 1
+	}
+	if len(report.Findings) == 0 {
+		fmt.Printf("Secrets in %s backend verified\n", mgr.BackendName())
+	} else {
+		fmt.Printf("Secrets in %s backend verified after %d repair(s)\n", mgr.BackendName(), len(report.Findings))
+	}
+	return 0
+}
+
+// defaultDataDir returns ~/.local/share/bloud, the data directory used when
+// a subcommand isn't given one
+func defaultDataDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".local", "share", "bloud"), nil
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -12,9 +13,12 @@ import (
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/appconfig"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/config"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/requestid"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/configurator"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/provisioner"
 )
 
 func main() {
@@ -25,6 +29,14 @@ func main() {
 			os.Exit(runConfigure(os.Args[2:]))
 		case "init-secrets":
 			os.Exit(runInitSecrets(os.Args[2:]))
+		case "rotate-ldap-token":
+			os.Exit(runRotateLDAPToken(os.Args[2:]))
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		case "secrets-key":
+			os.Exit(runSecretsKey(os.Args[2:]))
+		case "rewrap-host-secret":
+			os.Exit(runRewrapHostSecret(os.Args[2:]))
 		}
 	}
 
@@ -39,10 +51,10 @@ func runServer() {
 	// throughout the process (systemctl, journalctl, podman, nix, sudo, etc.).
 	_ = os.Setenv("PATH", nixgen.NixosSystemPath)
 
-	// Setup structured logging
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	// Setup structured logging; records logged with a request context carry its request_id
+	logger := slog.New(requestid.NewHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
-	}))
+	})))
 	slog.SetDefault(logger)
 
 	logger.Info("starting Bloud host agent")
@@ -66,26 +78,51 @@ func runServer() {
 	defer database.Close()
 	logger.Info("database initialized successfully")
 
+	if err := metrics.RegisterDB(database, "bloud"); err != nil {
+		logger.Warn("failed to register database metrics", "error", err)
+	}
+
+	maintainer, err := db.NewMaintainer(database, db.MaintainerConfig{
+		DatabaseURL: cfg.DatabaseURL,
+		BackupDir:   filepath.Join(cfg.DataDir, "backups", "db"),
+	}, logger)
+	if err != nil {
+		logger.Warn("database maintenance disabled", "error", err)
+	}
+
 	// Create configurator registry
 	registry := configurator.NewRegistry(logger)
 	appconfig.RegisterAll(registry, cfg)
 
+	// Create user provisioner registry
+	provisioners := provisioner.NewRegistry(logger)
+	appconfig.RegisterProvisioners(provisioners, cfg)
+
 	// Create HTTP server
 	server := api.NewServer(database, api.ServerConfig{
-		AppsDir:         cfg.AppsDir,
-		ConfigDir:       cfg.NixConfigDir,
-		DataDir:         cfg.DataDir,
-		FlakePath:       cfg.FlakePath,
-		FlakeTarget:     cfg.FlakeTarget,
-		NixosPath:       cfg.NixosPath,
-		Port:            cfg.Port,
-		SSOHostSecret:   cfg.SSOHostSecret,
-		SSOBaseURL:      cfg.SSOBaseURL,
-		SSOAuthentikURL: cfg.SSOAuthentikURL,
-		AuthentikToken:  cfg.AuthentikToken,
-		AuthentikPort:   cfg.AuthentikPort,
-		RedisAddr:       cfg.RedisAddr,
-		Registry:        registry,
+		AppsDir:              cfg.AppsDir,
+		ConfigDir:            cfg.NixConfigDir,
+		DataDir:              cfg.DataDir,
+		FlakePath:            cfg.FlakePath,
+		FlakeTarget:          cfg.FlakeTarget,
+		NixosPath:            cfg.NixosPath,
+		Port:                 cfg.Port,
+		SSOHostSecret:        cfg.SSOHostSecret,
+		SSOBaseURL:           cfg.SSOBaseURL,
+		SSOAuthentikURL:      cfg.SSOAuthentikURL,
+		AuthentikToken:       cfg.AuthentikToken,
+		AuthentikPort:        cfg.AuthentikPort,
+		RedisAddr:            cfg.RedisAddr,
+		Registry:             registry,
+		Provisioners:         provisioners,
+		ProvisioningInterval: time.Duration(cfg.ProvisioningSyncInterval) * time.Second,
+		Maintainer:           maintainer,
+		Secrets:              cfg.Secrets,
+		AvahiPublishBin:      cfg.AvahiPublishBin,
+		MDNSPort:             cfg.MDNSPort,
+		UpdateRepo:           cfg.UpdateRepo,
+		UpdateKeys:           cfg.UpdateKeys,
+		GitBin:               cfg.GitBin,
 	}, logger)
 
 	// Setup graceful shutdown
@@ -95,6 +132,39 @@ func runServer() {
 	// Start background system stats collector
 	system.StartStatsCollector(ctx)
 
+	// Persist stats samples for history charts
+	server.StartStatsRecorder(ctx)
+
+	// Disk, app down, temperature and backup age alerts
+	server.StartAlertEvaluator(ctx)
+
+	// Periodic app health checks, kept for uptime history
+	server.StartUptimeMonitor(ctx)
+
+	// Journal forwarding to Loki or Vector, when configured
+	server.StartLogShipper(ctx)
+
+	// Database backups, stats rollups and image pruning
+	server.StartScheduler(ctx)
+
+	// Settle and verify an update the host agent was restarted during
+	server.StartUpdater(ctx)
+
+	// Suspend or power off through the configured sleep windows
+	server.StartPowerSchedule(ctx)
+
+	// Move sessions back to Redis if it was unavailable at startup or fails later
+	server.StartSessionRecovery(ctx)
+
+	// Start background user provisioning sync
+	server.StartProvisioning(ctx)
+
+	// Prune OAuth redirect URIs for hosts/IPs Bloud no longer advertises
+	server.StartRedirectURICleanup(ctx)
+
+	// Start feeding the /api/events WebSocket
+	server.StartEventPublishers(ctx)
+
 	// Start server in a goroutine
 	go func() {
 		if err := server.Start(); err != nil {
@@ -118,4 +188,3 @@ func runServer() {
 
 	logger.Info("server stopped gracefully")
 }
-
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/config"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
+)
+
+// runMigrate handles the "migrate" subcommand
+// Usage:
+//
+//	host-agent migrate status
+//	host-agent migrate up
+//	host-agent migrate down <version>
+func runMigrate(args []string) int {
+	usage := "Usage: host-agent migrate <status|up|down <version>>"
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+
+	cfg := config.Load()
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "status":
+		statuses, err := db.MigrationStatuses(ctx, database)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-32s %s\n", s.Version, s.Name, applied)
+		}
+		return 0
+
+	case "up":
+		ran, err := db.Migrate(ctx, database)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(ran) == 0 {
+			fmt.Println("Schema is up to date")
+			return 0
+		}
+		fmt.Printf("Applied migrations: %v\n", ran)
+		return 0
+
+	case "down":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, usage)
+			return 1
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid version %q\n", args[1])
+			return 1
+		}
+		ran, err := db.MigrateDown(ctx, database, target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Rolled back migrations: %v\n", ran)
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, usage)
+	return 1
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/config"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/netutil"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/sso"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
+)
+
+// ssoHostSecretLength matches the length of the host secret generated on first boot
+const ssoHostSecretLength = 64
+
+// runRewrapHostSecret handles the "rewrap-host-secret" subcommand.
+// It moves the deployment to a host secret, re-deriving every native OIDC
+// app's client secret and pushing it to Authentik, secrets.json and the
+// app's blueprint together.
+//
+// Usage:
+//
+//	host-agent rewrap-host-secret [--generate] [--dry-run]
+//
+// Without --generate the host secret in effect is used: BLOUD_SSO_HOST_SECRET
+// if set (e.g. after restoring onto new hardware), otherwise the stored one,
+// which repairs client secrets that drifted from it. --generate replaces a
+// leaked host secret with a new random one. --dry-run only reports what
+// doesn't match.
+func runRewrapHostSecret(args []string) int {
+	generate, dryRun := false, false
+	for _, arg := range args {
+		switch arg {
+		case "--generate":
+			generate = true
+		case "--dry-run":
+			dryRun = true
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown argument %s\n", arg)
+			return 1
+		}
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	cfg := config.Load()
+	if cfg.Secrets == nil {
+		fmt.Fprintln(os.Stderr, "Error: secrets not available")
+		return 1
+	}
+	if cfg.AuthentikToken == "" {
+		fmt.Fprintln(os.Stderr, "Error: no Authentik API token configured")
+		return 1
+	}
+	if cfg.SSOBaseURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: SSO is not configured")
+		return 1
+	}
+
+	hostSecret := cfg.SSOHostSecret
+	if generate {
+		// A generated secret would be ignored in favour of the env var on next start
+		if os.Getenv("BLOUD_SSO_HOST_SECRET") != "" {
+			fmt.Fprintln(os.Stderr, "Error: BLOUD_SSO_HOST_SECRET is set; change it instead of using --generate")
+			return 1
+		}
+		hostSecret = secrets.GenerateSecret(ssoHostSecretLength)
+	}
+
+	database, err := db.InitDB(cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+	recordSecretAccess(cfg.Secrets, database, logger)
+
+	names, err := store.NewAppStore(database).GetInstalledNames()
+	if err != nil {
+		logger.Error("failed to list installed apps", "error", err)
+		return 1
+	}
+	catalogCache := catalog.NewCache(database)
+	apps := make([]*catalog.App, 0, len(names))
+	for _, name := range names {
+		if app, err := catalogCache.Get(name); err == nil && app != nil {
+			apps = append(apps, app)
+		}
+	}
+
+	internalURL := fmt.Sprintf("http://localhost:%d", cfg.AuthentikPort)
+	client := authentik.NewClient(internalURL, cfg.AuthentikToken)
+	rebuilder := nixgen.NewRebuilder(cfg.FlakePath, cfg.FlakeTarget, logger)
+	blueprints := func(hostSecret string) sso.BlueprintGeneratorInterface {
+		return sso.NewBlueprintGenerator(
+			hostSecret,
+			cfg.LDAPBindPassword,
+			netutil.BuildBaseURLs(cfg.SSOBaseURL),
+			cfg.SSOAuthentikURL,
+			filepath.Join(cfg.DataDir, "authentik-blueprints"),
+			cfg.Secrets,
+		)
+	}
+	rewrapper := sso.NewHostSecretRewrapper(client, cfg.Secrets, blueprints, rebuilder, logger)
+
+	if dryRun {
+		plan, err := rewrapper.Plan(apps, hostSecret)
+		if err != nil {
+			logger.Error("failed to check host secret", "error", err)
+			return 1
+		}
+		printRewrapPlan(plan)
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	plan, err := rewrapper.Rewrap(ctx, apps, hostSecret)
+	if plan != nil {
+		printRewrapPlan(plan)
+	}
+	if err != nil {
+		logger.Error("host secret rewrap failed", "error", err)
+		return 1
+	}
+	if plan.HostSecretChanged {
+		fmt.Println("Restart bloud-host-agent so it uses the new host secret")
+	}
+	return 0
+}
+
+// printRewrapPlan describes what a rewrap changes
+func printRewrapPlan(plan *sso.RewrapPlan) {
+	if !plan.Changed() {
+		fmt.Println("Host secret and client secrets match")
+		return
+	}
+	if plan.HostSecretChanged {
+		fmt.Println("host secret: differs from the stored one")
+	}
+	for _, app := range plan.Apps {
+		var stale []string
+		if app.StoredStale {
+			stale = append(stale, "stored secret")
+		}
+		if app.ProviderStale {
+			stale = append(stale, "Authentik provider")
+		}
+		fmt.Printf("%s: client secret differs (%s)\n", app.Name, strings.Join(stale, ", "))
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/appconfig"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/config"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/sso"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/configurator"
+)
+
+// runRotateLDAPToken handles the "rotate-ldap-token" subcommand.
+// It replaces the LDAP service account's bind key and pushes the new key
+// to every installed app that binds to the LDAP outpost.
+//
+// Usage:
+//
+//	host-agent rotate-ldap-token
+func runRotateLDAPToken(args []string) int {
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	cfg := config.Load()
+	if cfg.Secrets == nil {
+		fmt.Fprintln(os.Stderr, "Error: secrets not available")
+		return 1
+	}
+	if cfg.AuthentikToken == "" {
+		fmt.Fprintln(os.Stderr, "Error: no Authentik API token configured")
+		return 1
+	}
+
+	registry := configurator.NewRegistry(logger)
+	appconfig.RegisterAll(registry, cfg)
+
+	database, err := db.InitDB(cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to initialize database", "error", err)
+		return 1
+	}
+	defer database.Close()
+	recordSecretAccess(cfg.Secrets, database, logger)
+
+	appStore := store.NewAppStore(database)
+	settingsStore := store.NewSettingsStore(database)
+	catalogCache := catalog.NewCache(database)
+
+	names, err := appStore.GetInstalledNames()
+	if err != nil {
+		logger.Error("failed to list installed apps", "error", err)
+		return 1
+	}
+
+	states := make([]*configurator.AppState, 0, len(names))
+	for _, name := range names {
+		state, err := buildAppState(name, appStore, settingsStore, catalogCache, cfg.DataDir, logger)
+		if err != nil {
+			logger.Error("failed to build app state", "app", name, "error", err)
+			return 1
+		}
+		states = append(states, state)
+	}
+
+	internalURL := fmt.Sprintf("http://localhost:%d", cfg.AuthentikPort)
+	client := authentik.NewClient(internalURL, cfg.AuthentikToken)
+	rebuilder := nixgen.NewRebuilder(cfg.FlakePath, cfg.FlakeTarget, logger)
+
+	rotator := sso.NewLDAPRotator(client, cfg.Secrets, registry, rebuilder, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	updated, err := rotator.Rotate(ctx, states)
+	if err != nil {
+		logger.Error("LDAP bind token rotation failed", "error", err)
+		return 1
+	}
+
+	fmt.Printf("Rotated LDAP bind token (updated apps: %v)\n", updated)
+	return 0
+}
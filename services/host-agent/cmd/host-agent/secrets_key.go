@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+)
+
+// runSecretsKey handles the "secrets-key" subcommand, which provisions the
+// machine key that seals secrets.json and recovers a sealed file with a
+// backup of that key.
+//
+// Usage:
+//
+//	host-agent secrets-key generate <key-file>
+//	host-agent secrets-key unseal <key-file> [data-dir]
+//
+// generate writes a new random key (mode 0600) and refuses to overwrite one.
+// unseal prints the decrypted secrets.json to stdout; see the README's
+// recovery steps for moving a sealed file to new hardware.
+func runSecretsKey(args []string) int {
+	usage := "Usage: host-agent secrets-key <generate <key-file>|unseal <key-file> [data-dir]>"
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+
+	switch args[0] {
+	case "generate":
+		key, err := secrets.GenerateMachineKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := os.MkdirAll(filepath.Dir(args[1]), 0700); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		f, err := os.OpenFile(args[1], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		if _, err := fmt.Fprintln(f, key); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Wrote machine key to %s; keep a copy somewhere off this machine\n", args[1])
+		return 0
+
+	case "unseal":
+		var dataDir string
+		if len(args) > 2 {
+			dataDir = args[2]
+		} else {
+			var err error
+			if dataDir, err = defaultDataDir(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: cannot determine home directory: %v\n", err)
+				return 1
+			}
+		}
+		path := filepath.Join(dataDir, "secrets.json")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		plaintext, err := secrets.NewSealedFileBackend(path, secrets.NewKeyFile(args[1])).Unseal(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		os.Stdout.Write(plaintext)
+		return 0
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+}
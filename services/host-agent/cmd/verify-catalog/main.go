@@ -0,0 +1,38 @@
+// Command verify-catalog checks every apps/*/metadata.yaml with
+// catalog.Verify and prints one file:line: message per problem, exiting 1
+// if there are any. ./bloud verify-catalog and CI run it:
+//
+//	go run ./cmd/verify-catalog ../../apps
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: verify-catalog <apps-dir>\n")
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	problems, err := catalog.Verify(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify-catalog: %v\n", err)
+		os.Exit(1)
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "verify-catalog: %d problem(s)\n", len(problems))
+		os.Exit(1)
+	}
+}
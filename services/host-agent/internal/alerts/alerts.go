@@ -0,0 +1,307 @@
+// Package alerts evaluates threshold rules against the host's state (disk
+// usage, apps that stay down, CPU temperature, backup age) and publishes a
+// notification when one fires.
+package alerts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/notify"
+)
+
+// rearmMargin is how far below its threshold, as a fraction of it, a fired
+// rule's value must fall before the rule can fire again, so a value
+// hovering around the threshold alerts once
+const rearmMargin = 0.1
+
+// State is what the rules are evaluated against
+type State struct {
+	Disk        float64   // root filesystem usage, percent
+	Temperature float64   // CPU package °C; 0 without a sensor
+	DownApps    []string  // installed apps in error
+	Backups     bool      // whether database backups are set up; false skips backup_age
+	LastBackup  time.Time // newest database backup; zero if there is none
+}
+
+// Firing is a rule whose condition holds
+type Firing struct {
+	Rule     string    `json:"rule"`
+	Type     string    `json:"type"`
+	Severity string    `json:"severity"`
+	Subject  string    `json:"subject,omitempty"` // the app, for app_down
+	Value    float64   `json:"value"`             // percent, °C or days
+	Since    time.Time `json:"since"`
+	// Fired is set once the condition has held for the rule's ForMinutes;
+	// the notification went out unless the rule is muted
+	Fired bool `json:"fired"`
+}
+
+// Evaluator holds the rules, tracks which are firing and publishes an event
+// for each newly fired one
+type Evaluator struct {
+	path      string
+	publisher notify.Publisher // nil only tracks
+	logger    *slog.Logger
+
+	mu     sync.Mutex
+	cfg    Config
+	active map[string]*Firing // by rule ID and subject
+}
+
+// NewEvaluator creates an evaluator persisting its rules at path
+func NewEvaluator(path string, publisher notify.Publisher, logger *slog.Logger) *Evaluator {
+	return &Evaluator{
+		path:      path,
+		publisher: publisher,
+		logger:    logger,
+		cfg:       DefaultConfig(),
+		active:    make(map[string]*Firing),
+	}
+}
+
+// Load reads the rules file; a missing file means the default rules
+func (e *Evaluator) Load() error {
+	data, err := os.ReadFile(e.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading alert rules: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("parsing alert rules: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid alert rules: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cfg = cfg
+	return nil
+}
+
+// Config returns the current rules
+func (e *Evaluator) Config() Config {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return Config{Rules: append([]Rule{}, e.cfg.Rules...)}
+}
+
+// SetConfig validates and persists new rules. Rules that changed, including
+// being muted or unmuted, start over as if they had never fired.
+func (e *Evaluator) SetConfig(cfg Config) error {
+	if cfg.Rules == nil {
+		cfg.Rules = []Rule{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling alert rules: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(e.path, data, 0644); err != nil {
+		return fmt.Errorf("writing alert rules: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	unchanged := make(map[string]bool)
+	for _, rule := range cfg.Rules {
+		for _, old := range e.cfg.Rules {
+			if rule == old {
+				unchanged[rule.ID] = true
+			}
+		}
+	}
+	for key, f := range e.active {
+		if !unchanged[f.Rule] {
+			delete(e.active, key)
+		}
+	}
+	e.cfg = cfg
+	return nil
+}
+
+// Active returns the firing rules, most severe first
+func (e *Evaluator) Active() []Firing {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rank := map[string]int{SeverityCritical: 0, SeverityWarning: 1, SeverityInfo: 2}
+	firing := make([]Firing, 0, len(e.active))
+	for _, f := range e.active {
+		firing = append(firing, *f)
+	}
+	sort.Slice(firing, func(i, j int) bool {
+		a, b := firing[i], firing[j]
+		if rank[a.Severity] != rank[b.Severity] {
+			return rank[a.Severity] < rank[b.Severity]
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Subject < b.Subject
+	})
+	return firing
+}
+
+// reading is one rule's view of the state: a value per subject and whether
+// it breaches the rule
+type reading struct {
+	subject  string
+	value    float64
+	breached bool
+	rearmed  bool // far enough from the threshold for a fired rule to clear
+}
+
+// Evaluate checks every rule against state and publishes an event for each
+// one that fires, returning the events
+func (e *Evaluator) Evaluate(state State, now time.Time) []notify.Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []notify.Event
+	seen := make(map[string]bool)
+	for _, rule := range e.cfg.Rules {
+		for _, r := range readings(rule, state, now) {
+			key := rule.ID + "/" + r.subject
+			f := e.active[key]
+			if !r.breached {
+				if f != nil && (!f.Fired || r.rearmed) {
+					delete(e.active, key)
+				} else if f != nil {
+					seen[key] = true
+					f.Value = r.value
+				}
+				continue
+			}
+
+			seen[key] = true
+			if f == nil {
+				f = &Firing{Rule: rule.ID, Type: rule.Type, Severity: rule.Severity, Subject: r.subject, Since: now}
+				e.active[key] = f
+			}
+			f.Value = r.value
+			if f.Fired || now.Sub(f.Since) < time.Duration(rule.ForMinutes)*time.Minute {
+				continue
+			}
+			f.Fired = true
+			if rule.Muted {
+				continue
+			}
+			event := rule.event(*f, now)
+			events = append(events, event)
+			e.logger.Warn("alert fired", "rule", rule.ID, "subject", r.subject, "value", r.value)
+			if e.publisher != nil {
+				e.publisher.Publish(event)
+			}
+		}
+	}
+
+	// Subjects that are gone, such as an uninstalled app, and rules that
+	// don't apply any more
+	for key := range e.active {
+		if !seen[key] {
+			delete(e.active, key)
+		}
+	}
+	return events
+}
+
+// readings returns a rule's values in state; none when the rule doesn't
+// apply, which clears it
+func readings(rule Rule, state State, now time.Time) []reading {
+	numeric := func(value float64) reading {
+		return reading{
+			value:    value,
+			breached: value > rule.Threshold,
+			rearmed:  value < rule.Threshold*(1-rearmMargin),
+		}
+	}
+
+	switch rule.Type {
+	case TypeDisk:
+		return []reading{numeric(state.Disk)}
+	case TypeTemperature:
+		if state.Temperature == 0 {
+			return nil // no sensor
+		}
+		return []reading{numeric(state.Temperature)}
+	case TypeBackupAge:
+		if !state.Backups {
+			return nil
+		}
+		if state.LastBackup.IsZero() {
+			return []reading{{breached: true}}
+		}
+		return []reading{numeric(now.Sub(state.LastBackup).Hours() / 24)}
+	case TypeAppDown:
+		r := make([]reading, len(state.DownApps))
+		for i, app := range state.DownApps {
+			r[i] = reading{subject: app, breached: true}
+		}
+		return r
+	}
+	return nil
+}
+
+// event describes a fired rule
+func (rule Rule) event(f Firing, now time.Time) notify.Event {
+	event := notify.Event{Severity: rule.Severity, App: f.Subject}
+	held := ""
+	if rule.ForMinutes > 0 {
+		held = " for " + formatMinutes(now.Sub(f.Since))
+	}
+
+	switch rule.Type {
+	case TypeDisk:
+		event.Kind = notify.KindDiskFull
+		event.Title = "Disk almost full"
+		event.Message = fmt.Sprintf("The system disk is %.0f%% full%s. Free up space or uninstall apps you don't use before it fills up.", f.Value, held)
+	case TypeAppDown:
+		event.Kind = notify.KindAppDown
+		event.Title = fmt.Sprintf("%s is down", f.Subject)
+		event.Message = fmt.Sprintf("%s has been down%s.", f.Subject, held)
+	case TypeTemperature:
+		event.Kind = notify.KindTemperatureHigh
+		event.Title = "CPU running hot"
+		event.Message = fmt.Sprintf("The CPU has been above %.0f°C%s and is now at %.0f°C. Check that the fan works and the vents aren't blocked, or move the machine somewhere with more airflow.", rule.Threshold, held, f.Value)
+	case TypeBackupAge:
+		event.Kind = notify.KindBackupStale
+		event.Title = "Backups are out of date"
+		event.Message = fmt.Sprintf("The newest database backup is %.0f days old.", f.Value)
+		if f.Value == 0 {
+			event.Message = "There are no database backups."
+		}
+		event.Message += " Check the host agent's logs for failed maintenance runs."
+	}
+	return event
+}
+
+// formatMinutes formats a duration as whole minutes or hours
+func formatMinutes(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	switch {
+	case minutes == 1:
+		return "a minute"
+	case minutes < 120:
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return fmt.Sprintf("%d hours", minutes/60)
+}
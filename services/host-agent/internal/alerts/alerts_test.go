@@ -0,0 +1,149 @@
+package alerts
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePublisher struct {
+	events []notify.Event
+}
+
+func (f *fakePublisher) Publish(event notify.Event) {
+	f.events = append(f.events, event)
+}
+
+func newTestEvaluator(t *testing.T, rules ...Rule) (*Evaluator, *fakePublisher) {
+	t.Helper()
+	pub := &fakePublisher{}
+	e := NewEvaluator(filepath.Join(t.TempDir(), "alerts.json"), pub, slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+	require.NoError(t, e.SetConfig(Config{Rules: rules}))
+	return e, pub
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := Config{Rules: []Rule{
+		{Type: TypeDisk, Threshold: 90},
+		{Type: TypeDisk, Threshold: 95, Severity: SeverityCritical},
+		{Type: TypeAppDown},
+	}}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, "disk", cfg.Rules[0].ID)
+	assert.Equal(t, "disk-2", cfg.Rules[1].ID)
+	assert.Equal(t, SeverityWarning, cfg.Rules[0].Severity)
+
+	defaults := DefaultConfig()
+	require.NoError(t, defaults.Validate())
+
+	invalid := []Rule{
+		{Type: "cpu", Threshold: 90},
+		{Type: TypeDisk},
+		{Type: TypeDisk, Threshold: 120},
+		{Type: TypeTemperature, Threshold: 85, Severity: "page"},
+		{Type: TypeBackupAge, Threshold: 3, ForMinutes: -1},
+	}
+	for _, rule := range invalid {
+		cfg := Config{Rules: []Rule{rule}}
+		assert.Error(t, cfg.Validate(), "%+v", rule)
+	}
+
+	dup := Config{Rules: []Rule{{ID: "a", Type: TypeAppDown}, {ID: "a", Type: TypeAppDown}}}
+	assert.ErrorContains(t, dup.Validate(), "duplicate")
+}
+
+func TestEvaluator_SustainedAndRearm(t *testing.T) {
+	e, pub := newTestEvaluator(t, Rule{ID: "hot", Type: TypeTemperature, Threshold: 85, ForMinutes: 10, Severity: SeverityWarning})
+	start := time.Now()
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	e.Evaluate(State{}, at(0)) // no sensor
+	e.Evaluate(State{Temperature: 90}, at(0))
+	e.Evaluate(State{Temperature: 92}, at(9))
+	assert.Empty(t, pub.events, "not sustained yet")
+	require.Len(t, e.Active(), 1)
+	assert.False(t, e.Active()[0].Fired)
+
+	events := e.Evaluate(State{Temperature: 93}, at(10))
+	require.Len(t, events, 1)
+	assert.Equal(t, notify.KindTemperatureHigh, events[0].Kind)
+	assert.Equal(t, SeverityWarning, events[0].Severity)
+	assert.Contains(t, events[0].Message, "for 10 minutes")
+
+	// Once per episode: dipping just below the threshold doesn't re-arm,
+	// falling well below does
+	e.Evaluate(State{Temperature: 80}, at(11))
+	e.Evaluate(State{Temperature: 90}, at(30))
+	assert.Len(t, pub.events, 1)
+	e.Evaluate(State{Temperature: 60}, at(31))
+	assert.Empty(t, e.Active())
+	e.Evaluate(State{Temperature: 90}, at(32))
+	e.Evaluate(State{Temperature: 90}, at(42))
+	assert.Len(t, pub.events, 2)
+}
+
+func TestEvaluator_AppDown(t *testing.T) {
+	e, pub := newTestEvaluator(t, Rule{ID: "down", Type: TypeAppDown, ForMinutes: 5, Severity: SeverityCritical})
+	now := time.Now()
+
+	e.Evaluate(State{DownApps: []string{"miniflux", "immich"}}, now)
+	e.Evaluate(State{DownApps: []string{"immich"}}, now.Add(3*time.Minute)) // miniflux recovered
+	e.Evaluate(State{DownApps: []string{"immich", "miniflux"}}, now.Add(5*time.Minute))
+	require.Len(t, pub.events, 1)
+	assert.Equal(t, notify.KindAppDown, pub.events[0].Kind)
+	assert.Equal(t, "immich", pub.events[0].App)
+	assert.Equal(t, "immich is down", pub.events[0].Title)
+
+	active := e.Active()
+	require.Len(t, active, 2)
+	assert.True(t, active[0].Fired)
+	assert.False(t, active[1].Fired, "miniflux went down again two minutes ago")
+}
+
+func TestEvaluator_Muted(t *testing.T) {
+	e, pub := newTestEvaluator(t, Rule{ID: "disk", Type: TypeDisk, Threshold: 90, Muted: true})
+
+	e.Evaluate(State{Disk: 95}, time.Now())
+	assert.Empty(t, pub.events)
+	require.Len(t, e.Active(), 1, "muted rules still show as firing")
+
+	// Unmuting starts the rule over, so it notifies
+	require.NoError(t, e.SetConfig(Config{Rules: []Rule{{ID: "disk", Type: TypeDisk, Threshold: 90}}}))
+	assert.Empty(t, e.Active())
+	e.Evaluate(State{Disk: 95}, time.Now())
+	require.Len(t, pub.events, 1)
+	assert.Equal(t, notify.KindDiskFull, pub.events[0].Kind)
+}
+
+func TestEvaluator_BackupAge(t *testing.T) {
+	e, pub := newTestEvaluator(t, Rule{ID: "backups", Type: TypeBackupAge, Threshold: 3})
+	now := time.Now()
+
+	e.Evaluate(State{}, now)
+	e.Evaluate(State{Backups: true, LastBackup: now.Add(-24 * time.Hour)}, now)
+	assert.Empty(t, pub.events)
+
+	e.Evaluate(State{Backups: true, LastBackup: now.Add(-5 * 24 * time.Hour)}, now)
+	require.Len(t, pub.events, 1)
+	assert.Equal(t, notify.KindBackupStale, pub.events[0].Kind)
+	assert.Contains(t, pub.events[0].Message, "5 days old")
+}
+
+func TestEvaluator_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	e := NewEvaluator(path, nil, slog.Default())
+	require.NoError(t, e.Load(), "missing file is fine")
+	assert.Equal(t, DefaultConfig(), e.Config())
+
+	require.NoError(t, e.SetConfig(Config{Rules: []Rule{{Type: TypeDisk, Threshold: 80}}}))
+
+	reloaded := NewEvaluator(path, nil, slog.Default())
+	require.NoError(t, reloaded.Load())
+	assert.Equal(t, []Rule{{ID: "disk", Type: TypeDisk, Threshold: 80, Severity: SeverityWarning}}, reloaded.Config().Rules)
+}
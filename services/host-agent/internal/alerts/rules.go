@@ -0,0 +1,94 @@
+package alerts
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Rule types and what Threshold means for each
+const (
+	TypeDisk        = "disk"        // root filesystem usage above Threshold percent
+	TypeAppDown     = "app_down"    // an installed app in error; Threshold unused
+	TypeTemperature = "temperature" // CPU package above Threshold °C
+	TypeBackupAge   = "backup_age"  // newest database backup older than Threshold days
+)
+
+// Types lists every rule type
+var Types = []string{TypeDisk, TypeAppDown, TypeTemperature, TypeBackupAge}
+
+// Severities, lowest first
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Severities lists every severity
+var Severities = []string{SeverityInfo, SeverityWarning, SeverityCritical}
+
+// Config is the persisted alert configuration
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule fires an alert when its condition has held for ForMinutes
+type Rule struct {
+	ID         string  `json:"id"`
+	Type       string  `json:"type"`
+	Threshold  float64 `json:"threshold"`
+	ForMinutes int     `json:"forMinutes"` // 0 fires on the first evaluation that matches
+	Severity   string  `json:"severity"`   // defaults to warning
+	Muted      bool    `json:"muted"`      // evaluated but never published
+}
+
+// DefaultConfig is used until rules are saved
+func DefaultConfig() Config {
+	return Config{Rules: []Rule{
+		{ID: "disk", Type: TypeDisk, Threshold: 90, Severity: SeverityWarning},
+		{ID: "app-down", Type: TypeAppDown, ForMinutes: 5, Severity: SeverityCritical},
+		// Most CPUs throttle at 95-105 °C; mini PCs in cupboards sit at 85
+		// under any sustained load. The delay ignores bursts like a transcode.
+		{ID: "temperature", Type: TypeTemperature, Threshold: 85, ForMinutes: 10, Severity: SeverityWarning},
+		// Backups run daily, so this means several have failed or been skipped
+		{ID: "backup-age", Type: TypeBackupAge, Threshold: 3, Severity: SeverityWarning},
+	}}
+}
+
+// Validate checks every rule and fills in defaults: IDs from the type and
+// warning severity
+func (c *Config) Validate() error {
+	seen := make(map[string]bool)
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if !slices.Contains(Types, rule.Type) {
+			return fmt.Errorf("rule %d: unknown type %q", i+1, rule.Type)
+		}
+		if rule.ID == "" {
+			rule.ID = rule.Type
+			for n := 2; seen[rule.ID]; n++ {
+				rule.ID = fmt.Sprintf("%s-%d", rule.Type, n)
+			}
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("duplicate rule id %q", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Severity == "" {
+			rule.Severity = SeverityWarning
+		}
+		if !slices.Contains(Severities, rule.Severity) {
+			return fmt.Errorf("rule %q: unknown severity %q", rule.ID, rule.Severity)
+		}
+		if rule.Type != TypeAppDown && rule.Threshold <= 0 {
+			return fmt.Errorf("rule %q: threshold must be positive", rule.ID)
+		}
+		if rule.Type == TypeDisk && rule.Threshold > 100 {
+			return fmt.Errorf("rule %q: disk threshold is a percentage", rule.ID)
+		}
+		if rule.ForMinutes < 0 {
+			return fmt.Errorf("rule %q: forMinutes can't be negative", rule.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/alerts"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
+)
+
+// alertInterval is how often the alert rules are evaluated; the finest
+// ForMinutes a rule can have
+const alertInterval = time.Minute
+
+// StartAlertEvaluator evaluates the alert rules every minute, publishing
+// through the notification channels
+func (s *Server) StartAlertEvaluator(ctx context.Context) {
+	if s.alertEvaluator == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(alertInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if !s.healthChecksPaused(now) {
+					s.alertEvaluator.Evaluate(s.alertState(), now)
+				}
+			}
+		}
+	}()
+}
+
+// alertState gathers what the alert rules look at
+func (s *Server) alertState() alerts.State {
+	host, _ := system.GetStats()
+	state := alerts.State{
+		Disk:        float64(host.Disk),
+		Temperature: float64(host.Temperature),
+	}
+
+	if apps, err := s.appStore.GetAll(); err == nil {
+		for _, app := range apps {
+			if app.Status == "error" && app.UninstalledAt == nil {
+				state.DownApps = append(state.DownApps, app.Name)
+			}
+		}
+	} else {
+		s.logger.Warn("failed to get apps for alerts", "error", err)
+	}
+
+	if s.cfg.Maintainer != nil {
+		if backups, err := s.cfg.Maintainer.ListBackups(); err == nil {
+			state.Backups = true
+			if len(backups) > 0 {
+				state.LastBackup = backups[0].CreatedAt
+			}
+		} else {
+			s.logger.Warn("failed to list backups for alerts", "error", err)
+		}
+	}
+	return state
+}
+
+// handleGetAlerts returns the alert rules and the ones currently firing
+func (s *Server) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
+	if s.alertEvaluator == nil {
+		respondError(w, http.StatusServiceUnavailable, "alerts not available")
+		return
+	}
+	respondJSON(w, http.StatusOK, s.alertsResponse())
+}
+
+// handleSetAlerts replaces the alert rules
+func (s *Server) handleSetAlerts(w http.ResponseWriter, r *http.Request) {
+	if s.alertEvaluator == nil {
+		respondError(w, http.StatusServiceUnavailable, "alerts not available")
+		return
+	}
+
+	var cfg alerts.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := s.alertEvaluator.SetConfig(cfg); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.logger.Info("updated alert rules", "count", len(cfg.Rules))
+	respondJSON(w, http.StatusOK, s.alertsResponse())
+}
+
+func (s *Server) alertsResponse() AlertsResponse {
+	return AlertsResponse{
+		Rules:  s.alertEvaluator.Config().Rules,
+		Firing: s.alertEvaluator.Active(),
+	}
+}
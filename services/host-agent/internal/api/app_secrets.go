@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxAppSecretSize bounds a stored secret value
+const maxAppSecretSize = 8 * 1024
+
+// handleListAppSecrets lists the secrets stored for an app with their
+// fingerprints. Values are never returned.
+func (s *Server) handleListAppSecrets(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.appSecretsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	resp := AppSecretsResponse{App: name, Secrets: []AppSecretInfo{}}
+	for _, key := range s.secrets.AppSecretKeys(name) {
+		resp.Secrets = append(resp.Secrets, AppSecretInfo{
+			Key:         key,
+			Fingerprint: s.secrets.AppSecretFingerprint(name, key),
+			Managed:     secrets.IsBuiltinAppSecret(key),
+		})
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// handleSetAppSecret stores a custom secret for an app, such as an API key or
+// SMTP password. Secrets bloud generates itself can't be overwritten.
+func (s *Server) handleSetAppSecret(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.appSecretsTarget(w, r)
+	if !ok {
+		return
+	}
+	key := chi.URLParam(r, "key")
+	if err := secrets.ValidateAppSecretKey(key); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req SetAppSecretRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAppSecretSize+1024)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Secret == "" {
+		respondError(w, http.StatusBadRequest, "secret is required")
+		return
+	}
+	if len(req.Secret) > maxAppSecretSize {
+		respondError(w, http.StatusBadRequest, "secret is too large")
+		return
+	}
+
+	if err := s.secrets.SetAppSecret(name, key, req.Secret); err != nil {
+		s.logger.Error("failed to store app secret", "app", name, "key", key, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to store secret")
+		return
+	}
+
+	s.logger.Info("stored app secret", "app", name, "key", key)
+	respondJSON(w, http.StatusOK, AppSecretInfo{
+		Key:         key,
+		Fingerprint: s.secrets.AppSecretFingerprint(name, key),
+	})
+}
+
+// handleDeleteAppSecret removes a custom secret from an app
+func (s *Server) handleDeleteAppSecret(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.appSecretsTarget(w, r)
+	if !ok {
+		return
+	}
+	key := chi.URLParam(r, "key")
+	if secrets.IsBuiltinAppSecret(key) {
+		respondError(w, http.StatusBadRequest, "secret "+key+" is managed by bloud")
+		return
+	}
+	if s.secrets.GetAppSecret(name, key) == "" {
+		respondError(w, http.StatusNotFound, "secret not found")
+		return
+	}
+
+	if err := s.secrets.DeleteAppSecret(name, key); err != nil {
+		s.logger.Error("failed to delete app secret", "app", name, "key", key, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to delete secret")
+		return
+	}
+
+	s.logger.Info("deleted app secret", "app", name, "key", key)
+	respondJSON(w, http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+// appSecretsTarget resolves the installed app a secrets request is for,
+// writing the error response and returning false if there isn't one
+func (s *Server) appSecretsTarget(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if s.secrets == nil {
+		respondError(w, http.StatusServiceUnavailable, "secrets not available")
+		return "", false
+	}
+
+	name := chi.URLParam(r, "name")
+	if !validAppName.MatchString(name) {
+		respondError(w, http.StatusBadRequest, "invalid app name")
+		return "", false
+	}
+	app, err := s.appStore.GetByName(name)
+	if err != nil {
+		s.logger.Error("failed to get app", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get app")
+		return "", false
+	}
+	if app == nil {
+		respondError(w, http.StatusNotFound, "app not installed")
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// appUsageMaxAge is how old the latest app usage can be before a request
+// samples it again. The stats recorder refreshes it every minute; podman
+// takes about a second to measure CPU, so requests don't each pay for it.
+const appUsageMaxAge = 15 * time.Second
+
+const defaultTopAppsLimit = 5
+
+var errAppStatsUnavailable = errors.New("per-app stats not available")
+
+// topAppsSorts maps the sort query parameter to the value apps are ranked by
+var topAppsSorts = map[string]func(store.AppUsage) float64{
+	"cpu":     func(u store.AppUsage) float64 { return u.CPU },
+	"memory":  func(u store.AppUsage) float64 { return u.Memory },
+	"network": func(u store.AppUsage) float64 { return u.NetRxRate + u.NetTxRate },
+}
+
+// collectAppUsage samples the containers of every installed app and makes
+// the result the latest usage. Network rates are measured against the
+// previous sample.
+func (s *Server) collectAppUsage(ctx context.Context, now time.Time) (map[string]store.AppUsage, error) {
+	if s.containerStats == nil {
+		return nil, errAppStatsUnavailable
+	}
+	containers, err := s.containerStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := s.appStore.GetInstalledNames()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]store.AppUsage)
+	for _, c := range containers {
+		app := appForContainer(c.Name, installed)
+		if app == "" {
+			continue
+		}
+		u := usage[app]
+		u.CPU += c.CPU
+		u.Memory += c.MemPerc
+		u.MemoryBytes += c.MemUsage
+		u.NetRx += c.NetInput
+		u.NetTx += c.NetOutput
+		u.SampledAt = now
+		usage[app] = u
+	}
+
+	s.appUsageMu.Lock()
+	defer s.appUsageMu.Unlock()
+	for app, u := range usage {
+		prev, ok := s.appUsage[app]
+		elapsed := now.Sub(prev.SampledAt).Seconds()
+		// Counters go backwards when a container restarts
+		if ok && elapsed > 0 && u.NetRx >= prev.NetRx && u.NetTx >= prev.NetTx {
+			u.NetRxRate = float64(u.NetRx-prev.NetRx) / elapsed
+			u.NetTxRate = float64(u.NetTx-prev.NetTx) / elapsed
+			usage[app] = u
+		}
+	}
+	s.appUsage, s.appUsageAt = usage, now
+	return usage, nil
+}
+
+// latestAppUsage returns the latest app usage, sampling it again if it is
+// older than appUsageMaxAge
+func (s *Server) latestAppUsage(ctx context.Context) (map[string]store.AppUsage, error) {
+	if usage, ok := s.cachedAppUsage(appUsageMaxAge); ok {
+		return usage, nil
+	}
+	return s.collectAppUsage(ctx, time.Now())
+}
+
+// cachedAppUsage returns the latest app usage without sampling, and whether
+// it is newer than maxAge
+func (s *Server) cachedAppUsage(maxAge time.Duration) (map[string]store.AppUsage, bool) {
+	s.appUsageMu.Lock()
+	defer s.appUsageMu.Unlock()
+	return s.appUsage, s.appUsage != nil && time.Since(s.appUsageAt) < maxAge
+}
+
+// appForContainer returns the installed app a container belongs to, or ""
+// if none. Containers are named after their app, some with an "apps-"
+// prefix; apps with several containers add a suffix ("authentik-worker").
+func appForContainer(container string, installed []string) string {
+	name := strings.TrimPrefix(container, "apps-")
+	app := ""
+	for _, candidate := range installed {
+		if name == candidate {
+			return candidate
+		}
+		if strings.HasPrefix(name, candidate+"-") && len(candidate) > len(app) {
+			app = candidate
+		}
+	}
+	return app
+}
+
+// handleAppStats returns an app's current CPU, memory and network usage
+func (s *Server) handleAppStats(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	app, err := s.appStore.GetByName(name)
+	if err != nil {
+		s.logger.Error("failed to get app", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get app")
+		return
+	}
+	if app == nil || app.UninstalledAt != nil {
+		respondError(w, http.StatusNotFound, "app not installed")
+		return
+	}
+
+	usage, err := s.latestAppUsage(r.Context())
+	if err != nil {
+		s.respondAppUsageError(w, err)
+		return
+	}
+
+	resp := AppStatsResponse{App: name}
+	if u, ok := usage[name]; ok {
+		resp.Usage = &u
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// handleTopApps returns the installed apps using the most resources.
+//
+// Query parameters: sort (cpu, memory or network; default cpu), limit
+// (default 5).
+func (s *Server) handleTopApps(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	by := q.Get("sort")
+	if by == "" {
+		by = "cpu"
+	}
+	value, ok := topAppsSorts[by]
+	if !ok {
+		respondError(w, http.StatusBadRequest, "sort must be cpu, memory or network")
+		return
+	}
+	limit := defaultTopAppsLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	usage, err := s.latestAppUsage(r.Context())
+	if err != nil {
+		s.respondAppUsageError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, TopAppsResponse{Sort: by, Apps: rankAppUsage(usage, value, limit)})
+}
+
+// rankAppUsage returns the limit apps with the highest value, heaviest first
+func rankAppUsage(usage map[string]store.AppUsage, value func(store.AppUsage) float64, limit int) []AppStatsResponse {
+	apps := make([]AppStatsResponse, 0, len(usage))
+	for app, u := range usage {
+		apps = append(apps, AppStatsResponse{App: app, Usage: &u})
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		a, b := value(*apps[i].Usage), value(*apps[j].Usage)
+		if a != b {
+			return a > b
+		}
+		return apps[i].App < apps[j].App
+	})
+	if len(apps) > limit {
+		apps = apps[:limit]
+	}
+	return apps
+}
+
+func (s *Server) respondAppUsageError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAppStatsUnavailable) {
+		respondError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	s.logger.Error("failed to get app usage", "error", err)
+	respondError(w, http.StatusInternalServerError, "failed to get app usage")
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// maxAuditBody is how much of a request body is read for the summary
+	maxAuditBody = 16 * 1024
+	// maxAuditSummary is the stored length of the summary
+	maxAuditSummary = 512
+
+	// auditLocalUser is recorded for trusted localhost requests (the CLI)
+	auditLocalUser = "localhost"
+)
+
+// auditMiddleware records state-changing requests (anything but GET, HEAD and
+// OPTIONS) in the audit log. It must run after authMiddleware so the user is
+// known.
+func (s *Server) auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.auditStore == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Read (a bounded prefix of) the body for the summary, then hand the
+		// handler an equivalent reader
+		var summary string
+		if r.Body != nil {
+			prefix, _ := io.ReadAll(io.LimitReader(r.Body, maxAuditBody))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(prefix), r.Body), r.Body}
+			summary = summarizeBody(prefix)
+		}
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		entry := &store.AuditEntry{
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Summary:    summary,
+			Status:     ww.Status(),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		if user := getUserFromContext(r.Context()); user != nil {
+			entry.Username = user.Username
+		} else if isLocalRequest(r) {
+			entry.Username = auditLocalUser
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			entry.Route = rctx.RoutePattern()
+		}
+
+		if err := s.auditStore.Record(entry); err != nil {
+			s.logger.Warn("failed to record audit entry", "method", entry.Method, "path", entry.Path, "error", err)
+		}
+	})
+}
+
+// summarizeBody renders a request body for the audit log. JSON objects have
+// credential-looking fields and URLs redacted; other bodies are reduced to
+// their size.
+func summarizeBody(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return strconv.Itoa(len(body)) + " bytes"
+	}
+
+	data, err := json.Marshal(redact(value))
+	if err != nil {
+		return strconv.Itoa(len(body)) + " bytes"
+	}
+
+	summary := string(data)
+	if len(summary) > maxAuditSummary {
+		summary = summary[:maxAuditSummary] + "…"
+	}
+	return summary
+}
+
+// redact replaces the values of sensitive keys anywhere in a decoded JSON value
+func redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, inner := range v {
+			if sensitiveKey(key) {
+				v[key] = "[redacted]"
+				continue
+			}
+			v[key] = redact(inner)
+		}
+	case []any:
+		for i, inner := range v {
+			v[i] = redact(inner)
+		}
+	}
+	return value
+}
+
+// sensitiveKey reports whether a key's value may be a credential. URLs count:
+// webhook and ntfy topic URLs are the only secret those channels have.
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"password", "secret", "token", "key"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return strings.HasSuffix(key, "url")
+}
+
+// handleListAudit returns audit log entries, newest first.
+//
+// Query parameters: user, method, path (prefix), result (success|failure),
+// since and until (RFC 3339), limit.
+func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	if s.auditStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "audit log not available")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := store.AuditFilter{
+		Username:   q.Get("user"),
+		Method:     q.Get("method"),
+		PathPrefix: q.Get("path"),
+	}
+
+	switch q.Get("result") {
+	case "":
+	case "success":
+		failed := false
+		filter.Failed = &failed
+	case "failure":
+		failed := true
+		filter.Failed = &failed
+	default:
+		respondError(w, http.StatusBadRequest, "result must be success or failure")
+		return
+	}
+
+	for param, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, param+" must be an RFC 3339 timestamp")
+				return
+			}
+			*dst = t
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := s.auditStore.List(filter)
+	if err != nil {
+		s.logger.Error("failed to list audit log", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to list audit log")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AuditLogResponse{Entries: entries})
+}
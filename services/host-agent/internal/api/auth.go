@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
@@ -17,11 +18,18 @@ const (
 	sessionCookieName = "bloud_session"
 	stateCookieName   = "bloud_oauth_state"
 	stateCookieMaxAge = 10 * 60 // 10 minutes
+
+	// adminGroupName is the Authentik group whose members are Bloud admins.
+	// The first user created during setup is added to it.
+	adminGroupName = "authentik Admins"
 )
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey  contextKey = "user"
+	tokenContextKey contextKey = "token" // set when authenticated with a personal access token
+)
 
 // AuthConfig holds OIDC configuration for authentication.
 // OIDCConfig contains path templates only (no host). Full URLs are derived
@@ -68,6 +76,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	// Lazily register this redirect URI in Authentik if we haven't seen this host before.
 	// This handles access via mDNS, Tailscale, custom DNS, or any unexpected hostname.
+	// The last-used time is recorded so the periodic cleanup keeps hosts still in use.
 	if _, known := s.knownRedirectURIs.Load(redirectURI); !known {
 		if s.authentikClient != nil && s.authConfig.OIDCConfig.ProviderID > 0 {
 			if err := s.authentikClient.AddRedirectURI(s.authConfig.OIDCConfig.ProviderID, redirectURI); err != nil {
@@ -76,8 +85,10 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 			} else {
 				s.logger.Info("lazily registered redirect URI", "uri", redirectURI)
 			}
-			s.knownRedirectURIs.Store(redirectURI, true)
+			s.knownRedirectURIs.Store(redirectURI, time.Now())
 		}
+	} else {
+		s.knownRedirectURIs.Store(redirectURI, time.Now())
 	}
 
 	authURL, err := url.Parse(baseURL + s.authConfig.OIDCConfig.AuthURL)
@@ -195,7 +206,7 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 
 	// Create session
 	ctx := r.Context()
-	session, err := s.sessionStore.Create(ctx, user.ID, user.Username)
+	session, err := s.sessionStore.Create(ctx, user.ID, user.Username, roleFromGroups(userInfo.Groups))
 	if err != nil {
 		s.logger.Error("failed to create session", "error", err)
 		http.Error(w, "Failed to create session", http.StatusInternalServerError)
@@ -211,6 +222,9 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
 	})
+	if err := setCSRFCookie(w, session.ExpiresAt); err != nil {
+		s.logger.Warn("failed to issue CSRF token", "error", err)
+	}
 
 	s.logger.Info("user logged in", "username", user.Username)
 
@@ -237,6 +251,7 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 		MaxAge:   -1,
 		HttpOnly: true,
 	})
+	clearCSRFCookie(w)
 
 	// Redirect to home (which will show login page)
 	http.Redirect(w, r, "/", http.StatusFound)
@@ -264,16 +279,19 @@ func (s *Server) handleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	// Get session from store
 	ctx := r.Context()
 	session, err := s.sessionStore.Get(ctx, cookie.Value)
-	if err != nil || session == nil {
+	// Sessions created before roles existed carry none; the API rejects
+	// them, so they log in again
+	if err != nil || session == nil || session.Role == "" {
 		respondJSON(w, http.StatusUnauthorized, map[string]string{
 			"error": "Not authenticated",
 		})
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"id":       session.UserID,
-		"username": session.Username,
+	respondJSON(w, http.StatusOK, CurrentUserResponse{
+		ID:       session.UserID,
+		Username: session.Username,
+		Role:     session.Role,
 	})
 }
 
@@ -298,6 +316,12 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Personal access tokens are accepted alongside session cookies
+		if token, ok := bearerToken(r); ok {
+			s.authenticateToken(w, r, next, token)
+			return
+		}
+
 		// Check for session cookie
 		cookie, err := r.Cookie(sessionCookieName)
 		if err != nil || cookie.Value == "" {
@@ -318,8 +342,12 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		if session == nil {
-			// Session not found or expired
+		if session == nil || session.Role == "" {
+			// Session not found or expired, or created before sessions
+			// carried a role, which can't be assumed either way
+			if session != nil {
+				s.sessionStore.Delete(ctx, session.ID)
+			}
 			// Clear the invalid cookie
 			http.SetCookie(w, &http.Cookie{
 				Name:     sessionCookieName,
@@ -347,6 +375,7 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 		user := &store.User{
 			ID:       session.UserID,
 			Username: session.Username,
+			Role:     session.Role,
 		}
 
 		// Add user to context
@@ -355,6 +384,97 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// authenticateToken validates a personal access token and its scopes, then
+// serves the request as the token's owner
+func (s *Server) authenticateToken(w http.ResponseWriter, r *http.Request, next http.Handler, plaintext string) {
+	if s.tokenStore == nil {
+		respondError(w, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	token, err := s.tokenStore.Authenticate(plaintext)
+	if err != nil {
+		s.logger.Error("failed to validate token", "error", err)
+		respondError(w, http.StatusInternalServerError, "Failed to validate token")
+		return
+	}
+	if token == nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !token.HasScope(store.ScopeWrite) {
+		respondError(w, http.StatusForbidden, "token does not have the write scope")
+		return
+	}
+
+	// A token acts with its owner's current role, so demoting or disabling
+	// someone in Authentik takes effect on their tokens too
+	role, err := s.tokenOwnerRole(token.Username)
+	if err != nil {
+		s.logger.Error("failed to check token owner", "username", token.Username, "error", err)
+		respondError(w, http.StatusServiceUnavailable, "cannot verify the token's owner")
+		return
+	}
+	if role == "" {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+
+	// Tokens act as members unless they were granted the admin scope by
+	// someone who is still an admin
+	user := &store.User{
+		ID:       token.UserID,
+		Username: token.Username,
+		Role:     store.RoleMember,
+	}
+	if token.HasScope(store.ScopeAdmin) && role == store.RoleAdmin {
+		user.Role = store.RoleAdmin
+	}
+
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	ctx = context.WithValue(ctx, tokenContextKey, token)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// requireAdmin rejects requests from users without the admin role.
+// Like authMiddleware, it trusts localhost and is a no-op when auth is not configured.
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.sessionStore == nil || isLocalRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user := getUserFromContext(r.Context())
+		if user == nil || !user.IsAdmin() {
+			respondError(w, http.StatusForbidden, "admin access required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// roleFromGroups maps the OIDC groups claim to a Bloud role
+func roleFromGroups(groups []string) string {
+	for _, g := range groups {
+		if g == adminGroupName {
+			return store.RoleAdmin
+		}
+	}
+	return store.RoleMember
+}
+
 // getUserFromContext retrieves the user from the request context
 func getUserFromContext(ctx context.Context) *store.User {
 	user, ok := ctx.Value(userContextKey).(*store.User)
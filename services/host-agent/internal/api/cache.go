@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Cache policies for catalog responses. Catalog JSON changes only when the
+// catalog is refreshed or an app is installed, but must show those changes
+// immediately, so browsers revalidate on every use and get a 304 when nothing
+// changed. Catalog icons only change with a new Bloud release and may be
+// reused for a day before revalidating.
+const (
+	cacheRevalidate  = "private, no-cache"
+	cacheCatalogIcon = "public, max-age=86400, must-revalidate"
+)
+
+// contentETag returns a strong ETag for a response body
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondCachedJSON writes data like respondJSON with a 200, adding an ETag
+// and answering 304 Not Modified when the client already has this body
+func respondCachedJSON(w http.ResponseWriter, r *http.Request, data any) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+
+	etag := contentETag(buf.Bytes())
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheRevalidate)
+	w.Header().Set("Vary", "Cookie, Authorization")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// serveCachedFile serves a file with a content ETag; http.ServeFile answers
+// If-None-Match itself once the ETag header is set
+func serveCachedFile(w http.ResponseWriter, r *http.Request, path, cacheControl string) {
+	if data, err := os.ReadFile(path); err == nil {
+		w.Header().Set("ETag", contentETag(data))
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeFile(w, r, path)
+}
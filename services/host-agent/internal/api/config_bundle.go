@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/bundle"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/orchestrator"
+)
+
+// maxBundleSize bounds the body accepted by the import endpoint
+const maxBundleSize = 1 << 20
+
+// handleExportConfig returns a signed bundle of installed apps, their
+// integration choices, routing settings and secret names
+func (s *Server) handleExportConfig(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.SSOHostSecret == "" {
+		respondError(w, http.StatusServiceUnavailable, "bundle signing key not configured")
+		return
+	}
+
+	apps, err := s.appStore.GetAll()
+	if err != nil {
+		s.logger.Error("failed to get apps for export", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get apps")
+		return
+	}
+
+	b := bundle.Bundle{
+		Version:   bundle.FormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Routing: bundle.Routing{
+			BaseURL:      s.cfg.SSOBaseURL,
+			AuthentikURL: s.cfg.SSOAuthentikURL,
+		},
+		Apps: []bundle.App{},
+	}
+	for _, app := range apps {
+		exported := bundle.App{
+			Name:        app.Name,
+			DisplayName: app.DisplayName,
+			Version:     app.Version,
+			Port:        app.Port,
+			System:      app.IsSystem,
+			Choices:     app.IntegrationConfig,
+		}
+		if s.secrets != nil {
+			exported.Secrets = s.secrets.AppSecretKeys(app.Name)
+		}
+		b.Apps = append(b.Apps, exported)
+	}
+
+	if err := b.Sign(bundle.SigningKey(s.cfg.SSOHostSecret)); err != nil {
+		s.logger.Error("failed to sign config bundle", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to sign bundle")
+		return
+	}
+
+	filename := fmt.Sprintf("bloud-config-%s.json", b.CreatedAt.Format("20060102-150405"))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	respondJSON(w, http.StatusOK, b)
+}
+
+// handleImportConfig verifies a bundle and replays it through the orchestrator.
+//
+// Apps that are already installed are skipped, the rest are installed in
+// dependency order in the background; progress is published on the
+// operations event topic. With ?dryRun=true only the plan is returned.
+func (s *Server) handleImportConfig(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.SSOHostSecret == "" {
+		respondError(w, http.StatusServiceUnavailable, "bundle signing key not configured")
+		return
+	}
+
+	dryRun, err := parseBool(r.URL.Query(), "dryRun")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var b bundle.Bundle
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBundleSize)).Decode(&b); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid bundle")
+		return
+	}
+	if err := b.Verify(bundle.SigningKey(s.cfg.SSOHostSecret)); err != nil {
+		if errors.Is(err, bundle.ErrInvalidSignature) {
+			err = fmt.Errorf("%w (was it exported from a host with the same secrets file?)", err)
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := b.InstallOrder()
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := ImportConfigResponse{Install: []string{}, Skipped: []string{}, Warnings: []string{}, DryRun: dryRun != nil && *dryRun}
+	var toInstall []bundle.App
+	for _, app := range order {
+		if _, err := s.catalog.Get(app.Name); err != nil {
+			respondError(w, http.StatusBadRequest, "app not in catalog: "+app.Name)
+			return
+		}
+		installed, err := s.appStore.IsInstalled(app.Name)
+		if err != nil {
+			s.logger.Error("failed to check installed state", "app", app.Name, "error", err)
+			respondError(w, http.StatusInternalServerError, "failed to check installed apps")
+			return
+		}
+		if installed {
+			resp.Skipped = append(resp.Skipped, app.Name)
+			continue
+		}
+		resp.Install = append(resp.Install, app.Name)
+		toInstall = append(toInstall, app)
+	}
+
+	// Routing comes from the host's environment, so differences are only reported
+	if b.Routing.BaseURL != s.cfg.SSOBaseURL {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("base URL differs: bundle has %q, host uses %q", b.Routing.BaseURL, s.cfg.SSOBaseURL))
+	}
+	if b.Routing.AuthentikURL != s.cfg.SSOAuthentikURL {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("Authentik URL differs: bundle has %q, host uses %q", b.Routing.AuthentikURL, s.cfg.SSOAuthentikURL))
+	}
+	for _, app := range toInstall {
+		for _, key := range app.Secrets {
+			if s.secrets == nil || s.secrets.GetAppSecret(app.Name, key) == "" {
+				resp.Warnings = append(resp.Warnings, fmt.Sprintf("%s: secret %s not present, a new one will be generated", app.Name, key))
+			}
+		}
+	}
+
+	if resp.DryRun {
+		respondJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	nixOrch, ok := s.orchestrator.(*orchestrator.Orchestrator)
+	if !ok || nixOrch == nil {
+		respondError(w, http.StatusServiceUnavailable, "orchestrator not available (podman not running?)")
+		return
+	}
+
+	s.logger.Info("importing config bundle", "install", resp.Install, "skipped", resp.Skipped)
+	go s.replayBundle(context.WithoutCancel(r.Context()), nixOrch, toInstall)
+
+	respondJSON(w, http.StatusAccepted, resp)
+}
+
+// replayBundle installs apps one at a time, in order, restoring display names.
+// A failed install is reported and the replay continues; the orchestrator
+// rejects later apps whose integrations can no longer be satisfied.
+func (s *Server) replayBundle(ctx context.Context, nixOrch *orchestrator.Orchestrator, apps []bundle.App) {
+	for _, app := range apps {
+		s.publishOperation("install", app.Name, "queued", "")
+		result, err := nixOrch.EnqueueInstall(ctx, orchestrator.InstallRequest{
+			App:     app.Name,
+			Choices: app.Choices,
+		})
+		if err == nil && !result.IsSuccess() {
+			err = errors.New(result.GetError())
+		}
+		if err != nil {
+			s.logger.Error("import: install failed", "app", app.Name, "error", err)
+			s.publishOperation("install", app.Name, "failed", err.Error())
+			continue
+		}
+		s.publishOperation("install", app.Name, "completed", "")
+
+		if app.DisplayName != "" {
+			if err := s.appStore.UpdateDisplayName(app.Name, app.DisplayName); err != nil {
+				s.logger.Warn("import: failed to restore display name", "app", app.Name, "error", err)
+			}
+		}
+	}
+
+	s.triggerReconcile()
+	s.logger.Info("config bundle import finished", "apps", len(apps))
+}
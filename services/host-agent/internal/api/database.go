@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleListDatabaseBackups lists the host-agent database backups, newest first
+func (s *Server) handleListDatabaseBackups(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Maintainer == nil {
+		respondError(w, http.StatusServiceUnavailable, "database maintenance not available")
+		return
+	}
+
+	backups, err := s.cfg.Maintainer.ListBackups()
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to list database backups", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to list backups")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, DatabaseBackupsResponse{Backups: backups})
+}
+
+// handleDatabaseMaintenance runs the integrity check, vacuum and backup that
+// otherwise run daily in the background
+func (s *Server) handleDatabaseMaintenance(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Maintainer == nil {
+		respondError(w, http.StatusServiceUnavailable, "database maintenance not available")
+		return
+	}
+
+	report, err := s.cfg.Maintainer.Run(r.Context())
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "database maintenance failed", "error", err)
+		respondError(w, http.StatusInternalServerError, "database maintenance failed: "+err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// handleRestoreDatabaseBackup replaces the database with a backup. A safety
+// backup of the current state is taken first and returned so the restore
+// can be undone.
+func (s *Server) handleRestoreDatabaseBackup(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Maintainer == nil {
+		respondError(w, http.StatusServiceUnavailable, "database maintenance not available")
+		return
+	}
+	name := chi.URLParam(r, "name")
+
+	safety, err := s.cfg.Maintainer.Restore(r.Context(), name)
+	if errors.Is(err, db.ErrBackupNotFound) {
+		respondError(w, http.StatusNotFound, "backup not found")
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "database restore failed", "backup", name, "safety_backup", safety, "error", err)
+		respondError(w, http.StatusInternalServerError, "database restore failed: "+err.Error())
+		return
+	}
+
+	s.logger.InfoContext(r.Context(), "restored database backup", "backup", name, "safety_backup", safety)
+
+	// Installed apps may differ after the restore
+	if s.appHub != nil {
+		s.appHub.Broadcast()
+	}
+
+	respondJSON(w, http.StatusOK, RestoreBackupResponse{Restored: name, SafetyBackup: safety})
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Consistency checks run by the database check
+const (
+	checkAppNotInCatalog    = "app_not_in_catalog"
+	checkIntegrationMissing = "integration_source_missing"
+	checkOrphanedAppSecrets = "orphaned_app_secrets"
+)
+
+// handleDatabaseCheck reports inconsistencies between the database, the
+// catalog and the secrets file without changing anything
+func (s *Server) handleDatabaseCheck(w http.ResponseWriter, r *http.Request) {
+	report, err := s.checkDatabase(false)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "database check failed", "error", err)
+		respondError(w, http.StatusInternalServerError, "database check failed")
+		return
+	}
+	respondJSON(w, http.StatusOK, report)
+}
+
+// handleDatabaseRepair runs the database check and fixes what it safely can
+func (s *Server) handleDatabaseRepair(w http.ResponseWriter, r *http.Request) {
+	report, err := s.checkDatabase(true)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "database repair failed", "error", err)
+		respondError(w, http.StatusInternalServerError, "database repair failed")
+		return
+	}
+	if report.Repaired > 0 {
+		s.logger.InfoContext(r.Context(), "repaired database inconsistencies", "repaired", report.Repaired)
+		if s.appHub != nil {
+			s.appHub.Broadcast()
+		}
+	}
+	respondJSON(w, http.StatusOK, report)
+}
+
+// checkDatabase looks for installed apps missing from the catalog,
+// integration choices pointing at apps that aren't installed, and secrets
+// left behind for apps that were never installed. With repair, dangling
+// integration choices are dropped and orphaned secrets deleted.
+func (s *Server) checkDatabase(repair bool) (*DatabaseCheckResponse, error) {
+	apps, err := s.appStore.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get apps: %w", err)
+	}
+	uninstalled, err := s.appStore.GetUninstalled()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uninstalled apps: %w", err)
+	}
+
+	installed := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		installed[app.Name] = true
+	}
+	known := make(map[string]bool, len(apps)+len(uninstalled))
+	for name := range installed {
+		known[name] = true
+	}
+	for _, app := range uninstalled {
+		known[app.Name] = true
+	}
+
+	report := &DatabaseCheckResponse{Findings: []DatabaseCheckFinding{}}
+	add := func(f DatabaseCheckFinding, fix func() error) {
+		if repair && fix != nil {
+			if err := fix(); err != nil {
+				f.Error = err.Error()
+			} else {
+				f.Repaired = true
+				report.Repaired++
+			}
+		}
+		f.Repairable = fix != nil
+		report.Findings = append(report.Findings, f)
+	}
+
+	for _, app := range apps {
+		if !app.IsSystem {
+			if entry, err := s.catalog.Get(app.Name); err != nil || entry == nil {
+				add(DatabaseCheckFinding{
+					Check:  checkAppNotInCatalog,
+					App:    app.Name,
+					Detail: fmt.Sprintf("%s is installed but not in the catalog", app.Name),
+					Action: "refresh the catalog, or uninstall the app",
+				}, nil)
+			}
+		}
+
+		var missing []string
+		for integration, source := range app.IntegrationConfig {
+			if !installed[source] {
+				missing = append(missing, integration)
+			}
+		}
+		sort.Strings(missing)
+		for _, integration := range missing {
+			source := app.IntegrationConfig[integration]
+			add(DatabaseCheckFinding{
+				Check:  checkIntegrationMissing,
+				App:    app.Name,
+				Detail: fmt.Sprintf("integration %s points at %s, which is not installed", integration, source),
+				Action: fmt.Sprintf("install %s, or drop the choice so the integration is reconfigured", source),
+			}, func() error {
+				return s.dropIntegrationChoice(app.Name, integration)
+			})
+		}
+	}
+
+	if s.secrets != nil {
+		var orphaned []string
+		for _, name := range s.secrets.AppNames() {
+			if !known[name] {
+				orphaned = append(orphaned, name)
+			}
+		}
+		for _, name := range orphaned {
+			add(DatabaseCheckFinding{
+				Check:  checkOrphanedAppSecrets,
+				App:    name,
+				Detail: fmt.Sprintf("secrets are stored for %s, which has never been installed", name),
+				Action: "delete the secrets",
+			}, func() error {
+				return s.secrets.DeleteAppSecrets(name)
+			})
+		}
+	}
+
+	report.OK = true
+	for _, f := range report.Findings {
+		if !f.Repaired {
+			report.OK = false
+		}
+	}
+	return report, nil
+}
+
+// dropIntegrationChoice removes one integration choice from an app's config
+func (s *Server) dropIntegrationChoice(appName, integration string) error {
+	app, err := s.appStore.GetByName(appName)
+	if err != nil {
+		return err
+	}
+	if app == nil {
+		return fmt.Errorf("app not found: %s", appName)
+	}
+	config := make(map[string]string, len(app.IntegrationConfig))
+	for k, v := range app.IntegrationConfig {
+		if k != integration {
+			config[k] = v
+		}
+	}
+	return s.appStore.UpdateIntegrationConfig(appName, config)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/diagnostics"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
+)
+
+const (
+	// diagnosticsJournalLines is how many recent journal lines are bundled
+	// per unit
+	diagnosticsJournalLines = 1000
+
+	// diagnosticsTimeout bounds collecting a bundle
+	diagnosticsTimeout = time.Minute
+)
+
+// diagnosticsVersions is versions.json in a support bundle
+type diagnosticsVersions struct {
+	GeneratedAt     time.Time `json:"generatedAt"`
+	HostAgent       string    `json:"hostAgent"` // VCS revision the binary was built from
+	APIVersion      string    `json:"apiVersion"`
+	GoVersion       string    `json:"goVersion"`
+	Platform        string    `json:"platform"`
+	Kernel          string    `json:"kernel,omitempty"`
+	NixosVersion    string    `json:"nixosVersion,omitempty"`
+	NixosGeneration int       `json:"nixosGeneration,omitempty"`
+}
+
+// handleDiagnostics returns a support bundle: a gzipped tarball of recent
+// journals, app statuses, apps.nix, Traefik config, versions and the last
+// rebuild's output, with secrets scrubbed
+func (s *Server) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), diagnosticsTimeout)
+	defer cancel()
+
+	name := "bloud-diagnostics-" + time.Now().UTC().Format("20060102-150405")
+	var buf bytes.Buffer
+	if err := s.writeDiagnostics(ctx, &buf, name); err != nil {
+		s.logger.ErrorContext(ctx, "failed to build diagnostics bundle", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to build diagnostics bundle")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.tar.gz"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// writeDiagnostics writes a support bundle with its files under the name
+// directory
+func (s *Server) writeDiagnostics(ctx context.Context, buf *bytes.Buffer, name string) error {
+	secretValues := []string{s.cfg.SSOHostSecret, s.cfg.AuthentikToken}
+	if s.secrets != nil {
+		secretValues = append(secretValues, s.secrets.Values()...)
+	}
+	b := diagnostics.NewBundle(buf, name, diagnostics.NewRedactor(secretValues))
+
+	versions, err := json.MarshalIndent(collectVersions(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling versions: %w", err)
+	}
+	if err := b.Add("versions.json", versions); err != nil {
+		return err
+	}
+
+	apps, err := s.appStore.GetAll()
+	if err != nil {
+		b.Fail("apps.json", err)
+	} else {
+		data, err := json.MarshalIndent(apps, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling apps: %w", err)
+		}
+		if err := b.Add("apps.json", data); err != nil {
+			return err
+		}
+	}
+
+	if err := b.AddFile("apps.nix", filepath.Join(s.cfg.ConfigDir, "apps.nix")); err != nil {
+		return err
+	}
+	if err := b.AddFile("rebuild.log", filepath.Join(s.cfg.DataDir, nixgen.RebuildLogName)); err != nil {
+		return err
+	}
+	if err := s.addTraefikConfig(b); err != nil {
+		return err
+	}
+
+	journals := map[string][]string{
+		"host-agent": {"--unit=bloud-host-agent.service"},
+	}
+	for _, app := range apps {
+		journals[app.Name] = []string{"--user", "--unit=podman-" + app.Name + ".service"}
+	}
+	for _, unit := range slices.Sorted(maps.Keys(journals)) {
+		args := journals[unit]
+		file := "journal/" + unit + ".log"
+		output, err := s.readJournal(ctx, append(args, "--no-pager", "--output=short-iso", fmt.Sprintf("--lines=%d", diagnosticsJournalLines)))
+		if err != nil {
+			b.Fail(file, err)
+			continue
+		}
+		if err := b.Add(file, output); err != nil {
+			return err
+		}
+	}
+
+	return b.Close()
+}
+
+// addTraefikConfig adds the Traefik config files from the data directory.
+// Only YAML and TOML files are included; acme.json holds certificate keys.
+func (s *Server) addTraefikConfig(b *diagnostics.Bundle) error {
+	dir := filepath.Join(s.cfg.DataDir, "traefik")
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			b.Fail("traefik", err)
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yml", ".yaml", ".toml":
+		default:
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return b.AddFile("traefik/"+filepath.ToSlash(rel), path)
+	})
+}
+
+// readJournal returns journalctl's output for args
+func (s *Server) readJournal(ctx context.Context, args []string) ([]byte, error) {
+	if s.journalCommand != nil {
+		return s.journalCommand(ctx, args)
+	}
+	output, err := exec.CommandContext(ctx, "journalctl", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("journalctl: %w", err)
+	}
+	return output, nil
+}
+
+// collectVersions reports the versions of the host agent and the system
+func collectVersions() diagnosticsVersions {
+	v := diagnosticsVersions{
+		GeneratedAt: time.Now().UTC(),
+		HostAgent:   "unknown",
+		APIVersion:  currentAPIVersion,
+		GoVersion:   runtime.Version(),
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				v.HostAgent = setting.Value
+			}
+		}
+	}
+	if kernel, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		v.Kernel = strings.TrimSpace(string(kernel))
+	}
+	v.NixosGeneration, v.NixosVersion = system.CurrentGeneration()
+	return v
+}
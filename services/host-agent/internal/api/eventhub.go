@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
+)
+
+// Event topics a WebSocket client can subscribe to
+const (
+	TopicApps       = "apps"
+	TopicOperations = "operations"
+	TopicSystem     = "system"
+	TopicUpdates    = "updates"
+)
+
+// eventTopics lists every topic; clients that don't choose get all of them
+var eventTopics = []string{TopicApps, TopicOperations, TopicSystem, TopicUpdates}
+
+// Event types, each published on a single topic
+const (
+	EventAppStatus         = "app.status"         // apps: []*store.InstalledApp
+	EventOperationProgress = "operation.progress" // operations: OperationProgress
+	EventRebuild           = "rebuild"            // operations: nixgen.RebuildEvent
+	EventSystemStats       = "system.stats"       // system: system.Stats
+	EventUpdateStatus      = "update.status"      // updates: set by the update flow
+)
+
+// systemStatsInterval is how often system stats are published while anyone listens
+const systemStatsInterval = 2 * time.Second
+
+// Event is a typed message on the /api/events WebSocket
+type Event struct {
+	Type  string    `json:"type"`
+	Topic string    `json:"topic"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+}
+
+// OperationProgress describes a queued install or uninstall
+type OperationProgress struct {
+	Operation string `json:"operation"` // "install" or "uninstall"
+	App       string `json:"app"`
+	Phase     string `json:"phase"` // "queued", "completed" or "failed"
+	Error     string `json:"error,omitempty"`
+}
+
+// EventSubscription receives events for a changeable set of topics
+type EventSubscription struct {
+	C chan Event
+
+	mu     sync.RWMutex
+	topics map[string]bool
+}
+
+// SetTopics adds (subscribe=true) or removes topics from the subscription
+func (sub *EventSubscription) SetTopics(topics []string, subscribe bool) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	for _, topic := range topics {
+		if subscribe {
+			sub.topics[topic] = true
+		} else {
+			delete(sub.topics, topic)
+		}
+	}
+}
+
+// Wants reports whether the subscription includes topic
+func (sub *EventSubscription) Wants(topic string) bool {
+	sub.mu.RLock()
+	defer sub.mu.RUnlock()
+	return sub.topics[topic]
+}
+
+// EventHub fans typed events out to WebSocket subscribers by topic
+type EventHub struct {
+	mu          sync.RWMutex
+	subscribers map[*EventSubscription]struct{}
+}
+
+// NewEventHub creates a new event hub
+func NewEventHub() *EventHub {
+	return &EventHub{subscribers: make(map[*EventSubscription]struct{})}
+}
+
+// Subscribe creates a subscription for the given topics
+func (h *EventHub) Subscribe(topics []string) *EventSubscription {
+	sub := &EventSubscription{
+		C:      make(chan Event, 32),
+		topics: make(map[string]bool),
+	}
+	sub.SetTopics(topics, true)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes a subscription and closes its channel
+func (h *EventHub) Unsubscribe(sub *EventSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.C)
+	}
+}
+
+// Publish sends an event to every subscriber of its topic. Slow subscribers
+// miss events rather than blocking the publisher.
+func (h *EventHub) Publish(topic, eventType string, data any) {
+	event := Event{Type: eventType, Topic: topic, Time: time.Now(), Data: data}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subscribers {
+		if !sub.Wants(topic) {
+			continue
+		}
+		select {
+		case sub.C <- event:
+		default:
+		}
+	}
+}
+
+// HasSubscribers reports whether anyone is listening on topic
+func (h *EventHub) HasSubscribers(topic string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subscribers {
+		if sub.Wants(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// StartEventPublishers feeds the event hub from the existing app hub and the
+// system stats collector. It stops when ctx is cancelled.
+func (s *Server) StartEventPublishers(ctx context.Context) {
+	apps := s.appHub.Subscribe()
+
+	go func() {
+		defer s.appHub.Unsubscribe(apps)
+
+		ticker := time.NewTicker(systemStatsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-apps:
+				if !ok {
+					return
+				}
+				s.events.Publish(TopicApps, EventAppStatus, event.Apps)
+				s.syncScrapeConfig(event.Apps)
+			case <-ticker.C:
+				if !s.events.HasSubscribers(TopicSystem) {
+					continue
+				}
+				stats, err := system.GetStats()
+				if err != nil {
+					s.logger.Debug("failed to get system stats for events", "error", err)
+					continue
+				}
+				s.events.Publish(TopicSystem, EventSystemStats, stats)
+			}
+		}
+	}()
+}
+
+// publishOperation reports install/uninstall progress on the operations topic
+func (s *Server) publishOperation(operation, app, phase, errMsg string) {
+	s.events.Publish(TopicOperations, EventOperationProgress, OperationProgress{
+		Operation: operation,
+		App:       app,
+		Phase:     phase,
+		Error:     errMsg,
+	})
+}
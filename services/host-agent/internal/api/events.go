@@ -2,37 +2,53 @@ package api
 
 import (
 	"sync"
+	"time"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
 )
 
+// appEventBacklog is how many broadcasts are kept for clients that reconnect
+// with Last-Event-ID, enough to cover a rebuild's status transitions
+const appEventBacklog = 64
+
+// AppEvent is one app state broadcast. IDs increase monotonically, also
+// across restarts, so a reconnecting client can ask for what it missed.
+type AppEvent struct {
+	ID   uint64
+	Apps []*store.InstalledApp
+}
+
 // AppEventHub manages SSE subscribers for app state updates
 type AppEventHub struct {
-	subscribers map[chan []*store.InstalledApp]struct{}
+	subscribers map[chan AppEvent]struct{}
 	mu          sync.RWMutex
 	appStore    store.AppStoreInterface
+	lastID      uint64
+	backlog     []AppEvent // oldest first, at most appEventBacklog entries
 }
 
 // NewAppEventHub creates a new app event hub
 func NewAppEventHub(appStore store.AppStoreInterface) *AppEventHub {
 	return &AppEventHub{
-		subscribers: make(map[chan []*store.InstalledApp]struct{}),
+		subscribers: make(map[chan AppEvent]struct{}),
 		appStore:    appStore,
+		// Start from the clock so IDs from before a restart are always older
+		lastID: uint64(time.Now().UnixMilli()),
 	}
 }
 
 // Subscribe creates a new subscription channel for app updates
-func (h *AppEventHub) Subscribe() chan []*store.InstalledApp {
+func (h *AppEventHub) Subscribe() chan AppEvent {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	ch := make(chan []*store.InstalledApp, 10)
+	ch := make(chan AppEvent, 10)
 	h.subscribers[ch] = struct{}{}
 	return ch
 }
 
 // Unsubscribe removes a subscription channel
-func (h *AppEventHub) Unsubscribe(ch chan []*store.InstalledApp) {
+func (h *AppEventHub) Unsubscribe(ch chan AppEvent) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -40,25 +56,63 @@ func (h *AppEventHub) Unsubscribe(ch chan []*store.InstalledApp) {
 	close(ch)
 }
 
-// Broadcast sends the current app list to all subscribers
+// Broadcast records the current app list and sends it to all subscribers
 func (h *AppEventHub) Broadcast() {
 	apps, err := h.appStore.GetAll()
 	if err != nil {
 		return
 	}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := AppEvent{ID: h.lastID, Apps: apps}
+	h.backlog = append(h.backlog, event)
+	if len(h.backlog) > appEventBacklog {
+		h.backlog = h.backlog[len(h.backlog)-appEventBacklog:]
+	}
 
 	for ch := range h.subscribers {
 		select {
-		case ch <- apps:
+		case ch <- event:
 		default:
 			// Channel full, skip this subscriber
 		}
 	}
 }
 
+// Since returns the buffered events after lastID. ok is false when events
+// after lastID have already been dropped from the backlog, or lastID is not
+// one this hub issued, in which case the client needs a full snapshot.
+func (h *AppEventHub) Since(lastID uint64) (events []AppEvent, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if lastID > h.lastID {
+		return nil, false
+	}
+	if lastID == h.lastID {
+		return nil, true
+	}
+	if len(h.backlog) == 0 || lastID < h.backlog[0].ID-1 {
+		return nil, false
+	}
+	for _, event := range h.backlog {
+		if event.ID > lastID {
+			events = append(events, event)
+		}
+	}
+	return events, true
+}
+
+// LastID returns the ID of the most recent broadcast
+func (h *AppEventHub) LastID() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastID
+}
+
 // SubscriberCount returns the number of active subscribers
 func (h *AppEventHub) SubscriberCount() int {
 	h.mu.RLock()
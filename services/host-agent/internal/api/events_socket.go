@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// eventControlMessage is a JSON message sent by an events client to change
+// its topics, e.g. {"action":"subscribe","topics":["system"]}
+type eventControlMessage struct {
+	Action string   `json:"action"` // "subscribe" or "unsubscribe"
+	Topics []string `json:"topics"`
+}
+
+// handleEventsSocket serves typed events over a single WebSocket.
+//
+// The initial topics come from ?topics=apps,system (all topics when omitted);
+// clients can change them later with subscribe/unsubscribe messages. The
+// current app list is sent whenever the apps topic is subscribed, so clients
+// don't need a separate fetch.
+func (s *Server) handleEventsSocket(w http.ResponseWriter, r *http.Request) {
+	topics := eventTopics
+	if v := r.URL.Query().Get("topics"); v != "" {
+		topics = strings.Split(v, ",")
+	}
+	for _, topic := range topics {
+		if !slices.Contains(eventTopics, topic) {
+			respondError(w, http.StatusBadRequest, "unknown topic: "+topic)
+			return
+		}
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		s.logger.Warn("failed to accept events websocket", "error", err)
+		return
+	}
+
+	// The connection is hijacked, so the request timeout no longer applies.
+	// Cancelling a read context closes the socket, so connCtx is never cancelled;
+	// done signals that the client went away.
+	connCtx := context.WithoutCancel(r.Context())
+	done := make(chan struct{})
+
+	sub := s.events.Subscribe(topics)
+	s.logger.Info("events client connected", "topics", topics)
+	defer trackSSE("events")()
+
+	// Snapshots are queued on the subscription so they are ordered with
+	// live events by the single writer below
+	snapshot := func(topics []string) {
+		if !slices.Contains(topics, TopicApps) {
+			return
+		}
+		apps, err := s.appStore.GetAll()
+		if err != nil {
+			s.logger.Warn("failed to get apps for events snapshot", "error", err)
+			return
+		}
+		select {
+		case sub.C <- Event{Type: EventAppStatus, Topic: TopicApps, Time: time.Now(), Data: apps}:
+		default:
+		}
+	}
+	snapshot(topics)
+
+	// Client messages: topic changes. Reading also notices disconnects.
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.Read(connCtx)
+			if err != nil {
+				return
+			}
+
+			var msg eventControlMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+			valid := slices.DeleteFunc(slices.Clone(msg.Topics), func(t string) bool {
+				return !slices.Contains(eventTopics, t)
+			})
+			switch msg.Action {
+			case "subscribe":
+				sub.SetTopics(valid, true)
+				snapshot(valid)
+			case "unsubscribe":
+				sub.SetTopics(valid, false)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			s.events.Unsubscribe(sub)
+			conn.CloseNow()
+			s.logger.Info("events client disconnected")
+			return
+		case event := <-sub.C:
+			if err := wsjson.Write(connCtx, conn, event); err != nil {
+				// Closing the socket ends the reader, then the done case cleans up
+				conn.CloseNow()
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+
+	"github.com/coder/websocket"
+	"github.com/creack/pty"
+	"github.com/go-chi/chi/v5"
+)
+
+// execShells are the shells a terminal session may start
+var execShells = map[string]bool{
+	"/bin/sh":   true,
+	"/bin/bash": true,
+	"/bin/ash":  true,
+}
+
+// execControlMessage is a JSON text frame sent by the terminal client
+type execControlMessage struct {
+	Type string `json:"type"` // "resize"
+	Cols uint16 `json:"cols"`
+	Rows uint16 `json:"rows"`
+}
+
+// podmanExecCommand builds the command for an interactive shell in an app container.
+// Containers are named after the app (see nixos/lib/bloud-app.nix).
+func podmanExecCommand(ctx context.Context, container, shell string) *exec.Cmd {
+	return exec.CommandContext(ctx, "podman", "exec", "-it", container, shell)
+}
+
+// handleAppExec opens an interactive shell inside an app container over a WebSocket.
+//
+// Protocol:
+//   - binary frames from the client are written to the terminal's stdin
+//   - text frames from the client are JSON control messages, e.g.
+//     {"type":"resize","cols":120,"rows":40}
+//   - terminal output is sent to the client as binary frames
+//   - when the shell exits the socket is closed with the exit status as the reason
+func (s *Server) handleAppExec(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	app, err := s.appStore.GetByName(name)
+	if err != nil || app == nil {
+		respondError(w, http.StatusNotFound, "App not found")
+		return
+	}
+
+	shell := r.URL.Query().Get("shell")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	if !execShells[shell] {
+		respondError(w, http.StatusBadRequest, "unsupported shell")
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		s.logger.Warn("failed to accept exec websocket", "app", name, "error", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	// The connection is hijacked, so the request context's timeout no longer
+	// applies. Cancelling a read context closes the socket, so the shell gets
+	// its own context and the socket is only closed once the shell is reaped.
+	connCtx := context.WithoutCancel(r.Context())
+	cmdCtx, killShell := context.WithCancel(connCtx)
+	defer killShell()
+
+	newCommand := s.execCommand
+	if newCommand == nil {
+		newCommand = podmanExecCommand
+	}
+	cmd := newCommand(cmdCtx, name, shell)
+
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		s.logger.Error("failed to start exec session", "app", name, "error", err)
+		conn.Close(websocket.StatusInternalError, "failed to start shell")
+		return
+	}
+	defer ptmx.Close()
+
+	s.logger.Info("exec session started", "app", name, "shell", shell)
+
+	// Terminal output -> client
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		buf := make([]byte, 4096)
+		for {
+			n, err := ptmx.Read(buf)
+			if n > 0 {
+				if err := conn.Write(connCtx, websocket.MessageBinary, buf[:n]); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Client input -> terminal
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		for {
+			typ, data, err := conn.Read(connCtx)
+			if err != nil {
+				return
+			}
+
+			if typ == websocket.MessageBinary {
+				if _, err := ptmx.Write(data); err != nil {
+					return
+				}
+				continue
+			}
+
+			var msg execControlMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+			if msg.Type == "resize" && msg.Cols > 0 && msg.Rows > 0 {
+				pty.Setsize(ptmx, &pty.Winsize{Cols: msg.Cols, Rows: msg.Rows})
+			}
+		}
+	}()
+
+	select {
+	case <-outputDone:
+		// The shell exited (the pty returns EIO once it has no writers)
+	case <-inputDone:
+		// The client went away; don't leave the shell running
+		killShell()
+	}
+
+	waitErr := cmd.Wait()
+	reason := "exited"
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		reason = fmt.Sprintf("exited with status %d", exitErr.ExitCode())
+	}
+
+	s.logger.Info("exec session ended", "app", name, "reason", reason)
+	conn.Close(websocket.StatusNormalClosure, reason)
+}
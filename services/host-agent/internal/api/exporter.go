@@ -0,0 +1,171 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/orchestrator"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
+)
+
+// monitoringApps are catalog apps that scrape Prometheus targets. When one
+// is installed, a scrape config for the host agent is written for it to
+// load from scrapeConfigFile.
+var monitoringApps = []string{"prometheus", "grafana"}
+
+// scrapeConfigFile is where the scrape config is written, relative to the
+// data directory
+const scrapeConfigFile = "prometheus/bloud.yml"
+
+// exporterSnapshot gathers what /metrics/apps publishes
+func (s *Server) exporterSnapshot(ctx context.Context) metrics.Snapshot {
+	var snap metrics.Snapshot
+
+	apps, err := s.appStore.GetAll()
+	if err != nil {
+		s.logger.Warn("failed to get apps for metrics", "error", err)
+	}
+	var names []string
+	for _, app := range apps {
+		if app.UninstalledAt == nil {
+			names = append(names, app.Name)
+		}
+	}
+
+	restarts, err := s.serviceRestartCounts(ctx, names)
+	if err != nil {
+		s.logger.Debug("failed to get app restart counts", "error", err)
+	}
+	usage, err := s.latestAppUsage(ctx)
+	if err != nil && err != errAppStatsUnavailable {
+		s.logger.Debug("failed to get app usage for metrics", "error", err)
+	}
+
+	for _, app := range apps {
+		if app.UninstalledAt != nil {
+			continue
+		}
+		a := metrics.AppSnapshot{Name: app.Name, Status: app.Status}
+		if n, ok := restarts[app.Name]; ok {
+			a.Restarts = &n
+		}
+		if u, ok := usage[app.Name]; ok {
+			a.Usage = &metrics.AppUsage{
+				CPU:         u.CPU,
+				Memory:      u.Memory,
+				MemoryBytes: u.MemoryBytes,
+				NetRx:       u.NetRx,
+				NetTx:       u.NetTx,
+			}
+		}
+		snap.Apps = append(snap.Apps, a)
+	}
+
+	if host, err := system.GetStats(); err == nil {
+		snap.Host = metrics.HostSnapshot{
+			CPU:         float64(host.CPU),
+			Memory:      float64(host.Memory),
+			Disk:        float64(host.Disk),
+			Temperature: float64(host.Temperature),
+			Throttles:   host.Throttles,
+		}
+	}
+	snap.Host.Generation, snap.Host.NixOS = system.CurrentGeneration()
+	return snap
+}
+
+// serviceRestartCounts returns how often systemd restarted each app's
+// service, by app
+func (s *Server) serviceRestartCounts(ctx context.Context, apps []string) (map[string]uint64, error) {
+	if len(apps) == 0 {
+		return nil, nil
+	}
+	if s.serviceRestarts != nil {
+		return s.serviceRestarts(ctx, apps)
+	}
+
+	args := []string{"--user", "show", "-p", "Id", "-p", "NRestarts"}
+	units := make(map[string]string, len(apps))
+	for _, app := range apps {
+		unit := orchestrator.SystemdServiceName(app)
+		units[unit] = app
+		args = append(args, unit)
+	}
+	output, err := exec.CommandContext(ctx, "systemctl", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show: %w", err)
+	}
+	return parseRestartCounts(output, units), nil
+}
+
+// parseRestartCounts parses systemctl show output, one blank-line separated
+// block per unit, into restart counts by app
+func parseRestartCounts(output []byte, units map[string]string) map[string]uint64 {
+	counts := make(map[string]uint64)
+	var unit, restarts string
+	flush := func() {
+		if n, err := strconv.ParseUint(restarts, 10, 64); err == nil && units[unit] != "" {
+			counts[units[unit]] = n
+		}
+		unit, restarts = "", ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "Id":
+			unit = value
+		case "NRestarts":
+			restarts = value
+		case "":
+			flush()
+		}
+	}
+	flush()
+	return counts
+}
+
+// handleScrapeConfig returns a Prometheus scrape config for the host agent's
+// metrics, targeting the address the request was made to
+func (s *Server) handleScrapeConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write([]byte(metrics.ScrapeConfig(r.Host)))
+}
+
+// syncScrapeConfig writes the scrape config for a monitoring app that is
+// installed. Monitoring apps run on the host network, so they reach the
+// agent on localhost.
+func (s *Server) syncScrapeConfig(apps []*store.InstalledApp) {
+	if s.cfg.DataDir == "" || !slices.ContainsFunc(apps, func(app *store.InstalledApp) bool {
+		return app.UninstalledAt == nil && slices.Contains(monitoringApps, app.Name)
+	}) {
+		return
+	}
+
+	path := filepath.Join(s.cfg.DataDir, scrapeConfigFile)
+	config := []byte(metrics.ScrapeConfig(fmt.Sprintf("localhost:%d", s.cfg.Port)))
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, config) {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		s.logger.Warn("failed to create scrape config directory", "error", err)
+		return
+	}
+	if err := os.WriteFile(path, config, 0644); err != nil {
+		s.logger.Warn("failed to write scrape config", "error", err)
+		return
+	}
+	s.logger.Info("wrote Prometheus scrape config", "path", path)
+}
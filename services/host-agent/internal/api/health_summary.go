@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
+)
+
+// Health summary statuses
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // a dependency or app is failing
+	HealthDown     = "down"     // a critical check failed; the API can't do its job
+	HealthFailed   = "failed"   // a single check failed
+	HealthSkipped  = "skipped"  // the dependency isn't configured
+)
+
+// healthCheckTimeout bounds each dependency check in the summary
+const healthCheckTimeout = 3 * time.Second
+
+// processStart is reported as the host agent's uptime
+var processStart = time.Now()
+
+// healthCheck probes one dependency. Critical checks take the overall status
+// to down rather than degraded.
+type healthCheck struct {
+	name     string
+	critical bool
+	run      func(ctx context.Context) (detail string, err error) // errSkipped when not configured
+}
+
+// errSkipped marks a dependency that isn't configured on this host
+var errSkipped = fmt.Errorf("not configured")
+
+// handleHealthSummary combines dependency checks and each installed app's
+// last health result into one status. Responds 503 when the overall status
+// is down so uptime monitors can alert on the status code alone.
+func (s *Server) handleHealthSummary(w http.ResponseWriter, r *http.Request) {
+	results := runHealthChecks(r.Context(), s.healthChecks())
+
+	summary := HealthSummaryResponse{
+		Status:    HealthOK,
+		CheckedAt: time.Now(),
+		Checks:    results,
+		Apps:      []AppHealthResult{},
+	}
+	for _, result := range results {
+		if result.Status != HealthFailed {
+			continue
+		}
+		if result.Critical {
+			summary.Status = HealthDown
+		} else if summary.Status == HealthOK {
+			summary.Status = HealthDegraded
+		}
+	}
+
+	apps, err := s.appStore.GetAll()
+	if err != nil {
+		s.logger.Warn("failed to get apps for health summary", "error", err)
+	}
+	for _, app := range apps {
+		if app.IsSystem {
+			continue
+		}
+		result := AppHealthResult{Name: app.Name, Status: app.Status}
+		if last, ok := metrics.LastHealthCheck(app.Name); ok {
+			result.LastCheck = &last
+		}
+		healthy := app.Status != "error" && (result.LastCheck == nil || result.LastCheck.Healthy)
+		if !healthy && summary.Status == HealthOK {
+			summary.Status = HealthDegraded
+		}
+		summary.Apps = append(summary.Apps, result)
+	}
+	sort.Slice(summary.Apps, func(i, j int) bool { return summary.Apps[i].Name < summary.Apps[j].Name })
+
+	status := http.StatusOK
+	if summary.Status == HealthDown {
+		status = http.StatusServiceUnavailable
+	}
+	respondJSON(w, status, summary)
+}
+
+// runHealthChecks runs checks concurrently, each bounded by
+// healthCheckTimeout
+func runHealthChecks(ctx context.Context, checks []healthCheck) []HealthCheckResult {
+	results := make([]HealthCheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := check.run(ctx)
+			result := HealthCheckResult{
+				Name:      check.name,
+				Status:    HealthOK,
+				Critical:  check.critical,
+				Detail:    detail,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			switch {
+			case err == errSkipped:
+				result.Status = HealthSkipped
+			case err != nil:
+				result.Status = HealthFailed
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// healthChecks lists the dependency checks for the summary
+func (s *Server) healthChecks() []healthCheck {
+	return []healthCheck{
+		{name: "host-agent", critical: true, run: func(ctx context.Context) (string, error) {
+			return "up " + time.Since(processStart).Round(time.Second).String(), nil
+		}},
+		{name: "database", critical: true, run: func(ctx context.Context) (string, error) {
+			if s.db == nil {
+				return "", errSkipped
+			}
+			return "", s.db.PingContext(ctx)
+		}},
+		{name: "redis", run: func(ctx context.Context) (string, error) {
+			if s.sessionStore == nil || s.sessionStore.Client() == nil {
+				return "", errSkipped
+			}
+			return "", s.sessionStore.Client().Ping(ctx).Err()
+		}},
+		{name: "authentik", run: func(ctx context.Context) (string, error) {
+			return s.probeSystemApp(ctx, "authentik")
+		}},
+		{name: "traefik", run: func(ctx context.Context) (string, error) {
+			return s.probeSystemApp(ctx, "traefik")
+		}},
+	}
+}
+
+// probeSystemApp calls a system app's health check path from its catalog
+// metadata. Like reconcileAppHealth, any response below 500 counts as up.
+func (s *Server) probeSystemApp(ctx context.Context, name string) (string, error) {
+	app, err := s.catalog.Get(name)
+	if err != nil || app == nil || app.HealthCheck.Path == "" {
+		return "", errSkipped
+	}
+
+	url := fmt.Sprintf("http://localhost:%d%s", app.Port, app.HealthCheck.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return "", fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d", resp.StatusCode), nil
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// handleAppHistory returns an app's status transitions, newest first, with
+// a count of transitions into each status over the same window.
+//
+// Query parameters: since (RFC 3339, default all time), limit.
+func (s *Server) handleAppHistory(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if s.appEventStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "app history not available")
+		return
+	}
+
+	q := r.URL.Query()
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+		since = t
+	}
+
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	events, err := s.appEventStore.History(name, since, limit)
+	if err != nil {
+		s.logger.Error("failed to get app history", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get app history")
+		return
+	}
+	counts, err := s.appEventStore.CountByStatus(name, since)
+	if err != nil {
+		s.logger.Error("failed to count app transitions", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get app history")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AppHistoryResponse{App: name, Events: events, Counts: counts})
+}
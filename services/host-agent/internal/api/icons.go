@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxIconSize caps uploaded icons; catalog icons are a few KB
+const maxIconSize = 1 << 20
+
+// iconExtensions maps accepted upload media types to their file extension
+var iconExtensions = map[string]string{
+	"image/png":     ".png",
+	"image/svg+xml": ".svg",
+}
+
+// validAppName matches catalog app names
+var validAppName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// customIconDir holds user-uploaded icons as <app>.png or <app>.svg
+func (s *Server) customIconDir() string {
+	return filepath.Join(s.cfg.DataDir, "icons")
+}
+
+// customIconPath returns the uploaded icon for an app, or "" if there is none
+func (s *Server) customIconPath(name string) string {
+	for _, ext := range []string{".svg", ".png"} {
+		path := filepath.Join(s.customIconDir(), name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// handleAppIcon serves an app's icon, preferring an uploaded one over the
+// catalog's icon.png
+func (s *Server) handleAppIcon(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if validAppName.MatchString(name) {
+		if path := s.customIconPath(name); path != "" {
+			if filepath.Ext(path) == ".svg" {
+				// Never let an uploaded SVG run script when opened directly
+				w.Header().Set("Content-Type", "image/svg+xml")
+				w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+				w.Header().Set("X-Content-Type-Options", "nosniff")
+			}
+			// Custom icons can be replaced at any time
+			serveCachedFile(w, r, path, cacheRevalidate)
+			return
+		}
+	}
+
+	iconPath := filepath.Join(s.cfg.AppsDir, name, "icon.png")
+	if _, err := os.Stat(iconPath); os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+
+	serveCachedFile(w, r, iconPath, cacheCatalogIcon)
+}
+
+// handleSetAppIcon stores an uploaded PNG or SVG as the app's icon. The body is
+// the raw image; its type comes from Content-Type and must match the content.
+func (s *Server) handleSetAppIcon(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !validAppName.MatchString(name) {
+		respondError(w, http.StatusBadRequest, "invalid app name")
+		return
+	}
+	if _, err := s.catalog.Get(name); err != nil {
+		respondError(w, http.StatusNotFound, "app not found")
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	ext, ok := iconExtensions[contentType]
+	if !ok {
+		respondError(w, http.StatusUnsupportedMediaType, "icon must be image/png or image/svg+xml")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIconSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("icon exceeds %d bytes", maxIconSize))
+			return
+		}
+		respondError(w, http.StatusBadRequest, "failed to read icon")
+		return
+	}
+	if err := validateIcon(ext, data); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := os.MkdirAll(s.customIconDir(), 0755); err != nil {
+		s.logger.Error("failed to create icon directory", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to save icon")
+		return
+	}
+
+	// Write then rename so a reader never sees a partial file, and drop the
+	// other format so the new icon wins
+	path := filepath.Join(s.customIconDir(), name+ext)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		s.logger.Error("failed to write icon", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to save icon")
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		s.logger.Error("failed to write icon", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to save icon")
+		return
+	}
+	for _, other := range iconExtensions {
+		if other != ext {
+			os.Remove(filepath.Join(s.customIconDir(), name+other))
+		}
+	}
+
+	s.logger.Info("custom icon uploaded", "app", name, "format", ext, "bytes", len(data))
+	respondJSON(w, http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+// handleDeleteAppIcon removes an uploaded icon, restoring the catalog icon
+func (s *Server) handleDeleteAppIcon(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !validAppName.MatchString(name) {
+		respondError(w, http.StatusBadRequest, "invalid app name")
+		return
+	}
+
+	path := s.customIconPath(name)
+	if path == "" {
+		respondError(w, http.StatusNotFound, "app has no custom icon")
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		s.logger.Error("failed to remove icon", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to remove icon")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, StatusResponse{Status: "ok"})
+}
+
+// validateIcon checks that the content matches the declared format
+func validateIcon(ext string, data []byte) error {
+	switch ext {
+	case ".png":
+		if !bytes.HasPrefix(data, pngSignature) {
+			return errors.New("body is not a PNG image")
+		}
+	case ".svg":
+		head := data
+		if len(head) > 4096 {
+			head = head[:4096]
+		}
+		if !bytes.Contains(head, []byte("<svg")) {
+			return errors.New("body is not an SVG image")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+// maxListLimit caps the page size of list endpoints
+const maxListLimit = 500
+
+// page is the limit/offset window of a list request. Limit 0 means no limit.
+type page struct {
+	Limit  int
+	Offset int
+}
+
+// parsePage reads ?limit= and ?offset=
+func parsePage(q url.Values) (page, error) {
+	var p page
+	for param, dst := range map[string]*int{"limit": &p.Limit, "offset": &p.Offset} {
+		v := q.Get(param)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("%s must be a non-negative integer", param)
+		}
+		*dst = n
+	}
+	if p.Limit > maxListLimit {
+		p.Limit = maxListLimit
+	}
+	return p, nil
+}
+
+// paginate returns the window of items selected by p
+func paginate[T any](items []T, p page) []T {
+	if p.Offset >= len(items) {
+		return items[:0]
+	}
+	items = items[p.Offset:]
+	if p.Limit > 0 && p.Limit < len(items) {
+		items = items[:p.Limit]
+	}
+	return items
+}
+
+// parseSort reads ?sort=key or ?sort=-key (descending)
+func parseSort(q url.Values) (key string, desc bool) {
+	key = q.Get("sort")
+	if strings.HasPrefix(key, "-") {
+		return key[1:], true
+	}
+	return key, false
+}
+
+// parseBool reads an optional true/false query parameter
+func parseBool(q url.Values, param string) (*bool, error) {
+	v := q.Get(param)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", param)
+	}
+	return &b, nil
+}
+
+// parseAppQuery reads the catalog listing parameters:
+// category, tag, q, installed, sort, limit and offset
+func parseAppQuery(r *http.Request) (catalog.AppQuery, page, error) {
+	q := r.URL.Query()
+
+	installed, err := parseBool(q, "installed")
+	if err != nil {
+		return catalog.AppQuery{}, page{}, err
+	}
+	query := catalog.AppQuery{
+		Category:  q.Get("category"),
+		Tag:       q.Get("tag"),
+		Search:    q.Get("q"),
+		Installed: installed,
+	}
+	query.Sort, query.Desc = parseSort(q)
+	if err := query.Validate(); err != nil {
+		return query, page{}, err
+	}
+
+	p, err := parsePage(q)
+	return query, p, err
+}
+
+// parseInstalledAppQuery reads the installed listing parameters:
+// status, system, q, sort, limit and offset
+func parseInstalledAppQuery(r *http.Request) (store.InstalledAppQuery, page, error) {
+	q := r.URL.Query()
+
+	system, err := parseBool(q, "system")
+	if err != nil {
+		return store.InstalledAppQuery{}, page{}, err
+	}
+	query := store.InstalledAppQuery{
+		Status: q.Get("status"),
+		Search: q.Get("q"),
+		System: system,
+	}
+	query.Sort, query.Desc = parseSort(q)
+	if err := query.Validate(); err != nil {
+		return query, page{}, err
+	}
+
+	p, err := parsePage(q)
+	return query, p, err
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/logship"
+)
+
+// bundledLokiApp is the catalog app a loki sink without a URL ships to
+const bundledLokiApp = "loki"
+
+// StartLogShipper forwards the journal to the configured sink, if enabled
+func (s *Server) StartLogShipper(ctx context.Context) {
+	if s.logShipper == nil {
+		return
+	}
+	s.logShipper.Start(ctx)
+}
+
+// handleGetLogging returns the log shipping configuration, with the
+// password redacted, and how shipping is going
+func (s *Server) handleGetLogging(w http.ResponseWriter, r *http.Request) {
+	if s.logShipper == nil {
+		respondError(w, http.StatusServiceUnavailable, "log shipping not available")
+		return
+	}
+	respondJSON(w, http.StatusOK, s.loggingResponse())
+}
+
+// handleSetLogging replaces the log shipping configuration. A password sent
+// back redacted keeps its stored value.
+func (s *Server) handleSetLogging(w http.ResponseWriter, r *http.Request) {
+	if s.logShipper == nil {
+		respondError(w, http.StatusServiceUnavailable, "log shipping not available")
+		return
+	}
+
+	var cfg logship.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	cfg.KeepSecrets(s.logShipper.Config())
+
+	if cfg.Enabled && cfg.Sink == logship.SinkLoki && cfg.URL == "" {
+		app, err := s.appStore.GetByName(bundledLokiApp)
+		if err != nil {
+			s.logger.Error("failed to get app", "app", bundledLokiApp, "error", err)
+			respondError(w, http.StatusInternalServerError, "failed to get app")
+			return
+		}
+		if app == nil || app.UninstalledAt != nil {
+			respondError(w, http.StatusBadRequest, "install the Loki app or set url")
+			return
+		}
+	}
+
+	if err := s.logShipper.SetConfig(cfg); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.logger.Info("updated log shipping", "enabled", cfg.Enabled, "sink", cfg.Sink)
+	respondJSON(w, http.StatusOK, s.loggingResponse())
+}
+
+func (s *Server) loggingResponse() LoggingResponse {
+	return LoggingResponse{
+		Config: s.logShipper.Config().Redact(),
+		Status: s.logShipper.Status(),
+	}
+}
@@ -33,6 +33,7 @@ func (s *Server) handleAppLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.logger.Info("SSE client connected for app logs", "app", name)
+	defer trackSSE("app-logs")()
 
 	// Start journalctl with context for cleanup on client disconnect
 	ctx := r.Context()
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// metricsMiddleware records request latency labelled by chi route pattern.
+// SSE streams and WebSockets are skipped: their duration is the connection
+// lifetime, which would swamp the histogram. They are counted by trackSSE instead.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		if strings.HasPrefix(ww.Header().Get("Content-Type"), "text/event-stream") || ww.Status() == http.StatusSwitchingProtocols {
+			return
+		}
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.HTTPRequestDuration.
+			WithLabelValues(r.Method, route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+// trackSSE counts a connected SSE client until the returned func is called
+func trackSSE(stream string) func() {
+	gauge := metrics.SSESubscribers.WithLabelValues(stream)
+	gauge.Inc()
+	return gauge.Dec
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
+)
+
+// handleNetwork returns per-interface throughput and the apps using the most
+// network, to tell a slow uplink apart from an app saturating it
+func (s *Server) handleNetwork(w http.ResponseWriter, r *http.Request) {
+	stats := system.GetNetworkStats()
+	resp := NetworkResponse{
+		Interfaces:  stats.Interfaces,
+		Connections: stats.Connections,
+		Apps:        []AppStatsResponse{},
+	}
+
+	// Per-app usage is optional here; the host numbers stand on their own
+	if usage, err := s.latestAppUsage(r.Context()); err == nil {
+		resp.Apps = rankAppUsage(usage, topAppsSorts["network"], defaultTopAppsLimit)
+	} else if !errors.Is(err, errAppStatsUnavailable) {
+		s.logger.Warn("failed to get app usage", "error", err)
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/notify"
+)
+
+// handleGetNotifications returns the notification channels with credentials redacted
+func (s *Server) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	if s.notifier == nil {
+		respondError(w, http.StatusServiceUnavailable, "notifications not available")
+		return
+	}
+	respondJSON(w, http.StatusOK, s.notifier.Config().Redact())
+}
+
+// handleSetNotifications replaces the notification channels. Credentials sent
+// back redacted keep their stored values.
+func (s *Server) handleSetNotifications(w http.ResponseWriter, r *http.Request) {
+	if s.notifier == nil {
+		respondError(w, http.StatusServiceUnavailable, "notifications not available")
+		return
+	}
+
+	var cfg notify.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	cfg.KeepSecrets(s.notifier.Config())
+	if err := s.notifier.SetConfig(cfg); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.logger.Info("updated notification channels", "count", len(cfg.Channels))
+	respondJSON(w, http.StatusOK, s.notifier.Config().Redact())
+}
+
+// handleTestNotifications sends a test notification to ?channel=<id>, or to
+// every enabled channel
+func (s *Server) handleTestNotifications(w http.ResponseWriter, r *http.Request) {
+	if s.notifier == nil {
+		respondError(w, http.StatusServiceUnavailable, "notifications not available")
+		return
+	}
+
+	results, err := s.notifier.Test(r.Context(), r.URL.Query().Get("channel"))
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	resp := NotificationTestResponse{Results: []NotificationTestResult{}}
+	for _, ch := range s.notifier.Config().Channels {
+		err, tested := results[ch.ID]
+		if !tested {
+			continue
+		}
+		result := NotificationTestResult{ID: ch.ID, Name: ch.Name, Success: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,435 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/alerts"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/bundle"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/logship"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/notify"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/orchestrator"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/powersched"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/scheduler"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/watchdog"
+)
+
+const openAPIVersion = "1.0.0"
+
+// apiOperation documents a single route for the OpenAPI document.
+// Every route registered in setupRoutes must have an entry here; a test walks
+// the router to enforce it.
+type apiOperation struct {
+	Method      string
+	Path        string // path template, e.g. /api/apps/{name}/install; documented under /api/v1
+	OperationID string
+	Summary     string
+	Tag         string
+	Public      bool     // served without a session
+	BrowserOnly bool     // redirect-based flow, not useful to API clients
+	WebSocket   bool     // upgrades to a WebSocket; not generated into the HTTP client
+	Admin       bool     // requires the admin role
+	Query       []string // optional string query parameters
+	Status      int      // success status code (defaults to 200)
+	Request     any      // zero value of the JSON request body type, nil if none
+	Upload      []string // media types accepted as a raw (non-JSON) request body
+	Response    any      // zero value of the JSON response body type, nil if none
+	ContentType string   // response media type when the body is not JSON
+}
+
+// apiOperations lists every documented route, in the order they appear in the spec
+var apiOperations = []apiOperation{
+	// Auth
+	{Method: "GET", Path: "/auth/login", OperationID: "login", Summary: "Start the OIDC login flow", Tag: "auth", Public: true, BrowserOnly: true, Status: http.StatusFound},
+	{Method: "GET", Path: "/auth/callback", OperationID: "authCallback", Summary: "Complete the OIDC login flow", Tag: "auth", Public: true, BrowserOnly: true, Status: http.StatusFound},
+	{Method: "POST", Path: "/auth/logout", OperationID: "logout", Summary: "End the current session", Tag: "auth", Public: true, BrowserOnly: true, Status: http.StatusFound},
+	{Method: "GET", Path: "/api/auth/me", OperationID: "getCurrentUser", Summary: "Get the authenticated user", Tag: "auth", Public: true, Response: CurrentUserResponse{}},
+	{Method: "GET", Path: "/api/auth/tokens", OperationID: "listTokens", Summary: "List your personal access tokens", Tag: "auth", Response: TokenListResponse{}},
+	{Method: "POST", Path: "/api/auth/tokens", OperationID: "createToken", Summary: "Create a personal access token", Tag: "auth", Status: http.StatusCreated, Request: CreateTokenRequest{}, Response: CreateTokenResponse{}},
+	{Method: "DELETE", Path: "/api/auth/tokens/{id}", OperationID: "deleteToken", Summary: "Revoke a personal access token", Tag: "auth", Response: StatusResponse{}},
+	{Method: "DELETE", Path: "/api/users/{username}/tokens", OperationID: "revokeUserTokens", Summary: "Revoke all of a user's personal access tokens", Tag: "auth", Admin: true, Response: RevokeTokensResponse{}},
+
+	// Meta
+	{Method: "GET", Path: "/api/health", OperationID: "health", Summary: "Health check", Tag: "meta", Public: true, Response: StatusResponse{}},
+	{Method: "GET", Path: "/metrics", OperationID: "getMetrics", Summary: "Prometheus metrics", Tag: "meta", Public: true, ContentType: "text/plain"},
+	{Method: "GET", Path: "/metrics/apps", OperationID: "getAppMetrics", Summary: "Prometheus metrics for installed apps and the host", Tag: "meta", Public: true, ContentType: "text/plain"},
+	{Method: "GET", Path: "/api/openapi.json", OperationID: "getOpenAPISpec", Summary: "Get this OpenAPI document", Tag: "meta", Public: true, ContentType: "application/json"},
+
+	// Setup
+	{Method: "GET", Path: "/api/setup/status", OperationID: "getSetupStatus", Summary: "Check whether initial setup is required", Tag: "setup", Public: true, Response: SetupStatusResponse{}},
+	{Method: "POST", Path: "/api/setup/create-user", OperationID: "createUser", Summary: "Create the first admin user", Tag: "setup", Public: true, Request: CreateUserRequest{}, Response: CreateUserResponse{}},
+
+	// Events
+	{Method: "GET", Path: "/api/events", OperationID: "streamEvents", Summary: "Subscribe to typed events by topic (WebSocket)", Tag: "events", WebSocket: true, Status: http.StatusSwitchingProtocols, Query: []string{"topics"}},
+
+	// Apps
+	{Method: "GET", Path: "/api/apps", OperationID: "listApps", Summary: "List catalog apps", Tag: "apps", Query: []string{"category", "tag", "q", "installed", "sort", "limit", "offset"}, Response: AppListResponse{}},
+	{Method: "GET", Path: "/api/apps/search", OperationID: "searchApps", Summary: "Search the catalog, ranked by relevance", Tag: "apps", Query: []string{"q", "limit", "offset"}, Response: AppSearchResponse{}},
+	{Method: "GET", Path: "/api/apps/installed", OperationID: "listInstalledApps", Summary: "List installed apps", Tag: "apps", Query: []string{"status", "system", "q", "sort", "limit", "offset"}, Response: []*store.InstalledApp{}},
+	{Method: "GET", Path: "/api/apps/uninstalled", OperationID: "listUninstalledApps", Summary: "List previously installed apps and their saved configuration", Tag: "apps", Response: []*store.InstalledApp{}},
+	{Method: "GET", Path: "/api/apps/updates", OperationID: "listAppUpdates", Summary: "List the latest image update check of each installed app", Tag: "apps", Response: AppUpdatesResponse{}},
+	{Method: "GET", Path: "/api/apps/events", OperationID: "streamAppEvents", Summary: "Stream installed app state (SSE)", Tag: "apps", ContentType: "text/event-stream"},
+	{Method: "POST", Path: "/api/apps/refresh-catalog", OperationID: "refreshCatalog", Summary: "Reload the app catalog", Tag: "apps", Admin: true, Response: StatusResponse{}},
+	{Method: "GET", Path: "/api/apps/{name}/plan-install", OperationID: "planInstall", Summary: "Preview what installing an app will do", Tag: "apps", Response: catalog.InstallPlan{}},
+	{Method: "GET", Path: "/api/apps/{name}/plan-remove", OperationID: "planRemove", Summary: "Preview what removing an app will do", Tag: "apps", Response: catalog.RemovePlan{}},
+	{Method: "GET", Path: "/api/apps/{name}/metadata", OperationID: "getAppMetadata", Summary: "Get catalog metadata for an app", Tag: "apps", Response: catalog.App{}},
+	{Method: "POST", Path: "/api/apps/{name}/install", OperationID: "installApp", Summary: "Install an app", Tag: "apps", Admin: true, Request: InstallAppRequest{}, Response: orchestrator.InstallResult{}},
+	{Method: "POST", Path: "/api/apps/{name}/uninstall", OperationID: "uninstallApp", Summary: "Uninstall an app", Tag: "apps", Admin: true, Request: UninstallAppRequest{}, Response: orchestrator.UninstallResult{}},
+	{Method: "POST", Path: "/api/apps/{name}/clear-data", OperationID: "clearAppData", Summary: "Uninstall an app and delete its data", Tag: "apps", Admin: true, Response: ClearDataResponse{}},
+	{Method: "PATCH", Path: "/api/apps/{name}/rename", OperationID: "renameApp", Summary: "Change an app's display name", Tag: "apps", Admin: true, Request: RenameAppRequest{}, Response: RenameAppResponse{}},
+	{Method: "GET", Path: "/api/apps/{name}/logs", OperationID: "streamAppLogs", Summary: "Stream app logs (SSE)", Tag: "apps", ContentType: "text/event-stream"},
+	{Method: "GET", Path: "/api/apps/{name}/exec", OperationID: "execApp", Summary: "Open a shell in the app container (WebSocket)", Tag: "apps", Admin: true, WebSocket: true, Status: http.StatusSwitchingProtocols, Query: []string{"shell"}},
+	{Method: "GET", Path: "/api/apps/{name}/icon", OperationID: "getAppIcon", Summary: "Get an app's icon", Tag: "apps", ContentType: "image/png"},
+	{Method: "PUT", Path: "/api/apps/{name}/icon", OperationID: "setAppIcon", Summary: "Upload a custom icon for an app", Tag: "apps", Admin: true, Upload: []string{"image/png", "image/svg+xml"}, Response: StatusResponse{}},
+	{Method: "DELETE", Path: "/api/apps/{name}/icon", OperationID: "deleteAppIcon", Summary: "Remove an app's custom icon", Tag: "apps", Admin: true, Response: StatusResponse{}},
+	{Method: "GET", Path: "/api/apps/{name}/history", OperationID: "getAppHistory", Summary: "List an app's status transitions", Tag: "apps", Query: []string{"since", "limit"}, Response: AppHistoryResponse{}},
+	{Method: "GET", Path: "/api/apps/{name}/uptime", OperationID: "getAppUptime", Summary: "Get an app's uptime and downtime incidents over 30 or 90 days", Tag: "apps", Query: []string{"days"}, Response: AppUptimeResponse{}},
+	{Method: "GET", Path: "/api/apps/{name}/stats", OperationID: "getAppStats", Summary: "Get an app's current CPU, memory and network usage", Tag: "apps", Response: AppStatsResponse{}},
+	{Method: "GET", Path: "/api/apps/{name}/settings", OperationID: "getAppSettings", Summary: "Get an app's settings schema and values", Tag: "apps", Response: AppSettingsResponse{}},
+	{Method: "PUT", Path: "/api/apps/{name}/settings", OperationID: "setAppSettings", Summary: "Update an app's settings", Tag: "apps", Admin: true, Request: map[string]any{}, Response: AppSettingsResponse{}},
+	{Method: "GET", Path: "/api/apps/{name}/secrets", OperationID: "listAppSecrets", Summary: "List an app's secrets by key and fingerprint", Tag: "apps", Admin: true, Response: AppSecretsResponse{}},
+	{Method: "PUT", Path: "/api/apps/{name}/secrets/{key}", OperationID: "setAppSecret", Summary: "Store a secret for an app", Tag: "apps", Admin: true, Request: SetAppSecretRequest{}, Response: AppSecretInfo{}},
+	{Method: "DELETE", Path: "/api/apps/{name}/secrets/{key}", OperationID: "deleteAppSecret", Summary: "Delete a secret stored for an app", Tag: "apps", Admin: true, Response: StatusResponse{}},
+
+	// System
+	{Method: "POST", Path: "/api/system/rollback", OperationID: "rollback", Summary: "Roll back to the previous NixOS generation", Tag: "system", Admin: true, Response: RollbackResponse{}},
+	{Method: "GET", Path: "/api/system/status", OperationID: "getSystemStatus", Summary: "Get CPU, memory and disk usage", Tag: "system", Response: system.Stats{}},
+	{Method: "GET", Path: "/api/system/stats/history", OperationID: "getStatsHistory", Summary: "Get CPU, memory and disk usage over time", Tag: "system", Query: []string{"range", "resolution", "app"}, Response: StatsHistoryResponse{}},
+	{Method: "GET", Path: "/api/system/stats/apps", OperationID: "getTopApps", Summary: "List the apps using the most CPU, memory or network", Tag: "system", Query: []string{"sort", "limit"}, Response: TopAppsResponse{}},
+	{Method: "GET", Path: "/api/system/network", OperationID: "getNetwork", Summary: "Get per-interface throughput, TCP connections and the apps using the most network", Tag: "system", Response: NetworkResponse{}},
+	{Method: "GET", Path: "/api/system/health/summary", OperationID: "getHealthSummary", Summary: "Get the overall health of the host agent, its dependencies and installed apps", Tag: "system", Response: HealthSummaryResponse{}},
+	{Method: "GET", Path: "/api/system/status/stream", OperationID: "streamSystemStatus", Summary: "Stream system usage (SSE)", Tag: "system", ContentType: "text/event-stream"},
+	{Method: "GET", Path: "/api/system/storage", OperationID: "getStorage", Summary: "Get storage usage", Tag: "system", Response: system.StorageStats{}},
+	{Method: "GET", Path: "/api/system/storage/breakdown", OperationID: "getStorageBreakdown", Summary: "Get disk usage by category (media, downloads, app data, backups, Nix store) from the last storage scan", Tag: "system", Response: system.StorageBreakdown{}},
+	{Method: "POST", Path: "/api/system/storage/breakdown/refresh", OperationID: "refreshStorageBreakdown", Summary: "Start a new storage scan", Tag: "system", Admin: true, Status: http.StatusAccepted, Response: StatusResponse{}},
+	{Method: "GET", Path: "/api/system/versions", OperationID: "listGenerations", Summary: "List NixOS generations", Tag: "system", Admin: true, Response: GenerationsResponse{}},
+	{Method: "GET", Path: "/api/system/audit", OperationID: "listAuditLog", Summary: "List audited state-changing requests", Tag: "system", Admin: true, Query: []string{"user", "method", "path", "result", "since", "until", "limit"}, Response: AuditLogResponse{}},
+	{Method: "GET", Path: "/api/system/export", OperationID: "exportConfig", Summary: "Export a signed bundle of the system configuration", Tag: "system", Admin: true, Response: bundle.Bundle{}},
+	{Method: "POST", Path: "/api/system/diagnostics", OperationID: "createDiagnosticsBundle", Summary: "Download a support bundle of recent journals, app statuses, apps.nix, Traefik config, versions and the last rebuild output, with secrets scrubbed", Tag: "system", Admin: true, ContentType: "application/gzip"},
+	{Method: "POST", Path: "/api/system/import", OperationID: "importConfig", Summary: "Replay a configuration bundle through the orchestrator", Tag: "system", Admin: true, Query: []string{"dryRun"}, Status: http.StatusAccepted, Request: bundle.Bundle{}, Response: ImportConfigResponse{}},
+	{Method: "POST", Path: "/api/system/reboot", OperationID: "reboot", Summary: "Reboot the host (call twice: the first call returns a confirmation token)", Tag: "system", Admin: true, Status: http.StatusAccepted, Request: PowerActionRequest{}, Response: PowerActionResponse{}},
+	{Method: "POST", Path: "/api/system/shutdown", OperationID: "shutdown", Summary: "Power off the host (call twice: the first call returns a confirmation token)", Tag: "system", Admin: true, Status: http.StatusAccepted, Request: PowerActionRequest{}, Response: PowerActionResponse{}},
+	{Method: "GET", Path: "/api/system/power", OperationID: "getPowerSchedule", Summary: "Get the power schedule and its next and last sleep", Tag: "system", Admin: true, Response: PowerScheduleResponse{}},
+	{Method: "PUT", Path: "/api/system/power", OperationID: "setPowerSchedule", Summary: "Replace the windows the host suspends or powers off through", Tag: "system", Admin: true, Request: powersched.Config{}, Response: PowerScheduleResponse{}},
+	{Method: "GET", Path: "/api/system/notifications", OperationID: "getNotifications", Summary: "Get notification channels (credentials redacted)", Tag: "system", Admin: true, Response: notify.Config{}},
+	{Method: "PUT", Path: "/api/system/notifications", OperationID: "setNotifications", Summary: "Replace notification channels", Tag: "system", Admin: true, Request: notify.Config{}, Response: notify.Config{}},
+	{Method: "POST", Path: "/api/system/notifications/test", OperationID: "testNotifications", Summary: "Send a test notification", Tag: "system", Admin: true, Query: []string{"channel"}, Response: NotificationTestResponse{}},
+	{Method: "GET", Path: "/api/system/alerts", OperationID: "getAlerts", Summary: "Get alert rules and the alerts currently firing", Tag: "system", Admin: true, Response: AlertsResponse{}},
+	{Method: "PUT", Path: "/api/system/alerts", OperationID: "setAlerts", Summary: "Replace alert rules", Tag: "system", Admin: true, Request: alerts.Config{}, Response: AlertsResponse{}},
+	{Method: "GET", Path: "/api/system/metrics/scrape-config", OperationID: "getScrapeConfig", Summary: "Get a Prometheus scrape config for the host agent's metrics", Tag: "system", Admin: true, ContentType: "application/yaml"},
+	{Method: "GET", Path: "/api/system/logging", OperationID: "getLogging", Summary: "Get log shipping settings (password redacted) and status", Tag: "system", Admin: true, Response: LoggingResponse{}},
+	{Method: "PUT", Path: "/api/system/logging", OperationID: "setLogging", Summary: "Configure forwarding of host-agent and app logs to Loki or Vector", Tag: "system", Admin: true, Request: logship.Config{}, Response: LoggingResponse{}},
+	{Method: "GET", Path: "/api/system/updates", OperationID: "getUpdates", Summary: "Get the automatic update policy and the outcome of the last update", Tag: "system", Admin: true, Response: UpdatesResponse{}},
+	{Method: "PUT", Path: "/api/system/updates", OperationID: "setUpdates", Summary: "Replace the automatic update policy: mode (off, security-only or all), channel (stable or beta) and maintenance window", Tag: "system", Admin: true, Request: store.UpdatePolicy{}, Response: UpdatesResponse{}},
+	{Method: "POST", Path: "/api/system/updates/rollback", OperationID: "rollbackUpdate", Summary: "Switch back to the system the last applied update replaced", Tag: "system", Admin: true, Response: UpdatesResponse{}},
+	{Method: "POST", Path: "/api/system/updates/upload", OperationID: "uploadUpdate", Summary: "Update from a signed offline bundle", Tag: "system", Admin: true, Upload: []string{"application/x-tar", "application/gzip"}, Status: http.StatusAccepted, Response: UpdatesResponse{}},
+	{Method: "GET", Path: "/api/system/watchdog", OperationID: "getWatchdog", Summary: "Get the app watchdog settings", Tag: "system", Admin: true, Response: watchdog.Config{}},
+	{Method: "PUT", Path: "/api/system/watchdog", OperationID: "setWatchdog", Summary: "Replace the app watchdog settings: health check interval, failure threshold, stuck-state timeouts, grace periods and excluded apps", Tag: "system", Admin: true, Request: watchdog.Config{}, Response: watchdog.Config{}},
+	{Method: "GET", Path: "/api/system/schedules", OperationID: "listSchedules", Summary: "List scheduled jobs with their last and next runs", Tag: "system", Admin: true, Response: SchedulesResponse{}},
+	{Method: "PUT", Path: "/api/system/schedules/{name}", OperationID: "updateSchedule", Summary: "Change a scheduled job's cron schedule or turn it on or off", Tag: "system", Admin: true, Request: scheduler.Update{}, Response: scheduler.Status{}},
+	{Method: "POST", Path: "/api/system/schedules/{name}/run", OperationID: "runSchedule", Summary: "Run a scheduled job now", Tag: "system", Admin: true, Status: http.StatusAccepted, Response: StatusResponse{}},
+	{Method: "GET", Path: "/api/system/rebuild/stream", OperationID: "streamRebuild", Summary: "Stream NixOS rebuild events (SSE)", Tag: "system", Admin: true, ContentType: "text/event-stream"},
+
+	// Provisioning
+	{Method: "POST", Path: "/api/provisioning/webhook", OperationID: "provisioningWebhook", Summary: "Receive an Authentik notification webhook", Tag: "provisioning", Public: true, Status: http.StatusAccepted},
+	{Method: "GET", Path: "/api/system/database/backups", OperationID: "listDatabaseBackups", Summary: "List host-agent database backups", Tag: "system", Admin: true, Response: DatabaseBackupsResponse{}},
+	{Method: "POST", Path: "/api/system/database/maintenance", OperationID: "runDatabaseMaintenance", Summary: "Check, vacuum and back up the host-agent database now", Tag: "system", Admin: true, Response: db.MaintenanceReport{}},
+	{Method: "POST", Path: "/api/system/database/backups/{name}/restore", OperationID: "restoreDatabaseBackup", Summary: "Restore the host-agent database from a backup", Tag: "system", Admin: true, Response: RestoreBackupResponse{}},
+	{Method: "GET", Path: "/api/system/db/check", OperationID: "checkDatabase", Summary: "Check apps, integrations and secrets for inconsistencies", Tag: "system", Admin: true, Response: DatabaseCheckResponse{}},
+	{Method: "POST", Path: "/api/system/db/repair", OperationID: "repairDatabase", Summary: "Check for inconsistencies and fix the repairable ones", Tag: "system", Admin: true, Response: DatabaseCheckResponse{}},
+	{Method: "GET", Path: "/api/system/secrets/rotation", OperationID: "getSecretRotation", Summary: "Get the per-app secret key version and any rotation in progress", Tag: "system", Admin: true, Response: SecretRotationResponse{}},
+	{Method: "GET", Path: "/api/system/secrets/access", OperationID: "listSecretAccess", Summary: "List secret reads and writes, newest first", Tag: "system", Admin: true, Query: []string{"component", "action", "app", "secret", "since", "until", "limit"}, Response: SecretAccessLogResponse{}},
+	{Method: "POST", Path: "/api/system/secrets/rotate", OperationID: "rotateSecrets", Summary: "Rotate per-app OAuth client secrets to a new key version, or resume an interrupted rotation", Tag: "system", Admin: true, Status: http.StatusAccepted, Response: SecretRotationResponse{}},
+	{Method: "POST", Path: "/api/system/provisioning/sync", OperationID: "syncProvisioning", Summary: "Sync Authentik users into apps", Tag: "provisioning", Admin: true, Status: http.StatusAccepted, Response: StatusResponse{}},
+
+	// User
+	{Method: "GET", Path: "/api/user/layout", OperationID: "getLayout", Summary: "Get the home screen layout", Tag: "user", Response: []store.GridElement{}},
+	{Method: "PUT", Path: "/api/user/layout", OperationID: "setLayout", Summary: "Save the home screen layout", Tag: "user", Request: []store.GridElement{}, Response: StatusResponse{}},
+	{Method: "GET", Path: "/api/users/me/preferences", OperationID: "getPreferences", Summary: "Get the current user's preferences", Tag: "user", Response: store.Preferences{}},
+	{Method: "PUT", Path: "/api/users/me/preferences", OperationID: "updatePreferences", Summary: "Update the current user's preferences", Tag: "user", Request: UpdatePreferencesRequest{}, Response: store.Preferences{}},
+}
+
+// OpenAPISpec returns the OpenAPI 3 document for the host-agent API as indented JSON
+func OpenAPISpec() ([]byte, error) {
+	return json.MarshalIndent(buildOpenAPISpec(apiOperations), "", "  ")
+}
+
+// handleOpenAPISpec serves the OpenAPI document
+func (s *Server) handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	spec, err := OpenAPISpec()
+	if err != nil {
+		s.logger.Error("failed to build OpenAPI spec", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to build OpenAPI spec")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// buildOpenAPISpec renders the operations table into an OpenAPI 3.0 document
+func buildOpenAPISpec(ops []apiOperation) map[string]any {
+	schemas := newSchemaBuilder()
+	paths := map[string]any{}
+
+	for _, op := range ops {
+		operation := map[string]any{
+			"operationId": op.OperationID,
+			"summary":     op.Summary,
+			"tags":        []string{op.Tag},
+		}
+		if op.BrowserOnly {
+			operation["x-browser-only"] = true
+		}
+		if op.WebSocket {
+			operation["x-websocket"] = true
+		}
+		if op.Admin {
+			operation["x-admin-only"] = true
+		}
+		if op.Public {
+			operation["security"] = []any{}
+		}
+
+		var params []any
+		for _, m := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+			params = append(params, map[string]any{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		for _, q := range op.Query {
+			params = append(params, map[string]any{
+				"name":   q,
+				"in":     "query",
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemas.schemaFor(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+		if len(op.Upload) > 0 {
+			content := map[string]any{}
+			for _, mediaType := range op.Upload {
+				content[mediaType] = map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}
+			}
+			operation["requestBody"] = map[string]any{"required": true, "content": content}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		switch {
+		case op.Response != nil:
+			success["content"] = map[string]any{
+				"application/json": map[string]any{"schema": schemas.schemaFor(reflect.TypeOf(op.Response))},
+			}
+		case op.ContentType != "":
+			success["content"] = map[string]any{op.ContentType: map[string]any{}}
+		}
+
+		responses := map[string]any{
+			strconv.Itoa(status): success,
+			"default": map[string]any{
+				"description": "Error",
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemas.schemaFor(reflect.TypeOf(ErrorResponse{}))},
+				},
+			},
+		}
+		operation["responses"] = responses
+
+		path := versionedPath(op.Path)
+		item, _ := paths[path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Bloud Host Agent API",
+			"version": openAPIVersion,
+		},
+		"security": []any{
+			map[string]any{"sessionCookie": []string{}},
+			map[string]any{"bearerToken": []string{}},
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"sessionCookie": map[string]any{
+					"type": "apiKey",
+					"in":   "cookie",
+					"name": sessionCookieName,
+				},
+				"bearerToken": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "Personal access token from POST /api/v1/auth/tokens",
+				},
+			},
+		},
+	}
+}
+
+// versionedPath returns the /api/v1 form of an API path. The unversioned
+// /api routes are a compatibility shim and are not documented separately.
+func versionedPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "/api/"); ok {
+		return "/api/v" + currentAPIVersion + "/" + rest
+	}
+	return path
+}
+
+// schemaBuilder converts Go types into JSON schemas, registering named
+// struct types as reusable components
+type schemaBuilder struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		components: map[string]any{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns an inline schema or a $ref for t
+func (b *schemaBuilder) schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + b.register(t)}
+	default:
+		// interface{} and anything else: any JSON value
+		return map[string]any{}
+	}
+}
+
+// register adds a named struct to the components, returning its component name
+func (b *schemaBuilder) register(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := b.components[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	// Reserve the name before recursing so self-referencing types terminate
+	b.names[t] = name
+	b.components[name] = nil
+	b.components[name] = b.structSchema(t)
+	return name
+}
+
+// structSchema builds an object schema from exported, JSON-visible fields
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+
+			if f.Anonymous && name == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					addFields(ft)
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			properties[name] = b.schemaFor(f.Type)
+			if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
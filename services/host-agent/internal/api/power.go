@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/orchestrator"
+)
+
+// Power actions
+const (
+	PowerReboot   = "reboot"
+	PowerShutdown = "shutdown"
+)
+
+// powerConfirmTTL is how long a confirmation token stays valid
+const powerConfirmTTL = 2 * time.Minute
+
+// powerDelay gives the response time to reach the client before going down
+var powerDelay = 2 * time.Second
+
+// powerConfirmations holds the outstanding confirmation tokens. A token is
+// bound to one action and one user and can be used once.
+type powerConfirmations struct {
+	mu         sync.Mutex
+	tokens     map[string]powerConfirmation
+	inProgress bool
+}
+
+type powerConfirmation struct {
+	action    string
+	user      string
+	expiresAt time.Time
+}
+
+func (p *powerConfirmations) issue(action, user string) (string, time.Time) {
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	expiresAt := time.Now().Add(powerConfirmTTL)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tokens == nil {
+		p.tokens = make(map[string]powerConfirmation)
+	}
+	for t, c := range p.tokens {
+		if time.Now().After(c.expiresAt) {
+			delete(p.tokens, t)
+		}
+	}
+	p.tokens[token] = powerConfirmation{action: action, user: user, expiresAt: expiresAt}
+	return token, expiresAt
+}
+
+// begin marks a power action as started, unless one already is
+func (p *powerConfirmations) begin() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inProgress {
+		return fmt.Errorf("a power action is already in progress")
+	}
+	p.inProgress = true
+	return nil
+}
+
+// end clears a power action that failed to start
+func (p *powerConfirmations) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inProgress = false
+}
+
+// consume validates and spends a token, marking a power action as started.
+// It fails if the token is wrong or another action is already under way.
+func (p *powerConfirmations) consume(action, user, token string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.inProgress {
+		return fmt.Errorf("a power action is already in progress")
+	}
+	c, ok := p.tokens[token]
+	delete(p.tokens, token)
+	if !ok || c.action != action || c.user != user || time.Now().After(c.expiresAt) {
+		return fmt.Errorf("invalid or expired confirmation token")
+	}
+	p.inProgress = true
+	return nil
+}
+
+// handleReboot reboots the host
+func (s *Server) handleReboot(w http.ResponseWriter, r *http.Request) {
+	s.handlePowerAction(w, r, PowerReboot)
+}
+
+// handleShutdown powers off the host
+func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	s.handlePowerAction(w, r, PowerShutdown)
+}
+
+// handlePowerAction is a two-step reboot/shutdown. A request without a
+// confirmation token gets one back; repeating the request with it starts the
+// action. This keeps a stray click or replayed request from powering off a
+// headless box.
+func (s *Server) handlePowerAction(w http.ResponseWriter, r *http.Request, action string) {
+	var req PowerActionRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	user := auditLocalUser
+	if u := getUserFromContext(r.Context()); u != nil {
+		user = u.Username
+	}
+
+	if req.Confirm == "" {
+		token, expiresAt := s.power.issue(action, user)
+		respondJSON(w, http.StatusOK, PowerActionResponse{
+			Action:       action,
+			Status:       "confirmation-required",
+			ConfirmToken: token,
+			ExpiresAt:    &expiresAt,
+		})
+		return
+	}
+
+	if err := s.power.consume(action, user, req.Confirm); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	s.logger.Warn("power action requested", "action", action, "user", user)
+	respondJSON(w, http.StatusAccepted, PowerActionResponse{Action: action, Status: "accepted"})
+
+	go s.powerOff(action)
+}
+
+// powerOff finishes queued work, flushes state to disk and hands over to systemd
+func (s *Server) powerOff(action string) {
+	time.Sleep(powerDelay)
+
+	// Let the running install/uninstall batch finish; waiting ones are cancelled
+	if nixOrch, ok := s.orchestrator.(*orchestrator.Orchestrator); ok && nixOrch != nil {
+		s.logger.Info("draining operation queue before power action")
+		nixOrch.Stop()
+	}
+
+	if s.db != nil {
+		if err := s.db.Close(); err != nil {
+			s.logger.Warn("failed to close database", "error", err)
+		}
+	}
+	syscall.Sync()
+
+	run := s.powerCommand
+	if run == nil {
+		run = systemctlPower
+	}
+	if err := run(context.Background(), action); err != nil {
+		s.logger.Error("power action failed", "action", action, "error", err)
+	}
+}
+
+// systemctlPower runs systemctl reboot or poweroff
+func systemctlPower(ctx context.Context, action string) error {
+	verb := "reboot"
+	if action == PowerShutdown {
+		verb = "poweroff"
+	}
+	output, err := exec.CommandContext(ctx, "systemctl", verb).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s: %w: %s", verb, err, output)
+	}
+	return nil
+}
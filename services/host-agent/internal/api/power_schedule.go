@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/orchestrator"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/powersched"
+)
+
+// suspendTimeout is how long to wait for the host to go down after asking
+// systemd to suspend
+const suspendTimeout = 2 * time.Minute
+
+// StartPowerSchedule sleeps the host through the configured windows
+func (s *Server) StartPowerSchedule(ctx context.Context) {
+	if s.powerSchedule == nil {
+		return
+	}
+	s.powerSchedule.Start(ctx)
+}
+
+// healthChecksPaused reports whether health checks and alerts should be
+// skipped because the host is going to sleep or has just woken
+func (s *Server) healthChecksPaused(now time.Time) bool {
+	return s.sleeping.Load() || now.UnixNano() < s.healthGraceUntil.Load()
+}
+
+// sleepHost puts the host to sleep until wake with an RTC alarm. Around a
+// suspend the operation queue is paused, so no rebuild is cut off, and
+// health checks are paused, so the sleep isn't recorded as downtime.
+func (s *Server) sleepHost(ctx context.Context, mode powersched.Mode, wake time.Time) error {
+	if mode == powersched.ModePoweroff {
+		if err := s.power.begin(); err != nil {
+			return err
+		}
+		if err := setWakeAlarm(ctx, wake); err != nil {
+			s.power.end()
+			return err
+		}
+		s.powerOff(PowerShutdown)
+		return nil
+	}
+
+	s.sleeping.Store(true)
+	defer func() {
+		// Apps need a moment to reconnect to the network and each other
+		s.healthGraceUntil.Store(time.Now().Add(s.watchdogConfig().ResumeGrace()).UnixNano())
+		s.sleeping.Store(false)
+	}()
+
+	if nixOrch, ok := s.orchestrator.(*orchestrator.Orchestrator); ok && nixOrch != nil {
+		s.logger.Info("pausing operation queue for scheduled suspend")
+		nixOrch.PauseQueue()
+		defer nixOrch.ResumeQueue()
+	}
+
+	suspend := s.suspendCommand
+	if suspend == nil {
+		suspend = rtcSuspend
+	}
+	return suspend(ctx, wake)
+}
+
+// rtcSuspend sets the RTC alarm and suspends, returning once the host has
+// resumed
+func rtcSuspend(ctx context.Context, wake time.Time) error {
+	if err := setWakeAlarm(ctx, wake); err != nil {
+		return err
+	}
+	start := time.Now()
+	if output, err := exec.CommandContext(ctx, "systemctl", "suspend").CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl suspend: %w: %s", err, output)
+	}
+	return waitForResume(ctx, start)
+}
+
+// setWakeAlarm programs the RTC to wake the host at wake
+func setWakeAlarm(ctx context.Context, wake time.Time) error {
+	output, err := exec.CommandContext(ctx, "sudo", "-n", "env", "PATH="+nixgen.NixosSystemPath,
+		"rtcwake", "-m", "no", "-t", strconv.FormatInt(wake.Unix(), 10)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rtcwake: %w: %s", err, output)
+	}
+	return nil
+}
+
+// waitForResume returns once the host has been suspended and resumed. The
+// monotonic clock stops while suspended and the wall clock doesn't, so a gap
+// between them shows the host slept.
+func waitForResume(ctx context.Context, start time.Time) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			if now.Round(0).Sub(start.Round(0))-now.Sub(start) > 5*time.Second {
+				return nil
+			}
+			if now.Sub(start) > suspendTimeout {
+				return errors.New("host did not suspend")
+			}
+		}
+	}
+}
+
+// handleGetPowerSchedule returns the power schedule and its next and last
+// sleep
+func (s *Server) handleGetPowerSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.powerSchedule == nil {
+		respondError(w, http.StatusServiceUnavailable, "power schedule not available")
+		return
+	}
+	respondJSON(w, http.StatusOK, PowerScheduleResponse{
+		Config: s.powerSchedule.Config(),
+		Status: s.powerSchedule.Status(),
+	})
+}
+
+// handleSetPowerSchedule replaces the power schedule
+func (s *Server) handleSetPowerSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.powerSchedule == nil {
+		respondError(w, http.StatusServiceUnavailable, "power schedule not available")
+		return
+	}
+
+	var cfg powersched.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.powerSchedule.SetConfig(cfg); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to save power schedule", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to save power schedule")
+		return
+	}
+	respondJSON(w, http.StatusOK, PowerScheduleResponse{
+		Config: s.powerSchedule.Config(),
+		Status: s.powerSchedule.Status(),
+	})
+}
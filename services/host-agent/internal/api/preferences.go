@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+const (
+	// maxPreferencesBody bounds the size of a preferences update
+	maxPreferencesBody = 64 * 1024
+
+	// maxPinnedApps bounds how many apps a user can pin
+	maxPinnedApps = 50
+)
+
+// validLocale loosely matches a BCP 47 language tag such as "en" or "pt-BR"
+var validLocale = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// handleGetPreferences returns the authenticated user's preferences
+func (s *Server) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil {
+		respondError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+
+	prefs, err := s.preferencesStore.Get(user.ID)
+	if err != nil {
+		s.logger.Error("failed to get preferences", "user", user.Username, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get preferences")
+		return
+	}
+	if prefs == nil {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, prefs)
+}
+
+// handleUpdatePreferences updates the authenticated user's preferences.
+// Fields left out of the body keep their current values.
+func (s *Server) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil {
+		respondError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPreferencesBody)).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	prefs, err := s.preferencesStore.Get(user.ID)
+	if err != nil {
+		s.logger.Error("failed to get preferences", "user", user.Username, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get preferences")
+		return
+	}
+	if prefs == nil {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if req.Layout != nil {
+		prefs.Layout = *req.Layout
+	}
+	if req.PinnedApps != nil {
+		prefs.PinnedApps = *req.PinnedApps
+	}
+	if req.Theme != nil {
+		prefs.Theme = *req.Theme
+	}
+	if req.Locale != nil {
+		prefs.Locale = *req.Locale
+	}
+
+	if err := s.preferencesStore.Set(user.ID, prefs); err != nil {
+		s.logger.Error("failed to save preferences", "user", user.Username, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to save preferences")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, prefs)
+}
+
+// validate returns a message describing the first invalid field, or ""
+func (req *UpdatePreferencesRequest) validate() string {
+	if req.Layout != nil && *req.Layout == nil {
+		req.Layout = &[]store.GridElement{}
+	}
+	if req.PinnedApps != nil {
+		if *req.PinnedApps == nil {
+			req.PinnedApps = &[]string{}
+		}
+		if len(*req.PinnedApps) > maxPinnedApps {
+			return "too many pinned apps"
+		}
+		seen := make(map[string]bool)
+		for _, name := range *req.PinnedApps {
+			if !validAppName.MatchString(name) {
+				return "invalid app name in pinned_apps"
+			}
+			if seen[name] {
+				return "duplicate app in pinned_apps"
+			}
+			seen[name] = true
+		}
+	}
+	if req.Theme != nil {
+		switch *req.Theme {
+		case store.ThemeSystem, store.ThemeLight, store.ThemeDark:
+		default:
+			return "theme must be system, light or dark"
+		}
+	}
+	if req.Locale != nil && *req.Locale != "" && !validLocale.MatchString(*req.Locale) {
+		return "locale must be a language tag such as en-US"
+	}
+	return ""
+}
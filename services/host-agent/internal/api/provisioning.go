@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
+)
+
+// defaultProvisioningInterval is used when ServerConfig.ProvisioningInterval is unset
+const defaultProvisioningInterval = 5 * time.Minute
+
+// authentikUserSource resolves the Authentik client at sync time.
+// The client may only be created after startup, once the Authentik
+// configurator has written the API token (see tryInitAuth).
+type authentikUserSource struct {
+	s *Server
+}
+
+func (a authentikUserSource) ListUsers() ([]authentik.User, error) {
+	client := a.s.authentikClient
+	if client == nil {
+		return nil, fmt.Errorf("authentik client not available")
+	}
+	return client.ListUsers()
+}
+
+func (a authentikUserSource) FindUser(username string) (*authentik.User, error) {
+	client := a.s.authentikClient
+	if client == nil {
+		return nil, fmt.Errorf("authentik client not available")
+	}
+	return client.FindUser(username)
+}
+
+// StartProvisioning starts the background user provisioning loop.
+// It stops when ctx is cancelled. No-op if no provisioners are registered.
+func (s *Server) StartProvisioning(ctx context.Context) {
+	if s.provisioningSyncer == nil {
+		return
+	}
+
+	interval := s.cfg.ProvisioningInterval
+	if interval <= 0 {
+		interval = defaultProvisioningInterval
+	}
+
+	s.logger.Info("starting user provisioning sync", "interval", interval)
+	go s.provisioningSyncer.Run(ctx, interval)
+}
+
+// handleProvisioningSync triggers an immediate user provisioning sync
+func (s *Server) handleProvisioningSync(w http.ResponseWriter, r *http.Request) {
+	if s.provisioningSyncer == nil {
+		respondError(w, http.StatusServiceUnavailable, "user provisioning not configured")
+		return
+	}
+
+	s.provisioningSyncer.Trigger()
+
+	respondJSON(w, http.StatusAccepted, StatusResponse{Status: "sync triggered"})
+}
+
+// handleProvisioningWebhook receives Authentik notification webhooks for user changes.
+// The payload is not trusted or parsed: it only schedules a sync, which reads the
+// current user list from the Authentik API. Bursts of events coalesce into one sync.
+func (s *Server) handleProvisioningWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.provisioningSyncer == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.provisioningSyncer.Trigger()
+	w.WriteHeader(http.StatusAccepted)
+}
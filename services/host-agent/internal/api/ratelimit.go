@@ -0,0 +1,71 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/ratelimit"
+)
+
+// Rate limit policies
+var (
+	// authRateLimit covers login, OAuth callbacks, first-user setup and token creation
+	authRateLimit = ratelimit.PerMinute(10)
+	// expensiveRateLimit covers operations that rebuild or reconfigure the system
+	expensiveRateLimit = ratelimit.PerMinute(30)
+)
+
+// rateLimit limits requests per user when authenticated and per client IP
+// otherwise, setting RateLimit-* headers and answering 429 when exhausted.
+// Each policy name has its own buckets. Localhost (the CLI) is not limited.
+func (s *Server) rateLimit(policy string, limit ratelimit.Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.limiter == nil || isLocalRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := s.limiter.Allow(r.Context(), policy+":"+rateLimitKey(r), limit)
+			if err != nil {
+				s.logger.Warn("rate limiter error", "policy", policy, "error", err)
+				if res.Limit == 0 {
+					// No usable decision; fail open
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				respondError(w, http.StatusTooManyRequests, "rate limit exceeded, try again later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey identifies the client: the user when known, else the IP
+func rateLimitKey(r *http.Request) string {
+	if user := getUserFromContext(r.Context()); user != nil {
+		return "user:" + user.ID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
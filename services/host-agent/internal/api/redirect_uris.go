@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/netutil"
+)
+
+const (
+	// redirectURICleanupInterval is how often stale redirect URIs are pruned
+	redirectURICleanupInterval = time.Hour
+
+	// lazyRedirectURIRetention keeps lazily registered redirect URIs (mDNS, Tailscale,
+	// custom DNS) for this long after their last login, so a host that is still in
+	// use isn't pruned out from under an in-flight login.
+	lazyRedirectURIRetention = 24 * time.Hour
+)
+
+// StartRedirectURICleanup periodically prunes redirect URIs on the Bloud OAuth2
+// provider for hosts Bloud no longer advertises. It stops when ctx is cancelled.
+func (s *Server) StartRedirectURICleanup(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(redirectURICleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.pruneRedirectURIs(time.Now()); err != nil {
+					s.logger.Warn("failed to prune redirect URIs", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// pruneRedirectURIs reconciles the provider's redirect URIs to the canonical
+// hostname, the currently detected IPs, and recently used lazy hosts.
+// IPs the host no longer holds (e.g., old DHCP leases) are removed.
+func (s *Server) pruneRedirectURIs(now time.Time) error {
+	if s.authentikClient == nil || s.authConfig == nil || s.authConfig.OIDCConfig == nil {
+		return nil
+	}
+	providerID := s.authConfig.OIDCConfig.ProviderID
+	if providerID == 0 {
+		return nil
+	}
+
+	var advertised []string
+	for _, baseURL := range netutil.BuildBaseURLs(s.cfg.SSOBaseURL) {
+		advertised = append(advertised, baseURL+"/auth/callback")
+	}
+
+	recent := make(map[string]bool)
+	s.knownRedirectURIs.Range(func(key, value any) bool {
+		if lastUsed, ok := value.(time.Time); ok && now.Sub(lastUsed) < lazyRedirectURIRetention {
+			recent[key.(string)] = true
+		}
+		return true
+	})
+
+	current, err := s.authentikClient.GetRedirectURIs(providerID)
+	if err != nil {
+		return fmt.Errorf("failed to get redirect URIs: %w", err)
+	}
+
+	next, removed, changed := planRedirectURIs(current, advertised, recent)
+	if !changed {
+		return nil
+	}
+
+	if err := s.authentikClient.SetRedirectURIs(providerID, next); err != nil {
+		return fmt.Errorf("failed to update redirect URIs: %w", err)
+	}
+
+	// Forget pruned hosts so a later login from one re-registers it
+	for _, uri := range removed {
+		s.knownRedirectURIs.Delete(uri)
+	}
+	for _, uri := range advertised {
+		if _, ok := s.knownRedirectURIs.Load(uri); !ok {
+			s.knownRedirectURIs.Store(uri, now)
+		}
+	}
+
+	s.logger.Info("reconciled OAuth redirect URIs", "removed", removed, "count", len(next))
+	return nil
+}
+
+// planRedirectURIs computes the redirect URI list to keep: all advertised URIs
+// (in order) followed by any current URIs that are in recent.
+// Returns the new list, the URIs that were dropped, and whether anything changed.
+func planRedirectURIs(current, advertised []string, recent map[string]bool) (next, removed []string, changed bool) {
+	seen := make(map[string]bool)
+	for _, uri := range advertised {
+		if !seen[uri] {
+			seen[uri] = true
+			next = append(next, uri)
+		}
+	}
+
+	inCurrent := make(map[string]bool, len(current))
+	for _, uri := range current {
+		inCurrent[uri] = true
+		if seen[uri] {
+			continue
+		}
+		if recent[uri] {
+			seen[uri] = true
+			next = append(next, uri)
+			continue
+		}
+		removed = append(removed, uri)
+	}
+
+	changed = len(removed) > 0
+	for _, uri := range next {
+		if !inCurrent[uri] {
+			changed = true
+		}
+	}
+
+	return next, removed, changed
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/requestid"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// validRequestID limits client-supplied IDs to something safe to log and echo
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestIDMiddleware assigns every request an ID, reusing a well-formed
+// X-Request-ID from the client (e.g. the CLI). The ID is stored in the request
+// context, where the logger picks it up, and echoed in the response header,
+// where respondError picks it up for error bodies.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !validRequestID.MatchString(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
+
+// requestLogger logs one structured line per request once it completes
+func (s *Server) requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start),
+			"remote", r.RemoteAddr,
+		}
+		switch {
+		case status >= 500:
+			s.logger.ErrorContext(r.Context(), "request", attrs...)
+		case status >= 400:
+			s.logger.WarnContext(r.Context(), "request", attrs...)
+		default:
+			s.logger.InfoContext(r.Context(), "request", attrs...)
+		}
+	})
+}
@@ -2,12 +2,18 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/orchestrator"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/requestid"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
 	"github.com/go-chi/chi/v5"
@@ -16,77 +22,184 @@ import (
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes() {
 	// Auth routes at root level (for OAuth redirects)
-	s.router.Get("/auth/login", s.handleLogin)
-	s.router.Get("/auth/callback", s.handleCallback)
+	s.router.With(s.rateLimit("auth", authRateLimit)).Get("/auth/login", s.handleLogin)
+	s.router.With(s.rateLimit("auth", authRateLimit)).Get("/auth/callback", s.handleCallback)
 	s.router.Post("/auth/logout", s.handleLogout)
 
-	// API routes
+	// Prometheus scrape endpoint (public, like most exporters)
+	s.router.Method(http.MethodGet, "/metrics", metrics.Handler())
+	s.router.Method(http.MethodGet, "/metrics/apps", metrics.ExporterHandler(metrics.NewExporter(s.exporterSnapshot)))
+
+	// API routes. /api/v1 is the current surface; /api serves the same routes
+	// for existing clients, with the version picked by the Bloud-API-Version header.
+	s.router.Route("/api/v1", func(r chi.Router) {
+		r.Use(apiVersionMiddleware(currentAPIVersion))
+		s.setupAPIRoutes(r)
+	})
 	s.router.Route("/api", func(r chi.Router) {
-		// Public routes (no auth required)
-		r.Get("/health", s.handleHealth)
+		r.Use(apiVersionMiddleware(""))
+		s.setupAPIRoutes(r)
+	})
 
-		// Setup endpoints (public - used before first user exists)
-		r.Route("/setup", func(r chi.Router) {
-			r.Get("/status", s.handleSetupStatus)
-			r.Post("/create-user", s.handleCreateUser)
-		})
+	// Serve frontend static files
+	s.setupFrontend()
+}
 
-		// Auth info endpoint (public - returns user or 401)
-		r.Get("/auth/me", s.handleGetCurrentUser)
+// setupAPIRoutes registers the API routes relative to their version prefix
+func (s *Server) setupAPIRoutes(r chi.Router) {
+	// Public routes (no auth required)
+	r.Get("/health", s.handleHealth)
+	r.Get("/openapi.json", s.handleOpenAPISpec)
 
-		// Protected routes (require auth when session store is available)
-		r.Group(func(r chi.Router) {
-			if s.sessionStore != nil {
-				r.Use(s.authMiddleware)
-			}
+	// Setup endpoints (public - used before first user exists)
+	r.Route("/setup", func(r chi.Router) {
+		r.Get("/status", s.handleSetupStatus)
+		r.With(s.rateLimit("auth", authRateLimit), s.auditMiddleware).Post("/create-user", s.handleCreateUser)
+	})
 
-			// Apps endpoints
-			r.Route("/apps", func(r chi.Router) {
-				r.Get("/", s.handleListApps)
-				r.Get("/installed", s.handleListInstalledApps)
-				r.Get("/events", s.handleAppEvents)
-				r.Post("/refresh-catalog", s.handleRefreshCatalog)
+	// Auth info endpoint (public - returns user or 401)
+	r.Get("/auth/me", s.handleGetCurrentUser)
+
+	// Authentik notification webhook (public - only triggers a sync, payload is ignored)
+	r.Post("/provisioning/webhook", s.handleProvisioningWebhook)
+
+	// Protected routes (require auth when session store is available)
+	r.Group(func(r chi.Router) {
+		if s.sessionStore != nil {
+			r.Use(s.authMiddleware)
+			r.Use(s.csrfMiddleware)
+		}
+		r.Use(s.auditMiddleware)
+
+		// Personal access tokens
+		r.Get("/auth/tokens", s.handleListTokens)
+		r.With(s.rateLimit("auth", authRateLimit)).Post("/auth/tokens", s.handleCreateToken)
+		r.Delete("/auth/tokens/{id}", s.handleDeleteToken)
+		r.With(s.requireAdmin).Delete("/users/{username}/tokens", s.handleRevokeUserTokens)
+
+		// Typed event channel (WebSocket)
+		r.Get("/events", s.handleEventsSocket)
 
-				// Plan endpoints (use graph)
-				r.Get("/{name}/plan-install", s.handlePlanInstall)
-				r.Get("/{name}/plan-remove", s.handlePlanRemove)
+		// Apps endpoints
+		r.Route("/apps", func(r chi.Router) {
+			r.Get("/", s.handleListApps)
+			r.Get("/search", s.handleSearchApps)
+			r.Get("/installed", s.handleListInstalledApps)
+			r.Get("/uninstalled", s.handleListUninstalledApps)
+			r.Get("/updates", s.handleListAppUpdates)
+			r.Get("/events", s.handleAppEvents)
 
-				// Metadata endpoint
-				r.Get("/{name}/metadata", s.handleAppMetadata)
+			// Plan endpoints (use graph)
+			r.Get("/{name}/plan-install", s.handlePlanInstall)
+			r.Get("/{name}/plan-remove", s.handlePlanRemove)
+
+			// Metadata endpoint
+			r.Get("/{name}/metadata", s.handleAppMetadata)
+
+			// Logs streaming
+			r.Get("/{name}/logs", s.handleAppLogs)
+
+			// Static assets
+			r.Get("/{name}/icon", s.handleAppIcon)
+
+			// User-editable settings declared in metadata.yaml
+			r.Get("/{name}/settings", s.handleGetAppSettings)
+
+			// Status transition history
+			r.Get("/{name}/history", s.handleAppHistory)
+			r.Get("/{name}/uptime", s.handleAppUptime)
+
+			// Current CPU, memory and network usage
+			r.Get("/{name}/stats", s.handleAppStats)
+
+			// Admin-only: changing what is installed
+			r.Group(func(r chi.Router) {
+				r.Use(s.requireAdmin)
+				r.Use(s.rateLimit("expensive", expensiveRateLimit))
+
+				r.Post("/refresh-catalog", s.handleRefreshCatalog)
 
 				// Action endpoints (use orchestrator)
 				r.Post("/{name}/install", s.handleInstall)
 				r.Post("/{name}/uninstall", s.handleUninstall)
 				r.Post("/{name}/clear-data", s.handleClearData)
 				r.Patch("/{name}/rename", s.handleRename)
+				r.Put("/{name}/settings", s.handleSetAppSettings)
+				r.Put("/{name}/icon", s.handleSetAppIcon)
+				r.Delete("/{name}/icon", s.handleDeleteAppIcon)
 
-				// Logs streaming
-				r.Get("/{name}/logs", s.handleAppLogs)
+				// Per-app secrets; values are write-only
+				r.Get("/{name}/secrets", s.handleListAppSecrets)
+				r.Put("/{name}/secrets/{key}", s.handleSetAppSecret)
+				r.Delete("/{name}/secrets/{key}", s.handleDeleteAppSecret)
 
-				// Static assets
-				r.Get("/{name}/icon", s.handleAppIcon)
+				// Interactive shell in the app container (WebSocket)
+				r.Get("/{name}/exec", s.handleAppExec)
 			})
+		})
 
-			// System endpoints
-			r.Post("/system/rollback", s.handleRollback)
-			r.Route("/system", func(r chi.Router) {
-				r.Get("/status", s.handleSystemStatus)
-				r.Get("/status/stream", s.handleSystemStatusStream)
-				r.Get("/storage", s.handleStorage)
+		// System endpoints (usage is visible to everyone, changes are admin-only)
+		r.With(s.requireAdmin, s.rateLimit("expensive", expensiveRateLimit)).Post("/system/rollback", s.handleRollback)
+		r.Route("/system", func(r chi.Router) {
+			r.Get("/status", s.handleSystemStatus)
+			r.Get("/health/summary", s.handleHealthSummary)
+			r.Get("/status/stream", s.handleSystemStatusStream)
+			r.Get("/stats/history", s.handleStatsHistory)
+			r.Get("/stats/apps", s.handleTopApps)
+			r.Get("/network", s.handleNetwork)
+			r.Get("/storage", s.handleStorage)
+			r.Get("/storage/breakdown", s.handleStorageBreakdown)
+			r.With(s.requireAdmin).Post("/storage/breakdown/refresh", s.handleRefreshStorageBreakdown)
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.requireAdmin)
 				r.Get("/versions", s.handleListGenerations)
+				r.Get("/audit", s.handleListAudit)
+				r.Get("/export", s.handleExportConfig)
+				r.With(s.rateLimit("expensive", expensiveRateLimit)).Post("/diagnostics", s.handleDiagnostics)
+				r.With(s.rateLimit("expensive", expensiveRateLimit)).Post("/import", s.handleImportConfig)
+				r.Post("/reboot", s.handleReboot)
+				r.Post("/shutdown", s.handleShutdown)
+				r.Get("/power", s.handleGetPowerSchedule)
+				r.Put("/power", s.handleSetPowerSchedule)
+				r.Get("/updates", s.handleGetUpdates)
+				r.Put("/updates", s.handleSetUpdates)
+				r.With(s.rateLimit("expensive", expensiveRateLimit)).Post("/updates/rollback", s.handleRollbackUpdate)
+				r.With(s.rateLimit("expensive", expensiveRateLimit)).Post("/updates/upload", s.handleUploadUpdate)
+				r.Get("/watchdog", s.handleGetWatchdog)
+				r.Put("/watchdog", s.handleSetWatchdog)
+				r.Get("/notifications", s.handleGetNotifications)
+				r.Put("/notifications", s.handleSetNotifications)
+				r.Post("/notifications/test", s.handleTestNotifications)
+				r.Get("/alerts", s.handleGetAlerts)
+				r.Put("/alerts", s.handleSetAlerts)
+				r.Get("/metrics/scrape-config", s.handleScrapeConfig)
+				r.Get("/logging", s.handleGetLogging)
+				r.Put("/logging", s.handleSetLogging)
+				r.Get("/schedules", s.handleListSchedules)
+				r.Put("/schedules/{name}", s.handleUpdateSchedule)
+				r.Post("/schedules/{name}/run", s.handleRunSchedule)
 				r.Get("/rebuild/stream", s.handleRebuildStream)
+				r.With(s.rateLimit("expensive", expensiveRateLimit)).Post("/provisioning/sync", s.handleProvisioningSync)
+				r.Get("/database/backups", s.handleListDatabaseBackups)
+				r.With(s.rateLimit("expensive", expensiveRateLimit)).Post("/database/maintenance", s.handleDatabaseMaintenance)
+				r.With(s.rateLimit("expensive", expensiveRateLimit)).Post("/database/backups/{name}/restore", s.handleRestoreDatabaseBackup)
+				r.Get("/db/check", s.handleDatabaseCheck)
+				r.Post("/db/repair", s.handleDatabaseRepair)
+				r.Get("/secrets/rotation", s.handleGetSecretRotation)
+				r.Get("/secrets/access", s.handleListSecretAccess)
+				r.With(s.rateLimit("expensive", expensiveRateLimit)).Post("/secrets/rotate", s.handleRotateSecrets)
 			})
+		})
 
-			// User preferences endpoints
-			r.Route("/user", func(r chi.Router) {
-				r.Get("/layout", s.handleGetLayout)
-				r.Put("/layout", s.handleSetLayout)
-			})
+		// User preferences endpoints
+		r.Route("/user", func(r chi.Router) {
+			r.Get("/layout", s.handleGetLayout)
+			r.Put("/layout", s.handleSetLayout)
 		})
+		r.Get("/users/me/preferences", s.handleGetPreferences)
+		r.Put("/users/me/preferences", s.handleUpdatePreferences)
 	})
-
-	// Serve frontend static files
-	s.setupFrontend()
 }
 
 // setupFrontend configures serving the SvelteKit frontend
@@ -127,14 +240,18 @@ func (s *Server) setupFrontend() {
 
 // handleHealth returns the health status of the service
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	respondJSON(w, http.StatusOK, map[string]string{
-		"status": "ok",
-	})
+	respondJSON(w, http.StatusOK, StatusResponse{Status: "ok"})
 }
 
 // handleListApps returns the list of available user-facing apps from the catalog
 // System/infrastructure apps are filtered out by default
 func (s *Server) handleListApps(w http.ResponseWriter, r *http.Request) {
+	query, p, err := parseAppQuery(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	apps, err := s.catalog.GetUserApps()
 	if err != nil {
 		s.logger.Error("failed to get apps from catalog", "error", err)
@@ -142,24 +259,117 @@ func (s *Server) handleListApps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"apps": apps,
-	})
+	if query.Installed != nil {
+		names, err := s.appStore.GetInstalledNames()
+		if err != nil {
+			s.logger.Error("failed to get installed apps", "error", err)
+			respondError(w, http.StatusInternalServerError, "failed to get apps")
+			return
+		}
+		query = query.WithInstalled(names)
+	}
+
+	apps = catalog.FilterApps(apps, query)
+	respondCachedJSON(w, r, AppListResponse{Apps: paginate(apps, p), Total: len(apps)})
+}
+
+// handleSearchApps ranks user-facing catalog apps against ?q= by name,
+// tags, category and description
+func (s *Server) handleSearchApps(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		respondError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	p, err := parsePage(q)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apps, err := s.catalog.GetUserApps()
+	if err != nil {
+		s.logger.Error("failed to get apps from catalog", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get apps")
+		return
+	}
+
+	results := catalog.Search(apps, query)
+	respondJSON(w, http.StatusOK, AppSearchResponse{Query: query, Results: paginate(results, p), Total: len(results)})
 }
 
 // handleRefreshCatalog reloads the app catalog from YAML files
 func (s *Server) handleRefreshCatalog(w http.ResponseWriter, r *http.Request) {
 	s.refreshCatalog(s.cfg.AppsDir)
 
-	respondJSON(w, http.StatusOK, map[string]string{
-		"status": "catalog refreshed",
-	})
+	respondJSON(w, http.StatusOK, StatusResponse{Status: "catalog refreshed"})
 }
 
+// handleListUninstalledApps returns previously installed apps with the
+// configuration a re-install can restore, most recently uninstalled first
+func (s *Server) handleListUninstalledApps(w http.ResponseWriter, r *http.Request) {
+	apps, err := s.appStore.GetUninstalled()
+	if err != nil {
+		s.logger.Error("failed to get uninstalled apps", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get uninstalled apps")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, apps)
+}
+
+// handleListAppUpdates returns the last image update check of each
+// installed app, for badging outdated apps
+func (s *Server) handleListAppUpdates(w http.ResponseWriter, r *http.Request) {
+	if s.imageUpdates == nil {
+		respondError(w, http.StatusServiceUnavailable, "update checks not available")
+		return
+	}
+
+	checks, err := s.imageUpdates.All()
+	if err != nil {
+		s.logger.Error("failed to get app updates", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get app updates")
+		return
+	}
+	names, err := s.appStore.GetInstalledNames()
+	if err != nil {
+		s.logger.Error("failed to get installed apps", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get installed apps")
+		return
+	}
+	installed := make(map[string]bool, len(names))
+	for _, name := range names {
+		installed[name] = true
+	}
+
+	// Results outlive uninstalls until the next check
+	resp := AppUpdatesResponse{Updates: []store.ImageUpdate{}}
+	for _, u := range checks {
+		if !installed[u.App] {
+			continue
+		}
+		resp.Updates = append(resp.Updates, u)
+		if u.UpdateAvailable {
+			resp.Available++
+		}
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
 
 // handleListInstalledApps returns the list of installed apps
 // Uses the same data source as SSE for consistency
+//
+// The response stays a bare array for the SSE-compatible shape; the number of
+// matches before limit/offset is in the X-Total-Count header.
 func (s *Server) handleListInstalledApps(w http.ResponseWriter, r *http.Request) {
+	query, p, err := parseInstalledAppQuery(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	apps, err := s.appStore.GetAll()
 	if err != nil {
 		s.logger.Error("failed to get apps", "error", err)
@@ -167,7 +377,17 @@ func (s *Server) handleListInstalledApps(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, apps)
+	apps = store.FilterInstalledApps(apps, query)
+	// Whatever the stats recorder saw last; listing apps shouldn't wait on podman
+	if usage, ok := s.cachedAppUsage(2 * statsRecordInterval); ok {
+		for _, app := range apps {
+			if u, ok := usage[app.Name]; ok {
+				app.Usage = &u
+			}
+		}
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(apps)))
+	respondJSON(w, http.StatusOK, paginate(apps, p))
 }
 
 // handleAppMetadata returns the full catalog metadata for a single app
@@ -181,7 +401,7 @@ func (s *Server) handleAppMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, app)
+	respondCachedJSON(w, r, app)
 }
 
 // handleSystemStatus returns system metrics
@@ -282,9 +502,7 @@ func (s *Server) handleInstall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse request body for choices
-	var req struct {
-		Choices map[string]string `json:"choices"`
-	}
+	var req InstallAppRequest
 	if r.ContentLength > 0 {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			respondError(w, http.StatusBadRequest, "invalid request body")
@@ -292,21 +510,36 @@ func (s *Server) handleInstall(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	choices, err := s.installChoices(name, req)
+	if errors.Is(err, errNoPreviousInstall) {
+		respondError(w, http.StatusNotFound, "no previous installation to restore")
+		return
+	}
+	if err != nil {
+		s.logger.Error("failed to read previous installation", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to read previous installation")
+		return
+	}
+
 	// Use the queue to serialize concurrent install requests
+	s.publishOperation("install", name, "queued", "")
 	result, err := nixOrch.EnqueueInstall(r.Context(), orchestrator.InstallRequest{
 		App:     name,
-		Choices: req.Choices,
+		Choices: choices,
 	})
 	if err != nil {
 		s.logger.Error("install failed", "app", name, "error", err)
+		s.publishOperation("install", name, "failed", err.Error())
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	if !result.IsSuccess() {
+		s.publishOperation("install", name, "failed", result.GetError())
 		respondJSON(w, http.StatusBadRequest, result)
 		return
 	}
+	s.publishOperation("install", name, "completed", "")
 
 	// Trigger reconciliation to configure dependent apps
 	s.triggerReconcile()
@@ -314,6 +547,41 @@ func (s *Server) handleInstall(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, result)
 }
 
+// errNoPreviousInstall is returned when a restore is requested for an app
+// that was never installed
+var errNoPreviousInstall = errors.New("no previous installation")
+
+// installChoices returns the integration choices for an install. A restore
+// starts from the previous installation's choices, overridden by any given
+// ones; a fresh install of a previously installed app resets its settings.
+func (s *Server) installChoices(name string, req InstallAppRequest) (map[string]string, error) {
+	prev, err := s.appStore.GetUninstalledByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.Restore {
+		if prev != nil && s.settingsStore != nil {
+			if err := s.settingsStore.Set(name, map[string]any{}); err != nil {
+				return nil, err
+			}
+		}
+		return req.Choices, nil
+	}
+
+	if prev == nil {
+		return nil, errNoPreviousInstall
+	}
+	choices := make(map[string]string, len(prev.IntegrationConfig)+len(req.Choices))
+	for integration, source := range prev.IntegrationConfig {
+		choices[integration] = source
+	}
+	for integration, source := range req.Choices {
+		choices[integration] = source
+	}
+	return choices, nil
+}
+
 // handleUninstall removes an app
 func (s *Server) handleUninstall(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
@@ -325,9 +593,7 @@ func (s *Server) handleUninstall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse optional clearData from request body
-	var req struct {
-		ClearData bool `json:"clearData"`
-	}
+	var req UninstallAppRequest
 	if r.ContentLength > 0 {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			respondError(w, http.StatusBadRequest, "invalid request body")
@@ -336,20 +602,24 @@ func (s *Server) handleUninstall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Use the queue to serialize concurrent uninstall requests
+	s.publishOperation("uninstall", name, "queued", "")
 	result, err := nixOrch.EnqueueUninstall(r.Context(), orchestrator.UninstallRequest{
 		App:       name,
 		ClearData: req.ClearData,
 	})
 	if err != nil {
 		s.logger.Error("uninstall failed", "app", name, "error", err)
+		s.publishOperation("uninstall", name, "failed", err.Error())
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	if !result.IsSuccess() {
+		s.publishOperation("uninstall", name, "failed", result.GetError())
 		respondJSON(w, http.StatusBadRequest, result)
 		return
 	}
+	s.publishOperation("uninstall", name, "completed", "")
 
 	// Trigger reconciliation to update dependent apps
 	s.triggerReconcile()
@@ -375,19 +645,23 @@ func (s *Server) handleClearData(w http.ResponseWriter, r *http.Request) {
 	if app != nil && ok && nixOrch != nil {
 		// App is installed - uninstall with clearData=true using the queue
 		s.logger.Info("uninstalling app with data cleanup", "app", name)
+		s.publishOperation("uninstall", name, "queued", "")
 		result, err := nixOrch.EnqueueUninstall(r.Context(), orchestrator.UninstallRequest{
 			App:       name,
 			ClearData: true,
 		})
 		if err != nil {
 			s.logger.Error("uninstall failed during clear-data", "app", name, "error", err)
+			s.publishOperation("uninstall", name, "failed", err.Error())
 			respondError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 		if !result.IsSuccess() {
+			s.publishOperation("uninstall", name, "failed", result.GetError())
 			respondJSON(w, http.StatusBadRequest, result)
 			return
 		}
+		s.publishOperation("uninstall", name, "completed", "")
 	} else {
 		// App not installed - just clean up any orphaned data
 		s.logger.Info("cleaning up orphaned app data", "app", name)
@@ -402,9 +676,9 @@ func (s *Server) handleClearData(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{
-		"status": "data cleared",
-		"app":    name,
+	respondJSON(w, http.StatusOK, ClearDataResponse{
+		Status: "data cleared",
+		App:    name,
 	})
 }
 
@@ -412,9 +686,7 @@ func (s *Server) handleClearData(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleRename(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 
-	var req struct {
-		DisplayName string `json:"displayName"`
-	}
+	var req RenameAppRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "invalid request body")
 		return
@@ -431,10 +703,10 @@ func (s *Server) handleRename(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{
-		"status":      "renamed",
-		"app":         name,
-		"displayName": req.DisplayName,
+	respondJSON(w, http.StatusOK, RenameAppResponse{
+		Status:      "renamed",
+		App:         name,
+		DisplayName: req.DisplayName,
 	})
 }
 
@@ -465,20 +737,6 @@ func (s *Server) dropAppDatabase(appName string) error {
 	return nil
 }
 
-// handleAppIcon serves the icon.png for an app
-func (s *Server) handleAppIcon(w http.ResponseWriter, r *http.Request) {
-	name := chi.URLParam(r, "name")
-	iconPath := filepath.Join(s.cfg.AppsDir, name, "icon.png")
-
-	if _, err := os.Stat(iconPath); os.IsNotExist(err) {
-		http.NotFound(w, r)
-		return
-	}
-
-	w.Header().Set("Cache-Control", "public, max-age=86400")
-	http.ServeFile(w, r, iconPath)
-}
-
 // Helper functions for JSON responses
 
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -487,10 +745,10 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
+// respondError writes an error body. The request ID is read back from the
+// response header set by requestIDMiddleware, so handlers don't need to pass r.
 func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]string{
-		"error": message,
-	})
+	respondJSON(w, status, ErrorResponse{Error: message, RequestID: w.Header().Get(requestid.Header)})
 }
 
 // handleRollback reverts to the previous NixOS generation
@@ -511,12 +769,12 @@ func (s *Server) handleRollback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"success":      result.Success,
-		"output":       result.Output,
-		"errorMessage": result.ErrorMessage,
-		"changes":      result.Changes,
-		"duration":     result.Duration.String(),
+	respondJSON(w, http.StatusOK, RollbackResponse{
+		Success:      result.Success,
+		Output:       result.Output,
+		ErrorMessage: result.ErrorMessage,
+		Changes:      result.Changes,
+		Duration:     result.Duration.String(),
 	})
 }
 
@@ -537,9 +795,7 @@ func (s *Server) handleListGenerations(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"generations": generations,
-	})
+	respondJSON(w, http.StatusOK, GenerationsResponse{Generations: generations})
 }
 
 // handleGetLayout returns the user's layout
@@ -584,7 +840,5 @@ func (s *Server) handleSetLayout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{
-		"status": "saved",
-	})
+	respondJSON(w, http.StatusOK, StatusResponse{Status: "saved"})
 }
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/scheduler"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// newScheduler registers the host agent's periodic jobs
+func (s *Server) newScheduler(st store.ScheduleStoreInterface) *scheduler.Scheduler {
+	sched := scheduler.New(st, s.logger)
+	jobs := []scheduler.Job{{
+		Name:        "image-prune",
+		Description: "Remove dangling container images left behind by app updates",
+		Spec:        "0 4 * * 0",
+		Jitter:      time.Hour,
+		CatchUp:     true,
+		Run:         pruneImages,
+	}}
+	if s.cfg.Maintainer != nil {
+		jobs = append(jobs, scheduler.Job{
+			Name:        "backup",
+			Description: "Check, vacuum and back up the host-agent database",
+			Spec:        "0 3 * * *",
+			Jitter:      30 * time.Minute,
+			CatchUp:     true,
+			LastRun:     s.cfg.Maintainer.LastBackup,
+			Run:         s.runMaintenance,
+		})
+	}
+	if s.statsStore != nil {
+		jobs = append(jobs, scheduler.Job{
+			Name:        "stats-rollup",
+			Description: "Downsample and prune the stats history",
+			// Matches the first rollup so 5 minute averages are never far behind
+			Spec: "*/5 * * * *",
+			Run: func(ctx context.Context) error {
+				return s.statsStore.Compact(time.Now())
+			},
+		})
+	}
+
+	if s.storageScanner != nil {
+		jobs = append(jobs, scheduler.Job{
+			Name:        "storage-scan",
+			Description: "Measure what uses the disk for the storage breakdown",
+			Spec:        "0 5 * * *",
+			Jitter:      30 * time.Minute,
+			Run:         s.storageScanner.Scan,
+		})
+	}
+
+	if s.updater != nil {
+		jobs = append(jobs, scheduler.Job{
+			Name:        "auto-update",
+			Description: "Apply system updates inside the maintenance window, per the update policy",
+			// Often enough to catch short windows; runs outside them do nothing
+			Spec: "*/10 * * * *",
+			Run:  s.updater.Run,
+		}, scheduler.Job{
+			Name:        "update-check",
+			Description: "Check for a newer Bloud system and notify when one is available",
+			Spec:        "0 2 * * *",
+			Jitter:      time.Hour,
+			CatchUp:     true,
+			Run:         s.updater.Check,
+		})
+	}
+
+	if s.imageChecker != nil {
+		jobs = append(jobs, scheduler.Job{
+			Name:        "image-update-check",
+			Description: "Check installed apps' container images for newer versions",
+			Spec:        "0 */6 * * *",
+			Jitter:      time.Hour,
+			CatchUp:     true,
+			Run:         s.imageChecker.Check,
+		})
+	}
+
+	for _, job := range jobs {
+		if err := sched.Register(job); err != nil {
+			s.logger.Error("failed to register scheduled job", "job", job.Name, "error", err)
+		}
+	}
+	return sched
+}
+
+// StartScheduler runs the scheduled jobs in the background
+func (s *Server) StartScheduler(ctx context.Context) {
+	if s.scheduler == nil {
+		return
+	}
+	s.scheduler.Start(ctx)
+}
+
+// runMaintenance is the scheduled database backup
+func (s *Server) runMaintenance(ctx context.Context) error {
+	report, err := s.cfg.Maintainer.Run(ctx)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("database maintenance complete",
+		"integrity_ok", report.IntegrityOK,
+		"backup", report.Backup.Name,
+		"pruned", len(report.Pruned),
+		"duration_ms", report.DurationMs)
+	if !report.IntegrityOK {
+		return fmt.Errorf("backup taken, but the integrity check failed: %s", strings.Join(report.IntegrityErrors, "; "))
+	}
+	return nil
+}
+
+// pruneImages removes dangling images from the host agent user's Podman
+// storage, where the app containers run. Tagged images are kept so stopped
+// apps start without pulling again.
+func pruneImages(ctx context.Context) error {
+	out, err := exec.CommandContext(ctx, "podman", "image", "prune", "--force").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("podman image prune: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// handleListSchedules returns the scheduled jobs and their last and next runs
+func (s *Server) handleListSchedules(w http.ResponseWriter, r *http.Request) {
+	if s.scheduler == nil {
+		respondError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+	respondJSON(w, http.StatusOK, SchedulesResponse{Schedules: s.scheduler.List()})
+}
+
+// handleUpdateSchedule changes a job's schedule or turns it on or off
+func (s *Server) handleUpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.scheduler == nil {
+		respondError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+
+	var req scheduler.Update
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	status, err := s.scheduler.Update(chi.URLParam(r, "name"), req)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		respondError(w, http.StatusNotFound, "schedule not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid schedule: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+// handleRunSchedule starts a job now, outside its schedule
+func (s *Server) handleRunSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.scheduler == nil {
+		respondError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+
+	err := s.scheduler.RunNow(chi.URLParam(r, "name"))
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		respondError(w, http.StatusNotFound, "schedule not found")
+	case errors.Is(err, scheduler.ErrRunning):
+		respondError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	default:
+		respondJSON(w, http.StatusAccepted, StatusResponse{Status: "started"})
+	}
+}
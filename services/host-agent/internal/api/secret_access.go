@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+// recordSecretAccess appends a secret read or write to the secret access log
+func (s *Server) recordSecretAccess(a secrets.Access) {
+	if s.secretAccessStore == nil {
+		return
+	}
+	err := s.secretAccessStore.Record(&store.SecretAccessEntry{
+		Component: a.Component,
+		Caller:    a.Caller,
+		Action:    a.Action,
+		App:       a.App,
+		Secret:    a.Secret,
+		CreatedAt: a.At,
+	})
+	if err != nil {
+		s.logger.Warn("failed to record secret access", "secret", a.Secret, "app", a.App, "component", a.Component, "error", err)
+	}
+}
+
+// handleListSecretAccess returns secret access log entries, newest first.
+//
+// Query parameters: component, action (read|write|delete), app, secret,
+// since and until (RFC 3339), limit.
+func (s *Server) handleListSecretAccess(w http.ResponseWriter, r *http.Request) {
+	if s.secretAccessStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "secret access log not available")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := store.SecretAccessFilter{
+		Component: q.Get("component"),
+		Action:    q.Get("action"),
+		App:       q.Get("app"),
+		Secret:    q.Get("secret"),
+	}
+
+	switch filter.Action {
+	case "", secrets.AccessRead, secrets.AccessWrite, secrets.AccessDelete:
+	default:
+		respondError(w, http.StatusBadRequest, "action must be read, write or delete")
+		return
+	}
+
+	for param, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, param+" must be an RFC 3339 timestamp")
+				return
+			}
+			*dst = t
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := s.secretAccessStore.List(filter)
+	if err != nil {
+		s.logger.Error("failed to list secret access log", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to list secret access log")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, SecretAccessLogResponse{Entries: entries})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/netutil"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/sso"
+)
+
+// secretRotationTimeout bounds a whole rotation, which restarts every SSO app
+const secretRotationTimeout = 15 * time.Minute
+
+// handleGetSecretRotation reports the current key version and any rotation in progress
+func (s *Server) handleGetSecretRotation(w http.ResponseWriter, r *http.Request) {
+	if s.secrets == nil {
+		respondError(w, http.StatusServiceUnavailable, "secrets not available")
+		return
+	}
+	respondJSON(w, http.StatusOK, s.secretRotationStatus(false))
+}
+
+// handleRotateSecrets starts rotating per-app OAuth client secrets to a new
+// key version, or resumes an interrupted rotation. Apps are updated and
+// restarted in the background; poll GET /api/system/secrets/rotation for progress.
+func (s *Server) handleRotateSecrets(w http.ResponseWriter, r *http.Request) {
+	if s.secrets == nil || s.cfg.SSOHostSecret == "" {
+		respondError(w, http.StatusServiceUnavailable, "SSO secrets not configured")
+		return
+	}
+	if !s.rotatingSecrets.CompareAndSwap(false, true) {
+		respondError(w, http.StatusConflict, "a secret rotation is already running")
+		return
+	}
+
+	apps, err := s.installedCatalogApps()
+	if err != nil {
+		s.rotatingSecrets.Store(false)
+		s.logger.Error("failed to list apps for secret rotation", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to list installed apps")
+		return
+	}
+
+	rotator := sso.NewSecretRotator(s.blueprintGenerator(), s.secrets, serviceRestartFunc(s.appRestarter()), s.logger)
+	rotation, resumed, err := rotator.Begin(apps)
+	if err != nil {
+		s.rotatingSecrets.Store(false)
+		s.logger.Error("failed to start secret rotation", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to start secret rotation")
+		return
+	}
+
+	go func() {
+		defer s.rotatingSecrets.Store(false)
+
+		ctx, cancel := context.WithTimeout(context.Background(), secretRotationTimeout)
+		defer cancel()
+
+		if err := rotator.Run(ctx, rotation, apps); err != nil {
+			s.logger.Error("secret rotation interrupted, rotate again to resume", "version", rotation.Version, "error", err)
+		}
+	}()
+
+	respondJSON(w, http.StatusAccepted, s.secretRotationStatus(resumed))
+}
+
+// secretRotationStatus describes the key version and rotation in progress
+func (s *Server) secretRotationStatus(resumed bool) SecretRotationResponse {
+	return SecretRotationResponse{
+		KeyVersion: s.secrets.StoredKeyVersion(),
+		Running:    s.rotatingSecrets.Load(),
+		Resumed:    resumed,
+		Rotation:   s.secrets.Rotation(),
+	}
+}
+
+// installedCatalogApps returns the catalog entries of installed apps
+func (s *Server) installedCatalogApps() ([]*catalog.App, error) {
+	names, err := s.appStore.GetInstalledNames()
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]*catalog.App, 0, len(names))
+	for _, name := range names {
+		if app, err := s.catalog.Get(name); err == nil && app != nil {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+// blueprintGenerator creates a generator writing to the same blueprints
+// directory as the orchestrator's
+func (s *Server) blueprintGenerator() *sso.BlueprintGenerator {
+	return sso.NewBlueprintGenerator(
+		s.cfg.SSOHostSecret,
+		s.secrets.GetLDAPBindPassword(),
+		netutil.BuildBaseURLs(s.cfg.SSOBaseURL),
+		s.cfg.SSOAuthentikURL,
+		filepath.Join(s.cfg.DataDir, "authentik-blueprints"),
+		s.secrets,
+	)
+}
+
+// appRestarter returns the function used to restart an app's systemd unit
+func (s *Server) appRestarter() func(ctx context.Context, app string) error {
+	if s.restartApp != nil {
+		return s.restartApp
+	}
+	return nixgen.NewRebuilder(s.cfg.FlakePath, s.cfg.FlakeTarget, s.logger).RestartUserService
+}
+
+// serviceRestartFunc adapts a restart function to sso.ServiceRestarter
+type serviceRestartFunc func(ctx context.Context, app string) error
+
+func (f serviceRestartFunc) RestartUserService(ctx context.Context, app string) error {
+	return f(ctx, app)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// sessionRecoveryInterval is how often Redis is checked while sessions are
+// stored in the database
+const sessionRecoveryInterval = 30 * time.Second
+
+// StartSessionRecovery watches for Redis to come back after the session store
+// fell back to the database, then moves the sessions back into Redis. It
+// stops when ctx is cancelled.
+func (s *Server) StartSessionRecovery(ctx context.Context) {
+	if s.sessionStore == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(sessionRecoveryInterval)
+		defer ticker.Stop()
+
+		wasDegraded := s.sessionStore.Degraded()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				moved, err := s.sessionStore.Recover(ctx)
+				degraded := s.sessionStore.Degraded()
+				switch {
+				case degraded && !wasDegraded:
+					s.logger.Warn("Redis unavailable, storing sessions in the database", "error", err)
+				case !degraded && wasDegraded:
+					s.logger.Info("Redis is back, moved sessions out of the database", "sessions", moved)
+				case err != nil && !degraded:
+					s.logger.Warn("failed to move sessions back to Redis", "error", err)
+				}
+				wasDegraded = degraded
+			}
+		}
+	}()
+}
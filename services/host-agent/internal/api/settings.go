@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/configurator"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxSettingsBody bounds the size of a settings update
+const maxSettingsBody = 64 * 1024
+
+// appRestartTimeout bounds restarting an app after an env-mapped setting changes
+const appRestartTimeout = 2 * time.Minute
+
+// handleGetAppSettings returns an app's settings schema and current values
+func (s *Server) handleGetAppSettings(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	app, err := s.catalog.Get(name)
+	if err != nil || app == nil {
+		respondError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	if s.settingsStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "settings not available")
+		return
+	}
+
+	stored, err := s.settingsStore.Get(name)
+	if err != nil {
+		s.logger.Error("failed to get app settings", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get settings")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AppSettingsResponse{
+		App:    name,
+		Schema: app.Settings,
+		Values: app.ResolveSettings(stored),
+	})
+}
+
+// handleSetAppSettings updates an installed app's settings.
+//
+// The body is a partial object of setting values; null resets a setting to
+// its default. Values are validated against the schema in metadata.yaml,
+// stored, and applied through the app's configurator. If a setting mapped to
+// an env var changed, the app is restarted to pick it up.
+func (s *Server) handleSetAppSettings(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	app, err := s.catalog.Get(name)
+	if err != nil || app == nil {
+		respondError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	if len(app.Settings) == 0 {
+		respondError(w, http.StatusBadRequest, "app has no settings")
+		return
+	}
+	if s.settingsStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "settings not available")
+		return
+	}
+
+	installed, err := s.appStore.GetByName(name)
+	if err != nil {
+		s.logger.Error("failed to get app", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get app")
+		return
+	}
+	if installed == nil {
+		respondError(w, http.StatusNotFound, "app not installed")
+		return
+	}
+
+	var changes map[string]any
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSettingsBody)).Decode(&changes); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	changes, err = app.ValidateSettings(changes)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stored, err := s.settingsStore.Get(name)
+	if err != nil {
+		s.logger.Error("failed to get app settings", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get settings")
+		return
+	}
+	before := app.ResolveSettings(stored)
+
+	for key, value := range changes {
+		if value == nil {
+			delete(stored, key)
+		} else {
+			stored[key] = value
+		}
+	}
+	if err := s.settingsStore.Set(name, stored); err != nil {
+		s.logger.Error("failed to save app settings", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to save settings")
+		return
+	}
+	values := app.ResolveSettings(stored)
+	s.logger.Info("updated app settings", "app", name, "keys", len(changes))
+
+	// Settings the configurator handles are applied to the running app
+	if s.reconciler != nil {
+		if cfg, ok := s.cfg.Registry.Get(name).(configurator.SettingsConfigurator); ok {
+			state := s.reconciler.BuildAppState(installed)
+			if err := cfg.Configure(r.Context(), state, values); err != nil {
+				s.logger.Error("failed to apply app settings", "app", name, "error", err)
+				respondError(w, http.StatusInternalServerError, "settings saved but failed to apply: "+err.Error())
+				return
+			}
+		}
+	}
+
+	// Env-mapped settings are only read when the app starts
+	restarting := !maps.Equal(app.SettingsEnv(before), app.SettingsEnv(values))
+	if restarting {
+		go s.restartAppService(name)
+	}
+
+	respondJSON(w, http.StatusOK, AppSettingsResponse{
+		App:        name,
+		Schema:     app.Settings,
+		Values:     values,
+		Restarting: restarting,
+	})
+}
+
+// restartAppService restarts an app's systemd unit; its prestart hook
+// rewrites the env file with the current settings
+func (s *Server) restartAppService(name string) {
+	restart := s.appRestarter()
+
+	ctx, cancel := context.WithTimeout(context.Background(), appRestartTimeout)
+	defer cancel()
+
+	if err := restart(ctx, name); err != nil {
+		s.logger.Error("failed to restart app after settings change", "app", name, "error", err)
+	}
+}
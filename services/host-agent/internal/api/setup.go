@@ -105,7 +105,7 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add to admins group
-	if err := s.authentikClient.AddUserToGroup(authentikUserID, "authentik Admins"); err != nil {
+	if err := s.authentikClient.AddUserToGroup(authentikUserID, adminGroupName); err != nil {
 		s.logger.Warn("failed to add user to admins group", "error", err)
 		// Don't fail - user can be added manually
 	}
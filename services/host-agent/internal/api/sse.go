@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/orchestrator"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
 )
 
@@ -26,6 +28,8 @@ func (s *Server) handleSystemStatusStream(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	defer trackSSE("system-status")()
+
 	// Create a ticker for periodic updates (500ms)
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
@@ -86,6 +90,7 @@ func (s *Server) handleRebuildStream(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.logger.Info("SSE client connected for rebuild stream")
+	defer trackSSE("rebuild")()
 
 	// Create channel for rebuild events
 	events := make(chan nixgen.RebuildEvent, 100)
@@ -95,6 +100,8 @@ func (s *Server) handleRebuildStream(w http.ResponseWriter, r *http.Request) {
 
 	// Stream events to client
 	for event := range events {
+		s.events.Publish(TopicOperations, EventRebuild, event)
+
 		data, err := json.Marshal(event)
 		if err != nil {
 			s.logger.Error("failed to marshal rebuild event", "error", err)
@@ -116,7 +123,10 @@ func (s *Server) handleRebuildStream(w http.ResponseWriter, r *http.Request) {
 	s.logger.Info("rebuild stream complete")
 }
 
-// handleAppEvents streams app state updates via SSE
+// handleAppEvents streams app state updates via SSE. Every event carries an
+// id; a client reconnecting with Last-Event-ID (or ?lastEventId= when it
+// builds a new EventSource) is sent the broadcasts it missed, or a fresh
+// snapshot if they are no longer buffered.
 func (s *Server) handleAppEvents(w http.ResponseWriter, r *http.Request) {
 	// Set headers for SSE
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -131,20 +141,50 @@ func (s *Server) handleAppEvents(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.logger.Info("SSE client connected for app events")
+	defer trackSSE("app-events")()
 
-	// Subscribe to app updates
+	// Subscribe before replaying so nothing is lost in between; anything
+	// already replayed is skipped by ID below
 	ch := s.appHub.Subscribe()
 	defer s.appHub.Unsubscribe(ch)
 
-	// Send initial app list
-	apps, err := s.appStore.GetAll()
-	if err != nil {
-		s.logger.Error("failed to get apps for SSE", "error", err)
-	} else {
-		data, _ := json.Marshal(apps)
-		fmt.Fprintf(w, "data: %s\n\n", data)
-		flusher.Flush()
+	send := func(id uint64, apps []*store.InstalledApp) {
+		data, err := json.Marshal(apps)
+		if err != nil {
+			s.logger.Error("failed to marshal apps for SSE", "error", err)
+			return
+		}
+		fmt.Fprintf(w, "id: %d\ndata: %s\n\n", id, data)
+	}
+
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+
+	var sent uint64
+	replayed := false
+	if lastID, ok := lastEventID(r); ok {
+		if missed, ok := s.appHub.Since(lastID); ok {
+			for _, event := range missed {
+				send(event.ID, event.Apps)
+			}
+			sent = lastID
+			if len(missed) > 0 {
+				sent = missed[len(missed)-1].ID
+			}
+			replayed = true
+		}
+	}
+
+	// New clients and ones that fell too far behind get the current list
+	if !replayed {
+		sent = s.appHub.LastID()
+		apps, err := s.appStore.GetAll()
+		if err != nil {
+			s.logger.Error("failed to get apps for SSE", "error", err)
+		} else {
+			send(sent, apps)
+		}
 	}
+	flusher.Flush()
 
 	// Stream updates
 	ctx := r.Context()
@@ -153,17 +193,32 @@ func (s *Server) handleAppEvents(w http.ResponseWriter, r *http.Request) {
 		case <-ctx.Done():
 			s.logger.Info("SSE client disconnected from app events")
 			return
-		case apps, ok := <-ch:
+		case event, ok := <-ch:
 			if !ok {
 				return
 			}
-			data, err := json.Marshal(apps)
-			if err != nil {
-				s.logger.Error("failed to marshal apps for SSE", "error", err)
+			if event.ID <= sent {
 				continue
 			}
-			fmt.Fprintf(w, "data: %s\n\n", data)
+			send(event.ID, event.Apps)
+			sent = event.ID
 			flusher.Flush()
 		}
 	}
 }
+
+// sseRetry is the reconnection delay suggested to EventSource clients
+const sseRetry = 3 * time.Second
+
+// lastEventID reads the ID a reconnecting SSE client last saw
+func lastEventID(r *http.Request) (uint64, bool) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("lastEventId")
+	}
+	if raw == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	return id, err == nil
+}
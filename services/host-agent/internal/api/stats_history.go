@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
+)
+
+const (
+	// statsRecordInterval matches the finest stats history resolution
+	statsRecordInterval = time.Minute
+
+	defaultStatsRange = 24 * time.Hour
+)
+
+// StartStatsRecorder samples host and per-app resource usage into the stats
+// history every minute. The stats-rollup scheduled job downsamples and
+// prunes it.
+func (s *Server) StartStatsRecorder(ctx context.Context) {
+	if s.statsStore == nil {
+		return
+	}
+
+	go func() {
+		recordTicker := time.NewTicker(statsRecordInterval)
+		defer recordTicker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-recordTicker.C:
+				if err := s.statsStore.Record(s.sampleStats(ctx, now)); err != nil {
+					s.logger.Warn("failed to record stats", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// sampleStats returns the current host usage plus one sample per installed
+// app whose containers report stats
+func (s *Server) sampleStats(ctx context.Context, now time.Time) []store.StatsSample {
+	host, _ := system.GetStats()
+	samples := []store.StatsSample{{
+		CPU:         float64(host.CPU),
+		Memory:      float64(host.Memory),
+		Disk:        float64(host.Disk),
+		Temperature: float64(host.Temperature),
+		SampledAt:   now,
+	}}
+
+	usage, err := s.collectAppUsage(ctx, now)
+	if err != nil {
+		s.logger.Debug("failed to get app usage", "error", err)
+		return samples
+	}
+	apps := make([]string, 0, len(usage))
+	for app := range usage {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	for _, app := range apps {
+		samples = append(samples, store.StatsSample{
+			App:       app,
+			CPU:       usage[app].CPU,
+			Memory:    usage[app].Memory,
+			SampledAt: now,
+		})
+	}
+	return samples
+}
+
+// handleStatsHistory returns resource usage samples for charts.
+//
+// Query parameters: range (e.g. 1h, 24h, 7d; default 24h), resolution (1m,
+// 5m, 1h or 1d; default the finest one that covers the range), app (default
+// the whole host).
+func (s *Server) handleStatsHistory(w http.ResponseWriter, r *http.Request) {
+	if s.statsStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "stats history not available")
+		return
+	}
+
+	q := r.URL.Query()
+	statsRange := defaultStatsRange
+	if v := q.Get("range"); v != "" {
+		d, err := parseStatsRange(v)
+		if err != nil || d <= 0 {
+			respondError(w, http.StatusBadRequest, "range must be a duration such as 1h, 24h or 7d")
+			return
+		}
+		statsRange = d
+	}
+	tier, ok := store.TierFor(statsRange)
+	if !ok {
+		maxRange := store.StatsTiers[len(store.StatsTiers)-1].Retention
+		respondError(w, http.StatusBadRequest, "range can be at most "+formatStatsRange(maxRange))
+		return
+	}
+	if v := q.Get("resolution"); v != "" {
+		d, err := parseStatsRange(v)
+		if err == nil {
+			tier, ok = store.TierAt(d)
+		}
+		if err != nil || !ok {
+			respondError(w, http.StatusBadRequest, "resolution must be one of "+statsResolutions())
+			return
+		}
+		if statsRange > tier.Retention {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("%s samples are kept for %s; choose a shorter range or a coarser resolution",
+				formatStatsRange(tier.Resolution), formatStatsRange(tier.Retention)))
+			return
+		}
+	}
+
+	app := q.Get("app")
+	samples, err := s.statsStore.History(app, tier, time.Now().Add(-statsRange))
+	if err != nil {
+		s.logger.Error("failed to get stats history", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get stats history")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, StatsHistoryResponse{
+		Range:      formatStatsRange(statsRange),
+		Resolution: int(tier.Resolution.Seconds()),
+		App:        app,
+		Samples:    samples,
+	})
+}
+
+// parseStatsRange parses a Go duration, also accepting whole days ("7d")
+func parseStatsRange(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// statsResolutions lists the resolutions handleStatsHistory accepts
+func statsResolutions() string {
+	names := make([]string, len(store.StatsTiers))
+	for i, tier := range store.StatsTiers {
+		names[i] = formatStatsRange(tier.Resolution)
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatStatsRange formats a range the way parseStatsRange accepts it
+func formatStatsRange(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(d/(24*time.Hour))) + "d"
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(d.String(), "0s"), "0m")
+}
@@ -0,0 +1,33 @@
+package api
+
+import "net/http"
+
+// handleStorageBreakdown returns what uses the disk, as of the last scan.
+// The first request starts a scan; until it finishes the breakdown is empty
+// and marked as scanning.
+func (s *Server) handleStorageBreakdown(w http.ResponseWriter, r *http.Request) {
+	if s.storageScanner == nil {
+		respondError(w, http.StatusServiceUnavailable, "storage breakdown not available")
+		return
+	}
+
+	breakdown := s.storageScanner.Breakdown()
+	if breakdown.ScannedAt == nil && !breakdown.Scanning {
+		breakdown.Scanning = s.storageScanner.Refresh()
+	}
+	respondJSON(w, http.StatusOK, breakdown)
+}
+
+// handleRefreshStorageBreakdown starts a new storage scan in the background
+func (s *Server) handleRefreshStorageBreakdown(w http.ResponseWriter, r *http.Request) {
+	if s.storageScanner == nil {
+		respondError(w, http.StatusServiceUnavailable, "storage breakdown not available")
+		return
+	}
+
+	status := "started"
+	if !s.storageScanner.Refresh() {
+		status = "already running"
+	}
+	respondJSON(w, http.StatusAccepted, StatusResponse{Status: status})
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTokenExpiryDays = 90
+	maxTokenExpiryDays     = 365
+
+	// tokenOwnerTTL is how long a token owner's Authentik role is trusted
+	// before it is looked up again
+	tokenOwnerTTL = time.Minute
+)
+
+// tokenOwner is a token owner's role as last seen in Authentik. An empty
+// role means the owner is gone or deactivated.
+type tokenOwner struct {
+	role      string
+	checkedAt time.Time
+}
+
+// findUserFunc looks a user up in Authentik, returning nil if there is none
+type findUserFunc func(username string) (*authentik.User, error)
+
+// handleListTokens returns the current user's personal access tokens
+func (s *Server) handleListTokens(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || s.tokenStore == nil {
+		respondError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+
+	tokens, err := s.tokenStore.List(user.ID)
+	if err != nil {
+		s.logger.Error("failed to list tokens", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to list tokens")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, TokenListResponse{Tokens: tokens})
+}
+
+// handleCreateToken mints a personal access token for the current user.
+// Tokens can only be created from a browser session, so a leaked token
+// cannot be used to mint longer-lived ones.
+func (s *Server) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || s.tokenStore == nil {
+		respondError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	if r.Context().Value(tokenContextKey) != nil {
+		respondError(w, http.StatusForbidden, "tokens cannot be created with a token")
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		respondError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		respondError(w, http.StatusBadRequest, "at least one scope is required")
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !store.ValidScope(scope) {
+			respondError(w, http.StatusBadRequest, "unknown scope: "+scope)
+			return
+		}
+		if scope == store.ScopeAdmin && !user.IsAdmin() {
+			respondError(w, http.StatusForbidden, "only admins can create admin tokens")
+			return
+		}
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultTokenExpiryDays
+	}
+	if days < 0 || days > maxTokenExpiryDays {
+		respondError(w, http.StatusBadRequest, "expiresInDays must be between 1 and 365")
+		return
+	}
+
+	token, plaintext, err := s.tokenStore.Create(user.ID, req.Name, req.Scopes, time.Now().Add(time.Duration(days)*24*time.Hour))
+	if err != nil {
+		s.logger.Error("failed to create token", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to create token")
+		return
+	}
+	token.Username = user.Username
+
+	s.logger.Info("created API token", "username", user.Username, "name", req.Name, "scopes", req.Scopes)
+	respondJSON(w, http.StatusCreated, CreateTokenResponse{Token: plaintext, APIToken: token})
+}
+
+// handleDeleteToken revokes one of the current user's tokens
+func (s *Server) handleDeleteToken(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r.Context())
+	if user == nil || s.tokenStore == nil {
+		respondError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+
+	// Token IDs are UUIDs; anything else can't name a token
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		respondError(w, http.StatusNotFound, "token not found")
+		return
+	}
+	deleted, err := s.tokenStore.Delete(user.ID, id)
+	if err != nil {
+		s.logger.Error("failed to delete token", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to delete token")
+		return
+	}
+	if !deleted {
+		respondError(w, http.StatusNotFound, "token not found")
+		return
+	}
+
+	s.logger.Info("revoked API token", "username", user.Username, "id", id)
+	respondJSON(w, http.StatusOK, StatusResponse{Status: "revoked"})
+}
+
+// handleRevokeUserTokens revokes all of another user's tokens, for an admin
+// cutting off someone who shouldn't have access anymore
+func (s *Server) handleRevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	if s.tokenStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "tokens are not available")
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+	revoked, err := s.tokenStore.DeleteAll(username)
+	if err != nil {
+		s.logger.Error("failed to revoke tokens", "username", username, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to revoke tokens")
+		return
+	}
+
+	s.logger.Info("revoked user's API tokens", "username", username, "count", revoked)
+	respondJSON(w, http.StatusOK, RevokeTokensResponse{Revoked: revoked})
+}
+
+// tokenOwnerRole returns the current Authentik role of a token's owner, or
+// "" if the owner was deleted or deactivated. Roles aren't stored with the
+// token, so this keeps a demoted or disabled user's tokens from acting
+// with the access they had when they were created.
+func (s *Server) tokenOwnerRole(username string) (string, error) {
+	s.tokenOwnerMu.Lock()
+	cached, ok := s.tokenOwners[username]
+	s.tokenOwnerMu.Unlock()
+	if ok && time.Since(cached.checkedAt) < tokenOwnerTTL {
+		return cached.role, nil
+	}
+
+	findUser := s.findUser
+	if findUser == nil {
+		findUser = authentikUserSource{s}.FindUser
+	}
+	user, err := findUser(username)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", username, err)
+	}
+
+	role := ""
+	if user != nil && user.IsActive {
+		role = store.RoleMember
+		if user.InGroup(adminGroupName) {
+			role = store.RoleAdmin
+		}
+	}
+
+	s.tokenOwnerMu.Lock()
+	if s.tokenOwners == nil {
+		s.tokenOwners = make(map[string]tokenOwner)
+	}
+	s.tokenOwners[username] = tokenOwner{role: role, checkedAt: time.Now()}
+	s.tokenOwnerMu.Unlock()
+	return role, nil
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/alerts"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/logship"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/powersched"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/scheduler"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/system"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/updates"
+)
+
+// Request and response bodies shared by the HTTP handlers. These are also the
+// types the OpenAPI document is generated from, so keep JSON tags accurate.
+
+// ErrorResponse is the body returned by respondError
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"requestId,omitempty"` // matches the X-Request-ID response header
+}
+
+// StatusResponse is a simple acknowledgement body
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// CurrentUserResponse represents the response for GET /api/auth/me
+type CurrentUserResponse struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"` // "admin" or "member"
+}
+
+// TokenListResponse represents the response for GET /api/auth/tokens
+type TokenListResponse struct {
+	Tokens []*store.APIToken `json:"tokens"`
+}
+
+// CreateTokenRequest represents the request body for POST /api/auth/tokens
+type CreateTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`                  // "read", "write", "admin"
+	ExpiresInDays int      `json:"expiresInDays,omitempty"` // defaults to 90
+}
+
+// CreateTokenResponse represents the response for POST /api/auth/tokens.
+// Token is the plaintext bearer token and is never shown again.
+type CreateTokenResponse struct {
+	Token    string          `json:"token"`
+	APIToken *store.APIToken `json:"apiToken"`
+}
+
+// RevokeTokensResponse represents the response for DELETE /api/users/{username}/tokens
+type RevokeTokensResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// AppListResponse represents the response for GET /api/apps
+type AppListResponse struct {
+	Apps  []*catalog.App `json:"apps"`
+	Total int            `json:"total"` // matches before limit/offset
+}
+
+// AppSearchResponse represents the response for GET /api/apps/search
+type AppSearchResponse struct {
+	Query   string                 `json:"query"`
+	Results []catalog.SearchResult `json:"results"`
+	Total   int                    `json:"total"` // matches before limit/offset
+}
+
+// InstallAppRequest represents the optional request body for POST /api/apps/{name}/install
+type InstallAppRequest struct {
+	Choices map[string]string `json:"choices,omitempty"` // integration -> chosen app
+	Restore bool              `json:"restore,omitempty"` // reuse the previous installation's choices and settings
+}
+
+// UninstallAppRequest represents the optional request body for POST /api/apps/{name}/uninstall
+type UninstallAppRequest struct {
+	ClearData bool `json:"clearData,omitempty"`
+}
+
+// ClearDataResponse represents the response for POST /api/apps/{name}/clear-data
+type ClearDataResponse struct {
+	Status string `json:"status"`
+	App    string `json:"app"`
+}
+
+// RenameAppRequest represents the request body for PATCH /api/apps/{name}/rename
+type RenameAppRequest struct {
+	DisplayName string `json:"displayName"`
+}
+
+// RenameAppResponse represents the response for PATCH /api/apps/{name}/rename
+type RenameAppResponse struct {
+	Status      string `json:"status"`
+	App         string `json:"app"`
+	DisplayName string `json:"displayName"`
+}
+
+// AppSettingsResponse represents the response for GET and PUT /api/apps/{name}/settings
+type AppSettingsResponse struct {
+	App        string            `json:"app"`
+	Schema     []catalog.Setting `json:"schema"`
+	Values     map[string]any    `json:"values"`               // every setting's effective value
+	Restarting bool              `json:"restarting,omitempty"` // an env-mapped setting changed
+}
+
+// AppSecretsResponse represents the response for GET /api/apps/{name}/secrets
+type AppSecretsResponse struct {
+	App     string          `json:"app"`
+	Secrets []AppSecretInfo `json:"secrets"`
+}
+
+// AppSecretInfo describes a stored secret without revealing its value
+type AppSecretInfo struct {
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint"`       // keyed hash; changes when the value does
+	Managed     bool   `json:"managed,omitempty"` // generated by bloud, read-only through the API
+}
+
+// SetAppSecretRequest represents the request body for PUT /api/apps/{name}/secrets/{key}.
+// The field is named secret so the audit log redacts it.
+type SetAppSecretRequest struct {
+	Secret string `json:"secret"`
+}
+
+// HealthSummaryResponse represents the response for GET /api/system/health/summary
+type HealthSummaryResponse struct {
+	Status    string              `json:"status"` // ok, degraded or down
+	CheckedAt time.Time           `json:"checkedAt"`
+	Checks    []HealthCheckResult `json:"checks"`
+	Apps      []AppHealthResult   `json:"apps"`
+}
+
+// HealthCheckResult is the outcome of one dependency check
+type HealthCheckResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"` // ok, failed or skipped
+	Critical  bool   `json:"critical"`
+	Detail    string `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// AppHealthResult is an installed app's status and last health check
+type AppHealthResult struct {
+	Name      string                `json:"name"`
+	Status    string                `json:"status"`
+	LastCheck *metrics.HealthResult `json:"lastCheck,omitempty"` // nil until checked since the agent started
+}
+
+// RollbackResponse represents the response for POST /api/system/rollback
+type RollbackResponse struct {
+	Success      bool     `json:"success"`
+	Output       string   `json:"output"`
+	ErrorMessage string   `json:"errorMessage"`
+	Changes      []string `json:"changes"`
+	Duration     string   `json:"duration"`
+}
+
+// AppHistoryResponse represents the response for GET /api/apps/{name}/history
+type AppHistoryResponse struct {
+	App    string            `json:"app"`
+	Events []*store.AppEvent `json:"events"` // newest first
+	Counts map[string]int    `json:"counts"` // transitions into each status over the same window
+}
+
+// AppUptimeResponse represents the response for GET /api/apps/{name}/uptime
+type AppUptimeResponse struct {
+	App    string       `json:"app"`
+	Days   int          `json:"days"`
+	Uptime store.Uptime `json:"uptime"`
+}
+
+// DatabaseBackupsResponse represents the response for GET /api/system/database/backups
+type DatabaseBackupsResponse struct {
+	Backups []*db.Backup `json:"backups"` // newest first
+}
+
+// DatabaseCheckResponse represents the response for GET /api/system/db/check
+// and POST /api/system/db/repair
+type DatabaseCheckResponse struct {
+	OK       bool                   `json:"ok"` // no findings are left unrepaired
+	Findings []DatabaseCheckFinding `json:"findings"`
+	Repaired int                    `json:"repaired"`
+}
+
+// DatabaseCheckFinding is one inconsistency found by the database check
+type DatabaseCheckFinding struct {
+	Check      string `json:"check"` // app_not_in_catalog, integration_source_missing or orphaned_app_secrets
+	App        string `json:"app"`
+	Detail     string `json:"detail"`
+	Action     string `json:"action"` // what to do about it
+	Repairable bool   `json:"repairable"`
+	Repaired   bool   `json:"repaired,omitempty"`
+	Error      string `json:"error,omitempty"` // why a repair failed
+}
+
+// SecretRotationResponse represents the response for GET /api/system/secrets/rotation
+// and POST /api/system/secrets/rotate
+type SecretRotationResponse struct {
+	KeyVersion int               `json:"keyVersion"` // version current secrets are derived with
+	Running    bool              `json:"running"`
+	Resumed    bool              `json:"resumed,omitempty"` // the rotation was interrupted and picked up again
+	Rotation   *secrets.Rotation `json:"rotation,omitempty"`
+}
+
+// RestoreBackupResponse represents the response for POST /api/system/database/backups/{name}/restore
+type RestoreBackupResponse struct {
+	Restored     string `json:"restored"`
+	SafetyBackup string `json:"safetyBackup"` // taken just before restoring, to undo it
+}
+
+// StatsHistoryResponse represents the response for GET /api/system/stats/history
+type StatsHistoryResponse struct {
+	Range      string              `json:"range"`
+	Resolution int                 `json:"resolution"` // seconds between samples
+	App        string              `json:"app,omitempty"`
+	Samples    []store.StatsSample `json:"samples"` // oldest first
+}
+
+// AppStatsResponse represents the response for GET /api/apps/{name}/stats
+type AppStatsResponse struct {
+	App   string          `json:"app"`
+	Usage *store.AppUsage `json:"usage"` // null when none of the app's containers are running
+}
+
+// TopAppsResponse represents the response for GET /api/system/stats/apps
+type TopAppsResponse struct {
+	Sort string             `json:"sort"`
+	Apps []AppStatsResponse `json:"apps"` // heaviest first
+}
+
+// NetworkResponse represents the response for GET /api/system/network
+type NetworkResponse struct {
+	Interfaces  []system.InterfaceStats `json:"interfaces"`  // physical first
+	Connections int                     `json:"connections"` // established TCP connections
+	Apps        []AppStatsResponse      `json:"apps"`        // most network first; empty without per-app stats
+}
+
+// AlertsResponse represents the response for GET /api/system/alerts
+type AlertsResponse struct {
+	Rules  []alerts.Rule   `json:"rules"`
+	Firing []alerts.Firing `json:"firing"` // most severe first
+}
+
+// LoggingResponse represents the response for GET /api/system/logging
+type LoggingResponse struct {
+	Config logship.Config `json:"config"` // password redacted
+	Status logship.Status `json:"status"`
+}
+
+// PowerScheduleResponse represents the response for GET /api/system/power
+type PowerScheduleResponse struct {
+	Config powersched.Config `json:"config"`
+	Status powersched.Status `json:"status"`
+}
+
+// UpdatesResponse represents the response for GET /api/system/updates
+type UpdatesResponse struct {
+	Policy store.UpdatePolicy `json:"policy"`
+	Status updates.Status     `json:"status"`
+}
+
+// AppUpdatesResponse represents the response for GET /api/apps/updates
+type AppUpdatesResponse struct {
+	Updates   []store.ImageUpdate `json:"updates"`
+	Available int                 `json:"available"` // apps with an update available
+}
+
+// SchedulesResponse represents the response for GET /api/system/schedules
+type SchedulesResponse struct {
+	Schedules []scheduler.Status `json:"schedules"`
+}
+
+// UpdatePreferencesRequest is the body for PUT /api/users/me/preferences.
+// Omitted fields are left unchanged.
+type UpdatePreferencesRequest struct {
+	Layout     *[]store.GridElement `json:"layout,omitempty"`
+	PinnedApps *[]string            `json:"pinned_apps,omitempty"`
+	Theme      *string              `json:"theme,omitempty"`
+	Locale     *string              `json:"locale,omitempty"` // empty to follow the browser
+}
+
+// AuditLogResponse represents the response for GET /api/system/audit
+type AuditLogResponse struct {
+	Entries []*store.AuditEntry `json:"entries"`
+}
+
+// SecretAccessLogResponse represents the response for GET /api/system/secrets/access
+type SecretAccessLogResponse struct {
+	Entries []*store.SecretAccessEntry `json:"entries"`
+}
+
+// ImportConfigResponse represents the response for POST /api/system/import
+type ImportConfigResponse struct {
+	Install  []string `json:"install"`  // apps being installed, in order
+	Skipped  []string `json:"skipped"`  // apps already installed
+	Warnings []string `json:"warnings"` // settings that could not be carried over
+	DryRun   bool     `json:"dryRun"`
+}
+
+// NotificationTestResponse represents the response for POST /api/system/notifications/test
+type NotificationTestResponse struct {
+	Results []NotificationTestResult `json:"results"`
+}
+
+// NotificationTestResult is the outcome of sending a test to one channel
+type NotificationTestResult struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PowerActionRequest is the body for POST /api/system/reboot and /api/system/shutdown
+type PowerActionRequest struct {
+	Confirm string `json:"confirm,omitempty"` // token from a previous unconfirmed request
+}
+
+// PowerActionResponse represents the response for POST /api/system/reboot and /api/system/shutdown
+type PowerActionResponse struct {
+	Action       string     `json:"action"`
+	Status       string     `json:"status"` // "confirmation-required" or "accepted"
+	ConfirmToken string     `json:"confirmToken,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+}
+
+// GenerationsResponse represents the response for GET /api/system/versions
+type GenerationsResponse struct {
+	Generations []system.Generation `json:"generations"`
+}
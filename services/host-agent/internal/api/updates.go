@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/updates"
+)
+
+// maxUpdateBundleSize bounds an uploaded offline update bundle
+const maxUpdateBundleSize = 16 << 30
+
+// updateCriticalChecks are the health summary checks an update must keep
+// passing
+var updateCriticalChecks = map[string]bool{"database": true, "authentik": true, "traefik": true}
+
+// StartUpdater finishes an update the host agent was restarted during: the
+// switch is settled and the new system verified
+func (s *Server) StartUpdater(ctx context.Context) {
+	if s.updater == nil {
+		return
+	}
+	go func() {
+		if err := s.updater.Resume(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("failed to resume update", "error", err)
+		}
+	}()
+}
+
+// verifyUpdate checks the components an update must keep working: the
+// database, Authentik, Traefik, and the API as browsers reach it through
+// Traefik. Components that aren't configured pass.
+func (s *Server) verifyUpdate(ctx context.Context) error {
+	checks := []healthCheck{{name: "api via traefik", run: s.probeAPIViaTraefik}}
+	for _, check := range s.healthChecks() {
+		if updateCriticalChecks[check.name] {
+			checks = append(checks, check)
+		}
+	}
+
+	var failed []string
+	for _, result := range runHealthChecks(ctx, checks) {
+		if result.Status == HealthFailed {
+			failed = append(failed, result.Name+": "+result.Error)
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// probeAPIViaTraefik calls the API's health endpoint through Traefik
+func (s *Server) probeAPIViaTraefik(ctx context.Context) (string, error) {
+	traefik, err := s.catalog.Get("traefik")
+	if err != nil || traefik == nil || traefik.Port == 0 {
+		return "", errSkipped
+	}
+
+	url := fmt.Sprintf("http://localhost:%d/api/health", traefik.Port)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return "HTTP 200", nil
+}
+
+// handleGetUpdates returns the automatic update policy and the outcome of
+// the last update
+func (s *Server) handleGetUpdates(w http.ResponseWriter, r *http.Request) {
+	if s.updater == nil {
+		respondError(w, http.StatusServiceUnavailable, "updates not available")
+		return
+	}
+	s.respondUpdates(w, r, http.StatusOK)
+}
+
+// handleSetUpdates replaces the automatic update policy
+func (s *Server) handleSetUpdates(w http.ResponseWriter, r *http.Request) {
+	if s.updater == nil {
+		respondError(w, http.StatusServiceUnavailable, "updates not available")
+		return
+	}
+
+	var policy store.UpdatePolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := updates.Validate(policy); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.updater.SetPolicy(policy); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to save update policy", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to save update policy")
+		return
+	}
+	s.respondUpdates(w, r, http.StatusOK)
+}
+
+// handleRollbackUpdate switches back to the system the last applied update
+// replaced, then reconciles apps against it. Switching usually restarts the
+// host agent, in which case startup reconciles instead.
+func (s *Server) handleRollbackUpdate(w http.ResponseWriter, r *http.Request) {
+	if s.updater == nil {
+		respondError(w, http.StatusServiceUnavailable, "updates not available")
+		return
+	}
+
+	s.logger.InfoContext(r.Context(), "update rollback requested")
+	err := s.updater.Rollback(r.Context())
+	if errors.Is(err, updates.ErrNoRollback) || errors.Is(err, updates.ErrBusy) {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "update rollback failed", "error", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.triggerReconcile()
+	s.respondUpdates(w, r, http.StatusOK)
+}
+
+// handleUploadUpdate updates from an offline bundle, for hosts without
+// internet access. The bundle is imported before responding; the switch
+// to it continues in the background, reported by the update status.
+func (s *Server) handleUploadUpdate(w http.ResponseWriter, r *http.Request) {
+	if s.updater == nil {
+		respondError(w, http.StatusServiceUnavailable, "updates not available")
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != "application/x-tar" && contentType != "application/gzip" {
+		respondError(w, http.StatusUnsupportedMediaType, "bundle must be application/x-tar or application/gzip")
+		return
+	}
+	// A bundle holds a whole system closure, so it takes longer than the
+	// server's read timeout to upload
+	if err := http.NewResponseController(w).SetReadDeadline(time.Time{}); err != nil {
+		s.logger.WarnContext(r.Context(), "failed to clear read deadline for update upload", "error", err)
+	}
+
+	s.logger.InfoContext(r.Context(), "offline update uploaded")
+	err := s.updater.ApplyBundle(r.Context(), http.MaxBytesReader(w, r.Body, maxUpdateBundleSize))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		respondError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("bundle exceeds %d bytes", maxUpdateBundleSize))
+		return
+	case errors.Is(err, updates.ErrInvalidBundle):
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, updates.ErrBusy):
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, updates.ErrNoUpdateKeys):
+		respondError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		s.logger.ErrorContext(r.Context(), "offline update failed", "error", err)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.respondUpdates(w, r, http.StatusAccepted)
+}
+
+func (s *Server) respondUpdates(w http.ResponseWriter, r *http.Request, status int) {
+	policy, err := s.updater.Policy()
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to get update policy", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get update policy")
+		return
+	}
+	st, err := s.updater.Status()
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to get update status", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get update status")
+		return
+	}
+	respondJSON(w, status, UpdatesResponse{Policy: policy, Status: st})
+}
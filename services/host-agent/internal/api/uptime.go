@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/watchdog"
+	"github.com/go-chi/chi/v5"
+)
+
+const defaultUptimeDays = 30
+
+// uptimeViews are the windows, in days, /uptime can summarize
+var uptimeViews = map[int]bool{30: true, 90: true}
+
+// StartUptimeMonitor runs the app watchdog: it health checks every running
+// app each interval, keeping the results for uptime history. Apps that keep
+// failing are marked as in error, apps in error that pass again are marked
+// running, and apps stuck installing or starting are failed. The watchdog
+// settings are read each round, so changes apply from the next one.
+func (s *Server) StartUptimeMonitor(ctx context.Context) {
+	if s.healthCheckStore == nil {
+		return
+	}
+
+	go func() {
+		interval := s.watchdogConfig().CheckInterval()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		failures := make(map[string]int)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if !s.healthChecksPaused(now) {
+					s.checkUptime(ctx, failures, now)
+				}
+				if next := s.watchdogConfig().CheckInterval(); next != interval {
+					interval = next
+					ticker.Reset(interval)
+				}
+				if err := s.healthCheckStore.Prune(now); err != nil {
+					s.logger.Warn("failed to prune health checks", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// watchdogConfig returns the watchdog settings, or the defaults without
+// persisted settings
+func (s *Server) watchdogConfig() watchdog.Config {
+	if s.watchdog == nil {
+		return watchdog.DefaultConfig()
+	}
+	return s.watchdog.Config()
+}
+
+// checkUptime runs one round of the watchdog. failures counts each app's
+// consecutive failed checks across rounds.
+func (s *Server) checkUptime(ctx context.Context, failures map[string]int, now time.Time) {
+	apps, err := s.appStore.GetAll()
+	if err != nil {
+		s.logger.Warn("failed to get apps for health checks", "error", err)
+		return
+	}
+	cfg := s.watchdogConfig()
+	s.failStuckApps(apps, cfg, now)
+
+	type result struct {
+		healthy, checked bool
+		err              error
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	results := make(map[string]result)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, app := range apps {
+		// Installs, uninstalls and restarts check health themselves
+		if app.UninstalledAt != nil || (app.Status != "running" && app.Status != "error") || cfg.Excluded(app.Name) {
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			healthy, checked, err := s.checkAppHealth(client, name)
+			mu.Lock()
+			results[name] = result{healthy, checked, err}
+			mu.Unlock()
+		}(app.Name)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	for _, app := range apps {
+		r, ok := results[app.Name]
+		if !ok || !r.checked {
+			delete(failures, app.Name)
+			continue
+		}
+		s.recordHealthCheck(app.Name, r.healthy, now)
+
+		if r.healthy {
+			delete(failures, app.Name)
+			if app.Status == "error" {
+				s.logger.Info("app recovered", "app", app.Name)
+				s.appStore.UpdateStatus(app.Name, "running")
+			}
+			continue
+		}
+		// A freshly started app may still be warming up
+		if app.Status == "running" && now.Sub(app.UpdatedAt) < cfg.StartGrace() {
+			continue
+		}
+		failures[app.Name]++
+		if app.Status == "running" && failures[app.Name] >= cfg.FailuresBeforeError {
+			s.logger.Warn("app failing health checks, marking as error", "app", app.Name, "error", r.err)
+			s.appStore.UpdateStatusReason(app.Name, "error", "health check failed")
+		}
+	}
+}
+
+// failStuckApps marks apps that have been installing or starting for longer
+// than the watchdog allows as in error. The orchestrator normally moves them
+// on; an app stays stuck when that was interrupted.
+func (s *Server) failStuckApps(apps []*store.InstalledApp, cfg watchdog.Config, now time.Time) {
+	for _, app := range apps {
+		if app.UninstalledAt != nil || cfg.Excluded(app.Name) {
+			continue
+		}
+		var reason string
+		switch {
+		case app.Status == "installing" && now.Sub(app.UpdatedAt) > cfg.InstallingTimeout():
+			reason = "install timed out"
+		case app.Status == "starting" && now.Sub(app.UpdatedAt) > cfg.StartingTimeout():
+			reason = "start timed out"
+		default:
+			continue
+		}
+		s.logger.Warn("app stuck, marking as error", "app", app.Name, "status", app.Status, "since", app.UpdatedAt)
+		if err := s.appStore.UpdateStatusReason(app.Name, "error", reason); err != nil {
+			s.logger.Warn("failed to update app status", "app", app.Name, "error", err)
+			continue
+		}
+		app.Status = "error"
+	}
+}
+
+// recordHealthCheck records a health check in the metrics and the uptime
+// history
+func (s *Server) recordHealthCheck(app string, healthy bool, at time.Time) {
+	metrics.RecordHealthCheck(app, healthy)
+	if s.healthCheckStore == nil {
+		return
+	}
+	if err := s.healthCheckStore.Record(app, healthy, at); err != nil {
+		s.logger.Warn("failed to record health check", "app", app, "error", err)
+	}
+}
+
+// handleAppUptime returns an app's uptime percentage, daily uptime and
+// downtime incidents over the last 30 or 90 days (?days=, default 30)
+func (s *Server) handleAppUptime(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if s.healthCheckStore == nil {
+		respondError(w, http.StatusServiceUnavailable, "uptime history not available")
+		return
+	}
+
+	days := defaultUptimeDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || !uptimeViews[n] {
+			respondError(w, http.StatusBadRequest, "days must be 30 or 90")
+			return
+		}
+		days = n
+	}
+
+	app, err := s.appStore.GetByName(name)
+	if err != nil {
+		s.logger.Error("failed to get app", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get app")
+		return
+	}
+	if app == nil {
+		respondError(w, http.StatusNotFound, "app not found")
+		return
+	}
+
+	now := time.Now().UTC()
+	since := now.Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	checks, err := s.healthCheckStore.Checks(name, since)
+	if err != nil {
+		s.logger.Error("failed to get health checks", "app", name, "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to get uptime")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AppUptimeResponse{
+		App:    name,
+		Days:   days,
+		Uptime: store.ComputeUptime(checks, days, now),
+	})
+}
+
+// handleGetWatchdog returns the app watchdog settings
+func (s *Server) handleGetWatchdog(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, s.watchdogConfig())
+}
+
+// handleSetWatchdog replaces the app watchdog settings
+func (s *Server) handleSetWatchdog(w http.ResponseWriter, r *http.Request) {
+	if s.watchdog == nil {
+		respondError(w, http.StatusServiceUnavailable, "watchdog settings not available")
+		return
+	}
+
+	var cfg watchdog.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.watchdog.Set(cfg); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to save watchdog config", "error", err)
+		respondError(w, http.StatusInternalServerError, "failed to save watchdog config")
+		return
+	}
+	respondJSON(w, http.StatusOK, s.watchdogConfig())
+}
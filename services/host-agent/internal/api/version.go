@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// API versioning. Response shapes may only change incompatibly in a new
+// version; clients pin one with the /api/vN prefix or, on the unprefixed
+// /api routes, with the Bloud-API-Version header.
+const (
+	apiVersionHeader  = "Bloud-API-Version"
+	currentAPIVersion = "1"
+)
+
+// supportedAPIVersions lists every version the server can still answer
+var supportedAPIVersions = []string{"1"}
+
+// apiVersionMiddleware resolves the API version of a request and echoes it in
+// the Bloud-API-Version response header. pinned is the version from the path
+// prefix; a conflicting header is rejected. Without a prefix the header picks
+// the version, defaulting to the current one.
+func apiVersionMiddleware(pinned string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested := strings.TrimPrefix(strings.TrimSpace(r.Header.Get(apiVersionHeader)), "v")
+
+			version := pinned
+			switch {
+			case pinned != "" && requested != "" && requested != pinned:
+				respondError(w, http.StatusBadRequest, fmt.Sprintf("%s %q conflicts with /api/v%s", apiVersionHeader, requested, pinned))
+				return
+			case pinned == "" && requested != "":
+				version = requested
+			case pinned == "":
+				version = currentAPIVersion
+			}
+
+			if !slices.Contains(supportedAPIVersions, version) {
+				w.Header().Set(apiVersionHeader, currentAPIVersion)
+				respondError(w, http.StatusNotAcceptable, fmt.Sprintf("unsupported API version %q (supported: %s)", version, strings.Join(supportedAPIVersions, ", ")))
+				return
+			}
+
+			w.Header().Set(apiVersionHeader, version)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
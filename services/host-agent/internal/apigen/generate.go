@@ -0,0 +1,367 @@
+// Package apigen generates the Go API client in pkg/apiclient from the
+// host-agent OpenAPI document.
+package apigen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const refPrefix = "#/components/schemas/"
+
+// document is the subset of OpenAPI 3 the generator understands
+type document struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string `json:"operationId"`
+	Summary     string `json:"summary"`
+	BrowserOnly bool   `json:"x-browser-only"`
+	WebSocket   bool   `json:"x-websocket"`
+	Parameters  []struct {
+		Name string `json:"name"`
+		In   string `json:"in"`
+	} `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]mediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]mediaType `json:"content"`
+	} `json:"responses"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+}
+
+// generator accumulates output and the imports it needs
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+// Generate renders a Go client package from an OpenAPI document
+func Generate(spec []byte, pkg string) ([]byte, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	g := &generator{imports: map[string]bool{}}
+
+	// Types
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.printf("// %s is generated from the %s schema\n", goName(name), name)
+		g.printf("type %s %s\n\n", goName(name), g.typeExpr(doc.Components.Schemas[name]))
+	}
+
+	// Operations, ordered by path then method
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		methods := make([]string, 0, len(doc.Paths[path]))
+		for method := range doc.Paths[path] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			op := doc.Paths[path][method]
+			if op.BrowserOnly || op.WebSocket {
+				continue
+			}
+			if err := g.operation(strings.ToUpper(method), path, op); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by apigen from the host-agent OpenAPI document. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	imports := []string{"context", "net/http"}
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	out.WriteString("import (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n\n")
+	out.Write(g.buf.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated client: %w", err)
+	}
+	return formatted, nil
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// operation emits a client method for a single operation
+func (g *generator) operation(method, path string, op *operation) error {
+	if op.OperationID == "" {
+		return fmt.Errorf("%s %s has no operationId", method, path)
+	}
+
+	args := []string{"ctx context.Context"}
+	hasQuery := false
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			args = append(args, lowerFirst(goName(p.Name))+" string")
+		case "query":
+			hasQuery = true
+		}
+	}
+	if hasQuery {
+		args = append(args, "query url.Values")
+		g.imports["net/url"] = true
+	}
+
+	body := "nil"
+	if op.RequestBody != nil {
+		if mt, ok := op.RequestBody.Content["application/json"]; ok && mt.Schema != nil {
+			args = append(args, "body "+g.typeExpr(mt.Schema))
+			body = "body"
+		} else if isUpload(op) {
+			args = append(args, "body Upload")
+			body = "body"
+		}
+	}
+
+	// Build the path expression, escaping each parameter
+	var pathExpr []string
+	last := 0
+	for _, m := range pathParamPattern.FindAllStringSubmatchIndex(path, -1) {
+		pathExpr = append(pathExpr, fmt.Sprintf("%q", path[last:m[0]]))
+		pathExpr = append(pathExpr, fmt.Sprintf("url.PathEscape(%s)", lowerFirst(goName(path[m[2]:m[3]]))))
+		g.imports["net/url"] = true
+		last = m[1]
+	}
+	if last < len(path) {
+		pathExpr = append(pathExpr, fmt.Sprintf("%q", path[last:]))
+	}
+	fullPath := strings.Join(pathExpr, "+")
+	if hasQuery {
+		fullPath = fmt.Sprintf("withQuery(%s, query)", fullPath)
+	}
+
+	name := goName(op.OperationID)
+	result := successSchema(op)
+
+	g.printf("// %s calls %s %s: %s\n", name, method, path, lowerFirst(op.Summary))
+	if result == nil {
+		g.printf("// The caller must close the response body.\n")
+		g.printf("func (c *Client) %s(%s) (*http.Response, error) {\n", name, strings.Join(args, ", "))
+		g.printf("return c.doRaw(ctx, %q, %s, %s)\n}\n\n", method, fullPath, body)
+		return nil
+	}
+
+	resultType := g.typeExpr(result)
+	ret, value := resultType, "out"
+	switch {
+	case result.Ref != "":
+		ret, value = "*"+resultType, "&out"
+	case strings.HasPrefix(resultType, "[]"), strings.HasPrefix(resultType, "map["):
+	default:
+		return fmt.Errorf("%s %s: unsupported response type %s", method, path, resultType)
+	}
+	g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), ret)
+	g.printf("var out %s\n", resultType)
+	g.printf("if err := c.doJSON(ctx, %q, %s, %s, &out); err != nil {\nreturn nil, err\n}\n", method, fullPath, body)
+	g.printf("return %s, nil\n}\n\n", value)
+	return nil
+}
+
+// isUpload reports whether the request body is raw binary (e.g. an image)
+// rather than JSON; such operations take an Upload carrying the media type
+func isUpload(op *operation) bool {
+	for _, mt := range op.RequestBody.Content {
+		if mt.Schema != nil && mt.Schema.Type == "string" && mt.Schema.Format == "binary" {
+			return true
+		}
+	}
+	return false
+}
+
+// successSchema returns the JSON schema of the 2xx response, or nil when the
+// response is not JSON (streams, images, empty bodies)
+func successSchema(op *operation) *schema {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		if mt, ok := op.Responses[code].Content["application/json"]; ok && mt.Schema != nil {
+			if mt.Schema.Ref == "" && mt.Schema.Type == "" {
+				return nil
+			}
+			return mt.Schema
+		}
+	}
+	return nil
+}
+
+// typeExpr returns the Go type for a schema
+func (g *generator) typeExpr(s *schema) string {
+	if s == nil {
+		return "any"
+	}
+	if s.Ref != "" {
+		return goName(strings.TrimPrefix(s.Ref, refPrefix))
+	}
+
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		if s.Format == "int64" {
+			return "int64"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.typeExpr(s.Items)
+	case "object":
+		if s.Properties == nil && s.AdditionalProperties != nil {
+			return "map[string]" + g.typeExpr(s.AdditionalProperties)
+		}
+		return g.structExpr(s)
+	default:
+		return "any"
+	}
+}
+
+// structExpr renders an object schema as a struct type
+func (g *generator) structExpr(s *schema) string {
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+
+	props := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		props = append(props, name)
+	}
+	sort.Strings(props)
+
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	for _, name := range props {
+		prop := s.Properties[name]
+		typ := g.typeExpr(prop)
+		tag := name
+		if !required[name] {
+			tag += ",omitempty"
+			if prop.Ref != "" {
+				typ = "*" + typ
+			}
+		}
+		fmt.Fprintf(&b, "%s %s `json:%q`\n", goName(name), typ, tag)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// initialisms are upper-cased whole when they appear as a word
+var initialisms = map[string]bool{
+	"api": true, "cpu": true, "db": true, "dns": true, "gpu": true, "http": true,
+	"id": true, "ip": true, "json": true, "ldap": true, "oidc": true, "sse": true,
+	"sso": true, "uri": true, "url": true, "uuid": true,
+}
+
+// goName converts a JSON or schema name into an exported Go identifier
+func goName(name string) string {
+	var words []string
+	var cur []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			if len(cur) > 0 {
+				words = append(words, string(cur))
+			}
+			cur = nil
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			words = append(words, string(cur))
+			cur = nil
+		}
+		cur = append(cur, r)
+	}
+	if len(cur) > 0 {
+		words = append(words, string(cur))
+	}
+
+	var b strings.Builder
+	for _, w := range words {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	// Keep leading initialisms consistent: "ID" -> "id"
+	for i := range r {
+		if i > 0 && i < len(r)-1 && unicode.IsLower(r[i+1]) {
+			break
+		}
+		if !unicode.IsUpper(r[i]) {
+			break
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
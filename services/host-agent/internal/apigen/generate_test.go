@@ -0,0 +1,112 @@
+package apigen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"name":           "Name",
+		"display_name":   "DisplayName",
+		"id":             "ID",
+		"loginUrl":       "LoginURL",
+		"clientId":       "ClientID",
+		"indexedDB":      "IndexedDB",
+		"getOpenAPISpec": "GetOpenAPISpec",
+		"plan-install":   "PlanInstall",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, goName(in), in)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	spec := []byte(`{
+		"paths": {
+			"/api/things/{thingName}": {
+				"get": {
+					"operationId": "getThing",
+					"summary": "Get a thing",
+					"parameters": [{"name": "thingName", "in": "path"}],
+					"responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Thing"}}}}}
+				},
+				"put": {
+					"operationId": "putThing",
+					"summary": "Replace a thing",
+					"parameters": [{"name": "thingName", "in": "path"}],
+					"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Thing"}}}},
+					"responses": {"204": {}}
+				}
+			},
+			"/api/things/{thingName}/picture": {
+				"put": {
+					"operationId": "setThingPicture",
+					"summary": "Upload a picture",
+					"parameters": [{"name": "thingName", "in": "path"}],
+					"requestBody": {"content": {"image/png": {"schema": {"type": "string", "format": "binary"}}}},
+					"responses": {"204": {}}
+				}
+			},
+			"/api/things": {
+				"get": {
+					"operationId": "listThings",
+					"summary": "List things",
+					"parameters": [{"name": "tag", "in": "query"}],
+					"responses": {"200": {"content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Thing"}}}}}}
+				}
+			},
+			"/auth/login": {
+				"get": {"operationId": "login", "x-browser-only": true, "responses": {"302": {}}}
+			}
+		},
+		"components": {"schemas": {"Thing": {
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"created_at": {"type": "string", "format": "date-time"},
+				"tags": {"type": "array", "items": {"type": "string"}}
+			},
+			"required": ["name"]
+		}}}
+	}`)
+
+	out, err := Generate(spec, "things")
+	require.NoError(t, err)
+	src := string(out)
+
+	assert.Contains(t, src, "package things")
+	assert.Contains(t, src, "CreatedAt time.Time `json:\"created_at,omitempty\"`")
+	assert.Contains(t, src, "Name      string    `json:\"name\"`")
+	assert.Contains(t, src, "func (c *Client) GetThing(ctx context.Context, thingName string) (*Thing, error)")
+	assert.Contains(t, src, `"/api/things/"+url.PathEscape(thingName)`)
+	assert.Contains(t, src, "func (c *Client) PutThing(ctx context.Context, thingName string, body Thing) (*http.Response, error)")
+	assert.Contains(t, src, "func (c *Client) SetThingPicture(ctx context.Context, thingName string, body Upload) (*http.Response, error)")
+	assert.Contains(t, src, "func (c *Client) ListThings(ctx context.Context, query url.Values) ([]Thing, error)")
+	assert.Contains(t, src, `withQuery("/api/things", query)`)
+	assert.NotContains(t, src, "Login")
+}
+
+// TestGeneratedClientUpToDate fails when routes or API types change without
+// regenerating pkg/apiclient (go generate ./pkg/apiclient)
+func TestGeneratedClientUpToDate(t *testing.T) {
+	spec, err := api.OpenAPISpec()
+	require.NoError(t, err)
+
+	client, err := Generate(spec, "apiclient")
+	require.NoError(t, err)
+
+	dir := filepath.Join("..", "..", "pkg", "apiclient")
+	checkedIn, err := os.ReadFile(filepath.Join(dir, "client_gen.go"))
+	require.NoError(t, err)
+	assert.Equal(t, string(client), string(checkedIn), "pkg/apiclient/client_gen.go is stale; run go generate ./pkg/apiclient")
+
+	checkedInSpec, err := os.ReadFile(filepath.Join(dir, "openapi.json"))
+	require.NoError(t, err)
+	assert.Equal(t, string(spec)+"\n", string(checkedInSpec), "pkg/apiclient/openapi.json is stale; run go generate ./pkg/apiclient")
+}
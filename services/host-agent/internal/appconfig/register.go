@@ -18,6 +18,7 @@ import (
 	"codeberg.org/d-buckner/bloud-v3/apps/sonarr"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/config"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/configurator"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/provisioner"
 )
 
 // RegisterAll registers all available configurators with the registry.
@@ -45,3 +46,9 @@ func RegisterAll(registry *configurator.Registry, cfg *config.Config) {
 	registry.Register(jellyfin.NewConfigurator(8096, fmt.Sprintf("http://localhost:%d", cfg.AuthentikPort), cfg.AuthentikToken))
 	registry.Register(jellyseerr.NewConfigurator(5055))
 }
+
+// RegisterProvisioners registers all available user provisioners with the registry.
+// Provisioners are only used for apps that are installed.
+func RegisterProvisioners(registry *provisioner.Registry, cfg *config.Config) {
+	registry.Register(jellyfin.NewProvisioner(8096, filepath.Join(cfg.DataDir, "jellyfin")))
+}
@@ -0,0 +1,148 @@
+// Package bundle defines the signed configuration bundle used to move a Bloud
+// installation to new hardware.
+//
+// A bundle records what is installed and how it is wired together, not the
+// data itself. Secrets are listed by name only: the secrets file has to be
+// copied separately, and since the signing key is derived from the SSO host
+// secret, a bundle only verifies on a host that shares that secrets file.
+package bundle
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// FormatVersion is the bundle format written by this version of the host agent
+const FormatVersion = 1
+
+// ErrInvalidSignature is returned when a bundle was not signed with the expected key
+var ErrInvalidSignature = errors.New("bundle signature is invalid")
+
+// Bundle is a whole-system configuration export
+type Bundle struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Routing   Routing   `json:"routing"`
+	Apps      []App     `json:"apps"`
+	Signature string    `json:"signature,omitempty"` // hex HMAC-SHA256 of the bundle without this field
+}
+
+// Routing holds the externally visible URLs of the host
+type Routing struct {
+	BaseURL      string `json:"baseUrl"`
+	AuthentikURL string `json:"authentikUrl"`
+}
+
+// App is one installed app and its integration choices
+type App struct {
+	Name        string            `json:"name"`
+	DisplayName string            `json:"displayName"`
+	Version     string            `json:"version,omitempty"`
+	Port        int               `json:"port,omitempty"`
+	System      bool              `json:"system,omitempty"`  // managed by NixOS, not replayed on import
+	Choices     map[string]string `json:"choices,omitempty"` // integration -> chosen app
+	Secrets     []string          `json:"secrets,omitempty"` // names of secrets the app had, e.g. adminPassword
+}
+
+// SigningKey derives the bundle signing key from the host's SSO secret
+func SigningKey(hostSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(hostSecret))
+	mac.Write([]byte("bloud-config-bundle"))
+	return mac.Sum(nil)
+}
+
+// Sign sets the bundle's signature
+func (b *Bundle) Sign(key []byte) error {
+	sig, err := b.signature(key)
+	if err != nil {
+		return err
+	}
+	b.Signature = sig
+	return nil
+}
+
+// Verify checks the bundle's signature and format version
+func (b *Bundle) Verify(key []byte) error {
+	if b.Signature == "" {
+		return fmt.Errorf("bundle is not signed")
+	}
+	expected, err := b.signature(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(b.Signature)) {
+		return ErrInvalidSignature
+	}
+	if b.Version != FormatVersion {
+		return fmt.Errorf("unsupported bundle version %d (expected %d)", b.Version, FormatVersion)
+	}
+	return nil
+}
+
+// signature computes the HMAC over the bundle's JSON encoding with the
+// signature field cleared. encoding/json sorts map keys, so the encoding is
+// stable across export and import.
+func (b *Bundle) signature(key []byte) (string, error) {
+	unsigned := *b
+	unsigned.Signature = ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// InstallOrder returns the non-system apps ordered so that every app comes
+// after the apps it chose for its integrations. Choices pointing outside the
+// bundle (or at system apps) don't constrain the order.
+func (b *Bundle) InstallOrder() ([]App, error) {
+	pending := make(map[string]App)
+	for _, app := range b.Apps {
+		if !app.System {
+			pending[app.Name] = app
+		}
+	}
+
+	var order []App
+	for len(pending) > 0 {
+		// Pick the ready apps in name order so the result is deterministic
+		var ready []string
+		for name, app := range pending {
+			if !dependsOnPending(app, pending) {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			names := make([]string, 0, len(pending))
+			for name := range pending {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			return nil, fmt.Errorf("integration choices form a cycle between %v", names)
+		}
+
+		slices.Sort(ready)
+		for _, name := range ready {
+			order = append(order, pending[name])
+			delete(pending, name)
+		}
+	}
+	return order, nil
+}
+
+func dependsOnPending(app App, pending map[string]App) bool {
+	for _, provider := range app.Choices {
+		if _, ok := pending[provider]; ok && provider != app.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package bundle
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle() *Bundle {
+	return &Bundle{
+		Version:   FormatVersion,
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Routing:   Routing{BaseURL: "http://bloud.local"},
+		Apps: []App{
+			{Name: "postgres", DisplayName: "PostgreSQL", System: true},
+			{Name: "radarr", DisplayName: "Radarr", Choices: map[string]string{"downloadClient": "qbittorrent"}},
+			{Name: "qbittorrent", DisplayName: "qBittorrent"},
+			{Name: "jellyseerr", DisplayName: "Requests", Choices: map[string]string{"radarr": "radarr", "database": "postgres"}, Secrets: []string{"adminPassword"}},
+		},
+	}
+}
+
+func TestBundle_SignVerify(t *testing.T) {
+	key := SigningKey("host-secret")
+	b := testBundle()
+	require.NoError(t, b.Sign(key))
+	assert.Len(t, b.Signature, 64)
+
+	// Survives a JSON round trip
+	data, err := json.Marshal(b)
+	require.NoError(t, err)
+	var decoded Bundle
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.NoError(t, decoded.Verify(key))
+
+	// Wrong key
+	assert.ErrorIs(t, decoded.Verify(SigningKey("other-secret")), ErrInvalidSignature)
+
+	// Tampered content
+	decoded.Apps[1].Choices["downloadClient"] = "transmission"
+	assert.ErrorIs(t, decoded.Verify(key), ErrInvalidSignature)
+
+	// Unsigned
+	assert.Error(t, testBundle().Verify(key))
+}
+
+func TestBundle_VerifyRejectsOtherVersions(t *testing.T) {
+	key := SigningKey("host-secret")
+	b := testBundle()
+	b.Version = FormatVersion + 1
+	require.NoError(t, b.Sign(key))
+	assert.ErrorContains(t, b.Verify(key), "unsupported bundle version")
+}
+
+func TestBundle_InstallOrder(t *testing.T) {
+	order, err := testBundle().InstallOrder()
+	require.NoError(t, err)
+
+	var names []string
+	for _, app := range order {
+		names = append(names, app.Name)
+	}
+	assert.Equal(t, []string{"qbittorrent", "radarr", "jellyseerr"}, names)
+}
+
+func TestBundle_InstallOrderCycle(t *testing.T) {
+	b := &Bundle{Apps: []App{
+		{Name: "a", Choices: map[string]string{"x": "b"}},
+		{Name: "b", Choices: map[string]string{"y": "a"}},
+	}}
+	_, err := b.InstallOrder()
+	assert.ErrorContains(t, err, "cycle")
+}
@@ -85,6 +85,9 @@ func (l *Loader) validateApp(app *App) error {
 	if app.Category == "" {
 		return fmt.Errorf("category is required")
 	}
+	if err := validateSettings(app.Settings); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -20,6 +20,8 @@ type App struct {
 	Tags          []string               `yaml:"tags" json:"tags"`
 	Routing       *Routing               `yaml:"routing,omitempty" json:"routing,omitempty"`
 	Bootstrap     *BootstrapConfig       `yaml:"bootstrap,omitempty" json:"bootstrap,omitempty"`
+	Settings      []Setting              `yaml:"settings,omitempty" json:"settings,omitempty"`
+	ImagePin      string                 `yaml:"imagePin,omitempty" json:"imagePin,omitempty"` // tag or sha256 digest the image is held at
 }
 
 // Resources defines resource requirements for an app
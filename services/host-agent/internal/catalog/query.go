@@ -0,0 +1,94 @@
+package catalog
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AppSortKeys are the fields catalog listings can be sorted by
+var AppSortKeys = []string{"name", "displayName", "category"}
+
+// AppQuery filters and sorts a catalog listing. Zero values match everything.
+type AppQuery struct {
+	Category  string
+	Tag       string
+	Search    string          // case-insensitive match on name, display name, description and tags
+	Installed *bool           // filter by installed state
+	Sort      string          // one of AppSortKeys (default "name")
+	Desc      bool            // reverse the sort order
+	installed map[string]bool // set by WithInstalled
+}
+
+// WithInstalled supplies the installed app names used by the Installed filter
+func (q AppQuery) WithInstalled(names []string) AppQuery {
+	q.installed = make(map[string]bool, len(names))
+	for _, name := range names {
+		q.installed[name] = true
+	}
+	return q
+}
+
+// Validate reports an unknown sort key
+func (q AppQuery) Validate() error {
+	if q.Sort != "" && !slices.Contains(AppSortKeys, q.Sort) {
+		return fmt.Errorf("unknown sort key %q (expected one of %s)", q.Sort, strings.Join(AppSortKeys, ", "))
+	}
+	return nil
+}
+
+// FilterApps returns the apps matching q, sorted. The input is not modified.
+func FilterApps(apps []*App, q AppQuery) []*App {
+	search := strings.ToLower(strings.TrimSpace(q.Search))
+
+	result := make([]*App, 0, len(apps))
+	for _, app := range apps {
+		if q.Category != "" && !strings.EqualFold(app.Category, q.Category) {
+			continue
+		}
+		if q.Tag != "" && !slices.ContainsFunc(app.Tags, func(t string) bool { return strings.EqualFold(t, q.Tag) }) {
+			continue
+		}
+		if q.Installed != nil && q.installed[app.Name] != *q.Installed {
+			continue
+		}
+		if search != "" && !appMatches(app, search) {
+			continue
+		}
+		result = append(result, app)
+	}
+
+	key := func(app *App) string {
+		switch q.Sort {
+		case "displayName":
+			return strings.ToLower(app.DisplayName)
+		case "category":
+			return strings.ToLower(app.Category)
+		default:
+			return app.Name
+		}
+	}
+	slices.SortStableFunc(result, func(a, b *App) int {
+		c := strings.Compare(key(a), key(b))
+		if c == 0 {
+			c = strings.Compare(a.Name, b.Name)
+		}
+		if q.Desc {
+			return -c
+		}
+		return c
+	})
+
+	return result
+}
+
+// appMatches reports whether a lower-cased search term appears in the app's text fields
+func appMatches(app *App, search string) bool {
+	fields := append([]string{app.Name, app.DisplayName, app.Description}, app.Tags...)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), search) {
+			return true
+		}
+	}
+	return false
+}
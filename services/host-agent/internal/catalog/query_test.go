@@ -0,0 +1,56 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func queryTestApps() []*App {
+	return []*App{
+		{Name: "radarr", DisplayName: "Radarr", Category: "media", Description: "Movie collection manager", Tags: []string{"arr"}},
+		{Name: "miniflux", DisplayName: "Miniflux", Category: "productivity", Description: "Minimalist feed reader", Tags: []string{"rss"}},
+		{Name: "jellyfin", DisplayName: "Jellyfin", Category: "media", Description: "Media server", Tags: []string{"streaming"}},
+		{Name: "sonarr", DisplayName: "Sonarr", Category: "media", Description: "TV series manager", Tags: []string{"arr"}},
+	}
+}
+
+func names(apps []*App) []string {
+	var out []string
+	for _, app := range apps {
+		out = append(out, app.Name)
+	}
+	return out
+}
+
+func TestFilterApps(t *testing.T) {
+	installed := true
+	notInstalled := false
+
+	tests := []struct {
+		name  string
+		query AppQuery
+		want  []string
+	}{
+		{"default sorts by name", AppQuery{}, []string{"jellyfin", "miniflux", "radarr", "sonarr"}},
+		{"category", AppQuery{Category: "Media"}, []string{"jellyfin", "radarr", "sonarr"}},
+		{"tag", AppQuery{Tag: "arr"}, []string{"radarr", "sonarr"}},
+		{"search description", AppQuery{Search: "feed"}, []string{"miniflux"}},
+		{"search tag", AppQuery{Search: "STREAM"}, []string{"jellyfin"}},
+		{"installed", AppQuery{Installed: &installed}.WithInstalled([]string{"sonarr"}), []string{"sonarr"}},
+		{"not installed", AppQuery{Installed: &notInstalled}.WithInstalled([]string{"sonarr"}), []string{"jellyfin", "miniflux", "radarr"}},
+		{"sort by category then name", AppQuery{Sort: "category"}, []string{"jellyfin", "radarr", "sonarr", "miniflux"}},
+		{"descending", AppQuery{Desc: true}, []string{"sonarr", "radarr", "miniflux", "jellyfin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, names(FilterApps(queryTestApps(), tt.query)))
+		})
+	}
+}
+
+func TestAppQuery_Validate(t *testing.T) {
+	assert.NoError(t, AppQuery{Sort: "displayName"}.Validate())
+	assert.Error(t, AppQuery{Sort: "popularity"}.Validate())
+}
@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Field weights for search ranking. An exact token match scores the full
+// weight, a prefix match ("jelly" for "jellyfin") half of it.
+const (
+	searchWeightName        = 10 // name and display name
+	searchWeightTag         = 6
+	searchWeightCategory    = 4
+	searchWeightDescription = 2
+)
+
+// SearchResult is a catalog app ranked against a search query
+type SearchResult struct {
+	App     *App     `json:"app"`
+	Score   float64  `json:"score"`
+	Matched []string `json:"matched"` // fields that matched: name, tags, category, description
+}
+
+// Tokenize splits text into lower-case words, breaking on anything that
+// isn't a letter or digit ("adguard-home" becomes "adguard", "home")
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchField is a tokenized app field with its ranking weight
+type searchField struct {
+	name   string
+	weight float64
+	tokens []string
+}
+
+// Search ranks apps against a free-text query. Every query token has to
+// match some field of an app for it to be included; results are ordered by
+// score, then name. The input is not modified.
+func Search(apps []*App, query string) []SearchResult {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return []SearchResult{}
+	}
+
+	results := []SearchResult{}
+	for _, app := range apps {
+		fields := []searchField{
+			{"name", searchWeightName, append(Tokenize(app.Name), Tokenize(app.DisplayName)...)},
+			{"tags", searchWeightTag, Tokenize(strings.Join(app.Tags, " "))},
+			{"category", searchWeightCategory, Tokenize(app.Category)},
+			{"description", searchWeightDescription, Tokenize(app.Description)},
+		}
+
+		var score float64
+		var matched []string
+		for _, term := range terms {
+			best, field := 0.0, ""
+			for _, f := range fields {
+				if s := matchTokens(f.tokens, term) * f.weight; s > best {
+					best, field = s, f.name
+				}
+			}
+			if best == 0 {
+				score = 0
+				break
+			}
+			score += best
+			if !slices.Contains(matched, field) {
+				matched = append(matched, field)
+			}
+		}
+		if score > 0 {
+			results = append(results, SearchResult{App: app, Score: score, Matched: matched})
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.App.Name, b.App.Name)
+	})
+	return results
+}
+
+// matchTokens returns 1 if term equals one of tokens, 0.5 if it prefixes
+// one, and 0 otherwise
+func matchTokens(tokens []string, term string) float64 {
+	best := 0.0
+	for _, token := range tokens {
+		if token == term {
+			return 1
+		}
+		if strings.HasPrefix(token, term) {
+			best = 0.5
+		}
+	}
+	return best
+}
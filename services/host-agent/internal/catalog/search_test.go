@@ -0,0 +1,60 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTestApps() []*App {
+	return []*App{
+		{Name: "jellyfin", DisplayName: "Jellyfin", Category: "media", Description: "Free software media system for streaming movies", Tags: []string{"video", "streaming"}},
+		{Name: "radarr", DisplayName: "Radarr", Category: "media", Description: "Movie collection manager", Tags: []string{"movies", "automation"}},
+		{Name: "miniflux", DisplayName: "Miniflux", Category: "productivity", Description: "Minimalist feed reader", Tags: []string{"rss"}},
+		{Name: "adguard-home", DisplayName: "AdGuard Home", Category: "security", Description: "Network-wide ad blocking"},
+	}
+}
+
+func resultNames(results []SearchResult) []string {
+	var out []string
+	for _, r := range results {
+		out = append(out, r.App.Name)
+	}
+	return out
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"adguard", "home"}, Tokenize("AdGuard-Home"))
+	assert.Equal(t, []string{"network", "wide", "ad", "blocking"}, Tokenize("Network-wide ad blocking."))
+	assert.Empty(t, Tokenize("  -- "))
+}
+
+func TestSearch_Ranking(t *testing.T) {
+	apps := searchTestApps()
+
+	// A tag match outranks a description match
+	results := Search(apps, "movies")
+	assert.Equal(t, []string{"radarr", "jellyfin"}, resultNames(results))
+	assert.Equal(t, []string{"tags"}, results[0].Matched)
+	assert.Equal(t, []string{"description"}, results[1].Matched)
+
+	// Prefixes match names
+	assert.Equal(t, []string{"jellyfin"}, resultNames(Search(apps, "jelly")))
+
+	// Category matches every app in it
+	assert.Equal(t, []string{"jellyfin", "radarr"}, resultNames(Search(apps, "media")))
+
+	// Hyphenated names are split into words
+	assert.Equal(t, []string{"adguard-home"}, resultNames(Search(apps, "home")))
+}
+
+func TestSearch_AllTermsMustMatch(t *testing.T) {
+	apps := searchTestApps()
+
+	results := Search(apps, "media streaming")
+	assert.Equal(t, []string{"jellyfin"}, resultNames(results))
+	assert.ElementsMatch(t, []string{"category", "tags"}, results[0].Matched)
+
+	assert.Empty(t, Search(apps, "media rss"))
+	assert.Empty(t, Search(apps, "   "))
+}
@@ -0,0 +1,227 @@
+package catalog
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Setting types
+const (
+	SettingString = "string"
+	SettingInt    = "int"
+	SettingBool   = "bool"
+	SettingEnum   = "enum"  // one of Options
+	SettingPaths  = "paths" // list of absolute paths
+)
+
+// Setting declares a user-editable app option in metadata.yaml.
+//
+// Settings with Env are written to the app's env file at prestart, so
+// changing them restarts the app. The rest are applied by the app's
+// configurator.
+type Setting struct {
+	Key         string   `yaml:"key" json:"key"`
+	Label       string   `yaml:"label" json:"label"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Type        string   `yaml:"type" json:"type"`
+	Default     any      `yaml:"default,omitempty" json:"default,omitempty"`
+	Min         *int     `yaml:"min,omitempty" json:"min,omitempty"`         // int only
+	Max         *int     `yaml:"max,omitempty" json:"max,omitempty"`         // int only
+	Options     []string `yaml:"options,omitempty" json:"options,omitempty"` // enum only
+	Env         string   `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+// validateSettings checks an app's settings schema when the catalog is loaded
+func validateSettings(settings []Setting) error {
+	seen := make(map[string]bool)
+	for _, s := range settings {
+		if s.Key == "" {
+			return fmt.Errorf("setting key is required")
+		}
+		if seen[s.Key] {
+			return fmt.Errorf("duplicate setting %q", s.Key)
+		}
+		seen[s.Key] = true
+
+		switch s.Type {
+		case SettingString, SettingInt, SettingBool, SettingPaths:
+		case SettingEnum:
+			if len(s.Options) == 0 {
+				return fmt.Errorf("setting %q: enum requires options", s.Key)
+			}
+		default:
+			return fmt.Errorf("setting %q: unknown type %q", s.Key, s.Type)
+		}
+
+		if s.Default != nil {
+			if _, err := s.normalize(s.Default); err != nil {
+				return fmt.Errorf("setting %q: invalid default: %w", s.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// FindSetting returns the setting declared with key, or nil
+func (a *App) FindSetting(key string) *Setting {
+	for i := range a.Settings {
+		if a.Settings[i].Key == key {
+			return &a.Settings[i]
+		}
+	}
+	return nil
+}
+
+// ValidateSettings checks values against the app's settings schema and
+// returns them normalized. Unknown keys are rejected; a nil value is kept
+// and means "reset to the default".
+func (a *App) ValidateSettings(values map[string]any) (map[string]any, error) {
+	result := make(map[string]any, len(values))
+	for key, value := range values {
+		setting := a.FindSetting(key)
+		if setting == nil {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+		if value == nil {
+			result[key] = nil
+			continue
+		}
+		normalized, err := setting.normalize(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		result[key] = normalized
+	}
+	return result, nil
+}
+
+// ResolveSettings returns every declared setting's effective value: the
+// stored value when it is still valid, otherwise the default. Settings
+// without either are omitted.
+func (a *App) ResolveSettings(stored map[string]any) map[string]any {
+	result := make(map[string]any, len(a.Settings))
+	for _, setting := range a.Settings {
+		for _, value := range []any{stored[setting.Key], setting.Default} {
+			if value == nil {
+				continue
+			}
+			if normalized, err := setting.normalize(value); err == nil {
+				result[setting.Key] = normalized
+				break
+			}
+		}
+	}
+	return result
+}
+
+// SettingsEnv returns the env vars for env-mapped settings in values
+func (a *App) SettingsEnv(values map[string]any) map[string]string {
+	vars := make(map[string]string)
+	for _, setting := range a.Settings {
+		value, ok := values[setting.Key]
+		if setting.Env == "" || !ok || value == nil {
+			continue
+		}
+		vars[setting.Env] = formatSetting(value)
+	}
+	return vars
+}
+
+// normalize converts a value decoded from YAML or JSON to the setting's Go
+// type (string, int, bool or []string) and checks its constraints
+func (s *Setting) normalize(value any) (any, error) {
+	switch s.Type {
+	case SettingString:
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		// Values can end up in env files, one variable per line
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("must be a single line")
+		}
+		return v, nil
+
+	case SettingInt:
+		var n int
+		switch v := value.(type) {
+		case int:
+			n = v
+		case int64:
+			n = int(v)
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("must be a whole number")
+			}
+			n = int(v)
+		default:
+			return nil, fmt.Errorf("must be a number")
+		}
+		if s.Min != nil && n < *s.Min {
+			return nil, fmt.Errorf("must be at least %d", *s.Min)
+		}
+		if s.Max != nil && n > *s.Max {
+			return nil, fmt.Errorf("must be at most %d", *s.Max)
+		}
+		return n, nil
+
+	case SettingBool:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return v, nil
+
+	case SettingEnum:
+		v, ok := value.(string)
+		if !ok || !slices.Contains(s.Options, v) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(s.Options, ", "))
+		}
+		return v, nil
+
+	case SettingPaths:
+		var items []any
+		switch v := value.(type) {
+		case []any:
+			items = v
+		case []string:
+			for _, p := range v {
+				items = append(items, p)
+			}
+		default:
+			return nil, fmt.Errorf("must be a list of paths")
+		}
+		paths := make([]string, 0, len(items))
+		for _, item := range items {
+			p, ok := item.(string)
+			if !ok || !path.IsAbs(p) || strings.ContainsAny(p, "\r\n,") {
+				return nil, fmt.Errorf("paths must be absolute and not contain commas or newlines")
+			}
+			p = path.Clean(p)
+			if !slices.Contains(paths, p) {
+				paths = append(paths, p)
+			}
+		}
+		return paths, nil
+	}
+	return nil, fmt.Errorf("unknown setting type %q", s.Type)
+}
+
+// formatSetting renders a normalized value for an env file
+func formatSetting(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		return strings.Join(v, ",")
+	}
+	return fmt.Sprint(value)
+}
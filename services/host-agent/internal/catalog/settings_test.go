@@ -0,0 +1,97 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func settingsTestApp(t *testing.T) *App {
+	t.Helper()
+	var app App
+	require.NoError(t, yaml.Unmarshal([]byte(`
+name: miniflux
+settings:
+  - key: pollingFrequency
+    type: int
+    default: 60
+    min: 5
+    max: 1440
+    env: POLLING_FREQUENCY
+  - key: theme
+    type: enum
+    options: [light_serif, dark_serif]
+  - key: libraries
+    type: paths
+    default: [/movies]
+  - key: publicSignup
+    type: bool
+`), &app))
+	require.NoError(t, validateSettings(app.Settings))
+	return &app
+}
+
+func TestApp_ValidateSettings(t *testing.T) {
+	app := settingsTestApp(t)
+
+	values, err := app.ValidateSettings(map[string]any{
+		"pollingFrequency": float64(30), // JSON numbers decode as float64
+		"theme":            "dark_serif",
+		"libraries":        []any{"/movies/", "/films", "/movies"},
+		"publicSignup":     nil,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"pollingFrequency": 30,
+		"theme":            "dark_serif",
+		"libraries":        []string{"/movies", "/films"},
+		"publicSignup":     nil,
+	}, values)
+
+	for name, input := range map[string]map[string]any{
+		"unknown key":   {"nope": 1},
+		"below min":     {"pollingFrequency": float64(1)},
+		"fraction":      {"pollingFrequency": 7.5},
+		"wrong type":    {"pollingFrequency": "60"},
+		"bad option":    {"theme": "neon"},
+		"relative path": {"libraries": []any{"movies"}},
+		"not a bool":    {"publicSignup": "yes"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := app.ValidateSettings(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApp_ResolveSettings(t *testing.T) {
+	app := settingsTestApp(t)
+
+	values := app.ResolveSettings(map[string]any{
+		"pollingFrequency": float64(15),
+		"theme":            "retired-theme", // no longer valid, no default
+	})
+	assert.Equal(t, map[string]any{
+		"pollingFrequency": 15,
+		"libraries":        []string{"/movies"},
+	}, values)
+
+	assert.Equal(t, map[string]string{"POLLING_FREQUENCY": "15"}, app.SettingsEnv(values))
+}
+
+func TestValidateSettings_Schema(t *testing.T) {
+	tests := map[string][]Setting{
+		"missing key":     {{Type: SettingString}},
+		"duplicate key":   {{Key: "a", Type: SettingString}, {Key: "a", Type: SettingInt}},
+		"unknown type":    {{Key: "a", Type: "float"}},
+		"enum no options": {{Key: "a", Type: SettingEnum}},
+		"bad default":     {{Key: "a", Type: SettingInt, Default: "ten"}},
+	}
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, validateSettings(settings))
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package catalog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ssoStrategies are the strategies the orchestrator and blueprint generator
+// act on; an empty strategy means none
+var ssoStrategies = map[string]bool{"": true, "none": true, "native-oidc": true, "forward-auth": true, "ldap": true}
+
+// externalFields are top-level metadata keys read outside the Go loader
+var externalFields = map[string]bool{
+	"traefikConfig": true, // copied into Traefik's dynamic config by the app's module.nix
+}
+
+// yamlLinePattern finds the line number in yaml.v3 error messages
+var yamlLinePattern = regexp.MustCompile(`line (\d+): `)
+
+// Problem is a catalog mistake at a line of an app's metadata.yaml
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// verifiedApp is one metadata.yaml as Verify sees it
+type verifiedApp struct {
+	file string
+	root *yaml.Node // the document's top-level mapping
+	app  App
+	def  AppDefinition
+}
+
+// Verify loads every metadata.yaml under appsDir more strictly than the
+// loader: besides the loader's own checks it rejects unknown fields, apps
+// with a port but no health check, unknown SSO strategies, ports used by
+// two apps, and dependencies or integrations naming apps that don't exist.
+// Problems are sorted by file and line; the error is only for an unreadable
+// apps directory.
+func Verify(appsDir string) ([]Problem, error) {
+	entries, err := os.ReadDir(appsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apps directory: %w", err)
+	}
+
+	var problems []Problem
+	var apps []*verifiedApp
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		file := filepath.Join(appsDir, entry.Name(), "metadata.yaml")
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+
+		app, fileProblems := verifyFile(file, entry.Name())
+		problems = append(problems, fileProblems...)
+		if app != nil {
+			apps = append(apps, app)
+		}
+	}
+	problems = append(problems, verifyReferences(apps)...)
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// verifyFile checks one app on its own. The app is nil when the file
+// couldn't be decoded far enough to cross-check it with the others.
+func verifyFile(file, dirName string) (*verifiedApp, []Problem) {
+	var problems []Problem
+	report := func(line int, format string, args ...any) {
+		problems = append(problems, Problem{File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		report(0, "%v", err)
+		return nil, problems
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		reportYAMLError(report, err)
+		return nil, problems
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		report(1, "metadata must be a mapping")
+		return nil, problems
+	}
+
+	v := &verifiedApp{file: file, root: doc.Content[0]}
+	decodeErr := v.root.Decode(&v.app)
+	if decodeErr == nil {
+		decodeErr = v.root.Decode(&v.def)
+	}
+	if decodeErr != nil {
+		reportYAMLError(report, decodeErr)
+		return nil, problems
+	}
+
+	checkFields(v.root, []reflect.Type{reflect.TypeOf(App{}), reflect.TypeOf(AppDefinition{})}, true, report)
+
+	app := &v.app
+	for _, field := range []struct{ key, value string }{
+		{"name", app.Name}, {"displayName", app.DisplayName}, {"description", app.Description}, {"category", app.Category},
+	} {
+		if field.value == "" {
+			report(1, "%s is required", field.key)
+		}
+	}
+	if app.Name != "" && app.Name != dirName {
+		report(keyLine(v.root, "name"), "name %q does not match its directory %q", app.Name, dirName)
+	}
+	if err := validateSettings(app.Settings); err != nil {
+		report(keyLine(v.root, "settings"), "%v", err)
+	}
+
+	if !ssoStrategies[app.SSO.Strategy] {
+		report(keyLine(v.root, "sso", "strategy"), "unknown SSO strategy %q (use native-oidc, forward-auth, ldap or none)", app.SSO.Strategy)
+	}
+	if app.SSO.Strategy == "native-oidc" && app.SSO.CallbackPath == "" {
+		report(keyLine(v.root, "sso"), "native-oidc needs sso.callbackPath")
+	}
+
+	// Apps serving HTTP need a health check for the install to finish and
+	// the health summary to cover them; system services are checked by
+	// their own units
+	if app.Port != 0 && !app.IsSystem && app.HealthCheck.Path == "" {
+		report(keyLine(v.root, "port"), "app has a port but no healthCheck.path")
+	}
+	if app.HealthCheck.Path != "" && !strings.HasPrefix(app.HealthCheck.Path, "/") {
+		report(keyLine(v.root, "healthCheck", "path"), "healthCheck.path %q must start with /", app.HealthCheck.Path)
+	}
+
+	if app.Name == "" {
+		return nil, problems
+	}
+	return v, problems
+}
+
+// verifyReferences checks what only the whole catalog can tell: shared
+// ports and references to other apps
+func verifyReferences(apps []*verifiedApp) []Problem {
+	var problems []Problem
+	names := make(map[string]bool, len(apps))
+	for _, v := range apps {
+		names[v.app.Name] = true
+	}
+
+	ports := make(map[int]string)
+	for _, v := range apps {
+		report := func(line int, format string, args ...any) {
+			problems = append(problems, Problem{File: v.file, Line: line, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if port := v.app.Port; port != 0 {
+			if other, ok := ports[port]; ok {
+				report(keyLine(v.root, "port"), "port %d is also used by %s", port, other)
+			} else {
+				ports[port] = v.app.Name
+			}
+		}
+
+		for i, dep := range v.app.Dependencies {
+			if !names[dep] {
+				report(itemLine(v.root, i, "dependencies"), "dependency %q is not in the catalog", dep)
+			}
+		}
+
+		for _, intName := range sortedIntegrations(v.def.Integrations) {
+			for i, compatible := range v.def.Integrations[intName].Compatible {
+				if !names[compatible.App] {
+					report(itemLine(v.root, i, "integrations", intName, "compatible"), "integration %s: %q is not in the catalog", intName, compatible.App)
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// checkFields reports mapping keys no field of types accepts, walking
+// into nested structs, slices and maps alongside node
+func checkFields(node *yaml.Node, types []reflect.Type, topLevel bool, report func(int, string, ...any)) {
+	for i, t := range types {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Interface {
+			return // free-form
+		}
+		types[i] = t
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if topLevel && externalFields[key.Value] {
+				continue
+			}
+			var next []reflect.Type
+			for _, t := range types {
+				switch t.Kind() {
+				case reflect.Struct:
+					if field, ok := yamlField(t, key.Value); ok {
+						next = append(next, field.Type)
+					}
+				case reflect.Map:
+					next = append(next, t.Elem())
+				}
+			}
+			if len(next) == 0 {
+				report(key.Line, "unknown field %q", key.Value)
+				continue
+			}
+			checkFields(value, next, false, report)
+		}
+	case yaml.SequenceNode:
+		var next []reflect.Type
+		for _, t := range types {
+			if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+				next = append(next, t.Elem())
+			}
+		}
+		if len(next) == 0 {
+			return
+		}
+		for _, item := range node.Content {
+			checkFields(item, append([]reflect.Type(nil), next...), false, report)
+		}
+	}
+}
+
+// yamlField finds the struct field yaml.v3 decodes key into
+func yamlField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if name == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// keyLine is the line of the key at path under mapping, or of the deepest
+// key found on the way when the rest is missing
+func keyLine(mapping *yaml.Node, path ...string) int {
+	line, node := 1, mapping
+	for _, key := range path {
+		k := mappingKey(node, key)
+		if k == nil {
+			return line
+		}
+		line, node = k.Line, mappingValue(node, key)
+	}
+	return line
+}
+
+// itemLine is the line of the index'th item of the sequence at path
+func itemLine(mapping *yaml.Node, index int, path ...string) int {
+	node := mapping
+	for _, key := range path {
+		if node = mappingValue(node, key); node == nil {
+			return keyLine(mapping, path...)
+		}
+	}
+	if node.Kind != yaml.SequenceNode || index >= len(node.Content) {
+		return keyLine(mapping, path...)
+	}
+	return node.Content[index].Line
+}
+
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// reportYAMLError turns yaml.v3 syntax and type errors, which carry the
+// line in their message, into problems
+func reportYAMLError(report func(int, string, ...any), err error) {
+	messages := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+	for _, msg := range messages {
+		msg = strings.TrimPrefix(msg, "yaml: ")
+		line := 0
+		if m := yamlLinePattern.FindStringSubmatchIndex(msg); m != nil {
+			line, _ = strconv.Atoi(msg[m[2]:m[3]])
+			msg = msg[:m[0]] + msg[m[1]:]
+		}
+		report(line, "%s", msg)
+	}
+}
+
+func sortedIntegrations(integrations map[string]Integration) []string {
+	names := make([]string, 0, len(integrations))
+	for name := range integrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,161 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const verifyPostgresYAML = `name: postgres
+displayName: PostgreSQL
+description: Database
+category: infrastructure
+isSystem: true
+`
+
+const verifyMinifluxYAML = `name: miniflux
+displayName: Miniflux
+description: Feed reader
+category: productivity
+port: 8085
+integrations:
+  database:
+    required: true
+    compatible:
+      - app: postgres
+        default: true
+healthCheck:
+  path: /healthcheck
+sso:
+  strategy: native-oidc
+  callbackPath: /oauth2/oidc/callback
+`
+
+func writeVerifyApps(t *testing.T, apps map[string]string) string {
+	dir := t.TempDir()
+	for name, metadata := range apps {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name, "metadata.yaml"), []byte(metadata), 0644))
+	}
+	return dir
+}
+
+func messages(problems []Problem) []string {
+	var out []string
+	for _, p := range problems {
+		out = append(out, p.String())
+	}
+	return out
+}
+
+func TestVerify_ValidCatalog(t *testing.T) {
+	dir := writeVerifyApps(t, map[string]string{"postgres": verifyPostgresYAML, "miniflux": verifyMinifluxYAML})
+
+	problems, err := Verify(dir)
+	require.NoError(t, err)
+	assert.Empty(t, messages(problems))
+}
+
+func TestVerify_UnknownFields(t *testing.T) {
+	dir := writeVerifyApps(t, map[string]string{
+		"postgres": verifyPostgresYAML,
+		"miniflux": verifyMinifluxYAML + "healthcheck:\n  path: /x\ntraefikConfig:\n  http: {}\n",
+	})
+	// A typo nested in a struct
+	metadata := verifyMinifluxYAML[:len(verifyMinifluxYAML)-1] + "\n  providerNmae: Bloud SSO\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "postgres", "metadata.yaml"), []byte(verifyPostgresYAML+"resources:\n  minRAM: 128\n"), 0644))
+
+	problems, err := Verify(dir)
+	require.NoError(t, err)
+	file := filepath.Join(dir, "miniflux", "metadata.yaml")
+	pgFile := filepath.Join(dir, "postgres", "metadata.yaml")
+	assert.Equal(t, []string{
+		file + `:17: unknown field "healthcheck"`,
+		pgFile + `:7: unknown field "minRAM"`,
+	}, messages(problems), "traefikConfig is read by module.nix and allowed")
+
+	require.NoError(t, os.WriteFile(file, []byte(metadata), 0644))
+	problems, err = Verify(dir)
+	require.NoError(t, err)
+	assert.Contains(t, messages(problems), file+`:17: unknown field "providerNmae"`)
+}
+
+func TestVerify_AppRules(t *testing.T) {
+	dir := writeVerifyApps(t, map[string]string{
+		"postgres": verifyPostgresYAML,
+		"radarr": `name: radarr
+displayName: Radarr
+description: Movies
+category: media
+port: 7878
+dependencies:
+  - postgres
+  - sonarr
+integrations:
+  downloadClient:
+    compatible:
+      - app: qbittorrent
+sso:
+  strategy: oauth
+`,
+		"miniflux": verifyMinifluxYAML,
+		"feeds": `name: other
+displayName: Feeds
+description: Also feeds
+category: productivity
+port: 8085
+healthCheck:
+  path: health
+`,
+	})
+
+	problems, err := Verify(dir)
+	require.NoError(t, err)
+	feeds := filepath.Join(dir, "feeds", "metadata.yaml")
+	miniflux := filepath.Join(dir, "miniflux", "metadata.yaml")
+	radarr := filepath.Join(dir, "radarr", "metadata.yaml")
+	assert.Equal(t, []string{
+		feeds + `:1: name "other" does not match its directory "feeds"`,
+		feeds + `:7: healthCheck.path "health" must start with /`,
+		miniflux + `:5: port 8085 is also used by other`,
+		radarr + `:5: app has a port but no healthCheck.path`,
+		radarr + `:8: dependency "sonarr" is not in the catalog`,
+		radarr + `:12: integration downloadClient: "qbittorrent" is not in the catalog`,
+		radarr + `:14: unknown SSO strategy "oauth" (use native-oidc, forward-auth, ldap or none)`,
+	}, messages(problems))
+}
+
+func TestVerify_DecodeErrors(t *testing.T) {
+	dir := writeVerifyApps(t, map[string]string{
+		"broken": "name: broken\ndisplayName: [unclosed\n",
+		"typed":  "name: typed\ndisplayName: Typed\ndescription: x\ncategory: x\nport: eighty\n",
+		"empty":  "displayName: Empty\n",
+	})
+
+	problems, err := Verify(dir)
+	require.NoError(t, err)
+	require.Len(t, problems, 5)
+
+	byFile := map[string][]Problem{}
+	for _, p := range problems {
+		byFile[filepath.Base(filepath.Dir(p.File))] = append(byFile[filepath.Base(filepath.Dir(p.File))], p)
+	}
+	assert.Len(t, byFile["broken"], 1)
+	assert.Equal(t, 5, byFile["typed"][0].Line)
+	assert.Contains(t, byFile["typed"][0].Message, "cannot unmarshal")
+	assert.Len(t, byFile["empty"], 3, "name, description and category are required")
+}
+
+func TestVerify_RealCatalog(t *testing.T) {
+	appsDir := "../../../../apps"
+	if _, err := os.Stat(appsDir); err != nil {
+		t.Skip("apps directory not found")
+	}
+
+	problems, err := Verify(appsDir)
+	require.NoError(t, err)
+	assert.Empty(t, messages(problems))
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
 )
@@ -21,16 +22,29 @@ type Config struct {
 	DatabaseURL  string // PostgreSQL connection string
 	RedisAddr    string // Redis address for session storage
 	// SSO configuration
-	SSOHostSecret    string // Master secret for deriving client secrets
-	SSOBaseURL       string // Base URL for callbacks (e.g., "http://localhost:8080")
-	SSOAuthentikURL  string // Authentik external URL for discovery (e.g., "http://localhost:8080")
-	AuthentikToken   string // Authentik API token for SSO cleanup
+	SSOHostSecret   string // Master secret for deriving client secrets
+	SSOBaseURL      string // Base URL for callbacks (e.g., "http://localhost:8080")
+	SSOAuthentikURL string // Authentik external URL for discovery (e.g., "http://localhost:8080")
+	AuthentikToken  string // Authentik API token for SSO cleanup
 	// Authentik bootstrap configuration
 	AuthentikPort          int
 	AuthentikAdminPassword string
 	AuthentikAdminEmail    string
 	// LDAP configuration
 	LDAPBindPassword string
+	// User provisioning: seconds between full Authentik -> app user syncs
+	ProvisioningSyncInterval int
+	// mDNS: avahi-publish binary for advertising apps as <app>.local (empty
+	// disables) and Traefik's HTTP port for their service records
+	AvahiPublishBin string
+	MDNSPort        int
+	// Flake URL of the Bloud repository automatic updates follow
+	UpdateRepo string
+	// Public keys offline update bundles must be signed by
+	UpdateKeys []string
+	// git binary release notes of available updates are read with (empty
+	// skips release notes)
+	GitBin string
 	// Secrets manager for accessing generated secrets
 	Secrets *secrets.Manager
 }
@@ -54,12 +68,12 @@ func LoadWithLogger(logger *slog.Logger) *Config {
 
 	// Initialize secrets manager
 	secretsPath := filepath.Join(dataDir, "secrets.json")
-	secretsMgr := secrets.NewManager(secretsPath)
+	secretsMgr := secrets.NewManagerFromEnv(secretsPath)
 	if err := secretsMgr.Load(); err != nil {
 		logger.Warn("failed to load secrets, using fallback defaults", "error", err, "path", secretsPath)
 		// Don't fail - use fallback defaults
 	} else {
-		logger.Info("loaded secrets", "path", secretsPath)
+		logger.Info("loaded secrets", "backend", secretsMgr.BackendName(), "path", secretsPath)
 	}
 
 	// Get secrets with fallbacks to env vars or static defaults
@@ -78,24 +92,30 @@ func LoadWithLogger(logger *slog.Logger) *Config {
 	defaultDatabaseURL := "postgres://apps:" + postgresPassword + "@localhost:5432/bloud?sslmode=disable"
 
 	cfg := &Config{
-		Port:                   getEnvAsInt("BLOUD_PORT", 3000),
-		DataDir:                dataDir,
-		AppsDir:                appsDir,
-		NixConfigDir:           getEnv("BLOUD_NIX_CONFIG_DIR", filepath.Join(dataDir, "nix")),
-		FlakePath:              getEnv("BLOUD_FLAKE_PATH", defaultFlakePath),
-		FlakeTarget:            getEnv("BLOUD_FLAKE_TARGET", "vm-dev"),
-		NixosPath:              getEnv("BLOUD_NIXOS_PATH", defaultNixosPath),
-		DatabaseURL:            getEnv("DATABASE_URL", defaultDatabaseURL),
-		RedisAddr:              getEnv("BLOUD_REDIS_ADDR", "localhost:6379"),
-		SSOHostSecret:          ssoHostSecret,
-		SSOBaseURL:             getEnv("BLOUD_SSO_BASE_URL", "http://localhost:8080"),
-		SSOAuthentikURL:        getEnv("BLOUD_SSO_AUTHENTIK_URL", "http://localhost:8080"),
-		AuthentikToken:         authentikToken,
-		AuthentikPort:          getEnvAsInt("BLOUD_AUTHENTIK_PORT", 9001),
-		AuthentikAdminPassword: authentikAdminPassword,
-		AuthentikAdminEmail:    getEnv("BLOUD_AUTHENTIK_ADMIN_EMAIL", "admin@localhost"),
-		LDAPBindPassword:       ldapBindPassword,
-		Secrets:                secretsMgr,
+		Port:                     getEnvAsInt("BLOUD_PORT", 3000),
+		DataDir:                  dataDir,
+		AppsDir:                  appsDir,
+		NixConfigDir:             getEnv("BLOUD_NIX_CONFIG_DIR", filepath.Join(dataDir, "nix")),
+		FlakePath:                getEnv("BLOUD_FLAKE_PATH", defaultFlakePath),
+		FlakeTarget:              getEnv("BLOUD_FLAKE_TARGET", "vm-dev"),
+		NixosPath:                getEnv("BLOUD_NIXOS_PATH", defaultNixosPath),
+		DatabaseURL:              getEnv("DATABASE_URL", defaultDatabaseURL),
+		RedisAddr:                getEnv("BLOUD_REDIS_ADDR", "localhost:6379"),
+		SSOHostSecret:            ssoHostSecret,
+		SSOBaseURL:               getEnv("BLOUD_SSO_BASE_URL", "http://localhost:8080"),
+		SSOAuthentikURL:          getEnv("BLOUD_SSO_AUTHENTIK_URL", "http://localhost:8080"),
+		AuthentikToken:           authentikToken,
+		AuthentikPort:            getEnvAsInt("BLOUD_AUTHENTIK_PORT", 9001),
+		AuthentikAdminPassword:   authentikAdminPassword,
+		AuthentikAdminEmail:      getEnv("BLOUD_AUTHENTIK_ADMIN_EMAIL", "admin@localhost"),
+		LDAPBindPassword:         ldapBindPassword,
+		ProvisioningSyncInterval: getEnvAsInt("BLOUD_PROVISIONING_SYNC_INTERVAL", 300),
+		AvahiPublishBin:          getEnv("BLOUD_MDNS_AVAHI_PUBLISH", ""),
+		MDNSPort:                 getEnvAsInt("BLOUD_MDNS_PORT", 8080),
+		UpdateRepo:               getEnv("BLOUD_UPDATE_REPO", ""),
+		UpdateKeys:               strings.Fields(os.Getenv("BLOUD_UPDATE_KEYS")),
+		GitBin:                   getEnv("BLOUD_GIT", ""),
+		Secrets:                  secretsMgr,
 	}
 
 	return cfg
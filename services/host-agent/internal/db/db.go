@@ -1,43 +1,41 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
 	"fmt"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
-//go:embed schema.sql
-var schema string
-
-// InitDB initializes the PostgreSQL database connection and runs schema
+// InitDB initializes the PostgreSQL database connection and applies any
+// pending schema migrations
 func InitDB(databaseURL string) (*sql.DB, error) {
-	// Open database connection
-	db, err := sql.Open("pgx", databaseURL)
+	db, err := Open(databaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	// Test the connection
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	// Run schema initialization
-	if err := runSchema(db); err != nil {
+	if _, err := Migrate(context.Background(), db); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
 
 	return db, nil
 }
 
-// runSchema executes the embedded schema SQL
-func runSchema(db *sql.DB) error {
-	_, err := db.Exec(schema)
+// Open connects to the PostgreSQL database without touching the schema
+func Open(databaseURL string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", databaseURL)
 	if err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
-	return nil
+
+	// Test the connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
 }
@@ -0,0 +1,332 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBackupRetention is how many backups are kept when none is configured
+	DefaultBackupRetention = 7
+
+	// postgresContainer runs the shared PostgreSQL server, including pg_dump
+	// and pg_restore matching its version
+	postgresContainer = "apps-postgres"
+
+	backupTimeFormat = "20060102T150405Z"
+)
+
+// backupNameRe matches backup file names: bloud-20260101T030000Z.dump
+var backupNameRe = regexp.MustCompile(`^bloud-(\d{8}T\d{6}Z)\.dump$`)
+
+// ErrBackupNotFound is returned when restoring a backup that doesn't exist
+var ErrBackupNotFound = errors.New("backup not found")
+
+// Backup describes one database backup file
+type Backup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MaintenanceReport is the result of one maintenance run
+type MaintenanceReport struct {
+	IntegrityOK     bool      `json:"integrity_ok"`
+	IntegrityErrors []string  `json:"integrity_errors,omitempty"`
+	Vacuumed        bool      `json:"vacuumed"`
+	Backup          *Backup   `json:"backup,omitempty"`
+	Pruned          []string  `json:"pruned,omitempty"` // backups removed by retention
+	StartedAt       time.Time `json:"started_at"`
+	DurationMs      int64     `json:"duration_ms"`
+}
+
+// MaintainerConfig configures database maintenance
+type MaintainerConfig struct {
+	DatabaseURL string // used for the database name and user passed to pg_dump
+	BackupDir   string
+	Retention   int // backups to keep; defaults to DefaultBackupRetention
+}
+
+// Maintainer checks, vacuums, backs up and restores the host-agent database
+type Maintainer struct {
+	db        *sql.DB
+	backupDir string
+	retention int
+	dbName    string
+	dbUser    string
+	logger    *slog.Logger
+
+	// run executes a command in the PostgreSQL container; replaced in tests
+	run func(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error
+
+	mu sync.Mutex // serializes maintenance, backups and restores
+}
+
+// NewMaintainer creates a maintainer for database
+func NewMaintainer(database *sql.DB, cfg MaintainerConfig, logger *slog.Logger) (*Maintainer, error) {
+	u, err := url.Parse(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URL: %w", err)
+	}
+	dbName := strings.TrimPrefix(u.Path, "/")
+	if dbName == "" || u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("database URL must include a user and database name")
+	}
+
+	retention := cfg.Retention
+	if retention <= 0 {
+		retention = DefaultBackupRetention
+	}
+
+	return &Maintainer{
+		db:        database,
+		backupDir: cfg.BackupDir,
+		retention: retention,
+		dbName:    dbName,
+		dbUser:    u.User.Username(),
+		logger:    logger,
+		run:       podmanExec,
+	}, nil
+}
+
+// LastBackup returns when the newest backup was taken, zero if there is none
+// or the backup directory can't be read
+func (m *Maintainer) LastBackup() time.Time {
+	backups, err := m.ListBackups()
+	if err != nil || len(backups) == 0 {
+		return time.Time{}
+	}
+	return backups[0].CreatedAt
+}
+
+// Run checks integrity, vacuums, takes a backup and prunes old backups.
+// A failed integrity check is reported but doesn't stop the backup: a copy
+// of a damaged database is still better than none.
+func (m *Maintainer) Run(ctx context.Context) (*MaintenanceReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := &MaintenanceReport{StartedAt: time.Now()}
+
+	report.IntegrityErrors = m.checkIntegrity(ctx)
+	report.IntegrityOK = len(report.IntegrityErrors) == 0
+	if !report.IntegrityOK {
+		m.logger.Error("database integrity check failed", "errors", report.IntegrityErrors)
+	}
+
+	if _, err := m.db.ExecContext(ctx, "VACUUM (ANALYZE)"); err != nil {
+		m.logger.Warn("failed to vacuum database", "error", err)
+	} else {
+		report.Vacuumed = true
+	}
+
+	backup, err := m.backup(ctx, report.StartedAt)
+	if err != nil {
+		return report, err
+	}
+	report.Backup = backup
+
+	report.Pruned, err = m.prune()
+	if err != nil {
+		m.logger.Warn("failed to prune old backups", "error", err)
+	}
+
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	return report, nil
+}
+
+// checkIntegrity reads every row of every table in the public schema, so a
+// damaged page surfaces as an error here rather than in a request
+func (m *Maintainer) checkIntegrity(ctx context.Context) []string {
+	rows, err := m.db.QueryContext(ctx, `SELECT tablename FROM pg_tables WHERE schemaname = 'public' ORDER BY tablename`)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to list tables: %v", err)}
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return []string{fmt.Sprintf("failed to scan table name: %v", err)}
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	var problems []string
+	for _, table := range tables {
+		var n int64
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteIdent(table))
+		if err := m.db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", table, err))
+		}
+	}
+	return problems
+}
+
+// Backup takes a backup now
+func (m *Maintainer) Backup(ctx context.Context) (*Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.backup(ctx, time.Now())
+}
+
+// backup dumps the database while it stays online. The dump is written to a
+// temp file and renamed, so a partial dump never looks like a backup.
+func (m *Maintainer) backup(ctx context.Context, now time.Time) (*Backup, error) {
+	if err := os.MkdirAll(m.backupDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup dir: %w", err)
+	}
+
+	name := "bloud-" + now.UTC().Format(backupTimeFormat) + ".dump"
+	path := filepath.Join(m.backupDir, name)
+
+	tmp, err := os.CreateTemp(m.backupDir, ".backup-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = m.run(ctx, nil, tmp, "pg_dump", "-U", m.dbUser, "-d", m.dbName, "--format=custom")
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pg_dump failed: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to save backup: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat backup: %w", err)
+	}
+	return &Backup{Name: name, Size: info.Size(), CreatedAt: now.UTC().Truncate(time.Second)}, nil
+}
+
+// ListBackups returns the backups on disk, newest first
+func (m *Maintainer) ListBackups() ([]*Backup, error) {
+	entries, err := os.ReadDir(m.backupDir)
+	if os.IsNotExist(err) {
+		return []*Backup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup dir: %w", err)
+	}
+
+	backups := []*Backup{}
+	for _, entry := range entries {
+		match := backupNameRe.FindStringSubmatch(entry.Name())
+		if match == nil || entry.IsDir() {
+			continue
+		}
+		createdAt, err := time.Parse(backupTimeFormat, match[1])
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, &Backup{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// prune deletes backups beyond the retention count, oldest first
+func (m *Maintainer) prune() ([]string, error) {
+	backups, err := m.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	var pruned []string
+	for i := m.retention; i < len(backups); i++ {
+		if err := os.Remove(filepath.Join(m.backupDir, backups[i].Name)); err != nil {
+			return pruned, fmt.Errorf("failed to remove %s: %w", backups[i].Name, err)
+		}
+		pruned = append(pruned, backups[i].Name)
+	}
+	return pruned, nil
+}
+
+// Restore replaces the database contents with a backup. A fresh backup is
+// taken first so a mistaken restore can itself be undone, and migrations are
+// re-applied afterwards in case the backup predates the current schema.
+// It returns the name of the safety backup.
+func (m *Maintainer) Restore(ctx context.Context, name string) (string, error) {
+	if !backupNameRe.MatchString(name) {
+		return "", ErrBackupNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := filepath.Join(m.backupDir, name)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", ErrBackupNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	safety, err := m.backup(ctx, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to take safety backup before restore: %w", err)
+	}
+
+	err = m.run(ctx, f, io.Discard, "pg_restore", "-U", m.dbUser, "-d", m.dbName,
+		"--clean", "--if-exists", "--no-owner", "--single-transaction")
+	if err != nil {
+		return safety.Name, fmt.Errorf("pg_restore failed: %w", err)
+	}
+
+	if _, err := Migrate(ctx, m.db); err != nil {
+		return safety.Name, fmt.Errorf("restored, but failed to migrate schema: %w", err)
+	}
+	return safety.Name, nil
+}
+
+// podmanExec runs a command inside the PostgreSQL container
+func podmanExec(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error {
+	podmanArgs := []string{"exec"}
+	if stdin != nil {
+		podmanArgs = append(podmanArgs, "-i")
+	}
+	podmanArgs = append(podmanArgs, postgresContainer)
+	podmanArgs = append(podmanArgs, args...)
+
+	cmd := exec.CommandContext(ctx, "podman", podmanArgs...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// quoteIdent quotes a PostgreSQL identifier
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,320 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the pg_advisory_lock key held while migrating, so two
+// host-agent processes starting together don't apply the same migration twice
+const migrationLockID = 0x626c6f7564 // "bloud"
+
+// migrationFileRe matches migration files: 0002_add_app_events.up.sql
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes a known migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil if pending
+}
+
+// Migrations returns the migrations embedded in the binary, ordered by version
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+// LatestVersion returns the schema version this binary migrates up to
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// loadMigrations reads NNNN_name.up.sql / NNNN_name.down.sql pairs from dir.
+// Every version must have an up file; down files are optional.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := migrationFileRe.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		if version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies all pending migrations in order, each in its own
+// transaction. It returns the versions applied.
+func Migrate(ctx context.Context, db *sql.DB) ([]int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return migrateUp(ctx, db, migrations)
+}
+
+// MigrateDown rolls back applied migrations, newest first, until the schema
+// is at target. It returns the versions rolled back.
+func MigrateDown(ctx context.Context, db *sql.DB, target int) ([]int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return migrateDown(ctx, db, migrations, target)
+}
+
+// SchemaVersion returns the highest applied migration version, or 0 for a
+// database that has never been migrated
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return 0, err
+	}
+	var version int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// MigrationStatuses lists every embedded migration with its applied time
+func MigrationStatuses(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		status := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func migrateUp(ctx context.Context, db *sql.DB, migrations []Migration) ([]int, error) {
+	var ran []int
+	err := withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkNotNewer(applied, migrations); err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			ran = append(ran, mig.Version)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+func migrateDown(ctx context.Context, db *sql.DB, migrations []Migration, target int) ([]int, error) {
+	if target < 0 {
+		return nil, fmt.Errorf("invalid target version %d", target)
+	}
+
+	var ran []int
+	err := withMigrationLock(ctx, db, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkNotNewer(applied, migrations); err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			mig := migrations[i]
+			if mig.Version <= target {
+				break
+			}
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be rolled back (no down file)", mig.Version, mig.Name)
+			}
+			if err := runMigration(ctx, conn, mig.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			ran = append(ran, mig.Version)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// runMigration executes a migration script and its bookkeeping statement in
+// one transaction, so a failed migration leaves no partial schema behind
+func runMigration(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return tx.Commit()
+}
+
+// checkNotNewer refuses to touch a database migrated by a newer host-agent,
+// whose schema this binary doesn't know how to handle
+func checkNotNewer(applied map[int]time.Time, migrations []Migration) error {
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("database schema version %d is newer than this host-agent supports (%d)", version, latest)
+		}
+	}
+	return nil
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the migration
+// advisory lock. Session-level advisory locks belong to a connection, so the
+// lock and all migration work must share one.
+func withMigrationLock(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// execQueryer is satisfied by *sql.DB and *sql.Conn
+type execQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func ensureMigrationsTable(ctx context.Context, q execQueryer) error {
+	_, err := q.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations returns applied versions mapped to when they were applied
+func appliedMigrations(ctx context.Context, q execQueryer) (map[int]time.Time, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan migration row: %w", err)
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, mig := range migrations {
+		assert.Equal(t, i+1, mig.Version, "migration versions must be contiguous")
+		assert.NotEmpty(t, mig.Down, "migration %d_%s should have a down file", mig.Version, mig.Name)
+	}
+	assert.Equal(t, "initial", migrations[0].Name)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("pairs up and down files in version order", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/0002_add_events.up.sql":   {Data: []byte("CREATE TABLE events ();")},
+			"m/0001_initial.up.sql":      {Data: []byte("CREATE TABLE apps ();")},
+			"m/0001_initial.down.sql":    {Data: []byte("DROP TABLE apps;")},
+			"m/0002_add_events.down.sql": {Data: []byte("DROP TABLE events;")},
+		}
+		migrations, err := loadMigrations(fsys, "m")
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+		assert.Equal(t, Migration{Version: 1, Name: "initial", Up: "CREATE TABLE apps ();", Down: "DROP TABLE apps;"}, migrations[0])
+		assert.Equal(t, 2, migrations[1].Version)
+		assert.Equal(t, "add_events", migrations[1].Name)
+	})
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"bad file name", fstest.MapFS{"m/initial.sql": {Data: []byte("x")}}},
+		{"zero version", fstest.MapFS{"m/0000_initial.up.sql": {Data: []byte("x")}}},
+		{"missing up file", fstest.MapFS{"m/0001_initial.down.sql": {Data: []byte("x")}}},
+		{"conflicting names", fstest.MapFS{
+			"m/0001_initial.up.sql": {Data: []byte("x")},
+			"m/0001_other.down.sql": {Data: []byte("x")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.files, "m")
+			assert.Error(t, err)
+		})
+	}
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "initial", Up: "CREATE TABLE apps", Down: "DROP TABLE apps"},
+	{Version: 2, Name: "add_events", Up: "CREATE TABLE events", Down: "DROP TABLE events"},
+}
+
+func expectLockAndTable(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigrateUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectLockAndTable(mock)
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE events`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(2, "add_events").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ran, err := migrateUp(context.Background(), db, testMigrations)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, ran, "only the pending migration runs")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateUp_FailureRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectLockAndTable(mock)
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE apps`).WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ran, err := migrateUp(context.Background(), db, testMigrations)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1_initial")
+	assert.Empty(t, ran)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateUp_RefusesNewerSchema(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectLockAndTable(mock)
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(3, time.Now()))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = migrateUp(context.Background(), db, testMigrations)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "newer")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateDown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	expectLockAndTable(mock)
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()).AddRow(2, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DROP TABLE events`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ran, err := migrateDown(context.Background(), db, testMigrations, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, ran)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
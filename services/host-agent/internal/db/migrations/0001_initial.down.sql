@@ -0,0 +1,7 @@
+-- Drops the initial schema. Destroys all host-agent data.
+DROP TABLE IF EXISTS app_settings;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS catalog_cache;
+DROP TABLE IF EXISTS apps;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,68 @@
+-- Initial Bloud Host Agent schema (PostgreSQL).
+-- Uses IF NOT EXISTS so databases created before migrations existed adopt it as-is.
+
+-- Users registered in Bloud (credentials stored in Authentik)
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username TEXT UNIQUE NOT NULL,
+    layout JSONB DEFAULT '[]',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Apps installed on this host
+CREATE TABLE IF NOT EXISTS apps (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    display_name TEXT NOT NULL,
+    version TEXT,
+    status TEXT NOT NULL DEFAULT 'stopped',  -- 'running', 'stopped', 'error', 'installing'
+    port INTEGER,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,    -- true for system apps (postgres, traefik, etc)
+    integration_config TEXT,  -- JSON: {"downloadClient": "qbittorrent", "mediaServer": "jellyfin"}
+    installed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- App catalog cache (synced from git repository)
+CREATE TABLE IF NOT EXISTS catalog_cache (
+    name TEXT PRIMARY KEY,
+    yaml_content TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Personal access tokens for API automation (only a SHA-256 hash is stored)
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL DEFAULT '[]',  -- JSON: ["read"], ["write"], ["write", "admin"]
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Audit log of state-changing API requests
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    username TEXT NOT NULL DEFAULT '',  -- empty for unauthenticated requests
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    route TEXT NOT NULL DEFAULT '',     -- route pattern, e.g. /api/apps/{name}/install
+    summary TEXT NOT NULL DEFAULT '',   -- redacted request body
+    status INTEGER NOT NULL,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- User-editable app settings, validated against the catalog settings schema
+CREATE TABLE IF NOT EXISTS app_settings (
+    app_name TEXT PRIMARY KEY REFERENCES apps(name) ON DELETE CASCADE,
+    settings TEXT NOT NULL DEFAULT '{}',  -- JSON: {"pollingFrequency": 60}
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for common queries
+CREATE INDEX IF NOT EXISTS idx_apps_status ON apps(status);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
@@ -0,0 +1,13 @@
+-- Status transition history for installed apps
+-- (installing -> starting -> running -> error, ...). Rows outlive the app so
+-- history survives an uninstall and reinstall.
+CREATE TABLE app_events (
+    id BIGSERIAL PRIMARY KEY,
+    app_name TEXT NOT NULL,
+    from_status TEXT NOT NULL DEFAULT '', -- empty for a fresh install
+    to_status TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_app_events_app_created ON app_events(app_name, created_at);
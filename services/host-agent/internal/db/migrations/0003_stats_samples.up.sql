@@ -0,0 +1,12 @@
+-- System and per-app resource usage history. Samples are kept at several
+-- resolutions (seconds per sample); finer ones are downsampled into coarser
+-- ones and pruned, so the table stays bounded like a ring buffer.
+CREATE TABLE stats_samples (
+    resolution INTEGER NOT NULL,
+    app TEXT NOT NULL DEFAULT '', -- empty for the whole host
+    cpu REAL NOT NULL,
+    memory REAL NOT NULL,
+    disk REAL NOT NULL DEFAULT 0, -- host only
+    sampled_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (resolution, app, sampled_at)
+);
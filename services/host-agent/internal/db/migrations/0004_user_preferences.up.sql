@@ -0,0 +1,7 @@
+-- Per-user preferences that follow the user across devices. The dashboard
+-- layout stays in users.layout; this holds everything else as one document.
+CREATE TABLE user_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    preferences JSONB NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
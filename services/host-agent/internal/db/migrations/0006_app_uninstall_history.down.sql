@@ -0,0 +1,3 @@
+DELETE FROM apps WHERE uninstalled_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_apps_uninstalled_at;
+ALTER TABLE apps DROP COLUMN uninstalled_at;
@@ -0,0 +1,5 @@
+-- Uninstalled apps are kept with their integration config so a re-install
+-- can restore it; rows with uninstalled_at set are not installed.
+ALTER TABLE apps ADD COLUMN uninstalled_at TIMESTAMP;
+
+CREATE INDEX idx_apps_uninstalled_at ON apps(uninstalled_at);
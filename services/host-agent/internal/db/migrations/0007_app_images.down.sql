@@ -0,0 +1,3 @@
+ALTER TABLE apps DROP COLUMN previous_image_digest;
+ALTER TABLE apps DROP COLUMN image_digest;
+ALTER TABLE apps DROP COLUMN image;
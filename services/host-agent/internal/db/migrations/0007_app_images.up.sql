@@ -0,0 +1,5 @@
+-- The container image each app runs, captured from Podman after a rebuild.
+-- previous_image_digest keeps the digest it replaced, for before/after views.
+ALTER TABLE apps ADD COLUMN image TEXT NOT NULL DEFAULT '';
+ALTER TABLE apps ADD COLUMN image_digest TEXT NOT NULL DEFAULT '';
+ALTER TABLE apps ADD COLUMN previous_image_digest TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS secret_access_log;
+DROP FUNCTION IF EXISTS secret_access_log_append_only();
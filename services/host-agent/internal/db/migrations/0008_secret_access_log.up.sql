@@ -0,0 +1,28 @@
+-- Append-only record of every read and write of a secret, for investigating
+-- a compromise. The triggers refuse updates and deletes so entries can't be
+-- rewritten through the host agent's connection.
+CREATE TABLE IF NOT EXISTS secret_access_log (
+    id BIGSERIAL PRIMARY KEY,
+    component TEXT NOT NULL,            -- package that touched the secret, e.g. internal/api
+    caller TEXT NOT NULL DEFAULT '',    -- function, e.g. api.(*Server).handleSetAppSecret
+    action TEXT NOT NULL,               -- read, write or delete
+    app TEXT NOT NULL DEFAULT '',       -- empty for deployment-wide secrets
+    secret TEXT NOT NULL,               -- e.g. postgresPassword, apiKey, or * for all
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_secret_access_log_created ON secret_access_log(created_at);
+
+CREATE OR REPLACE FUNCTION secret_access_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'secret_access_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER secret_access_log_no_update
+    BEFORE UPDATE OR DELETE ON secret_access_log
+    FOR EACH ROW EXECUTE FUNCTION secret_access_log_append_only();
+
+CREATE TRIGGER secret_access_log_no_truncate
+    BEFORE TRUNCATE ON secret_access_log
+    FOR EACH STATEMENT EXECUTE FUNCTION secret_access_log_append_only();
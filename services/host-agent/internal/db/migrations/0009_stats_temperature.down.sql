@@ -0,0 +1 @@
+ALTER TABLE stats_samples DROP COLUMN temperature;
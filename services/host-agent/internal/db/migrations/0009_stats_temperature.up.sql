@@ -0,0 +1,3 @@
+-- CPU package temperature in °C for host samples, 0 where the machine has no
+-- sensor. Downsampled like the other columns.
+ALTER TABLE stats_samples ADD COLUMN temperature REAL NOT NULL DEFAULT 0;
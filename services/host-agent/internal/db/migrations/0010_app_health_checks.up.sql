@@ -0,0 +1,10 @@
+-- Every periodic health check of an installed app, for uptime percentages
+-- and downtime incidents. Kept for the longest uptime view, 90 days.
+CREATE TABLE app_health_checks (
+    app_name TEXT NOT NULL,
+    healthy BOOLEAN NOT NULL,
+    checked_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (app_name, checked_at)
+);
+
+CREATE INDEX idx_app_health_checks_checked_at ON app_health_checks(checked_at);
@@ -0,0 +1,11 @@
+-- Scheduled background jobs (backups, stats rollups, image cleanup). A row
+-- exists once a job has run or its schedule was changed; spec is empty for
+-- the job's default schedule.
+CREATE TABLE schedules (
+    name TEXT PRIMARY KEY,
+    spec TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_run_at TIMESTAMP,
+    last_duration_ms BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,11 @@
+-- Automatic updates: the policy and the outcome of the last update. There's
+-- a single row, once the policy was changed or an update was attempted.
+CREATE TABLE updates (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    policy JSONB,
+    last_attempt_at TIMESTAMP,
+    status TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    from_system TEXT NOT NULL DEFAULT '',
+    to_system TEXT NOT NULL DEFAULT ''
+);
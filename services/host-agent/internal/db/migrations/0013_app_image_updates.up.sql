@@ -0,0 +1,13 @@
+-- Results of the image update checker: the digest each installed app's image
+-- tag (or catalog pin) currently points at, and whether the app runs an
+-- older one.
+CREATE TABLE app_image_updates (
+    app TEXT PRIMARY KEY,
+    image TEXT NOT NULL,
+    current_digest TEXT NOT NULL DEFAULT '',
+    latest_digest TEXT NOT NULL DEFAULT '',
+    update_available BOOLEAN NOT NULL DEFAULT FALSE,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    error TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE updates DROP COLUMN snapshot;
@@ -0,0 +1,2 @@
+-- The consistency point taken before the last update switched systems
+ALTER TABLE updates ADD COLUMN snapshot JSONB;
@@ -0,0 +1,2 @@
+-- When the last update's system started running, for verifying its health
+ALTER TABLE updates ADD COLUMN switched_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- The newer system the last update check found, while not yet running
+ALTER TABLE updates ADD COLUMN available JSONB;
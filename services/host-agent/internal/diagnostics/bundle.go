@@ -0,0 +1,82 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Bundle writes a gzipped tarball whose files are redacted as they're added.
+// Files that can't be collected are listed in errors.txt, so one missing
+// piece doesn't cost the rest of the bundle.
+type Bundle struct {
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	redactor *Redactor
+	prefix   string // directory every file is placed in
+	modTime  time.Time
+	errors   []string
+}
+
+// NewBundle starts a bundle written to w, with its files under the prefix
+// directory
+func NewBundle(w io.Writer, prefix string, redactor *Redactor) *Bundle {
+	gz := gzip.NewWriter(w)
+	return &Bundle{
+		gz:       gz,
+		tw:       tar.NewWriter(gz),
+		redactor: redactor,
+		prefix:   prefix,
+		modTime:  time.Now(),
+	}
+}
+
+// Add redacts data and adds it as name
+func (b *Bundle) Add(name string, data []byte) error {
+	data = b.redactor.Redact(data)
+	header := &tar.Header{
+		Name:    b.prefix + "/" + name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: b.modTime,
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// AddFile adds the file at path as name, noting it if it can't be read
+func (b *Bundle) AddFile(name, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.Fail(name, err)
+		return nil
+	}
+	return b.Add(name, data)
+}
+
+// Fail notes that name couldn't be collected
+func (b *Bundle) Fail(name string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+}
+
+// Close writes errors.txt, if anything failed, and finishes the tarball
+func (b *Bundle) Close() error {
+	if len(b.errors) > 0 {
+		if err := b.Add("errors.txt", []byte(strings.Join(b.errors, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+	if err := b.tw.Close(); err != nil {
+		return fmt.Errorf("closing tarball: %w", err)
+	}
+	return b.gz.Close()
+}
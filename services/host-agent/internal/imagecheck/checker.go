@@ -0,0 +1,142 @@
+// Package imagecheck finds installed apps whose container images have newer
+// versions. It resolves what each app's image tag (or catalog pin) points
+// at in its registry and compares that with the digest the app runs.
+package imagecheck
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/notify"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/registry"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+// Resolver resolves an image reference to the digest it points at
+type Resolver interface {
+	Digest(ctx context.Context, ref registry.Reference) (string, error)
+}
+
+// Checker checks installed apps' images for updates
+type Checker struct {
+	apps     store.AppStoreInterface
+	catalog  catalog.CacheInterface
+	store    store.ImageUpdateStoreInterface
+	resolver Resolver
+	notifier notify.Publisher // nil sends no notifications
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// New creates a checker
+func New(apps store.AppStoreInterface, cat catalog.CacheInterface, st store.ImageUpdateStoreInterface, resolver Resolver, notifier notify.Publisher, logger *slog.Logger) *Checker {
+	return &Checker{
+		apps:     apps,
+		catalog:  cat,
+		store:    st,
+		resolver: resolver,
+		notifier: notifier,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Check is the scheduled job. It records a result for every installed app
+// with a known image and forgets apps that were uninstalled. Registry
+// errors are recorded per app rather than failing the run.
+func (c *Checker) Check(ctx context.Context) error {
+	installed, err := c.apps.GetAll()
+	if err != nil {
+		return fmt.Errorf("listing installed apps: %w", err)
+	}
+	previous, err := c.store.All()
+	if err != nil {
+		return err
+	}
+	before := make(map[string]store.ImageUpdate, len(previous))
+	for _, u := range previous {
+		before[u.App] = u
+	}
+
+	checked := map[string]bool{}
+	for _, app := range installed {
+		if app.Image == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		u := c.check(ctx, app)
+		checked[app.Name] = true
+		if err := c.store.Save(u); err != nil {
+			return err
+		}
+		// Notify once per new version, not on every check
+		if prev, ok := before[app.Name]; u.UpdateAvailable && (!ok || !prev.UpdateAvailable || prev.LatestDigest != u.LatestDigest) {
+			c.publish(app, u)
+		}
+	}
+
+	for name := range before {
+		if !checked[name] {
+			if err := c.store.Delete(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check resolves one app's image
+func (c *Checker) check(ctx context.Context, app *store.InstalledApp) store.ImageUpdate {
+	u := store.ImageUpdate{App: app.Name, Image: app.Image, CurrentDigest: app.ImageDigest, CheckedAt: c.now()}
+	ref, err := registry.ParseReference(app.Image)
+	if err != nil {
+		u.Error = err.Error()
+		return u
+	}
+
+	// A catalog pin holds the app at a version; otherwise the tag it was
+	// installed from is followed
+	if entry, err := c.catalog.Get(app.Name); err == nil && entry != nil && entry.ImagePin != "" {
+		if strings.HasPrefix(entry.ImagePin, "sha256:") {
+			ref.Digest = entry.ImagePin
+		} else {
+			ref.Tag, ref.Digest = entry.ImagePin, ""
+		}
+		u.Pinned = true
+	}
+	u.Image = ref.String()
+	u.Pinned = u.Pinned || ref.Digest != ""
+
+	latest, err := c.resolver.Digest(ctx, ref)
+	if err != nil {
+		c.logger.WarnContext(ctx, "image update check failed", "app", app.Name, "image", u.Image, "error", err)
+		u.Error = err.Error()
+		return u
+	}
+	u.LatestDigest = latest
+	u.UpdateAvailable = app.ImageDigest != "" && latest != app.ImageDigest
+	return u
+}
+
+func (c *Checker) publish(app *store.InstalledApp, u store.ImageUpdate) {
+	if c.notifier == nil {
+		return
+	}
+	name := app.DisplayName
+	if name == "" {
+		name = app.Name
+	}
+	c.notifier.Publish(notify.Event{
+		Kind:    notify.KindUpdateAvailable,
+		Title:   name + " update available",
+		Message: fmt.Sprintf("A newer %s image is available.", u.Image),
+		App:     app.Name,
+		Time:    u.CheckedAt,
+	})
+}
@@ -0,0 +1,130 @@
+package imagecheck
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/notify"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/registry"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeApps struct {
+	store.AppStoreInterface
+	apps []*store.InstalledApp
+}
+
+func (f *fakeApps) GetAll() ([]*store.InstalledApp, error) { return f.apps, nil }
+
+type fakeCatalog struct {
+	catalog.CacheInterface
+	apps map[string]*catalog.App
+}
+
+func (f *fakeCatalog) Get(name string) (*catalog.App, error) {
+	if app, ok := f.apps[name]; ok {
+		return app, nil
+	}
+	return nil, errors.New("not found")
+}
+
+type fakeStore struct {
+	updates map[string]store.ImageUpdate
+}
+
+func (f *fakeStore) All() ([]store.ImageUpdate, error) {
+	var all []store.ImageUpdate
+	for _, u := range f.updates {
+		all = append(all, u)
+	}
+	return all, nil
+}
+func (f *fakeStore) Save(u store.ImageUpdate) error { f.updates[u.App] = u; return nil }
+func (f *fakeStore) Delete(app string) error        { delete(f.updates, app); return nil }
+
+// fakeResolver maps references to digests
+type fakeResolver map[string]string
+
+func (f fakeResolver) Digest(ctx context.Context, ref registry.Reference) (string, error) {
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+	if digest, ok := f[ref.String()]; ok {
+		return digest, nil
+	}
+	return "", errors.New("registry returned status 404")
+}
+
+type fakeNotifier struct {
+	events []notify.Event
+}
+
+func (f *fakeNotifier) Publish(event notify.Event) { f.events = append(f.events, event) }
+
+func TestChecker_Check(t *testing.T) {
+	apps := &fakeApps{apps: []*store.InstalledApp{
+		{Name: "miniflux", DisplayName: "Miniflux", Image: "docker.io/miniflux/miniflux:latest", ImageDigest: "sha256:old"},
+		{Name: "jellyfin", Image: "docker.io/jellyfin/jellyfin:10.9", ImageDigest: "sha256:j1"},
+		{Name: "actual-budget", Image: "docker.io/actualbudget/actual-server:latest", ImageDigest: "sha256:a1"},
+		{Name: "missing", Image: "ghcr.io/team/missing:1.0", ImageDigest: "sha256:m1"},
+		{Name: "redis"}, // image not recorded yet
+	}}
+	cat := &fakeCatalog{apps: map[string]*catalog.App{
+		"jellyfin":      {Name: "jellyfin", ImagePin: "10.8"},
+		"actual-budget": {Name: "actual-budget", ImagePin: "sha256:a1"},
+	}}
+	st := &fakeStore{updates: map[string]store.ImageUpdate{
+		"uninstalled": {App: "uninstalled"},
+	}}
+	resolver := fakeResolver{
+		"docker.io/miniflux/miniflux:latest": "sha256:new",
+		"docker.io/jellyfin/jellyfin:10.8":   "sha256:j0",
+	}
+	notifier := &fakeNotifier{}
+	c := New(apps, cat, st, resolver, notifier, slog.New(slog.DiscardHandler))
+	at := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return at }
+
+	require.NoError(t, c.Check(context.Background()))
+	require.Len(t, st.updates, 4)
+	assert.NotContains(t, st.updates, "uninstalled")
+
+	miniflux := st.updates["miniflux"]
+	assert.True(t, miniflux.UpdateAvailable)
+	assert.False(t, miniflux.Pinned)
+	assert.Equal(t, "sha256:new", miniflux.LatestDigest)
+	assert.Equal(t, at, miniflux.CheckedAt)
+
+	// Tag pins are followed instead of the installed tag
+	jellyfin := st.updates["jellyfin"]
+	assert.True(t, jellyfin.Pinned)
+	assert.Equal(t, "docker.io/jellyfin/jellyfin:10.8", jellyfin.Image)
+	assert.Equal(t, "sha256:j0", jellyfin.LatestDigest)
+
+	// Apps running their digest pin are up to date
+	actual := st.updates["actual-budget"]
+	assert.True(t, actual.Pinned)
+	assert.False(t, actual.UpdateAvailable)
+
+	assert.Contains(t, st.updates["missing"].Error, "404")
+	assert.False(t, st.updates["missing"].UpdateAvailable)
+
+	require.Len(t, notifier.events, 2)
+	assert.Equal(t, notify.KindUpdateAvailable, notifier.events[0].Kind)
+	assert.Equal(t, "Miniflux update available", notifier.events[0].Title)
+	assert.Equal(t, "miniflux", notifier.events[0].App)
+
+	// Already-reported updates aren't reported again
+	require.NoError(t, c.Check(context.Background()))
+	assert.Len(t, notifier.events, 2)
+
+	resolver["docker.io/miniflux/miniflux:latest"] = "sha256:newer"
+	require.NoError(t, c.Check(context.Background()))
+	assert.Len(t, notifier.events, 3)
+}
@@ -0,0 +1,111 @@
+package logship
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// Sinks
+const (
+	SinkLoki   = "loki"   // Loki's push API
+	SinkVector = "vector" // a Vector http_server source
+)
+
+// BundledLokiURL is the Loki app from the catalog, which runs on the host
+// network; a loki sink without a URL ships there
+const BundledLokiURL = "http://localhost:3100"
+
+// Redacted replaces the password when the configuration is returned to
+// clients. Saving a configuration that still contains it keeps the stored
+// value.
+const Redacted = "********"
+
+// labelName is what Loki accepts as a label name
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are set on every entry and can't be overridden
+var reservedLabels = map[string]bool{"job": true, "host": true, "app": true, "unit": true, "level": true}
+
+// Config is the persisted log shipping configuration
+type Config struct {
+	Enabled bool   `json:"enabled"`
+	Sink    string `json:"sink"`          // loki or vector
+	URL     string `json:"url,omitempty"` // empty with loki uses BundledLokiURL
+	// Username and Password authenticate with HTTP basic auth, as hosted
+	// Loki services require
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Labels are added to every entry, e.g. to tell several hosts apart
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Validate checks the sink, URL and labels. A disabled configuration is
+// still validated so it can be enabled later without changes.
+func (c Config) Validate() error {
+	switch c.Sink {
+	case SinkLoki:
+	case SinkVector:
+		if c.URL == "" {
+			return fmt.Errorf("vector requires a url")
+		}
+	case "":
+		if c.Enabled {
+			return fmt.Errorf("sink is required")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown sink %q", c.Sink)
+	}
+
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http(s) url")
+		}
+	}
+	for name := range c.Labels {
+		if !labelName.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		if reservedLabels[name] {
+			return fmt.Errorf("label %q is set by the host agent", name)
+		}
+	}
+	return nil
+}
+
+// endpoint returns the URL entries are posted to
+func (c Config) endpoint() string {
+	if c.Sink != SinkLoki {
+		return c.URL
+	}
+	base := c.URL
+	if base == "" {
+		base = BundledLokiURL
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	// Accept both the server's address and the full push URL
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/loki/api/v1/push"
+	}
+	return u.String()
+}
+
+// Redact returns a copy with the password replaced by Redacted
+func (c Config) Redact() Config {
+	if c.Password != "" {
+		c.Password = Redacted
+	}
+	return c
+}
+
+// KeepSecrets replaces a Redacted password with the one in prev
+func (c *Config) KeepSecrets(prev Config) {
+	if c.Password == Redacted {
+		c.Password = prev.Password
+	}
+}
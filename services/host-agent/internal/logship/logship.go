@@ -0,0 +1,350 @@
+// Package logship forwards the journal of the host agent and the app
+// services to a Loki or Vector endpoint, so every app's logs can be searched
+// in one place. Entries are shipped at least once: the journal cursor is
+// saved only after a batch is accepted, and shipping resumes from it after a
+// failure or a restart.
+package logship
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// batchSize and batchWait bound how many entries are pushed at once and
+	// how long an entry waits for others to join its batch
+	batchSize = 500
+	batchWait = 2 * time.Second
+
+	pushTimeout = 30 * time.Second
+
+	// maxBackoff caps the wait before retrying after the sink or journalctl
+	// failed
+	maxBackoff = time.Minute
+
+	// maxLine is the longest journal entry read; journald truncates
+	// messages at 48K by default
+	maxLine = 1 << 20
+)
+
+// Status reports how shipping is going
+type Status struct {
+	Running     bool       `json:"running"`
+	Shipped     uint64     `json:"shipped"` // entries since the host agent started
+	LastShipped *time.Time `json:"lastShipped,omitempty"`
+	LastError   string     `json:"lastError,omitempty"` // cleared by the next successful push
+}
+
+// Shipper follows the journal and pushes entries to the configured sink
+type Shipper struct {
+	path       string
+	cursorPath string
+	logger     *slog.Logger
+	client     *http.Client
+	follow     func(ctx context.Context, cursor string) (io.ReadCloser, error)
+	host       string
+
+	restartMu sync.Mutex // serializes restarts
+	mu        sync.Mutex
+	cfg       Config
+	status    Status
+	parent    context.Context // from Start; nil until started
+	stop      context.CancelFunc
+	done      chan struct{}
+}
+
+// NewShipper creates a shipper persisting its configuration at path and
+// the journal cursor it has shipped up to at cursorPath
+func NewShipper(path, cursorPath string, logger *slog.Logger) *Shipper {
+	host, _ := os.Hostname()
+	return &Shipper{
+		path:       path,
+		cursorPath: cursorPath,
+		logger:     logger,
+		client:     &http.Client{Timeout: pushTimeout},
+		follow:     journalctl,
+		host:       host,
+	}
+}
+
+// Load reads the configuration file; a missing file means shipping is off
+func (s *Shipper) Load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading logging config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("parsing logging config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid logging config: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	return nil
+}
+
+// Config returns the current configuration, including the password
+func (s *Shipper) Config() Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+// Status returns how shipping is going
+func (s *Shipper) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// SetConfig validates and persists a new configuration, and restarts
+// shipping with it once Start has been called
+func (s *Shipper) SetConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling logging config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	// The password is stored in the file, so keep it private
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("writing logging config: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cfg = cfg
+	s.status.LastError = ""
+	s.mu.Unlock()
+	s.restart()
+	return nil
+}
+
+// Start ships in the background while ctx is live, if enabled
+func (s *Shipper) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.parent = ctx
+	s.mu.Unlock()
+	s.restart()
+}
+
+// restart stops the running shipper, if any, and starts one with the
+// current configuration
+func (s *Shipper) restart() {
+	s.restartMu.Lock()
+	defer s.restartMu.Unlock()
+
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.mu.Unlock()
+	if stop != nil {
+		stop()
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop, s.done = nil, nil
+	s.status.Running = false
+	if s.parent == nil || !s.cfg.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.parent)
+	done = make(chan struct{})
+	s.stop, s.done = cancel, done
+	s.status.Running = true
+	cfg := s.cfg
+	go func() {
+		defer close(done)
+		s.run(ctx, cfg)
+	}()
+}
+
+// run ships until ctx is cancelled, backing off after failures
+func (s *Shipper) run(ctx context.Context, cfg Config) {
+	s.logger.Info("shipping logs", "sink", cfg.Sink, "url", cfg.endpoint())
+	backoff := time.Second
+	for {
+		shipped, err := s.ship(ctx, cfg)
+		if ctx.Err() != nil {
+			return
+		}
+		if shipped {
+			backoff = time.Second
+		}
+		s.logger.Warn("log shipping failed", "error", err, "retry", backoff)
+		s.mu.Lock()
+		s.status.LastError = err.Error()
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// ship follows the journal from the saved cursor, pushing batches until a
+// push or journalctl fails. It reports whether any batch was shipped.
+func (s *Shipper) ship(ctx context.Context, cfg Config) (bool, error) {
+	followCtx, cancel := context.WithCancel(ctx)
+	r, err := s.follow(followCtx, s.readCursor())
+	if err != nil {
+		cancel()
+		return false, fmt.Errorf("following journal: %w", err)
+	}
+	defer func() {
+		cancel()
+		r.Close()
+	}()
+
+	lines := make(chan []byte)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxLine)
+		for scanner.Scan() {
+			line := append([]byte{}, scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-followCtx.Done():
+				return
+			}
+		}
+	}()
+
+	shipped := false
+	var batch []Entry
+	timer := time.NewTimer(batchWait)
+	timer.Stop()
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		pushCtx, cancel := context.WithTimeout(ctx, pushTimeout)
+		defer cancel()
+		if err := push(pushCtx, s.client, cfg, s.host, batch); err != nil {
+			return err
+		}
+		s.saveCursor(batch[len(batch)-1].Cursor)
+		now := time.Now()
+		s.mu.Lock()
+		s.status.Shipped += uint64(len(batch))
+		s.status.LastShipped = &now
+		s.status.LastError = ""
+		s.mu.Unlock()
+		batch = batch[:0]
+		shipped = true
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return shipped, ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				if err := flush(); err != nil {
+					return shipped, err
+				}
+				return shipped, errors.New("journalctl exited")
+			}
+			entry, err := parseEntry(line)
+			if err != nil {
+				s.logger.Debug("skipping journal entry", "error", err)
+				continue
+			}
+			if len(batch) == 0 {
+				timer.Reset(batchWait)
+			}
+			batch = append(batch, entry)
+			if len(batch) >= batchSize {
+				timer.Stop()
+				if err := flush(); err != nil {
+					return shipped, err
+				}
+			}
+		case <-timer.C:
+			if err := flush(); err != nil {
+				return shipped, err
+			}
+		}
+	}
+}
+
+func (s *Shipper) readCursor() string {
+	data, err := os.ReadFile(s.cursorPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func (s *Shipper) saveCursor(cursor string) {
+	if cursor == "" {
+		return
+	}
+	if err := os.WriteFile(s.cursorPath, []byte(cursor+"\n"), 0600); err != nil {
+		s.logger.Warn("failed to save journal cursor", "error", err)
+	}
+}
+
+// journalctl follows the host agent's unit and the app services' user
+// units, after cursor or from now if there is none
+func journalctl(ctx context.Context, cursor string) (io.ReadCloser, error) {
+	args := []string{"--follow", "--output=json", "--no-pager",
+		"--unit=" + hostAgentUnit, "--user-unit=podman-*.service"}
+	if cursor != "" {
+		args = append(args, "--after-cursor="+cursor, "--lines=all")
+	} else {
+		args = append(args, "--lines=0")
+	}
+
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &cmdReader{ReadCloser: stdout, cmd: cmd}, nil
+}
+
+// cmdReader is a command's output; closing it waits for the command
+type cmdReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (r *cmdReader) Close() error {
+	r.ReadCloser.Close()
+	return r.cmd.Wait()
+}
@@ -0,0 +1,179 @@
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := []Config{
+		{},
+		{Enabled: true, Sink: SinkLoki},
+		{Enabled: true, Sink: SinkLoki, URL: "https://logs.example.com", Labels: map[string]string{"site": "home"}},
+		{Enabled: true, Sink: SinkVector, URL: "http://vector.lan:8686"},
+	}
+	for _, cfg := range valid {
+		assert.NoError(t, cfg.Validate(), cfg)
+	}
+
+	invalid := []Config{
+		{Enabled: true},
+		{Sink: "syslog"},
+		{Sink: SinkVector},
+		{Sink: SinkLoki, URL: "logs.example.com"},
+		{Sink: SinkLoki, Labels: map[string]string{"my-label": "x"}},
+		{Sink: SinkLoki, Labels: map[string]string{"app": "x"}},
+	}
+	for _, cfg := range invalid {
+		assert.Error(t, cfg.Validate(), cfg)
+	}
+}
+
+func TestConfigEndpoint(t *testing.T) {
+	assert.Equal(t, "http://localhost:3100/loki/api/v1/push", Config{Sink: SinkLoki}.endpoint())
+	assert.Equal(t, "https://logs.example.com/loki/api/v1/push", Config{Sink: SinkLoki, URL: "https://logs.example.com/"}.endpoint())
+	assert.Equal(t, "https://logs.example.com/api/push", Config{Sink: SinkLoki, URL: "https://logs.example.com/api/push"}.endpoint())
+	assert.Equal(t, "http://vector.lan:8686", Config{Sink: SinkVector, URL: "http://vector.lan:8686"}.endpoint())
+}
+
+func TestConfigRedact(t *testing.T) {
+	cfg := Config{Sink: SinkLoki, Username: "bloud", Password: "hunter2"}
+	redacted := cfg.Redact()
+	assert.Equal(t, Redacted, redacted.Password)
+	assert.Equal(t, "hunter2", cfg.Password)
+
+	redacted.KeepSecrets(cfg)
+	assert.Equal(t, "hunter2", redacted.Password)
+}
+
+func TestParseEntry(t *testing.T) {
+	entry, err := parseEntry([]byte(`{"__CURSOR":"s=1","__REALTIME_TIMESTAMP":"1700000000000000","_SYSTEMD_USER_UNIT":"podman-apps-postgres.service","PRIORITY":"3","MESSAGE":"connection refused"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "s=1", entry.Cursor)
+	assert.Equal(t, "postgres", entry.App)
+	assert.Equal(t, "error", entry.Level)
+	assert.Equal(t, "connection refused", entry.Message)
+	assert.Equal(t, time.Unix(1700000000, 0), entry.Time)
+
+	// Messages that aren't valid UTF-8 come as byte arrays
+	entry, err = parseEntry([]byte(`{"_SYSTEMD_UNIT":"bloud-host-agent.service","MESSAGE":[104,105]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "host-agent", entry.App)
+	assert.Equal(t, "hi", entry.Message)
+}
+
+func TestEncodeLoki(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	entries := []Entry{
+		{Time: at, Unit: "podman-miniflux.service", App: "miniflux", Level: "info", Message: "one"},
+		{Time: at, Unit: "podman-immich.service", App: "immich", Level: "info", Message: "two"},
+		{Time: at, Unit: "podman-miniflux.service", App: "miniflux", Level: "info", Message: "three"},
+	}
+	data, contentType, err := encodeLoki(Config{Labels: map[string]string{"site": "home"}}, "bloud", entries)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+
+	var body struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	require.NoError(t, json.Unmarshal(data, &body))
+	require.Len(t, body.Streams, 2)
+	assert.Equal(t, map[string]string{"job": "bloud", "host": "bloud", "app": "miniflux", "unit": "podman-miniflux.service", "level": "info", "site": "home"}, body.Streams[0].Stream)
+	assert.Equal(t, [][2]string{{"1700000000000000000", "one"}, {"1700000000000000000", "three"}}, body.Streams[0].Values)
+}
+
+func TestShipper(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	var auth string
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, strings.TrimSpace(string(data)))
+		auth = r.Header.Get("Authorization")
+	}))
+	defer sink.Close()
+
+	dir := t.TempDir()
+	s := NewShipper(filepath.Join(dir, "logging.json"), filepath.Join(dir, "logging.cursor"), testLogger())
+	var cursors []string
+	s.follow = func(ctx context.Context, cursor string) (io.ReadCloser, error) {
+		mu.Lock()
+		cursors = append(cursors, cursor)
+		mu.Unlock()
+		return io.NopCloser(strings.NewReader(
+			`{"__CURSOR":"c1","_SYSTEMD_USER_UNIT":"podman-miniflux.service","MESSAGE":"hello"}` + "\n" +
+				`{"__CURSOR":"c2","_SYSTEMD_USER_UNIT":"podman-miniflux.service","MESSAGE":"world"}` + "\n")), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	assert.False(t, s.Status().Running)
+
+	require.NoError(t, s.SetConfig(Config{Enabled: true, Sink: SinkVector, URL: sink.URL, Username: "bloud", Password: "secret"}))
+	require.Eventually(t, func() bool { return s.Status().Shipped == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	assert.Contains(t, received[0], `"message":"hello"`)
+	assert.Contains(t, received[0], `"app":"miniflux"`)
+	assert.NotEmpty(t, auth)
+	assert.Equal(t, "", cursors[0])
+	mu.Unlock()
+
+	// The cursor of the last shipped entry is saved for the next run
+	data, err := os.ReadFile(filepath.Join(dir, "logging.cursor"))
+	require.NoError(t, err)
+	assert.Equal(t, "c2\n", string(data))
+
+	// Disabling stops shipping; the config survives a reload
+	require.NoError(t, s.SetConfig(Config{Sink: SinkVector, URL: sink.URL}))
+	assert.False(t, s.Status().Running)
+	reloaded := NewShipper(filepath.Join(dir, "logging.json"), "", testLogger())
+	require.NoError(t, reloaded.Load())
+	assert.Equal(t, sink.URL, reloaded.Config().URL)
+}
+
+func TestShipper_SinkError(t *testing.T) {
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "entry too far behind", http.StatusBadRequest)
+	}))
+	defer sink.Close()
+
+	dir := t.TempDir()
+	s := NewShipper(filepath.Join(dir, "logging.json"), filepath.Join(dir, "logging.cursor"), testLogger())
+	s.follow = func(ctx context.Context, cursor string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(`{"__CURSOR":"c1","MESSAGE":"hello"}` + "\n")), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	require.NoError(t, s.SetConfig(Config{Enabled: true, Sink: SinkLoki, URL: sink.URL}))
+	require.Eventually(t, func() bool { return s.Status().LastError != "" }, 5*time.Second, 10*time.Millisecond)
+
+	assert.Contains(t, s.Status().LastError, "entry too far behind")
+	assert.Zero(t, s.Status().Shipped)
+	assert.NoFileExists(t, filepath.Join(dir, "logging.cursor"))
+}
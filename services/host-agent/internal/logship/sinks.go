@@ -0,0 +1,202 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hostAgentUnit is the host agent's own system unit
+const hostAgentUnit = "bloud-host-agent.service"
+
+// levels maps syslog priorities to the level label
+var levels = []string{"emerg", "alert", "crit", "error", "warning", "notice", "info", "debug"}
+
+// Entry is one journal entry
+type Entry struct {
+	Cursor  string
+	Time    time.Time
+	Unit    string
+	App     string // the app the unit runs, or "host-agent"
+	Level   string
+	Message string
+}
+
+// journalEntry is the subset of journalctl's JSON output that is shipped
+type journalEntry struct {
+	Cursor   string          `json:"__CURSOR"`
+	Realtime string          `json:"__REALTIME_TIMESTAMP"` // microseconds since the epoch
+	Unit     string          `json:"_SYSTEMD_UNIT"`
+	UserUnit string          `json:"_SYSTEMD_USER_UNIT"`
+	Priority string          `json:"PRIORITY"`
+	Message  json.RawMessage `json:"MESSAGE"`
+}
+
+// parseEntry decodes one line of journalctl -o json
+func parseEntry(line []byte) (Entry, error) {
+	var je journalEntry
+	if err := json.Unmarshal(line, &je); err != nil {
+		return Entry{}, fmt.Errorf("parsing journal entry: %w", err)
+	}
+
+	entry := Entry{Cursor: je.Cursor, Unit: je.UserUnit, Message: journalMessage(je.Message)}
+	if entry.Unit == "" {
+		entry.Unit = je.Unit
+	}
+	entry.App = appForUnit(entry.Unit)
+	if us, err := strconv.ParseInt(je.Realtime, 10, 64); err == nil {
+		entry.Time = time.UnixMicro(us)
+	}
+	if p, err := strconv.Atoi(je.Priority); err == nil && p >= 0 && p < len(levels) {
+		entry.Level = levels[p]
+	}
+	return entry, nil
+}
+
+// journalMessage decodes MESSAGE, which journald sends as an array of bytes
+// when it isn't valid UTF-8
+func journalMessage(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if err := json.Unmarshal(raw, &ints); err == nil {
+		for _, i := range ints {
+			b = append(b, byte(i))
+		}
+	}
+	return strings.ToValidUTF8(string(b), "�")
+}
+
+// appForUnit names the app a unit runs: podman-apps-postgres.service is
+// postgres, podman-miniflux.service is miniflux
+func appForUnit(unit string) string {
+	if unit == hostAgentUnit {
+		return "host-agent"
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(unit, "podman-"), ".service")
+	return strings.TrimPrefix(name, "apps-")
+}
+
+// encoder turns a batch of entries into a request body
+type encoder func(cfg Config, host string, entries []Entry) ([]byte, string, error)
+
+// encoders by sink, returning the body and its content type
+var encoders = map[string]encoder{
+	SinkLoki:   encodeLoki,
+	SinkVector: encodeVector,
+}
+
+// encodeLoki groups entries into Loki streams by their labels
+func encodeLoki(cfg Config, host string, entries []Entry) ([]byte, string, error) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	streams := make(map[string]*stream)
+	var keys []string
+	for _, e := range entries {
+		labels := entryLabels(cfg, host, e)
+		key := labelKey(labels)
+		s, ok := streams[key]
+		if !ok {
+			s = &stream{Stream: labels}
+			streams[key] = s
+			keys = append(keys, key)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), e.Message})
+	}
+
+	body := struct {
+		Streams []*stream `json:"streams"`
+	}{}
+	for _, key := range keys {
+		body.Streams = append(body.Streams, streams[key])
+	}
+	data, err := json.Marshal(body)
+	return data, "application/json", err
+}
+
+// encodeVector writes one JSON object per line, for an http_server source
+// with the json codec and newline-delimited framing
+func encodeVector(cfg Config, host string, entries []Entry) ([]byte, string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		event := map[string]any{"timestamp": e.Time.UTC().Format(time.RFC3339Nano), "message": e.Message}
+		for k, v := range entryLabels(cfg, host, e) {
+			event[k] = v
+		}
+		if err := enc.Encode(event); err != nil {
+			return nil, "", err
+		}
+	}
+	return buf.Bytes(), "application/x-ndjson", nil
+}
+
+// entryLabels returns the labels of an entry: where it came from and the
+// configured labels
+func entryLabels(cfg Config, host string, e Entry) map[string]string {
+	labels := map[string]string{"job": "bloud", "host": host, "app": e.App, "unit": e.Unit}
+	if e.Level != "" {
+		labels["level"] = e.Level
+	}
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + labels[k] + "\x00")
+	}
+	return b.String()
+}
+
+// push sends a batch to the configured sink
+func push(ctx context.Context, client *http.Client, cfg Config, host string, entries []Entry) error {
+	encode, ok := encoders[cfg.Sink]
+	if !ok {
+		return fmt.Errorf("unknown sink %q", cfg.Sink)
+	}
+	body, contentType, err := encode(cfg, host, entries)
+	if err != nil {
+		return fmt.Errorf("encoding entries: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.endpoint(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if cfg.Username != "" || cfg.Password != "" {
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", cfg.Sink, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
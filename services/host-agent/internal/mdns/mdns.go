@@ -0,0 +1,199 @@
+// Package mdns advertises installed apps on the LAN, so browsers can reach
+// http://jellyfin.local and the app shows up in service browsers. Each app
+// gets a <name>.local address record for the host and an _http._tcp service
+// pointing at Traefik, which routes the hostname to the app.
+//
+// Records are published with avahi-publish, which keeps a record registered
+// while it runs; avahi withdraws it when the process exits.
+package mdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// restartDelay is how long to wait before republishing an app whose
+// avahi-publish exited, e.g. because avahi-daemon restarted
+const restartDelay = 10 * time.Second
+
+// App is an app to advertise
+type App struct {
+	Name        string // advertised as <name>.local
+	DisplayName string // the service's instance name
+}
+
+// Hostname returns the mDNS hostname an app is advertised under
+func Hostname(app string) string {
+	return app + ".local"
+}
+
+// PublisherInterface advertises apps
+type PublisherInterface interface {
+	// Sync advertises exactly the given apps, withdrawing any others
+	Sync(apps []App)
+	// Close withdraws every app
+	Close()
+}
+
+// Compile-time assertion
+var _ PublisherInterface = (*Publisher)(nil)
+
+// Publisher advertises apps with avahi-publish
+type Publisher struct {
+	port    int // Traefik's HTTP port
+	logger  *slog.Logger
+	command func(ctx context.Context, args ...string) *exec.Cmd
+	address func() (string, error) // the host's LAN address
+
+	mu        sync.Mutex
+	published map[string]*publication
+}
+
+// publication is one app's running avahi-publish processes
+type publication struct {
+	app    App
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPublisher creates a publisher running the avahi-publish binary at bin,
+// advertising services on Traefik's port
+func NewPublisher(bin string, port int, logger *slog.Logger) *Publisher {
+	return &Publisher{
+		port:   port,
+		logger: logger,
+		command: func(ctx context.Context, args ...string) *exec.Cmd {
+			return exec.CommandContext(ctx, bin, args...)
+		},
+		address:   lanAddress,
+		published: map[string]*publication{},
+	}
+}
+
+// Sync advertises exactly the given apps, withdrawing any others
+func (p *Publisher) Sync(apps []App) {
+	wanted := map[string]App{}
+	for _, app := range apps {
+		wanted[app.Name] = app
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, pub := range p.published {
+		if app, ok := wanted[name]; !ok || app != pub.app {
+			p.logger.Info("withdrawing mDNS advertisement", "hostname", Hostname(name))
+			pub.stop()
+			delete(p.published, name)
+		}
+	}
+	for name, app := range wanted {
+		if _, ok := p.published[name]; ok {
+			continue
+		}
+		p.logger.Info("advertising app over mDNS", "hostname", Hostname(name))
+		ctx, cancel := context.WithCancel(context.Background())
+		pub := &publication{app: app, cancel: cancel, done: make(chan struct{})}
+		p.published[name] = pub
+		go p.run(ctx, pub)
+	}
+}
+
+// Published returns the names of the apps being advertised
+func (p *Publisher) Published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Sorted(maps.Keys(p.published))
+}
+
+// Close withdraws every app
+func (p *Publisher) Close() {
+	p.Sync(nil)
+}
+
+// stop withdraws the app and waits for its processes to exit
+func (pub *publication) stop() {
+	pub.cancel()
+	<-pub.done
+}
+
+// run keeps an app advertised until ctx is cancelled
+func (p *Publisher) run(ctx context.Context, pub *publication) {
+	defer close(pub.done)
+	for {
+		err := p.publish(ctx, pub.app)
+		if ctx.Err() != nil {
+			return
+		}
+		p.logger.Warn("mDNS advertisement stopped, retrying", "hostname", Hostname(pub.app.Name), "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(restartDelay):
+		}
+	}
+}
+
+// publish runs avahi-publish for an app's address record and service until
+// ctx is cancelled or one of them exits
+func (p *Publisher) publish(ctx context.Context, app App) error {
+	ip, err := p.address()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	hostname := Hostname(app.Name)
+	cmds := []*exec.Cmd{
+		// The host's own name already owns the reverse lookup
+		p.command(ctx, "--address", "--no-reverse", hostname, ip),
+		p.command(ctx, "--service", "--host="+hostname, app.DisplayName, "_http._tcp", strconv.Itoa(p.port), "path=/"),
+	}
+
+	exited := make(chan error, len(cmds))
+	var started int
+	for _, cmd := range cmds {
+		if err = cmd.Start(); err != nil {
+			err = fmt.Errorf("starting avahi-publish: %w", err)
+			break
+		}
+		started++
+		go func() { exited <- cmd.Wait() }()
+	}
+	if err == nil {
+		err = <-exited
+		started--
+		if err == nil {
+			err = errors.New("avahi-publish exited")
+		}
+	}
+
+	// Stop the rest, so the app is never half advertised
+	cancel()
+	for range started {
+		<-exited
+	}
+	return err
+}
+
+// lanAddress returns the address the host reaches the LAN from: the source
+// address of the route to the mDNS multicast group. Dialing UDP sends
+// nothing.
+func lanAddress() (string, error) {
+	conn, err := net.Dial("udp4", "224.0.0.251:5353")
+	if err != nil {
+		return "", fmt.Errorf("finding LAN address: %w", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
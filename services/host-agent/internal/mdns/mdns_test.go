@@ -0,0 +1,95 @@
+package mdns
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAvahi records avahi-publish invocations and runs sleep in their place
+type fakeAvahi struct {
+	mu      sync.Mutex
+	running map[string]int // invocation -> processes still running
+}
+
+func newTestPublisher(t *testing.T) (*Publisher, *fakeAvahi) {
+	t.Helper()
+	fake := &fakeAvahi{running: map[string]int{}}
+	p := NewPublisher("avahi-publish", 8080, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.address = func() (string, error) { return "192.168.1.20", nil }
+	p.command = func(ctx context.Context, args ...string) *exec.Cmd {
+		key := strings.Join(args, " ")
+		fake.mu.Lock()
+		fake.running[key]++
+		fake.mu.Unlock()
+		cmd := exec.CommandContext(ctx, "sleep", "60")
+		cmd.Cancel = func() error {
+			fake.mu.Lock()
+			fake.running[key]--
+			fake.mu.Unlock()
+			return cmd.Process.Kill()
+		}
+		return cmd
+	}
+	t.Cleanup(p.Close)
+	return p, fake
+}
+
+func (f *fakeAvahi) count(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running[key]
+}
+
+func TestPublisher_Sync(t *testing.T) {
+	p, fake := newTestPublisher(t)
+
+	jellyfinAddress := "--address --no-reverse jellyfin.local 192.168.1.20"
+	jellyfinService := "--service --host=jellyfin.local Jellyfin _http._tcp 8080 path=/"
+	minifluxAddress := "--address --no-reverse miniflux.local 192.168.1.20"
+
+	p.Sync([]App{{Name: "jellyfin", DisplayName: "Jellyfin"}, {Name: "miniflux", DisplayName: "Miniflux"}})
+	assert.Equal(t, []string{"jellyfin", "miniflux"}, p.Published())
+	require.Eventually(t, func() bool {
+		return fake.count(jellyfinAddress) == 1 && fake.count(jellyfinService) == 1 && fake.count(minifluxAddress) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Uninstalled apps are withdrawn, the rest keep running
+	p.Sync([]App{{Name: "jellyfin", DisplayName: "Jellyfin"}})
+	assert.Equal(t, []string{"jellyfin"}, p.Published())
+	assert.Zero(t, fake.count(minifluxAddress))
+	assert.Equal(t, 1, fake.count(jellyfinAddress))
+
+	p.Close()
+	assert.Empty(t, p.Published())
+	assert.Zero(t, fake.count(jellyfinAddress))
+	assert.Zero(t, fake.count(jellyfinService))
+}
+
+func TestPublisher_PublishStopsWhenOneExits(t *testing.T) {
+	p, _ := newTestPublisher(t)
+	command := p.command
+	p.command = func(ctx context.Context, args ...string) *exec.Cmd {
+		if args[0] == "--address" {
+			return exec.CommandContext(ctx, "false")
+		}
+		return command(ctx, args...)
+	}
+
+	done := make(chan error)
+	go func() { done <- p.publish(context.Background(), App{Name: "jellyfin", DisplayName: "Jellyfin"}) }()
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("publish kept running after avahi-publish exited")
+	}
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// gatherTimeout bounds one scrape of the app exporter. Measuring container
+// CPU takes podman about a second; Prometheus' default scrape timeout is 10.
+const gatherTimeout = 8 * time.Second
+
+// Snapshot is the state the app exporter publishes, gathered on each scrape
+type Snapshot struct {
+	Apps []AppSnapshot
+	Host HostSnapshot
+}
+
+// AppSnapshot is one installed app
+type AppSnapshot struct {
+	Name   string
+	Status string
+	// Restarts is how often systemd restarted the app's service since it
+	// was last started by hand; nil when systemd couldn't be asked
+	Restarts *uint64
+	// Usage is nil when per-app stats aren't available
+	Usage *AppUsage
+}
+
+// AppUsage is an app's resource usage summed over its containers
+type AppUsage struct {
+	CPU         float64 // percent of one core
+	Memory      float64 // percent of the host's memory
+	MemoryBytes uint64
+	NetRx       uint64 // bytes since the containers started
+	NetTx       uint64
+}
+
+// HostSnapshot is the host's resource usage and NixOS generation
+type HostSnapshot struct {
+	CPU         float64 // percent
+	Memory      float64
+	Disk        float64
+	Temperature float64 // °C; 0 without a sensor
+	Throttles   uint64
+	Generation  int // 0 when unknown
+	NixOS       string
+}
+
+var (
+	appStatusDesc = prometheus.NewDesc(namespace+"_app_status",
+		"Installed app by its current status; always 1.", []string{"app", "status"}, nil)
+	appRestartsDesc = prometheus.NewDesc(namespace+"_app_restarts_total",
+		"Times systemd restarted the app's service.", []string{"app"}, nil)
+	appCPUDesc = prometheus.NewDesc(namespace+"_app_cpu_percent",
+		"CPU used by the app's containers, as a percentage of one core.", []string{"app"}, nil)
+	appMemoryDesc = prometheus.NewDesc(namespace+"_app_memory_bytes",
+		"Memory used by the app's containers.", []string{"app"}, nil)
+	appMemoryPercentDesc = prometheus.NewDesc(namespace+"_app_memory_percent",
+		"Memory used by the app's containers, as a percentage of the host's memory.", []string{"app"}, nil)
+	appNetRxDesc = prometheus.NewDesc(namespace+"_app_network_receive_bytes_total",
+		"Bytes received by the app's containers.", []string{"app"}, nil)
+	appNetTxDesc = prometheus.NewDesc(namespace+"_app_network_transmit_bytes_total",
+		"Bytes sent by the app's containers.", []string{"app"}, nil)
+
+	hostCPUDesc = prometheus.NewDesc(namespace+"_host_cpu_percent",
+		"Host CPU usage.", nil, nil)
+	hostMemoryDesc = prometheus.NewDesc(namespace+"_host_memory_percent",
+		"Host memory usage.", nil, nil)
+	hostDiskDesc = prometheus.NewDesc(namespace+"_host_disk_percent",
+		"Root filesystem usage.", nil, nil)
+	hostTempDesc = prometheus.NewDesc(namespace+"_host_cpu_temperature_celsius",
+		"CPU package temperature.", nil, nil)
+	hostThrottlesDesc = prometheus.NewDesc(namespace+"_host_cpu_throttles_total",
+		"Thermal throttle events since boot.", nil, nil)
+	nixosGenerationDesc = prometheus.NewDesc(namespace+"_nixos_generation",
+		"Current NixOS system generation, labelled with the NixOS version.", []string{"version"}, nil)
+)
+
+// Exporter is a collector that publishes a Snapshot gathered on each scrape.
+// Unlike the collectors in Registry it holds no state: apps that are
+// uninstalled simply stop appearing.
+type Exporter struct {
+	gather func(ctx context.Context) Snapshot
+}
+
+// NewExporter creates an exporter publishing what gather returns
+func NewExporter(gather func(ctx context.Context) Snapshot) *Exporter {
+	return &Exporter{gather: gather}
+}
+
+// Describe implements prometheus.Collector
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		appStatusDesc, appRestartsDesc, appCPUDesc, appMemoryDesc, appMemoryPercentDesc, appNetRxDesc, appNetTxDesc,
+		hostCPUDesc, hostMemoryDesc, hostDiskDesc, hostTempDesc, hostThrottlesDesc, nixosGenerationDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), gatherTimeout)
+	defer cancel()
+	snap := e.gather(ctx)
+
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+	counter := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, labels...)
+	}
+
+	for _, app := range snap.Apps {
+		gauge(appStatusDesc, 1, app.Name, app.Status)
+		if app.Restarts != nil {
+			counter(appRestartsDesc, float64(*app.Restarts), app.Name)
+		}
+		if u := app.Usage; u != nil {
+			gauge(appCPUDesc, u.CPU, app.Name)
+			gauge(appMemoryDesc, float64(u.MemoryBytes), app.Name)
+			gauge(appMemoryPercentDesc, u.Memory, app.Name)
+			counter(appNetRxDesc, float64(u.NetRx), app.Name)
+			counter(appNetTxDesc, float64(u.NetTx), app.Name)
+		}
+	}
+
+	host := snap.Host
+	gauge(hostCPUDesc, host.CPU)
+	gauge(hostMemoryDesc, host.Memory)
+	gauge(hostDiskDesc, host.Disk)
+	if host.Temperature > 0 {
+		gauge(hostTempDesc, host.Temperature)
+	}
+	counter(hostThrottlesDesc, float64(host.Throttles))
+	if host.Generation > 0 {
+		gauge(nixosGenerationDesc, float64(host.Generation), host.NixOS)
+	}
+}
+
+// ExporterHandler serves an exporter from its own registry, so a slow
+// gather doesn't hold up scrapes of the agent's own metrics on /metrics
+func ExporterHandler(e *Exporter) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ScrapeConfig returns a Prometheus scrape_configs section for the host
+// agent's own metrics and the app exporter at target (host:port)
+func ScrapeConfig(target string) string {
+	return `scrape_configs:
+  - job_name: bloud-host-agent
+    metrics_path: /metrics
+    static_configs:
+      - targets: [` + strconv.Quote(target) + `]
+  - job_name: bloud-apps
+    metrics_path: /metrics/apps
+    scrape_interval: 1m
+    scrape_timeout: 10s
+    static_configs:
+      - targets: [` + strconv.Quote(target) + `]
+`
+}
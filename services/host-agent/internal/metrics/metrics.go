@@ -0,0 +1,136 @@
+// Package metrics defines the Prometheus metrics exported by the host agent
+// on /metrics. Collectors are package-level so any package can record into
+// them without threading a registry through constructors.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bloud"
+
+// Registry holds every host-agent collector. A dedicated registry (rather than
+// the global default) keeps tests and other importers from sharing state.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	// HTTPRequestDuration observes API request latency by chi route pattern
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of host-agent HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	// SSESubscribers counts open server-sent event streams
+	SSESubscribers = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sse_subscribers",
+		Help:      "Number of connected server-sent event clients.",
+	}, []string{"stream"})
+
+	// QueueDepth counts install/uninstall requests waiting for a result
+	QueueDepth = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "operation_queue_depth",
+		Help:      "Install and uninstall operations queued or in progress.",
+	})
+
+	// RebuildDuration observes nixos-rebuild runs by operation and outcome
+	RebuildDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "nixos_rebuild_duration_seconds",
+		Help:      "Duration of nixos-rebuild runs.",
+		Buckets:   []float64{5, 15, 30, 60, 120, 300, 600, 1200},
+	}, []string{"operation", "result"})
+
+	// AppHealthy reports the most recent health check result per app
+	AppHealthy = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "app_healthy",
+		Help:      "Whether the app passed its most recent health check (1) or not (0).",
+	}, []string{"app"})
+
+	// AppHealthChecks counts health check outcomes per app
+	AppHealthChecks = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "app_health_checks_total",
+		Help:      "Health checks run against installed apps.",
+	}, []string{"app", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB exports connection pool statistics for db
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveRebuild records a finished nixos-rebuild run
+func ObserveRebuild(operation string, success bool, duration time.Duration) {
+	RebuildDuration.WithLabelValues(operation, resultLabel(success)).Observe(duration.Seconds())
+}
+
+// HealthResult is the outcome of an app's most recent health check
+type HealthResult struct {
+	Healthy   bool      `json:"healthy"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// lastHealth holds the latest HealthResult per app for the health summary
+var lastHealth sync.Map
+
+// LastHealthCheck returns an app's most recent health check outcome, if any
+// was recorded since the host agent started
+func LastHealthCheck(app string) (HealthResult, bool) {
+	v, ok := lastHealth.Load(app)
+	if !ok {
+		return HealthResult{}, false
+	}
+	return v.(HealthResult), true
+}
+
+// RecordHealthCheck records the outcome of an app health check
+func RecordHealthCheck(app string, healthy bool) {
+	lastHealth.Store(app, HealthResult{Healthy: healthy, CheckedAt: time.Now()})
+	AppHealthChecks.WithLabelValues(app, resultLabel(healthy)).Inc()
+	if healthy {
+		AppHealthy.WithLabelValues(app).Set(1)
+	} else {
+		AppHealthy.WithLabelValues(app).Set(0)
+	}
+}
+
+// ForgetApp drops per-app series once an app is uninstalled
+func ForgetApp(app string) {
+	lastHealth.Delete(app)
+	AppHealthy.DeleteLabelValues(app)
+	AppHealthChecks.DeletePartialMatch(prometheus.Labels{"app": app})
+}
+
+func resultLabel(success bool) string {
+	if success {
+		return "success"
+	}
+	return "failure"
+}
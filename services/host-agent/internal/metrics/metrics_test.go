@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordHealthCheck(t *testing.T) {
+	t.Cleanup(func() { ForgetApp("miniflux") })
+
+	RecordHealthCheck("miniflux", true)
+	assert.Equal(t, 1.0, testutil.ToFloat64(AppHealthy.WithLabelValues("miniflux")))
+
+	RecordHealthCheck("miniflux", false)
+	assert.Equal(t, 0.0, testutil.ToFloat64(AppHealthy.WithLabelValues("miniflux")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(AppHealthChecks.WithLabelValues("miniflux", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(AppHealthChecks.WithLabelValues("miniflux", "failure")))
+
+	last, ok := LastHealthCheck("miniflux")
+	assert.True(t, ok)
+	assert.False(t, last.Healthy)
+	assert.WithinDuration(t, time.Now(), last.CheckedAt, time.Second)
+}
+
+func TestForgetApp(t *testing.T) {
+	t.Cleanup(func() { ForgetApp("sonarr") })
+
+	RecordHealthCheck("radarr", true)
+	RecordHealthCheck("sonarr", true)
+
+	ForgetApp("radarr")
+	_, ok := LastHealthCheck("radarr")
+	assert.False(t, ok)
+
+	expected := `
+# HELP bloud_app_healthy Whether the app passed its most recent health check (1) or not (0).
+# TYPE bloud_app_healthy gauge
+bloud_app_healthy{app="sonarr"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(AppHealthy, strings.NewReader(expected), "bloud_app_healthy"))
+}
+
+func TestObserveRebuild(t *testing.T) {
+	ObserveRebuild("switch", true, 42*time.Second)
+	ObserveRebuild("switch", false, time.Second)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(RebuildDuration, "bloud_nixos_rebuild_duration_seconds"))
+}
+
+func TestExporter(t *testing.T) {
+	restarts := uint64(3)
+	exporter := NewExporter(func(ctx context.Context) Snapshot {
+		return Snapshot{
+			Apps: []AppSnapshot{
+				{Name: "miniflux", Status: "running", Restarts: &restarts, Usage: &AppUsage{CPU: 2.5, MemoryBytes: 1024, NetRx: 10}},
+				{Name: "immich", Status: "error"},
+			},
+			Host: HostSnapshot{CPU: 12, Generation: 42, NixOS: "24.11"},
+		}
+	})
+
+	expected := `
+# HELP bloud_app_restarts_total Times systemd restarted the app's service.
+# TYPE bloud_app_restarts_total counter
+bloud_app_restarts_total{app="miniflux"} 3
+# HELP bloud_app_status Installed app by its current status; always 1.
+# TYPE bloud_app_status gauge
+bloud_app_status{app="immich",status="error"} 1
+bloud_app_status{app="miniflux",status="running"} 1
+# HELP bloud_nixos_generation Current NixOS system generation, labelled with the NixOS version.
+# TYPE bloud_nixos_generation gauge
+bloud_nixos_generation{version="24.11"} 42
+`
+	assert.NoError(t, testutil.CollectAndCompare(exporter, strings.NewReader(expected),
+		"bloud_app_status", "bloud_app_restarts_total", "bloud_nixos_generation"))
+
+	// Apps without usage have no usage series; no sensor, no temperature
+	assert.Equal(t, 1, testutil.CollectAndCount(exporter, "bloud_app_cpu_percent"))
+	assert.Equal(t, 0, testutil.CollectAndCount(exporter, "bloud_host_cpu_temperature_celsius"))
+}
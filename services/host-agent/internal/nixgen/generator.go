@@ -14,6 +14,10 @@ type AppConfig struct {
 	Name         string
 	Integrations map[string]string
 	Enabled      bool
+
+	// Credentials maps systemd credential IDs the app's unit loads to the
+	// env var its container reads each one as
+	Credentials map[string]string
 }
 
 // GlobalConfig represents global Nix configuration options
@@ -146,6 +150,22 @@ func (g *Generator) generateConfig(tx *Transaction) string {
 			// Note: Integration config is stored in state but not emitted to Nix
 			// since the Nix modules handle their own integration (e.g., database URLs)
 			b.WriteString(fmt.Sprintf("  bloud.apps.%s.enable = true;\n", name))
+
+			// Secrets are loaded with LoadCredential= from the credentials
+			// directory rather than written into the unit or env file
+			if len(app.Credentials) > 0 {
+				ids := make([]string, 0, len(app.Credentials))
+				for id := range app.Credentials {
+					ids = append(ids, id)
+				}
+				sort.Strings(ids)
+
+				b.WriteString(fmt.Sprintf("  bloud.apps.%s.credentials = {\n", name))
+				for _, id := range ids {
+					b.WriteString(fmt.Sprintf("    %q = %q;\n", id, app.Credentials[id]))
+				}
+				b.WriteString("  };\n")
+			}
 		}
 	}
 
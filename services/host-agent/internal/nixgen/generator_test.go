@@ -412,3 +412,26 @@ func TestGenerator_ApplyCreatesDirIfNeeded(t *testing.T) {
 	_, err = os.Stat(filepath.Join(tmpDir, "nested", "deep"))
 	require.NoError(t, err)
 }
+
+func TestGenerator_Credentials(t *testing.T) {
+	gen := NewGenerator("/etc/bloud/apps.nix", "/etc/bloud/nixos")
+
+	tx := &Transaction{
+		Apps: map[string]AppConfig{
+			"miniflux": {
+				Name:        "miniflux",
+				Enabled:     true,
+				Credentials: map[string]string{"oauth-client-secret": "OAUTH2_CLIENT_SECRET"},
+			},
+			"radarr": {
+				Name:    "radarr",
+				Enabled: true,
+			},
+		},
+	}
+
+	config := gen.generateConfig(tx)
+
+	assert.Contains(t, config, "  bloud.apps.miniflux.credentials = {\n    \"oauth-client-secret\" = \"OAUTH2_CLIENT_SECRET\";\n  };\n")
+	assert.NotContains(t, config, "bloud.apps.radarr.credentials")
+}
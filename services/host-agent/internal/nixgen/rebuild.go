@@ -9,7 +9,10 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
 )
 
 // NixosSystemPath is the PATH required for NixOS system commands.
@@ -23,6 +26,10 @@ import (
 // (sudo resets the environment by default).
 const NixosSystemPath = "/run/current-system/sw/bin:/run/wrappers/bin:/bin"
 
+// RebuildLogName is the file in the data directory holding the output of the
+// last rebuild
+const RebuildLogName = "last-rebuild.log"
+
 // Rebuilder handles nixos-rebuild operations
 type Rebuilder struct {
 	flakePath string
@@ -30,7 +37,8 @@ type Rebuilder struct {
 	logger    *slog.Logger
 	dryRun    bool
 	impure    bool // Allow impure evaluation (for runtime-generated config)
-	useSudo   bool // Run nixos-rebuild with sudo
+	useSudo   bool   // Run nixos-rebuild with sudo
+	outputLog string // File the last rebuild's output is saved to, if set
 }
 
 // NewRebuilder creates a nixos-rebuild wrapper
@@ -44,6 +52,28 @@ func NewRebuilder(flakePath, hostname string, logger *slog.Logger) *Rebuilder {
 	}
 }
 
+// SetOutputLog saves the output of each rebuild to path, replacing the
+// previous one, so a failed rebuild can be looked into afterwards
+func (r *Rebuilder) SetOutputLog(path string) {
+	r.outputLog = path
+}
+
+// saveOutput writes a rebuild's output to the output log, if one is set
+func (r *Rebuilder) saveOutput(action string, result *RebuildResult) {
+	if r.outputLog == "" || r.dryRun {
+		return
+	}
+
+	header := fmt.Sprintf("nixos-rebuild %s at %s, took %s, success: %t\n",
+		action, time.Now().UTC().Format(time.RFC3339), result.Duration.Round(time.Millisecond), result.Success)
+	if result.ErrorMessage != "" {
+		header += "error: " + result.ErrorMessage + "\n"
+	}
+	if err := os.WriteFile(r.outputLog, []byte(header+"\n"+result.Output+"\n"), 0600); err != nil {
+		r.logger.Warn("failed to save rebuild output", "path", r.outputLog, "error", err)
+	}
+}
+
 // RebuildResult contains the result of a rebuild operation
 type RebuildResult struct {
 	Success      bool
@@ -114,7 +144,7 @@ func (r *Rebuilder) Switch(ctx context.Context) (*RebuildResult, error) {
 		args = append(args, "--dry-run")
 	}
 
-	r.logger.Info("running nixos-rebuild", "args", args, "sudo", r.useSudo)
+	r.logger.InfoContext(ctx, "running nixos-rebuild", "args", args, "sudo", r.useSudo)
 
 	cmd := r.nixosRebuildCmd(ctx, args)
 
@@ -147,12 +177,14 @@ func (r *Rebuilder) Switch(ctx context.Context) (*RebuildResult, error) {
 	<-outputDone
 
 	result.Duration = time.Since(start)
+	metrics.ObserveRebuild("switch", cmdErr == nil, result.Duration)
 	result.Output = strings.Join(outputLines, "\n")
 
 	if cmdErr != nil {
 		result.Success = false
 		result.ErrorMessage = cmdErr.Error()
-		r.logger.Error("nixos-rebuild failed",
+		r.saveOutput("switch", result)
+		r.logger.ErrorContext(ctx, "nixos-rebuild failed",
 			"error", cmdErr,
 			"duration", result.Duration,
 		)
@@ -160,7 +192,8 @@ func (r *Rebuilder) Switch(ctx context.Context) (*RebuildResult, error) {
 	}
 
 	result.Success = true
-	r.logger.Info("nixos-rebuild completed successfully",
+	r.saveOutput("switch", result)
+	r.logger.InfoContext(ctx, "nixos-rebuild completed successfully",
 		"duration", result.Duration,
 		"changes", len(result.Changes),
 	)
@@ -229,15 +262,18 @@ func (r *Rebuilder) Test(ctx context.Context) (*RebuildResult, error) {
 	output, err := cmd.CombinedOutput()
 
 	result.Duration = time.Since(start)
+	metrics.ObserveRebuild("test", err == nil, result.Duration)
 	result.Output = string(output)
 
 	if err != nil {
 		result.Success = false
 		result.ErrorMessage = err.Error()
+		r.saveOutput("test", result)
 		return result, nil
 	}
 
 	result.Success = true
+	r.saveOutput("test", result)
 	return result, nil
 }
 
@@ -260,6 +296,7 @@ type RebuildEvent struct {
 // SwitchStream performs a nixos-rebuild switch with streaming output
 func (r *Rebuilder) SwitchStream(ctx context.Context, events chan<- RebuildEvent) {
 	defer close(events)
+	start := time.Now()
 
 	args := []string{"switch"}
 
@@ -271,7 +308,7 @@ func (r *Rebuilder) SwitchStream(ctx context.Context, events chan<- RebuildEvent
 		args = append(args, "--impure")
 	}
 
-	r.logger.Info("running nixos-rebuild (streaming)", "args", args, "sudo", r.useSudo)
+	r.logger.InfoContext(ctx, "running nixos-rebuild (streaming)", "args", args, "sudo", r.useSudo)
 	events <- RebuildEvent{Type: "output", Message: fmt.Sprintf("Running: nixos-rebuild %s", strings.Join(args, " "))}
 
 	cmd := r.nixosRebuildCmd(ctx, args)
@@ -296,28 +333,38 @@ func (r *Rebuilder) SwitchStream(ctx context.Context, events chan<- RebuildEvent
 		return
 	}
 
-	// Stream stdout
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			events <- RebuildEvent{Type: "output", Message: scanner.Text()}
-		}
-	}()
-
-	// Stream stderr
-	go func() {
-		scanner := bufio.NewScanner(stderr)
+	// Stream stdout and stderr, keeping the lines for the output log
+	var (
+		outputMu    sync.Mutex
+		outputLines []string
+	)
+	forward := func(pipe io.Reader) {
+		scanner := bufio.NewScanner(pipe)
 		for scanner.Scan() {
+			outputMu.Lock()
+			outputLines = append(outputLines, scanner.Text())
+			outputMu.Unlock()
 			events <- RebuildEvent{Type: "output", Message: scanner.Text()}
 		}
-	}()
+	}
+	go forward(stdout)
+	go forward(stderr)
 
 	// Wait for command to complete
-	if err := cmd.Wait(); err != nil {
+	err = cmd.Wait()
+	result := &RebuildResult{Success: err == nil, Duration: time.Since(start)}
+	metrics.ObserveRebuild("switch", result.Success, result.Duration)
+	outputMu.Lock()
+	result.Output = strings.Join(outputLines, "\n")
+	outputMu.Unlock()
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		r.saveOutput("switch", result)
 		events <- RebuildEvent{Type: "error", Message: fmt.Sprintf("Rebuild failed: %v", err)}
 		events <- RebuildEvent{Type: "complete", Success: false}
 		return
 	}
+	r.saveOutput("switch", result)
 
 	events <- RebuildEvent{Type: "complete", Success: true, Message: "Rebuild completed successfully"}
 }
@@ -334,16 +381,19 @@ func (r *Rebuilder) Rollback(ctx context.Context) (*RebuildResult, error) {
 	output, err := cmd.CombinedOutput()
 
 	result.Duration = time.Since(start)
+	metrics.ObserveRebuild("rollback", err == nil, result.Duration)
 	result.Output = string(output)
 
 	if err != nil {
 		result.Success = false
 		result.ErrorMessage = err.Error()
+		r.saveOutput("rollback", result)
 		r.logger.Error("rollback failed", "error", err)
 		return result, nil
 	}
 
 	result.Success = true
+	r.saveOutput("rollback", result)
 	r.logger.Info("rollback completed successfully")
 
 	return result, nil
@@ -364,6 +414,21 @@ func (r *Rebuilder) StopUserService(ctx context.Context, appName string) error {
 	return nil
 }
 
+// RestartUserService restarts a systemd user service for an app
+func (r *Rebuilder) RestartUserService(ctx context.Context, appName string) error {
+	serviceName := fmt.Sprintf("podman-%s.service", appName)
+	r.logger.Info("restarting user service", "service", serviceName)
+
+	output, err := r.userSystemctlCmd(ctx, []string{"restart", serviceName}).CombinedOutput()
+	if err != nil {
+		r.logger.Warn("failed to restart service", "service", serviceName, "error", err, "output", string(output))
+		return fmt.Errorf("failed to restart %s: %w", serviceName, err)
+	}
+
+	r.logger.Info("service restarted", "service", serviceName)
+	return nil
+}
+
 // ReloadAndRestartApps reloads systemd user daemon and restarts all bloud apps.
 // Call after nixos-rebuild to pick up new/changed unit files and restart apps.
 func (r *Rebuilder) ReloadAndRestartApps(ctx context.Context) error {
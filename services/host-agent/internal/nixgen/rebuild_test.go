@@ -3,9 +3,12 @@ package nixgen
 import (
 	"log/slog"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewRebuilder(t *testing.T) {
@@ -160,3 +163,29 @@ func TestRebuildEvent_Types(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveOutput(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	r := NewRebuilder("/path/to/flake", "myhost", logger)
+
+	// Without an output log nothing is written
+	r.saveOutput("switch", &RebuildResult{Output: "ignored"})
+
+	path := filepath.Join(t.TempDir(), RebuildLogName)
+	r.SetOutputLog(path)
+	r.saveOutput("switch", &RebuildResult{Output: "building...", ErrorMessage: "exit status 1", Duration: time.Second})
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "nixos-rebuild switch at")
+	assert.Contains(t, string(data), "success: false")
+	assert.Contains(t, string(data), "error: exit status 1")
+	assert.Contains(t, string(data), "building...")
+
+	// The next rebuild replaces it
+	r.saveOutput("rollback", &RebuildResult{Success: true, Output: "activating"})
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "building...")
+	assert.Contains(t, string(data), "nixos-rebuild rollback at")
+}
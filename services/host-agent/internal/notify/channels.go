@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramAPIURL is the Telegram Bot API base URL (overridden in tests)
+var telegramAPIURL = "https://api.telegram.org"
+
+var httpClient = &http.Client{}
+
+// Send delivers an event to a single channel
+func Send(ctx context.Context, ch ChannelConfig, event Event) error {
+	if err := ch.validateSettings(); err != nil {
+		return err
+	}
+	switch ch.Type {
+	case TypeEmail:
+		return sendEmail(ch.Email, event)
+	case TypeNtfy:
+		return sendNtfy(ctx, ch.Ntfy, event)
+	case TypeTelegram:
+		return sendTelegram(ctx, ch.Telegram, event)
+	case TypeDiscord:
+		return sendDiscord(ctx, ch.Discord, event)
+	}
+	return fmt.Errorf("unknown channel type %q", ch.Type)
+}
+
+// sendEmail sends over SMTP, upgrading with STARTTLS when the server offers it
+func sendEmail(cfg *EmailSettings, event Event) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	if err := smtp.SendMail(addr, auth, cfg.From, cfg.To, emailMessage(cfg, event)); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+// emailMessage renders an event as a plain text RFC 5322 message
+func emailMessage(cfg *EmailSettings, event Event) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerSafe(cfg.From))
+	fmt.Fprintf(&b, "To: %s\r\n", headerSafe(strings.Join(cfg.To, ", ")))
+	fmt.Fprintf(&b, "Subject: [Bloud] %s\r\n", headerSafe(event.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(event.Message, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// sendNtfy publishes to an ntfy topic URL
+func sendNtfy(ctx context.Context, cfg *NtfySettings, event Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, strings.NewReader(event.Message))
+	if err != nil {
+		return fmt.Errorf("ntfy: %w", err)
+	}
+	req.Header.Set("Title", headerSafe(event.Title))
+	req.Header.Set("Tags", event.Kind)
+	if priority := ntfyPriority(event); priority != "" {
+		req.Header.Set("Priority", priority)
+	}
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+	return do(req, "ntfy")
+}
+
+// ntfyPriority maps an alert's severity to an ntfy priority; other events
+// are high priority when something broke
+func ntfyPriority(event Event) string {
+	switch event.Severity {
+	case "critical":
+		return "urgent"
+	case "warning":
+		return "high"
+	case "info":
+		return "default"
+	}
+	if event.Kind == KindAppDown || event.Kind == KindBackupFailed || event.Kind == KindUpdateFailed {
+		return "high"
+	}
+	return ""
+}
+
+// sendTelegram sends a message through the Bot API
+func sendTelegram(ctx context.Context, cfg *TelegramSettings, event Event) error {
+	body, err := json.Marshal(map[string]string{
+		"chat_id": cfg.ChatID,
+		"text":    event.Title + "\n\n" + event.Message,
+	})
+	if err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIURL, cfg.BotToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		// The URL contains the token, so don't echo it
+		return fmt.Errorf("telegram: invalid request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return do(req, "telegram")
+}
+
+// sendDiscord posts an embed to a Discord webhook
+func sendDiscord(ctx context.Context, cfg *DiscordSettings, event Event) error {
+	body, err := json.Marshal(map[string]any{
+		"embeds": []map[string]any{{
+			"title":       event.Title,
+			"description": event.Message,
+			"timestamp":   event.Time.Format(time.RFC3339),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("discord: invalid webhook url")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return do(req, "discord")
+}
+
+// do sends req and turns non-2xx responses into errors. Transport errors are
+// reported without the URL, which may contain a token.
+func do(req *http.Request, service string) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %w", service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s: %s", service, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// headerSafe strips line breaks so a value can't inject extra headers
+func headerSafe(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,196 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Channel types
+const (
+	TypeEmail    = "email"
+	TypeNtfy     = "ntfy"
+	TypeTelegram = "telegram"
+	TypeDiscord  = "discord"
+)
+
+// Redacted replaces credentials when a configuration is returned to clients.
+// Saving a configuration that still contains it keeps the stored value.
+const Redacted = "********"
+
+// Config is the persisted notification configuration
+type Config struct {
+	Channels []ChannelConfig `json:"channels"`
+}
+
+// ChannelConfig configures one destination. Exactly the settings block
+// matching Type is used.
+type ChannelConfig struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"` // email, ntfy, telegram or discord
+	Enabled bool     `json:"enabled"`
+	Events  []string `json:"events,omitempty"` // event kinds to send; empty sends all
+
+	Email    *EmailSettings    `json:"email,omitempty"`
+	Ntfy     *NtfySettings     `json:"ntfy,omitempty"`
+	Telegram *TelegramSettings `json:"telegram,omitempty"`
+	Discord  *DiscordSettings  `json:"discord,omitempty"`
+}
+
+// EmailSettings configures delivery over SMTP
+type EmailSettings struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"` // defaults to 587
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// NtfySettings configures an ntfy topic, e.g. https://ntfy.sh/my-bloud
+type NtfySettings struct {
+	URL   string `json:"url"`
+	Token string `json:"token,omitempty"` // access token for protected topics
+}
+
+// TelegramSettings configures a Telegram bot
+type TelegramSettings struct {
+	BotToken string `json:"botToken"`
+	ChatID   string `json:"chatId"`
+}
+
+// DiscordSettings configures a Discord webhook
+type DiscordSettings struct {
+	WebhookURL string `json:"webhookUrl"`
+}
+
+// Wants reports whether the channel should receive events of kind
+func (c ChannelConfig) Wants(kind string) bool {
+	return c.Enabled && (len(c.Events) == 0 || slices.Contains(c.Events, kind))
+}
+
+// Validate checks every channel has the settings its type needs and assigns
+// IDs to new channels
+func (c *Config) Validate() error {
+	seen := make(map[string]bool)
+	for i := range c.Channels {
+		ch := &c.Channels[i]
+		if ch.ID == "" {
+			ch.ID = newChannelID()
+		}
+		if seen[ch.ID] {
+			return fmt.Errorf("duplicate channel id %q", ch.ID)
+		}
+		seen[ch.ID] = true
+
+		for _, kind := range ch.Events {
+			if !slices.Contains(Kinds, kind) {
+				return fmt.Errorf("channel %q: unknown event %q", ch.Name, kind)
+			}
+		}
+		if err := ch.validateSettings(); err != nil {
+			return fmt.Errorf("channel %q: %w", ch.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *ChannelConfig) validateSettings() error {
+	switch c.Type {
+	case TypeEmail:
+		if c.Email == nil || c.Email.Host == "" || c.Email.From == "" || len(c.Email.To) == 0 {
+			return fmt.Errorf("email requires host, from and to")
+		}
+	case TypeNtfy:
+		if c.Ntfy == nil || !isHTTPURL(c.Ntfy.URL) {
+			return fmt.Errorf("ntfy requires an http(s) topic url")
+		}
+	case TypeTelegram:
+		if c.Telegram == nil || c.Telegram.BotToken == "" || c.Telegram.ChatID == "" {
+			return fmt.Errorf("telegram requires botToken and chatId")
+		}
+	case TypeDiscord:
+		if c.Discord == nil || !isHTTPURL(c.Discord.WebhookURL) {
+			return fmt.Errorf("discord requires an http(s) webhookUrl")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", c.Type)
+	}
+	return nil
+}
+
+// Redact returns a copy with credentials replaced by Redacted
+func (c Config) Redact() Config {
+	out := Config{Channels: make([]ChannelConfig, len(c.Channels))}
+	for i, ch := range c.Channels {
+		for _, secret := range ch.secrets() {
+			if *secret != "" {
+				*secret = Redacted
+			}
+		}
+		out.Channels[i] = ch
+	}
+	return out
+}
+
+// KeepSecrets replaces Redacted values with the matching channel's values in
+// prev, so clients can save a configuration they were given redacted
+func (c *Config) KeepSecrets(prev Config) {
+	old := make(map[string]ChannelConfig)
+	for _, ch := range prev.Channels {
+		old[ch.ID] = ch
+	}
+	for i := range c.Channels {
+		ch := &c.Channels[i]
+		prevCh, ok := old[ch.ID]
+		prevSecrets := prevCh.secrets()
+		for j, secret := range ch.secrets() {
+			if *secret != Redacted {
+				continue
+			}
+			if ok && prevCh.Type == ch.Type && j < len(prevSecrets) {
+				*secret = *prevSecrets[j]
+			} else {
+				*secret = ""
+			}
+		}
+	}
+}
+
+// secrets returns pointers to the channel's credential fields. The settings
+// blocks are copied first so the result never aliases another config.
+func (c *ChannelConfig) secrets() []*string {
+	switch {
+	case c.Type == TypeEmail && c.Email != nil:
+		email := *c.Email
+		c.Email = &email
+		return []*string{&c.Email.Password}
+	case c.Type == TypeNtfy && c.Ntfy != nil:
+		ntfy := *c.Ntfy
+		c.Ntfy = &ntfy
+		return []*string{&c.Ntfy.Token}
+	case c.Type == TypeTelegram && c.Telegram != nil:
+		telegram := *c.Telegram
+		c.Telegram = &telegram
+		return []*string{&c.Telegram.BotToken}
+	case c.Type == TypeDiscord && c.Discord != nil:
+		discord := *c.Discord
+		c.Discord = &discord
+		// The webhook URL embeds its token
+		return []*string{&c.Discord.WebhookURL}
+	}
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func newChannelID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
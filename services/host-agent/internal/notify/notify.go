@@ -0,0 +1,187 @@
+// Package notify delivers system events (an app going down, an update being
+// available, a failed backup, the CPU running hot) to the channels the admin
+// configured: email over SMTP, ntfy, Telegram and Discord webhooks.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event kinds
+const (
+	KindAppDown         = "app.down"
+	KindUpdateAvailable = "update.available"
+	KindUpdateApplied   = "update.applied"
+	KindUpdateFailed    = "update.failed"
+	KindBackupFailed    = "backup.failed"
+	KindBackupStale     = "backup.stale"
+	KindDiskFull        = "disk.full"
+	KindTemperatureHigh = "temperature.high"
+	KindTest            = "test"
+)
+
+// Kinds lists every event kind a channel can filter on
+var Kinds = []string{KindAppDown, KindUpdateAvailable, KindUpdateApplied, KindUpdateFailed, KindBackupFailed, KindBackupStale, KindDiskFull, KindTemperatureHigh, KindTest}
+
+// sendTimeout bounds delivery to a single channel
+const sendTimeout = 30 * time.Second
+
+// Event is a notification to deliver
+type Event struct {
+	Kind    string `json:"kind"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	App     string `json:"app,omitempty"`
+	// Severity is info, warning or critical for alerts; empty otherwise
+	Severity string    `json:"severity,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// Publisher accepts events for delivery. Publish must not block.
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Compile-time assertion that Notifier implements Publisher
+var _ Publisher = (*Notifier)(nil)
+
+// Notifier holds the channel configuration and delivers events to it
+type Notifier struct {
+	path   string
+	logger *slog.Logger
+	send   func(ctx context.Context, ch ChannelConfig, event Event) error
+
+	mu  sync.RWMutex
+	cfg Config
+}
+
+// NewNotifier creates a notifier persisting its configuration at path
+func NewNotifier(path string, logger *slog.Logger) *Notifier {
+	return &Notifier{
+		path:   path,
+		logger: logger,
+		send:   Send,
+		cfg:    Config{Channels: []ChannelConfig{}},
+	}
+}
+
+// Load reads the configuration file; a missing file means no channels
+func (n *Notifier) Load() error {
+	data, err := os.ReadFile(n.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading notification config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("parsing notification config: %w", err)
+	}
+	if cfg.Channels == nil {
+		cfg.Channels = []ChannelConfig{}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cfg = cfg
+	return nil
+}
+
+// Config returns the current configuration, including credentials
+func (n *Notifier) Config() Config {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.cfg
+}
+
+// SetConfig validates and persists a new configuration
+func (n *Notifier) SetConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.Channels == nil {
+		cfg.Channels = []ChannelConfig{}
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling notification config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(n.path), 0700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	// Credentials are stored in the file, so keep it private
+	if err := os.WriteFile(n.path, data, 0600); err != nil {
+		return fmt.Errorf("writing notification config: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cfg = cfg
+	return nil
+}
+
+// Publish delivers an event in the background to every channel that wants it
+func (n *Notifier) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, ch := range n.Config().Channels {
+		if !ch.Wants(event.Kind) {
+			continue
+		}
+		go func(ch ChannelConfig) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := n.send(ctx, ch, event); err != nil {
+				n.logger.Warn("failed to send notification", "channel", ch.Name, "type", ch.Type, "kind", event.Kind, "error", err)
+			}
+		}(ch)
+	}
+}
+
+// Test sends a test event synchronously to one channel, or to every enabled
+// channel when id is empty, returning the error per channel ID
+func (n *Notifier) Test(ctx context.Context, id string) (map[string]error, error) {
+	event := Event{
+		Kind:    KindTest,
+		Title:   "Bloud test notification",
+		Message: "Notifications from your Bloud server are working.",
+		Time:    time.Now(),
+	}
+
+	results := make(map[string]error)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, ch := range n.Config().Channels {
+		if id != "" && ch.ID != id || id == "" && !ch.Enabled {
+			continue
+		}
+		wg.Add(1)
+		go func(ch ChannelConfig) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+			defer cancel()
+			err := n.send(ctx, ch, event)
+			mu.Lock()
+			results[ch.ID] = err
+			mu.Unlock()
+		}(ch)
+	}
+	wg.Wait()
+
+	if id != "" && len(results) == 0 {
+		return nil, fmt.Errorf("channel %q not found", id)
+	}
+	return results, nil
+}
@@ -0,0 +1,208 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := Config{Channels: []ChannelConfig{
+		{Name: "phone", Type: TypeNtfy, Ntfy: &NtfySettings{URL: "https://ntfy.sh/bloud"}},
+	}}
+	require.NoError(t, cfg.Validate())
+	assert.NotEmpty(t, cfg.Channels[0].ID, "new channels get an ID")
+
+	invalid := []ChannelConfig{
+		{Name: "no settings", Type: TypeDiscord},
+		{Name: "bad url", Type: TypeNtfy, Ntfy: &NtfySettings{URL: "ntfy.sh/bloud"}},
+		{Name: "no recipients", Type: TypeEmail, Email: &EmailSettings{Host: "smtp", From: "a@b"}},
+		{Name: "no chat", Type: TypeTelegram, Telegram: &TelegramSettings{BotToken: "t"}},
+		{Name: "unknown type", Type: "pager"},
+		{Name: "unknown event", Type: TypeDiscord, Discord: &DiscordSettings{WebhookURL: "https://d"}, Events: []string{"app.exploded"}},
+	}
+	for _, ch := range invalid {
+		cfg := Config{Channels: []ChannelConfig{ch}}
+		assert.Error(t, cfg.Validate(), ch.Name)
+	}
+
+	dup := Config{Channels: []ChannelConfig{
+		{ID: "a", Type: TypeDiscord, Discord: &DiscordSettings{WebhookURL: "https://d"}},
+		{ID: "a", Type: TypeDiscord, Discord: &DiscordSettings{WebhookURL: "https://d"}},
+	}}
+	assert.ErrorContains(t, dup.Validate(), "duplicate")
+}
+
+func TestConfig_RedactAndKeepSecrets(t *testing.T) {
+	stored := Config{Channels: []ChannelConfig{
+		{ID: "mail", Type: TypeEmail, Email: &EmailSettings{Host: "smtp", From: "a@b", To: []string{"c@d"}, Password: "hunter2"}},
+		{ID: "tg", Type: TypeTelegram, Telegram: &TelegramSettings{BotToken: "123:abc", ChatID: "42"}},
+	}}
+
+	redacted := stored.Redact()
+	assert.Equal(t, Redacted, redacted.Channels[0].Email.Password)
+	assert.Equal(t, Redacted, redacted.Channels[1].Telegram.BotToken)
+	assert.Equal(t, "hunter2", stored.Channels[0].Email.Password, "redacting must not modify the original")
+
+	// Saving the redacted config back keeps the stored credentials
+	redacted.Channels[1].Telegram.ChatID = "43"
+	redacted.KeepSecrets(stored)
+	assert.Equal(t, "hunter2", redacted.Channels[0].Email.Password)
+	assert.Equal(t, "123:abc", redacted.Channels[1].Telegram.BotToken)
+	assert.Equal(t, "43", redacted.Channels[1].Telegram.ChatID)
+
+	// A redacted value on an unknown channel is cleared rather than saved literally
+	unknown := Config{Channels: []ChannelConfig{{ID: "new", Type: TypeNtfy, Ntfy: &NtfySettings{URL: "https://n", Token: Redacted}}}}
+	unknown.KeepSecrets(stored)
+	assert.Empty(t, unknown.Channels[0].Ntfy.Token)
+}
+
+func TestNotifier_PersistsConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.json")
+	n := NewNotifier(path, slog.Default())
+	require.NoError(t, n.Load(), "missing file is not an error")
+	assert.Empty(t, n.Config().Channels)
+
+	cfg := Config{Channels: []ChannelConfig{
+		{ID: "d", Name: "discord", Type: TypeDiscord, Enabled: true, Discord: &DiscordSettings{WebhookURL: "https://discord.test/hook"}},
+	}}
+	require.NoError(t, n.SetConfig(cfg))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	reloaded := NewNotifier(path, slog.Default())
+	require.NoError(t, reloaded.Load())
+	assert.Equal(t, cfg, reloaded.Config())
+
+	assert.Error(t, n.SetConfig(Config{Channels: []ChannelConfig{{Type: "pager"}}}))
+	assert.Equal(t, cfg, n.Config(), "invalid configs are not applied")
+}
+
+func TestNotifier_PublishFiltersByKind(t *testing.T) {
+	n := NewNotifier(filepath.Join(t.TempDir(), "n.json"), slog.Default())
+	require.NoError(t, n.SetConfig(Config{Channels: []ChannelConfig{
+		{ID: "all", Type: TypeDiscord, Enabled: true, Discord: &DiscordSettings{WebhookURL: "https://d"}},
+		{ID: "updates", Type: TypeDiscord, Enabled: true, Events: []string{KindUpdateAvailable}, Discord: &DiscordSettings{WebhookURL: "https://d"}},
+		{ID: "off", Type: TypeDiscord, Enabled: false, Discord: &DiscordSettings{WebhookURL: "https://d"}},
+	}}))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var sent []string
+	n.send = func(_ context.Context, ch ChannelConfig, event Event) error {
+		defer wg.Done()
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, ch.ID+":"+event.Kind)
+		return nil
+	}
+
+	wg.Add(1)
+	n.Publish(Event{Kind: KindAppDown, Title: "down"})
+	wg.Wait()
+	wg.Add(2)
+	n.Publish(Event{Kind: KindUpdateAvailable, Title: "update"})
+	wg.Wait()
+
+	assert.ElementsMatch(t, []string{"all:app.down", "all:update.available", "updates:update.available"}, sent)
+}
+
+func TestNotifier_Test(t *testing.T) {
+	n := NewNotifier(filepath.Join(t.TempDir(), "n.json"), slog.Default())
+	require.NoError(t, n.SetConfig(Config{Channels: []ChannelConfig{
+		{ID: "ok", Type: TypeDiscord, Enabled: true, Discord: &DiscordSettings{WebhookURL: "https://d"}},
+		{ID: "off", Type: TypeDiscord, Discord: &DiscordSettings{WebhookURL: "https://d"}},
+	}}))
+	n.send = func(_ context.Context, ch ChannelConfig, event Event) error {
+		assert.Equal(t, KindTest, event.Kind)
+		if ch.ID == "off" {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+
+	results, err := n.Test(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]error{"ok": nil}, results, "only enabled channels by default")
+
+	// A specific channel is tested even when disabled
+	results, err = n.Test(context.Background(), "off")
+	require.NoError(t, err)
+	assert.ErrorIs(t, results["off"], io.ErrUnexpectedEOF)
+
+	_, err = n.Test(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+func TestNtfyPriority(t *testing.T) {
+	assert.Equal(t, "high", ntfyPriority(Event{Kind: KindAppDown}))
+	assert.Equal(t, "", ntfyPriority(Event{Kind: KindUpdateAvailable}))
+	assert.Equal(t, "urgent", ntfyPriority(Event{Kind: KindAppDown, Severity: "critical"}))
+	assert.Equal(t, "default", ntfyPriority(Event{Kind: KindDiskFull, Severity: "info"}))
+}
+
+func TestSend_HTTPChannels(t *testing.T) {
+	var got []*http.Request
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, r)
+		bodies = append(bodies, string(body))
+		if strings.Contains(r.URL.Path, "fail") {
+			http.Error(w, "nope", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+	telegramAPIURL = srv.URL
+	defer func() { telegramAPIURL = "https://api.telegram.org" }()
+
+	event := Event{Kind: KindAppDown, Title: "Radarr is down", Message: "Health check failed", App: "radarr", Time: time.Now()}
+	ctx := context.Background()
+
+	require.NoError(t, Send(ctx, ChannelConfig{Type: TypeNtfy, Ntfy: &NtfySettings{URL: srv.URL + "/bloud", Token: "tk"}}, event))
+	assert.Equal(t, "/bloud", got[0].URL.Path)
+	assert.Equal(t, "Radarr is down", got[0].Header.Get("Title"))
+	assert.Equal(t, "high", got[0].Header.Get("Priority"))
+	assert.Equal(t, "Bearer tk", got[0].Header.Get("Authorization"))
+	assert.Equal(t, "Health check failed", bodies[0])
+
+	require.NoError(t, Send(ctx, ChannelConfig{Type: TypeTelegram, Telegram: &TelegramSettings{BotToken: "123:abc", ChatID: "42"}}, event))
+	assert.Equal(t, "/bot123:abc/sendMessage", got[1].URL.Path)
+	var tg map[string]string
+	require.NoError(t, json.Unmarshal([]byte(bodies[1]), &tg))
+	assert.Equal(t, "42", tg["chat_id"])
+	assert.Contains(t, tg["text"], "Radarr is down")
+
+	require.NoError(t, Send(ctx, ChannelConfig{Type: TypeDiscord, Discord: &DiscordSettings{WebhookURL: srv.URL + "/webhook"}}, event))
+	assert.Contains(t, bodies[2], `"title":"Radarr is down"`)
+
+	err := Send(ctx, ChannelConfig{Type: TypeDiscord, Discord: &DiscordSettings{WebhookURL: srv.URL + "/fail"}}, event)
+	assert.ErrorContains(t, err, "403")
+}
+
+func TestEmailMessage(t *testing.T) {
+	msg := string(emailMessage(&EmailSettings{From: "bloud@home", To: []string{"a@b", "c@d"}}, Event{
+		Title:   "Backup failed\r\nBcc: evil@x",
+		Message: "line one\nline two",
+		Time:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}))
+
+	assert.Contains(t, msg, "To: a@b, c@d\r\n")
+	assert.Contains(t, msg, "Subject: [Bloud] Backup failed  Bcc: evil@x\r\n")
+	assert.NotContains(t, msg, "\r\nBcc:")
+	assert.Contains(t, msg, "\r\n\r\nline one\r\nline two\r\n")
+}
@@ -621,6 +621,15 @@ func (f *FakeAppStore) Install(name, displayName, version string, integrationCon
 	return nil
 }
 
+func (f *FakeAppStore) InstallAll(apps []store.AppInstall) error {
+	for _, app := range apps {
+		if err := f.Install(app.Name, app.DisplayName, app.Version, app.IntegrationConfig, app.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (f *FakeAppStore) UpdateStatus(name, status string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -631,6 +640,10 @@ func (f *FakeAppStore) UpdateStatus(name, status string) error {
 	return nil
 }
 
+func (f *FakeAppStore) UpdateStatusReason(name, status, reason string) error {
+	return f.UpdateStatus(name, status)
+}
+
 func (f *FakeAppStore) EnsureSystemApp(name, displayName string, port int) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -664,6 +677,20 @@ func (f *FakeAppStore) UpdateDisplayName(name, displayName string) error {
 	return nil
 }
 
+func (f *FakeAppStore) UpdateImage(name, image, digest string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if app, ok := f.apps[name]; ok {
+		if app.ImageDigest != "" && app.ImageDigest != digest {
+			app.PreviousImageDigest = app.ImageDigest
+		}
+		app.Image = image
+		app.ImageDigest = digest
+		f.notify()
+	}
+	return nil
+}
+
 func (f *FakeAppStore) Uninstall(name string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -672,6 +699,14 @@ func (f *FakeAppStore) Uninstall(name string) error {
 	return nil
 }
 
+func (f *FakeAppStore) GetUninstalled() ([]*store.InstalledApp, error) {
+	return []*store.InstalledApp{}, nil
+}
+
+func (f *FakeAppStore) GetUninstalledByName(name string) (*store.InstalledApp, error) {
+	return nil, nil
+}
+
 func (f *FakeAppStore) IsInstalled(name string) (bool, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
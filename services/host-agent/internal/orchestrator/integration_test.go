@@ -138,6 +138,34 @@ func TestIntegration_Install_TransactionContent(t *testing.T) {
 	assert.True(t, postgres.Enabled)
 }
 
+func TestIntegration_Install_TransactionCredentials(t *testing.T) {
+	h := newIntegrationHarness(t)
+	defer h.Close()
+
+	h.cache.AddApp(&catalog.App{
+		Name: "miniflux",
+		SSO: catalog.SSO{
+			Strategy: "native-oidc",
+			Env:      catalog.SSOEnv{ClientSecret: "OAUTH2_CLIENT_SECRET"},
+		},
+	})
+	h.cache.AddApp(&catalog.App{Name: "qbittorrent"})
+
+	ctx := context.Background()
+	_, err := h.orch.Install(ctx, InstallRequest{App: "qbittorrent"})
+	require.NoError(t, err)
+	result, err := h.orch.Install(ctx, InstallRequest{App: "miniflux"})
+	require.NoError(t, err)
+	require.True(t, result.IsSuccess())
+
+	tx := h.generator.LastTransaction()
+	require.NotNil(t, tx)
+
+	// The client secret is loaded as a credential rather than an env var
+	assert.Equal(t, map[string]string{"oauth-client-secret": "OAUTH2_CLIENT_SECRET"}, tx.Apps["miniflux"].Credentials)
+	assert.Empty(t, tx.Apps["qbittorrent"].Credentials)
+}
+
 func TestIntegration_Install_GraphUpdated(t *testing.T) {
 	h := newIntegrationHarness(t)
 	defer h.Close()
@@ -1,6 +1,10 @@
 package orchestrator
 
-import "context"
+import (
+	"context"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/podman"
+)
 
 // AppOrchestrator defines the interface for app installation orchestrators
 type AppOrchestrator interface {
@@ -15,6 +19,12 @@ type AppOrchestrator interface {
 	RegenerateRoutes() error
 }
 
+// ImageInspector reports the image a container was created from.
+// Implemented by podman.Client.
+type ImageInspector interface {
+	ContainerImage(ctx context.Context, nameOrID string) (*podman.ImageInfo, error)
+}
+
 // InstallRequest specifies what to install and how
 type InstallRequest struct {
 	App     string            `json:"app"`
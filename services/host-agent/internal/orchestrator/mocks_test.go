@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/mdns"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/sso"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
@@ -46,11 +47,21 @@ func (m *MockAppStore) Install(name, displayName, version string, integrationCon
 	return args.Error(0)
 }
 
+func (m *MockAppStore) InstallAll(apps []store.AppInstall) error {
+	args := m.Called(apps)
+	return args.Error(0)
+}
+
 func (m *MockAppStore) UpdateStatus(name, status string) error {
 	args := m.Called(name, status)
 	return args.Error(0)
 }
 
+func (m *MockAppStore) UpdateStatusReason(name, status, reason string) error {
+	args := m.Called(name, status, reason)
+	return args.Error(0)
+}
+
 func (m *MockAppStore) UpdateIntegrationConfig(name string, config map[string]string) error {
 	args := m.Called(name, config)
 	return args.Error(0)
@@ -61,11 +72,32 @@ func (m *MockAppStore) UpdateDisplayName(name, displayName string) error {
 	return args.Error(0)
 }
 
+func (m *MockAppStore) UpdateImage(name, image, digest string) error {
+	args := m.Called(name, image, digest)
+	return args.Error(0)
+}
+
 func (m *MockAppStore) Uninstall(name string) error {
 	args := m.Called(name)
 	return args.Error(0)
 }
 
+func (m *MockAppStore) GetUninstalled() ([]*store.InstalledApp, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*store.InstalledApp), args.Error(1)
+}
+
+func (m *MockAppStore) GetUninstalledByName(name string) (*store.InstalledApp, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.InstalledApp), args.Error(1)
+}
+
 func (m *MockAppStore) IsInstalled(name string) (bool, error) {
 	args := m.Called(name)
 	return args.Bool(0), args.Error(1)
@@ -258,6 +290,19 @@ func (m *MockTraefikGenerator) Preview(apps []*catalog.App) string {
 	return args.String(0)
 }
 
+// MockMDNSPublisher implements mdns.PublisherInterface for testing
+type MockMDNSPublisher struct {
+	mock.Mock
+}
+
+func (m *MockMDNSPublisher) Sync(apps []mdns.App) {
+	m.Called(apps)
+}
+
+func (m *MockMDNSPublisher) Close() {
+	m.Called()
+}
+
 // MockBlueprintGenerator implements sso.BlueprintGeneratorInterface for testing
 type MockBlueprintGenerator struct {
 	mock.Mock
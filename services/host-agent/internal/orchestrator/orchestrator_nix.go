@@ -13,7 +13,10 @@ import (
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/mdns"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/notify"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/sso"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
@@ -27,12 +30,15 @@ type Orchestrator struct {
 	appStore        store.AppStoreInterface
 	generator       nixgen.GeneratorInterface
 	traefikGen      traefikgen.GeneratorInterface
+	mdns            mdns.PublisherInterface
 	blueprintGen    sso.BlueprintGeneratorInterface
 	authentikClient authentik.ClientInterface
 	rebuilder       nixgen.RebuilderInterface
 	dataDir         string
 	logger          *slog.Logger
 	queue           *OperationQueue
+	notifier        notify.Publisher
+	images          ImageInspector
 }
 
 // Config holds Orchestrator configuration
@@ -55,6 +61,9 @@ type Config struct {
 	AuthentikToken   string // Authentik API token for SSO cleanup
 	LDAPBindPassword string // LDAP bind password for service accounts
 	Secrets          *secrets.Manager // Secrets manager for persisting derived secrets
+	Notifier         notify.Publisher // Optional: receives app down events
+	Images           ImageInspector   // Optional: records the image each app runs
+	MDNS             mdns.PublisherInterface // Optional: advertises apps as <name>.local
 
 	// Optional: inject dependencies for testing (if nil, defaults will be created)
 	Generator       nixgen.GeneratorInterface
@@ -77,13 +86,19 @@ func New(cfg Config) *Orchestrator {
 	// Rebuilder
 	var rebuilder nixgen.RebuilderInterface = cfg.Rebuilder
 	if rebuilder == nil {
-		rebuilder = nixgen.NewRebuilder(cfg.FlakePath, cfg.Hostname, cfg.Logger)
+		nixRebuilder := nixgen.NewRebuilder(cfg.FlakePath, cfg.Hostname, cfg.Logger)
+		if cfg.DataDir != "" {
+			nixRebuilder.SetOutputLog(filepath.Join(cfg.DataDir, nixgen.RebuildLogName))
+		}
+		rebuilder = nixRebuilder
 	}
 
 	// Traefik generator
 	var traefikGen traefikgen.GeneratorInterface = cfg.TraefikGen
 	if traefikGen == nil {
-		traefikGen = traefikgen.NewGenerator(cfg.TraefikConfigPath)
+		defaultGen := traefikgen.NewGenerator(cfg.TraefikConfigPath)
+		defaultGen.SetLocalHostnames(cfg.MDNS != nil)
+		traefikGen = defaultGen
 	}
 
 	// Blueprint generator (if SSO config is provided)
@@ -111,11 +126,14 @@ func New(cfg Config) *Orchestrator {
 		appStore:        cfg.AppStore,
 		generator:       generator,
 		traefikGen:      traefikGen,
+		mdns:            cfg.MDNS,
 		blueprintGen:    blueprintGen,
 		authentikClient: authentikClient,
 		rebuilder:       rebuilder,
 		dataDir:         cfg.DataDir,
 		logger:          cfg.Logger,
+		notifier:        cfg.Notifier,
+		images:          cfg.Images,
 	}
 
 	// Create and start the operation queue
@@ -132,6 +150,21 @@ func (o *Orchestrator) Stop() {
 	}
 }
 
+// PauseQueue lets the running install/uninstall batch finish and holds new
+// ones until ResumeQueue, e.g. while the host sleeps.
+func (o *Orchestrator) PauseQueue() {
+	if o.queue != nil {
+		o.queue.Pause()
+	}
+}
+
+// ResumeQueue runs the batches held by PauseQueue.
+func (o *Orchestrator) ResumeQueue() {
+	if o.queue != nil {
+		o.queue.Resume()
+	}
+}
+
 // EnqueueInstall adds an install request to the queue and waits for the result.
 // This is the primary entry point for install operations from HTTP handlers.
 func (o *Orchestrator) EnqueueInstall(ctx context.Context, req InstallRequest) (InstallResponse, error) {
@@ -160,7 +193,7 @@ type InstallResult struct {
 func (o *Orchestrator) Install(ctx context.Context, req InstallRequest) (InstallResponse, error) {
 	result := &InstallResult{App: req.App}
 
-	o.logger.Info("starting Nix installation", "app", req.App)
+	o.logger.InfoContext(ctx, "starting Nix installation", "app", req.App)
 
 	// 1. Build install plan
 	plan, err := o.graph.PlanInstall(req.App)
@@ -183,7 +216,7 @@ func (o *Orchestrator) Install(ctx context.Context, req InstallRequest) (Install
 
 	// 3. Show preview
 	preview := o.generator.Preview(tx)
-	o.logger.Debug("Nix config preview", "config", preview)
+	o.logger.DebugContext(ctx, "Nix config preview", "config", preview)
 
 	// 4. Record intent in database (before Nix rebuild)
 	if err := o.recordInstallIntent(req, plan); err != nil {
@@ -193,7 +226,7 @@ func (o *Orchestrator) Install(ctx context.Context, req InstallRequest) (Install
 
 	// 5. Generate SSO blueprints for apps with native-oidc strategy
 	if err := o.generateSSOBlueprints(tx); err != nil {
-		o.logger.Warn("failed to generate SSO blueprints", "error", err)
+		o.logger.WarnContext(ctx, "failed to generate SSO blueprints", "error", err)
 		// Non-fatal - apps will work, just without SSO
 	}
 
@@ -204,7 +237,7 @@ func (o *Orchestrator) Install(ctx context.Context, req InstallRequest) (Install
 	}
 
 	// 7. Trigger nixos-rebuild switch (atomic transaction)
-	o.logger.Info("triggering nixos-rebuild switch")
+	o.logger.InfoContext(ctx, "triggering nixos-rebuild switch")
 	rebuildResult, err := o.rebuilder.Switch(ctx)
 	if err != nil {
 		result.Error = fmt.Sprintf("nixos-rebuild failed: %v", err)
@@ -215,26 +248,30 @@ func (o *Orchestrator) Install(ctx context.Context, req InstallRequest) (Install
 
 	if !rebuildResult.Success {
 		result.Error = rebuildResult.ErrorMessage
-		o.appStore.UpdateStatus(req.App, "failed")
+		o.appStore.UpdateStatusReason(req.App, "failed", "nixos-rebuild failed")
 		return result, nil
 	}
 
 	// 8. Reload systemd and restart all apps via bloud-apps.target
 	// This properly handles daemon-reload and restarts services in dependency order
 	if err := o.rebuilder.ReloadAndRestartApps(ctx); err != nil {
-		o.logger.Warn("failed to reload and restart apps", "error", err)
+		o.logger.WarnContext(ctx, "failed to reload and restart apps", "error", err)
 		// Don't fail the install - apps may still come up via systemd dependencies
 	}
 
 	// 9. Update database status to 'starting' and begin health checks
 	for appName := range tx.Apps {
 		if err := o.appStore.UpdateStatus(appName, "starting"); err != nil {
-			o.logger.Warn("failed to update app status", "app", appName, "error", err)
+			o.logger.WarnContext(ctx, "failed to update app status", "app", appName, "error", err)
 		}
 		result.AppsInstalled = append(result.AppsInstalled, appName)
 
-		// Start health check polling in background
-		go o.waitForHealthy(appName)
+		// Start health check polling in background, then record the image
+		// the new container runs
+		go func() {
+			o.waitForHealthy(appName)
+			o.recordImage(context.Background(), appName)
+		}()
 	}
 
 	// 10. Update graph state
@@ -243,14 +280,14 @@ func (o *Orchestrator) Install(ctx context.Context, req InstallRequest) (Install
 
 	// 11. Regenerate Traefik routes for all installed apps
 	if err := o.regenerateTraefikRoutes(); err != nil {
-		o.logger.Warn("failed to regenerate Traefik routes", "error", err)
+		o.logger.WarnContext(ctx, "failed to regenerate Traefik routes", "error", err)
 		// Non-fatal - apps may still work, just not via iframe embedding
 	}
 
 	result.Success = true
 	result.GenerationInfo = fmt.Sprintf("Rebuild completed in %v", rebuildResult.Duration)
 
-	o.logger.Info("installation complete",
+	o.logger.InfoContext(ctx, "installation complete",
 		"app", req.App,
 		"apps_installed", result.AppsInstalled,
 		"configured", len(result.Configured),
@@ -331,9 +368,27 @@ func (o *Orchestrator) buildInstallTransaction(req InstallRequest, plan *catalog
 		}
 	}
 
+	o.setCredentials(tx)
+
 	return tx, nil
 }
 
+// setCredentials records the systemd credentials each enabled app loads,
+// so secrets reach containers without passing through the Nix config
+func (o *Orchestrator) setCredentials(tx *nixgen.Transaction) {
+	for name, appConfig := range tx.Apps {
+		if !appConfig.Enabled {
+			continue
+		}
+		app, err := o.catalogCache.Get(name)
+		if err != nil || app == nil {
+			continue
+		}
+		appConfig.Credentials = sso.SSOCredentials(app)
+		tx.Apps[name] = appConfig
+	}
+}
+
 // recordInstallIntent records the installation in the database
 func (o *Orchestrator) recordInstallIntent(req InstallRequest, plan *catalog.InstallPlan) error {
 	integrationConfig := make(map[string]string)
@@ -361,43 +416,44 @@ func (o *Orchestrator) recordInstallIntent(req InstallRequest, plan *catalog.Ins
 		}
 	}
 
-	// Record the main app with port, isSystem, and displayName from catalog
-	mainApp, _ := o.catalogCache.Get(req.App)
-	opts := &store.InstallOptions{}
-	displayName := req.App // fallback to internal name
-	if mainApp != nil {
-		opts.Port = mainApp.Port
-		opts.IsSystem = mainApp.IsSystem
-		displayName = mainApp.DisplayName
-	}
-	if err := o.appStore.Install(req.App, displayName, "", integrationConfig, opts); err != nil {
-		return err
-	}
-
-	// Record dependencies that will be installed
+	// Record the main app and the dependencies it pulls in together, so a
+	// failure part way through doesn't leave some of them marked installing
+	apps := []store.AppInstall{o.appInstall(req.App, integrationConfig)}
+	recorded := map[string]bool{req.App: true}
 	for _, source := range integrationConfig {
+		if recorded[source] {
+			continue
+		}
+		recorded[source] = true
+
 		// Check if already installed
 		existing, err := o.appStore.GetByName(source)
 		if err != nil {
 			return err // Real error
 		}
 		if existing == nil {
-			// Not installed yet, record it with port/isSystem/displayName from catalog
-			depApp, _ := o.catalogCache.Get(source)
-			depOpts := &store.InstallOptions{}
-			depDisplayName := source // fallback to internal name
-			if depApp != nil {
-				depOpts.Port = depApp.Port
-				depOpts.IsSystem = depApp.IsSystem
-				depDisplayName = depApp.DisplayName
-			}
-			if err := o.appStore.Install(source, depDisplayName, "", nil, depOpts); err != nil {
-				return err
-			}
+			apps = append(apps, o.appInstall(source, nil))
 		}
 	}
 
-	return nil
+	return o.appStore.InstallAll(apps)
+}
+
+// appInstall describes an app to record, with port, isSystem, and
+// displayName from the catalog
+func (o *Orchestrator) appInstall(name string, integrationConfig map[string]string) store.AppInstall {
+	app := store.AppInstall{
+		Name:              name,
+		DisplayName:       name, // fallback to internal name
+		IntegrationConfig: integrationConfig,
+		Options:           &store.InstallOptions{},
+	}
+	if entry, _ := o.catalogCache.Get(name); entry != nil {
+		app.Options.Port = entry.Port
+		app.Options.IsSystem = entry.IsSystem
+		app.DisplayName = entry.DisplayName
+	}
+	return app
 }
 
 // Rollback reverts to the previous NixOS generation
@@ -445,7 +501,7 @@ func (o *Orchestrator) Uninstall(ctx context.Context, req UninstallRequest) (Uni
 	appName := req.App
 	result := &UninstallResult{App: appName}
 
-	o.logger.Info("starting Nix uninstallation", "app", appName, "clearData", req.ClearData)
+	o.logger.InfoContext(ctx, "starting Nix uninstallation", "app", appName, "clearData", req.ClearData)
 
 	// Get app metadata early for SSO cleanup
 	catalogApp, _ := o.catalogCache.Get(appName)
@@ -504,7 +560,7 @@ func (o *Orchestrator) Uninstall(ctx context.Context, req UninstallRequest) (Uni
 		}
 
 		// 5. Trigger nixos-rebuild switch
-		o.logger.Info("triggering nixos-rebuild switch for uninstall")
+		o.logger.InfoContext(ctx, "triggering nixos-rebuild switch for uninstall")
 		rebuildResult, err := o.rebuilder.Switch(ctx)
 		if err != nil {
 			result.Error = fmt.Sprintf("nixos-rebuild failed: %v", err)
@@ -517,17 +573,17 @@ func (o *Orchestrator) Uninstall(ctx context.Context, req UninstallRequest) (Uni
 		}
 
 		// 6. Stop the user service
-		o.logger.Info("stopping user service", "app", appName)
+		o.logger.InfoContext(ctx, "stopping user service", "app", appName)
 		if err := o.rebuilder.StopUserService(ctx, appName); err != nil {
-			o.logger.Warn("failed to stop user service", "app", appName, "error", err)
+			o.logger.WarnContext(ctx, "failed to stop user service", "app", appName, "error", err)
 		}
 	} else {
 		// App not in Nix config - it's orphaned, just clean up
-		o.logger.Info("app not in Nix config, cleaning up orphaned entry", "app", appName)
+		o.logger.InfoContext(ctx, "app not in Nix config, cleaning up orphaned entry", "app", appName)
 
 		// Try to stop the service anyway (it might be running from old config)
 		if err := o.rebuilder.StopUserService(ctx, appName); err != nil {
-			o.logger.Debug("service not running or already stopped", "app", appName)
+			o.logger.DebugContext(ctx, "service not running or already stopped", "app", appName)
 		}
 	}
 
@@ -536,6 +592,7 @@ func (o *Orchestrator) Uninstall(ctx context.Context, req UninstallRequest) (Uni
 		result.Error = fmt.Sprintf("failed to remove from database: %v", err)
 		return result, nil
 	}
+	metrics.ForgetApp(appName)
 
 	// Update graph state
 	installedNames, _ := o.appStore.GetInstalledNames()
@@ -543,7 +600,7 @@ func (o *Orchestrator) Uninstall(ctx context.Context, req UninstallRequest) (Uni
 
 	// Regenerate Traefik routes (removes the uninstalled app)
 	if err := o.regenerateTraefikRoutes(); err != nil {
-		o.logger.Warn("failed to regenerate Traefik routes", "error", err)
+		o.logger.WarnContext(ctx, "failed to regenerate Traefik routes", "error", err)
 		// Non-fatal - just means old routes may persist
 	}
 
@@ -556,7 +613,7 @@ func (o *Orchestrator) Uninstall(ctx context.Context, req UninstallRequest) (Uni
 	}
 
 	result.Success = true
-	o.logger.Info("uninstallation complete", "app", appName)
+	o.logger.InfoContext(ctx, "uninstallation complete", "app", appName)
 
 	return result, nil
 }
@@ -636,6 +693,32 @@ func (o *Orchestrator) dropAppDatabase(appName string) error {
 	return nil
 }
 
+// recordImage stores the image reference and digest of an app's container.
+// Containers are named after the app, some with an "apps-" prefix.
+func (o *Orchestrator) recordImage(ctx context.Context, appName string) {
+	if o.images == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	for _, name := range []string{appName, "apps-" + appName} {
+		info, err := o.images.ContainerImage(ctx, name)
+		if err != nil {
+			o.logger.Warn("failed to inspect app container image", "app", appName, "error", err)
+			return
+		}
+		if info == nil {
+			continue
+		}
+		if err := o.appStore.UpdateImage(appName, info.Image, info.Digest); err != nil {
+			o.logger.Warn("failed to record app image", "app", appName, "error", err)
+		}
+		return
+	}
+	o.logger.Debug("no container found for app image", "app", appName)
+}
+
 // waitForHealthy polls an app's health endpoint until it responds or times out
 func (o *Orchestrator) waitForHealthy(appName string) {
 	o.logger.Info("starting health check", "app", appName)
@@ -703,6 +786,7 @@ func (o *Orchestrator) waitForHealthy(appName string) {
 			// 401/403 means the service is responding but requires authentication
 			if (resp.StatusCode >= 200 && resp.StatusCode < 400) || resp.StatusCode == 401 || resp.StatusCode == 403 {
 				o.logger.Info("health check passed", "app", appName, "status", resp.StatusCode, "attempts", attempts)
+				metrics.RecordHealthCheck(appName, true)
 				o.appStore.UpdateStatus(appName, "running")
 				// Ensure forward-auth providers are in the embedded outpost
 				// (async call to avoid blocking health check completion)
@@ -723,7 +807,17 @@ func (o *Orchestrator) waitForHealthy(appName string) {
 		"lastError", lastErr,
 		"lastStatus", lastStatus,
 		"url", url)
-	o.appStore.UpdateStatus(appName, "error")
+	metrics.RecordHealthCheck(appName, false)
+	o.appStore.UpdateStatusReason(appName, "error", fmt.Sprintf("health check did not pass within %s", timeout))
+
+	if o.notifier != nil {
+		o.notifier.Publish(notify.Event{
+			Kind:    notify.KindAppDown,
+			Title:   fmt.Sprintf("%s is down", appName),
+			Message: fmt.Sprintf("%s did not pass its health check within %s.", appName, timeout),
+			App:     appName,
+		})
+	}
 }
 
 
@@ -771,6 +865,17 @@ func (o *Orchestrator) regenerateTraefikRoutes() error {
 	}
 
 	o.logger.Info("regenerated Traefik routes", "apps", len(installedApps))
+
+	// Advertise the apps that just got routes
+	if o.mdns != nil {
+		var advertised []mdns.App
+		for _, app := range installedApps {
+			if !app.IsSystem && app.Port > 0 {
+				advertised = append(advertised, mdns.App{Name: app.Name, DisplayName: app.DisplayName})
+			}
+		}
+		o.mdns.Sync(advertised)
+	}
 	return nil
 }
 
@@ -905,8 +1010,8 @@ var systemApps = []systemAppInfo{
 	{"redis", "Redis", 6379, "podman-apps-redis.service"},
 }
 
-// getSystemdServiceName returns the systemd service name for an app
-func getSystemdServiceName(appName string) string {
+// SystemdServiceName returns the systemd service name for an app
+func SystemdServiceName(appName string) string {
 	for _, app := range systemApps {
 		if app.name == appName {
 			return app.serviceName
@@ -918,7 +1023,7 @@ func getSystemdServiceName(appName string) string {
 
 // checkSystemdServiceActive checks if a systemd user service is active
 func (o *Orchestrator) checkSystemdServiceActive(appName string) bool {
-	serviceName := getSystemdServiceName(appName)
+	serviceName := SystemdServiceName(appName)
 	cmd := exec.Command("systemctl", "--user", "is-active", serviceName)
 	output, err := cmd.Output()
 	if err != nil {
@@ -966,7 +1071,7 @@ func (o *Orchestrator) ReconcileState() {
 	o.logger.Info("reconciling apps", "count", len(apps))
 
 	for _, app := range apps {
-		serviceName := getSystemdServiceName(app.Name)
+		serviceName := SystemdServiceName(app.Name)
 		o.logger.Debug("reconciling app",
 			"app", app.Name,
 			"status", app.Status,
@@ -978,7 +1083,7 @@ func (o *Orchestrator) ReconcileState() {
 			// Server crashed mid-install - mark as error
 			o.logger.Warn("found app stuck in installing state", "app", app.Name,
 				"updated_at", app.UpdatedAt)
-			o.appStore.UpdateStatus(app.Name, "error")
+			o.appStore.UpdateStatusReason(app.Name, "error", "host agent restarted during install")
 
 		case "starting":
 			// Server crashed during health check - restart health check
@@ -997,16 +1102,18 @@ func (o *Orchestrator) ReconcileState() {
 					"app", app.Name,
 					"serviceName", serviceName)
 				// Try to restart health check - service might be starting
-				o.appStore.UpdateStatus(app.Name, "starting")
+				o.appStore.UpdateStatusReason(app.Name, "starting", "service not active")
 				go o.waitForHealthy(app.Name)
 			} else {
 				o.logger.Debug("app service is active, keeping running status", "app", app.Name)
+				// Images can change outside the host agent (e.g. a rebuild from the CLI)
+				o.recordImage(context.Background(), app.Name)
 			}
 
 		case "uninstalling":
 			// Server crashed mid-uninstall - mark as error
 			o.logger.Warn("found app stuck in uninstalling state", "app", app.Name)
-			o.appStore.UpdateStatus(app.Name, "error")
+			o.appStore.UpdateStatusReason(app.Name, "error", "host agent restarted during uninstall")
 
 		case "error", "failed":
 			o.logger.Debug("app in error/failed state", "app", app.Name)
@@ -12,7 +12,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/mdns"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/podman"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
 )
 
@@ -71,6 +73,21 @@ func newTestOrchestratorWithMocks() *testOrchestrator {
 	return t
 }
 
+// recordingApps matches an InstallAll call recording exactly the named apps
+func recordingApps(names ...string) interface{} {
+	return mock.MatchedBy(func(apps []store.AppInstall) bool {
+		if len(apps) != len(names) {
+			return false
+		}
+		for i, app := range apps {
+			if app.Name != names[i] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
 // setupSuccessfulInstall sets up mocks for a successful app installation
 func (t *testOrchestrator) setupSuccessfulInstall(appName string, app *catalog.App) {
 	plan := fixtureInstallPlanCanInstall(appName)
@@ -85,7 +102,7 @@ func (t *testOrchestrator) setupSuccessfulInstall(appName string, app *catalog.A
 	t.generator.On("Preview", mock.Anything).Return("preview")
 	t.generator.On("Apply", mock.Anything).Return(nil)
 
-	t.appStore.On("Install", appName, app.DisplayName, "", mock.Anything, mock.Anything).Return(nil)
+	t.appStore.On("InstallAll", recordingApps(appName)).Return(nil)
 	t.appStore.On("UpdateStatus", appName, "starting").Return(nil)
 	t.appStore.On("GetInstalledNames").Return([]string{appName}, nil)
 
@@ -247,8 +264,8 @@ func TestInstall_RebuildFails(t *testing.T) {
 	to.generator.On("Preview", mock.Anything).Return("preview")
 	to.generator.On("Apply", mock.Anything).Return(nil)
 
-	to.appStore.On("Install", "qbittorrent", "qBittorrent", "", mock.Anything, mock.Anything).Return(nil)
-	to.appStore.On("UpdateStatus", "qbittorrent", "failed").Return(nil) // Should mark as failed
+	to.appStore.On("InstallAll", recordingApps("qbittorrent")).Return(nil)
+	to.appStore.On("UpdateStatusReason", "qbittorrent", "failed", "nixos-rebuild failed").Return(nil) // Should mark as failed
 
 	// Rebuild fails
 	to.rebuilder.On("Switch", mock.Anything).Return(fixtureRebuildFailure("nix build failed"), nil)
@@ -261,7 +278,7 @@ func TestInstall_RebuildFails(t *testing.T) {
 	assert.False(t, result.IsSuccess())
 
 	// Verify status was updated to failed
-	to.appStore.AssertCalled(t, "UpdateStatus", "qbittorrent", "failed")
+	to.appStore.AssertCalled(t, "UpdateStatusReason", "qbittorrent", "failed", "nixos-rebuild failed")
 }
 
 func TestInstall_IntentRecordingFails(t *testing.T) {
@@ -280,7 +297,7 @@ func TestInstall_IntentRecordingFails(t *testing.T) {
 	to.generator.On("Preview", mock.Anything).Return("preview")
 
 	// Database write fails
-	to.appStore.On("Install", "qbittorrent", "qBittorrent", "", mock.Anything, mock.Anything).Return(errors.New("database locked"))
+	to.appStore.On("InstallAll", recordingApps("qbittorrent")).Return(errors.New("database locked"))
 
 	ctx := context.Background()
 	result, err := to.orch.Install(ctx, InstallRequest{App: "qbittorrent"})
@@ -426,7 +443,7 @@ func TestInstall_TransactionStructure(t *testing.T) {
 		capturedTx = args.Get(0)
 	}).Return(nil)
 
-	to.appStore.On("Install", "qbittorrent", "qBittorrent", "", mock.Anything, mock.Anything).Return(nil)
+	to.appStore.On("InstallAll", recordingApps("qbittorrent")).Return(nil)
 	to.appStore.On("UpdateStatus", "qbittorrent", "starting").Return(nil)
 	to.appStore.On("GetInstalledNames").Return([]string{"qbittorrent"}, nil)
 
@@ -485,9 +502,9 @@ func TestInstall_WithDependency_TransactionIncludesBoth(t *testing.T) {
 		capturedTx = args.Get(0).(*nixgen.Transaction)
 	}).Return(nil)
 
-	to.appStore.On("Install", "miniflux", "Miniflux", "", mock.Anything, mock.Anything).Return(nil)
 	to.appStore.On("GetByName", "postgres").Return(nil, nil) // Not yet installed
-	to.appStore.On("Install", "postgres", "PostgreSQL", "", mock.Anything, mock.Anything).Return(nil)
+	// Both apps are recorded together
+	to.appStore.On("InstallAll", recordingApps("miniflux", "postgres")).Return(nil)
 	to.appStore.On("UpdateStatus", "miniflux", "starting").Return(nil)
 	to.appStore.On("UpdateStatus", "postgres", "starting").Return(nil)
 	to.appStore.On("GetInstalledNames").Return([]string{"miniflux", "postgres"}, nil)
@@ -538,9 +555,9 @@ func TestInstall_InstallOptionsPassedCorrectly(t *testing.T) {
 
 	// Capture install options to verify port and system flag
 	var capturedPort int
-	var capturedOpts interface{}
-	to.appStore.On("Install", "qbittorrent", "qBittorrent", "", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
-		capturedOpts = args.Get(4) // 5th argument (index 4) is InstallOptions
+	var capturedOpts *store.InstallOptions
+	to.appStore.On("InstallAll", recordingApps("qbittorrent")).Run(func(args mock.Arguments) {
+		capturedOpts = args.Get(0).([]store.AppInstall)[0].Options
 	}).Return(nil)
 	to.appStore.On("UpdateStatus", "qbittorrent", "starting").Return(nil)
 	to.appStore.On("GetInstalledNames").Return([]string{"qbittorrent"}, nil)
@@ -558,8 +575,7 @@ func TestInstall_InstallOptionsPassedCorrectly(t *testing.T) {
 
 	// Verify install options contain correct port from catalog
 	require.NotNil(t, capturedOpts)
-	opts := capturedOpts.(*store.InstallOptions)
-	capturedPort = opts.Port
+	capturedPort = capturedOpts.Port
 	assert.Equal(t, 8180, capturedPort, "port should come from catalog")
 }
 
@@ -626,7 +642,7 @@ func TestInstall_TraefikRoutesIncludeAllInstalledApps(t *testing.T) {
 	to.generator.On("Preview", mock.Anything).Return("preview")
 	to.generator.On("Apply", mock.Anything).Return(nil)
 
-	to.appStore.On("Install", "qbittorrent", "qBittorrent", "", mock.Anything, mock.Anything).Return(nil)
+	to.appStore.On("InstallAll", recordingApps("qbittorrent")).Return(nil)
 	to.appStore.On("UpdateStatus", "qbittorrent", "starting").Return(nil)
 	to.appStore.On("GetInstalledNames").Return([]string{"qbittorrent"}, nil)
 
@@ -686,6 +702,25 @@ func TestRegenerateRoutes_WithAuthentik(t *testing.T) {
 	to.traefikGen.AssertCalled(t, "SetAuthentikEnabled", true)
 }
 
+func TestRegenerateRoutes_AdvertisesApps(t *testing.T) {
+	to := newTestOrchestratorWithMocks()
+	publisher := new(MockMDNSPublisher)
+	to.orch.mdns = publisher
+
+	to.appStore.On("GetInstalledNames").Return([]string{"authentik", "miniflux"}, nil)
+	to.cache.On("Get", "authentik").Return(&catalog.App{Name: "authentik", Port: 9000, IsSystem: true}, nil)
+	to.cache.On("Get", "miniflux").Return(fixtureMiniflux(), nil)
+	to.traefikGen.On("SetAuthentikEnabled", true).Return()
+	to.traefikGen.On("Generate", mock.Anything).Return(nil)
+	publisher.On("Sync", []mdns.App{{Name: "miniflux", DisplayName: "Miniflux"}}).Return()
+
+	err := to.orch.RegenerateRoutes()
+
+	require.NoError(t, err)
+	// System apps have no route of their own, so aren't advertised
+	publisher.AssertExpectations(t)
+}
+
 func TestRegenerateRoutes_AppStoreError(t *testing.T) {
 	to := newTestOrchestratorWithMocks()
 
@@ -711,3 +746,47 @@ func TestRegenerateRoutes_GenerateFails(t *testing.T) {
 	assert.Contains(t, err.Error(), "traefik error")
 }
 
+
+// ============================================================================
+// Image Recording Tests
+// ============================================================================
+
+// fakeImageInspector returns images for known container names
+type fakeImageInspector struct {
+	images map[string]*podman.ImageInfo
+	err    error
+}
+
+func (f *fakeImageInspector) ContainerImage(ctx context.Context, nameOrID string) (*podman.ImageInfo, error) {
+	return f.images[nameOrID], f.err
+}
+
+func TestRecordImage_PrefixedContainer(t *testing.T) {
+	to := newTestOrchestratorWithMocks()
+	to.orch.images = &fakeImageInspector{images: map[string]*podman.ImageInfo{
+		"apps-miniflux": {Image: "docker.io/miniflux/miniflux:2.2.0", Digest: "sha256:abc"},
+	}}
+	to.appStore.On("UpdateImage", "miniflux", "docker.io/miniflux/miniflux:2.2.0", "sha256:abc").Return(nil)
+
+	to.orch.recordImage(context.Background(), "miniflux")
+
+	to.appStore.AssertExpectations(t)
+}
+
+func TestRecordImage_NoContainer(t *testing.T) {
+	to := newTestOrchestratorWithMocks()
+	to.orch.images = &fakeImageInspector{}
+
+	to.orch.recordImage(context.Background(), "miniflux")
+
+	to.appStore.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRecordImage_InspectFails(t *testing.T) {
+	to := newTestOrchestratorWithMocks()
+	to.orch.images = &fakeImageInspector{err: errors.New("podman unavailable")}
+
+	to.orch.recordImage(context.Background(), "miniflux")
+
+	to.appStore.AssertNotCalled(t, "UpdateImage", mock.Anything, mock.Anything, mock.Anything)
+}
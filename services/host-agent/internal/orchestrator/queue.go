@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"sync"
 	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
 )
 
 // OperationQueue serializes install/uninstall operations to prevent race conditions.
@@ -16,6 +18,9 @@ type OperationQueue struct {
 	requestCh    chan QueuedOperation
 	stopCh       chan struct{}
 	stoppedCh    chan struct{}
+	stopOnce     sync.Once
+	busy         sync.Mutex    // held while a batch executes
+	resumeCh     chan struct{} // non-nil while paused; closed on resume
 	orchestrator *Orchestrator
 	logger       *slog.Logger
 }
@@ -77,14 +82,42 @@ func (q *OperationQueue) Start() {
 	go q.worker()
 }
 
-// Stop signals the worker to stop and waits for it to finish.
+// Stop signals the worker to stop and waits for it to finish. The batch in
+// progress completes; operations still waiting are cancelled. Safe to call
+// more than once.
 func (q *OperationQueue) Stop() {
-	close(q.stopCh)
+	q.stopOnce.Do(func() { close(q.stopCh) })
 	<-q.stoppedCh
 }
 
+// Pause waits for the batch in progress, if any, and holds later batches
+// until Resume. Requests keep queueing while paused.
+func (q *OperationQueue) Pause() {
+	q.mu.Lock()
+	if q.resumeCh == nil {
+		q.resumeCh = make(chan struct{})
+	}
+	q.mu.Unlock()
+
+	q.busy.Lock()
+	q.busy.Unlock()
+}
+
+// Resume lets held batches run. Safe to call when not paused.
+func (q *OperationQueue) Resume() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.resumeCh != nil {
+		close(q.resumeCh)
+		q.resumeCh = nil
+	}
+}
+
 // EnqueueInstall adds an install request to the queue and waits for the result.
 func (q *OperationQueue) EnqueueInstall(ctx context.Context, req InstallRequest) (InstallResponse, error) {
+	metrics.QueueDepth.Inc()
+	defer metrics.QueueDepth.Dec()
+
 	resultCh := make(chan OperationResult, 1)
 
 	op := QueuedOperation{
@@ -94,37 +127,40 @@ func (q *OperationQueue) EnqueueInstall(ctx context.Context, req InstallRequest)
 		Ctx:      ctx,
 	}
 
-	q.logger.Info("enqueueing install request", "app", req.App)
+	q.logger.InfoContext(ctx, "enqueueing install request", "app", req.App)
 
 	select {
 	case q.requestCh <- op:
-		q.logger.Debug("install request queued", "app", req.App)
+		q.logger.DebugContext(ctx, "install request queued", "app", req.App)
 	case <-ctx.Done():
-		q.logger.Warn("install request cancelled before queuing", "app", req.App, "error", ctx.Err())
+		q.logger.WarnContext(ctx, "install request cancelled before queuing", "app", req.App, "error", ctx.Err())
 		return nil, ctx.Err()
 	case <-q.stopCh:
-		q.logger.Warn("install request rejected, queue stopping", "app", req.App)
+		q.logger.WarnContext(ctx, "install request rejected, queue stopping", "app", req.App)
 		return nil, context.Canceled
 	}
 
-	q.logger.Info("waiting for install result", "app", req.App)
+	q.logger.InfoContext(ctx, "waiting for install result", "app", req.App)
 
 	select {
 	case result := <-resultCh:
 		if result.Err != nil {
-			q.logger.Error("install completed with error", "app", req.App, "error", result.Err)
+			q.logger.ErrorContext(ctx, "install completed with error", "app", req.App, "error", result.Err)
 		} else {
-			q.logger.Info("install completed", "app", req.App, "success", result.InstallResult.IsSuccess())
+			q.logger.InfoContext(ctx, "install completed", "app", req.App, "success", result.InstallResult.IsSuccess())
 		}
 		return result.InstallResult, result.Err
 	case <-ctx.Done():
-		q.logger.Warn("install request cancelled while waiting", "app", req.App, "error", ctx.Err())
+		q.logger.WarnContext(ctx, "install request cancelled while waiting", "app", req.App, "error", ctx.Err())
 		return nil, ctx.Err()
 	}
 }
 
 // EnqueueUninstall adds an uninstall request to the queue and waits for the result.
 func (q *OperationQueue) EnqueueUninstall(ctx context.Context, req UninstallRequest) (UninstallResponse, error) {
+	metrics.QueueDepth.Inc()
+	defer metrics.QueueDepth.Dec()
+
 	resultCh := make(chan OperationResult, 1)
 
 	op := QueuedOperation{
@@ -134,31 +170,31 @@ func (q *OperationQueue) EnqueueUninstall(ctx context.Context, req UninstallRequ
 		Ctx:       ctx,
 	}
 
-	q.logger.Info("enqueueing uninstall request", "app", req.App, "clearData", req.ClearData)
+	q.logger.InfoContext(ctx, "enqueueing uninstall request", "app", req.App, "clearData", req.ClearData)
 
 	select {
 	case q.requestCh <- op:
-		q.logger.Debug("uninstall request queued", "app", req.App)
+		q.logger.DebugContext(ctx, "uninstall request queued", "app", req.App)
 	case <-ctx.Done():
-		q.logger.Warn("uninstall request cancelled before queuing", "app", req.App, "error", ctx.Err())
+		q.logger.WarnContext(ctx, "uninstall request cancelled before queuing", "app", req.App, "error", ctx.Err())
 		return nil, ctx.Err()
 	case <-q.stopCh:
-		q.logger.Warn("uninstall request rejected, queue stopping", "app", req.App)
+		q.logger.WarnContext(ctx, "uninstall request rejected, queue stopping", "app", req.App)
 		return nil, context.Canceled
 	}
 
-	q.logger.Info("waiting for uninstall result", "app", req.App)
+	q.logger.InfoContext(ctx, "waiting for uninstall result", "app", req.App)
 
 	select {
 	case result := <-resultCh:
 		if result.Err != nil {
-			q.logger.Error("uninstall completed with error", "app", req.App, "error", result.Err)
+			q.logger.ErrorContext(ctx, "uninstall completed with error", "app", req.App, "error", result.Err)
 		} else {
-			q.logger.Info("uninstall completed", "app", req.App, "success", result.UninstallResult.IsSuccess())
+			q.logger.InfoContext(ctx, "uninstall completed", "app", req.App, "success", result.UninstallResult.IsSuccess())
 		}
 		return result.UninstallResult, result.Err
 	case <-ctx.Done():
-		q.logger.Warn("uninstall request cancelled while waiting", "app", req.App, "error", ctx.Err())
+		q.logger.WarnContext(ctx, "uninstall request cancelled while waiting", "app", req.App, "error", ctx.Err())
 		return nil, ctx.Err()
 	}
 }
@@ -177,8 +213,35 @@ func (q *OperationQueue) worker() {
 			// Got first request of a batch - collect more
 			batch := q.collectBatch(op)
 			if len(batch) > 0 {
-				q.executeBatch(batch)
+				q.runBatch(batch)
+			}
+		}
+	}
+}
+
+// runBatch executes a batch once the queue isn't paused. If the queue stops
+// while paused, the batch is cancelled.
+func (q *OperationQueue) runBatch(batch []QueuedOperation) {
+	for {
+		q.busy.Lock()
+		q.mu.Lock()
+		resume := q.resumeCh
+		q.mu.Unlock()
+		if resume == nil {
+			q.executeBatch(batch)
+			q.busy.Unlock()
+			return
+		}
+		q.busy.Unlock()
+
+		q.logger.Info("operation queue paused, holding batch", "operations", len(batch))
+		select {
+		case <-resume:
+		case <-q.stopCh:
+			for _, op := range batch {
+				op.ResultCh <- OperationResult{Err: context.Canceled}
 			}
+			return
 		}
 	}
 }
@@ -341,14 +404,14 @@ func (q *OperationQueue) executeBatch(batch []QueuedOperation) {
 
 	// Process uninstalls first (in case an app is being reinstalled)
 	for i, op := range uninstalls {
-		q.logger.Info("executing uninstall", "app", op.Uninstall.App, "index", i+1, "total", len(uninstalls))
+		q.logger.InfoContext(op.Ctx, "executing uninstall", "app", op.Uninstall.App, "index", i+1, "total", len(uninstalls))
 		q.executeUninstall(op)
 	}
 
 	// Process installs
 	// TODO: Combine all installs into a single transaction for efficiency
 	for i, op := range installs {
-		q.logger.Info("executing install", "app", op.Install.App, "index", i+1, "total", len(installs))
+		q.logger.InfoContext(op.Ctx, "executing install", "app", op.Install.App, "index", i+1, "total", len(installs))
 		q.executeInstall(op)
 	}
 
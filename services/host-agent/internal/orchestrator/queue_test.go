@@ -406,3 +406,57 @@ func TestOperationQueue_ContextCancellation(t *testing.T) {
 		// Expected
 	}
 }
+
+func TestOperationQueue_StopIsIdempotent(t *testing.T) {
+	queue := NewOperationQueue(nil, QueueConfig{BatchWait: 10 * time.Millisecond}, slog.Default())
+	queue.Start()
+
+	done := make(chan struct{})
+	go func() {
+		queue.Stop()
+		queue.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return")
+	}
+}
+
+func TestOperationQueue_PauseHoldsBatches(t *testing.T) {
+	queue := NewOperationQueue(nil, QueueConfig{BatchWait: 10 * time.Millisecond}, slog.Default())
+	queue.Start()
+	queue.Pause()
+	queue.Pause()
+
+	resultCh := make(chan OperationResult, 1)
+	queue.requestCh <- QueuedOperation{
+		Type:     OpInstall,
+		Install:  &InstallRequest{App: "test-app"},
+		ResultCh: resultCh,
+		Ctx:      context.Background(),
+	}
+
+	// The batch is held rather than executed
+	select {
+	case <-resultCh:
+		t.Fatal("batch ran while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Stopping while paused cancels the held batch
+	queue.Stop()
+	select {
+	case result := <-resultCh:
+		if result.Err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", result.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("held batch was not cancelled")
+	}
+
+	queue.Resume()
+	queue.Resume()
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/metrics"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/configurator"
 )
@@ -39,6 +40,7 @@ type Reconciler struct {
 	registry     configurator.RegistryInterface
 	appStore     store.AppStoreInterface
 	catalogCache catalog.CacheInterface
+	settings     store.SettingsStoreInterface // optional
 	dataDir      string
 	logger       *slog.Logger
 	config       ReconcileConfig
@@ -63,6 +65,12 @@ func NewReconciler(
 	}
 }
 
+// SetSettingsStore makes stored app settings available to configurators
+// through AppState.Settings
+func (r *Reconciler) SetSettingsStore(settings store.SettingsStoreInterface) {
+	r.settings = settings
+}
+
 // Reconcile runs the full reconciliation cycle for all installed apps.
 // This is idempotent and safe to call repeatedly.
 func (r *Reconciler) Reconcile(ctx context.Context) error {
@@ -101,7 +109,7 @@ func (r *Reconciler) Reconcile(ctx context.Context) error {
 			continue
 		}
 
-		state := r.buildAppState(app)
+		state := r.BuildAppState(app)
 		if err := cfg.PreStart(ctx, state); err != nil {
 			r.logger.Warn("PreStart failed", "app", app.Name, "error", err)
 			errors = append(errors, fmt.Sprintf("%s: PreStart failed: %v", app.Name, err))
@@ -129,14 +137,16 @@ func (r *Reconciler) Reconcile(ctx context.Context) error {
 			healthCtx, cancel := context.WithTimeout(ctx, r.config.HealthCheckTimeout)
 			if err := cfg.HealthCheck(healthCtx); err != nil {
 				cancel()
+				metrics.RecordHealthCheck(app.Name, false)
 				r.logger.Warn("HealthCheck failed, skipping PostStart", "app", app.Name, "error", err)
 				errors = append(errors, fmt.Sprintf("%s: HealthCheck failed: %v", app.Name, err))
 				continue
 			}
 			cancel()
+			metrics.RecordHealthCheck(app.Name, true)
 
 			// Run PostStart
-			state := r.buildAppState(app)
+			state := r.BuildAppState(app)
 			if err := cfg.PostStart(ctx, state); err != nil {
 				r.logger.Warn("PostStart failed", "app", app.Name, "error", err)
 				errors = append(errors, fmt.Sprintf("%s: PostStart failed: %v", app.Name, err))
@@ -219,15 +229,16 @@ func (r *Reconciler) computeLevels(apps map[string]*store.InstalledApp) [][]stri
 	return result
 }
 
-// buildAppState creates an AppState from a database app record
-func (r *Reconciler) buildAppState(app *store.InstalledApp) *configurator.AppState {
+// BuildAppState creates an AppState from a database app record
+func (r *Reconciler) BuildAppState(app *store.InstalledApp) *configurator.AppState {
 	// Parse integrations from database
 	integrations := make(map[string][]string)
 	for name, source := range app.IntegrationConfig {
 		integrations[name] = []string{source}
 	}
 
-	// Load SSO config from catalog if available
+	// Load SSO config and settings from catalog if available
+	settings := make(map[string]any)
 	if r.catalogCache != nil {
 		if catalogApp, err := r.catalogCache.Get(app.Name); err == nil && catalogApp != nil {
 			if catalogApp.SSO.Strategy != "" {
@@ -236,6 +247,14 @@ func (r *Reconciler) buildAppState(app *store.InstalledApp) *configurator.AppSta
 					"app", app.Name,
 					"strategy", catalogApp.SSO.Strategy)
 			}
+
+			var stored map[string]any
+			if r.settings != nil {
+				if stored, err = r.settings.Get(app.Name); err != nil {
+					r.logger.Warn("failed to load app settings, using defaults", "app", app.Name, "error", err)
+				}
+			}
+			settings = catalogApp.ResolveSettings(stored)
 		}
 	}
 
@@ -246,6 +265,7 @@ func (r *Reconciler) buildAppState(app *store.InstalledApp) *configurator.AppSta
 		Port:          app.Port,
 		Integrations:  integrations,
 		Options:       make(map[string]any),
+		Settings:      settings,
 	}
 }
 
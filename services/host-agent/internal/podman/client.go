@@ -283,6 +283,76 @@ func (c *Client) GetContainer(ctx context.Context, nameOrID string) (*Container,
 	return &container, nil
 }
 
+// ImageInfo identifies the image a container was created from
+type ImageInfo struct {
+	Image  string `json:"ImageName"`   // reference, e.g. docker.io/library/redis:7
+	Digest string `json:"ImageDigest"` // e.g. sha256:...
+}
+
+// ContainerImage returns the image a container runs, or nil if the container
+// doesn't exist
+func (c *Client) ContainerImage(ctx context.Context, nameOrID string) (*ImageInfo, error) {
+	resp, err := c.get(ctx, fmt.Sprintf("/libpod/containers/%s/json", nameOrID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("inspect container returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var info ImageInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &info, nil
+}
+
+// ContainerStats is a point-in-time resource usage sample for one container
+type ContainerStats struct {
+	ContainerID string  `json:"ContainerID"`
+	Name        string  `json:"Name"`
+	CPU         float64 `json:"CPU"`     // percent of one host's total CPU
+	MemPerc     float64 `json:"MemPerc"` // percent of host memory
+	MemUsage    uint64  `json:"MemUsage"`
+	NetInput    uint64  `json:"NetInput"`  // bytes received since the container started
+	NetOutput   uint64  `json:"NetOutput"` // bytes sent since the container started
+}
+
+// Stats returns a resource usage sample for every running container
+func (c *Client) Stats(ctx context.Context) ([]ContainerStats, error) {
+	resp, err := c.get(ctx, "/libpod/containers/stats?stream=false")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("container stats returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var report struct {
+		Error *string          `json:"Error"`
+		Stats []ContainerStats `json:"Stats"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if report.Error != nil && *report.Error != "" {
+		return nil, fmt.Errorf("container stats failed: %s", *report.Error)
+	}
+
+	return report.Stats, nil
+}
+
 // HTTP helpers
 
 func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
@@ -228,6 +228,59 @@ func TestClient_GetContainer(t *testing.T) {
 	assert.Nil(t, container)
 }
 
+func TestClient_Stats(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/libpod/containers/stats", r.URL.Path)
+		assert.Equal(t, "false", r.URL.Query().Get("stream"))
+		w.Write([]byte(`{"Error":null,"Stats":[{"ContainerID":"abc123","Name":"miniflux","CPU":12.5,"MemPerc":3.2,"MemUsage":104857600,"NetInput":2048,"NetOutput":512}]}`))
+	})
+
+	socketPath, cleanup := setupMockPodman(t, handler)
+	defer cleanup()
+
+	client, err := NewClientWithSocket(socketPath)
+	require.NoError(t, err)
+
+	stats, err := client.Stats(context.Background())
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, "miniflux", stats[0].Name)
+	assert.Equal(t, 12.5, stats[0].CPU)
+	assert.Equal(t, 3.2, stats[0].MemPerc)
+	assert.Equal(t, uint64(104857600), stats[0].MemUsage)
+	assert.Equal(t, uint64(2048), stats[0].NetInput)
+	assert.Equal(t, uint64(512), stats[0].NetOutput)
+}
+
+func TestClient_ContainerImage(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/libpod/containers/miniflux/json":
+			w.Write([]byte(`{"Id":"abc123","Image":"5d0da3dc9764","ImageName":"docker.io/miniflux/miniflux:2.1.0","ImageDigest":"sha256:8e1d"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	socketPath, cleanup := setupMockPodman(t, handler)
+	defer cleanup()
+
+	client, err := NewClientWithSocket(socketPath)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	info, err := client.ContainerImage(ctx, "miniflux")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, "docker.io/miniflux/miniflux:2.1.0", info.Image)
+	assert.Equal(t, "sha256:8e1d", info.Digest)
+
+	info, err = client.ContainerImage(ctx, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, info)
+}
+
 func TestClient_DefaultSocketPath(t *testing.T) {
 	// Test with XDG_RUNTIME_DIR set
 	originalXDG := os.Getenv("XDG_RUNTIME_DIR")
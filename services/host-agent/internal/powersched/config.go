@@ -0,0 +1,155 @@
+package powersched
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Mode is how the host sleeps
+type Mode string
+
+const (
+	// ModeSuspend suspends to RAM; apps resume where they left off
+	ModeSuspend Mode = "suspend"
+	// ModePoweroff shuts down cleanly; apps start again on boot
+	ModePoweroff Mode = "poweroff"
+)
+
+// clockFormat is the format of sleep and wake times
+const clockFormat = "15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Config is the persisted power schedule
+type Config struct {
+	Enabled bool     `json:"enabled"`
+	Windows []Window `json:"windows"`
+}
+
+// Window is a recurring period the host sleeps through
+type Window struct {
+	Sleep string `json:"sleep"` // HH:MM local time
+	// Wake is HH:MM local time; at or before Sleep means the next day
+	Wake string `json:"wake"`
+	// Days the window starts on (sun, mon, ... sat); empty is every day
+	Days []string `json:"days,omitempty"`
+	Mode Mode     `json:"mode,omitempty"` // defaults to suspend
+}
+
+// Validate checks the schedule
+func (c Config) Validate() error {
+	if c.Enabled && len(c.Windows) == 0 {
+		return fmt.Errorf("at least one window is required")
+	}
+	for i, w := range c.Windows {
+		if err := w.validate(); err != nil {
+			return fmt.Errorf("window %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (w Window) validate() error {
+	sleep, err := time.Parse(clockFormat, w.Sleep)
+	if err != nil {
+		return fmt.Errorf("sleep must be HH:MM")
+	}
+	wake, err := time.Parse(clockFormat, w.Wake)
+	if err != nil {
+		return fmt.Errorf("wake must be HH:MM")
+	}
+	if sleep.Equal(wake) {
+		return fmt.Errorf("sleep and wake must differ")
+	}
+	for _, d := range w.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("unknown day %q", d)
+		}
+	}
+	switch w.Mode {
+	case "", ModeSuspend, ModePoweroff:
+	default:
+		return fmt.Errorf("mode must be suspend or poweroff")
+	}
+	return nil
+}
+
+func (w Window) mode() Mode {
+	if w.Mode == "" {
+		return ModeSuspend
+	}
+	return w.Mode
+}
+
+// occurrence returns the sleep and wake times of the window starting on
+// day's date, and whether it starts that weekday. The window is assumed
+// valid.
+func (w Window) occurrence(day time.Time) (sleep, wake time.Time, ok bool) {
+	if len(w.Days) > 0 {
+		found := false
+		for _, d := range w.Days {
+			if weekdays[strings.ToLower(d)] == day.Weekday() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	s, _ := time.Parse(clockFormat, w.Sleep)
+	e, _ := time.Parse(clockFormat, w.Wake)
+	y, m, d := day.Date()
+	sleep = time.Date(y, m, d, s.Hour(), s.Minute(), 0, 0, day.Location())
+	wake = time.Date(y, m, d, e.Hour(), e.Minute(), 0, 0, day.Location())
+	if !wake.After(sleep) {
+		wake = wake.AddDate(0, 0, 1)
+	}
+	return sleep, wake, true
+}
+
+// period is one occurrence of a window
+type period struct {
+	Sleep, Wake time.Time
+	Mode        Mode
+}
+
+// due returns the period that began in the last grace, if any. Periods that
+// began longer ago are skipped, so a host woken early or booted mid-window
+// stays up.
+func (c Config) due(now time.Time, grace time.Duration) (period, bool) {
+	for _, w := range c.Windows {
+		// A window that crosses midnight may have started yesterday
+		for _, offset := range []int{0, -1} {
+			sleep, wake, ok := w.occurrence(now.AddDate(0, 0, offset))
+			if ok && !now.Before(sleep) && now.Sub(sleep) < grace && now.Before(wake) {
+				return period{Sleep: sleep, Wake: wake, Mode: w.mode()}, true
+			}
+		}
+	}
+	return period{}, false
+}
+
+// next returns the first period starting after now
+func (c Config) next(now time.Time) (period, bool) {
+	var best period
+	found := false
+	for _, w := range c.Windows {
+		for offset := 0; offset <= 7; offset++ {
+			sleep, wake, ok := w.occurrence(now.AddDate(0, 0, offset))
+			if !ok || !sleep.After(now) {
+				continue
+			}
+			if !found || sleep.Before(best.Sleep) {
+				best, found = period{Sleep: sleep, Wake: wake, Mode: w.mode()}, true
+			}
+			break
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,185 @@
+// Package powersched puts the host to sleep on a schedule, for users who
+// don't want the box running around the clock. Each window suspends or
+// powers off the host at its sleep time and sets an RTC alarm to wake it.
+package powersched
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// checkInterval is how often the schedule is checked
+	checkInterval = 30 * time.Second
+
+	// sleepGrace is how late after a window's sleep time the host still
+	// goes to sleep, covering a busy operation queue or a late tick
+	sleepGrace = 15 * time.Minute
+)
+
+// SleepFunc puts the host to sleep until wake. For suspend it returns after
+// the host resumes; a poweroff doesn't return.
+type SleepFunc func(ctx context.Context, mode Mode, wake time.Time) error
+
+// Status reports the schedule's next and last sleep
+type Status struct {
+	Sleeping  bool       `json:"sleeping"`
+	NextSleep *time.Time `json:"nextSleep,omitempty"` // unset while disabled
+	NextWake  *time.Time `json:"nextWake,omitempty"`
+	NextMode  Mode       `json:"nextMode,omitempty"`
+	LastSleep *time.Time `json:"lastSleep,omitempty"`
+	LastWake  *time.Time `json:"lastWake,omitempty"` // when the host agent resumed
+	LastError string     `json:"lastError,omitempty"`
+}
+
+// Manager runs the power schedule
+type Manager struct {
+	path   string
+	sleep  SleepFunc
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	cfg    Config
+	status Status
+	last   time.Time // sleep time of the last period acted on
+}
+
+// NewManager creates a manager persisting its schedule at path and calling
+// sleep when a window starts
+func NewManager(path string, sleep SleepFunc, logger *slog.Logger) *Manager {
+	return &Manager{
+		path:   path,
+		sleep:  sleep,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Load reads the schedule file; a missing file means no schedule
+func (m *Manager) Load() error {
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading power schedule: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("parsing power schedule: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid power schedule: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+	return nil
+}
+
+// Config returns the current schedule
+func (m *Manager) Config() Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg
+}
+
+// SetConfig validates and persists a new schedule
+func (m *Manager) SetConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling power schedule: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(m.path, data, 0644); err != nil {
+		return fmt.Errorf("writing power schedule: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+	// A window already under way when it's saved doesn't send the host to
+	// sleep straight away
+	if p, ok := cfg.due(m.now(), sleepGrace); ok {
+		m.last = p.Sleep
+	}
+	return nil
+}
+
+// Status returns the next and last sleep
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := m.status
+	if m.cfg.Enabled {
+		if p, ok := m.cfg.next(m.now()); ok {
+			status.NextSleep, status.NextWake, status.NextMode = &p.Sleep, &p.Wake, p.Mode
+		}
+	}
+	return status
+}
+
+// Start checks the schedule in the background until ctx is cancelled
+func (m *Manager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.check(ctx)
+			}
+		}
+	}()
+}
+
+// check sleeps through a window that has just started
+func (m *Manager) check(ctx context.Context) {
+	m.mu.Lock()
+	p, ok := m.cfg.due(m.now(), sleepGrace)
+	if !m.cfg.Enabled || !ok || p.Sleep.Equal(m.last) {
+		m.mu.Unlock()
+		return
+	}
+	m.last = p.Sleep
+	start := m.now()
+	m.status.Sleeping = true
+	m.status.LastSleep = &start
+	m.mu.Unlock()
+
+	m.logger.Info("sleeping on schedule", "mode", p.Mode, "wake", p.Wake)
+	err := m.sleep(ctx, p.Mode, p.Wake)
+
+	woke := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.Sleeping = false
+	m.status.LastWake = &woke
+	m.status.LastError = ""
+	if err != nil {
+		m.logger.Error("scheduled sleep failed", "mode", p.Mode, "error", err)
+		m.status.LastError = err.Error()
+		return
+	}
+	// The monotonic clock stops while suspended, so compare wall clock times
+	m.logger.Info("woke from scheduled sleep", "slept", woke.Round(0).Sub(start.Round(0)).Round(time.Second))
+}
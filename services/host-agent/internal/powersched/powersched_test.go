@@ -0,0 +1,118 @@
+package powersched
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := []Config{
+		{},
+		{Enabled: true, Windows: []Window{{Sleep: "02:00", Wake: "07:00"}}},
+		{Enabled: true, Windows: []Window{{Sleep: "23:30", Wake: "06:00", Days: []string{"Mon", "tue"}, Mode: ModePoweroff}}},
+	}
+	for _, cfg := range valid {
+		assert.NoError(t, cfg.Validate(), cfg)
+	}
+
+	invalid := []Config{
+		{Enabled: true},
+		{Windows: []Window{{Sleep: "2am", Wake: "07:00"}}},
+		{Windows: []Window{{Sleep: "02:00", Wake: "24:00"}}},
+		{Windows: []Window{{Sleep: "02:00", Wake: "02:00"}}},
+		{Windows: []Window{{Sleep: "02:00", Wake: "07:00", Days: []string{"monday"}}}},
+		{Windows: []Window{{Sleep: "02:00", Wake: "07:00", Mode: "hibernate"}}},
+	}
+	for _, cfg := range invalid {
+		assert.Error(t, cfg.Validate(), cfg)
+	}
+}
+
+func TestConfigDue(t *testing.T) {
+	cfg := Config{Windows: []Window{
+		{Sleep: "23:55", Wake: "06:00", Days: []string{"fri"}},
+		{Sleep: "02:00", Wake: "07:00", Days: []string{"mon", "tue", "wed", "thu"}, Mode: ModePoweroff},
+	}}
+	// Friday 16 January 2026
+	friday := time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)
+
+	p, ok := cfg.due(friday.Add(23*time.Hour+58*time.Minute), sleepGrace)
+	require.True(t, ok)
+	assert.Equal(t, ModeSuspend, p.Mode)
+	assert.Equal(t, time.Date(2026, 1, 17, 6, 0, 0, 0, time.UTC), p.Wake)
+
+	// Still due just after midnight, within the grace period
+	p, ok = cfg.due(friday.Add(24*time.Hour+5*time.Minute), sleepGrace)
+	require.True(t, ok)
+	assert.Equal(t, friday.Add(23*time.Hour+55*time.Minute), p.Sleep)
+
+	// Too long after the sleep time, or on a day the window doesn't start
+	_, ok = cfg.due(friday.Add(25*time.Hour), sleepGrace)
+	assert.False(t, ok)
+	_, ok = cfg.due(friday.Add(2*time.Hour+time.Minute), sleepGrace)
+	assert.False(t, ok)
+
+	p, ok = cfg.next(friday.Add(24 * time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 1, 19, 2, 0, 0, 0, time.UTC), p.Sleep)
+	assert.Equal(t, ModePoweroff, p.Mode)
+}
+
+func TestManager(t *testing.T) {
+	now := time.Date(2026, 1, 16, 1, 59, 0, 0, time.UTC)
+	var sleeps []time.Time
+	var sleepErr error
+	m := NewManager(filepath.Join(t.TempDir(), "power.json"), func(ctx context.Context, mode Mode, wake time.Time) error {
+		sleeps = append(sleeps, wake)
+		now = wake.Add(time.Minute)
+		return sleepErr
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.SetConfig(Config{Enabled: true, Windows: []Window{{Sleep: "02:00", Wake: "07:00"}}}))
+	status := m.Status()
+	require.NotNil(t, status.NextSleep)
+	assert.Equal(t, time.Date(2026, 1, 16, 2, 0, 0, 0, time.UTC), *status.NextSleep)
+
+	m.check(context.Background())
+	assert.Empty(t, sleeps)
+
+	now = now.Add(2 * time.Minute)
+	m.check(context.Background())
+	require.Len(t, sleeps, 1)
+	assert.Equal(t, time.Date(2026, 1, 16, 7, 0, 0, 0, time.UTC), sleeps[0])
+	status = m.Status()
+	assert.False(t, status.Sleeping)
+	assert.Equal(t, now, *status.LastWake)
+
+	// Woken early in the same window: stays up
+	now = time.Date(2026, 1, 16, 2, 5, 0, 0, time.UTC)
+	m.check(context.Background())
+	assert.Len(t, sleeps, 1)
+
+	// The next night's failure is reported
+	sleepErr = errors.New("rtcwake: permission denied")
+	now = time.Date(2026, 1, 17, 2, 0, 30, 0, time.UTC)
+	m.check(context.Background())
+	assert.Len(t, sleeps, 2)
+	assert.Equal(t, "rtcwake: permission denied", m.Status().LastError)
+
+	// Saving during a window doesn't sleep right away; the schedule survives
+	// a reload
+	now = time.Date(2026, 1, 18, 2, 1, 0, 0, time.UTC)
+	require.NoError(t, m.SetConfig(m.Config()))
+	m.check(context.Background())
+	assert.Len(t, sleeps, 2)
+
+	reloaded := NewManager(m.path, nil, m.logger)
+	require.NoError(t, reloaded.Load())
+	assert.Equal(t, m.Config(), reloaded.Config())
+}
@@ -0,0 +1,22 @@
+package provisioning
+
+import (
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
+)
+
+// UserSource lists the users that should exist in provisioned apps.
+type UserSource interface {
+	// ListUsers returns all human users from the identity provider
+	ListUsers() ([]authentik.User, error)
+}
+
+// InstalledAppLister lists installed apps.
+type InstalledAppLister interface {
+	// GetInstalledNames returns the names of installed apps
+	GetInstalledNames() ([]string, error)
+}
+
+// Compile-time assertions
+var _ UserSource = (*authentik.Client)(nil)
+var _ InstalledAppLister = (*store.AppStore)(nil)
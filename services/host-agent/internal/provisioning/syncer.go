@@ -0,0 +1,144 @@
+// Package provisioning keeps app user tables in sync with Authentik.
+// Authentik is the source of truth: users are created in each provisioned app
+// when they appear in Authentik, and disabled or re-enabled to match is_active.
+// Users that only exist in the app (e.g., local admins) are left alone.
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/provisioner"
+)
+
+// Syncer pushes Authentik users into installed apps that have a provisioner.
+// Syncs run on a fixed interval and on demand via Trigger (e.g., from an Authentik webhook).
+type Syncer struct {
+	source   UserSource
+	registry provisioner.RegistryInterface
+	apps     InstalledAppLister
+	logger   *slog.Logger
+	trigger  chan struct{}
+}
+
+// NewSyncer creates a new provisioning syncer
+func NewSyncer(source UserSource, registry provisioner.RegistryInterface, apps InstalledAppLister, logger *slog.Logger) *Syncer {
+	return &Syncer{
+		source:   source,
+		registry: registry,
+		apps:     apps,
+		logger:   logger,
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// Trigger requests a sync without blocking.
+// Multiple triggers before the next sync starts are coalesced into one.
+func (s *Syncer) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run syncs every interval and whenever Trigger is called, until ctx is cancelled.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+
+		if err := s.Sync(ctx); err != nil {
+			s.logger.Warn("user provisioning sync failed", "error", err)
+		}
+	}
+}
+
+// Sync runs a single provisioning pass over all installed apps with a provisioner.
+// Errors from individual apps are collected; one failing app doesn't block the others.
+func (s *Syncer) Sync(ctx context.Context) error {
+	installed, err := s.apps.GetInstalledNames()
+	if err != nil {
+		return fmt.Errorf("failed to list installed apps: %w", err)
+	}
+
+	var targets []provisioner.Provisioner
+	for _, name := range installed {
+		if p := s.registry.Get(name); p != nil {
+			targets = append(targets, p)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	users, err := s.source.ListUsers()
+	if err != nil {
+		return fmt.Errorf("failed to list Authentik users: %w", err)
+	}
+
+	var errs []error
+	for _, p := range targets {
+		if err := s.syncApp(ctx, p, users); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// syncApp reconciles one app's users against the Authentik user list
+func (s *Syncer) syncApp(ctx context.Context, p provisioner.Provisioner, users []authentik.User) error {
+	appUsers, err := p.ListUsers(ctx)
+	if errors.Is(err, provisioner.ErrNotReady) {
+		s.logger.Debug("provisioner not ready, skipping", "app", p.Name())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list app users: %w", err)
+	}
+
+	// Usernames are matched case-insensitively; LDAP logins are case-insensitive too
+	existing := make(map[string]provisioner.User, len(appUsers))
+	for _, u := range appUsers {
+		existing[strings.ToLower(u.Username)] = u
+	}
+
+	var errs []error
+	for _, user := range users {
+		appUser, ok := existing[strings.ToLower(user.Username)]
+
+		switch {
+		case !ok && user.IsActive:
+			s.logger.Info("provisioning user", "app", p.Name(), "user", user.Username)
+			err = p.CreateUser(ctx, provisioner.User{
+				Username: user.Username,
+				Email:    user.Email,
+				Name:     user.Name,
+				Active:   true,
+			})
+		case ok && appUser.Active != user.IsActive:
+			s.logger.Info("updating user status", "app", p.Name(), "user", user.Username, "active", user.IsActive)
+			err = p.SetUserDisabled(ctx, user.Username, !user.IsActive)
+		default:
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", user.Username, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,170 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/provisioner"
+)
+
+type fakeUserSource struct {
+	users []authentik.User
+	err   error
+}
+
+func (f *fakeUserSource) ListUsers() ([]authentik.User, error) { return f.users, f.err }
+
+type fakeAppLister struct {
+	names []string
+}
+
+func (f *fakeAppLister) GetInstalledNames() ([]string, error) { return f.names, nil }
+
+// fakeProvisioner keeps users in memory
+type fakeProvisioner struct {
+	name     string
+	users    map[string]provisioner.User
+	listErr  error
+	created  []string
+	disabled map[string]bool
+}
+
+func newFakeProvisioner(name string, users ...provisioner.User) *fakeProvisioner {
+	p := &fakeProvisioner{name: name, users: make(map[string]provisioner.User), disabled: make(map[string]bool)}
+	for _, u := range users {
+		p.users[u.Username] = u
+	}
+	return p
+}
+
+func (f *fakeProvisioner) Name() string { return f.name }
+
+func (f *fakeProvisioner) ListUsers(ctx context.Context) ([]provisioner.User, error) {
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	result := make([]provisioner.User, 0, len(f.users))
+	for _, u := range f.users {
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+func (f *fakeProvisioner) CreateUser(ctx context.Context, user provisioner.User) error {
+	f.created = append(f.created, user.Username)
+	f.users[user.Username] = user
+	return nil
+}
+
+func (f *fakeProvisioner) SetUserDisabled(ctx context.Context, username string, disabled bool) error {
+	f.disabled[username] = disabled
+	return nil
+}
+
+func testSyncer(source UserSource, installed []string, provisioners ...provisioner.Provisioner) *Syncer {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry := provisioner.NewRegistry(logger)
+	for _, p := range provisioners {
+		registry.Register(p)
+	}
+	return NewSyncer(source, registry, &fakeAppLister{names: installed}, logger)
+}
+
+func TestSyncer_CreatesMissingUsers(t *testing.T) {
+	source := &fakeUserSource{users: []authentik.User{
+		{Username: "alice", IsActive: true},
+		{Username: "bob", IsActive: true},
+		{Username: "carol", IsActive: false}, // inactive users are not created
+	}}
+	jellyfin := newFakeProvisioner("jellyfin", provisioner.User{Username: "Alice", Active: true})
+
+	s := testSyncer(source, []string{"jellyfin"}, jellyfin)
+	if err := s.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if len(jellyfin.created) != 1 || jellyfin.created[0] != "bob" {
+		t.Errorf("created = %v, want [bob]", jellyfin.created)
+	}
+}
+
+func TestSyncer_MirrorsActiveState(t *testing.T) {
+	source := &fakeUserSource{users: []authentik.User{
+		{Username: "alice", IsActive: false},
+		{Username: "bob", IsActive: true},
+	}}
+	jellyfin := newFakeProvisioner("jellyfin",
+		provisioner.User{Username: "alice", Active: true},
+		provisioner.User{Username: "bob", Active: false},
+		provisioner.User{Username: "local-admin", Active: true},
+	)
+
+	s := testSyncer(source, []string{"jellyfin"}, jellyfin)
+	if err := s.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if disabled, ok := jellyfin.disabled["alice"]; !ok || !disabled {
+		t.Error("alice should be disabled")
+	}
+	if disabled, ok := jellyfin.disabled["bob"]; !ok || disabled {
+		t.Error("bob should be re-enabled")
+	}
+	if _, ok := jellyfin.disabled["local-admin"]; ok {
+		t.Error("users unknown to Authentik should not be touched")
+	}
+}
+
+func TestSyncer_SkipsUninstalledAndNotReadyApps(t *testing.T) {
+	source := &fakeUserSource{users: []authentik.User{{Username: "alice", IsActive: true}}}
+	uninstalled := newFakeProvisioner("gitea")
+	notReady := newFakeProvisioner("jellyfin")
+	notReady.listErr = provisioner.ErrNotReady
+
+	s := testSyncer(source, []string{"jellyfin"}, uninstalled, notReady)
+	if err := s.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if len(uninstalled.created) != 0 {
+		t.Error("apps that aren't installed should not be provisioned")
+	}
+	if len(notReady.created) != 0 {
+		t.Error("apps that aren't ready should be skipped")
+	}
+}
+
+func TestSyncer_NoProvisionedAppsSkipsSource(t *testing.T) {
+	source := &fakeUserSource{err: errors.New("should not be called")}
+
+	s := testSyncer(source, []string{"miniflux"})
+	if err := s.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+}
+
+func TestSyncer_ReportsAppErrors(t *testing.T) {
+	source := &fakeUserSource{users: []authentik.User{{Username: "alice", IsActive: true}}}
+	broken := newFakeProvisioner("jellyfin")
+	broken.listErr = errors.New("connection refused")
+
+	s := testSyncer(source, []string{"jellyfin"}, broken)
+	if err := s.Sync(context.Background()); err == nil {
+		t.Error("Sync() expected error from failing app")
+	}
+}
+
+func TestSyncer_TriggerCoalesces(t *testing.T) {
+	s := testSyncer(&fakeUserSource{}, nil)
+
+	s.Trigger()
+	s.Trigger() // must not block
+
+	if len(s.trigger) != 1 {
+		t.Errorf("pending triggers = %d, want 1", len(s.trigger))
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter() (*MemoryLimiter, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMemoryLimiter()
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func TestMemoryLimiter_Burst(t *testing.T) {
+	m, _ := newTestLimiter()
+	limit := PerMinute(3)
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		res, err := m.Allow(ctx, "ip:1.2.3.4", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := m.Allow(ctx, "ip:1.2.3.4", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 20*time.Second, res.RetryAfter)
+	assert.Equal(t, time.Minute, res.Reset)
+
+	// Other keys have their own bucket
+	res, _ = m.Allow(ctx, "ip:5.6.7.8", limit)
+	assert.True(t, res.Allowed)
+}
+
+func TestMemoryLimiter_Refill(t *testing.T) {
+	m, now := newTestLimiter()
+	limit := PerMinute(2)
+	ctx := context.Background()
+
+	m.Allow(ctx, "k", limit)
+	m.Allow(ctx, "k", limit)
+	res, _ := m.Allow(ctx, "k", limit)
+	require.False(t, res.Allowed)
+
+	*now = now.Add(30 * time.Second)
+	res, _ = m.Allow(ctx, "k", limit)
+	assert.True(t, res.Allowed)
+	res, _ = m.Allow(ctx, "k", limit)
+	assert.False(t, res.Allowed)
+}
+
+func TestMemoryLimiter_SweepsIdleBuckets(t *testing.T) {
+	m, now := newTestLimiter()
+	ctx := context.Background()
+
+	m.Allow(ctx, "idle", PerMinute(10))
+	*now = now.Add(2 * time.Minute)
+	m.Allow(ctx, "active", PerMinute(10))
+
+	_, idle := m.buckets["idle"]
+	_, active := m.buckets["active"]
+	assert.False(t, idle)
+	assert.True(t, active)
+}
@@ -0,0 +1,62 @@
+// Package ratelimit implements token bucket rate limiting, backed by Redis
+// when available so limits are shared across restarts, and by memory otherwise.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Burst requests at once, refilling continuously so that Burst
+// more are allowed every Period
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// PerMinute returns a limit of n requests per minute
+func PerMinute(n int) Limit {
+	return Limit{Burst: n, Period: time.Minute}
+}
+
+// perMillisecond is the refill rate in tokens per millisecond
+func (l Limit) perMillisecond() float64 {
+	return float64(l.Burst) / float64(l.Period.Milliseconds())
+}
+
+// Result describes a rate limit decision
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed; zero when allowed
+}
+
+// Limiter decides whether a request identified by key may proceed
+type Limiter interface {
+	// Allow takes a token from key's bucket if one is available. When an
+	// error is returned alongside a result, the result is still usable
+	// (e.g. it came from a fallback).
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Compile-time assertions that both limiters implement Limiter
+var _ Limiter = (*MemoryLimiter)(nil)
+var _ Limiter = (*RedisLimiter)(nil)
+
+// newResult builds a Result from the tokens left in a bucket after the decision
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.perMillisecond()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration(math.Ceil((float64(limit.Burst)-tokens)/rate)) * time.Millisecond,
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration(math.Ceil((1-tokens)/rate)) * time.Millisecond
+	}
+	return res
+}
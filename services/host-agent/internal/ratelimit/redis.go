@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "bloud:ratelimit:"
+
+// tokenBucketScript refills and takes from a bucket atomically.
+// Tokens are returned as a string because Redis truncates Lua numbers to integers.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisLimiter keeps token buckets in Redis. If Redis is unreachable it falls
+// back to an in-memory limiter rather than failing requests.
+type RedisLimiter struct {
+	client   *redis.Client
+	fallback *MemoryLimiter
+}
+
+// NewRedisLimiter creates a limiter using an existing Redis client
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{client: client, fallback: NewMemoryLimiter()}
+}
+
+// Allow takes a token from key's bucket if one is available
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now().UnixMilli()
+	// Keys outlive a full refill by a little, then expire on their own
+	ttl := limit.Period.Milliseconds() + 1000
+
+	reply, err := tokenBucketScript.Run(ctx, l.client, []string{keyPrefix + key},
+		limit.Burst, limit.perMillisecond(), now, ttl).Slice()
+	if err != nil {
+		res, _ := l.fallback.Allow(ctx, key, limit)
+		return res, fmt.Errorf("redis rate limit failed, used memory: %w", err)
+	}
+	if len(reply) != 2 {
+		res, _ := l.fallback.Allow(ctx, key, limit)
+		return res, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokensStr, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid token count %q: %w", tokensStr, err)
+	}
+	return newResult(limit, tokens, allowed == 1), nil
+}
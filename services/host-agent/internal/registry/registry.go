@@ -0,0 +1,224 @@
+// Package registry resolves container image tags to digests with the OCI
+// distribution API, without pulling the images.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds each registry request
+const requestTimeout = 30 * time.Second
+
+// manifestTypes are the manifests a tag may point at, multi-arch indexes
+// first, as Podman pulls them
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// challengeParam matches a key="value" parameter of a WWW-Authenticate
+// challenge
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Reference is a parsed image reference, e.g. docker.io/library/redis:7
+type Reference struct {
+	Registry   string // e.g. docker.io, ghcr.io
+	Repository string // e.g. library/redis
+	Tag        string // empty when referenced by digest only
+	Digest     string // e.g. sha256:...; empty when referenced by tag
+}
+
+// ParseReference parses an image reference the way Podman does: a missing
+// registry is docker.io, a missing tag is latest, and single-name Docker Hub
+// images are under library/
+func ParseReference(s string) (Reference, error) {
+	var ref Reference
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+	}
+	// A colon after the last slash starts the tag; one before it is a port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	registry, repo, found := strings.Cut(name, "/")
+	if !found || !(strings.ContainsAny(registry, ".:") || registry == "localhost") {
+		registry, repo = "docker.io", name
+	}
+	if registry == "docker.io" && !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	if repo == "" || repo == "library/" {
+		return Reference{}, fmt.Errorf("invalid image reference %q", s)
+	}
+	ref.Registry = registry
+	ref.Repository = repo
+	return ref, nil
+}
+
+// String returns the full reference
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// host is the registry's API host
+func (r Reference) host() string {
+	if r.Registry == "docker.io" {
+		return "registry-1.docker.io"
+	}
+	return r.Registry
+}
+
+// Client queries registries anonymously
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client; a nil http client uses http.DefaultClient
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{http: httpClient}
+}
+
+// Digest returns the digest a reference's tag currently points at. A
+// reference with a digest is already resolved.
+func (c *Client) Digest(ctx context.Context, ref Reference) (string, error) {
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.host(), ref.Repository, ref.Tag)
+	resp, err := c.manifest(ctx, http.MethodHead, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	// Most registries want a token, even for public images
+	var token string
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err = c.token(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", err
+		}
+		resp, err = c.manifest(ctx, http.MethodHead, manifestURL, token)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry returned status %d for %s", resp.StatusCode, ref)
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Without the header, the digest is the manifest's hash
+	resp, err = c.manifest(ctx, http.MethodGet, manifestURL, token)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry returned status %d for %s", resp.StatusCode, ref)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", fmt.Errorf("reading manifest: %w", err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *Client) manifest(ctx context.Context, method, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying registry: %w", err)
+	}
+	return resp, nil
+}
+
+// token gets an anonymous pull token for a Bearer challenge
+func (c *Client) token(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported registry authentication %q", scheme)
+	}
+	values := map[string]string{}
+	for _, m := range challengeParam.FindAllStringSubmatch(params, -1) {
+		values[m[1]] = m[2]
+	}
+	realm, err := url.Parse(values["realm"])
+	if err != nil || realm.Scheme == "" {
+		return "", fmt.Errorf("invalid registry authentication realm %q", values["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if values[key] != "" {
+			query.Set(key, values[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("getting registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token request returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	for input, want := range map[string]Reference{
+		"redis":                                  {Registry: "docker.io", Repository: "library/redis", Tag: "latest"},
+		"miniflux/miniflux:2.1":                  {Registry: "docker.io", Repository: "miniflux/miniflux", Tag: "2.1"},
+		"docker.io/jellyfin/jellyfin:latest":     {Registry: "docker.io", Repository: "jellyfin/jellyfin", Tag: "latest"},
+		"ghcr.io/goauthentik/server:2025.10.3":   {Registry: "ghcr.io", Repository: "goauthentik/server", Tag: "2025.10.3"},
+		"localhost:5000/app@sha256:abc":          {Registry: "localhost:5000", Repository: "app", Digest: "sha256:abc"},
+		"traefik:v3.0@sha256:def":                {Registry: "docker.io", Repository: "library/traefik", Tag: "v3.0", Digest: "sha256:def"},
+		"registry.example.com:8443/team/app:1.0": {Registry: "registry.example.com:8443", Repository: "team/app", Tag: "1.0"},
+	} {
+		got, err := ParseReference(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	assert.Equal(t, "docker.io/library/redis:7", mustParse(t, "redis:7").String())
+	_, err := ParseReference("docker.io/")
+	assert.Error(t, err)
+}
+
+func mustParse(t *testing.T, s string) Reference {
+	ref, err := ParseReference(s)
+	require.NoError(t, err)
+	return ref
+}
+
+func TestClient_Digest(t *testing.T) {
+	manifest := `{"schemaVersion": 2}`
+	sum := sha256.Sum256([]byte(manifest))
+	bodyDigest := "sha256:" + hex.EncodeToString(sum[:])
+
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			assert.Equal(t, "repository:team/app:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token": "t0k3n"}`)
+		case r.Header.Get("Authorization") != "Bearer t0k3n":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:team/app:pull"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/team/app/manifests/1.0":
+			assert.Contains(t, r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json")
+			w.Header().Set("Docker-Content-Digest", "sha256:111")
+		case r.URL.Path == "/v2/team/app/manifests/nohdr":
+			fmt.Fprint(w, manifest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.Client())
+	host := strings.TrimPrefix(srv.URL, "https://")
+	ctx := context.Background()
+
+	digest, err := c.Digest(ctx, mustParse(t, host+"/team/app:1.0"))
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:111", digest)
+
+	digest, err = c.Digest(ctx, mustParse(t, host+"/team/app:nohdr"))
+	require.NoError(t, err)
+	assert.Equal(t, bodyDigest, digest)
+
+	_, err = c.Digest(ctx, mustParse(t, host+"/team/app:missing"))
+	assert.ErrorContains(t, err, "status 404")
+
+	// Digest references need no lookup
+	digest, err = c.Digest(ctx, mustParse(t, "nowhere.invalid/app@sha256:222"))
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:222", digest)
+}
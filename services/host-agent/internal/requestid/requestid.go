@@ -0,0 +1,71 @@
+// Package requestid carries a per-request correlation ID through contexts and
+// into slog records, so one API call can be followed through the orchestrator
+// and nixos-rebuild logs.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// Header is the HTTP header used to accept and return request IDs
+const Header = "X-Request-ID"
+
+// LogKey is the attribute name request IDs are logged under
+const LogKey = "request_id"
+
+type contextKey struct{}
+
+// New returns a random 16 character hex ID
+func New() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Handler is a slog.Handler that adds the request ID from the record's
+// context to every record logged with a *Context method (InfoContext etc.).
+type Handler struct {
+	slog.Handler
+}
+
+// NewHandler wraps next so records carry request IDs
+func NewHandler(next slog.Handler) *Handler {
+	return &Handler{Handler: next}
+}
+
+// Handle adds the request_id attribute when ctx carries one
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String(LogKey, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs keeps the wrapper when attributes are added
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps the wrapper when a group is opened
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{Handler: h.Handler.WithGroup(name)}
+}
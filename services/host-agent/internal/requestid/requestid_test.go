@@ -0,0 +1,47 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextRoundTrip(t *testing.T) {
+	assert.Equal(t, "", FromContext(context.Background()))
+
+	ctx := NewContext(context.Background(), "abc123")
+	assert.Equal(t, "abc123", FromContext(ctx))
+
+	// Detached contexts keep their values
+	assert.Equal(t, "abc123", FromContext(context.WithoutCancel(ctx)))
+}
+
+func TestNew(t *testing.T) {
+	a, b := New(), New()
+	assert.Len(t, a, 16)
+	assert.NotEqual(t, a, b)
+}
+
+func TestHandler_AddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+
+	logger.InfoContext(NewContext(context.Background(), "req-1"), "with id")
+	logger.Info("without id")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var first, second map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+
+	assert.Equal(t, "req-1", first[LogKey])
+	assert.Equal(t, "test", first["component"])
+	assert.NotContains(t, second, LogKey)
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Next(t *testing.T) {
+	// Wednesday
+	from := time.Date(2026, 1, 14, 10, 30, 20, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 1, 14, 10, 31, 0, 0, time.UTC)},
+		{"*/5 * * * *", time.Date(2026, 1, 14, 10, 35, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, 1, 15, 3, 0, 0, 0, time.UTC)},
+		{"15,45 10-11 * * *", time.Date(2026, 1, 14, 10, 45, 0, 0, time.UTC)},
+		{"0 4 * * 0", time.Date(2026, 1, 18, 4, 0, 0, 0, time.UTC)},
+		{"0 4 * * 7", time.Date(2026, 1, 18, 4, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		// A restricted day of month and day of week match if either does
+		{"0 0 20 * 5", time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 1, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2026, 1, 14, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.want, s.Next(from), tt.spec)
+	}
+}
+
+func TestParse_NeverMatches(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@yearly",
+		"@every 30s",
+		"@every soon",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
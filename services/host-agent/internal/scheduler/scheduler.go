@@ -0,0 +1,351 @@
+// Package scheduler runs the host agent's periodic background jobs, such as
+// database backups and stats rollups, on cron-like schedules that admins can
+// change. Schedules and the last run of each job are persisted, so a run
+// missed while the host agent was down can be caught up at startup.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+// tickInterval is how often due jobs are looked for; schedules have minute
+// resolution
+const tickInterval = 30 * time.Second
+
+var (
+	// ErrUnknownJob is returned for a job name that isn't registered
+	ErrUnknownJob = errors.New("unknown job")
+
+	// ErrRunning is returned when starting a job that is already running
+	ErrRunning = errors.New("job is already running")
+)
+
+// Job is a registered background job
+type Job struct {
+	Name        string
+	Description string
+	Spec        string // default schedule, see Parse
+	// Jitter is the most a run is randomly delayed by, so jobs on the same
+	// schedule don't all start at once
+	Jitter time.Duration
+	// CatchUp runs the job at startup when a scheduled run was missed while
+	// the host agent was down
+	CatchUp bool
+	// LastRun reports when the job last ran, for when the scheduler has no
+	// record of it, such as the newest backup; zero means it never ran,
+	// which makes a CatchUp job due. Optional.
+	LastRun func() time.Time
+	Run     func(ctx context.Context) error
+}
+
+// Status describes a job and its schedule
+type Status struct {
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Spec           string     `json:"spec"`
+	DefaultSpec    string     `json:"defaultSpec"`
+	Enabled        bool       `json:"enabled"`
+	Running        bool       `json:"running"`
+	LastRun        *time.Time `json:"lastRun,omitempty"`
+	LastDurationMs int64      `json:"lastDurationMs,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextRun        *time.Time `json:"nextRun,omitempty"` // unset while disabled
+}
+
+// Update changes a job's schedule; nil fields are left unchanged
+type Update struct {
+	Spec    *string `json:"spec,omitempty"` // empty restores the default
+	Enabled *bool   `json:"enabled,omitempty"`
+}
+
+type entry struct {
+	job      Job
+	state    store.ScheduleState
+	schedule Schedule
+	next     time.Time
+	running  bool
+}
+
+// Scheduler runs registered jobs when they are due
+type Scheduler struct {
+	store  store.ScheduleStoreInterface // nil keeps state in memory only
+	logger *slog.Logger
+	now    func() time.Time
+	jitter func(max time.Duration) time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	order   []string        // registration order, for listing
+	ctx     context.Context // jobs run with it; set by Start
+	wg      sync.WaitGroup
+}
+
+// New creates a scheduler persisting job state in st
+func New(st store.ScheduleStoreInterface, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		store:   st,
+		logger:  logger,
+		now:     time.Now,
+		jitter:  randomJitter,
+		entries: make(map[string]*entry),
+		ctx:     context.Background(),
+	}
+}
+
+func randomJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
+
+// Register adds a job. Jobs are registered before Start.
+func (s *Scheduler) Register(job Job) error {
+	schedule, err := Parse(job.Spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[job.Name]; ok {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	s.entries[job.Name] = &entry{
+		job:      job,
+		state:    store.ScheduleState{Name: job.Name, Enabled: true},
+		schedule: schedule,
+	}
+	s.order = append(s.order, job.Name)
+	return nil
+}
+
+// Start loads the persisted schedules and runs jobs as they fall due until
+// ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.load(ctx)
+
+	go func() {
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.tick()
+			}
+		}
+	}()
+}
+
+// load applies the persisted state and works out each job's next run,
+// catching up missed ones
+func (s *Scheduler) load(ctx context.Context) {
+	var states map[string]store.ScheduleState
+	if s.store != nil {
+		var err error
+		if states, err = s.store.All(); err != nil {
+			s.logger.Warn("failed to load schedules, using defaults", "error", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+	now := s.now()
+	for _, name := range s.order {
+		e := s.entries[name]
+		if st, ok := states[name]; ok {
+			e.state = st
+			if st.Spec != "" {
+				if schedule, err := Parse(st.Spec); err == nil {
+					e.schedule = schedule
+				} else {
+					s.logger.Warn("invalid saved schedule, using the default", "job", name, "spec", st.Spec, "error", err)
+					e.state.Spec = ""
+				}
+			}
+		}
+
+		lastRun := e.state.LastRunAt
+		known := !lastRun.IsZero()
+		if !known && e.job.LastRun != nil {
+			lastRun, known = e.job.LastRun(), true
+		}
+		switch {
+		case e.job.CatchUp && known && (lastRun.IsZero() || !e.schedule.Next(lastRun).After(now)):
+			s.logger.Info("catching up missed job run", "job", name, "lastRun", lastRun)
+			e.next = now
+		case known && !lastRun.IsZero() && e.schedule.Next(lastRun).After(now):
+			e.next = s.nextAfter(e, lastRun)
+		default:
+			e.next = s.nextAfter(e, now)
+		}
+	}
+}
+
+// nextAfter returns a job's next run after t, jitter included
+func (s *Scheduler) nextAfter(e *entry, t time.Time) time.Time {
+	next := e.schedule.Next(t)
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(s.jitter(e.job.Jitter))
+}
+
+// tick starts every enabled job that is due
+func (s *Scheduler) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for _, name := range s.order {
+		e := s.entries[name]
+		if e.state.Enabled && !e.running && !e.next.IsZero() && !e.next.After(now) {
+			s.startLocked(e)
+		}
+	}
+}
+
+// startLocked runs a job in the background and schedules its next run
+func (s *Scheduler) startLocked(e *entry) {
+	ctx := s.ctx
+	e.running = true
+	start := s.now()
+	e.next = s.nextAfter(e, start)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := e.job.Run(ctx)
+		duration := s.now().Sub(start)
+		if err != nil {
+			s.logger.Warn("scheduled job failed", "job", e.job.Name, "error", err, "duration", duration)
+		} else {
+			s.logger.Debug("scheduled job finished", "job", e.job.Name, "duration", duration)
+		}
+
+		s.mu.Lock()
+		e.running = false
+		e.state.LastRunAt = start
+		e.state.LastDuration = duration
+		e.state.LastError = ""
+		if err != nil {
+			e.state.LastError = err.Error()
+		}
+		state := e.state
+		s.mu.Unlock()
+		s.save(state)
+	}()
+}
+
+func (s *Scheduler) save(state store.ScheduleState) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.Save(state); err != nil {
+		s.logger.Warn("failed to save schedule", "job", state.Name, "error", err)
+	}
+}
+
+// List returns every job's status in registration order
+func (s *Scheduler) List() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, 0, len(s.order))
+	for _, name := range s.order {
+		statuses = append(statuses, s.statusLocked(s.entries[name]))
+	}
+	return statuses
+}
+
+func (s *Scheduler) statusLocked(e *entry) Status {
+	st := Status{
+		Name:           e.job.Name,
+		Description:    e.job.Description,
+		Spec:           e.job.Spec,
+		DefaultSpec:    e.job.Spec,
+		Enabled:        e.state.Enabled,
+		Running:        e.running,
+		LastDurationMs: e.state.LastDuration.Milliseconds(),
+		LastError:      e.state.LastError,
+	}
+	if e.state.Spec != "" {
+		st.Spec = e.state.Spec
+	}
+	if !e.state.LastRunAt.IsZero() {
+		lastRun := e.state.LastRunAt
+		st.LastRun = &lastRun
+	}
+	if e.state.Enabled && !e.next.IsZero() {
+		next := e.next
+		st.NextRun = &next
+	}
+	return st
+}
+
+// Update changes a job's schedule or turns it on or off, and persists it
+func (s *Scheduler) Update(name string, u Update) (Status, error) {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	if !ok {
+		s.mu.Unlock()
+		return Status{}, ErrUnknownJob
+	}
+
+	if u.Spec != nil {
+		spec := *u.Spec
+		if spec == e.job.Spec {
+			spec = ""
+		}
+		source := spec
+		if source == "" {
+			source = e.job.Spec
+		}
+		schedule, err := Parse(source)
+		if err != nil {
+			s.mu.Unlock()
+			return Status{}, err
+		}
+		e.schedule = schedule
+		e.state.Spec = spec
+	}
+	if u.Enabled != nil {
+		e.state.Enabled = *u.Enabled
+	}
+	e.next = s.nextAfter(e, s.now())
+	state, status := e.state, s.statusLocked(e)
+	s.mu.Unlock()
+
+	s.save(state)
+	return status, nil
+}
+
+// RunNow starts a job immediately, whether or not it is enabled
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[name]
+	if !ok {
+		return ErrUnknownJob
+	}
+	if e.running {
+		return ErrRunning
+	}
+	s.startLocked(e)
+	return nil
+}
+
+// wait blocks until running jobs finish; for tests
+func (s *Scheduler) wait() {
+	s.wg.Wait()
+}
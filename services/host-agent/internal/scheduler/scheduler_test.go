@@ -0,0 +1,192 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	mu     sync.Mutex
+	states map[string]store.ScheduleState
+}
+
+func (f *fakeStore) All() (map[string]store.ScheduleState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	states := make(map[string]store.ScheduleState, len(f.states))
+	for k, v := range f.states {
+		states[k] = v
+	}
+	return states, nil
+}
+
+func (f *fakeStore) Save(st store.ScheduleState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.states[st.Name] = st
+	return nil
+}
+
+// newTestScheduler returns a scheduler without jitter whose clock reads *now
+func newTestScheduler(st *fakeStore, now *time.Time) *Scheduler {
+	s := New(st, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.now = func() time.Time { return *now }
+	s.jitter = func(time.Duration) time.Duration { return 0 }
+	return s
+}
+
+func countingJob(name, spec string, runs *atomic.Int32) Job {
+	return Job{Name: name, Spec: spec, Run: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}}
+}
+
+func TestScheduler_RunsWhenDue(t *testing.T) {
+	now := time.Date(2026, 1, 14, 2, 59, 0, 0, time.UTC)
+	st := &fakeStore{states: map[string]store.ScheduleState{}}
+	s := newTestScheduler(st, &now)
+
+	var runs atomic.Int32
+	require.NoError(t, s.Register(countingJob("backup", "0 3 * * *", &runs)))
+	s.load(context.Background())
+
+	s.tick()
+	s.wait()
+	assert.Zero(t, runs.Load())
+
+	now = now.Add(time.Minute)
+	s.tick()
+	s.wait()
+	assert.Equal(t, int32(1), runs.Load())
+
+	// Not again until tomorrow
+	now = now.Add(time.Hour)
+	s.tick()
+	s.wait()
+	assert.Equal(t, int32(1), runs.Load())
+
+	status := s.List()[0]
+	assert.Equal(t, time.Date(2026, 1, 15, 3, 0, 0, 0, time.UTC), *status.NextRun)
+	assert.Equal(t, time.Date(2026, 1, 14, 3, 0, 0, 0, time.UTC), st.states["backup"].LastRunAt)
+}
+
+func TestScheduler_CatchUp(t *testing.T) {
+	now := time.Date(2026, 1, 14, 12, 0, 0, 0, time.UTC)
+	st := &fakeStore{states: map[string]store.ScheduleState{
+		// Missed this morning's 03:00 run
+		"backup": {Name: "backup", Enabled: true, LastRunAt: now.Add(-33 * time.Hour)},
+		"prune":  {Name: "prune", Enabled: true, LastRunAt: now.Add(-33 * time.Hour)},
+	}}
+	s := newTestScheduler(st, &now)
+
+	var backups, prunes atomic.Int32
+	backup := countingJob("backup", "0 3 * * *", &backups)
+	backup.CatchUp = true
+	require.NoError(t, s.Register(backup))
+	require.NoError(t, s.Register(countingJob("prune", "0 3 * * *", &prunes)))
+	s.load(context.Background())
+
+	s.tick()
+	s.wait()
+	assert.Equal(t, int32(1), backups.Load())
+	assert.Zero(t, prunes.Load(), "jobs without catch-up wait for the next run")
+}
+
+func TestScheduler_CatchUp_JobLastRun(t *testing.T) {
+	now := time.Date(2026, 1, 14, 12, 0, 0, 0, time.UTC)
+	s := newTestScheduler(&fakeStore{states: map[string]store.ScheduleState{}}, &now)
+
+	var runs atomic.Int32
+	job := countingJob("backup", "0 3 * * *", &runs)
+	job.CatchUp = true
+	job.LastRun = func() time.Time { return now.Add(-8 * time.Hour) } // after 03:00
+	require.NoError(t, s.Register(job))
+	s.load(context.Background())
+
+	s.tick()
+	s.wait()
+	assert.Zero(t, runs.Load())
+	assert.Equal(t, time.Date(2026, 1, 15, 3, 0, 0, 0, time.UTC), *s.List()[0].NextRun)
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+	now := time.Date(2026, 1, 14, 12, 0, 0, 0, time.UTC)
+	s := newTestScheduler(&fakeStore{states: map[string]store.ScheduleState{}}, &now)
+	s.jitter = func(max time.Duration) time.Duration { return max / 2 }
+
+	var runs atomic.Int32
+	job := countingJob("prune", "0 13 * * *", &runs)
+	job.Jitter = 20 * time.Minute
+	require.NoError(t, s.Register(job))
+	s.load(context.Background())
+
+	assert.Equal(t, time.Date(2026, 1, 14, 13, 10, 0, 0, time.UTC), *s.List()[0].NextRun)
+}
+
+func TestScheduler_UpdateAndRunNow(t *testing.T) {
+	now := time.Date(2026, 1, 14, 12, 0, 0, 0, time.UTC)
+	st := &fakeStore{states: map[string]store.ScheduleState{}}
+	s := newTestScheduler(st, &now)
+
+	release := make(chan struct{})
+	var runs atomic.Int32
+	require.NoError(t, s.Register(Job{Name: "prune", Spec: "@weekly", Run: func(context.Context) error {
+		runs.Add(1)
+		<-release
+		return errors.New("podman not found")
+	}}))
+	s.load(context.Background())
+
+	spec := "*/10 * * * *"
+	status, err := s.Update("prune", Update{Spec: &spec})
+	require.NoError(t, err)
+	assert.Equal(t, spec, status.Spec)
+	assert.Equal(t, "@weekly", status.DefaultSpec)
+	assert.Equal(t, time.Date(2026, 1, 14, 12, 10, 0, 0, time.UTC), *status.NextRun)
+	assert.Equal(t, spec, st.states["prune"].Spec)
+
+	disabled := false
+	status, err = s.Update("prune", Update{Enabled: &disabled})
+	require.NoError(t, err)
+	assert.Nil(t, status.NextRun)
+	now = now.Add(time.Hour)
+	s.tick()
+	s.wait()
+	assert.Zero(t, runs.Load(), "disabled jobs don't run on schedule")
+
+	bad := "every tuesday"
+	_, err = s.Update("prune", Update{Spec: &bad})
+	assert.Error(t, err)
+	_, err = s.Update("missing", Update{})
+	assert.ErrorIs(t, err, ErrUnknownJob)
+
+	// Disabled jobs still run on demand, one at a time
+	require.NoError(t, s.RunNow("prune"))
+	assert.ErrorIs(t, s.RunNow("prune"), ErrRunning)
+	assert.True(t, s.List()[0].Running)
+	close(release)
+	s.wait()
+
+	status = s.List()[0]
+	assert.False(t, status.Running)
+	assert.Equal(t, "podman not found", status.LastError)
+	assert.Equal(t, int32(1), runs.Load())
+
+	// Restoring the default spec clears the override
+	weekly := "@weekly"
+	status, err = s.Update("prune", Update{Spec: &weekly})
+	require.NoError(t, err)
+	assert.Equal(t, "@weekly", status.Spec)
+	assert.Equal(t, "", st.states["prune"].Spec)
+}
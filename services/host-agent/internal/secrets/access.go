@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Secret access actions
+const (
+	AccessRead   = "read"
+	AccessWrite  = "write"
+	AccessDelete = "delete"
+)
+
+// maxPendingAccesses bounds the accesses buffered before a recorder is set
+const maxPendingAccesses = 1000
+
+// Access is one read or write of a secret value.
+type Access struct {
+	Component string // package that touched the secret, e.g. internal/api
+	Caller    string // function, e.g. api.(*Server).handleSetAppSecret
+	Action    string // read, write or delete
+	App       string // empty for deployment-wide secrets
+	Secret    string // e.g. postgresPassword, apiKey, or * for all of them
+	At        time.Time
+}
+
+// AccessRecorder receives every secret access, such as the audit table.
+// It must not call back into the manager.
+type AccessRecorder func(Access)
+
+// secretsPackage is this package's import path, so callers outside it can be found
+var secretsPackage = reflect.TypeOf(Manager{}).PkgPath()
+
+// SetAccessRecorder sends secret accesses to record. Accesses made before a
+// recorder is set (while config loads, before the database is up) are
+// buffered and passed on now.
+func (m *Manager) SetAccessRecorder(record AccessRecorder) {
+	m.accessMu.Lock()
+	pending := m.pendingAccesses
+	m.pendingAccesses = nil
+	m.recordAccess = record
+	m.accessMu.Unlock()
+
+	if record != nil {
+		for _, a := range pending {
+			record(a)
+		}
+	}
+}
+
+// logAccess records an access to a secret by the first caller outside this package
+func (m *Manager) logAccess(action, app, secret string) {
+	a := Access{Action: action, App: app, Secret: secret, At: time.Now()}
+	a.Component, a.Caller = accessCaller()
+
+	m.accessMu.Lock()
+	record := m.recordAccess
+	if record == nil && len(m.pendingAccesses) < maxPendingAccesses {
+		m.pendingAccesses = append(m.pendingAccesses, a)
+	}
+	m.accessMu.Unlock()
+
+	if record != nil {
+		record(a)
+	}
+}
+
+// accessCaller returns the package (relative to the module) and function
+// of the nearest caller outside the secrets package
+func accessCaller() (component, caller string) {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		pkg, fn := splitFuncName(frame.Function)
+		if pkg != "" && pkg != secretsPackage {
+			return relativePackage(pkg), fn
+		}
+		if !more {
+			return "unknown", ""
+		}
+	}
+}
+
+// splitFuncName splits a runtime function name such as
+// example.com/mod/internal/api.(*Server).handleX into its package path and
+// the package-qualified function, api.(*Server).handleX
+func splitFuncName(name string) (pkg, fn string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", ""
+	}
+	return name[:slash+1+dot], name[slash+1:]
+}
+
+// relativePackage trims this module's path from pkg, e.g. internal/api
+func relativePackage(pkg string) string {
+	module := strings.TrimSuffix(secretsPackage, "/internal/secrets")
+	return strings.TrimPrefix(pkg, module+"/")
+}
@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestManager_RecordsAccess(t *testing.T) {
+	m := NewManager(filepath.Join(t.TempDir(), "secrets.json"))
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	m.GetPostgresPassword()
+
+	var accesses []Access
+	m.SetAccessRecorder(func(a Access) { accesses = append(accesses, a) })
+
+	// Generation and the read before the recorder was set were buffered
+	if len(accesses) != 2 {
+		t.Fatalf("expected 2 buffered accesses, got %+v", accesses)
+	}
+	if a := accesses[0]; a.Action != AccessWrite || a.Secret != "*" {
+		t.Errorf("expected generation to be recorded as a write of *, got %+v", a)
+	}
+	if a := accesses[1]; a.Action != AccessRead || a.Secret != "postgresPassword" {
+		t.Errorf("expected postgres password read, got %+v", a)
+	}
+	// Attributed to the first caller outside this package (here the test runner)
+	if a := accesses[1]; a.Component != "testing" {
+		t.Errorf("expected a caller outside the secrets package, got %q", a.Component)
+	}
+
+	if err := m.SetAppSecret("radarr", "apiKey", "k"); err != nil {
+		t.Fatalf("failed to set secret: %v", err)
+	}
+	m.AppSecretFingerprint("radarr", "apiKey")
+	m.AppSecretKeys("radarr")
+	if err := m.DeleteAppSecret("radarr", "apiKey"); err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+
+	if len(accesses) != 4 {
+		t.Fatalf("expected a write and a delete, got %+v", accesses[2:])
+	}
+	if a := accesses[2]; a.Action != AccessWrite || a.App != "radarr" || a.Secret != "apiKey" {
+		t.Errorf("unexpected write access %+v", a)
+	}
+	if a := accesses[3]; a.Action != AccessDelete || a.App != "radarr" || a.Secret != "apiKey" {
+		t.Errorf("unexpected delete access %+v", a)
+	}
+}
+
+func TestSplitFuncName(t *testing.T) {
+	pkg, fn := splitFuncName("example.com/mod/internal/api.(*Server).handleX.func1")
+	if pkg != "example.com/mod/internal/api" || fn != "api.(*Server).handleX.func1" {
+		t.Errorf("got %q, %q", pkg, fn)
+	}
+	if pkg, _ := splitFuncName("main.main"); pkg != "main" {
+		t.Errorf("expected main package, got %q", pkg)
+	}
+}
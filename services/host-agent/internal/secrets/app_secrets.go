@@ -0,0 +1,150 @@
+package secrets
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// builtinAppSecrets are the per-app secrets bloud generates itself
+var builtinAppSecrets = []string{"adminPassword", "oauthClientSecret", "databasePassword"}
+
+// appSecretKeyPattern limits custom secret names to identifiers such as
+// apiKey, smtp.password or SONARR_API_KEY
+var appSecretKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]{0,63}$`)
+
+// fingerprintKeyInfo is the HKDF context for the fingerprint key, so
+// fingerprints can't be used to recover the app secrets key
+const fingerprintKeyInfo = "bloud secret fingerprints v1"
+
+// AppNames returns the apps that have secrets stored, without reading them.
+func (m *Manager) AppNames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.secrets == nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(m.secrets.AppSecrets))
+}
+
+// StoredKeyVersion returns the key version per-app secrets currently use
+// (at least 1), ignoring any rotation in progress.
+func (m *Manager) StoredKeyVersion() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.secrets == nil {
+		return 1
+	}
+	return max(m.secrets.KeyVersion, 1)
+}
+
+// IsBuiltinAppSecret reports whether key names a secret bloud generates and
+// manages itself, rather than one stored through the API.
+func IsBuiltinAppSecret(key string) bool {
+	return slices.Contains(builtinAppSecrets, key)
+}
+
+// ValidateAppSecretKey checks that key can be used as a custom secret name.
+func ValidateAppSecretKey(key string) error {
+	if IsBuiltinAppSecret(key) {
+		return fmt.Errorf("secret %s is managed by bloud", key)
+	}
+	for _, id := range builtinCredentialIDs {
+		if key == id {
+			return fmt.Errorf("secret %s is managed by bloud", key)
+		}
+	}
+	if !appSecretKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid secret key %q: use up to 64 letters, digits, '_', '.' or '-', starting with a letter", key)
+	}
+	return nil
+}
+
+// DeleteAppSecret removes one secret from an app and saves to file.
+// Deleting a secret that isn't set is a no-op.
+func (m *Manager) DeleteAppSecret(appName, key string) error {
+	m.logAccess(AccessDelete, appName, key)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.secrets == nil || m.secrets.AppSecrets == nil {
+		return nil
+	}
+	appSecrets, ok := m.secrets.AppSecrets[appName]
+	if !ok {
+		return nil
+	}
+
+	switch key {
+	case "adminPassword":
+		appSecrets.AdminPassword = ""
+	case "oauthClientSecret":
+		appSecrets.OAuthClientSecret = ""
+	case "databasePassword":
+		appSecrets.DatabasePassword = ""
+	default:
+		if _, ok := appSecrets.Custom[key]; !ok {
+			return nil
+		}
+		custom := maps.Clone(appSecrets.Custom)
+		delete(custom, key)
+		if len(custom) == 0 {
+			custom = nil
+		}
+		appSecrets.Custom = custom
+	}
+	m.secrets.AppSecrets[appName] = appSecrets
+
+	return m.saveLocked()
+}
+
+// AppSecretFingerprint returns a short keyed hash of an app secret, or ""
+// if it isn't set. Fingerprints show whether a value changed without
+// revealing it, and can't be brute-forced without the host secret. They
+// aren't recorded as accesses since the value never leaves the manager.
+func (m *Manager) AppSecretFingerprint(appName, key string) string {
+	value := m.appSecret(appName, key)
+	hostSecret := m.get("ssoHostSecret")
+	if value == "" || hostSecret == "" {
+		return ""
+	}
+
+	var fpKey [32]byte
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(hostSecret), nil, []byte(fingerprintKeyInfo)), fpKey[:]); err != nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, fpKey[:])
+	mac.Write([]byte(appName + "\x00" + key + "\x00" + value))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// Values returns every secret value the manager holds, deployment-wide and
+// per-app, so they can be scrubbed from logs and support bundles.
+func (m *Manager) Values() []string {
+	m.logAccess(AccessRead, "", "*")
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.secrets == nil {
+		return nil
+	}
+	s := m.secrets
+	values := []string{
+		s.PostgresPassword, s.AuthentikSecretKey, s.AuthentikBootstrapPassword,
+		s.AuthentikBootstrapToken, s.LDAPOutpostToken, s.LDAPBindPassword, s.SSOHostSecret,
+	}
+	for _, app := range s.AppSecrets {
+		values = append(values, app.AdminPassword, app.OAuthClientSecret, app.DatabasePassword)
+		values = slices.AppendSeq(values, maps.Values(app.Custom))
+	}
+	return slices.DeleteFunc(values, func(v string) bool { return v == "" })
+}
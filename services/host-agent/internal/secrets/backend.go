@@ -0,0 +1,319 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Backend stores the secrets document (the JSON that used to live only in
+// secrets.json). Per-app secrets inside it are already sealed by the Manager.
+type Backend interface {
+	// Name identifies the backend in logs, e.g. "file"
+	Name() string
+
+	// Read returns the stored document, or an error wrapping fs.ErrNotExist
+	// if nothing has been stored yet
+	Read() ([]byte, error)
+
+	// Write replaces the stored document
+	Write(data []byte) error
+}
+
+// Backend types selectable with BLOUD_SECRETS_BACKEND
+const (
+	BackendFile  = "file"
+	BackendSops  = "sops"
+	BackendVault = "vault"
+)
+
+// BackendFromEnv builds the backend selected by BLOUD_SECRETS_BACKEND.
+// The file backend (default) stores the document at path, sealed with a
+// machine key when one is configured.
+//
+//	file:  BLOUD_SECRETS_KEY_FILE or BLOUD_SECRETS_KEY_CREDENTIAL (TPM-sealed), both optional
+//	sops:  BLOUD_SECRETS_SOPS_FILE (required), BLOUD_SECRETS_SOPS_BIN (default "sops")
+//	vault: VAULT_ADDR (required), VAULT_TOKEN or BLOUD_SECRETS_VAULT_TOKEN_FILE (required),
+//	       BLOUD_SECRETS_VAULT_PATH (KV v2 "<mount>/<path>", default "secret/bloud")
+func BackendFromEnv(path string) (Backend, error) {
+	switch backend := os.Getenv("BLOUD_SECRETS_BACKEND"); backend {
+	case "", BackendFile:
+		keys, err := keySourceFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		if keys != nil {
+			return NewSealedFileBackend(path, keys), nil
+		}
+		return NewFileBackend(path), nil
+
+	case BackendSops:
+		file := os.Getenv("BLOUD_SECRETS_SOPS_FILE")
+		if file == "" {
+			return nil, errors.New("BLOUD_SECRETS_SOPS_FILE is required for the sops backend")
+		}
+		bin := os.Getenv("BLOUD_SECRETS_SOPS_BIN")
+		if bin == "" {
+			bin = "sops"
+		}
+		return NewSopsBackend(file, bin), nil
+
+	case BackendVault:
+		addr := os.Getenv("VAULT_ADDR")
+		if addr == "" {
+			return nil, errors.New("VAULT_ADDR is required for the vault backend")
+		}
+		token := os.Getenv("VAULT_TOKEN")
+		if tokenFile := os.Getenv("BLOUD_SECRETS_VAULT_TOKEN_FILE"); token == "" && tokenFile != "" {
+			data, err := os.ReadFile(tokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading vault token file: %w", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if token == "" {
+			return nil, errors.New("VAULT_TOKEN or BLOUD_SECRETS_VAULT_TOKEN_FILE is required for the vault backend")
+		}
+		kvPath := os.Getenv("BLOUD_SECRETS_VAULT_PATH")
+		if kvPath == "" {
+			kvPath = "secret/bloud"
+		}
+		return NewVaultBackend(addr, token, kvPath)
+
+	default:
+		return nil, fmt.Errorf("unknown secrets backend %q (want file, sops or vault)", backend)
+	}
+}
+
+// FileBackend stores the document as a plain JSON file readable only by its owner.
+type FileBackend struct {
+	path string
+}
+
+// NewFileBackend creates a backend storing the document at path.
+func NewFileBackend(path string) *FileBackend {
+	return &FileBackend{path: path}
+}
+
+// Name implements Backend.
+func (b *FileBackend) Name() string { return BackendFile }
+
+// Read implements Backend.
+func (b *FileBackend) Read() ([]byte, error) {
+	return os.ReadFile(b.path)
+}
+
+// Write implements Backend.
+func (b *FileBackend) Write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		return fmt.Errorf("creating secrets directory: %w", err)
+	}
+	return os.WriteFile(b.path, data, 0600)
+}
+
+// SopsBackend stores the document in a sops-encrypted JSON file, such as one
+// committed with the flake and decrypted by sops-nix. Encryption uses the
+// creation rules in the repository's .sops.yaml; decryption needs the host's
+// key (e.g. SOPS_AGE_KEY_FILE).
+type SopsBackend struct {
+	path string
+	bin  string
+
+	// run executes sops with stdin and returns its stdout; replaced in tests
+	run func(stdin []byte, args ...string) ([]byte, error)
+}
+
+// NewSopsBackend creates a backend for the encrypted file at path, using the
+// sops binary bin.
+func NewSopsBackend(path, bin string) *SopsBackend {
+	b := &SopsBackend{path: path, bin: bin}
+	b.run = b.exec
+	return b
+}
+
+// Name implements Backend.
+func (b *SopsBackend) Name() string { return BackendSops }
+
+// Read implements Backend.
+func (b *SopsBackend) Read() ([]byte, error) {
+	if _, err := os.Stat(b.path); err != nil {
+		return nil, err
+	}
+	data, err := b.run(nil, "--decrypt", "--input-type", "json", "--output-type", "json", b.path)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %w", b.path, err)
+	}
+	return data, nil
+}
+
+// Write implements Backend. The file is replaced atomically so a failed
+// encryption never leaves it truncated.
+func (b *SopsBackend) Write(data []byte) error {
+	encrypted, err := b.run(data, "--encrypt", "--input-type", "json", "--output-type", "json",
+		"--filename-override", b.path, "/dev/stdin")
+	if err != nil {
+		return fmt.Errorf("encrypting %s: %w", b.path, err)
+	}
+
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, encrypted, 0600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replacing %s: %w", b.path, err)
+	}
+	return nil
+}
+
+func (b *SopsBackend) exec(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(b.bin, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// vaultTimeout bounds each request to Vault
+const vaultTimeout = 10 * time.Second
+
+// VaultBackend stores the document as the fields of a HashiCorp Vault KV v2 secret.
+type VaultBackend struct {
+	addr   string
+	token  string
+	mount  string
+	path   string
+	client *http.Client
+}
+
+// NewVaultBackend creates a backend for the KV v2 secret at kvPath
+// ("<mount>/<path>", e.g. "secret/bloud") on the Vault server at addr.
+func NewVaultBackend(addr, token, kvPath string) (*VaultBackend, error) {
+	mount, path, ok := strings.Cut(strings.Trim(kvPath, "/"), "/")
+	if !ok || mount == "" || path == "" {
+		return nil, fmt.Errorf("vault path %q must be <mount>/<path>", kvPath)
+	}
+	return &VaultBackend{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		mount:  mount,
+		path:   path,
+		client: &http.Client{Timeout: vaultTimeout},
+	}, nil
+}
+
+// Name implements Backend.
+func (b *VaultBackend) Name() string { return BackendVault }
+
+// Read implements Backend.
+func (b *VaultBackend) Read() ([]byte, error) {
+	resp, err := b.do(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("vault secret %s/%s: %w", b.mount, b.path, fs.ErrNotExist)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, vaultError(resp)
+	}
+
+	var body struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding vault response: %w", err)
+	}
+	// A deleted latest version reads back with null data
+	if len(body.Data.Data) == 0 || string(body.Data.Data) == "null" {
+		return nil, fmt.Errorf("vault secret %s/%s: %w", b.mount, b.path, fs.ErrNotExist)
+	}
+	return body.Data.Data, nil
+}
+
+// Write implements Backend.
+func (b *VaultBackend) Write(data []byte) error {
+	payload, err := json.Marshal(struct {
+		Data json.RawMessage `json:"data"`
+	}{Data: data})
+	if err != nil {
+		return fmt.Errorf("encoding vault request: %w", err)
+	}
+
+	resp, err := b.do(http.MethodPost, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return vaultError(resp)
+	}
+	return nil
+}
+
+func (b *VaultBackend) do(method string, body []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s", b.addr, b.mount, b.path)
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", b.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	// Read the body before the context is cancelled
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading vault response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// vaultError describes a failed Vault response using its "errors" field
+func vaultError(resp *http.Response) error {
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && len(body.Errors) > 0 {
+		return fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+	}
+	return fmt.Errorf("vault returned %d", resp.StatusCode)
+}
+
+// failingBackend reports an invalid backend configuration on every use
+type failingBackend struct {
+	err error
+}
+
+func (b failingBackend) Name() string            { return "invalid" }
+func (b failingBackend) Read() ([]byte, error)   { return nil, b.err }
+func (b failingBackend) Write(data []byte) error { return b.err }
@@ -0,0 +1,198 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVault serves a single KV v2 secret at secret/bloud
+func fakeVault(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	var stored json.RawMessage
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/bloud" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": stored}})
+		case http.MethodPost:
+			var body struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			stored = body.Data
+			w.Write([]byte(`{"data":{"version":1}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVaultBackend_ManagerRoundTrip(t *testing.T) {
+	srv := fakeVault(t, "root-token")
+	backend, err := NewVaultBackend(srv.URL, "root-token", "secret/bloud")
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+
+	secretsPath := filepath.Join(t.TempDir(), "secrets.json")
+	m := NewManagerWithBackend(secretsPath, backend)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to generate secrets: %v", err)
+	}
+	if err := m.SetAppSecret("miniflux", "adminPassword", "vault-stored"); err != nil {
+		t.Fatalf("failed to set app secret: %v", err)
+	}
+
+	// Nothing but env files is written to the data dir
+	if _, err := os.Stat(secretsPath); !os.IsNotExist(err) {
+		t.Errorf("expected no secrets.json with the vault backend, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(secretsPath), "postgres.env")); err != nil {
+		t.Errorf("expected env files to be written: %v", err)
+	}
+
+	m2 := NewManagerWithBackend(secretsPath, backend)
+	if err := m2.Load(); err != nil {
+		t.Fatalf("failed to load secrets: %v", err)
+	}
+	if m2.GetPostgresPassword() != m.GetPostgresPassword() {
+		t.Error("postgres password not read back from vault")
+	}
+	if got := m2.GetAppSecret("miniflux", "adminPassword"); got != "vault-stored" {
+		t.Errorf("expected app secret from vault, got %q", got)
+	}
+}
+
+func TestVaultBackend_Errors(t *testing.T) {
+	srv := fakeVault(t, "root-token")
+
+	backend, _ := NewVaultBackend(srv.URL, "wrong-token", "secret/bloud")
+	if _, err := backend.Read(); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected permission denied, got %v", err)
+	}
+
+	backend, _ = NewVaultBackend(srv.URL, "root-token", "secret/bloud")
+	if _, err := backend.Read(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not-exist for an unwritten secret, got %v", err)
+	}
+
+	if _, err := NewVaultBackend(srv.URL, "root-token", "bloud"); err == nil {
+		t.Error("expected a path without a mount to be rejected")
+	}
+}
+
+func TestSopsBackend_ManagerRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	encryptedPath := filepath.Join(dir, "bloud.sops.json")
+	backend := NewSopsBackend(encryptedPath, "sops")
+
+	// Stand-in for sops: "encrypts" by prefixing the document
+	const marker = "SOPS:"
+	var calls [][]string
+	backend.run = func(stdin []byte, args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		switch args[0] {
+		case "--encrypt":
+			return append([]byte(marker), stdin...), nil
+		case "--decrypt":
+			data, err := os.ReadFile(args[len(args)-1])
+			if err != nil {
+				return nil, err
+			}
+			return bytes.TrimPrefix(data, []byte(marker)), nil
+		}
+		return nil, errors.New("unexpected sops call")
+	}
+
+	m := NewManagerWithBackend(filepath.Join(dir, "secrets.json"), backend)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to generate secrets: %v", err)
+	}
+
+	data, err := os.ReadFile(encryptedPath)
+	if err != nil {
+		t.Fatalf("expected encrypted file: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte(marker)) {
+		t.Error("expected the file to be written through sops")
+	}
+	if !strings.Contains(strings.Join(calls[0], " "), "--filename-override "+encryptedPath) {
+		t.Errorf("expected creation rules to match the target file, got %v", calls[0])
+	}
+
+	m2 := NewManagerWithBackend(filepath.Join(dir, "secrets.json"), backend)
+	if err := m2.Load(); err != nil {
+		t.Fatalf("failed to load secrets: %v", err)
+	}
+	if m2.GetSSOHostSecret() != m.GetSSOHostSecret() {
+		t.Error("host secret not read back through sops")
+	}
+}
+
+func TestBackendFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+
+	t.Setenv("BLOUD_SECRETS_BACKEND", "")
+	backend, err := BackendFromEnv(path)
+	if err != nil || backend.Name() != BackendFile {
+		t.Errorf("expected file backend by default, got %v, %v", backend, err)
+	}
+
+	t.Setenv("BLOUD_SECRETS_BACKEND", "sops")
+	t.Setenv("BLOUD_SECRETS_SOPS_FILE", "")
+	if _, err := BackendFromEnv(path); err == nil {
+		t.Error("expected sops without a file to be rejected")
+	}
+
+	t.Setenv("BLOUD_SECRETS_BACKEND", "vault")
+	t.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
+	t.Setenv("VAULT_TOKEN", "")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BLOUD_SECRETS_VAULT_TOKEN_FILE", tokenFile)
+	backend, err = BackendFromEnv(path)
+	if err != nil {
+		t.Fatalf("expected vault backend: %v", err)
+	}
+	if vault := backend.(*VaultBackend); vault.token != "file-token" || vault.mount != "secret" || vault.path != "bloud" {
+		t.Errorf("unexpected vault config: %+v", vault)
+	}
+
+	// An invalid configuration surfaces when secrets are loaded
+	t.Setenv("BLOUD_SECRETS_BACKEND", "keychain")
+	if err := NewManagerFromEnv(path).Load(); err == nil || !strings.Contains(err.Error(), "unknown secrets backend") {
+		t.Errorf("expected unknown backend error, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Credential IDs of the built-in per-app secrets. Custom secrets are loaded
+// under their own key.
+const (
+	CredentialAdminPassword     = "admin-password"
+	CredentialOAuthClientSecret = "oauth-client-secret"
+	CredentialDatabasePassword  = "database-password"
+)
+
+// CredentialsDir is the directory beside the env files holding one
+// directory of credential files per app, read by systemd's LoadCredential=
+const CredentialsDir = "credentials"
+
+// builtinCredentialIDs maps built-in secret keys to their credential IDs
+var builtinCredentialIDs = map[string]string{
+	"adminPassword":     CredentialAdminPassword,
+	"oauthClientSecret": CredentialOAuthClientSecret,
+	"databasePassword":  CredentialDatabasePassword,
+}
+
+// CredentialID returns the systemd credential ID an app secret is loaded as.
+func CredentialID(key string) string {
+	if id, ok := builtinCredentialIDs[key]; ok {
+		return id
+	}
+	return key
+}
+
+// CredentialPath returns the file an app's credential is written to, given
+// the data directory.
+func CredentialPath(dataDir, appName, id string) string {
+	return filepath.Join(dataDir, CredentialsDir, appName, id)
+}
+
+// writeCredentialFiles writes each app secret to credentials/<app>/<id> and
+// removes credential files for secrets and apps no longer stored
+func (m *Manager) writeCredentialFiles(dir string) error {
+	root := filepath.Join(dir, CredentialsDir)
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return fmt.Errorf("creating credentials directory: %w", err)
+	}
+
+	for appName, appSecrets := range m.secrets.AppSecrets {
+		appDir := filepath.Join(root, appName)
+		if err := os.MkdirAll(appDir, 0700); err != nil {
+			return fmt.Errorf("creating credentials directory for %s: %w", appName, err)
+		}
+
+		values := map[string]string{}
+		for key, value := range map[string]string{
+			"adminPassword":     appSecrets.AdminPassword,
+			"oauthClientSecret": appSecrets.OAuthClientSecret,
+			"databasePassword":  appSecrets.DatabasePassword,
+		} {
+			if value != "" {
+				values[CredentialID(key)] = value
+			}
+		}
+		for key, value := range appSecrets.Custom {
+			values[CredentialID(key)] = value
+		}
+
+		for id, value := range values {
+			if err := os.WriteFile(filepath.Join(appDir, id), []byte(value), 0600); err != nil {
+				return fmt.Errorf("writing %s credential %s: %w", appName, id, err)
+			}
+		}
+
+		entries, err := os.ReadDir(appDir)
+		if err != nil {
+			return fmt.Errorf("reading credentials of %s: %w", appName, err)
+		}
+		for _, entry := range entries {
+			if _, ok := values[entry.Name()]; !ok {
+				if err := os.Remove(filepath.Join(appDir, entry.Name())); err != nil {
+					return fmt.Errorf("removing %s credential %s: %w", appName, entry.Name(), err)
+				}
+			}
+		}
+	}
+
+	// Apps whose secrets were deleted, e.g. on uninstall
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return fmt.Errorf("reading credentials directory: %w", err)
+	}
+	for _, entry := range entries {
+		if _, ok := m.secrets.AppSecrets[entry.Name()]; !ok {
+			if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+				return fmt.Errorf("removing credentials of %s: %w", entry.Name(), err)
+			}
+		}
+	}
+
+	return nil
+}
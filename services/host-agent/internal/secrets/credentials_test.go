@@ -0,0 +1,68 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManager_WritesCredentialFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(filepath.Join(tmpDir, "secrets.json"))
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if err := m.SetAppSecret("miniflux", "oauthClientSecret", "client-secret"); err != nil {
+		t.Fatalf("failed to set secret: %v", err)
+	}
+	if err := m.SetAppSecret("miniflux", "smtpPassword", "smtp-secret"); err != nil {
+		t.Fatalf("failed to set secret: %v", err)
+	}
+
+	for id, want := range map[string]string{
+		CredentialOAuthClientSecret: "client-secret",
+		"smtpPassword":              "smtp-secret",
+	} {
+		path := CredentialPath(tmpDir, "miniflux", id)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading credential %s: %v", id, err)
+		}
+		if string(data) != want {
+			t.Errorf("credential %s = %q, want %q", id, data, want)
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+			t.Errorf("credential %s has mode %o, want 0600", id, info.Mode().Perm())
+		}
+	}
+
+	// The client secret is no longer written to the env file
+	env, err := os.ReadFile(filepath.Join(tmpDir, "miniflux.env"))
+	if err != nil {
+		t.Fatalf("reading env file: %v", err)
+	}
+	if strings.Contains(string(env), "client-secret") {
+		t.Error("expected the OAuth client secret to be kept out of the env file")
+	}
+
+	// Deleted secrets and apps lose their credential files
+	if err := m.DeleteAppSecret("miniflux", "smtpPassword"); err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+	if _, err := os.Stat(CredentialPath(tmpDir, "miniflux", "smtpPassword")); !os.IsNotExist(err) {
+		t.Error("expected the deleted secret's credential file to be removed")
+	}
+	if err := m.DeleteAppSecrets("miniflux"); err != nil {
+		t.Fatalf("failed to delete app secrets: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, CredentialsDir, "miniflux")); !os.IsNotExist(err) {
+		t.Error("expected the app's credentials directory to be removed")
+	}
+}
+
+func TestValidateAppSecretKey_RejectsCredentialIDs(t *testing.T) {
+	if err := ValidateAppSecretKey(CredentialOAuthClientSecret); err == nil {
+		t.Error("expected a built-in credential ID to be rejected as a custom key")
+	}
+}
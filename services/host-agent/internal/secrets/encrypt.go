@@ -0,0 +1,151 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// encryptedPrefix marks a secret value sealed with NaCl secretbox.
+// The version lets the key derivation change without guessing formats.
+const encryptedPrefix = "enc:v1:"
+
+// appSecretsKeyInfo is the HKDF context for the app secrets key, so it
+// never matches a key derived from the host secret for anything else
+const appSecretsKeyInfo = "bloud app secrets at rest v1"
+
+// deriveKey derives the app secrets encryption key from the host secret.
+func deriveKey(hostSecret string) (*[32]byte, error) {
+	if hostSecret == "" {
+		return nil, errors.New("host secret is empty")
+	}
+	var key [32]byte
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(hostSecret), nil, []byte(appSecretsKeyInfo)), key[:]); err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	return &key, nil
+}
+
+// isEncrypted reports whether a stored value is sealed rather than plaintext.
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// encryptValue seals a value with a random nonce. Empty values stay empty
+// so unset secrets remain recognisable in the file.
+func encryptValue(key *[32]byte, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	sealed, err := seal(key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptValue opens a sealed value. Plaintext values (written before
+// encryption existed) are returned unchanged.
+func decryptValue(key *[32]byte, value string) (string, error) {
+	if !isEncrypted(value) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("decoding encrypted secret: %w", err)
+	}
+	plaintext, err := open(key, sealed)
+	if err != nil {
+		return "", fmt.Errorf("decrypting secret failed, check the host secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// seal encrypts plaintext with secretbox, returning nonce||box.
+func seal(key *[32]byte, plaintext []byte) ([]byte, error) {
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	return secretbox.Seal(nonce[:], plaintext, &nonce, key), nil
+}
+
+// open decrypts nonce||box produced by seal.
+func open(key *[32]byte, sealed []byte) ([]byte, error) {
+	if len(sealed) < 24+secretbox.Overhead {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	var nonce [24]byte
+	copy(nonce[:], sealed[:24])
+	plaintext, ok := secretbox.Open(nil, sealed[24:], &nonce, key)
+	if !ok {
+		return nil, errors.New("wrong key or corrupted data")
+	}
+	return plaintext, nil
+}
+
+// sealAppSecrets returns a copy of the app secrets with every value encrypted.
+func sealAppSecrets(key *[32]byte, appSecrets map[string]AppSecrets) (map[string]AppSecrets, error) {
+	sealed := make(map[string]AppSecrets, len(appSecrets))
+	for app, s := range appSecrets {
+		var out AppSecrets
+		for _, f := range []struct {
+			in  string
+			out *string
+		}{
+			{s.AdminPassword, &out.AdminPassword},
+			{s.OAuthClientSecret, &out.OAuthClientSecret},
+			{s.DatabasePassword, &out.DatabasePassword},
+		} {
+			v, err := encryptValue(key, f.in)
+			if err != nil {
+				return nil, fmt.Errorf("encrypting secrets for %s: %w", app, err)
+			}
+			*f.out = v
+		}
+		for name, value := range s.Custom {
+			v, err := encryptValue(key, value)
+			if err != nil {
+				return nil, fmt.Errorf("encrypting secrets for %s: %w", app, err)
+			}
+			if out.Custom == nil {
+				out.Custom = make(map[string]string, len(s.Custom))
+			}
+			out.Custom[name] = v
+		}
+		sealed[app] = out
+	}
+	return sealed, nil
+}
+
+// openAppSecrets decrypts app secrets in place, reporting whether any were
+// still stored as plaintext and need re-saving.
+func openAppSecrets(key *[32]byte, appSecrets map[string]AppSecrets) (plaintext bool, err error) {
+	for app, s := range appSecrets {
+		for _, field := range []*string{&s.AdminPassword, &s.OAuthClientSecret, &s.DatabasePassword} {
+			if *field != "" && !isEncrypted(*field) {
+				plaintext = true
+			}
+			if *field, err = decryptValue(key, *field); err != nil {
+				return false, fmt.Errorf("decrypting secrets for %s: %w", app, err)
+			}
+		}
+		for name, value := range s.Custom {
+			if value != "" && !isEncrypted(value) {
+				plaintext = true
+			}
+			if s.Custom[name], err = decryptValue(key, value); err != nil {
+				return false, fmt.Errorf("decrypting secrets for %s: %w", app, err)
+			}
+		}
+		appSecrets[app] = s
+	}
+	return plaintext, nil
+}
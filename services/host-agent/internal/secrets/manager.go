@@ -4,18 +4,32 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
+	"time"
 )
 
 // Manager handles generation and persistence of deployment secrets.
-// Secrets are generated on first run and stored in a JSON file.
+// Secrets are generated on first run and stored as a JSON document in a
+// Backend (secrets.json by default). Per-app secrets are encrypted in the
+// document with a key derived from SSOHostSecret and decrypted transparently
+// on load. Env files for systemd services are always written beside path.
 type Manager struct {
 	path    string
+	backend Backend
 	secrets *Secrets
 	mu      sync.RWMutex
+
+	// Secret accesses go to recordAccess, or are buffered until it's set
+	accessMu        sync.Mutex
+	recordAccess    AccessRecorder
+	pendingAccesses []Access
 }
 
 // Secrets contains all generated secrets for the deployment.
@@ -39,6 +53,36 @@ type Secrets struct {
 
 	// Per-app secrets (generated during install)
 	AppSecrets map[string]AppSecrets `json:"appSecrets,omitempty"`
+
+	// Version of the key used to derive per-app secrets from SSOHostSecret.
+	// Zero means the original derivation, from before rotation existed.
+	KeyVersion int `json:"keyVersion,omitempty"`
+
+	// Rotation in progress, if any; cleared once every app has moved over
+	Rotation *Rotation `json:"rotation,omitempty"`
+}
+
+// Rotation tracks a per-app secret rotation so an interrupted one can resume.
+type Rotation struct {
+	// Key version the apps are being moved to
+	Version int `json:"version"`
+
+	// Apps being rotated, and those already moved to the new version
+	Apps      []string `json:"apps"`
+	Completed []string `json:"completed"`
+
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// Pending returns the apps not yet moved to the new key version.
+func (r *Rotation) Pending() []string {
+	var pending []string
+	for _, app := range r.Apps {
+		if !slices.Contains(r.Completed, app) {
+			pending = append(pending, app)
+		}
+	}
+	return pending
 }
 
 // AppSecrets contains secrets specific to an individual app.
@@ -51,28 +95,49 @@ type AppSecrets struct {
 
 	// App-specific database password (if different from shared postgres)
 	DatabasePassword string `json:"databasePassword,omitempty"`
+
+	// Secrets stored through the API, such as API keys and SMTP passwords
+	Custom map[string]string `json:"custom,omitempty"`
 }
 
 // NewManager creates a new secrets manager that uses the given file path.
 func NewManager(path string) *Manager {
+	return NewManagerWithBackend(path, NewFileBackend(path))
+}
+
+// NewManagerWithBackend creates a secrets manager that stores secrets in
+// backend and writes env files beside path.
+func NewManagerWithBackend(path string, backend Backend) *Manager {
 	return &Manager{
-		path: path,
+		path:    path,
+		backend: backend,
 	}
 }
 
+// NewManagerFromEnv creates a secrets manager using the backend selected by
+// BLOUD_SECRETS_BACKEND (see BackendFromEnv). An invalid backend
+// configuration is reported by Load.
+func NewManagerFromEnv(path string) *Manager {
+	backend, err := BackendFromEnv(path)
+	if err != nil {
+		backend = failingBackend{err: fmt.Errorf("invalid secrets backend: %w", err)}
+	}
+	return NewManagerWithBackend(path, backend)
+}
+
 // Load reads secrets from file or generates new ones if the file doesn't exist.
 func (m *Manager) Load() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Try to read existing secrets
-	data, err := os.ReadFile(m.path)
+	data, err := m.backend.Read()
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			// Generate new secrets
 			return m.generateAndSave()
 		}
-		return fmt.Errorf("reading secrets file: %w", err)
+		return fmt.Errorf("reading secrets from %s backend: %w", m.backend.Name(), err)
 	}
 
 	// Parse existing secrets
@@ -117,6 +182,19 @@ func (m *Manager) Load() error {
 		updated = true
 	}
 
+	// Decrypt app secrets; any still in plaintext are encrypted on save
+	key, err := deriveKey(secrets.SSOHostSecret)
+	if err != nil {
+		return fmt.Errorf("deriving app secrets key: %w", err)
+	}
+	plaintext, err := openAppSecrets(key, secrets.AppSecrets)
+	if err != nil {
+		return err
+	}
+	if plaintext {
+		updated = true
+	}
+
 	m.secrets = &secrets
 
 	if updated {
@@ -129,6 +207,7 @@ func (m *Manager) Load() error {
 // generateAndSave generates all secrets and saves to file.
 // Secrets are cryptographically random and unique per deployment.
 func (m *Manager) generateAndSave() error {
+	m.logAccess(AccessWrite, "", "*")
 	m.secrets = &Secrets{
 		PostgresPassword:           generateSecret(32),
 		AuthentikSecretKey:         generateSecret(64),
@@ -151,20 +230,31 @@ func (m *Manager) saveLocked() error {
 		return fmt.Errorf("creating secrets directory: %w", err)
 	}
 
-	data, err := json.MarshalIndent(m.secrets, "", "  ")
+	key, err := deriveKey(m.secrets.SSOHostSecret)
+	if err != nil {
+		return fmt.Errorf("deriving app secrets key: %w", err)
+	}
+	stored := *m.secrets
+	if stored.AppSecrets, err = sealAppSecrets(key, m.secrets.AppSecrets); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(&stored, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshaling secrets: %w", err)
 	}
 
-	// Write JSON with restrictive permissions (owner read/write only)
-	if err := os.WriteFile(m.path, data, 0600); err != nil {
-		return fmt.Errorf("writing secrets file: %w", err)
+	if err := m.backend.Write(data); err != nil {
+		return fmt.Errorf("writing secrets to %s backend: %w", m.backend.Name(), err)
 	}
 
-	// Write environment files for systemd services
+	// Write environment files and credentials for systemd services
 	if err := m.writeEnvFiles(dir); err != nil {
 		return fmt.Errorf("writing env files: %w", err)
 	}
+	if err := m.writeCredentialFiles(dir); err != nil {
+		return fmt.Errorf("writing credential files: %w", err)
+	}
 
 	return nil
 }
@@ -234,11 +324,8 @@ func (m *Manager) writeAppEnvFile(dir, appName string, appSecrets AppSecrets) er
 	if appSecrets.AdminPassword != "" {
 		env += fmt.Sprintf("ADMIN_PASSWORD=%s\n", appSecrets.AdminPassword)
 	}
-	if appSecrets.OAuthClientSecret != "" {
-		// Use app-specific env var names for OAuth secrets
-		oauthEnvName := getOAuthEnvVarName(appName)
-		env += fmt.Sprintf("%s=%s\n", oauthEnvName, appSecrets.OAuthClientSecret)
-	}
+	// The OAuth client secret is loaded as a systemd credential instead,
+	// keeping it out of env files and podman inspect
 	if appSecrets.DatabasePassword != "" {
 		env += fmt.Sprintf("DATABASE_PASSWORD=%s\n", appSecrets.DatabasePassword)
 	}
@@ -269,23 +356,13 @@ func getDatabaseURL(appName, password string) string {
 	return fmt.Sprintf("postgres://apps:%s@localhost:5432/%s?sslmode=disable", password, appName)
 }
 
-// getOAuthEnvVarName returns the app-specific environment variable name for OAuth client secret
-func getOAuthEnvVarName(appName string) string {
-	// Map app names to their expected OAuth env var names
-	appOAuthEnvVars := map[string]string{
-		"miniflux":      "OAUTH2_CLIENT_SECRET",
-		"actual-budget": "ACTUAL_OPENID_CLIENT_SECRET",
-		"affine":        "OAUTH_OIDC_CLIENT_SECRET",
-	}
-	if envName, ok := appOAuthEnvVars[appName]; ok {
-		return envName
-	}
-	// Default to generic name
-	return "OAUTH_CLIENT_SECRET"
-}
-
 // Get returns a top-level secret by name.
 func (m *Manager) Get(name string) string {
+	m.logAccess(AccessRead, "", name)
+	return m.get(name)
+}
+
+func (m *Manager) get(name string) string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -343,13 +420,168 @@ func (m *Manager) GetLDAPBindPassword() string {
 	return m.Get("ldapBindPassword")
 }
 
+// SetLDAPBindPassword replaces the LDAP bind password and saves to file.
+// Used by bind token rotation once the new key is staged in Authentik.
+func (m *Manager) SetLDAPBindPassword(value string) error {
+	m.logAccess(AccessWrite, "", "ldapBindPassword")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.secrets == nil {
+		return fmt.Errorf("secrets not loaded")
+	}
+
+	m.secrets.LDAPBindPassword = value
+
+	return m.saveLocked()
+}
+
 // GetSSOHostSecret returns the master secret for OAuth client secret derivation.
 func (m *Manager) GetSSOHostSecret() string {
 	return m.Get("ssoHostSecret")
 }
 
+// ReplaceHostSecret switches the host secret and sets the OAuth client
+// secrets derived from it, in a single save. Per-app secrets are re-sealed
+// with the new host secret's key. Nothing changes if the save fails.
+func (m *Manager) ReplaceHostSecret(hostSecret string, clientSecrets map[string]string) error {
+	m.logAccess(AccessWrite, "", "ssoHostSecret")
+	for appName := range clientSecrets {
+		m.logAccess(AccessWrite, appName, "oauthClientSecret")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.secrets == nil {
+		return fmt.Errorf("secrets not loaded")
+	}
+	if hostSecret == "" {
+		return fmt.Errorf("host secret must not be empty")
+	}
+
+	previous := *m.secrets
+	updated := *m.secrets
+	updated.SSOHostSecret = hostSecret
+	updated.AppSecrets = maps.Clone(m.secrets.AppSecrets)
+	if updated.AppSecrets == nil {
+		updated.AppSecrets = make(map[string]AppSecrets)
+	}
+	for appName, secret := range clientSecrets {
+		appSecrets := updated.AppSecrets[appName]
+		appSecrets.OAuthClientSecret = secret
+		updated.AppSecrets[appName] = appSecrets
+	}
+
+	m.secrets = &updated
+	if err := m.saveLocked(); err != nil {
+		m.secrets = &previous
+		return err
+	}
+	return nil
+}
+
+// KeyVersion returns the key version per-app secrets are derived with. While
+// a rotation is in progress this is the version being rotated to, so any
+// secret derived mid-rotation is already the new one.
+func (m *Manager) KeyVersion() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.secrets == nil {
+		return 0
+	}
+	if m.secrets.Rotation != nil {
+		return m.secrets.Rotation.Version
+	}
+	return m.secrets.KeyVersion
+}
+
+// Rotation returns a copy of the rotation in progress, or nil.
+func (m *Manager) Rotation() *Rotation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.secrets == nil || m.secrets.Rotation == nil {
+		return nil
+	}
+	return copyRotation(m.secrets.Rotation)
+}
+
+// BeginRotation starts rotating the given apps to the next key version and
+// saves the rotation to file. If a rotation is already in progress it is
+// returned unchanged, so the caller resumes it; resumed reports which.
+func (m *Manager) BeginRotation(apps []string) (rotation *Rotation, resumed bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.secrets == nil {
+		return nil, false, fmt.Errorf("secrets not loaded")
+	}
+	if m.secrets.Rotation != nil {
+		return copyRotation(m.secrets.Rotation), true, nil
+	}
+
+	m.secrets.Rotation = &Rotation{
+		Version:   max(m.secrets.KeyVersion, 1) + 1,
+		Apps:      slices.Clone(apps),
+		Completed: []string{},
+		StartedAt: time.Now().UTC(),
+	}
+	if err := m.saveLocked(); err != nil {
+		m.secrets.Rotation = nil
+		return nil, false, err
+	}
+	return copyRotation(m.secrets.Rotation), false, nil
+}
+
+// CompleteRotationApp records that an app has moved to the new key version.
+func (m *Manager) CompleteRotationApp(appName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.secrets == nil || m.secrets.Rotation == nil {
+		return fmt.Errorf("no rotation in progress")
+	}
+	if slices.Contains(m.secrets.Rotation.Completed, appName) {
+		return nil
+	}
+	m.secrets.Rotation.Completed = append(m.secrets.Rotation.Completed, appName)
+
+	return m.saveLocked()
+}
+
+// FinishRotation makes the rotation's key version current and clears the rotation.
+func (m *Manager) FinishRotation() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.secrets == nil || m.secrets.Rotation == nil {
+		return fmt.Errorf("no rotation in progress")
+	}
+	rotation, version := m.secrets.Rotation, m.secrets.KeyVersion
+	m.secrets.KeyVersion = rotation.Version
+	m.secrets.Rotation = nil
+	if err := m.saveLocked(); err != nil {
+		m.secrets.Rotation, m.secrets.KeyVersion = rotation, version
+		return err
+	}
+	return nil
+}
+
+func copyRotation(r *Rotation) *Rotation {
+	c := *r
+	c.Apps = slices.Clone(r.Apps)
+	c.Completed = slices.Clone(r.Completed)
+	return &c
+}
+
 // GetAppSecret returns a specific secret for an app.
 func (m *Manager) GetAppSecret(appName, key string) string {
+	m.logAccess(AccessRead, appName, key)
+	return m.appSecret(appName, key)
+}
+
+func (m *Manager) appSecret(appName, key string) string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -370,12 +602,43 @@ func (m *Manager) GetAppSecret(appName, key string) string {
 	case "databasePassword":
 		return appSecrets.DatabasePassword
 	default:
-		return ""
+		return appSecrets.Custom[key]
 	}
 }
 
-// SetAppSecret sets a specific secret for an app and saves to file.
+// AppSecretKeys returns the names of the secrets set for an app, e.g. adminPassword.
+func (m *Manager) AppSecretKeys(appName string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.secrets == nil || m.secrets.AppSecrets == nil {
+		return nil
+	}
+
+	appSecrets, ok := m.secrets.AppSecrets[appName]
+	if !ok {
+		return nil
+	}
+
+	var keys []string
+	if appSecrets.AdminPassword != "" {
+		keys = append(keys, "adminPassword")
+	}
+	if appSecrets.OAuthClientSecret != "" {
+		keys = append(keys, "oauthClientSecret")
+	}
+	if appSecrets.DatabasePassword != "" {
+		keys = append(keys, "databasePassword")
+	}
+	custom := slices.Sorted(maps.Keys(appSecrets.Custom))
+	return append(keys, custom...)
+}
+
+// SetAppSecret sets a specific secret for an app and saves to file. Keys
+// other than the built-in ones are stored as custom secrets and must pass
+// ValidateAppSecretKey.
 func (m *Manager) SetAppSecret(appName, key, value string) error {
+	m.logAccess(AccessWrite, appName, key)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -397,7 +660,15 @@ func (m *Manager) SetAppSecret(appName, key, value string) error {
 	case "databasePassword":
 		appSecrets.DatabasePassword = value
 	default:
-		return fmt.Errorf("unknown secret key: %s", key)
+		if err := ValidateAppSecretKey(key); err != nil {
+			return err
+		}
+		custom := maps.Clone(appSecrets.Custom)
+		if custom == nil {
+			custom = make(map[string]string)
+		}
+		custom[key] = value
+		appSecrets.Custom = custom
 	}
 
 	m.secrets.AppSecrets[appName] = appSecrets
@@ -407,6 +678,7 @@ func (m *Manager) SetAppSecret(appName, key, value string) error {
 
 // DeleteAppSecrets removes all secrets for an app and saves to file.
 func (m *Manager) DeleteAppSecrets(appName string) error {
+	m.logAccess(AccessDelete, appName, "*")
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -421,6 +693,7 @@ func (m *Manager) DeleteAppSecrets(appName string) error {
 
 // GetAllSecrets returns a copy of all secrets (for NixOS generation).
 func (m *Manager) GetAllSecrets() *Secrets {
+	m.logAccess(AccessRead, "", "*")
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -433,21 +706,43 @@ func (m *Manager) GetAllSecrets() *Secrets {
 	if m.secrets.AppSecrets != nil {
 		copy.AppSecrets = make(map[string]AppSecrets, len(m.secrets.AppSecrets))
 		for k, v := range m.secrets.AppSecrets {
+			v.Custom = maps.Clone(v.Custom)
 			copy.AppSecrets[k] = v
 		}
 	}
+	if m.secrets.Rotation != nil {
+		copy.Rotation = copyRotation(m.secrets.Rotation)
+	}
 
 	return &copy
 }
 
-// Path returns the file path where secrets are stored.
+// Path returns the file path where secrets are stored with the file
+// backend; env files are written beside it with any backend.
 func (m *Manager) Path() string {
 	return m.path
 }
 
-// WriteEnvFiles regenerates all env files from the current secrets.
-// This ensures env files are always in sync with secrets.json.
+// BackendName returns the name of the backend secrets are stored in.
+func (m *Manager) BackendName() string {
+	return m.backend.Name()
+}
+
+// Exists reports whether the backend already holds secrets.
+func (m *Manager) Exists() (bool, error) {
+	if _, err := m.backend.Read(); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// WriteEnvFiles regenerates all env files and credential files from the
+// current secrets. This ensures they are always in sync with secrets.json.
 func (m *Manager) WriteEnvFiles() error {
+	m.logAccess(AccessRead, "", "*")
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -456,7 +751,10 @@ func (m *Manager) WriteEnvFiles() error {
 	}
 
 	dir := filepath.Dir(m.path)
-	return m.writeEnvFiles(dir)
+	if err := m.writeEnvFiles(dir); err != nil {
+		return err
+	}
+	return m.writeCredentialFiles(dir)
 }
 
 // AppendEnvVars appends additional environment variables to an app's env file.
@@ -496,6 +794,12 @@ func generateSecret(length int) string {
 	return base64.URLEncoding.EncodeToString(bytes)[:length]
 }
 
+// GenerateSecret returns a new random secret of the given length, in the same
+// format as the secrets generated on first boot.
+func GenerateSecret(length int) string {
+	return generateSecret(length)
+}
+
 // GenerateAppAdminPassword generates a new admin password for an app if one doesn't exist.
 func (m *Manager) GenerateAppAdminPassword(appName string) (string, error) {
 	m.mu.Lock()
@@ -511,8 +815,10 @@ func (m *Manager) GenerateAppAdminPassword(appName string) (string, error) {
 
 	appSecrets := m.secrets.AppSecrets[appName]
 	if appSecrets.AdminPassword != "" {
+		m.logAccess(AccessRead, appName, "adminPassword")
 		return appSecrets.AdminPassword, nil
 	}
+	m.logAccess(AccessWrite, appName, "adminPassword")
 
 	appSecrets.AdminPassword = generateSecret(24)
 	m.secrets.AppSecrets[appName] = appSecrets
@@ -1,8 +1,11 @@
 package secrets
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -161,6 +164,29 @@ func TestManager_AppSecrets(t *testing.T) {
 	}
 }
 
+func TestManager_AppSecretKeys(t *testing.T) {
+	m := NewManager(filepath.Join(t.TempDir(), "secrets.json"))
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	if keys := m.AppSecretKeys("miniflux"); len(keys) != 0 {
+		t.Errorf("expected no keys, got %v", keys)
+	}
+
+	if err := m.SetAppSecret("miniflux", "oauthClientSecret", "s1"); err != nil {
+		t.Fatalf("failed to set app secret: %v", err)
+	}
+	if err := m.SetAppSecret("miniflux", "adminPassword", "s2"); err != nil {
+		t.Fatalf("failed to set app secret: %v", err)
+	}
+
+	keys := m.AppSecretKeys("miniflux")
+	if len(keys) != 2 || keys[0] != "adminPassword" || keys[1] != "oauthClientSecret" {
+		t.Errorf("expected [adminPassword oauthClientSecret], got %v", keys)
+	}
+}
+
 func TestManager_GenerateAppAdminPassword(t *testing.T) {
 	tmpDir := t.TempDir()
 	secretsPath := filepath.Join(tmpDir, "secrets.json")
@@ -320,3 +346,252 @@ func TestManager_FilePermissions(t *testing.T) {
 		t.Errorf("expected permissions 0600, got %o", perm)
 	}
 }
+
+func TestManager_SetLDAPBindPassword(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretsPath := filepath.Join(tmpDir, "secrets.json")
+
+	m := NewManager(secretsPath)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	if err := m.SetLDAPBindPassword("rotated-bind-password"); err != nil {
+		t.Fatalf("failed to set bind password: %v", err)
+	}
+	if got := m.GetLDAPBindPassword(); got != "rotated-bind-password" {
+		t.Errorf("expected rotated password, got '%s'", got)
+	}
+
+	// Verify persisted
+	m2 := NewManager(secretsPath)
+	if err := m2.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if got := m2.GetLDAPBindPassword(); got != "rotated-bind-password" {
+		t.Errorf("bind password not persisted, got '%s'", got)
+	}
+}
+
+func TestManager_Rotation(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretsPath := filepath.Join(tmpDir, "secrets.json")
+
+	m := NewManager(secretsPath)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	rotation, resumed, err := m.BeginRotation([]string{"miniflux", "affine"})
+	if err != nil {
+		t.Fatalf("failed to begin rotation: %v", err)
+	}
+	if resumed || rotation.Version != 2 {
+		t.Errorf("expected a new rotation to version 2, got version %d (resumed %v)", rotation.Version, resumed)
+	}
+	if got := m.KeyVersion(); got != 2 {
+		t.Errorf("expected secrets to derive with version 2 during rotation, got %d", got)
+	}
+	if err := m.CompleteRotationApp("miniflux"); err != nil {
+		t.Fatalf("failed to complete app: %v", err)
+	}
+
+	// An interrupted rotation survives a restart
+	m2 := NewManager(secretsPath)
+	if err := m2.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	rotation, resumed, err = m2.BeginRotation([]string{"other"})
+	if err != nil {
+		t.Fatalf("failed to resume rotation: %v", err)
+	}
+	if !resumed {
+		t.Error("expected the saved rotation to resume")
+	}
+	if pending := rotation.Pending(); len(pending) != 1 || pending[0] != "affine" {
+		t.Errorf("expected affine pending, got %v", pending)
+	}
+
+	if err := m2.FinishRotation(); err != nil {
+		t.Fatalf("failed to finish rotation: %v", err)
+	}
+	if m2.Rotation() != nil {
+		t.Error("expected rotation to be cleared")
+	}
+
+	m3 := NewManager(secretsPath)
+	if err := m3.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if got := m3.KeyVersion(); got != 2 {
+		t.Errorf("expected key version 2 to be persisted, got %d", got)
+	}
+}
+
+func TestManager_EncryptsAppSecretsAtRest(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretsPath := filepath.Join(tmpDir, "secrets.json")
+
+	m := NewManager(secretsPath)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if err := m.SetAppSecret("miniflux", "oauthClientSecret", "client-secret-value"); err != nil {
+		t.Fatalf("failed to set app secret: %v", err)
+	}
+
+	data, err := os.ReadFile(secretsPath)
+	if err != nil {
+		t.Fatalf("failed to read secrets file: %v", err)
+	}
+	if strings.Contains(string(data), "client-secret-value") {
+		t.Error("app secret stored in plaintext")
+	}
+	if !strings.Contains(string(data), encryptedPrefix) {
+		t.Error("app secret not marked as encrypted")
+	}
+
+	// Decrypted transparently on load
+	m2 := NewManager(secretsPath)
+	if err := m2.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if got := m2.GetAppSecret("miniflux", "oauthClientSecret"); got != "client-secret-value" {
+		t.Errorf("expected decrypted secret, got '%s'", got)
+	}
+}
+
+func TestManager_MigratesPlaintextAppSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretsPath := filepath.Join(tmpDir, "secrets.json")
+
+	plaintext := `{
+		"ssoHostSecret": "host-secret",
+		"appSecrets": {"miniflux": {"adminPassword": "plain-admin-password"}}
+	}`
+	if err := os.WriteFile(secretsPath, []byte(plaintext), 0600); err != nil {
+		t.Fatalf("failed to write secrets: %v", err)
+	}
+
+	m := NewManager(secretsPath)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if got := m.GetAppSecret("miniflux", "adminPassword"); got != "plain-admin-password" {
+		t.Errorf("expected existing password, got '%s'", got)
+	}
+
+	data, err := os.ReadFile(secretsPath)
+	if err != nil {
+		t.Fatalf("failed to read secrets file: %v", err)
+	}
+	if strings.Contains(string(data), "plain-admin-password") {
+		t.Error("plaintext app secret was not re-encrypted on load")
+	}
+}
+
+func TestManager_WrongHostSecretFailsToLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretsPath := filepath.Join(tmpDir, "secrets.json")
+
+	m := NewManager(secretsPath)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if err := m.SetAppSecret("miniflux", "adminPassword", "admin-password"); err != nil {
+		t.Fatalf("failed to set app secret: %v", err)
+	}
+
+	// Replace the host secret, as a restore onto a different host might
+	var stored map[string]any
+	data, _ := os.ReadFile(secretsPath)
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("failed to parse secrets: %v", err)
+	}
+	stored["ssoHostSecret"] = "a-different-host-secret"
+	data, _ = json.Marshal(stored)
+	if err := os.WriteFile(secretsPath, data, 0600); err != nil {
+		t.Fatalf("failed to write secrets: %v", err)
+	}
+
+	if err := NewManager(secretsPath).Load(); err == nil {
+		t.Error("expected load to fail with the wrong host secret")
+	}
+}
+
+func TestManager_CustomAppSecrets(t *testing.T) {
+	secretsPath := filepath.Join(t.TempDir(), "secrets.json")
+
+	m := NewManager(secretsPath)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if err := m.SetAppSecret("radarr", "apiKey", "radarr-api-key"); err != nil {
+		t.Fatalf("failed to set custom secret: %v", err)
+	}
+	if err := m.SetAppSecret("radarr", "adminPassword", "admin"); err != nil {
+		t.Fatalf("failed to set app secret: %v", err)
+	}
+	if err := m.SetAppSecret("radarr", "bad key", "x"); err == nil {
+		t.Error("expected an invalid key to be rejected")
+	}
+
+	keys := m.AppSecretKeys("radarr")
+	if len(keys) != 2 || keys[0] != "adminPassword" || keys[1] != "apiKey" {
+		t.Errorf("expected [adminPassword apiKey], got %v", keys)
+	}
+
+	data, err := os.ReadFile(secretsPath)
+	if err != nil {
+		t.Fatalf("failed to read secrets file: %v", err)
+	}
+	if strings.Contains(string(data), "radarr-api-key") {
+		t.Error("custom secret stored in plaintext")
+	}
+
+	m2 := NewManager(secretsPath)
+	if err := m2.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if got := m2.GetAppSecret("radarr", "apiKey"); got != "radarr-api-key" {
+		t.Errorf("expected custom secret after reload, got '%s'", got)
+	}
+
+	fp := m2.AppSecretFingerprint("radarr", "apiKey")
+	if fp == "" || fp != m.AppSecretFingerprint("radarr", "apiKey") || strings.Contains(fp, "radarr-api-key") {
+		t.Errorf("expected a stable fingerprint, got %q", fp)
+	}
+	if m2.AppSecretFingerprint("radarr", "missing") != "" {
+		t.Error("expected no fingerprint for an unset secret")
+	}
+
+	if err := m2.DeleteAppSecret("radarr", "apiKey"); err != nil {
+		t.Fatalf("failed to delete custom secret: %v", err)
+	}
+	if got := m2.GetAppSecret("radarr", "apiKey"); got != "" {
+		t.Errorf("expected deleted secret to be gone, got '%s'", got)
+	}
+	if got := m2.GetAppSecret("radarr", "adminPassword"); got != "admin" {
+		t.Errorf("expected other secrets to remain, got '%s'", got)
+	}
+}
+
+func TestManager_Values(t *testing.T) {
+	m := NewManager(filepath.Join(t.TempDir(), "secrets.json"))
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if err := m.SetAppSecret("radarr", "apiKey", "radarr-api-key"); err != nil {
+		t.Fatalf("failed to set custom secret: %v", err)
+	}
+
+	values := m.Values()
+	for _, want := range []string{m.GetPostgresPassword(), m.GetSSOHostSecret(), "radarr-api-key"} {
+		if !slices.Contains(values, want) {
+			t.Errorf("expected %q in values", want)
+		}
+	}
+	if slices.Contains(values, "") {
+		t.Error("expected unset secrets to be left out")
+	}
+}
@@ -0,0 +1,228 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// sealedFilePrefix marks a secrets file sealed with the machine key.
+// Anything else is read as a plaintext file from before sealing.
+const sealedFilePrefix = "bloud-sealed:v1:"
+
+// machineKeyCredential is the name a TPM-sealed machine key is encrypted under
+const machineKeyCredential = "bloud-secrets-key"
+
+// KeySource provides the machine key that seals the secrets file.
+type KeySource interface {
+	// Describe says where the key comes from, for logs and errors
+	Describe() string
+
+	// Key returns the 32-byte machine key
+	Key() (*[32]byte, error)
+}
+
+// KeyFile reads the machine key from a separately provisioned file.
+type KeyFile struct {
+	path string
+}
+
+// NewKeyFile creates a key source for the key file at path.
+func NewKeyFile(path string) *KeyFile {
+	return &KeyFile{path: path}
+}
+
+// Describe implements KeySource.
+func (k *KeyFile) Describe() string { return "key file " + k.path }
+
+// Key implements KeySource.
+func (k *KeyFile) Key() (*[32]byte, error) {
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading machine key: %w", err)
+	}
+	return ParseMachineKey(data)
+}
+
+// TPMCredential unseals the machine key from a systemd credential encrypted
+// to the TPM (systemd-creds encrypt --with-key=tpm2).
+type TPMCredential struct {
+	path string
+
+	// run executes systemd-creds and returns its stdout; replaced in tests
+	run func(args ...string) ([]byte, error)
+}
+
+// NewTPMCredential creates a key source for the credential at path.
+func NewTPMCredential(path string) *TPMCredential {
+	return &TPMCredential{path: path, run: runSystemdCreds}
+}
+
+// Describe implements KeySource.
+func (k *TPMCredential) Describe() string { return "TPM credential " + k.path }
+
+// Key implements KeySource.
+func (k *TPMCredential) Key() (*[32]byte, error) {
+	data, err := k.run("decrypt", "--name="+machineKeyCredential, k.path, "-")
+	if err != nil {
+		return nil, fmt.Errorf("unsealing machine key: %w", err)
+	}
+	return ParseMachineKey(data)
+}
+
+func runSystemdCreds(args ...string) ([]byte, error) {
+	cmd := exec.Command("systemd-creds", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemd-creds: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// GenerateMachineKey returns a new random machine key, base64 encoded.
+func GenerateMachineKey() (string, error) {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return "", fmt.Errorf("generating machine key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key[:]), nil
+}
+
+// ParseMachineKey accepts a 32-byte key as base64, hex or raw bytes.
+func ParseMachineKey(data []byte) (*[32]byte, error) {
+	var key [32]byte
+	text := strings.TrimSpace(string(data))
+	if decoded, err := base64.StdEncoding.DecodeString(text); err == nil && len(decoded) == len(key) {
+		copy(key[:], decoded)
+		return &key, nil
+	}
+	if decoded, err := hex.DecodeString(text); err == nil && len(decoded) == len(key) {
+		copy(key[:], decoded)
+		return &key, nil
+	}
+	if len(data) == len(key) {
+		copy(key[:], data)
+		return &key, nil
+	}
+	return nil, errors.New("machine key must be 32 bytes, base64 or hex encoded")
+}
+
+// SealedFileBackend stores the secrets document in a file encrypted with a
+// machine key, so the file alone is useless without the key. A plaintext
+// file from before sealing is encrypted the first time it is read.
+type SealedFileBackend struct {
+	path string
+	keys KeySource
+
+	mu  sync.Mutex
+	key *[32]byte // cached after the first successful unseal
+}
+
+// NewSealedFileBackend creates a backend for the sealed file at path.
+func NewSealedFileBackend(path string, keys KeySource) *SealedFileBackend {
+	return &SealedFileBackend{path: path, keys: keys}
+}
+
+// Name implements Backend.
+func (b *SealedFileBackend) Name() string { return "sealed-file" }
+
+// Read implements Backend.
+func (b *SealedFileBackend) Read() ([]byte, error) {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(sealedFilePrefix)) {
+		// Seal the plaintext file left by an earlier release or a recovery
+		if err := b.Write(data); err != nil {
+			return nil, fmt.Errorf("sealing plaintext secrets file: %w", err)
+		}
+		return data, nil
+	}
+	return b.Unseal(data)
+}
+
+// Unseal decrypts the contents of a sealed secrets file.
+func (b *SealedFileBackend) Unseal(data []byte) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(string(data), sealedFilePrefix)))
+	if err != nil {
+		return nil, fmt.Errorf("decoding sealed secrets file: %w", err)
+	}
+	key, err := b.machineKey()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(key, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("unsealing secrets file with %s: %w", b.keys.Describe(), err)
+	}
+	return plaintext, nil
+}
+
+// Write implements Backend. The file is replaced atomically.
+func (b *SealedFileBackend) Write(data []byte) error {
+	key, err := b.machineKey()
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(key, data)
+	if err != nil {
+		return err
+	}
+	contents := sealedFilePrefix + base64.StdEncoding.EncodeToString(sealed) + "\n"
+
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		return fmt.Errorf("creating secrets directory: %w", err)
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(contents), 0600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replacing %s: %w", b.path, err)
+	}
+	return nil
+}
+
+func (b *SealedFileBackend) machineKey() (*[32]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.key == nil {
+		key, err := b.keys.Key()
+		if err != nil {
+			return nil, fmt.Errorf("loading machine key from %s: %w", b.keys.Describe(), err)
+		}
+		b.key = key
+	}
+	return b.key, nil
+}
+
+// keySourceFromEnv returns the machine key source configured with
+// BLOUD_SECRETS_KEY_FILE or BLOUD_SECRETS_KEY_CREDENTIAL, or nil if the
+// secrets file isn't sealed.
+func keySourceFromEnv() (KeySource, error) {
+	keyFile := os.Getenv("BLOUD_SECRETS_KEY_FILE")
+	credential := os.Getenv("BLOUD_SECRETS_KEY_CREDENTIAL")
+	switch {
+	case keyFile != "" && credential != "":
+		return nil, errors.New("set only one of BLOUD_SECRETS_KEY_FILE and BLOUD_SECRETS_KEY_CREDENTIAL")
+	case keyFile != "":
+		return NewKeyFile(keyFile), nil
+	case credential != "":
+		return NewTPMCredential(credential), nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,115 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeMachineKey(t *testing.T) string {
+	t.Helper()
+	key, err := GenerateMachineKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "machine.key")
+	if err := os.WriteFile(path, []byte(key+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSealedFileBackend_RoundTrip(t *testing.T) {
+	secretsPath := filepath.Join(t.TempDir(), "secrets.json")
+	backend := NewSealedFileBackend(secretsPath, NewKeyFile(writeMachineKey(t)))
+
+	m := NewManagerWithBackend(secretsPath, backend)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to generate secrets: %v", err)
+	}
+
+	data, err := os.ReadFile(secretsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(sealedFilePrefix)) {
+		t.Fatal("expected the secrets file to be sealed")
+	}
+	if bytes.Contains(data, []byte(m.GetPostgresPassword())) {
+		t.Error("sealed file contains a plaintext secret")
+	}
+
+	m2 := NewManagerWithBackend(secretsPath, backend)
+	if err := m2.Load(); err != nil {
+		t.Fatalf("failed to load sealed secrets: %v", err)
+	}
+	if m2.GetPostgresPassword() != m.GetPostgresPassword() {
+		t.Error("secrets not read back from the sealed file")
+	}
+}
+
+func TestSealedFileBackend_SealsPlaintextFile(t *testing.T) {
+	secretsPath := filepath.Join(t.TempDir(), "secrets.json")
+
+	// A plaintext file from before sealing (or restored during recovery)
+	plain := NewManager(secretsPath)
+	if err := plain.Load(); err != nil {
+		t.Fatalf("failed to generate secrets: %v", err)
+	}
+
+	m := NewManagerWithBackend(secretsPath, NewSealedFileBackend(secretsPath, NewKeyFile(writeMachineKey(t))))
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load plaintext secrets: %v", err)
+	}
+	if m.GetSSOHostSecret() != plain.GetSSOHostSecret() {
+		t.Error("secrets changed when sealing")
+	}
+
+	data, _ := os.ReadFile(secretsPath)
+	if !bytes.HasPrefix(data, []byte(sealedFilePrefix)) {
+		t.Error("expected the plaintext file to be sealed on first read")
+	}
+}
+
+func TestSealedFileBackend_WrongKey(t *testing.T) {
+	secretsPath := filepath.Join(t.TempDir(), "secrets.json")
+	if err := NewManagerWithBackend(secretsPath, NewSealedFileBackend(secretsPath, NewKeyFile(writeMachineKey(t)))).Load(); err != nil {
+		t.Fatalf("failed to generate secrets: %v", err)
+	}
+
+	m := NewManagerWithBackend(secretsPath, NewSealedFileBackend(secretsPath, NewKeyFile(writeMachineKey(t))))
+	err := m.Load()
+	if err == nil || !strings.Contains(err.Error(), "unsealing secrets file") {
+		t.Errorf("expected unseal failure with the wrong key, got %v", err)
+	}
+}
+
+func TestTPMCredential_Key(t *testing.T) {
+	raw := bytes.Repeat([]byte{0x42}, 32)
+	var args []string
+	cred := NewTPMCredential("/etc/bloud/secrets-key.cred")
+	cred.run = func(a ...string) ([]byte, error) {
+		args = a
+		return []byte(hex.EncodeToString(raw)), nil
+	}
+
+	key, err := cred.Key()
+	if err != nil {
+		t.Fatalf("failed to unseal key: %v", err)
+	}
+	if !bytes.Equal(key[:], raw) {
+		t.Error("unexpected key")
+	}
+	if strings.Join(args, " ") != "decrypt --name=bloud-secrets-key /etc/bloud/secrets-key.cred -" {
+		t.Errorf("unexpected systemd-creds arguments: %v", args)
+	}
+}
+
+func TestParseMachineKey_RejectsShortKeys(t *testing.T) {
+	if _, err := ParseMachineKey([]byte("too-short")); err == nil {
+		t.Error("expected a short key to be rejected")
+	}
+}
@@ -0,0 +1,288 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Checks run by Verify
+const (
+	CheckSecretsMissing   = "secrets_missing"
+	CheckSecretMissing    = "secret_missing"
+	CheckPlaintextSecrets = "app_secrets_plaintext"
+	CheckUnreadable       = "secrets_unreadable"
+	CheckClientSecret     = "client_secret_mismatch"
+	CheckEnvFile          = "env_file_stale"
+	CheckPermissions      = "permissions"
+	CheckOwnership        = "ownership"
+)
+
+// secretFileMode is the mode secrets.json, env files and credential files are written with
+const secretFileMode = 0600
+
+// VerifyFinding is one problem found by Verify.
+type VerifyFinding struct {
+	Check    string `json:"check"`
+	App      string `json:"app,omitempty"`
+	Detail   string `json:"detail"`
+	Repaired bool   `json:"repaired"`
+	Action   string `json:"action,omitempty"` // what to do by hand when it couldn't be repaired
+	Error    string `json:"error,omitempty"`  // why a repair failed
+}
+
+// VerifyReport is the outcome of Verify. OK means nothing is left to fix.
+type VerifyReport struct {
+	OK       bool            `json:"ok"`
+	Findings []VerifyFinding `json:"findings"`
+}
+
+// VerifyOptions configures Verify.
+type VerifyOptions struct {
+	// DeriveClientSecret derives an app's OAuth client secret from the host
+	// secret for a key version; nil skips the derivation check, and "" skips
+	// an app whose secret isn't derived
+	DeriveClientSecret func(appName string, version int) string
+}
+
+// Verify checks that every expected secret exists and can be decrypted,
+// that OAuth client secrets match their derivation from the host secret,
+// that env files are current, and that secret files are private to their
+// owner. It repairs what it safely can (generating missing secrets,
+// re-deriving client secrets, rewriting env files, fixing modes) and
+// reports the rest. The manager is loaded as a side effect.
+func (m *Manager) Verify(opts VerifyOptions) (*VerifyReport, error) {
+	m.logAccess(AccessRead, "", "*")
+
+	report := &VerifyReport{Findings: []VerifyFinding{}}
+	add := func(f VerifyFinding, repair func() error) {
+		if repair != nil {
+			if err := repair(); err != nil {
+				f.Error = err.Error()
+			} else {
+				f.Repaired = true
+			}
+		}
+		report.Findings = append(report.Findings, f)
+	}
+
+	// Load generates missing secrets and encrypts plaintext ones; look at
+	// the stored document first so those repairs are reported
+	stored, missing, plaintext, err := m.inspectStored()
+	if err != nil {
+		return nil, err
+	}
+	var loadRepairs []VerifyFinding
+	if !stored {
+		loadRepairs = append(loadRepairs, VerifyFinding{
+			Check:  CheckSecretsMissing,
+			Detail: fmt.Sprintf("no secrets stored in the %s backend", m.backend.Name()),
+		})
+	}
+	for _, name := range missing {
+		loadRepairs = append(loadRepairs, VerifyFinding{
+			Check:  CheckSecretMissing,
+			Detail: fmt.Sprintf("%s is missing; a new one is generated, so services using the old value need reconfiguring", name),
+		})
+	}
+	for _, app := range plaintext {
+		loadRepairs = append(loadRepairs, VerifyFinding{
+			Check:  CheckPlaintextSecrets,
+			App:    app,
+			Detail: fmt.Sprintf("secrets for %s are stored unencrypted", app),
+		})
+	}
+
+	loadErr := m.Load()
+	for _, f := range loadRepairs {
+		add(f, func() error { return loadErr })
+	}
+	if loadErr != nil {
+		add(VerifyFinding{
+			Check:  CheckUnreadable,
+			Detail: loadErr.Error(),
+			Action: "restore the host secret or machine key the secrets were written with",
+		}, nil)
+		return report.finish(), nil
+	}
+
+	if opts.DeriveClientSecret != nil {
+		m.verifyClientSecrets(opts.DeriveClientSecret, add)
+	}
+	if err := m.verifyEnvFiles(add); err != nil {
+		return nil, err
+	}
+	m.verifyFileModes(add)
+
+	return report.finish(), nil
+}
+
+// finish sets OK once every finding is repaired
+func (r *VerifyReport) finish() *VerifyReport {
+	r.OK = true
+	for _, f := range r.Findings {
+		if !f.Repaired {
+			r.OK = false
+		}
+	}
+	return r
+}
+
+// inspectStored reports whether any secrets are stored, which top-level
+// secrets are missing from the stored document, and which apps have
+// plaintext secrets
+func (m *Manager) inspectStored() (exists bool, missing, plaintext []string, err error) {
+	data, err := m.backend.Read()
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil, nil, nil
+	}
+	if err != nil {
+		return false, nil, nil, fmt.Errorf("reading secrets from %s backend: %w", m.backend.Name(), err)
+	}
+
+	var stored Secrets
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return false, nil, nil, fmt.Errorf("parsing secrets file: %w", err)
+	}
+	for _, s := range []struct {
+		name  string
+		value string
+	}{
+		{"postgresPassword", stored.PostgresPassword},
+		{"authentikSecretKey", stored.AuthentikSecretKey},
+		{"authentikBootstrapPassword", stored.AuthentikBootstrapPassword},
+		{"authentikBootstrapToken", stored.AuthentikBootstrapToken},
+		{"ldapOutpostToken", stored.LDAPOutpostToken},
+		{"ldapBindPassword", stored.LDAPBindPassword},
+		{"ssoHostSecret", stored.SSOHostSecret},
+	} {
+		if s.value == "" {
+			missing = append(missing, s.name)
+		}
+	}
+
+	for app, s := range stored.AppSecrets {
+		values := []string{s.AdminPassword, s.OAuthClientSecret, s.DatabasePassword}
+		for _, v := range s.Custom {
+			values = append(values, v)
+		}
+		if slices.ContainsFunc(values, func(v string) bool { return v != "" && !isEncrypted(v) }) {
+			plaintext = append(plaintext, app)
+		}
+	}
+	sort.Strings(plaintext)
+	return true, missing, plaintext, nil
+}
+
+// verifyClientSecrets re-derives each stored OAuth client secret. Apps
+// already moved by an unfinished rotation use the rotation's key version.
+func (m *Manager) verifyClientSecrets(derive func(string, int) string, add func(VerifyFinding, func() error)) {
+	version := m.StoredKeyVersion()
+	rotation := m.Rotation()
+
+	for _, app := range m.AppNames() {
+		stored := m.appSecret(app, "oauthClientSecret")
+		if stored == "" {
+			continue
+		}
+		appVersion := version
+		if rotation != nil && slices.Contains(rotation.Completed, app) {
+			appVersion = rotation.Version
+		}
+		expected := derive(app, appVersion)
+		if expected == "" || stored == expected {
+			continue
+		}
+		add(VerifyFinding{
+			Check:  CheckClientSecret,
+			App:    app,
+			Detail: fmt.Sprintf("the OAuth client secret for %s doesn't match its derivation from the host secret (key version %d)", app, appVersion),
+		}, func() error {
+			return m.SetAppSecret(app, "oauthClientSecret", expected)
+		})
+	}
+}
+
+// verifyEnvFiles checks each env file starts with what WriteEnvFiles would
+// write (prestart hooks append host-dependent variables after it)
+func (m *Manager) verifyEnvFiles(add func(VerifyFinding, func() error)) error {
+	// Rendered beside the real files so secrets never leave the data dir
+	expectedDir, err := os.MkdirTemp(filepath.Dir(m.path), ".env-verify-")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(expectedDir)
+
+	m.mu.RLock()
+	err = m.writeEnvFiles(expectedDir)
+	m.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("rendering env files: %w", err)
+	}
+
+	entries, err := os.ReadDir(expectedDir)
+	if err != nil {
+		return fmt.Errorf("reading rendered env files: %w", err)
+	}
+	var stale []string
+	for _, entry := range entries {
+		expected, err := os.ReadFile(filepath.Join(expectedDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("reading rendered %s: %w", entry.Name(), err)
+		}
+		actual, err := os.ReadFile(filepath.Join(filepath.Dir(m.path), entry.Name()))
+		if err != nil || !bytes.HasPrefix(actual, expected) {
+			stale = append(stale, entry.Name())
+		}
+	}
+	if len(stale) > 0 {
+		add(VerifyFinding{
+			Check:  CheckEnvFile,
+			Detail: "env files missing or out of date: " + strings.Join(stale, ", "),
+		}, m.WriteEnvFiles)
+	}
+	return nil
+}
+
+// verifyFileModes checks secrets.json, the env files and credential files
+// are owned by this user and not readable by anyone else
+func (m *Manager) verifyFileModes(add func(VerifyFinding, func() error)) {
+	dir := filepath.Dir(m.path)
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.env"))
+	credentials, _ := filepath.Glob(filepath.Join(dir, CredentialsDir, "*", "*"))
+	paths = append(paths, credentials...)
+	if _, err := os.Stat(m.path); err == nil {
+		paths = append([]string{m.path}, paths...)
+	}
+
+	uid := os.Getuid()
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != uid {
+			add(VerifyFinding{
+				Check:  CheckOwnership,
+				Detail: fmt.Sprintf("%s is owned by uid %d, not %d", path, st.Uid, uid),
+				Action: fmt.Sprintf("chown %d %s", uid, path),
+			}, nil)
+		}
+		if mode := info.Mode().Perm(); mode != secretFileMode {
+			add(VerifyFinding{
+				Check:  CheckPermissions,
+				Detail: fmt.Sprintf("%s has mode %04o, want %04o", path, mode, secretFileMode),
+			}, func() error {
+				return os.Chmod(path, secretFileMode)
+			})
+		}
+	}
+}
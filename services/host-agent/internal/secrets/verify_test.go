@@ -0,0 +1,133 @@
+package secrets
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeDerive stands in for the SSO client secret derivation
+func fakeDerive(appName string, version int) string {
+	return "derived-" + appName
+}
+
+func findingChecks(report *VerifyReport) map[string]VerifyFinding {
+	checks := make(map[string]VerifyFinding)
+	for _, f := range report.Findings {
+		checks[f.Check] = f
+	}
+	return checks
+}
+
+func TestManager_VerifyRepairs(t *testing.T) {
+	dir := t.TempDir()
+	secretsPath := filepath.Join(dir, "secrets.json")
+
+	m := NewManager(secretsPath)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if err := m.SetAppSecret("miniflux", "oauthClientSecret", "not-derived"); err != nil {
+		t.Fatalf("failed to set app secret: %v", err)
+	}
+
+	// Drop a top-level secret and loosen the file's mode
+	var stored map[string]any
+	data, _ := os.ReadFile(secretsPath)
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("failed to parse secrets: %v", err)
+	}
+	delete(stored, "ldapBindPassword")
+	data, _ = json.Marshal(stored)
+	if err := os.WriteFile(secretsPath, data, 0600); err != nil {
+		t.Fatalf("failed to write secrets: %v", err)
+	}
+	if err := os.Chmod(secretsPath, 0644); err != nil {
+		t.Fatalf("failed to chmod secrets: %v", err)
+	}
+
+	report, err := NewManager(secretsPath).Verify(VerifyOptions{DeriveClientSecret: fakeDerive})
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !report.OK {
+		t.Errorf("expected everything to be repaired, got %+v", report.Findings)
+	}
+	checks := findingChecks(report)
+	for _, check := range []string{CheckSecretMissing, CheckClientSecret, CheckPermissions} {
+		if f, ok := checks[check]; !ok || !f.Repaired {
+			t.Errorf("expected a repaired %s finding, got %+v", check, report.Findings)
+		}
+	}
+
+	m2 := NewManager(secretsPath)
+	if err := m2.Load(); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	if got := m2.GetAppSecret("miniflux", "oauthClientSecret"); got != "derived-miniflux" {
+		t.Errorf("expected the client secret to be re-derived, got %q", got)
+	}
+	if info, err := os.Stat(secretsPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected secrets.json mode 0600, got %v", info.Mode())
+	}
+
+	// Missing env files are rewritten
+	if err := os.Remove(filepath.Join(dir, "database.env")); err != nil {
+		t.Fatalf("failed to remove env file: %v", err)
+	}
+	report, err = m2.Verify(VerifyOptions{DeriveClientSecret: fakeDerive})
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if f, ok := findingChecks(report)[CheckEnvFile]; !ok || !f.Repaired {
+		t.Errorf("expected a repaired %s finding, got %+v", CheckEnvFile, report.Findings)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "database.env")); err != nil {
+		t.Errorf("expected database.env to be rewritten: %v", err)
+	}
+
+	// A second pass finds nothing, and appended prestart vars don't count as stale
+	if err := m2.AppendEnvVars("miniflux", map[string]string{"OAUTH2_REDIRECT_URL": "http://host/callback"}); err != nil {
+		t.Fatalf("failed to append env vars: %v", err)
+	}
+	report, err = m2.Verify(VerifyOptions{DeriveClientSecret: fakeDerive})
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !report.OK || len(report.Findings) != 0 {
+		t.Errorf("expected a clean report, got %+v", report.Findings)
+	}
+}
+
+func TestManager_VerifyReportsUnreadable(t *testing.T) {
+	secretsPath := filepath.Join(t.TempDir(), "secrets.json")
+
+	m := NewManager(secretsPath)
+	if err := m.Load(); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if err := m.SetAppSecret("miniflux", "adminPassword", "admin-password"); err != nil {
+		t.Fatalf("failed to set app secret: %v", err)
+	}
+
+	var stored map[string]any
+	data, _ := os.ReadFile(secretsPath)
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("failed to parse secrets: %v", err)
+	}
+	stored["ssoHostSecret"] = "a-different-host-secret"
+	data, _ = json.Marshal(stored)
+	if err := os.WriteFile(secretsPath, data, 0600); err != nil {
+		t.Fatalf("failed to write secrets: %v", err)
+	}
+
+	report, err := NewManager(secretsPath).Verify(VerifyOptions{})
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	f, ok := findingChecks(report)[CheckUnreadable]
+	if report.OK || !ok || f.Repaired || f.Action == "" {
+		t.Errorf("expected an unrepaired unreadable finding with an action, got %+v", report.Findings)
+	}
+}
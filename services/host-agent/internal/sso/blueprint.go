@@ -214,7 +214,8 @@ func (g *BlueprintGenerator) renderOutpostBlueprint(providers []ForwardAuthProvi
 }
 
 // GetSSOEnvVars returns the environment variables needed for an app's SSO config.
-// Uses the primary base URL for redirect/discovery URLs.
+// Uses the primary base URL for redirect/discovery URLs. The client secret is
+// not among them; see SSOCredentials.
 func (g *BlueprintGenerator) GetSSOEnvVars(app *catalog.App) map[string]string {
 	if app.SSO.Strategy != "native-oidc" {
 		return nil
@@ -222,7 +223,9 @@ func (g *BlueprintGenerator) GetSSOEnvVars(app *catalog.App) map[string]string {
 
 	baseURL := g.primaryBaseURL()
 	clientID := g.generateClientID(app.Name)
-	clientSecret := g.generateClientSecret(app.Name)
+	// The secret reaches the app as its oauth-client-secret systemd
+	// credential, not an env var; deriving it here keeps the stored copy current
+	g.generateClientSecret(app.Name)
 	discoveryURL := fmt.Sprintf("%s/application/o/%s/", g.authentikURL, app.Name)
 	redirectURL := fmt.Sprintf("%s/embed/%s%s", baseURL, app.Name, app.SSO.CallbackPath)
 	serverHostname := fmt.Sprintf("%s/embed/%s", baseURL, app.Name)
@@ -233,9 +236,6 @@ func (g *BlueprintGenerator) GetSSOEnvVars(app *catalog.App) map[string]string {
 	if app.SSO.Env.ClientID != "" {
 		env[app.SSO.Env.ClientID] = clientID
 	}
-	if app.SSO.Env.ClientSecret != "" {
-		env[app.SSO.Env.ClientSecret] = clientSecret
-	}
 	if app.SSO.Env.DiscoveryURL != "" {
 		env[app.SSO.Env.DiscoveryURL] = discoveryURL
 	}
@@ -265,6 +265,15 @@ func (g *BlueprintGenerator) GetSSOEnvVars(app *catalog.App) map[string]string {
 	return env
 }
 
+// SSOCredentials returns the systemd credentials an app's SSO config needs,
+// mapping each credential ID to the env var the container reads it as.
+func SSOCredentials(app *catalog.App) map[string]string {
+	if app.SSO.Strategy != "native-oidc" || app.SSO.Env.ClientSecret == "" {
+		return nil
+	}
+	return map[string]string{secrets.CredentialOAuthClientSecret: app.SSO.Env.ClientSecret}
+}
+
 func (g *BlueprintGenerator) generateClientID(appName string) string {
 	return fmt.Sprintf("%s-client", appName)
 }
@@ -275,7 +284,7 @@ func (g *BlueprintGenerator) generateClientSecret(appName string) string {
 	// 1. Same secret is generated for the same app + hostSecret
 	// 2. Different apps get different secrets
 	// 3. Secrets are cryptographically strong
-	secret := deriveSecret(g.hostSecret, "oauth-client-secret:"+appName, 32)
+	secret := deriveSecret(g.hostSecret, clientSecretContext(appName, g.keyVersion()), 32)
 
 	// Persist the derived secret so NixOS modules can read it
 	if g.secrets != nil {
@@ -285,6 +294,30 @@ func (g *BlueprintGenerator) generateClientSecret(appName string) string {
 	return secret
 }
 
+// keyVersion returns the key version client secrets are derived with
+func (g *BlueprintGenerator) keyVersion() int {
+	if g.secrets == nil {
+		return 0
+	}
+	return g.secrets.KeyVersion()
+}
+
+// DeriveClientSecret returns the OAuth client secret an app gets from the
+// host secret at a key version, as written by the blueprint generator.
+func DeriveClientSecret(hostSecret, appName string, version int) string {
+	return deriveSecret(hostSecret, clientSecretContext(appName, version), 32)
+}
+
+// clientSecretContext returns the HKDF context for an app's client secret.
+// Version 0 and 1 keep the original context so existing secrets don't change;
+// rotated versions include the version number.
+func clientSecretContext(appName string, version int) string {
+	if version <= 1 {
+		return "oauth-client-secret:" + appName
+	}
+	return fmt.Sprintf("oauth-client-secret:v%d:%s", version, appName)
+}
+
 // deriveSecret uses HKDF-SHA256 to derive a deterministic secret from a master secret.
 func deriveSecret(masterSecret, context string, length int) string {
 	if masterSecret == "" {
@@ -465,3 +465,15 @@ func TestDeriveSecret_NoPadding(t *testing.T) {
 		}
 	}
 }
+
+func TestClientSecretContext_Versions(t *testing.T) {
+	// Unrotated deployments must keep deriving the secrets they already use
+	for _, version := range []int{0, 1} {
+		if got := clientSecretContext("miniflux", version); got != "oauth-client-secret:miniflux" {
+			t.Errorf("version %d: expected original context, got %q", version, got)
+		}
+	}
+	if clientSecretContext("miniflux", 2) == clientSecretContext("miniflux", 1) {
+		t.Error("rotated version should derive a different secret")
+	}
+}
@@ -0,0 +1,208 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+)
+
+// HostSecretRewrapper moves the SSO host secret to a new value, such as after
+// restoring onto new hardware with a different BLOUD_SSO_HOST_SECRET or
+// replacing a leaked secret.
+//
+// Every native OIDC app's client secret is derived from the host secret, so
+// changing it breaks SSO unless the derived secrets move with it:
+//  1. Authentik providers holding a different client secret are updated.
+//  2. The host secret and the new client secrets are saved to secrets.json
+//     in a single write, re-sealing per-app secrets with the new key.
+//  3. Blueprints are regenerated so Authentik keeps the new secrets when it
+//     re-applies them.
+//  4. Affected apps are restarted so their prestart hooks write the new secret.
+//
+// If any step before the restarts fails, providers, secrets and blueprints
+// are put back as they were.
+type HostSecretRewrapper struct {
+	client     OAuthSecretClient
+	secrets    HostSecretStore
+	blueprints func(hostSecret string) BlueprintGeneratorInterface
+	restarter  ServiceRestarter
+	logger     *slog.Logger
+}
+
+// RewrapPlan describes what moving to a host secret changes.
+type RewrapPlan struct {
+	HostSecretChanged bool
+	Apps              []RewrapApp // native OIDC apps whose client secret changes
+}
+
+// Changed reports whether the rewrap has anything to do
+func (p *RewrapPlan) Changed() bool {
+	return p.HostSecretChanged || len(p.Apps) > 0
+}
+
+// RewrapApp is a native OIDC app whose client secret doesn't match its
+// derivation from the host secret.
+type RewrapApp struct {
+	Name          string
+	Provider      string // Authentik OAuth2 provider name
+	StoredStale   bool   // secrets.json holds a different client secret
+	ProviderStale bool   // Authentik holds a different client secret
+
+	app              *catalog.App
+	clientSecret     string // derived from the new host secret
+	previousStored   string
+	previousProvider string
+}
+
+// NewHostSecretRewrapper creates a new host secret rewrapper. blueprints
+// returns a blueprint generator deriving client secrets from hostSecret.
+func NewHostSecretRewrapper(client OAuthSecretClient, secretStore HostSecretStore, blueprints func(hostSecret string) BlueprintGeneratorInterface, restarter ServiceRestarter, logger *slog.Logger) *HostSecretRewrapper {
+	return &HostSecretRewrapper{
+		client:     client,
+		secrets:    secretStore,
+		blueprints: blueprints,
+		restarter:  restarter,
+		logger:     logger,
+	}
+}
+
+// Plan compares the stored and Authentik client secrets of the native OIDC
+// apps among apps with their derivation from hostSecret, without changing anything.
+func (r *HostSecretRewrapper) Plan(apps []*catalog.App, hostSecret string) (*RewrapPlan, error) {
+	if hostSecret == "" {
+		return nil, errors.New("host secret must not be empty")
+	}
+	if rotation := r.secrets.Rotation(); rotation != nil {
+		return nil, fmt.Errorf("a secret rotation to version %d is in progress, finish it first", rotation.Version)
+	}
+
+	plan := &RewrapPlan{HostSecretChanged: r.secrets.GetSSOHostSecret() != hostSecret}
+	version := r.secrets.StoredKeyVersion()
+
+	for _, app := range apps {
+		if app.SSO.Strategy != "native-oidc" {
+			continue
+		}
+		provider := fmt.Sprintf("%s OAuth2 Provider", app.DisplayName)
+		current, err := r.client.GetOAuth2ProviderClientSecret(provider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read provider for %s: %w", app.Name, err)
+		}
+
+		derived := DeriveClientSecret(hostSecret, app.Name, version)
+		stored := r.secrets.GetAppSecret(app.Name, "oauthClientSecret")
+		entry := RewrapApp{
+			Name:             app.Name,
+			Provider:         provider,
+			StoredStale:      stored != derived,
+			ProviderStale:    current != "" && current != derived, // a missing provider is created from the blueprint
+			app:              app,
+			clientSecret:     derived,
+			previousStored:   stored,
+			previousProvider: current,
+		}
+		if entry.StoredStale || entry.ProviderStale {
+			plan.Apps = append(plan.Apps, entry)
+		}
+	}
+	sort.Slice(plan.Apps, func(i, j int) bool { return plan.Apps[i].Name < plan.Apps[j].Name })
+
+	return plan, nil
+}
+
+// Rewrap moves the deployment to hostSecret, re-deriving the client secrets
+// of the native OIDC apps among apps. The returned plan says what changed;
+// nothing is changed if the secrets already match.
+func (r *HostSecretRewrapper) Rewrap(ctx context.Context, apps []*catalog.App, hostSecret string) (*RewrapPlan, error) {
+	plan, err := r.Plan(apps, hostSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.Changed() {
+		r.logger.Info("host secret and client secrets already match")
+		return plan, nil
+	}
+	oldHostSecret := r.secrets.GetSSOHostSecret()
+
+	var pushed []RewrapApp
+	for _, app := range plan.Apps {
+		if !app.ProviderStale {
+			continue
+		}
+		if err := r.client.SetOAuth2ProviderClientSecret(app.Provider, app.clientSecret); err != nil {
+			r.restoreProviders(pushed)
+			return nil, fmt.Errorf("failed to update provider for %s: %w", app.Name, err)
+		}
+		pushed = append(pushed, app)
+	}
+
+	clientSecrets := make(map[string]string, len(plan.Apps))
+	for _, app := range plan.Apps {
+		clientSecrets[app.Name] = app.clientSecret
+	}
+	if err := r.secrets.ReplaceHostSecret(hostSecret, clientSecrets); err != nil {
+		r.restoreProviders(pushed)
+		return nil, fmt.Errorf("failed to save new host secret: %w", err)
+	}
+
+	gen := r.blueprints(hostSecret)
+	var regenerated []RewrapApp
+	for _, app := range plan.Apps {
+		if err := gen.GenerateForApp(app.app); err != nil {
+			r.abort(plan, append(regenerated, app), pushed, oldHostSecret)
+			return nil, fmt.Errorf("failed to regenerate blueprint for %s: %w", app.Name, err)
+		}
+		regenerated = append(regenerated, app)
+	}
+	r.logger.Info("rewrapped host secret", "hostSecretChanged", plan.HostSecretChanged, "apps", len(plan.Apps))
+
+	// Everything agrees on the new secrets from here; an app that fails to
+	// restart keeps its old secret until it next starts, so don't roll back
+	var errs []error
+	for _, app := range plan.Apps {
+		if err := r.restarter.RestartUserService(ctx, app.Name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", app.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return plan, fmt.Errorf("secrets were updated but some apps failed to restart: %w", errors.Join(errs...))
+	}
+
+	return plan, nil
+}
+
+// abort puts the blueprints, stored secrets and providers back as they were.
+// Failures are logged; the original error is what gets returned to the caller.
+func (r *HostSecretRewrapper) abort(plan *RewrapPlan, regenerated, pushed []RewrapApp, oldHostSecret string) {
+	// Blueprints first: regenerating stores the old derivation, which the
+	// exact previous values then replace
+	gen := r.blueprints(oldHostSecret)
+	for _, app := range regenerated {
+		if err := gen.GenerateForApp(app.app); err != nil {
+			r.logger.Error("failed to restore blueprint", "app", app.Name, "error", err)
+		}
+	}
+
+	previous := make(map[string]string, len(plan.Apps))
+	for _, app := range plan.Apps {
+		previous[app.Name] = app.previousStored
+	}
+	if err := r.secrets.ReplaceHostSecret(oldHostSecret, previous); err != nil {
+		r.logger.Error("failed to restore old host secret", "error", err)
+	}
+
+	r.restoreProviders(pushed)
+}
+
+// restoreProviders puts back the client secrets of providers already updated
+func (r *HostSecretRewrapper) restoreProviders(pushed []RewrapApp) {
+	for _, app := range pushed {
+		if err := r.client.SetOAuth2ProviderClientSecret(app.Provider, app.previousProvider); err != nil {
+			r.logger.Error("failed to restore provider client secret", "app", app.Name, "error", err)
+		}
+	}
+}
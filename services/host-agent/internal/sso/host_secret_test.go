@@ -0,0 +1,171 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+)
+
+// fakeOAuthSecretClient holds provider client secrets in memory
+type fakeOAuthSecretClient struct {
+	providers map[string]string
+	failSet   string // provider whose update fails
+}
+
+func (f *fakeOAuthSecretClient) GetOAuth2ProviderClientSecret(providerName string) (string, error) {
+	return f.providers[providerName], nil
+}
+
+func (f *fakeOAuthSecretClient) SetOAuth2ProviderClientSecret(providerName, clientSecret string) error {
+	if providerName == f.failSet {
+		return errors.New("authentik unavailable")
+	}
+	f.providers[providerName] = clientSecret
+	return nil
+}
+
+// newRewrapTest sets up installed apps whose providers hold the secrets
+// derived from the current host secret
+func newRewrapTest(t *testing.T) (*secrets.Manager, *fakeOAuthSecretClient, []*catalog.App, string) {
+	t.Helper()
+	mgr, _, apps := newRotationTest(t)
+	for _, app := range apps {
+		app.DisplayName = strings.ToUpper(app.Name[:1]) + app.Name[1:]
+	}
+
+	client := &fakeOAuthSecretClient{providers: map[string]string{}}
+	for _, app := range apps {
+		if app.SSO.Strategy == "native-oidc" {
+			client.providers[app.DisplayName+" OAuth2 Provider"] = mgr.GetAppSecret(app.Name, "oauthClientSecret")
+		}
+	}
+	return mgr, client, apps, t.TempDir()
+}
+
+func testRewrapper(mgr *secrets.Manager, client *fakeOAuthSecretClient, restarter ServiceRestarter, blueprintsDir string) *HostSecretRewrapper {
+	blueprints := func(hostSecret string) BlueprintGeneratorInterface {
+		return NewBlueprintGenerator(hostSecret, "test-ldap-password",
+			[]string{"http://localhost:8080"}, "http://localhost:8080", blueprintsDir, mgr)
+	}
+	return NewHostSecretRewrapper(client, mgr, blueprints, restarter, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestHostSecretRewrapper_NothingToDo(t *testing.T) {
+	mgr, client, apps, dir := newRewrapTest(t)
+	restarter := &fakeRestarter{}
+
+	plan, err := testRewrapper(mgr, client, restarter, dir).Rewrap(context.Background(), apps, mgr.GetSSOHostSecret())
+	if err != nil {
+		t.Fatalf("Rewrap failed: %v", err)
+	}
+	if plan.Changed() {
+		t.Errorf("expected no changes, got %+v", plan)
+	}
+	if len(restarter.restarted) != 0 {
+		t.Errorf("expected no restarts, got %v", restarter.restarted)
+	}
+}
+
+func TestHostSecretRewrapper_NewHostSecret(t *testing.T) {
+	mgr, client, apps, dir := newRewrapTest(t)
+	restarter := &fakeRestarter{}
+	newHostSecret := secrets.GenerateSecret(64)
+
+	plan, err := testRewrapper(mgr, client, restarter, dir).Rewrap(context.Background(), apps, newHostSecret)
+	if err != nil {
+		t.Fatalf("Rewrap failed: %v", err)
+	}
+	if !plan.HostSecretChanged || len(plan.Apps) != 2 {
+		t.Fatalf("expected host secret and both OIDC apps to change, got %+v", plan)
+	}
+
+	want := DeriveClientSecret(newHostSecret, "miniflux", 1)
+	if got := client.providers["Miniflux OAuth2 Provider"]; got != want {
+		t.Errorf("provider secret = %q, want %q", got, want)
+	}
+	if !slices.Equal(restarter.restarted, []string{"actual-budget", "miniflux"}) {
+		t.Errorf("expected OIDC apps restarted, got %v", restarter.restarted)
+	}
+	blueprint, err := os.ReadFile(filepath.Join(dir, "miniflux.yaml"))
+	if err != nil {
+		t.Fatalf("reading blueprint: %v", err)
+	}
+	if !strings.Contains(string(blueprint), "client_secret: "+want) {
+		t.Error("expected the blueprint to carry the new client secret")
+	}
+
+	// The new host secret and client secrets survive a reload
+	reloaded := secrets.NewManager(mgr.Path())
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("reloading secrets: %v", err)
+	}
+	if reloaded.GetSSOHostSecret() != newHostSecret {
+		t.Error("expected the new host secret to be saved")
+	}
+	if got := reloaded.GetAppSecret("miniflux", "oauthClientSecret"); got != want {
+		t.Errorf("stored secret = %q, want %q", got, want)
+	}
+}
+
+func TestHostSecretRewrapper_StaleProvider(t *testing.T) {
+	// Authentik restored from a backup taken with another host secret
+	mgr, client, apps, dir := newRewrapTest(t)
+	client.providers["Miniflux OAuth2 Provider"] = "restored-secret"
+	restarter := &fakeRestarter{}
+
+	plan, err := testRewrapper(mgr, client, restarter, dir).Rewrap(context.Background(), apps, mgr.GetSSOHostSecret())
+	if err != nil {
+		t.Fatalf("Rewrap failed: %v", err)
+	}
+	if plan.HostSecretChanged || len(plan.Apps) != 1 || plan.Apps[0].Name != "miniflux" || plan.Apps[0].StoredStale || !plan.Apps[0].ProviderStale {
+		t.Fatalf("expected only the miniflux provider to be stale, got %+v", plan)
+	}
+	if got := client.providers["Miniflux OAuth2 Provider"]; got != mgr.GetAppSecret("miniflux", "oauthClientSecret") {
+		t.Errorf("expected the provider to get the stored secret, got %q", got)
+	}
+}
+
+func TestHostSecretRewrapper_RollsBackOnFailure(t *testing.T) {
+	mgr, client, apps, dir := newRewrapTest(t)
+	oldHostSecret := mgr.GetSSOHostSecret()
+	oldSecret := mgr.GetAppSecret("actual-budget", "oauthClientSecret")
+	client.failSet = "Miniflux OAuth2 Provider"
+	restarter := &fakeRestarter{}
+
+	if _, err := testRewrapper(mgr, client, restarter, dir).Rewrap(context.Background(), apps, secrets.GenerateSecret(64)); err == nil {
+		t.Fatal("expected Rewrap to fail")
+	}
+
+	if mgr.GetSSOHostSecret() != oldHostSecret {
+		t.Error("expected the host secret to be unchanged")
+	}
+	if got := client.providers["Actual-budget OAuth2 Provider"]; got != oldSecret {
+		t.Errorf("expected the updated provider to be restored, got %q", got)
+	}
+	if got := mgr.GetAppSecret("actual-budget", "oauthClientSecret"); got != oldSecret {
+		t.Errorf("expected the stored secret to be unchanged, got %q", got)
+	}
+	if len(restarter.restarted) != 0 {
+		t.Errorf("expected no restarts, got %v", restarter.restarted)
+	}
+}
+
+func TestHostSecretRewrapper_RefusesDuringRotation(t *testing.T) {
+	mgr, client, apps, dir := newRewrapTest(t)
+	if _, _, err := mgr.BeginRotation([]string{"miniflux"}); err != nil {
+		t.Fatalf("BeginRotation failed: %v", err)
+	}
+
+	if _, err := testRewrapper(mgr, client, &fakeRestarter{}, dir).Plan(apps, secrets.GenerateSecret(64)); err == nil {
+		t.Error("expected Plan to refuse while a rotation is in progress")
+	}
+}
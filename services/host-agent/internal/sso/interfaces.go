@@ -4,6 +4,9 @@ import (
 	"context"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/authentik"
 )
 
 // BlueprintGeneratorInterface defines the interface for generating Authentik blueprints.
@@ -35,3 +38,77 @@ type BlueprintGeneratorInterface interface {
 
 // Compile-time assertion
 var _ BlueprintGeneratorInterface = (*BlueprintGenerator)(nil)
+
+// LDAPTokenClient defines the Authentik operations needed to rotate the LDAP bind token.
+type LDAPTokenClient interface {
+	// GetLDAPServiceTokenKey returns the current bind key
+	GetLDAPServiceTokenKey() (string, error)
+
+	// StageLDAPServiceToken creates a second token with the new key so both keys bind
+	StageLDAPServiceToken(key string) error
+
+	// PromoteLDAPServiceToken switches the canonical token to the new key and removes the staged one
+	PromoteLDAPServiceToken(key string) error
+
+	// DiscardStagedLDAPServiceToken removes the staged token, aborting a rotation
+	DiscardStagedLDAPServiceToken() error
+}
+
+// LDAPSecretStore persists the LDAP bind password.
+type LDAPSecretStore interface {
+	// SetLDAPBindPassword replaces the stored bind password
+	SetLDAPBindPassword(value string) error
+}
+
+// SecretRotationStore tracks the progress of a per-app secret rotation.
+type SecretRotationStore interface {
+	// BeginRotation starts a rotation of apps, or returns the one in progress
+	BeginRotation(apps []string) (rotation *secrets.Rotation, resumed bool, err error)
+
+	// CompleteRotationApp records that an app has moved to the new key version
+	CompleteRotationApp(appName string) error
+
+	// FinishRotation makes the rotation's key version current
+	FinishRotation() error
+}
+
+// OAuthSecretClient reads and replaces OAuth2 provider client secrets in Authentik.
+type OAuthSecretClient interface {
+	// GetOAuth2ProviderClientSecret returns a provider's client secret, or "" if it doesn't exist
+	GetOAuth2ProviderClientSecret(providerName string) (string, error)
+
+	// SetOAuth2ProviderClientSecret replaces a provider's client secret
+	SetOAuth2ProviderClientSecret(providerName, clientSecret string) error
+}
+
+// HostSecretStore persists the host secret and the client secrets derived from it.
+type HostSecretStore interface {
+	// GetSSOHostSecret returns the stored host secret
+	GetSSOHostSecret() string
+
+	// GetAppSecret returns a stored per-app secret
+	GetAppSecret(appName, key string) string
+
+	// StoredKeyVersion returns the key version client secrets are derived with
+	StoredKeyVersion() int
+
+	// Rotation returns the per-app secret rotation in progress, or nil
+	Rotation() *secrets.Rotation
+
+	// ReplaceHostSecret switches the host secret and sets the derived client secrets in one save
+	ReplaceHostSecret(hostSecret string, clientSecrets map[string]string) error
+}
+
+// ServiceRestarter restarts an app's systemd user service.
+type ServiceRestarter interface {
+	// RestartUserService restarts the podman service for an app
+	RestartUserService(ctx context.Context, appName string) error
+}
+
+// Compile-time assertions
+var _ LDAPTokenClient = (*authentik.Client)(nil)
+var _ OAuthSecretClient = (*authentik.Client)(nil)
+var _ LDAPSecretStore = (*secrets.Manager)(nil)
+var _ SecretRotationStore = (*secrets.Manager)(nil)
+var _ HostSecretStore = (*secrets.Manager)(nil)
+var _ ServiceRestarter = (*nixgen.Rebuilder)(nil)
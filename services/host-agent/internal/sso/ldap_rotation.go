@@ -0,0 +1,140 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/configurator"
+)
+
+// ldapBindPasswordLength matches the length of the bind password generated on first boot
+const ldapBindPasswordLength = 32
+
+// LDAPRotator rotates the key of the LDAP service account token that apps use
+// to bind to the Authentik LDAP outpost.
+//
+// Rotation is staged so the outpost never rejects a running app:
+//  1. A second token with the new key is created; both keys now bind.
+//  2. Each LDAP app gets the new password and is restarted.
+//  3. The new key is persisted to secrets.json.
+//  4. The canonical token is switched to the new key and the staged token removed.
+//
+// If any step before promotion fails, apps that were already switched are
+// moved back to the old key and the staged token is discarded.
+type LDAPRotator struct {
+	client    LDAPTokenClient
+	secrets   LDAPSecretStore
+	registry  configurator.RegistryInterface
+	restarter ServiceRestarter
+	logger    *slog.Logger
+}
+
+// NewLDAPRotator creates a new LDAP bind token rotator
+func NewLDAPRotator(client LDAPTokenClient, secretStore LDAPSecretStore, registry configurator.RegistryInterface, restarter ServiceRestarter, logger *slog.Logger) *LDAPRotator {
+	return &LDAPRotator{
+		client:    client,
+		secrets:   secretStore,
+		registry:  registry,
+		restarter: restarter,
+		logger:    logger,
+	}
+}
+
+// Rotate mints a new bind key and moves every app in apps over to it.
+// Apps without a configurator implementing configurator.LDAPBindUpdater are skipped.
+// Returns the names of the apps that were updated.
+func (r *LDAPRotator) Rotate(ctx context.Context, apps []*configurator.AppState) ([]string, error) {
+	oldKey, err := r.client.GetLDAPServiceTokenKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current bind key: %w", err)
+	}
+	newKey := secrets.GenerateSecret(ldapBindPasswordLength)
+
+	if err := r.client.StageLDAPServiceToken(newKey); err != nil {
+		return nil, fmt.Errorf("failed to stage new bind token: %w", err)
+	}
+	r.logger.Info("staged new LDAP bind token")
+
+	var updated []*configurator.AppState
+	for _, state := range apps {
+		updater := r.bindUpdater(state.Name)
+		if updater == nil {
+			continue
+		}
+
+		if err := r.pushPassword(ctx, updater, state, newKey); err != nil {
+			r.abort(ctx, append(updated, state), oldKey)
+			return nil, fmt.Errorf("failed to update %s: %w", state.Name, err)
+		}
+		updated = append(updated, state)
+	}
+
+	if err := r.secrets.SetLDAPBindPassword(newKey); err != nil {
+		r.abort(ctx, updated, oldKey)
+		return nil, fmt.Errorf("failed to persist new bind password: %w", err)
+	}
+
+	if err := r.client.PromoteLDAPServiceToken(newKey); err != nil {
+		// Both keys still bind while the staged token exists, so leave it in place
+		// rather than cutting off apps that already use the new key.
+		return nil, fmt.Errorf("failed to promote new bind token: %w", err)
+	}
+
+	names := make([]string, 0, len(updated))
+	for _, state := range updated {
+		names = append(names, state.Name)
+	}
+	r.logger.Info("rotated LDAP bind token", "apps", names)
+
+	return names, nil
+}
+
+// bindUpdater returns the app's configurator if it consumes the LDAP bind password
+func (r *LDAPRotator) bindUpdater(appName string) configurator.LDAPBindUpdater {
+	cfg := r.registry.Get(appName)
+	if cfg == nil {
+		return nil
+	}
+	updater, ok := cfg.(configurator.LDAPBindUpdater)
+	if !ok {
+		return nil
+	}
+	return updater
+}
+
+// pushPassword writes the password into the app's config and restarts it
+func (r *LDAPRotator) pushPassword(ctx context.Context, updater configurator.LDAPBindUpdater, state *configurator.AppState, password string) error {
+	if err := updater.UpdateLDAPBindPassword(ctx, state, password); err != nil {
+		return err
+	}
+	return r.restarter.RestartUserService(ctx, state.Name)
+}
+
+// abort moves apps back to the old key and removes the staged token.
+// Failures are logged; the original error is what gets returned to the caller.
+func (r *LDAPRotator) abort(ctx context.Context, apps []*configurator.AppState, oldKey string) {
+	var errs []error
+	for _, state := range apps {
+		updater := r.bindUpdater(state.Name)
+		if updater == nil {
+			continue
+		}
+		if err := r.pushPassword(ctx, updater, state, oldKey); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", state.Name, err))
+		}
+	}
+
+	// Only discard the staged token if every app is back on the old key,
+	// otherwise an app left on the new key would lose LDAP access.
+	if len(errs) > 0 {
+		r.logger.Error("failed to restore old LDAP bind password, leaving staged token in place", "error", errors.Join(errs...))
+		return
+	}
+
+	if err := r.client.DiscardStagedLDAPServiceToken(); err != nil {
+		r.logger.Error("failed to discard staged LDAP bind token", "error", err)
+	}
+}
@@ -0,0 +1,209 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/pkg/configurator"
+)
+
+type fakeTokenClient struct {
+	currentKey string
+	stagedKey  string
+	stageErr   error
+	promoteErr error
+	discarded  bool
+}
+
+func (f *fakeTokenClient) GetLDAPServiceTokenKey() (string, error) { return f.currentKey, nil }
+
+func (f *fakeTokenClient) StageLDAPServiceToken(key string) error {
+	if f.stageErr != nil {
+		return f.stageErr
+	}
+	f.stagedKey = key
+	return nil
+}
+
+func (f *fakeTokenClient) PromoteLDAPServiceToken(key string) error {
+	if f.promoteErr != nil {
+		return f.promoteErr
+	}
+	f.currentKey = key
+	f.stagedKey = ""
+	return nil
+}
+
+func (f *fakeTokenClient) DiscardStagedLDAPServiceToken() error {
+	f.discarded = true
+	f.stagedKey = ""
+	return nil
+}
+
+type fakeSecretStore struct {
+	password string
+}
+
+func (f *fakeSecretStore) SetLDAPBindPassword(value string) error {
+	f.password = value
+	return nil
+}
+
+type fakeRestarter struct {
+	restarted []string
+}
+
+func (f *fakeRestarter) RestartUserService(ctx context.Context, appName string) error {
+	f.restarted = append(f.restarted, appName)
+	return nil
+}
+
+// fakeLDAPConfigurator records the bind password pushed to it
+type fakeLDAPConfigurator struct {
+	name      string
+	password  string
+	updateErr error
+}
+
+func (f *fakeLDAPConfigurator) Name() string { return f.name }
+func (f *fakeLDAPConfigurator) PreStart(ctx context.Context, state *configurator.AppState) error {
+	return nil
+}
+func (f *fakeLDAPConfigurator) HealthCheck(ctx context.Context) error { return nil }
+func (f *fakeLDAPConfigurator) PostStart(ctx context.Context, state *configurator.AppState) error {
+	return nil
+}
+
+func (f *fakeLDAPConfigurator) UpdateLDAPBindPassword(ctx context.Context, state *configurator.AppState, password string) error {
+	if f.updateErr != nil && password != "old-key" {
+		return f.updateErr
+	}
+	f.password = password
+	return nil
+}
+
+// fakePlainConfigurator has no LDAP integration
+type fakePlainConfigurator struct{ name string }
+
+func (f *fakePlainConfigurator) Name() string { return f.name }
+func (f *fakePlainConfigurator) PreStart(ctx context.Context, state *configurator.AppState) error {
+	return nil
+}
+func (f *fakePlainConfigurator) HealthCheck(ctx context.Context) error { return nil }
+func (f *fakePlainConfigurator) PostStart(ctx context.Context, state *configurator.AppState) error {
+	return nil
+}
+
+func testRotator(t *testing.T, client *fakeTokenClient, secretStore *fakeSecretStore, restarter *fakeRestarter, cfgs ...configurator.Configurator) *LDAPRotator {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry := configurator.NewRegistry(logger)
+	for _, c := range cfgs {
+		registry.Register(c)
+	}
+	return NewLDAPRotator(client, secretStore, registry, restarter, logger)
+}
+
+func TestLDAPRotator_Rotate(t *testing.T) {
+	client := &fakeTokenClient{currentKey: "old-key"}
+	secretStore := &fakeSecretStore{password: "old-key"}
+	restarter := &fakeRestarter{}
+	jellyfin := &fakeLDAPConfigurator{name: "jellyfin", password: "old-key"}
+
+	rotator := testRotator(t, client, secretStore, restarter, jellyfin)
+
+	apps := []*configurator.AppState{
+		{Name: "jellyfin"},
+		{Name: "miniflux"}, // no configurator
+	}
+	updated, err := rotator.Rotate(context.Background(), apps)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	if client.currentKey == "old-key" || client.currentKey == "" {
+		t.Errorf("canonical token key not rotated: %q", client.currentKey)
+	}
+	if client.stagedKey != "" {
+		t.Error("staged token should be removed after promotion")
+	}
+	if jellyfin.password != client.currentKey {
+		t.Errorf("jellyfin password = %q, want %q", jellyfin.password, client.currentKey)
+	}
+	if secretStore.password != client.currentKey {
+		t.Errorf("stored password = %q, want %q", secretStore.password, client.currentKey)
+	}
+	if len(updated) != 1 || updated[0] != "jellyfin" {
+		t.Errorf("updated = %v, want [jellyfin]", updated)
+	}
+	if len(restarter.restarted) != 1 || restarter.restarted[0] != "jellyfin" {
+		t.Errorf("restarted = %v, want [jellyfin]", restarter.restarted)
+	}
+}
+
+func TestLDAPRotator_Rotate_SkipsAppsWithoutLDAP(t *testing.T) {
+	client := &fakeTokenClient{currentKey: "old-key"}
+	restarter := &fakeRestarter{}
+	plain := &fakePlainConfigurator{name: "radarr"}
+
+	rotator := testRotator(t, client, &fakeSecretStore{}, restarter, plain)
+
+	updated, err := rotator.Rotate(context.Background(), []*configurator.AppState{{Name: "radarr"}})
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if len(updated) != 0 {
+		t.Errorf("updated = %v, want none", updated)
+	}
+	if len(restarter.restarted) != 0 {
+		t.Errorf("restarted = %v, want none", restarter.restarted)
+	}
+}
+
+func TestLDAPRotator_Rotate_RollsBackOnAppFailure(t *testing.T) {
+	client := &fakeTokenClient{currentKey: "old-key"}
+	secretStore := &fakeSecretStore{password: "old-key"}
+	restarter := &fakeRestarter{}
+	first := &fakeLDAPConfigurator{name: "jellyfin", password: "old-key"}
+	second := &fakeLDAPConfigurator{name: "gitea", password: "old-key", updateErr: errors.New("write failed")}
+
+	rotator := testRotator(t, client, secretStore, restarter, first, second)
+
+	apps := []*configurator.AppState{{Name: "jellyfin"}, {Name: "gitea"}}
+	if _, err := rotator.Rotate(context.Background(), apps); err == nil {
+		t.Fatal("Rotate() expected error")
+	}
+
+	if client.currentKey != "old-key" {
+		t.Errorf("canonical token key = %q, want old-key", client.currentKey)
+	}
+	if !client.discarded {
+		t.Error("staged token should be discarded on failure")
+	}
+	if first.password != "old-key" {
+		t.Errorf("jellyfin password = %q, want old-key restored", first.password)
+	}
+	if secretStore.password != "old-key" {
+		t.Errorf("stored password = %q, want old-key", secretStore.password)
+	}
+}
+
+func TestLDAPRotator_Rotate_StageFailure(t *testing.T) {
+	client := &fakeTokenClient{currentKey: "old-key", stageErr: errors.New("authentik down")}
+	restarter := &fakeRestarter{}
+	jellyfin := &fakeLDAPConfigurator{name: "jellyfin", password: "old-key"}
+
+	rotator := testRotator(t, client, &fakeSecretStore{password: "old-key"}, restarter, jellyfin)
+
+	if _, err := rotator.Rotate(context.Background(), []*configurator.AppState{{Name: "jellyfin"}}); err == nil {
+		t.Fatal("Rotate() expected error")
+	}
+	if jellyfin.password != "old-key" {
+		t.Error("apps should not be touched when staging fails")
+	}
+	if len(restarter.restarted) != 0 {
+		t.Errorf("restarted = %v, want none", restarter.restarted)
+	}
+}
@@ -0,0 +1,108 @@
+package sso
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+)
+
+// SecretRotator moves the OAuth client secrets of native OIDC apps to a new
+// key version.
+//
+// Client secrets are derived from the host secret and the key version, so
+// rotating bumps the version and re-derives each app's secret:
+//  1. The rotation (new version and app list) is saved to secrets.json.
+//  2. Each app's blueprint is regenerated, updating its Authentik provider
+//     and stored secret, and the app is restarted so its prestart hook
+//     writes the new secret into its config. The app is then marked done.
+//  3. Once every app is done the new version becomes current.
+//
+// An interrupted rotation stays in secrets.json; rotating again resumes it
+// with the apps that haven't moved yet.
+type SecretRotator struct {
+	blueprints BlueprintGeneratorInterface
+	state      SecretRotationStore
+	restarter  ServiceRestarter
+	logger     *slog.Logger
+}
+
+// NewSecretRotator creates a new per-app secret rotator
+func NewSecretRotator(blueprints BlueprintGeneratorInterface, state SecretRotationStore, restarter ServiceRestarter, logger *slog.Logger) *SecretRotator {
+	return &SecretRotator{
+		blueprints: blueprints,
+		state:      state,
+		restarter:  restarter,
+		logger:     logger,
+	}
+}
+
+// Rotate begins (or resumes) a rotation and runs it to completion.
+func (r *SecretRotator) Rotate(ctx context.Context, apps []*catalog.App) (*secrets.Rotation, error) {
+	rotation, _, err := r.Begin(apps)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Run(ctx, rotation, apps); err != nil {
+		return nil, err
+	}
+	return rotation, nil
+}
+
+// Begin records a rotation of the native OIDC apps among apps, or returns
+// the rotation already in progress with resumed set.
+func (r *SecretRotator) Begin(apps []*catalog.App) (rotation *secrets.Rotation, resumed bool, err error) {
+	var names []string
+	for _, app := range apps {
+		if app.SSO.Strategy == "native-oidc" {
+			names = append(names, app.Name)
+		}
+	}
+	sort.Strings(names)
+
+	rotation, resumed, err = r.state.BeginRotation(names)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record rotation: %w", err)
+	}
+	if resumed {
+		r.logger.Info("resuming secret rotation", "version", rotation.Version, "pending", rotation.Pending())
+	} else {
+		r.logger.Info("starting secret rotation", "version", rotation.Version, "apps", rotation.Apps)
+	}
+	return rotation, resumed, nil
+}
+
+// Run moves each pending app of the rotation to the new key version, then
+// makes the version current. Apps no longer in apps (uninstalled since the
+// rotation began) are marked done without changes.
+func (r *SecretRotator) Run(ctx context.Context, rotation *secrets.Rotation, apps []*catalog.App) error {
+	byName := make(map[string]*catalog.App, len(apps))
+	for _, app := range apps {
+		byName[app.Name] = app
+	}
+
+	for _, name := range rotation.Pending() {
+		if app, ok := byName[name]; ok {
+			if err := r.blueprints.GenerateForApp(app); err != nil {
+				return fmt.Errorf("failed to update provider for %s: %w", name, err)
+			}
+			if err := r.restarter.RestartUserService(ctx, name); err != nil {
+				return fmt.Errorf("failed to restart %s: %w", name, err)
+			}
+		}
+		if err := r.state.CompleteRotationApp(name); err != nil {
+			return fmt.Errorf("failed to record rotation of %s: %w", name, err)
+		}
+		r.logger.Info("rotated app secrets", "app", name, "version", rotation.Version)
+	}
+
+	if err := r.state.FinishRotation(); err != nil {
+		return fmt.Errorf("failed to finish rotation: %w", err)
+	}
+	r.logger.Info("secret rotation complete", "version", rotation.Version, "apps", rotation.Apps)
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/secrets"
+)
+
+// failingRestarter fails to restart the named app
+type failingRestarter struct {
+	fakeRestarter
+	fail string
+}
+
+func (f *failingRestarter) RestartUserService(ctx context.Context, appName string) error {
+	if appName == f.fail {
+		return errors.New("unit failed to start")
+	}
+	return f.fakeRestarter.RestartUserService(ctx, appName)
+}
+
+func newRotationTest(t *testing.T) (*secrets.Manager, *BlueprintGenerator, []*catalog.App) {
+	t.Helper()
+	dir := t.TempDir()
+	mgr := secrets.NewManager(filepath.Join(dir, "secrets.json"))
+	if err := mgr.Load(); err != nil {
+		t.Fatalf("failed to load secrets: %v", err)
+	}
+	gen := NewBlueprintGenerator(mgr.GetSSOHostSecret(), "test-ldap-password",
+		[]string{"http://localhost:8080"}, "http://localhost:8080", dir, mgr)
+
+	apps := []*catalog.App{
+		{Name: "miniflux", Port: 8085, SSO: catalog.SSO{Strategy: "native-oidc", CallbackPath: "/oauth2/oidc/callback"}},
+		{Name: "actual-budget", Port: 5006, SSO: catalog.SSO{Strategy: "native-oidc", CallbackPath: "/openid/callback"}},
+		{Name: "radarr", Port: 7878, SSO: catalog.SSO{Strategy: "forward-auth"}},
+	}
+	for _, app := range apps {
+		if err := gen.GenerateForApp(app); err != nil {
+			t.Fatalf("GenerateForApp(%s) failed: %v", app.Name, err)
+		}
+	}
+	return mgr, gen, apps
+}
+
+func TestSecretRotator_RotatesNativeOIDCApps(t *testing.T) {
+	mgr, gen, apps := newRotationTest(t)
+	before := mgr.GetAppSecret("miniflux", "oauthClientSecret")
+
+	restarter := &fakeRestarter{}
+	rotator := NewSecretRotator(gen, mgr, restarter, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rotation, err := rotator.Rotate(context.Background(), apps)
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+
+	if rotation.Version != 2 {
+		t.Errorf("expected version 2, got %d", rotation.Version)
+	}
+	if !slices.Equal(restarter.restarted, []string{"actual-budget", "miniflux"}) {
+		t.Errorf("expected only OIDC apps restarted, got %v", restarter.restarted)
+	}
+	if after := mgr.GetAppSecret("miniflux", "oauthClientSecret"); after == before || after == "" {
+		t.Errorf("expected a new client secret, got %q (was %q)", after, before)
+	}
+	if mgr.Rotation() != nil {
+		t.Error("expected rotation to be cleared")
+	}
+	if got := mgr.KeyVersion(); got != 2 {
+		t.Errorf("expected key version 2, got %d", got)
+	}
+}
+
+func TestSecretRotator_ResumesInterruptedRotation(t *testing.T) {
+	mgr, gen, apps := newRotationTest(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	failing := &failingRestarter{fail: "miniflux"}
+	if _, err := NewSecretRotator(gen, mgr, failing, logger).Rotate(context.Background(), apps); err == nil {
+		t.Fatal("expected rotation to fail")
+	}
+
+	rotation := mgr.Rotation()
+	if rotation == nil {
+		t.Fatal("expected interrupted rotation to be kept")
+	}
+	if !slices.Equal(rotation.Pending(), []string{"miniflux"}) {
+		t.Errorf("expected miniflux pending, got %v", rotation.Pending())
+	}
+
+	restarter := &fakeRestarter{}
+	rotator := NewSecretRotator(gen, mgr, restarter, logger)
+	_, resumed, err := rotator.Begin(apps)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if !resumed {
+		t.Error("expected the rotation to resume")
+	}
+	if _, err := rotator.Rotate(context.Background(), apps); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+
+	if !slices.Equal(restarter.restarted, []string{"miniflux"}) {
+		t.Errorf("expected only the pending app restarted, got %v", restarter.restarted)
+	}
+	if got := mgr.KeyVersion(); got != 2 {
+		t.Errorf("expected key version 2, got %d", got)
+	}
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StatusUninstalled is the status of an app that has been removed. Its row
+// is kept, marked with uninstalled_at, and it closes the app's status history.
+const StatusUninstalled = "uninstalled"
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// AppEvent records one status transition of an installed app
+type AppEvent struct {
+	ID         int64     `json:"id"`
+	App        string    `json:"app"`
+	FromStatus string    `json:"from_status"` // empty for a fresh install
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AppEventStore reads app status history. Transitions are written by
+// AppStore as statuses change.
+type AppEventStore struct {
+	db *sql.DB
+}
+
+// NewAppEventStore creates a new app event store
+func NewAppEventStore(db *sql.DB) *AppEventStore {
+	return &AppEventStore{db: db}
+}
+
+// History returns an app's transitions since the given time (zero for all
+// time), newest first. limit defaults to 100 and is capped at 1000.
+func (s *AppEventStore) History(appName string, since time.Time, limit int) ([]*AppEvent, error) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, app_name, from_status, to_status, reason, created_at
+		FROM app_events
+		WHERE app_name = $1 AND created_at >= $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`, appName, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query app history: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*AppEvent{}
+	for rows.Next() {
+		var e AppEvent
+		if err := rows.Scan(&e.ID, &e.App, &e.FromStatus, &e.ToStatus, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan app event: %w", err)
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// CountByStatus returns how many times an app entered each status since the
+// given time, e.g. {"error": 3} for an app that failed three times
+func (s *AppEventStore) CountByStatus(appName string, since time.Time) (map[string]int, error) {
+	rows, err := s.db.Query(`
+		SELECT to_status, COUNT(*)
+		FROM app_events
+		WHERE app_name = $1 AND created_at >= $2
+		GROUP BY to_status
+	`, appName, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count app transitions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan transition count: %w", err)
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
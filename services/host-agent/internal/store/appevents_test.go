@@ -0,0 +1,60 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppEventStore_History(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAppEventStore(db)
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := since.Add(time.Hour)
+
+	mock.ExpectQuery(`SELECT id, app_name, from_status, to_status, reason, created_at FROM app_events WHERE app_name = \$1 AND created_at >= \$2 ORDER BY created_at DESC, id DESC LIMIT \$3`).
+		WithArgs("radarr", since, defaultHistoryLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "app_name", "from_status", "to_status", "reason", "created_at"}).
+			AddRow(2, "radarr", "running", "error", "health check failed", at))
+
+	events, err := store.History("radarr", since, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, &AppEvent{ID: 2, App: "radarr", FromStatus: "running", ToStatus: "error", Reason: "health check failed", CreatedAt: at}, events[0])
+
+	mock.ExpectQuery(`SELECT .* FROM app_events`).
+		WithArgs("radarr", since, maxHistoryLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "app_name", "from_status", "to_status", "reason", "created_at"}))
+
+	events, err = store.History("radarr", since, 5000)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.NotNil(t, events, "empty history encodes as [] rather than null")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppEventStore_CountByStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAppEventStore(db)
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT to_status, COUNT\(\*\) FROM app_events WHERE app_name = \$1 AND created_at >= \$2 GROUP BY to_status`).
+		WithArgs("radarr", since).
+		WillReturnRows(sqlmock.NewRows([]string{"to_status", "count"}).AddRow("error", 3).AddRow("running", 4))
+
+	counts, err := store.CountByStatus("radarr", since)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"error": 3, "running": 4}, counts)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,25 +3,32 @@ package store
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
 
 // InstalledApp represents an app installed on this host
 type InstalledApp struct {
-	ID                int               `json:"id"`
-	Name              string            `json:"name"`
-	DisplayName       string            `json:"display_name"`
-	Version           string            `json:"version"`
-	Status            string            `json:"status"`
-	Port              int               `json:"port,omitempty"`
-	IsSystem          bool              `json:"is_system"`
-	IntegrationConfig map[string]string `json:"integration_config,omitempty"`
-	InstalledAt       time.Time         `json:"installed_at"`
-	UpdatedAt         time.Time         `json:"updated_at"`
+	ID                  int               `json:"id"`
+	Name                string            `json:"name"`
+	DisplayName         string            `json:"display_name"`
+	Version             string            `json:"version"`
+	Status              string            `json:"status"`
+	Port                int               `json:"port,omitempty"`
+	IsSystem            bool              `json:"is_system"`
+	IntegrationConfig   map[string]string `json:"integration_config,omitempty"`
+	Image               string            `json:"image,omitempty"`                 // container image reference, e.g. docker.io/miniflux/miniflux:2.1
+	ImageDigest         string            `json:"image_digest,omitempty"`          // digest of the running image
+	PreviousImageDigest string            `json:"previous_image_digest,omitempty"` // digest the current one replaced
+	InstalledAt         time.Time         `json:"installed_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+	UninstalledAt       *time.Time        `json:"uninstalled_at,omitempty"` // set for previously installed apps
+	Usage               *AppUsage         `json:"usage,omitempty"`          // latest resource usage, not stored
 }
 
-// AppStore manages installed apps in the database
+// AppStore manages installed apps in the database. Uninstalled apps are kept,
+// marked with uninstalled_at, so their configuration can be restored.
 type AppStore struct {
 	db       *sql.DB
 	onChange func() // Called when app state changes
@@ -47,8 +54,9 @@ func (s *AppStore) notify() {
 // GetAll returns all installed apps
 func (s *AppStore) GetAll() ([]*InstalledApp, error) {
 	rows, err := s.db.Query(`
-		SELECT id, name, display_name, version, status, port, is_system, integration_config, installed_at, updated_at
+		SELECT id, name, display_name, version, status, port, is_system, integration_config, image, image_digest, previous_image_digest, installed_at, updated_at
 		FROM apps
+		WHERE uninstalled_at IS NULL
 		ORDER BY name
 	`)
 	if err != nil {
@@ -71,9 +79,9 @@ func (s *AppStore) GetAll() ([]*InstalledApp, error) {
 // GetByName returns an installed app by name
 func (s *AppStore) GetByName(name string) (*InstalledApp, error) {
 	row := s.db.QueryRow(`
-		SELECT id, name, display_name, version, status, port, is_system, integration_config, installed_at, updated_at
+		SELECT id, name, display_name, version, status, port, is_system, integration_config, image, image_digest, previous_image_digest, installed_at, updated_at
 		FROM apps
-		WHERE name = $1
+		WHERE name = $1 AND uninstalled_at IS NULL
 	`, name)
 
 	app, err := s.scanAppRow(row)
@@ -89,7 +97,7 @@ func (s *AppStore) GetByName(name string) (*InstalledApp, error) {
 
 // GetInstalledNames returns just the names of installed apps
 func (s *AppStore) GetInstalledNames() ([]string, error) {
-	rows, err := s.db.Query("SELECT name FROM apps ORDER BY name")
+	rows, err := s.db.Query("SELECT name FROM apps WHERE uninstalled_at IS NULL ORDER BY name")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query app names: %w", err)
 	}
@@ -113,23 +121,73 @@ type InstallOptions struct {
 	IsSystem bool
 }
 
-// Install records a new app installation (or re-install)
+// AppInstall describes one app recorded by InstallAll
+type AppInstall struct {
+	Name              string
+	DisplayName       string
+	Version           string
+	IntegrationConfig map[string]string
+	Options           *InstallOptions
+}
+
+// Install records a new app installation (or re-install). Re-installing an
+// uninstalled app clears its uninstalled mark; the caller decides whether to
+// pass its previous integration config.
 func (s *AppStore) Install(name, displayName, version string, integrationConfig map[string]string, opts *InstallOptions) error {
-	configJSON, err := json.Marshal(integrationConfig)
+	return s.InstallAll([]AppInstall{{
+		Name:              name,
+		DisplayName:       displayName,
+		Version:           version,
+		IntegrationConfig: integrationConfig,
+		Options:           opts,
+	}})
+}
+
+// InstallAll records several app installations in one transaction, so an
+// app and the dependencies installed with it are either all recorded or
+// none are.
+func (s *AppStore) InstallAll(apps []AppInstall) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, app := range apps {
+		if err := installTx(tx, app); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit install: %w", err)
+	}
+
+	s.notify()
+	return nil
+}
+
+// installTx upserts an app as installing within tx
+func installTx(tx *sql.Tx, app AppInstall) error {
+	configJSON, err := json.Marshal(app.IntegrationConfig)
 	if err != nil {
 		return fmt.Errorf("failed to marshal integration config: %w", err)
 	}
 
 	var port sql.NullInt64
 	var isSystem bool
-	if opts != nil {
-		if opts.Port > 0 {
-			port = sql.NullInt64{Int64: int64(opts.Port), Valid: true}
+	if app.Options != nil {
+		if app.Options.Port > 0 {
+			port = sql.NullInt64{Int64: int64(app.Options.Port), Valid: true}
 		}
-		isSystem = opts.IsSystem
+		isSystem = app.Options.IsSystem
+	}
+
+	prev, err := lockStatus(tx, app.Name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read app status: %w", err)
 	}
 
-	_, err = s.db.Exec(`
+	_, err = tx.Exec(`
 		INSERT INTO apps (name, display_name, version, status, port, is_system, integration_config)
 		VALUES ($1, $2, $3, 'installing', $4, $5, $6)
 		ON CONFLICT(name) DO UPDATE SET
@@ -139,35 +197,80 @@ func (s *AppStore) Install(name, displayName, version string, integrationConfig
 			port = excluded.port,
 			is_system = excluded.is_system,
 			integration_config = excluded.integration_config,
+			installed_at = CASE WHEN apps.uninstalled_at IS NULL THEN apps.installed_at ELSE CURRENT_TIMESTAMP END,
+			uninstalled_at = NULL,
 			updated_at = CURRENT_TIMESTAMP
-	`, name, displayName, version, port, isSystem, string(configJSON))
+	`, app.Name, app.DisplayName, app.Version, port, isSystem, string(configJSON))
 	if err != nil {
-		return fmt.Errorf("failed to insert app: %w", err)
+		return fmt.Errorf("failed to insert app %s: %w", app.Name, err)
 	}
-
-	s.notify()
-	return nil
+	return recordTransition(tx, app.Name, prev, "installing", "")
 }
 
 // UpdateStatus updates the status of an installed app
 func (s *AppStore) UpdateStatus(name, status string) error {
-	result, err := s.db.Exec(`
-		UPDATE apps SET status = $1, updated_at = CURRENT_TIMESTAMP
-		WHERE name = $2
-	`, status, name)
+	return s.UpdateStatusReason(name, status, "")
+}
+
+// UpdateStatusReason updates the status of an installed app, recording why
+// in the app's status history
+func (s *AppStore) UpdateStatusReason(name, status, reason string) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to update status: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
+	prev, err := lockStatus(tx, name)
+	if errors.Is(err, sql.ErrNoRows) || prev == StatusUninstalled {
 		return fmt.Errorf("app not found: %s", name)
 	}
+	if err != nil {
+		return fmt.Errorf("failed to read app status: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE apps SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE name = $2
+	`, status, name); err != nil {
+		return fmt.Errorf("failed to update status: %w", err)
+	}
+	if err := recordTransition(tx, name, prev, status, reason); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit status update: %w", err)
+	}
 
 	s.notify()
 	return nil
 }
 
+// lockStatus reads an app's current status, locking its row until the
+// transaction ends so concurrent updates record transitions in order.
+// Uninstalled apps report StatusUninstalled.
+func lockStatus(tx *sql.Tx, name string) (string, error) {
+	var status string
+	err := tx.QueryRow(`SELECT status FROM apps WHERE name = $1 FOR UPDATE`, name).Scan(&status)
+	return status, err
+}
+
+// recordTransition appends to an app's status history. Re-setting the
+// current status is not a transition and is skipped.
+func recordTransition(tx *sql.Tx, name, from, to, reason string) error {
+	if from == to {
+		return nil
+	}
+	_, err := tx.Exec(`
+		INSERT INTO app_events (app_name, from_status, to_status, reason)
+		VALUES ($1, $2, $3, $4)
+	`, name, from, to, reason)
+	if err != nil {
+		return fmt.Errorf("failed to record status transition: %w", err)
+	}
+	return nil
+}
+
 // EnsureSystemApp ensures a system app (managed by NixOS, not user-installed) is registered
 // System apps are marked with is_system=true and their status is set to "running"
 // This is idempotent - it creates or updates the app entry
@@ -180,6 +283,8 @@ func (s *AppStore) EnsureSystemApp(name, displayName string, port int) error {
 			status = 'running',
 			port = excluded.port,
 			is_system = true,
+			installed_at = CASE WHEN apps.uninstalled_at IS NULL THEN apps.installed_at ELSE CURRENT_TIMESTAMP END,
+			uninstalled_at = NULL,
 			updated_at = CURRENT_TIMESTAMP
 	`, name, displayName, port)
 	if err != nil {
@@ -194,7 +299,7 @@ func (s *AppStore) EnsureSystemApp(name, displayName string, port int) error {
 func (s *AppStore) UpdateDisplayName(name, displayName string) error {
 	result, err := s.db.Exec(`
 		UPDATE apps SET display_name = $1, updated_at = CURRENT_TIMESTAMP
-		WHERE name = $2
+		WHERE name = $2 AND uninstalled_at IS NULL
 	`, displayName, name)
 	if err != nil {
 		return fmt.Errorf("failed to update display name: %w", err)
@@ -218,7 +323,7 @@ func (s *AppStore) UpdateIntegrationConfig(name string, config map[string]string
 
 	result, err := s.db.Exec(`
 		UPDATE apps SET integration_config = $1, updated_at = CURRENT_TIMESTAMP
-		WHERE name = $2
+		WHERE name = $2 AND uninstalled_at IS NULL
 	`, string(configJSON), name)
 	if err != nil {
 		return fmt.Errorf("failed to update integration config: %w", err)
@@ -232,11 +337,22 @@ func (s *AppStore) UpdateIntegrationConfig(name string, config map[string]string
 	return nil
 }
 
-// Uninstall removes an app from the database
-func (s *AppStore) Uninstall(name string) error {
-	result, err := s.db.Exec("DELETE FROM apps WHERE name = $1", name)
+// UpdateImage records the container image an app is running. When the
+// digest changes, the old one is kept as the previous digest.
+func (s *AppStore) UpdateImage(name, image, digest string) error {
+	result, err := s.db.Exec(`
+		UPDATE apps SET
+			previous_image_digest = CASE
+				WHEN image_digest <> '' AND image_digest <> $2 THEN image_digest
+				ELSE previous_image_digest
+			END,
+			image = $1,
+			image_digest = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE name = $3 AND uninstalled_at IS NULL
+	`, image, digest, name)
 	if err != nil {
-		return fmt.Errorf("failed to delete app: %w", err)
+		return fmt.Errorf("failed to update image: %w", err)
 	}
 
 	rows, _ := result.RowsAffected()
@@ -248,16 +364,94 @@ func (s *AppStore) Uninstall(name string) error {
 	return nil
 }
 
+// Uninstall marks an app as uninstalled, keeping its integration config and
+// settings for a later restore
+func (s *AppStore) Uninstall(name string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	prev, err := lockStatus(tx, name)
+	if errors.Is(err, sql.ErrNoRows) || prev == StatusUninstalled {
+		return fmt.Errorf("app not found: %s", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read app status: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE apps SET status = $1, uninstalled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE name = $2
+	`, StatusUninstalled, name); err != nil {
+		return fmt.Errorf("failed to mark app uninstalled: %w", err)
+	}
+	if err := recordTransition(tx, name, prev, StatusUninstalled, ""); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit uninstall: %w", err)
+	}
+
+	s.notify()
+	return nil
+}
+
 // IsInstalled checks if an app is installed
 func (s *AppStore) IsInstalled(name string) (bool, error) {
 	var count int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM apps WHERE name = $1", name).Scan(&count)
+	err := s.db.QueryRow("SELECT COUNT(*) FROM apps WHERE name = $1 AND uninstalled_at IS NULL", name).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to check if installed: %w", err)
 	}
 	return count > 0, nil
 }
 
+// GetUninstalled returns previously installed apps, most recently uninstalled first
+func (s *AppStore) GetUninstalled() ([]*InstalledApp, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, display_name, version, status, port, is_system, integration_config, image, image_digest, previous_image_digest, installed_at, updated_at, uninstalled_at
+		FROM apps
+		WHERE uninstalled_at IS NOT NULL
+		ORDER BY uninstalled_at DESC, name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query uninstalled apps: %w", err)
+	}
+	defer rows.Close()
+
+	apps := []*InstalledApp{}
+	for rows.Next() {
+		app, err := s.scanUninstalledApp(rows)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+
+	return apps, rows.Err()
+}
+
+// GetUninstalledByName returns a previously installed app, or nil if the app
+// was never installed or is installed now
+func (s *AppStore) GetUninstalledByName(name string) (*InstalledApp, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, display_name, version, status, port, is_system, integration_config, image, image_digest, previous_image_digest, installed_at, updated_at, uninstalled_at
+		FROM apps
+		WHERE name = $1 AND uninstalled_at IS NOT NULL
+	`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uninstalled app: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return s.scanUninstalledApp(rows)
+}
+
 func (s *AppStore) scanApp(rows *sql.Rows) (*InstalledApp, error) {
 	var app InstalledApp
 	var port sql.NullInt64
@@ -272,6 +466,9 @@ func (s *AppStore) scanApp(rows *sql.Rows) (*InstalledApp, error) {
 		&port,
 		&app.IsSystem,
 		&configJSON,
+		&app.Image,
+		&app.ImageDigest,
+		&app.PreviousImageDigest,
 		&app.InstalledAt,
 		&app.UpdatedAt,
 	)
@@ -306,6 +503,9 @@ func (s *AppStore) scanAppRow(row *sql.Row) (*InstalledApp, error) {
 		&port,
 		&app.IsSystem,
 		&configJSON,
+		&app.Image,
+		&app.ImageDigest,
+		&app.PreviousImageDigest,
 		&app.InstalledAt,
 		&app.UpdatedAt,
 	)
@@ -325,3 +525,45 @@ func (s *AppStore) scanAppRow(row *sql.Row) (*InstalledApp, error) {
 
 	return &app, nil
 }
+
+func (s *AppStore) scanUninstalledApp(rows *sql.Rows) (*InstalledApp, error) {
+	var app InstalledApp
+	var port sql.NullInt64
+	var configJSON sql.NullString
+	var uninstalledAt sql.NullTime
+
+	err := rows.Scan(
+		&app.ID,
+		&app.Name,
+		&app.DisplayName,
+		&app.Version,
+		&app.Status,
+		&port,
+		&app.IsSystem,
+		&configJSON,
+		&app.Image,
+		&app.ImageDigest,
+		&app.PreviousImageDigest,
+		&app.InstalledAt,
+		&app.UpdatedAt,
+		&uninstalledAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan app: %w", err)
+	}
+
+	if port.Valid {
+		app.Port = int(port.Int64)
+	}
+	if uninstalledAt.Valid {
+		app.UninstalledAt = &uninstalledAt.Time
+	}
+
+	if configJSON.Valid && configJSON.String != "" {
+		if err := json.Unmarshal([]byte(configJSON.String), &app.IntegrationConfig); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal integration config: %w", err)
+		}
+	}
+
+	return &app, nil
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -17,9 +18,17 @@ func TestAppStore_Install(t *testing.T) {
 
 	store := NewAppStore(db)
 
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM apps WHERE name = \$1 FOR UPDATE`).
+		WithArgs("radarr").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
 	mock.ExpectExec(`INSERT INTO apps`).
 		WithArgs("radarr", "Radarr", "5.0.0", sql.NullInt64{Int64: 7878, Valid: true}, false, `{"downloadClient":"qbittorrent"}`).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO app_events`).
+		WithArgs("radarr", "", "installing", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	err = store.Install("radarr", "Radarr", "5.0.0", map[string]string{
 		"downloadClient": "qbittorrent",
@@ -36,9 +45,14 @@ func TestAppStore_Install_SystemApp(t *testing.T) {
 
 	store := NewAppStore(db)
 
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM apps WHERE name = \$1 FOR UPDATE`).
+		WithArgs("postgres").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("installing"))
 	mock.ExpectExec(`INSERT INTO apps`).
 		WithArgs("postgres", "PostgreSQL", "16.0", sql.NullInt64{Int64: 5432, Valid: true}, true, `null`).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit() // already installing: no transition recorded
 
 	err = store.Install("postgres", "PostgreSQL", "16.0", nil, &InstallOptions{
 		Port:     5432,
@@ -58,8 +72,8 @@ func TestAppStore_GetByName(t *testing.T) {
 
 	now := time.Now()
 	rows := sqlmock.NewRows([]string{
-		"id", "name", "display_name", "version", "status", "port", "is_system", "integration_config", "installed_at", "updated_at",
-	}).AddRow(1, "radarr", "Radarr", "5.0.0", "running", 7878, false, `{"downloadClient":"qbittorrent"}`, now, now)
+		"id", "name", "display_name", "version", "status", "port", "is_system", "integration_config", "image", "image_digest", "previous_image_digest", "installed_at", "updated_at",
+	}).AddRow(1, "radarr", "Radarr", "5.0.0", "running", 7878, false, `{"downloadClient":"qbittorrent"}`, "lscr.io/linuxserver/radarr:5.0.0", "sha256:bbb", "sha256:aaa", now, now)
 
 	mock.ExpectQuery(`SELECT .+ FROM apps WHERE name = \$1`).
 		WithArgs("radarr").
@@ -76,6 +90,31 @@ func TestAppStore_GetByName(t *testing.T) {
 	assert.Equal(t, 7878, app.Port)
 	assert.False(t, app.IsSystem)
 	assert.Equal(t, "qbittorrent", app.IntegrationConfig["downloadClient"])
+	assert.Equal(t, "lscr.io/linuxserver/radarr:5.0.0", app.Image)
+	assert.Equal(t, "sha256:bbb", app.ImageDigest)
+	assert.Equal(t, "sha256:aaa", app.PreviousImageDigest)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppStore_UpdateImage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAppStore(db)
+
+	mock.ExpectExec(`UPDATE apps SET\s+previous_image_digest = CASE`).
+		WithArgs("lscr.io/linuxserver/radarr:5.1.0", "sha256:ccc", "radarr").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, store.UpdateImage("radarr", "lscr.io/linuxserver/radarr:5.1.0", "sha256:ccc"))
+
+	mock.ExpectExec(`UPDATE apps SET`).
+		WithArgs("img", "sha256:ddd", "missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.ErrorContains(t, store.UpdateImage("missing", "img", "sha256:ddd"), "app not found")
 
 	require.NoError(t, mock.ExpectationsWereMet())
 }
@@ -98,6 +137,41 @@ func TestAppStore_GetByName_NotFound(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestAppStore_InstallAll_RollsBackOnFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAppStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM apps WHERE name = \$1 FOR UPDATE`).
+		WithArgs("miniflux").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
+	mock.ExpectExec(`INSERT INTO apps`).
+		WithArgs("miniflux", "Miniflux", "", sql.NullInt64{}, false, `{"database":"postgres"}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO app_events`).
+		WithArgs("miniflux", "", "installing", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT status FROM apps WHERE name = \$1 FOR UPDATE`).
+		WithArgs("postgres").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
+	mock.ExpectExec(`INSERT INTO apps`).
+		WithArgs("postgres", "PostgreSQL", "", sql.NullInt64{}, false, `null`).
+		WillReturnError(errors.New("database locked"))
+	mock.ExpectRollback()
+
+	err = store.InstallAll([]AppInstall{
+		{Name: "miniflux", DisplayName: "Miniflux", IntegrationConfig: map[string]string{"database": "postgres"}},
+		{Name: "postgres", DisplayName: "PostgreSQL"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "postgres")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAppStore_GetInstalledNames(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -131,9 +205,17 @@ func TestAppStore_UpdateStatus(t *testing.T) {
 
 	store := NewAppStore(db)
 
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM apps WHERE name = \$1 FOR UPDATE`).
+		WithArgs("radarr").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("starting"))
 	mock.ExpectExec(`UPDATE apps SET status = \$1, updated_at = CURRENT_TIMESTAMP WHERE name = \$2`).
 		WithArgs("running", "radarr").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO app_events`).
+		WithArgs("radarr", "starting", "running", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	err = store.UpdateStatus("radarr", "running")
 	require.NoError(t, err)
@@ -141,6 +223,40 @@ func TestAppStore_UpdateStatus(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestAppStore_UpdateStatusReason(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAppStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM apps WHERE name = \$1 FOR UPDATE`).
+		WithArgs("radarr").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("running"))
+	mock.ExpectExec(`UPDATE apps SET status`).
+		WithArgs("error", "radarr").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO app_events`).
+		WithArgs("radarr", "running", "error", "health check failed").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, store.UpdateStatusReason("radarr", "error", "health check failed"))
+
+	// Unknown app: nothing is written
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM apps WHERE name = \$1 FOR UPDATE`).
+		WithArgs("sonarr").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
+	mock.ExpectRollback()
+
+	err = store.UpdateStatusReason("sonarr", "error", "")
+	assert.ErrorContains(t, err, "app not found")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAppStore_Uninstall(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -148,12 +264,84 @@ func TestAppStore_Uninstall(t *testing.T) {
 
 	store := NewAppStore(db)
 
-	mock.ExpectExec(`DELETE FROM apps WHERE name = \$1`).
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM apps WHERE name = \$1 FOR UPDATE`).
 		WithArgs("radarr").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("uninstalling"))
+	mock.ExpectExec(`UPDATE apps SET status = \$1, uninstalled_at = CURRENT_TIMESTAMP`).
+		WithArgs(StatusUninstalled, "radarr").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO app_events`).
+		WithArgs("radarr", "uninstalling", StatusUninstalled, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = store.Uninstall("radarr")
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppStore_Uninstall_AlreadyUninstalled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAppStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM apps WHERE name = \$1 FOR UPDATE`).
+		WithArgs("radarr").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(StatusUninstalled))
+	mock.ExpectRollback()
 
 	err = store.Uninstall("radarr")
+	assert.ErrorContains(t, err, "app not found")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppStore_GetUninstalled(t *testing.T) {
+	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAppStore(db)
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{
+		"id", "name", "display_name", "version", "status", "port", "is_system", "integration_config", "image", "image_digest", "previous_image_digest", "installed_at", "updated_at", "uninstalled_at",
+	}).AddRow(2, "radarr", "Radarr", "5.0.0", StatusUninstalled, 7878, false, `{"downloadClient":"qbittorrent"}`, "", "", "", now, now, now)
+
+	mock.ExpectQuery(`SELECT .+ FROM apps\s+WHERE uninstalled_at IS NOT NULL\s+ORDER BY uninstalled_at DESC`).
+		WillReturnRows(rows)
+
+	apps, err := store.GetUninstalled()
+	require.NoError(t, err)
+	require.Len(t, apps, 1)
+
+	assert.Equal(t, "radarr", apps[0].Name)
+	assert.Equal(t, "qbittorrent", apps[0].IntegrationConfig["downloadClient"])
+	require.NotNil(t, apps[0].UninstalledAt)
+	assert.Equal(t, now, *apps[0].UninstalledAt)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAppStore_GetUninstalledByName_NotUninstalled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAppStore(db)
+
+	mock.ExpectQuery(`SELECT .+ FROM apps\s+WHERE name = \$1 AND uninstalled_at IS NOT NULL`).
+		WithArgs("radarr").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	app, err := store.GetUninstalledByName("radarr")
+	require.NoError(t, err)
+	assert.Nil(t, app)
 
 	require.NoError(t, mock.ExpectationsWereMet())
 }
@@ -239,12 +427,12 @@ func TestAppStore_GetAll(t *testing.T) {
 
 	now := time.Now()
 	rows := sqlmock.NewRows([]string{
-		"id", "name", "display_name", "version", "status", "port", "is_system", "integration_config", "installed_at", "updated_at",
+		"id", "name", "display_name", "version", "status", "port", "is_system", "integration_config", "image", "image_digest", "previous_image_digest", "installed_at", "updated_at",
 	}).
-		AddRow(1, "postgres", "PostgreSQL", "16.0", "running", 5432, true, `{}`, now, now).
-		AddRow(2, "radarr", "Radarr", "5.0.0", "running", 7878, false, `{"downloadClient":"qbittorrent"}`, now, now)
+		AddRow(1, "postgres", "PostgreSQL", "16.0", "running", 5432, true, `{}`, "", "", "", now, now).
+		AddRow(2, "radarr", "Radarr", "5.0.0", "running", 7878, false, `{"downloadClient":"qbittorrent"}`, "", "", "", now, now)
 
-	mock.ExpectQuery(`SELECT .+ FROM apps ORDER BY name`).
+	mock.ExpectQuery(`SELECT .+ FROM apps WHERE uninstalled_at IS NULL ORDER BY name`).
 		WillReturnRows(rows)
 
 	apps, err := store.GetAll()
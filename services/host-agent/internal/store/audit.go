@@ -0,0 +1,128 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditEntry records one state-changing API request
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"` // empty for unauthenticated requests
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Route      string    `json:"route"`   // chi route pattern, e.g. /api/apps/{name}/install
+	Summary    string    `json:"summary"` // redacted, truncated request body
+	Status     int       `json:"status"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditFilter narrows an audit log query. Zero values match everything.
+type AuditFilter struct {
+	Username   string
+	Method     string
+	PathPrefix string
+	Failed     *bool // true: status >= 400, false: status < 400
+	Since      time.Time
+	Until      time.Time
+	Limit      int // defaults to 100, capped at 1000
+}
+
+// AuditStore manages the audit log in the database
+type AuditStore struct {
+	db *sql.DB
+}
+
+// NewAuditStore creates a new audit store
+func NewAuditStore(db *sql.DB) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+// Record appends an entry to the audit log
+func (s *AuditStore) Record(entry *AuditEntry) error {
+	_, err := s.db.Exec(`
+		INSERT INTO audit_log (username, method, path, route, summary, status, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, entry.Username, entry.Method, entry.Path, entry.Route, entry.Summary, entry.Status, entry.DurationMs)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// List returns entries matching filter, newest first
+func (s *AuditStore) List(filter AuditFilter) ([]*AuditEntry, error) {
+	var where []string
+	var args []any
+	add := func(clause string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.Username != "" {
+		add("username = $%d", filter.Username)
+	}
+	if filter.Method != "" {
+		add("method = $%d", strings.ToUpper(filter.Method))
+	}
+	if filter.PathPrefix != "" {
+		add("path LIKE $%d", escapeLike(filter.PathPrefix)+"%")
+	}
+	if filter.Failed != nil {
+		if *filter.Failed {
+			where = append(where, "status >= 400")
+		} else {
+			where = append(where, "status < 400")
+		}
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+
+	query := "SELECT id, username, method, path, route, summary, status, duration_ms, created_at FROM audit_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Username, &e.Method, &e.Path, &e.Route, &e.Summary, &e.Status, &e.DurationMs, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards so a prefix matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditStore_Record(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAuditStore(db)
+
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs("alice", "POST", "/api/apps/radarr/uninstall", "/api/apps/{name}/uninstall", `{"clearData":true}`, 200, int64(42)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = store.Record(&AuditEntry{
+		Username:   "alice",
+		Method:     "POST",
+		Path:       "/api/apps/radarr/uninstall",
+		Route:      "/api/apps/{name}/uninstall",
+		Summary:    `{"clearData":true}`,
+		Status:     200,
+		DurationMs: 42,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditStore_List_Filters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAuditStore(db)
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	failed := true
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT .+ FROM audit_log WHERE username = \$1 AND method = \$2 AND path LIKE \$3 AND status >= 400 AND created_at >= \$4 ORDER BY created_at DESC, id DESC LIMIT \$5`).
+		WithArgs("alice", "POST", `/api/apps/my\_app%`, since, 1000).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "username", "method", "path", "route", "summary", "status", "duration_ms", "created_at",
+		}).AddRow(7, "alice", "POST", "/api/apps/my_app/install", "/api/apps/{name}/install", "", 500, 1200, now))
+
+	entries, err := store.List(AuditFilter{
+		Username:   "alice",
+		Method:     "post",
+		PathPrefix: "/api/apps/my_app",
+		Failed:     &failed,
+		Since:      since,
+		Limit:      5000,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 500, entries[0].Status)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditStore_List_Defaults(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewAuditStore(db)
+
+	mock.ExpectQuery(`SELECT .+ FROM audit_log ORDER BY created_at DESC, id DESC LIMIT \$1`).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "username", "method", "path", "route", "summary", "status", "duration_ms", "created_at",
+		}))
+
+	entries, err := store.List(AuditFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
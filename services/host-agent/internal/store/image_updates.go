@@ -0,0 +1,80 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ImageUpdate is the result of checking an app's image for updates
+type ImageUpdate struct {
+	App             string    `json:"app"`
+	Image           string    `json:"image"`                   // reference checked, e.g. docker.io/miniflux/miniflux:latest
+	CurrentDigest   string    `json:"current_digest"`          // digest the app runs
+	LatestDigest    string    `json:"latest_digest,omitempty"` // digest the reference points at now
+	UpdateAvailable bool      `json:"update_available"`
+	Pinned          bool      `json:"pinned"`          // held at a version by the catalog
+	Error           string    `json:"error,omitempty"` // why the check failed
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// ImageUpdateStore persists image update check results
+type ImageUpdateStore struct {
+	db *sql.DB
+}
+
+// NewImageUpdateStore creates a new image update store
+func NewImageUpdateStore(db *sql.DB) *ImageUpdateStore {
+	return &ImageUpdateStore{db: db}
+}
+
+// All returns every app's last check, by app name
+func (s *ImageUpdateStore) All() ([]ImageUpdate, error) {
+	rows, err := s.db.Query(`
+		SELECT app, image, current_digest, latest_digest, update_available, pinned, error, checked_at
+		FROM app_image_updates
+		ORDER BY app
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image updates: %w", err)
+	}
+	defer rows.Close()
+
+	var updates []ImageUpdate
+	for rows.Next() {
+		var u ImageUpdate
+		if err := rows.Scan(&u.App, &u.Image, &u.CurrentDigest, &u.LatestDigest, &u.UpdateAvailable, &u.Pinned, &u.Error, &u.CheckedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan image update: %w", err)
+		}
+		updates = append(updates, u)
+	}
+	return updates, rows.Err()
+}
+
+// Save writes an app's check result
+func (s *ImageUpdateStore) Save(u ImageUpdate) error {
+	_, err := s.db.Exec(`
+		INSERT INTO app_image_updates (app, image, current_digest, latest_digest, update_available, pinned, error, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (app) DO UPDATE SET
+			image = EXCLUDED.image,
+			current_digest = EXCLUDED.current_digest,
+			latest_digest = EXCLUDED.latest_digest,
+			update_available = EXCLUDED.update_available,
+			pinned = EXCLUDED.pinned,
+			error = EXCLUDED.error,
+			checked_at = EXCLUDED.checked_at
+	`, u.App, u.Image, u.CurrentDigest, u.LatestDigest, u.UpdateAvailable, u.Pinned, u.Error, u.CheckedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save image update: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an app's check result
+func (s *ImageUpdateStore) Delete(app string) error {
+	if _, err := s.db.Exec(`DELETE FROM app_image_updates WHERE app = $1`, app); err != nil {
+		return fmt.Errorf("failed to delete image update: %w", err)
+	}
+	return nil
+}
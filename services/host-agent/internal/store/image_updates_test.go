@@ -0,0 +1,41 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageUpdateStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewImageUpdateStore(db)
+	at := time.Date(2026, 3, 1, 3, 12, 0, 0, time.UTC)
+
+	mock.ExpectExec(`INSERT INTO app_image_updates .* ON CONFLICT \(app\) DO UPDATE`).
+		WithArgs("miniflux", "docker.io/miniflux/miniflux:latest", "sha256:old", "sha256:new", true, false, "", at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.Save(ImageUpdate{
+		App: "miniflux", Image: "docker.io/miniflux/miniflux:latest",
+		CurrentDigest: "sha256:old", LatestDigest: "sha256:new", UpdateAvailable: true, CheckedAt: at,
+	}))
+
+	mock.ExpectQuery(`SELECT app, image, current_digest, latest_digest, update_available, pinned, error, checked_at FROM app_image_updates`).
+		WillReturnRows(sqlmock.NewRows([]string{"app", "image", "current_digest", "latest_digest", "update_available", "pinned", "error", "checked_at"}).
+			AddRow("miniflux", "docker.io/miniflux/miniflux:latest", "sha256:old", "sha256:new", true, false, "", at))
+	updates, err := store.All()
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	assert.True(t, updates[0].UpdateAvailable)
+	assert.Equal(t, at, updates[0].CheckedAt)
+
+	mock.ExpectExec(`DELETE FROM app_image_updates WHERE app = \$1`).WithArgs("miniflux").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.Delete("miniflux"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,5 +1,7 @@
 package store
 
+import "time"
+
 // AppStoreInterface defines the interface for managing installed apps.
 // This interface enables mocking for testing.
 type AppStoreInterface interface {
@@ -15,9 +17,15 @@ type AppStoreInterface interface {
 	// Install records a new app installation (or re-install)
 	Install(name, displayName, version string, integrationConfig map[string]string, opts *InstallOptions) error
 
+	// InstallAll records several app installations atomically
+	InstallAll(apps []AppInstall) error
+
 	// UpdateStatus updates the status of an installed app
 	UpdateStatus(name, status string) error
 
+	// UpdateStatusReason updates the status of an installed app, recording why in its history
+	UpdateStatusReason(name, status, reason string) error
+
 	// EnsureSystemApp ensures a system app (managed by NixOS) is registered with running status
 	EnsureSystemApp(name, displayName string, port int) error
 
@@ -27,9 +35,18 @@ type AppStoreInterface interface {
 	// UpdateDisplayName updates the display name of an installed app
 	UpdateDisplayName(name, displayName string) error
 
-	// Uninstall removes an app from the database
+	// UpdateImage records the container image and digest an app is running
+	UpdateImage(name, image, digest string) error
+
+	// Uninstall marks an app as uninstalled, keeping its configuration
 	Uninstall(name string) error
 
+	// GetUninstalled returns previously installed apps, most recently uninstalled first
+	GetUninstalled() ([]*InstalledApp, error)
+
+	// GetUninstalledByName returns a previously installed app, or nil
+	GetUninstalledByName(name string) (*InstalledApp, error)
+
 	// IsInstalled checks if an app is installed
 	IsInstalled(name string) (bool, error)
 
@@ -39,3 +56,177 @@ type AppStoreInterface interface {
 
 // Compile-time assertion that AppStore implements AppStoreInterface
 var _ AppStoreInterface = (*AppStore)(nil)
+
+// TokenStoreInterface defines the interface for managing personal access tokens.
+// This interface enables mocking for testing.
+type TokenStoreInterface interface {
+	// Create mints a token for a user and returns its plaintext value
+	Create(userID, name string, scopes []string, expiresAt time.Time) (*APIToken, string, error)
+
+	// List returns a user's tokens, newest first
+	List(userID string) ([]*APIToken, error)
+
+	// Delete revokes one of a user's tokens, reporting whether it existed
+	Delete(userID, tokenID string) (bool, error)
+
+	// DeleteAll revokes every token of a user, by username, returning how many there were
+	DeleteAll(username string) (int64, error)
+
+	// Authenticate resolves a plaintext token, returning nil if unknown or expired
+	Authenticate(plaintext string) (*APIToken, error)
+}
+
+// Compile-time assertion that TokenStore implements TokenStoreInterface
+var _ TokenStoreInterface = (*TokenStore)(nil)
+
+// AuditStoreInterface defines the interface for the audit log.
+// This interface enables mocking for testing.
+type AuditStoreInterface interface {
+	// Record appends an entry to the audit log
+	Record(entry *AuditEntry) error
+
+	// List returns entries matching filter, newest first
+	List(filter AuditFilter) ([]*AuditEntry, error)
+}
+
+// Compile-time assertion that AuditStore implements AuditStoreInterface
+var _ AuditStoreInterface = (*AuditStore)(nil)
+
+// SecretAccessStoreInterface defines the interface for the secret access log.
+// This interface enables mocking for testing.
+type SecretAccessStoreInterface interface {
+	// Record appends an entry to the secret access log
+	Record(entry *SecretAccessEntry) error
+
+	// List returns entries matching filter, newest first
+	List(filter SecretAccessFilter) ([]*SecretAccessEntry, error)
+}
+
+// Compile-time assertion that SecretAccessStore implements SecretAccessStoreInterface
+var _ SecretAccessStoreInterface = (*SecretAccessStore)(nil)
+
+// SettingsStoreInterface defines the interface for user-editable app settings.
+// This interface enables mocking for testing.
+type SettingsStoreInterface interface {
+	// Get returns the stored settings for an app (empty if none are stored)
+	Get(appName string) (map[string]any, error)
+
+	// Set replaces the stored settings for an app
+	Set(appName string, settings map[string]any) error
+}
+
+// Compile-time assertion that SettingsStore implements SettingsStoreInterface
+var _ SettingsStoreInterface = (*SettingsStore)(nil)
+
+// AppEventStoreInterface defines the interface for reading app status history.
+// This interface enables mocking for testing.
+type AppEventStoreInterface interface {
+	// History returns an app's status transitions since a time, newest first
+	History(appName string, since time.Time, limit int) ([]*AppEvent, error)
+
+	// CountByStatus returns how many times an app entered each status since a time
+	CountByStatus(appName string, since time.Time) (map[string]int, error)
+}
+
+// Compile-time assertion that AppEventStore implements AppEventStoreInterface
+var _ AppEventStoreInterface = (*AppEventStore)(nil)
+
+// StatsStoreInterface defines the interface for the stats history.
+// This interface enables mocking for testing.
+type StatsStoreInterface interface {
+	// Record stores samples at the finest resolution
+	Record(samples []StatsSample) error
+
+	// Compact downsamples complete buckets into coarser tiers and prunes old samples
+	Compact(now time.Time) error
+
+	// History returns samples at a tier's resolution since a time, oldest first
+	History(app string, tier StatsTier, since time.Time) ([]StatsSample, error)
+}
+
+// Compile-time assertion that StatsStore implements StatsStoreInterface
+var _ StatsStoreInterface = (*StatsStore)(nil)
+
+// HealthCheckStoreInterface defines the interface for app health check history.
+// This interface enables mocking for testing.
+type HealthCheckStoreInterface interface {
+	// Record stores one health check result
+	Record(app string, healthy bool, at time.Time) error
+
+	// Checks returns an app's health checks since a time, oldest first
+	Checks(app string, since time.Time) ([]HealthCheck, error)
+
+	// Prune drops health checks older than UptimeRetention
+	Prune(now time.Time) error
+}
+
+// Compile-time assertion that HealthCheckStore implements HealthCheckStoreInterface
+var _ HealthCheckStoreInterface = (*HealthCheckStore)(nil)
+
+// ScheduleStoreInterface defines the interface for scheduled job state.
+// This interface enables mocking for testing.
+type ScheduleStoreInterface interface {
+	// All returns the state of every job that has one, by name
+	All() (map[string]ScheduleState, error)
+
+	// Save writes a job's state
+	Save(st ScheduleState) error
+}
+
+// Compile-time assertion that ScheduleStore implements ScheduleStoreInterface
+var _ ScheduleStoreInterface = (*ScheduleStore)(nil)
+
+// PreferencesStoreInterface defines the interface for per-user preferences.
+// This interface enables mocking for testing.
+type PreferencesStoreInterface interface {
+	// Get returns a user's preferences with defaults filled in (nil if the user doesn't exist)
+	Get(userID string) (*Preferences, error)
+
+	// Set replaces a user's preferences
+	Set(userID string, prefs *Preferences) error
+}
+
+// Compile-time assertion that PreferencesStore implements PreferencesStoreInterface
+var _ PreferencesStoreInterface = (*PreferencesStore)(nil)
+
+// UpdateStoreInterface defines the interface for the automatic update
+// policy, state and available update. This interface enables mocking for testing.
+type UpdateStoreInterface interface {
+	// Policy returns the stored policy, or nil if it was never set
+	Policy() (*UpdatePolicy, error)
+
+	// SetPolicy stores the policy
+	SetPolicy(p UpdatePolicy) error
+
+	// State returns the outcome of the last update
+	State() (UpdateState, error)
+
+	// SaveState writes the outcome of the last update
+	SaveState(st UpdateState) error
+
+	// Available returns the update the last check found, or nil if there
+	// was none
+	Available() (*AvailableUpdate, error)
+
+	// SetAvailable stores the update the last check found; nil clears it
+	SetAvailable(a *AvailableUpdate) error
+}
+
+// Compile-time assertion that UpdateStore implements UpdateStoreInterface
+var _ UpdateStoreInterface = (*UpdateStore)(nil)
+
+// ImageUpdateStoreInterface defines the interface for image update check
+// results. This interface enables mocking for testing.
+type ImageUpdateStoreInterface interface {
+	// All returns every app's last check, by app name
+	All() ([]ImageUpdate, error)
+
+	// Save writes an app's check result
+	Save(u ImageUpdate) error
+
+	// Delete removes an app's check result
+	Delete(app string) error
+}
+
+// Compile-time assertion that ImageUpdateStore implements ImageUpdateStoreInterface
+var _ ImageUpdateStoreInterface = (*ImageUpdateStore)(nil)
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Themes a user can choose
+const (
+	ThemeSystem = "system" // follow the OS / browser setting
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// Preferences are a user's UI settings, stored server-side so they follow
+// the user across browsers and devices
+type Preferences struct {
+	Layout     []GridElement `json:"layout"`
+	PinnedApps []string      `json:"pinned_apps"`
+	Theme      string        `json:"theme"`
+	Locale     string        `json:"locale,omitempty"` // BCP 47 tag, e.g. "en-US"; empty uses the browser's
+}
+
+// storedPreferences is the user_preferences document; the layout lives in
+// users.layout for compatibility with the layout endpoints
+type storedPreferences struct {
+	PinnedApps []string `json:"pinned_apps,omitempty"`
+	Theme      string   `json:"theme,omitempty"`
+	Locale     string   `json:"locale,omitempty"`
+}
+
+// PreferencesStore manages user preferences in the database
+type PreferencesStore struct {
+	db *sql.DB
+}
+
+// NewPreferencesStore creates a new preferences store
+func NewPreferencesStore(db *sql.DB) *PreferencesStore {
+	return &PreferencesStore{db: db}
+}
+
+// Get returns a user's preferences with defaults filled in, or nil if the
+// user doesn't exist
+func (s *PreferencesStore) Get(userID string) (*Preferences, error) {
+	var layoutJSON, prefsJSON []byte
+	err := s.db.QueryRow(`
+		SELECT COALESCE(u.layout, '[]'), COALESCE(p.preferences, '{}')
+		FROM users u
+		LEFT JOIN user_preferences p ON p.user_id = u.id
+		WHERE u.id = $1
+	`, userID).Scan(&layoutJSON, &prefsJSON)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	layout, err := parseLayout(layoutJSON)
+	if err != nil {
+		return nil, err
+	}
+	var stored storedPreferences
+	if err := json.Unmarshal(prefsJSON, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse preferences: %w", err)
+	}
+
+	prefs := &Preferences{
+		Layout:     layout,
+		PinnedApps: stored.PinnedApps,
+		Theme:      stored.Theme,
+		Locale:     stored.Locale,
+	}
+	if prefs.Layout == nil {
+		prefs.Layout = []GridElement{}
+	}
+	if prefs.PinnedApps == nil {
+		prefs.PinnedApps = []string{}
+	}
+	if prefs.Theme == "" {
+		prefs.Theme = ThemeSystem
+	}
+	return prefs, nil
+}
+
+// Set replaces a user's preferences
+func (s *PreferencesStore) Set(userID string, prefs *Preferences) error {
+	layoutJSON, err := json.Marshal(prefs.Layout)
+	if err != nil {
+		return fmt.Errorf("failed to marshal layout: %w", err)
+	}
+	prefsJSON, err := json.Marshal(storedPreferences{
+		PinnedApps: prefs.PinnedApps,
+		Theme:      prefs.Theme,
+		Locale:     prefs.Locale,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal preferences: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET layout = $1 WHERE id = $2", layoutJSON, userID); err != nil {
+		return fmt.Errorf("failed to update layout: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO user_preferences (user_id, preferences, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id) DO UPDATE SET
+			preferences = excluded.preferences,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, prefsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit preferences: %w", err)
+	}
+	return nil
+}
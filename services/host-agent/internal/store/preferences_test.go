@@ -0,0 +1,74 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferencesStore_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewPreferencesStore(db)
+
+	mock.ExpectQuery(`SELECT COALESCE\(u.layout, '\[\]'\), COALESCE\(p.preferences, '\{\}'\) FROM users u LEFT JOIN user_preferences p`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"layout", "preferences"}).
+			AddRow(`[{"type":"app","id":"miniflux","col":1,"row":1,"colspan":1,"rowspan":1}]`, `{"pinned_apps":["miniflux"],"theme":"dark","locale":"de-DE"}`))
+
+	prefs, err := store.Get("user-1")
+	require.NoError(t, err)
+	assert.Equal(t, &Preferences{
+		Layout:     []GridElement{{Type: "app", ID: "miniflux", Col: 1, Row: 1, Colspan: 1, Rowspan: 1}},
+		PinnedApps: []string{"miniflux"},
+		Theme:      ThemeDark,
+		Locale:     "de-DE",
+	}, prefs)
+
+	// A user who never saved preferences gets defaults
+	mock.ExpectQuery(`SELECT .* FROM users u`).
+		WithArgs("user-2").
+		WillReturnRows(sqlmock.NewRows([]string{"layout", "preferences"}).AddRow(`[]`, `{}`))
+
+	prefs, err = store.Get("user-2")
+	require.NoError(t, err)
+	assert.Equal(t, &Preferences{Layout: []GridElement{}, PinnedApps: []string{}, Theme: ThemeSystem}, prefs)
+
+	mock.ExpectQuery(`SELECT .* FROM users u`).
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"layout", "preferences"}))
+
+	prefs, err = store.Get("missing")
+	require.NoError(t, err)
+	assert.Nil(t, prefs)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPreferencesStore_Set(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewPreferencesStore(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET layout = \$1 WHERE id = \$2`).
+		WithArgs([]byte(`[]`), "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO user_preferences .* ON CONFLICT \(user_id\) DO UPDATE`).
+		WithArgs("user-1", []byte(`{"pinned_apps":["miniflux"],"theme":"light"}`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, store.Set("user-1", &Preferences{
+		Layout:     []GridElement{},
+		PinnedApps: []string{"miniflux"},
+		Theme:      ThemeLight,
+	}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// InstalledAppSortKeys are the fields installed app listings can be sorted by
+var InstalledAppSortKeys = []string{"name", "displayName", "status", "installedAt", "updatedAt"}
+
+// InstalledAppQuery filters and sorts installed apps. Zero values match everything.
+type InstalledAppQuery struct {
+	Status string
+	Search string // case-insensitive match on name and display name
+	System *bool  // filter system (infrastructure) apps in or out
+	Sort   string // one of InstalledAppSortKeys (default "name")
+	Desc   bool   // reverse the sort order
+}
+
+// Validate reports an unknown sort key
+func (q InstalledAppQuery) Validate() error {
+	if q.Sort != "" && !slices.Contains(InstalledAppSortKeys, q.Sort) {
+		return fmt.Errorf("unknown sort key %q (expected one of %s)", q.Sort, strings.Join(InstalledAppSortKeys, ", "))
+	}
+	return nil
+}
+
+// FilterInstalledApps returns the apps matching q, sorted. The input is not modified.
+func FilterInstalledApps(apps []*InstalledApp, q InstalledAppQuery) []*InstalledApp {
+	search := strings.ToLower(strings.TrimSpace(q.Search))
+
+	result := make([]*InstalledApp, 0, len(apps))
+	for _, app := range apps {
+		if q.Status != "" && app.Status != q.Status {
+			continue
+		}
+		if q.System != nil && app.IsSystem != *q.System {
+			continue
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(app.Name), search) &&
+			!strings.Contains(strings.ToLower(app.DisplayName), search) {
+			continue
+		}
+		result = append(result, app)
+	}
+
+	slices.SortStableFunc(result, func(a, b *InstalledApp) int {
+		var c int
+		switch q.Sort {
+		case "displayName":
+			c = strings.Compare(strings.ToLower(a.DisplayName), strings.ToLower(b.DisplayName))
+		case "status":
+			c = strings.Compare(a.Status, b.Status)
+		case "installedAt":
+			c = a.InstalledAt.Compare(b.InstalledAt)
+		case "updatedAt":
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		}
+		if c == 0 {
+			c = strings.Compare(a.Name, b.Name)
+		}
+		if q.Desc {
+			return -c
+		}
+		return c
+	})
+
+	return result
+}
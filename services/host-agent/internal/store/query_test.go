@@ -0,0 +1,33 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterInstalledApps(t *testing.T) {
+	now := time.Now()
+	apps := []*InstalledApp{
+		{Name: "sonarr", DisplayName: "Sonarr", Status: "running", InstalledAt: now.Add(-time.Hour)},
+		{Name: "postgres", DisplayName: "PostgreSQL", Status: "running", IsSystem: true, InstalledAt: now.Add(-2 * time.Hour)},
+		{Name: "radarr", DisplayName: "Movies", Status: "error", InstalledAt: now},
+	}
+	names := func(apps []*InstalledApp) []string {
+		var out []string
+		for _, app := range apps {
+			out = append(out, app.Name)
+		}
+		return out
+	}
+	noSystem := false
+
+	assert.Equal(t, []string{"postgres", "radarr", "sonarr"}, names(FilterInstalledApps(apps, InstalledAppQuery{})))
+	assert.Equal(t, []string{"postgres", "sonarr"}, names(FilterInstalledApps(apps, InstalledAppQuery{Status: "running"})))
+	assert.Equal(t, []string{"radarr", "sonarr"}, names(FilterInstalledApps(apps, InstalledAppQuery{System: &noSystem})))
+	assert.Equal(t, []string{"radarr"}, names(FilterInstalledApps(apps, InstalledAppQuery{Search: "movie"})))
+	assert.Equal(t, []string{"radarr", "sonarr", "postgres"}, names(FilterInstalledApps(apps, InstalledAppQuery{Sort: "installedAt", Desc: true})))
+
+	assert.Error(t, InstalledAppQuery{Sort: "size"}.Validate())
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ScheduleState is the persisted state of one scheduled job
+type ScheduleState struct {
+	Name         string
+	Spec         string // empty for the job's default schedule
+	Enabled      bool
+	LastRunAt    time.Time // zero if it never ran
+	LastDuration time.Duration
+	LastError    string
+}
+
+// ScheduleStore persists scheduled job settings and their last run
+type ScheduleStore struct {
+	db *sql.DB
+}
+
+// NewScheduleStore creates a new schedule store
+func NewScheduleStore(db *sql.DB) *ScheduleStore {
+	return &ScheduleStore{db: db}
+}
+
+// All returns the state of every job that has one, by name
+func (s *ScheduleStore) All() (map[string]ScheduleState, error) {
+	rows, err := s.db.Query(`SELECT name, spec, enabled, last_run_at, last_duration_ms, last_error FROM schedules`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]ScheduleState)
+	for rows.Next() {
+		var st ScheduleState
+		var lastRun sql.NullTime
+		var durationMs int64
+		if err := rows.Scan(&st.Name, &st.Spec, &st.Enabled, &lastRun, &durationMs, &st.LastError); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		if lastRun.Valid {
+			st.LastRunAt = lastRun.Time
+		}
+		st.LastDuration = time.Duration(durationMs) * time.Millisecond
+		states[st.Name] = st
+	}
+	return states, rows.Err()
+}
+
+// Save writes a job's state
+func (s *ScheduleStore) Save(st ScheduleState) error {
+	var lastRun sql.NullTime
+	if !st.LastRunAt.IsZero() {
+		lastRun = sql.NullTime{Time: st.LastRunAt.UTC(), Valid: true}
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO schedules (name, spec, enabled, last_run_at, last_duration_ms, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (name) DO UPDATE SET
+			spec = EXCLUDED.spec,
+			enabled = EXCLUDED.enabled,
+			last_run_at = EXCLUDED.last_run_at,
+			last_duration_ms = EXCLUDED.last_duration_ms,
+			last_error = EXCLUDED.last_error
+	`, st.Name, st.Spec, st.Enabled, lastRun, st.LastDuration.Milliseconds(), st.LastError)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+	return nil
+}
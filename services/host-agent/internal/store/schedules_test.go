@@ -0,0 +1,37 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewScheduleStore(db)
+	at := time.Date(2026, 3, 1, 3, 12, 0, 0, time.UTC)
+
+	mock.ExpectExec(`INSERT INTO schedules .* ON CONFLICT \(name\) DO UPDATE`).
+		WithArgs("backup", "0 4 * * *", true, sql.NullTime{Time: at, Valid: true}, int64(1500), "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.Save(ScheduleState{Name: "backup", Spec: "0 4 * * *", Enabled: true, LastRunAt: at, LastDuration: 1500 * time.Millisecond}))
+
+	mock.ExpectQuery(`SELECT name, spec, enabled, last_run_at, last_duration_ms, last_error FROM schedules`).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "spec", "enabled", "last_run_at", "last_duration_ms", "last_error"}).
+			AddRow("backup", "0 4 * * *", true, at, 1500, "").
+			AddRow("image-prune", "", false, nil, 0, ""))
+	states, err := store.All()
+	require.NoError(t, err)
+	assert.Equal(t, at, states["backup"].LastRunAt)
+	assert.Equal(t, 1500*time.Millisecond, states["backup"].LastDuration)
+	assert.True(t, states["image-prune"].LastRunAt.IsZero())
+	assert.False(t, states["image-prune"].Enabled)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
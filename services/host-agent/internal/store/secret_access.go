@@ -0,0 +1,117 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SecretAccessEntry records one read or write of a secret
+type SecretAccessEntry struct {
+	ID        int64     `json:"id"`
+	Component string    `json:"component"` // package that touched the secret, e.g. internal/api
+	Caller    string    `json:"caller"`    // function, e.g. api.(*Server).handleSetAppSecret
+	Action    string    `json:"action"`    // read, write or delete
+	App       string    `json:"app"`       // empty for deployment-wide secrets
+	Secret    string    `json:"secret"`    // e.g. postgresPassword, or * for all of them
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SecretAccessFilter narrows a secret access query. Zero values match everything.
+type SecretAccessFilter struct {
+	Component string
+	Action    string
+	App       string
+	Secret    string
+	Since     time.Time
+	Until     time.Time
+	Limit     int // defaults to 100, capped at 1000
+}
+
+// SecretAccessStore manages the append-only secret access log in the database
+type SecretAccessStore struct {
+	db *sql.DB
+}
+
+// NewSecretAccessStore creates a new secret access store
+func NewSecretAccessStore(db *sql.DB) *SecretAccessStore {
+	return &SecretAccessStore{db: db}
+}
+
+// Record appends an entry to the secret access log. CreatedAt is kept if set,
+// so accesses buffered before the database was up keep their time.
+func (s *SecretAccessStore) Record(entry *SecretAccessEntry) error {
+	createdAt := entry.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO secret_access_log (component, caller, action, app, secret, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, entry.Component, entry.Caller, entry.Action, entry.App, entry.Secret, createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to record secret access: %w", err)
+	}
+	return nil
+}
+
+// List returns entries matching filter, newest first
+func (s *SecretAccessStore) List(filter SecretAccessFilter) ([]*SecretAccessEntry, error) {
+	var where []string
+	var args []any
+	add := func(clause string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.Component != "" {
+		add("component = $%d", filter.Component)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.App != "" {
+		add("app = $%d", filter.App)
+	}
+	if filter.Secret != "" {
+		add("secret = $%d", filter.Secret)
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+
+	query := "SELECT id, component, caller, action, app, secret, created_at FROM secret_access_log"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query secret access log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*SecretAccessEntry{}
+	for rows.Next() {
+		var e SecretAccessEntry
+		if err := rows.Scan(&e.ID, &e.Component, &e.Caller, &e.Action, &e.App, &e.Secret, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan secret access entry: %w", err)
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
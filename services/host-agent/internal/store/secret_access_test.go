@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretAccessStore_Record(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewSecretAccessStore(db)
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(`INSERT INTO secret_access_log`).
+		WithArgs("internal/api", "api.(*Server).handleSetAppSecret", "write", "radarr", "apiKey", at).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = store.Record(&SecretAccessEntry{
+		Component: "internal/api",
+		Caller:    "api.(*Server).handleSetAppSecret",
+		Action:    "write",
+		App:       "radarr",
+		Secret:    "apiKey",
+		CreatedAt: at,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSecretAccessStore_List_Filters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewSecretAccessStore(db)
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT .+ FROM secret_access_log WHERE action = \$1 AND app = \$2 AND created_at >= \$3 ORDER BY created_at DESC, id DESC LIMIT \$4`).
+		WithArgs("read", "radarr", since, 100).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "component", "caller", "action", "app", "secret", "created_at",
+		}).AddRow(3, "internal/api", "api.(*Server).handleX", "read", "radarr", "apiKey", now))
+
+	entries, err := store.List(SecretAccessFilter{Action: "read", App: "radarr", Since: since})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "apiKey", entries[0].Secret)
+	assert.Equal(t, "internal/api", entries[0].Component)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
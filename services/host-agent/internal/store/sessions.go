@@ -3,9 +3,14 @@ package store
 import (
 	"context"
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -22,14 +27,21 @@ type Session struct {
 	ID        string    `json:"id"`
 	UserID    string    `json:"user_id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role,omitempty"` // RoleAdmin or RoleMember
 	CreatedAt time.Time `json:"created_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// SessionStore manages sessions in Redis
+// SessionStore manages sessions in Redis. With a database fallback it keeps
+// working while Redis is unavailable, storing sessions in the sessions table
+// until Recover moves them back.
 type SessionStore struct {
 	client *redis.Client
+	db     *sql.DB // fallback storage; nil for Redis only
 	ttl    time.Duration
+
+	mu       sync.RWMutex
+	degraded bool // Redis is unreachable and sessions go to the database
 }
 
 // NewSessionStore creates a new Redis-backed session store
@@ -52,8 +64,67 @@ func NewSessionStore(redisAddr string) (*SessionStore, error) {
 	}, nil
 }
 
+// NewFallbackSessionStore creates a session store that uses Redis when it's
+// reachable and the database otherwise. It starts degraded if Redis can't be
+// reached now.
+func NewFallbackSessionStore(redisAddr string, db *sql.DB) *SessionStore {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return &SessionStore{
+		client:   client,
+		db:       db,
+		ttl:      defaultSessionTTL,
+		degraded: client.Ping(ctx).Err() != nil,
+	}
+}
+
+// Client returns the underlying Redis client, for sharing the connection
+func (s *SessionStore) Client() *redis.Client {
+	return s.client
+}
+
+// Degraded reports whether sessions are currently stored in the database
+// because Redis is unavailable
+func (s *SessionStore) Degraded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.degraded
+}
+
+// usingRedis reports whether operations should go to Redis first
+func (s *SessionStore) usingRedis() bool {
+	return s.client != nil && !s.Degraded()
+}
+
+// failover switches to the database if err means Redis is unreachable,
+// reporting whether it did
+func (s *SessionStore) failover(err error) bool {
+	if s.db == nil || !redisUnavailable(err) {
+		return false
+	}
+	s.mu.Lock()
+	s.degraded = true
+	s.mu.Unlock()
+	return true
+}
+
+// redisUnavailable reports whether err is a connection failure rather than a
+// problem with the request itself
+func redisUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, redis.ErrClosed)
+}
+
 // Create creates a new session for a user
-func (s *SessionStore) Create(ctx context.Context, userID string, username string) (*Session, error) {
+func (s *SessionStore) Create(ctx context.Context, userID string, username string, role string) (*Session, error) {
 	sessionID, err := generateSessionID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate session ID: %w", err)
@@ -64,46 +135,54 @@ func (s *SessionStore) Create(ctx context.Context, userID string, username strin
 		ID:        sessionID,
 		UserID:    userID,
 		Username:  username,
+		Role:      role,
 		CreatedAt: now,
 		ExpiresAt: now.Add(s.ttl),
 	}
 
-	data, err := json.Marshal(session)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal session: %w", err)
+	if s.usingRedis() {
+		err := s.redisSet(ctx, session, s.ttl)
+		if !s.failover(err) {
+			if err != nil {
+				return nil, err
+			}
+			return session, nil
+		}
 	}
 
-	key := sessionPrefix + sessionID
-	if err := s.client.Set(ctx, key, data, s.ttl).Err(); err != nil {
-		return nil, fmt.Errorf("failed to store session: %w", err)
+	if err := s.dbSet(ctx, session); err != nil {
+		return nil, err
 	}
-
 	return session, nil
 }
 
 // Get retrieves a session by ID
 func (s *SessionStore) Get(ctx context.Context, sessionID string) (*Session, error) {
-	key := sessionPrefix + sessionID
-	data, err := s.client.Get(ctx, key).Bytes()
-	if err == redis.Nil {
-		return nil, nil // Session not found
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get session: %w", err)
-	}
-
-	var session Session
-	if err := json.Unmarshal(data, &session); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	if s.usingRedis() {
+		session, err := s.redisGet(ctx, sessionID)
+		if !s.failover(err) {
+			// Sessions created while Redis was down may not have moved back yet
+			if err != nil || session != nil || s.db == nil {
+				return session, err
+			}
+		}
 	}
-
-	return &session, nil
+	return s.dbGet(ctx, sessionID)
 }
 
 // Delete removes a session
 func (s *SessionStore) Delete(ctx context.Context, sessionID string) error {
-	key := sessionPrefix + sessionID
-	if err := s.client.Del(ctx, key).Err(); err != nil {
+	if s.usingRedis() {
+		key := sessionPrefix + sessionID
+		err := s.client.Del(ctx, key).Err()
+		if !s.failover(err) && err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+	if s.db == nil {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1", sessionID); err != nil {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 	return nil
@@ -111,6 +190,23 @@ func (s *SessionStore) Delete(ctx context.Context, sessionID string) error {
 
 // DeleteByUserID removes all sessions for a user
 func (s *SessionStore) DeleteByUserID(ctx context.Context, userID string) error {
+	if s.usingRedis() {
+		err := s.redisDeleteByUserID(ctx, userID)
+		if !s.failover(err) && err != nil {
+			return err
+		}
+	}
+	if s.db == nil {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
+
+// redisDeleteByUserID removes a user's sessions from Redis
+func (s *SessionStore) redisDeleteByUserID(ctx context.Context, userID string) error {
 	// Scan for all sessions and delete those belonging to this user
 	// This is O(n) but acceptable for session counts we expect
 	var cursor uint64
@@ -147,38 +243,172 @@ func (s *SessionStore) DeleteByUserID(ctx context.Context, userID string) error
 
 // Refresh extends a session's TTL
 func (s *SessionStore) Refresh(ctx context.Context, sessionID string) error {
-	key := sessionPrefix + sessionID
+	session, err := s.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return fmt.Errorf("session not found")
+	}
 
-	// Get current session
+	// Update expiry
+	session.ExpiresAt = time.Now().Add(s.ttl)
+
+	if s.usingRedis() {
+		err := s.redisSet(ctx, session, s.ttl)
+		if !s.failover(err) {
+			if err != nil {
+				return fmt.Errorf("failed to update session: %w", err)
+			}
+			if s.db != nil {
+				// The session now lives in Redis; drop any fallback copy
+				s.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1", sessionID)
+			}
+			return nil
+		}
+	}
+
+	if err := s.dbSet(ctx, session); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// Recover checks whether Redis is reachable again and, if so, leaves degraded
+// mode and moves sessions stored in the database back into Redis. It returns
+// how many sessions were moved.
+func (s *SessionStore) Recover(ctx context.Context) (int, error) {
+	if s.client == nil || s.db == nil {
+		return 0, nil
+	}
+
+	if s.Degraded() {
+		if err := s.client.Ping(ctx).Err(); err != nil {
+			return 0, fmt.Errorf("redis still unavailable: %w", err)
+		}
+		s.mu.Lock()
+		s.degraded = false
+		s.mu.Unlock()
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, username, role, created_at, expires_at
+		FROM sessions
+		WHERE expires_at > $1
+	`, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query fallback sessions: %w", err)
+	}
+	var sessions []*Session
+	for rows.Next() {
+		var session Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.Username, &session.Role, &session.CreatedAt, &session.ExpiresAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan fallback session: %w", err)
+		}
+		sessions = append(sessions, &session)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query fallback sessions: %w", err)
+	}
+
+	moved := 0
+	for _, session := range sessions {
+		ttl := time.Until(session.ExpiresAt)
+		if ttl <= 0 {
+			continue
+		}
+		if err := s.redisSet(ctx, session, ttl); err != nil {
+			s.failover(err)
+			return moved, err
+		}
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1", session.ID); err != nil {
+			return moved, fmt.Errorf("failed to delete fallback session: %w", err)
+		}
+		moved++
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= $1", time.Now().UTC()); err != nil {
+		return moved, fmt.Errorf("failed to prune fallback sessions: %w", err)
+	}
+	return moved, nil
+}
+
+// redisSet stores a session in Redis with the given TTL
+func (s *SessionStore) redisSet(ctx context.Context, session *Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	key := sessionPrefix + session.ID
+	if err := s.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	return nil
+}
+
+// redisGet retrieves a session from Redis, or nil if it doesn't exist
+func (s *SessionStore) redisGet(ctx context.Context, sessionID string) (*Session, error) {
+	key := sessionPrefix + sessionID
 	data, err := s.client.Get(ctx, key).Bytes()
 	if err == redis.Nil {
-		return fmt.Errorf("session not found")
+		return nil, nil // Session not found
 	}
 	if err != nil {
-		return fmt.Errorf("failed to get session: %w", err)
+		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
 	var session Session
 	if err := json.Unmarshal(data, &session); err != nil {
-		return fmt.Errorf("failed to unmarshal session: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
 	}
 
-	// Update expiry
-	session.ExpiresAt = time.Now().Add(s.ttl)
-	newData, err := json.Marshal(session)
+	return &session, nil
+}
+
+// dbSet stores or updates a session in the database
+func (s *SessionStore) dbSet(ctx context.Context, session *Session) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, username, role, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET expires_at = excluded.expires_at
+	`, session.ID, session.UserID, session.Username, session.Role, session.CreatedAt.UTC(), session.ExpiresAt.UTC())
 	if err != nil {
-		return fmt.Errorf("failed to marshal session: %w", err)
+		return fmt.Errorf("failed to store session: %w", err)
 	}
+	return nil
+}
 
-	if err := s.client.Set(ctx, key, newData, s.ttl).Err(); err != nil {
-		return fmt.Errorf("failed to update session: %w", err)
+// dbGet retrieves an unexpired session from the database, or nil
+func (s *SessionStore) dbGet(ctx context.Context, sessionID string) (*Session, error) {
+	if s.db == nil {
+		return nil, nil
 	}
 
-	return nil
+	var session Session
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, username, role, created_at, expires_at
+		FROM sessions
+		WHERE id = $1 AND expires_at > $2
+	`, sessionID, time.Now().UTC()).Scan(
+		&session.ID, &session.UserID, &session.Username, &session.Role, &session.CreatedAt, &session.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &session, nil
 }
 
 // Close closes the Redis connection
 func (s *SessionStore) Close() error {
+	if s.client == nil {
+		return nil
+	}
 	return s.client.Close()
 }
 
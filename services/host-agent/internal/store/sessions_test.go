@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableRedis is an address nothing listens on
+const unreachableRedis = "127.0.0.1:1"
+
+// degradedSessionStore returns a store that has already fallen back to db
+func degradedSessionStore(db *sql.DB) *SessionStore {
+	return &SessionStore{db: db, ttl: defaultSessionTTL, degraded: true}
+}
+
+var sessionColumns = []string{"id", "user_id", "username", "role", "created_at", "expires_at"}
+
+func TestSessionStore_FallsBackToDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sessions := degradedSessionStore(db)
+
+	ctx := context.Background()
+	mock.ExpectExec(`INSERT INTO sessions`).
+		WithArgs(sqlmock.AnyArg(), "user-1", "alice", RoleAdmin, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	session, err := sessions.Create(ctx, "user-1", "alice", RoleAdmin)
+	require.NoError(t, err)
+	assert.Len(t, session.ID, 64)
+
+	mock.ExpectQuery(`SELECT id, user_id, username, role, created_at, expires_at\s+FROM sessions\s+WHERE id = \$1`).
+		WithArgs(session.ID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).
+			AddRow(session.ID, "user-1", "alice", RoleAdmin, session.CreatedAt, session.ExpiresAt))
+
+	got, err := sessions.Get(ctx, session.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "alice", got.Username)
+	assert.Equal(t, RoleAdmin, got.Role)
+
+	mock.ExpectExec(`DELETE FROM sessions WHERE id = \$1`).
+		WithArgs(session.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, sessions.Delete(ctx, session.ID))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionStore_GetMissingFromDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sessions := degradedSessionStore(db)
+
+	mock.ExpectQuery(`FROM sessions`).
+		WithArgs("missing", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(sessionColumns))
+
+	got, err := sessions.Get(context.Background(), "missing")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionStore_RefreshInDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sessions := degradedSessionStore(db)
+
+	created := time.Now().Add(-time.Hour)
+	mock.ExpectQuery(`FROM sessions`).
+		WithArgs("sess-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).
+			AddRow("sess-1", "user-1", "alice", RoleMember, created, created.Add(time.Minute*90)))
+	mock.ExpectExec(`INSERT INTO sessions .* ON CONFLICT \(id\) DO UPDATE SET expires_at`).
+		WithArgs("sess-1", "user-1", "alice", RoleMember, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, sessions.Refresh(context.Background(), "sess-1"))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionStore_RecoverWhileRedisDown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sessions := NewFallbackSessionStore(unreachableRedis, db)
+	defer sessions.Close()
+	require.True(t, sessions.Degraded(), "should start degraded when Redis is unreachable")
+
+	// Sessions stay in the database until Redis answers
+	moved, err := sessions.Recover(context.Background())
+	assert.Error(t, err)
+	assert.Zero(t, moved)
+	assert.True(t, sessions.Degraded())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionStore_RedisOnlyHasNoFallback(t *testing.T) {
+	var zero SessionStore
+	moved, err := zero.Recover(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, moved)
+	assert.NoError(t, zero.Close())
+}
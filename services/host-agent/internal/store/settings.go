@@ -0,0 +1,54 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// SettingsStore manages user-editable app settings in the database.
+// Values are validated against the catalog schema before they are stored.
+type SettingsStore struct {
+	db *sql.DB
+}
+
+// NewSettingsStore creates a new settings store
+func NewSettingsStore(db *sql.DB) *SettingsStore {
+	return &SettingsStore{db: db}
+}
+
+// Get returns the stored settings for an app (empty if none are stored)
+func (s *SettingsStore) Get(appName string) (map[string]any, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT settings FROM app_settings WHERE app_name = $1`, appName).Scan(&data)
+	if err == sql.ErrNoRows {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings for %s: %w", appName, err)
+	}
+
+	settings := map[string]any{}
+	if err := json.Unmarshal([]byte(data), &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings for %s: %w", appName, err)
+	}
+	return settings, nil
+}
+
+// Set replaces the stored settings for an app
+func (s *SettingsStore) Set(appName string, settings map[string]any) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to encode settings for %s: %w", appName, err)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO app_settings (app_name, settings, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (app_name) DO UPDATE SET settings = EXCLUDED.settings, updated_at = CURRENT_TIMESTAMP
+	`, appName, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save settings for %s: %w", appName, err)
+	}
+	return nil
+}
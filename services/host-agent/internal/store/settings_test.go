@@ -0,0 +1,49 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsStore_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewSettingsStore(db)
+
+	mock.ExpectQuery(`SELECT settings FROM app_settings WHERE app_name = \$1`).
+		WithArgs("miniflux").
+		WillReturnRows(sqlmock.NewRows([]string{"settings"}).AddRow(`{"pollingFrequency":30}`))
+	mock.ExpectQuery(`SELECT settings FROM app_settings WHERE app_name = \$1`).
+		WithArgs("jellyfin").
+		WillReturnRows(sqlmock.NewRows([]string{"settings"}))
+
+	settings, err := store.Get("miniflux")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"pollingFrequency": float64(30)}, settings)
+
+	settings, err = store.Get("jellyfin")
+	require.NoError(t, err)
+	assert.Empty(t, settings, "apps without stored settings get an empty map")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSettingsStore_Set(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewSettingsStore(db)
+
+	mock.ExpectExec(`INSERT INTO app_settings .* ON CONFLICT \(app_name\) DO UPDATE`).
+		WithArgs("miniflux", `{"pollingFrequency":30}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, store.Set("miniflux", map[string]any{"pollingFrequency": 30}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StatsTier is one resolution of the stats history and how long it is kept
+type StatsTier struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// StatsTiers are the stats history resolutions, finest first. Each tier is
+// downsampled from the one before it.
+var StatsTiers = []StatsTier{
+	{Resolution: time.Minute, Retention: 24 * time.Hour},
+	{Resolution: 5 * time.Minute, Retention: 7 * 24 * time.Hour},
+	{Resolution: time.Hour, Retention: 90 * 24 * time.Hour},
+	{Resolution: 24 * time.Hour, Retention: 2 * 365 * 24 * time.Hour},
+}
+
+// StatsSample is resource usage at one point in time, as percentages
+type StatsSample struct {
+	App    string  `json:"app,omitempty"` // empty for the whole host
+	CPU    float64 `json:"cpu"`
+	Memory float64 `json:"memory"`
+	Disk   float64 `json:"disk,omitempty"`
+	// Temperature is the CPU package temperature in °C; host only
+	Temperature float64   `json:"temperature,omitempty"`
+	SampledAt   time.Time `json:"t"`
+}
+
+// AppUsage is an app's latest resource usage, summed over its containers
+type AppUsage struct {
+	CPU         float64   `json:"cpu"`    // percent of the host's total CPU
+	Memory      float64   `json:"memory"` // percent of host memory
+	MemoryBytes uint64    `json:"memory_bytes"`
+	NetRx       uint64    `json:"net_rx"`      // bytes received since the containers started
+	NetTx       uint64    `json:"net_tx"`      // bytes sent since the containers started
+	NetRxRate   float64   `json:"net_rx_rate"` // bytes per second since the previous sample
+	NetTxRate   float64   `json:"net_tx_rate"`
+	SampledAt   time.Time `json:"sampled_at"`
+}
+
+// StatsStore manages the stats history in the database
+type StatsStore struct {
+	db *sql.DB
+}
+
+// NewStatsStore creates a new stats store
+func NewStatsStore(db *sql.DB) *StatsStore {
+	return &StatsStore{db: db}
+}
+
+// Record stores samples at the finest resolution
+func (s *StatsStore) Record(samples []StatsSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	resolution := int(StatsTiers[0].Resolution.Seconds())
+	for _, sample := range samples {
+		_, err := tx.Exec(`
+			INSERT INTO stats_samples (resolution, app, cpu, memory, disk, temperature, sampled_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (resolution, app, sampled_at) DO NOTHING
+		`, resolution, sample.App, sample.CPU, sample.Memory, sample.Disk, sample.Temperature, sample.SampledAt.UTC().Truncate(StatsTiers[0].Resolution))
+		if err != nil {
+			return fmt.Errorf("failed to record stats sample: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stats samples: %w", err)
+	}
+	return nil
+}
+
+// Compact averages each tier into the next coarser one for every complete
+// bucket, then drops samples older than their tier's retention. It is
+// idempotent, so it can run on any schedule.
+func (s *StatsStore) Compact(now time.Time) error {
+	now = now.UTC()
+	for i := 1; i < len(StatsTiers); i++ {
+		fine, coarse := StatsTiers[i-1], StatsTiers[i]
+		fineSec, coarseSec := int(fine.Resolution.Seconds()), int(coarse.Resolution.Seconds())
+
+		// Only buckets that have fully elapsed, and only as far back as the
+		// finer tier still has data
+		until := now.Truncate(coarse.Resolution)
+		since := now.Add(-fine.Retention).Truncate(coarse.Resolution)
+
+		_, err := s.db.Exec(`
+			INSERT INTO stats_samples (resolution, app, cpu, memory, disk, temperature, sampled_at)
+			SELECT $2, app, AVG(cpu), AVG(memory), AVG(disk), AVG(temperature),
+				to_timestamp(floor(extract(epoch FROM sampled_at) / $2) * $2) AT TIME ZONE 'UTC' AS bucket
+			FROM stats_samples
+			WHERE resolution = $1 AND sampled_at >= $3 AND sampled_at < $4
+			GROUP BY app, bucket
+			ON CONFLICT (resolution, app, sampled_at) DO NOTHING
+		`, fineSec, coarseSec, since, until)
+		if err != nil {
+			return fmt.Errorf("failed to downsample stats to %s: %w", coarse.Resolution, err)
+		}
+	}
+
+	for _, tier := range StatsTiers {
+		_, err := s.db.Exec(`DELETE FROM stats_samples WHERE resolution = $1 AND sampled_at < $2`,
+			int(tier.Resolution.Seconds()), now.Add(-tier.Retention))
+		if err != nil {
+			return fmt.Errorf("failed to prune stats: %w", err)
+		}
+	}
+	return nil
+}
+
+// TierFor returns the finest tier that still covers the given range, or
+// false if the range is longer than any tier keeps
+func TierFor(r time.Duration) (StatsTier, bool) {
+	for _, tier := range StatsTiers {
+		if r <= tier.Retention {
+			return tier, true
+		}
+	}
+	return StatsTier{}, false
+}
+
+// TierAt returns the tier with the given resolution, or false if there is
+// none
+func TierAt(resolution time.Duration) (StatsTier, bool) {
+	for _, tier := range StatsTiers {
+		if tier.Resolution == resolution {
+			return tier, true
+		}
+	}
+	return StatsTier{}, false
+}
+
+// History returns an app's samples (empty app for the host) at a tier's
+// resolution since the given time, oldest first
+func (s *StatsStore) History(app string, tier StatsTier, since time.Time) ([]StatsSample, error) {
+	rows, err := s.db.Query(`
+		SELECT app, cpu, memory, disk, temperature, sampled_at
+		FROM stats_samples
+		WHERE resolution = $1 AND app = $2 AND sampled_at >= $3
+		ORDER BY sampled_at
+	`, int(tier.Resolution.Seconds()), app, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats history: %w", err)
+	}
+	defer rows.Close()
+
+	samples := []StatsSample{}
+	for rows.Next() {
+		var sample StatsSample
+		if err := rows.Scan(&sample.App, &sample.CPU, &sample.Memory, &sample.Disk, &sample.Temperature, &sample.SampledAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stats sample: %w", err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
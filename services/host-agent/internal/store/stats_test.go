@@ -0,0 +1,114 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsStore_Record(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewStatsStore(db)
+	at := time.Date(2026, 3, 1, 12, 30, 42, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO stats_samples .* ON CONFLICT`).
+		WithArgs(60, "", 12.0, 40.0, 55.0, 61.0, at.Truncate(time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stats_samples`).
+		WithArgs(60, "miniflux", 1.5, 2.0, 0.0, 0.0, at.Truncate(time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, store.Record([]StatsSample{
+		{CPU: 12, Memory: 40, Disk: 55, Temperature: 61, SampledAt: at},
+		{App: "miniflux", CPU: 1.5, Memory: 2, SampledAt: at},
+	}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatsStore_Compact(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewStatsStore(db)
+	now := time.Date(2026, 3, 1, 12, 37, 0, 0, time.UTC)
+
+	// minute -> 5 minute, for complete buckets within the last day
+	mock.ExpectExec(`INSERT INTO stats_samples .* SELECT .* GROUP BY app, bucket`).
+		WithArgs(60, 300, time.Date(2026, 2, 28, 12, 35, 0, 0, time.UTC), time.Date(2026, 3, 1, 12, 35, 0, 0, time.UTC)).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	// 5 minute -> hour, within the last week
+	mock.ExpectExec(`INSERT INTO stats_samples .* SELECT`).
+		WithArgs(300, 3600, time.Date(2026, 2, 22, 12, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// hour -> day, within the last 90 days
+	mock.ExpectExec(`INSERT INTO stats_samples .* SELECT`).
+		WithArgs(3600, 86400, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, tier := range StatsTiers {
+		mock.ExpectExec(`DELETE FROM stats_samples WHERE resolution = \$1 AND sampled_at < \$2`).
+			WithArgs(int(tier.Resolution.Seconds()), now.Add(-tier.Retention)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	require.NoError(t, store.Compact(now))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTierFor(t *testing.T) {
+	tier, ok := TierFor(time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, time.Minute, tier.Resolution)
+
+	tier, ok = TierFor(7 * 24 * time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, 5*time.Minute, tier.Resolution)
+
+	tier, ok = TierFor(30 * 24 * time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, time.Hour, tier.Resolution)
+
+	tier, ok = TierFor(365 * 24 * time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, 24*time.Hour, tier.Resolution)
+
+	_, ok = TierFor(1000 * 24 * time.Hour)
+	assert.False(t, ok)
+}
+
+func TestTierAt(t *testing.T) {
+	tier, ok := TierAt(time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, 90*24*time.Hour, tier.Retention)
+
+	_, ok = TierAt(15 * time.Minute)
+	assert.False(t, ok)
+}
+
+func TestStatsStore_History(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewStatsStore(db)
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT app, cpu, memory, disk, temperature, sampled_at FROM stats_samples WHERE resolution = \$1 AND app = \$2 AND sampled_at >= \$3 ORDER BY sampled_at`).
+		WithArgs(300, "", since).
+		WillReturnRows(sqlmock.NewRows([]string{"app", "cpu", "memory", "disk", "temperature", "sampled_at"}).
+			AddRow("", 10.5, 40.0, 55.0, 58.5, since.Add(5*time.Minute)))
+
+	samples, err := store.History("", StatsTiers[1], since)
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, StatsSample{CPU: 10.5, Memory: 40, Disk: 55, Temperature: 58.5, SampledAt: since.Add(5 * time.Minute)}, samples[0])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// tokenPrefix marks personal access tokens so they are recognizable in
+	// config files and secret scanners
+	tokenPrefix = "bloud_pat_"
+
+	// Token scopes
+	ScopeRead  = "read"  // GET requests only
+	ScopeWrite = "write" // any request
+	ScopeAdmin = "admin" // admin-only endpoints (requires an admin creator)
+)
+
+// APIToken is a personal access token. The plaintext token is only returned
+// once, from Create; the database stores a SHA-256 hash.
+type APIToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Username   string     `json:"username"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the token was granted scope. Write implies read.
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || (scope == ScopeRead && s == ScopeWrite) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidScope reports whether scope is a known token scope
+func ValidScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeWrite || scope == ScopeAdmin
+}
+
+// TokenStore manages personal access tokens in the database
+type TokenStore struct {
+	db *sql.DB
+}
+
+// NewTokenStore creates a new token store
+func NewTokenStore(db *sql.DB) *TokenStore {
+	return &TokenStore{db: db}
+}
+
+// Create mints a token for a user and returns its plaintext value
+func (s *TokenStore) Create(userID, name string, scopes []string, expiresAt time.Time) (*APIToken, string, error) {
+	plaintext, err := generateToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal scopes: %w", err)
+	}
+
+	token := &APIToken{
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	err = s.db.QueryRow(`
+		INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, userID, name, hashToken(plaintext), string(scopesJSON), expiresAt).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return token, plaintext, nil
+}
+
+// List returns a user's tokens, newest first
+func (s *TokenStore) List(userID string) ([]*APIToken, error) {
+	rows, err := s.db.Query(`
+		SELECT t.id, t.user_id, u.username, t.name, t.scopes, t.expires_at, t.last_used_at, t.created_at
+		FROM api_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.user_id = $1
+		ORDER BY t.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*APIToken{}
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// Delete revokes one of a user's tokens. Returns false if no such token exists.
+func (s *TokenStore) Delete(userID, tokenID string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM api_tokens WHERE id = $1 AND user_id = $2", tokenID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete token: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete token: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteAll revokes every token of the user with username and returns how
+// many there were
+func (s *TokenStore) DeleteAll(username string) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM api_tokens t USING users u WHERE u.id = t.user_id AND u.username = $1", username)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete tokens: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete tokens: %w", err)
+	}
+	return n, nil
+}
+
+// Authenticate looks up an unexpired token by its plaintext value and records
+// its use. Returns nil if the token is unknown or expired.
+func (s *TokenStore) Authenticate(plaintext string) (*APIToken, error) {
+	if !strings.HasPrefix(plaintext, tokenPrefix) {
+		return nil, nil
+	}
+
+	row := s.db.QueryRow(`
+		UPDATE api_tokens t SET last_used_at = CURRENT_TIMESTAMP
+		FROM users u
+		WHERE u.id = t.user_id AND t.token_hash = $1 AND t.expires_at > CURRENT_TIMESTAMP
+		RETURNING t.id, t.user_id, u.username, t.name, t.scopes, t.expires_at, t.last_used_at, t.created_at
+	`, hashToken(plaintext))
+
+	token, err := scanToken(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// scanToken reads a token from a row produced by List or Authenticate
+func scanToken(row interface{ Scan(...any) error }) (*APIToken, error) {
+	var token APIToken
+	var scopesJSON string
+	var lastUsed sql.NullTime
+	err := row.Scan(&token.ID, &token.UserID, &token.Username, &token.Name, &scopesJSON, &token.ExpiresAt, &lastUsed, &token.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan token: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(scopesJSON), &token.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to parse token scopes: %w", err)
+	}
+	if lastUsed.Valid {
+		token.LastUsedAt = &lastUsed.Time
+	}
+	return &token, nil
+}
+
+// generateToken creates a random plaintext token
+func generateToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return tokenPrefix + hex.EncodeToString(bytes), nil
+}
+
+// hashToken returns the stored form of a plaintext token
+func hashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var tokenColumns = []string{
+	"id", "user_id", "username", "name", "scopes", "expires_at", "last_used_at", "created_at",
+}
+
+func TestTokenStore_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewTokenStore(db)
+
+	expires := time.Now().Add(24 * time.Hour)
+	mock.ExpectQuery(`INSERT INTO api_tokens`).
+		WithArgs("user-1", "home assistant", sqlmock.AnyArg(), `["read"]`, expires).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("token-1", time.Now()))
+
+	token, plaintext, err := store.Create("user-1", "home assistant", []string{"read"}, expires)
+	require.NoError(t, err)
+
+	assert.Equal(t, "token-1", token.ID)
+	assert.True(t, strings.HasPrefix(plaintext, tokenPrefix))
+	assert.NotEqual(t, plaintext, hashToken(plaintext), "only the hash should be stored")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenStore_Authenticate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewTokenStore(db)
+
+	now := time.Now()
+	plaintext := tokenPrefix + "abc123"
+	mock.ExpectQuery(`UPDATE api_tokens t SET last_used_at`).
+		WithArgs(hashToken(plaintext)).
+		WillReturnRows(sqlmock.NewRows(tokenColumns).
+			AddRow("token-1", "user-1", "alice", "cli", `["write"]`, now.Add(time.Hour), now, now))
+
+	token, err := store.Authenticate(plaintext)
+	require.NoError(t, err)
+	require.NotNil(t, token)
+
+	assert.Equal(t, "alice", token.Username)
+	assert.Equal(t, []string{"write"}, token.Scopes)
+	assert.NotNil(t, token.LastUsedAt)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenStore_Authenticate_Unknown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewTokenStore(db)
+
+	mock.ExpectQuery(`UPDATE api_tokens t SET last_used_at`).
+		WillReturnRows(sqlmock.NewRows(tokenColumns))
+
+	token, err := store.Authenticate(tokenPrefix + "expired")
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	// Values without the prefix never reach the database
+	token, err = store.Authenticate("some-session-id")
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenStore_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewTokenStore(db)
+
+	mock.ExpectExec(`DELETE FROM api_tokens WHERE id = \$1 AND user_id = \$2`).
+		WithArgs("token-1", "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM api_tokens`).
+		WithArgs("token-1", "user-2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	deleted, err := store.Delete("user-1", "token-1")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = store.Delete("user-2", "token-1")
+	require.NoError(t, err)
+	assert.False(t, deleted, "tokens belonging to other users are not deleted")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenStore_DeleteAll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewTokenStore(db)
+
+	mock.ExpectExec(`DELETE FROM api_tokens t USING users u WHERE u.id = t.user_id AND u.username = \$1`).
+		WithArgs("bob").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err := store.DeleteAll("bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIToken_HasScope(t *testing.T) {
+	write := &APIToken{Scopes: []string{ScopeWrite}}
+	assert.True(t, write.HasScope(ScopeRead), "write implies read")
+	assert.True(t, write.HasScope(ScopeWrite))
+	assert.False(t, write.HasScope(ScopeAdmin))
+
+	read := &APIToken{Scopes: []string{ScopeRead}}
+	assert.False(t, read.HasScope(ScopeWrite))
+}
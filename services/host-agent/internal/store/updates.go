@@ -0,0 +1,224 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// UpdatePolicy is how the host updates itself
+type UpdatePolicy struct {
+	Mode    string       `json:"mode"`    // off, security-only or all
+	Channel string       `json:"channel"` // stable or beta
+	Window  UpdateWindow `json:"window"`
+}
+
+// UpdateWindow is the recurring maintenance window updates are applied in
+type UpdateWindow struct {
+	Start string `json:"start"` // HH:MM local time
+	// End is HH:MM local time; at or before Start means the next day
+	End string `json:"end"`
+	// Days the window opens on (sun, mon, ... sat); empty is every day
+	Days []string `json:"days,omitempty"`
+}
+
+// UpdateState is the outcome of the last automatic update
+type UpdateState struct {
+	LastAttemptAt time.Time // zero if none was attempted
+	Status        string
+	Error         string
+	FromSystem    string    // NixOS system the update started from
+	ToSystem      string    // NixOS system the update switches to
+	SwitchedAt    time.Time // when ToSystem started running; zero before
+	// Snapshot is the consistency point taken before the last switch to an
+	// update; nil if there never was one
+	Snapshot *UpdateSnapshot
+}
+
+// UpdateSnapshot records the user data saved before an update switched
+// systems, so a failed update can be restored from it
+type UpdateSnapshot struct {
+	TakenAt    time.Time `json:"takenAt"`
+	Database   string    `json:"database,omitempty"`   // database backup name
+	Secrets    string    `json:"secrets,omitempty"`    // path of the secrets copy
+	Filesystem string    `json:"filesystem,omitempty"` // btrfs or zfs; empty if not snapshotted
+	Name       string    `json:"name,omitempty"`       // btrfs snapshot path or ZFS snapshot name
+}
+
+// AvailableUpdate is a newer system found by the update check
+type AvailableUpdate struct {
+	System    string    `json:"system"`            // NixOS system an update would switch to
+	Channel   string    `json:"channel,omitempty"` // empty for security fixes to the installed release
+	FoundAt   time.Time `json:"foundAt"`           // when the check first found System
+	CheckedAt time.Time `json:"checkedAt"`         // when the check last ran
+	// Notes is what the update brings, read when System was first found;
+	// nil when they couldn't be read
+	Notes *ReleaseNotes `json:"notes,omitempty"`
+}
+
+// ReleaseNotes is what an update brings: the commits between the running
+// system's revision and the channel's, and the notes their messages carry
+type ReleaseNotes struct {
+	FromRevision string          `json:"fromRevision"`
+	ToRevision   string          `json:"toRevision"`
+	Breaking     []string        `json:"breaking,omitempty"`   // breaking changes, from commit messages
+	Migrations   []string        `json:"migrations,omitempty"` // what to do before or after updating
+	NewApps      []string        `json:"newApps,omitempty"`    // apps added to the catalog
+	Changes      []ReleaseChange `json:"changes"`              // newest first
+	Truncated    bool            `json:"truncated,omitempty"`  // older changes were left out
+}
+
+// ReleaseChange is one commit an update brings
+type ReleaseChange struct {
+	Commit   string `json:"commit"`
+	Summary  string `json:"summary"` // the commit's subject
+	Breaking bool   `json:"breaking,omitempty"`
+}
+
+// UpdateStore persists the automatic update policy, the last update and
+// the last update check
+type UpdateStore struct {
+	db *sql.DB
+}
+
+// NewUpdateStore creates a new update store
+func NewUpdateStore(db *sql.DB) *UpdateStore {
+	return &UpdateStore{db: db}
+}
+
+// Policy returns the stored policy, or nil if it was never set
+func (s *UpdateStore) Policy() (*UpdatePolicy, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT policy FROM updates WHERE id = 1`).Scan(&data)
+	if err == sql.ErrNoRows || (err == nil && data == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get update policy: %w", err)
+	}
+
+	var p UpdatePolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse update policy: %w", err)
+	}
+	return &p, nil
+}
+
+// SetPolicy stores the policy
+func (s *UpdateStore) SetPolicy(p UpdatePolicy) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal update policy: %w", err)
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO updates (id, policy) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET policy = EXCLUDED.policy
+	`, data)
+	if err != nil {
+		return fmt.Errorf("failed to save update policy: %w", err)
+	}
+	return nil
+}
+
+// State returns the outcome of the last update
+func (s *UpdateStore) State() (UpdateState, error) {
+	var st UpdateState
+	var lastAttempt, switched sql.NullTime
+	var snapshot []byte
+	err := s.db.QueryRow(`
+		SELECT last_attempt_at, status, error, from_system, to_system, snapshot, switched_at FROM updates WHERE id = 1
+	`).Scan(&lastAttempt, &st.Status, &st.Error, &st.FromSystem, &st.ToSystem, &snapshot, &switched)
+	if err == sql.ErrNoRows {
+		return st, nil
+	}
+	if err != nil {
+		return st, fmt.Errorf("failed to get update state: %w", err)
+	}
+	if lastAttempt.Valid {
+		st.LastAttemptAt = lastAttempt.Time
+	}
+	if switched.Valid {
+		st.SwitchedAt = switched.Time
+	}
+	if snapshot != nil {
+		st.Snapshot = &UpdateSnapshot{}
+		if err := json.Unmarshal(snapshot, st.Snapshot); err != nil {
+			return st, fmt.Errorf("failed to parse update snapshot: %w", err)
+		}
+	}
+	return st, nil
+}
+
+// SaveState writes the outcome of the last update
+func (s *UpdateStore) SaveState(st UpdateState) error {
+	var lastAttempt, switched sql.NullTime
+	if !st.LastAttemptAt.IsZero() {
+		lastAttempt = sql.NullTime{Time: st.LastAttemptAt.UTC(), Valid: true}
+	}
+	if !st.SwitchedAt.IsZero() {
+		switched = sql.NullTime{Time: st.SwitchedAt.UTC(), Valid: true}
+	}
+	var snapshot []byte
+	if st.Snapshot != nil {
+		data, err := json.Marshal(st.Snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to marshal update snapshot: %w", err)
+		}
+		snapshot = data
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO updates (id, last_attempt_at, status, error, from_system, to_system, snapshot, switched_at)
+		VALUES (1, $1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			last_attempt_at = EXCLUDED.last_attempt_at,
+			status = EXCLUDED.status,
+			error = EXCLUDED.error,
+			from_system = EXCLUDED.from_system,
+			to_system = EXCLUDED.to_system,
+			snapshot = EXCLUDED.snapshot,
+			switched_at = EXCLUDED.switched_at
+	`, lastAttempt, st.Status, st.Error, st.FromSystem, st.ToSystem, snapshot, switched)
+	if err != nil {
+		return fmt.Errorf("failed to save update state: %w", err)
+	}
+	return nil
+}
+
+// Available returns the update the last check found, or nil if there was
+// none
+func (s *UpdateStore) Available() (*AvailableUpdate, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT available FROM updates WHERE id = 1`).Scan(&data)
+	if err == sql.ErrNoRows || (err == nil && data == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get available update: %w", err)
+	}
+
+	var a AvailableUpdate
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse available update: %w", err)
+	}
+	return &a, nil
+}
+
+// SetAvailable stores the update the last check found; nil clears it
+func (s *UpdateStore) SetAvailable(a *AvailableUpdate) error {
+	var data []byte
+	if a != nil {
+		var err error
+		if data, err = json.Marshal(a); err != nil {
+			return fmt.Errorf("failed to marshal available update: %w", err)
+		}
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO updates (id, available) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET available = EXCLUDED.available
+	`, data)
+	if err != nil {
+		return fmt.Errorf("failed to save available update: %w", err)
+	}
+	return nil
+}
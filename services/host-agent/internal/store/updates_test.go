@@ -0,0 +1,85 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewUpdateStore(db)
+
+	// Unset until a policy is saved
+	mock.ExpectQuery(`SELECT policy FROM updates`).WillReturnRows(sqlmock.NewRows([]string{"policy"}))
+	p, err := store.Policy()
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	mock.ExpectExec(`INSERT INTO updates \(id, policy\) .* ON CONFLICT \(id\) DO UPDATE`).
+		WithArgs([]byte(`{"mode":"all","channel":"beta","window":{"start":"02:00","end":"04:00","days":["sat"]}}`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.SetPolicy(UpdatePolicy{Mode: "all", Channel: "beta", Window: UpdateWindow{Start: "02:00", End: "04:00", Days: []string{"sat"}}}))
+
+	mock.ExpectQuery(`SELECT policy FROM updates`).
+		WillReturnRows(sqlmock.NewRows([]string{"policy"}).AddRow([]byte(`{"mode":"security-only","channel":"stable","window":{"start":"03:00","end":"05:00"}}`)))
+	p, err = store.Policy()
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	assert.Equal(t, "security-only", p.Mode)
+	assert.Equal(t, "05:00", p.Window.End)
+
+	at := time.Date(2026, 3, 1, 3, 12, 0, 0, time.UTC)
+	mock.ExpectExec(`INSERT INTO updates \(id, last_attempt_at, .*\) .* ON CONFLICT \(id\) DO UPDATE`).
+		WithArgs(sql.NullTime{Time: at, Valid: true}, "applying", "", "/nix/store/a-system", "/nix/store/b-system",
+			[]byte(`{"takenAt":"2026-03-01T03:12:00Z","database":"bloud-20260301T031200Z.dump"}`), sql.NullTime{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.SaveState(UpdateState{LastAttemptAt: at, Status: "applying", FromSystem: "/nix/store/a-system", ToSystem: "/nix/store/b-system",
+		Snapshot: &UpdateSnapshot{TakenAt: at, Database: "bloud-20260301T031200Z.dump"}}))
+
+	mock.ExpectQuery(`SELECT last_attempt_at, status, error, from_system, to_system, snapshot, switched_at FROM updates`).
+		WillReturnRows(sqlmock.NewRows([]string{"last_attempt_at", "status", "error", "from_system", "to_system", "snapshot", "switched_at"}).
+			AddRow(nil, "", "", "", "", nil, nil))
+	st, err := store.State()
+	require.NoError(t, err)
+	assert.True(t, st.LastAttemptAt.IsZero())
+	assert.Nil(t, st.Snapshot)
+
+	mock.ExpectQuery(`SELECT last_attempt_at, status, error, from_system, to_system, snapshot, switched_at FROM updates`).
+		WillReturnRows(sqlmock.NewRows([]string{"last_attempt_at", "status", "error", "from_system", "to_system", "snapshot", "switched_at"}).
+			AddRow(at, "applied", "", "/nix/store/a-system", "/nix/store/b-system", []byte(`{"takenAt":"2026-03-01T03:12:00Z","filesystem":"zfs","name":"bloud/data@bloud-update-20260301T031200Z"}`), at.Add(time.Minute)))
+	st, err = store.State()
+	require.NoError(t, err)
+	require.NotNil(t, st.Snapshot)
+	assert.Equal(t, "zfs", st.Snapshot.Filesystem)
+	assert.Equal(t, at.Add(time.Minute), st.SwitchedAt)
+
+	mock.ExpectQuery(`SELECT available FROM updates`).WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow(nil))
+	a, err := store.Available()
+	require.NoError(t, err)
+	assert.Nil(t, a)
+
+	available := AvailableUpdate{System: "/nix/store/c-system", Channel: "stable", FoundAt: at, CheckedAt: at}
+	mock.ExpectExec(`INSERT INTO updates \(id, available\) .* ON CONFLICT \(id\) DO UPDATE`).
+		WithArgs([]byte(`{"system":"/nix/store/c-system","channel":"stable","foundAt":"2026-03-01T03:12:00Z","checkedAt":"2026-03-01T03:12:00Z"}`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.SetAvailable(&available))
+
+	mock.ExpectExec(`INSERT INTO updates \(id, available\)`).WithArgs([]byte(nil)).WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.SetAvailable(nil))
+
+	mock.ExpectQuery(`SELECT available FROM updates`).
+		WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow([]byte(`{"system":"/nix/store/c-system","channel":"stable","foundAt":"2026-03-01T03:12:00Z","checkedAt":"2026-03-01T03:12:00Z"}`)))
+	a, err = store.Available()
+	require.NoError(t, err)
+	require.NotNil(t, a)
+	assert.Equal(t, available, *a)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UptimeRetention is how long health checks are kept, the longest uptime
+// view
+const UptimeRetention = 90 * 24 * time.Hour
+
+// HealthCheck is the result of one periodic health check
+type HealthCheck struct {
+	Healthy   bool      `json:"healthy"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Uptime summarizes an app's health checks over a window
+type Uptime struct {
+	// Percent is the share of checks that passed; nil without checks
+	Percent   *float64    `json:"percent"`
+	Checks    int         `json:"checks"`
+	Failed    int         `json:"failed"`
+	Daily     []UptimeDay `json:"daily"`     // one per UTC day of the window, oldest first
+	Incidents []Incident  `json:"incidents"` // newest first
+}
+
+// UptimeDay is the uptime of one UTC day
+type UptimeDay struct {
+	Date    string   `json:"date"`    // YYYY-MM-DD
+	Percent *float64 `json:"percent"` // nil without checks that day
+	Checks  int      `json:"checks"`
+}
+
+// Incident is a run of failed health checks
+type Incident struct {
+	Start time.Time  `json:"start"`         // first failed check
+	End   *time.Time `json:"end,omitempty"` // first passing check after it; nil while still down
+	// Duration is in seconds, up to now for an ongoing incident
+	Duration float64 `json:"duration"`
+	Checks   int     `json:"checks"` // failed checks in the run
+}
+
+// HealthCheckStore persists app health check results
+type HealthCheckStore struct {
+	db *sql.DB
+}
+
+// NewHealthCheckStore creates a new health check store
+func NewHealthCheckStore(db *sql.DB) *HealthCheckStore {
+	return &HealthCheckStore{db: db}
+}
+
+// Record stores one health check result
+func (s *HealthCheckStore) Record(app string, healthy bool, at time.Time) error {
+	_, err := s.db.Exec(`
+		INSERT INTO app_health_checks (app_name, healthy, checked_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (app_name, checked_at) DO NOTHING
+	`, app, healthy, at.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record health check: %w", err)
+	}
+	return nil
+}
+
+// Checks returns an app's health checks since the given time, oldest first
+func (s *HealthCheckStore) Checks(app string, since time.Time) ([]HealthCheck, error) {
+	rows, err := s.db.Query(`
+		SELECT healthy, checked_at
+		FROM app_health_checks
+		WHERE app_name = $1 AND checked_at >= $2
+		ORDER BY checked_at
+	`, app, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query health checks: %w", err)
+	}
+	defer rows.Close()
+
+	checks := []HealthCheck{}
+	for rows.Next() {
+		var c HealthCheck
+		if err := rows.Scan(&c.Healthy, &c.CheckedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan health check: %w", err)
+		}
+		checks = append(checks, c)
+	}
+	return checks, rows.Err()
+}
+
+// Prune drops health checks older than UptimeRetention
+func (s *HealthCheckStore) Prune(now time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM app_health_checks WHERE checked_at < $1`, now.UTC().Add(-UptimeRetention)); err != nil {
+		return fmt.Errorf("failed to prune health checks: %w", err)
+	}
+	return nil
+}
+
+// ComputeUptime summarizes checks (oldest first) over the days ending today
+func ComputeUptime(checks []HealthCheck, days int, now time.Time) Uptime {
+	now = now.UTC()
+	today := now.Truncate(24 * time.Hour)
+	first := today.AddDate(0, 0, -(days - 1))
+
+	uptime := Uptime{Daily: make([]UptimeDay, days), Incidents: []Incident{}}
+	passed := make([]int, days)
+	for i := range uptime.Daily {
+		uptime.Daily[i].Date = first.AddDate(0, 0, i).Format("2006-01-02")
+	}
+
+	var open *Incident
+	for _, c := range checks {
+		at := c.CheckedAt.UTC()
+		day := int(at.Sub(first) / (24 * time.Hour))
+		if at.Before(first) || day >= days {
+			continue
+		}
+		uptime.Checks++
+		uptime.Daily[day].Checks++
+
+		if c.Healthy {
+			passed[day]++
+			if open != nil {
+				end := at
+				open.End = &end
+				open.Duration = end.Sub(open.Start).Seconds()
+				uptime.Incidents = append(uptime.Incidents, *open)
+				open = nil
+			}
+			continue
+		}
+		uptime.Failed++
+		if open == nil {
+			open = &Incident{Start: at}
+		}
+		open.Checks++
+	}
+	if open != nil {
+		open.Duration = now.Sub(open.Start).Seconds()
+		uptime.Incidents = append(uptime.Incidents, *open)
+	}
+
+	// Newest incident first
+	for i, j := 0, len(uptime.Incidents)-1; i < j; i, j = i+1, j-1 {
+		uptime.Incidents[i], uptime.Incidents[j] = uptime.Incidents[j], uptime.Incidents[i]
+	}
+	uptime.Percent = percent(uptime.Checks-uptime.Failed, uptime.Checks)
+	for i := range uptime.Daily {
+		uptime.Daily[i].Percent = percent(passed[i], uptime.Daily[i].Checks)
+	}
+	return uptime
+}
+
+func percent(n, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	p := float64(n) / float64(total) * 100
+	return &p
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheckStore_RecordAndChecks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewHealthCheckStore(db)
+	at := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	mock.ExpectExec(`INSERT INTO app_health_checks .* ON CONFLICT`).
+		WithArgs("miniflux", false, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.Record("miniflux", false, at))
+
+	mock.ExpectQuery(`SELECT healthy, checked_at FROM app_health_checks`).
+		WithArgs("miniflux", at.Add(-time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"healthy", "checked_at"}).
+			AddRow(true, at.Add(-time.Minute)).
+			AddRow(false, at))
+	checks, err := store.Checks("miniflux", at.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []HealthCheck{{Healthy: true, CheckedAt: at.Add(-time.Minute)}, {Healthy: false, CheckedAt: at}}, checks)
+
+	mock.ExpectExec(`DELETE FROM app_health_checks WHERE checked_at < \$1`).
+		WithArgs(at.Add(-UptimeRetention)).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	require.NoError(t, store.Prune(at))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestComputeUptime(t *testing.T) {
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	day := func(d, h, m int) time.Time { return time.Date(2026, 3, d, h, m, 0, 0, time.UTC) }
+	checks := []HealthCheck{
+		{Healthy: false, CheckedAt: day(1, 23, 0)}, // before the window
+		{Healthy: true, CheckedAt: day(2, 10, 0)},
+		{Healthy: false, CheckedAt: day(2, 10, 1)},
+		{Healthy: false, CheckedAt: day(2, 10, 2)},
+		{Healthy: true, CheckedAt: day(2, 10, 3)},
+		{Healthy: true, CheckedAt: day(3, 11, 58)},
+		{Healthy: false, CheckedAt: day(3, 11, 59)},
+	}
+
+	uptime := ComputeUptime(checks, 2, now)
+	assert.Equal(t, 6, uptime.Checks)
+	assert.Equal(t, 3, uptime.Failed)
+	require.NotNil(t, uptime.Percent)
+	assert.InDelta(t, 50, *uptime.Percent, 0.001)
+
+	require.Len(t, uptime.Daily, 2)
+	assert.Equal(t, "2026-03-02", uptime.Daily[0].Date)
+	assert.Equal(t, 4, uptime.Daily[0].Checks)
+	assert.InDelta(t, 50, *uptime.Daily[0].Percent, 0.001)
+	assert.Equal(t, "2026-03-03", uptime.Daily[1].Date)
+
+	// The ongoing incident first, running until now
+	require.Len(t, uptime.Incidents, 2)
+	assert.Nil(t, uptime.Incidents[0].End)
+	assert.Equal(t, 60.0, uptime.Incidents[0].Duration)
+	assert.Equal(t, day(2, 10, 1), uptime.Incidents[1].Start)
+	assert.Equal(t, day(2, 10, 3), *uptime.Incidents[1].End)
+	assert.Equal(t, 120.0, uptime.Incidents[1].Duration)
+	assert.Equal(t, 2, uptime.Incidents[1].Checks)
+
+	// No checks, no percentage
+	uptime = ComputeUptime(nil, 30, now)
+	assert.Nil(t, uptime.Percent)
+	assert.Len(t, uptime.Daily, 30)
+	assert.Empty(t, uptime.Incidents)
+}
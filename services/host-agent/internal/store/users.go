@@ -7,11 +7,23 @@ import (
 	"time"
 )
 
+// User roles, derived from Authentik group membership at login
+const (
+	RoleAdmin  = "admin"  // may install/uninstall apps and manage the system
+	RoleMember = "member" // may use installed apps and the dashboard
+)
+
 // User represents a Bloud user (credentials stored in Authentik)
 type User struct {
 	ID        string    `json:"id"`
 	Username  string    `json:"username"`
 	CreatedAt time.Time `json:"created_at"`
+	Role      string    `json:"role,omitempty"` // From the session or token, not persisted
+}
+
+// IsAdmin reports whether the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
 }
 
 // GridElement represents an element (app or widget) in the layout grid
@@ -86,6 +98,11 @@ func (s *UserStore) GetLayout(userID string) ([]GridElement, error) {
 		return nil, fmt.Errorf("failed to get layout: %w", err)
 	}
 
+	return parseLayout(layoutJSON)
+}
+
+// parseLayout decodes a stored layout
+func parseLayout(layoutJSON []byte) ([]GridElement, error) {
 	// Try to parse as array first (new format)
 	var elements []GridElement
 	if err := json.Unmarshal(layoutJSON, &elements); err == nil {
@@ -0,0 +1,261 @@
+package system
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// NixStorePath is the Nix store, shared by the system and every app image
+const NixStorePath = "/nix/store"
+
+// snapshotDir holds the btrfs snapshots taken before updates when the data
+// directory is a btrfs data store. They share their data with the live
+// files, so they aren't counted.
+const snapshotDir = ".bloud-snapshots"
+
+// Storage categories, in the order they're reported
+const (
+	StorageMedia     = "media"     // the shared media library
+	StorageDownloads = "downloads" // download clients' output
+	StorageApps      = "apps"      // per-app data directories
+	StorageBackups   = "backups"   // database and app backups
+	StorageBloud     = "bloud"     // host agent database and config
+	StorageNixStore  = "nix-store" // the system, app images and old generations
+	StorageOther     = "other"     // whatever else uses the disk
+)
+
+// StorageCategory is the disk space one category of files uses
+type StorageCategory struct {
+	Name  string        `json:"name"`
+	Bytes uint64        `json:"bytes"`
+	Items []StorageItem `json:"items,omitempty"` // per-app usage, for apps
+}
+
+// StorageItem is the disk space one directory uses
+type StorageItem struct {
+	Name  string `json:"name"`
+	Bytes uint64 `json:"bytes"`
+}
+
+// StorageBreakdown is the result of the last storage scan
+type StorageBreakdown struct {
+	Categories []StorageCategory `json:"categories"`
+	Disk       *StorageStats     `json:"disk,omitempty"`
+	ScannedAt  *time.Time        `json:"scannedAt,omitempty"` // unset until a scan finishes
+	DurationMs int64             `json:"durationMs,omitempty"`
+	Scanning   bool              `json:"scanning"`
+	// Unreadable counts files and directories skipped, e.g. for permissions
+	Unreadable int    `json:"unreadable,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// StorageScanner measures what uses the disk, du-style, and caches the
+// result; a scan of a large media library or Nix store takes a while
+type StorageScanner struct {
+	dataDir   string
+	nixStore  string
+	diskUsage func() (*StorageStats, error)
+
+	mu       sync.Mutex
+	last     StorageBreakdown
+	scanning bool
+	done     chan struct{} // closed when the running scan finishes
+}
+
+// NewStorageScanner creates a scanner for the bloud data directory and the
+// Nix store, usually NixStorePath
+func NewStorageScanner(dataDir, nixStore string) *StorageScanner {
+	return &StorageScanner{
+		dataDir:   dataDir,
+		nixStore:  nixStore,
+		diskUsage: GetStorageStats,
+		last:      StorageBreakdown{Categories: []StorageCategory{}},
+	}
+}
+
+// Breakdown returns the last scan's result
+func (s *StorageScanner) Breakdown() StorageBreakdown {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.last
+	b.Scanning = s.scanning
+	return b
+}
+
+// Refresh starts a scan in the background, unless one is running. It
+// reports whether a scan was started.
+func (s *StorageScanner) Refresh() bool {
+	done, started := s.begin()
+	if started {
+		go s.scan(context.Background(), done)
+	}
+	return started
+}
+
+// Scan runs a scan and waits for it, or for the one already running
+func (s *StorageScanner) Scan(ctx context.Context) error {
+	done, started := s.begin()
+	if started {
+		s.scan(ctx, done)
+	} else {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last.Error != "" {
+		return errors.New(s.last.Error)
+	}
+	return nil
+}
+
+// begin marks a scan as running, returning the channel closed when the
+// running scan finishes and whether the caller is to run it
+func (s *StorageScanner) begin() (chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scanning {
+		return s.done, false
+	}
+	s.scanning = true
+	s.done = make(chan struct{})
+	return s.done, true
+}
+
+func (s *StorageScanner) scan(ctx context.Context, done chan struct{}) {
+	start := time.Now()
+	b := s.measure(ctx)
+	finished := time.Now()
+	b.ScannedAt = &finished
+	b.DurationMs = finished.Sub(start).Milliseconds()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = b
+	s.scanning = false
+	close(done)
+}
+
+// measure walks the data directory and the Nix store
+func (s *StorageScanner) measure(ctx context.Context) StorageBreakdown {
+	w := &duWalker{ctx: ctx, seen: map[inode]bool{}}
+	sizes := map[string]uint64{}
+	var apps []StorageItem
+
+	entries, err := os.ReadDir(s.dataDir)
+	if err != nil {
+		return StorageBreakdown{Categories: []StorageCategory{}, Error: err.Error()}
+	}
+	for _, entry := range entries {
+		if entry.Name() == snapshotDir {
+			continue
+		}
+		path := filepath.Join(s.dataDir, entry.Name())
+		size := w.du(path)
+		switch {
+		case !entry.IsDir():
+			sizes[StorageBloud] += size
+		case entry.Name() == StorageMedia || entry.Name() == StorageDownloads || entry.Name() == StorageBackups:
+			sizes[entry.Name()] += size
+		default:
+			apps = append(apps, StorageItem{Name: entry.Name(), Bytes: size})
+			sizes[StorageApps] += size
+		}
+	}
+	// Off NixOS there's no store to measure
+	if _, err := os.Stat(s.nixStore); err == nil {
+		sizes[StorageNixStore] = w.du(s.nixStore)
+	}
+	if ctx.Err() != nil {
+		return StorageBreakdown{Categories: []StorageCategory{}, Error: ctx.Err().Error()}
+	}
+
+	// Biggest apps first
+	slices.SortFunc(apps, func(a, b StorageItem) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Name, b.Name))
+	})
+
+	b := StorageBreakdown{Unreadable: w.unreadable}
+	var measured uint64
+	for _, name := range []string{StorageMedia, StorageDownloads, StorageApps, StorageBackups, StorageBloud, StorageNixStore} {
+		category := StorageCategory{Name: name, Bytes: sizes[name]}
+		if name == StorageApps {
+			category.Items = apps
+		}
+		b.Categories = append(b.Categories, category)
+		measured += sizes[name]
+	}
+
+	// The rest of what's used on the disk, if the data directory is on it
+	if disk, err := s.diskUsage(); err == nil {
+		b.Disk = disk
+		if disk.Used > measured {
+			b.Categories = append(b.Categories, StorageCategory{Name: StorageOther, Bytes: disk.Used - measured})
+		}
+	}
+	return b
+}
+
+// duWalker adds up the disk space files use, like du: allocated blocks
+// rather than apparent size, and hard links counted once
+type duWalker struct {
+	ctx        context.Context
+	seen       map[inode]bool // hard-linked files already counted
+	unreadable int
+}
+
+// inode identifies a file across filesystems
+type inode struct {
+	dev, ino uint64
+}
+
+// du returns the disk space used under path
+func (w *duWalker) du(path string) uint64 {
+	var total uint64
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			w.unreadable++
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			w.unreadable++
+			return nil
+		}
+		total += w.size(info)
+		return nil
+	})
+	return total
+}
+
+// size returns the disk space a file uses, or zero for a hard link already
+// counted
+func (w *duWalker) size(info fs.FileInfo) uint64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return uint64(info.Size())
+	}
+	if st.Nlink > 1 && !info.IsDir() {
+		id := inode{dev: uint64(st.Dev), ino: st.Ino}
+		if w.seen[id] {
+			return 0
+		}
+		w.seen[id] = true
+	}
+	return uint64(st.Blocks) * 512
+}
@@ -0,0 +1,77 @@
+package system
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageScanner(t *testing.T) {
+	dataDir := t.TempDir()
+	write := func(path string, size int) {
+		path = filepath.Join(dataDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	}
+	write("downloads/film.mkv", 64<<10)
+	write("downloads/file.iso", 16<<10)
+	write("jellyfin/cache.db", 32<<10)
+	write("miniflux/db", 8<<10)
+	write("backups/db/bloud-1.db", 8<<10)
+	write("state.db", 4<<10)
+	write(".bloud-snapshots/update-20260306T030000Z/jellyfin/cache.db", 32<<10)
+	// A hard link, like a download imported into the library, is counted once
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "media/movies"), 0755))
+	require.NoError(t, os.Link(filepath.Join(dataDir, "downloads/film.mkv"), filepath.Join(dataDir, "media/movies/film.mkv")))
+
+	s := NewStorageScanner(dataDir, filepath.Join(dataDir, "missing-store"))
+	s.diskUsage = func() (*StorageStats, error) {
+		return &StorageStats{Used: 1 << 30, Total: 4 << 30}, nil
+	}
+
+	assert.Nil(t, s.Breakdown().ScannedAt)
+	require.NoError(t, s.Scan(context.Background()))
+
+	b := s.Breakdown()
+	require.NotNil(t, b.ScannedAt)
+	assert.False(t, b.Scanning)
+
+	sizes := map[string]uint64{}
+	var measured uint64
+	for _, c := range b.Categories {
+		sizes[c.Name] = c.Bytes
+		if c.Name != StorageOther {
+			measured += c.Bytes
+		}
+	}
+	assert.GreaterOrEqual(t, sizes[StorageDownloads], uint64(80<<10))
+	assert.Less(t, sizes[StorageMedia], uint64(64<<10), "hard link counted twice")
+	assert.GreaterOrEqual(t, sizes[StorageApps], uint64(40<<10))
+	assert.GreaterOrEqual(t, sizes[StorageBloud], uint64(4<<10))
+	assert.Zero(t, sizes[StorageNixStore])
+	assert.Equal(t, uint64(1<<30)-measured, sizes[StorageOther])
+
+	apps := b.Categories[2]
+	require.Equal(t, StorageApps, apps.Name)
+	require.Len(t, apps.Items, 2, "update snapshots aren't an app")
+	assert.Equal(t, "jellyfin", apps.Items[0].Name, "biggest first")
+	assert.Equal(t, "miniflux", apps.Items[1].Name)
+}
+
+func TestStorageScanner_Refresh(t *testing.T) {
+	s := NewStorageScanner(t.TempDir(), "")
+
+	done, started := s.begin()
+	require.True(t, started)
+	assert.False(t, s.Refresh(), "a scan is already running")
+	assert.True(t, s.Breakdown().Scanning)
+
+	s.scan(context.Background(), done)
+	assert.False(t, s.Breakdown().Scanning)
+	assert.True(t, s.Refresh())
+	require.NoError(t, s.Scan(context.Background()))
+}
@@ -0,0 +1,36 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// systemProfile links to the current generation's profile link, named
+// system-<n>-link
+const systemProfile = "/nix/var/nix/profiles/system"
+
+// nixosVersionFile holds the running system's NixOS version
+const nixosVersionFile = "/run/current-system/nixos-version"
+
+// CurrentGeneration returns the NixOS system generation the profile points
+// at and the running NixOS version. The generation is 0 off NixOS. Unlike
+// ListGenerations it doesn't run nixos-rebuild, so it is cheap to call on
+// every metrics scrape.
+func CurrentGeneration() (int, string) {
+	return readGeneration(systemProfile, nixosVersionFile)
+}
+
+func readGeneration(profile, versionFile string) (int, string) {
+	target, err := os.Readlink(profile)
+	if err != nil {
+		return 0, ""
+	}
+	name := strings.TrimSuffix(filepath.Base(target), "-link")
+	n, err := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+	if err != nil {
+		return 0, ""
+	}
+	return n, readTrimmed(versionFile)
+}
@@ -0,0 +1,26 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadGeneration(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "system")
+	version := filepath.Join(dir, "nixos-version")
+	require.NoError(t, os.Symlink("system-42-link", profile))
+	require.NoError(t, os.WriteFile(version, []byte("24.11.20250101.abcdef\n"), 0644))
+
+	n, v := readGeneration(profile, version)
+	assert.Equal(t, 42, n)
+	assert.Equal(t, "24.11.20250101.abcdef", v)
+
+	n, v = readGeneration(filepath.Join(dir, "missing"), version)
+	assert.Equal(t, 0, n)
+	assert.Empty(t, v)
+}
@@ -22,6 +22,10 @@ type Stats struct {
 	CPU    int `json:"cpu"`
 	Memory int `json:"memory"`
 	Disk   int `json:"disk"`
+	// Temperature is the CPU package temperature in °C, 0 without a sensor
+	Temperature int `json:"temperature,omitempty"`
+	// Throttles counts thermal throttle events since boot
+	Throttles uint64 `json:"throttles,omitempty"`
 }
 
 // statsCache holds cached system stats updated in background
@@ -85,6 +89,12 @@ func collectStats() {
 		stats.Disk = int(math.Round(diskStats.UsedPercent))
 	}
 
+	collectNetwork()
+
+	thermal := ReadThermal("/sys")
+	stats.Temperature = int(math.Round(thermal.CPUTemp))
+	stats.Throttles = thermal.Throttles
+
 	statsCacheMu.Lock()
 	statsCache = stats
 	statsCacheMu.Unlock()
@@ -101,9 +111,11 @@ func GetStats() (*Stats, error) {
 
 	// Return a copy
 	return &Stats{
-		CPU:    statsCache.CPU,
-		Memory: statsCache.Memory,
-		Disk:   statsCache.Disk,
+		CPU:         statsCache.CPU,
+		Memory:      statsCache.Memory,
+		Disk:        statsCache.Disk,
+		Temperature: statsCache.Temperature,
+		Throttles:   statsCache.Throttles,
 	}, nil
 }
 
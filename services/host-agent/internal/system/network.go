@@ -0,0 +1,113 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// NetworkStats is the host's network throughput and connections
+type NetworkStats struct {
+	Interfaces []InterfaceStats `json:"interfaces"`
+	// Connections is the number of established TCP connections
+	Connections int `json:"connections"`
+}
+
+// InterfaceStats is one network interface's traffic
+type InterfaceStats struct {
+	Name string `json:"name"`
+	// Physical is false for bridges, VPN tunnels and other virtual interfaces
+	Physical bool    `json:"physical"`
+	RxBytes  uint64  `json:"rxBytes"` // since boot
+	TxBytes  uint64  `json:"txBytes"`
+	RxRate   float64 `json:"rxRate"` // bytes per second over the last collection interval
+	TxRate   float64 `json:"txRate"`
+	Errors   uint64  `json:"errors"` // receive and transmit errors since boot
+	Drops    uint64  `json:"drops"`
+}
+
+var (
+	networkCache   NetworkStats
+	networkCacheMu sync.RWMutex
+	networkPrev    []net.IOCountersStat
+	networkPrevAt  time.Time
+)
+
+// collectNetwork updates the cached network stats; called from the stats
+// collector loop, which sets the interval the rates are measured over
+func collectNetwork() {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	stats := NetworkStats{Interfaces: interfaceRates(networkPrev, counters, now.Sub(networkPrevAt))}
+	networkPrev, networkPrevAt = counters, now
+
+	if protos, err := net.ProtoCounters([]string{"tcp"}); err == nil && len(protos) > 0 {
+		stats.Connections = int(protos[0].Stats["CurrEstab"])
+	}
+
+	networkCacheMu.Lock()
+	networkCache = stats
+	networkCacheMu.Unlock()
+}
+
+// interfaceRates turns two readings of the interface counters into
+// per-interface stats. Loopback and container veth pairs are left out; a
+// container's traffic is reported per app instead.
+func interfaceRates(prev, cur []net.IOCountersStat, elapsed time.Duration) []InterfaceStats {
+	before := make(map[string]net.IOCountersStat, len(prev))
+	for _, c := range prev {
+		before[c.Name] = c
+	}
+
+	interfaces := []InterfaceStats{}
+	for _, c := range cur {
+		if c.Name == "lo" || strings.HasPrefix(c.Name, "veth") {
+			continue
+		}
+		iface := InterfaceStats{
+			Name:     c.Name,
+			Physical: isPhysical(c.Name),
+			RxBytes:  c.BytesRecv,
+			TxBytes:  c.BytesSent,
+			Errors:   c.Errin + c.Errout,
+			Drops:    c.Dropin + c.Dropout,
+		}
+		// Counters reset when an interface is recreated
+		if p, ok := before[c.Name]; ok && elapsed > 0 && c.BytesRecv >= p.BytesRecv && c.BytesSent >= p.BytesSent {
+			iface.RxRate = float64(c.BytesRecv-p.BytesRecv) / elapsed.Seconds()
+			iface.TxRate = float64(c.BytesSent-p.BytesSent) / elapsed.Seconds()
+		}
+		interfaces = append(interfaces, iface)
+	}
+	sort.Slice(interfaces, func(i, j int) bool {
+		if interfaces[i].Physical != interfaces[j].Physical {
+			return interfaces[i].Physical
+		}
+		return interfaces[i].Name < interfaces[j].Name
+	})
+	return interfaces
+}
+
+// isPhysical reports whether an interface is backed by a device
+func isPhysical(name string) bool {
+	_, err := os.Stat(filepath.Join("/sys/class/net", name, "device"))
+	return err == nil
+}
+
+// GetNetworkStats returns the cached network stats
+func GetNetworkStats() NetworkStats {
+	networkCacheMu.RLock()
+	defer networkCacheMu.RUnlock()
+
+	stats := networkCache
+	stats.Interfaces = append([]InterfaceStats{}, networkCache.Interfaces...)
+	return stats
+}
@@ -0,0 +1,33 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterfaceRates(t *testing.T) {
+	prev := []net.IOCountersStat{
+		{Name: "lo", BytesRecv: 100, BytesSent: 100},
+		{Name: "test-eth", BytesRecv: 1000, BytesSent: 500},
+		{Name: "test-wg", BytesRecv: 9000, BytesSent: 9000},
+	}
+	cur := []net.IOCountersStat{
+		{Name: "lo", BytesRecv: 200, BytesSent: 200},
+		{Name: "vethab12", BytesRecv: 50, BytesSent: 50},
+		{Name: "test-eth", BytesRecv: 5000, BytesSent: 2500, Errin: 1, Dropout: 2},
+		{Name: "test-wg", BytesRecv: 10, BytesSent: 10}, // recreated
+		{Name: "test-new", BytesRecv: 10, BytesSent: 10},
+	}
+
+	interfaces := interfaceRates(prev, cur, 2*time.Second)
+	require.Len(t, interfaces, 3, "loopback and veth pairs are left out")
+	assert.Equal(t, InterfaceStats{Name: "test-eth", RxBytes: 5000, TxBytes: 2500, RxRate: 2000, TxRate: 1000, Errors: 1, Drops: 2}, interfaces[0])
+	assert.Equal(t, "test-new", interfaces[1].Name)
+	assert.Zero(t, interfaces[1].RxRate, "no previous reading")
+	assert.Equal(t, "test-wg", interfaces[2].Name)
+	assert.Zero(t, interfaces[2].RxRate, "counters went backwards")
+}
@@ -0,0 +1,90 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Thermal is the CPU's temperature and how often it has throttled itself
+type Thermal struct {
+	CPUTemp   float64 // package temperature in °C; 0 without a sensor
+	Throttles uint64  // thermal throttle events since boot; Intel only
+}
+
+// cpuSensors are the hwmon drivers that report the CPU's temperature, with
+// the label of the package reading where the driver has several. Other
+// readings of the same driver are per core.
+var cpuSensors = map[string][]string{
+	"coretemp":    {"Package id 0"}, // Intel
+	"k10temp":     {"Tdie", "Tctl"}, // AMD
+	"zenpower":    {"Tdie", "Tctl"},
+	"cpu_thermal": nil, // Raspberry Pi and other ARM boards
+}
+
+// ReadThermal reads the CPU sensors and throttle counters under the sysfs
+// root (normally /sys)
+func ReadThermal(sysfs string) Thermal {
+	return Thermal{
+		CPUTemp:   cpuTemp(sysfs),
+		Throttles: throttleCount(sysfs),
+	}
+}
+
+// cpuTemp returns the package temperature of the first CPU sensor, falling
+// back to its hottest reading
+func cpuTemp(sysfs string) float64 {
+	dirs, _ := filepath.Glob(filepath.Join(sysfs, "class", "hwmon", "hwmon*"))
+	for _, dir := range dirs {
+		labels, ok := cpuSensors[readTrimmed(filepath.Join(dir, "name"))]
+		if !ok {
+			continue
+		}
+
+		readings := map[string]float64{}
+		var hottest float64
+		inputs, _ := filepath.Glob(filepath.Join(dir, "temp*_input"))
+		for _, input := range inputs {
+			millideg, err := strconv.Atoi(readTrimmed(input))
+			if err != nil {
+				continue
+			}
+			temp := float64(millideg) / 1000
+			readings[readTrimmed(strings.TrimSuffix(input, "_input")+"_label")] = temp
+			hottest = max(hottest, temp)
+		}
+		for _, label := range labels {
+			if temp, ok := readings[label]; ok {
+				return temp
+			}
+		}
+		if hottest > 0 {
+			return hottest
+		}
+	}
+	return 0
+}
+
+// throttleCount returns how many times the CPU package has throttled since
+// boot. Every CPU reports its package's counter, so single-socket machines
+// (all Bloud targets) see the same value on each; the highest is taken.
+func throttleCount(sysfs string) uint64 {
+	files, _ := filepath.Glob(filepath.Join(sysfs, "devices", "system", "cpu", "cpu*", "thermal_throttle", "package_throttle_count"))
+	var count uint64
+	for _, file := range files {
+		n, err := strconv.ParseUint(readTrimmed(file), 10, 64)
+		if err == nil {
+			count = max(count, n)
+		}
+	}
+	return count
+}
+
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
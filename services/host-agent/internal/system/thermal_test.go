@@ -0,0 +1,53 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSysfs(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content+"\n"), 0644))
+	}
+}
+
+func TestReadThermal_Intel(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, map[string]string{
+		"class/hwmon/hwmon0/name":                                         "acpitz",
+		"class/hwmon/hwmon0/temp1_input":                                  "99000",
+		"class/hwmon/hwmon1/name":                                         "coretemp",
+		"class/hwmon/hwmon1/temp1_label":                                  "Package id 0",
+		"class/hwmon/hwmon1/temp1_input":                                  "71000",
+		"class/hwmon/hwmon1/temp2_label":                                  "Core 0",
+		"class/hwmon/hwmon1/temp2_input":                                  "74500",
+		"devices/system/cpu/cpu0/thermal_throttle/package_throttle_count": "12",
+		"devices/system/cpu/cpu1/thermal_throttle/package_throttle_count": "12",
+	})
+
+	assert.Equal(t, Thermal{CPUTemp: 71, Throttles: 12}, ReadThermal(root))
+}
+
+func TestReadThermal_AMD(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, map[string]string{
+		"class/hwmon/hwmon2/name":        "k10temp",
+		"class/hwmon/hwmon2/temp1_label": "Tctl",
+		"class/hwmon/hwmon2/temp1_input": "65250",
+		"class/hwmon/hwmon2/temp3_label": "Tccd1",
+		"class/hwmon/hwmon2/temp3_input": "60000",
+	})
+
+	assert.Equal(t, Thermal{CPUTemp: 65.25}, ReadThermal(root))
+}
+
+func TestReadThermal_NoSensor(t *testing.T) {
+	assert.Equal(t, Thermal{}, ReadThermal(t.TempDir()))
+}
@@ -29,6 +29,10 @@ CREATE TABLE IF NOT EXISTS apps (
     port INTEGER,
     is_system BOOLEAN NOT NULL DEFAULT FALSE,
     integration_config TEXT,
+    uninstalled_at TIMESTAMP,
+    image TEXT NOT NULL DEFAULT '',
+    image_digest TEXT NOT NULL DEFAULT '',
+    previous_image_digest TEXT NOT NULL DEFAULT '',
     installed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
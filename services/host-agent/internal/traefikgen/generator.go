@@ -8,12 +8,14 @@ import (
 	"strings"
 
 	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/catalog"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/mdns"
 )
 
 // Generator generates Traefik dynamic configuration for installed apps
 type Generator struct {
 	configPath       string // Path to apps-routes.yml
 	authentikEnabled bool   // Whether Authentik is installed (for SSO middlewares)
+	localHostnames   bool   // Whether apps are advertised as <app>.local over mDNS
 }
 
 // NewGenerator creates a Traefik config generator
@@ -28,6 +30,12 @@ func (g *Generator) SetAuthentikEnabled(enabled bool) {
 	g.authentikEnabled = enabled
 }
 
+// SetLocalHostnames adds a Host(`<app>.local`) router for each app, for apps
+// advertised over mDNS
+func (g *Generator) SetLocalHostnames(enabled bool) {
+	g.localHostnames = enabled
+}
+
 // Generate creates Traefik routes for the given installed apps
 func (g *Generator) Generate(apps []*catalog.App) error {
 	config := g.generateConfig(apps)
@@ -84,6 +92,9 @@ func (g *Generator) generateConfig(apps []*catalog.App) string {
 	for _, app := range routableApps {
 		g.writeRouter(&b, app, g.authentikEnabled)
 		g.writeAbsolutePathRouters(&b, app)
+		if g.localHostnames {
+			g.writeHostnameRouter(&b, app)
+		}
 	}
 
 	// Generate middlewares section
@@ -224,6 +235,40 @@ func (g *Generator) writeAbsolutePathRouters(b *strings.Builder, app *catalog.Ap
 	}
 }
 
+// writeHostnameRouter writes the router serving an app at its mDNS hostname,
+// e.g. http://miniflux.local. Apps that keep their /embed/<app> prefix are
+// redirected there from the root.
+func (g *Generator) writeHostnameRouter(b *strings.Builder, app *catalog.App) {
+	b.WriteString(fmt.Sprintf("    %s-local:\n", app.Name))
+	b.WriteString(fmt.Sprintf("      rule: \"Host(`%s`)\"\n", mdns.Hostname(app.Name)))
+
+	var middlewares []string
+
+	// Forward auth applies however the app is reached
+	if app.SSO.Strategy == "forward-auth" && g.authentikEnabled {
+		middlewares = append(middlewares, fmt.Sprintf("%s-forwardauth", app.Name))
+	}
+
+	// Links the app generated for the embedded route still carry the prefix
+	if shouldStripPrefix(app) {
+		middlewares = append(middlewares, fmt.Sprintf("%s-stripprefix", app.Name))
+	} else {
+		middlewares = append(middlewares, fmt.Sprintf("%s-root", app.Name))
+	}
+
+	if app.Routing != nil && len(app.Routing.Headers) > 0 {
+		middlewares = append(middlewares, fmt.Sprintf("%s-headers", app.Name))
+	}
+
+	b.WriteString("      middlewares:\n")
+	for _, mw := range middlewares {
+		b.WriteString(fmt.Sprintf("        - %s\n", mw))
+	}
+
+	b.WriteString(fmt.Sprintf("      service: %s\n", app.Name))
+	b.WriteString("      priority: 100\n")
+}
+
 // writeMiddleware writes the middleware configuration for an app
 func (g *Generator) writeMiddleware(b *strings.Builder, app *catalog.App) {
 	// ForwardAuth middleware for apps using forward-auth SSO strategy
@@ -252,6 +297,14 @@ func (g *Generator) writeMiddleware(b *strings.Builder, app *catalog.App) {
 		b.WriteString(fmt.Sprintf("          - \"%s\"\n", pathPrefix))
 	}
 
+	// Redirect from the root of the app's mDNS hostname to where it's served
+	if g.localHostnames && !shouldStripPrefix(app) {
+		b.WriteString(fmt.Sprintf("    %s-root:\n", app.Name))
+		b.WriteString("      redirectRegex:\n")
+		b.WriteString("        regex: \"^(https?://[^/]+)/?$\"\n")
+		b.WriteString(fmt.Sprintf("        replacement: \"${1}/embed/%s/\"\n", app.Name))
+	}
+
 	// Custom headers middleware (only if app has routing headers)
 	if app.Routing != nil && len(app.Routing.Headers) > 0 {
 		g.writeHeadersMiddleware(b, fmt.Sprintf("%s-headers", app.Name), app.Routing.Headers)
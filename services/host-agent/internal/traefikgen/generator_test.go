@@ -659,3 +659,28 @@ func TestGenerator_Generate_ForwardAuth_AuthentikDisabled(t *testing.T) {
 	}
 }
 
+
+func TestGolden_LocalHostnames(t *testing.T) {
+	g := NewGenerator("/tmp/test.yml")
+	g.SetLocalHostnames(true)
+	g.SetAuthentikEnabled(true)
+	apps := []*catalog.App{
+		{Name: "jellyfin", Port: 8096, IsSystem: false},
+		{
+			Name:     "miniflux",
+			Port:     8085,
+			IsSystem: false,
+			SSO:      catalog.SSO{Strategy: "forward-auth"},
+			Routing: &catalog.Routing{
+				StripPrefix: boolPtr(false),
+			},
+		},
+	}
+
+	got := g.Preview(apps)
+	want := loadGoldenFile(t, "local_hostnames.golden.yml")
+
+	if got != want {
+		t.Errorf("Output mismatch.\nGot:\n%s\nWant:\n%s", got, want)
+	}
+}
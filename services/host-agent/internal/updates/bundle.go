@@ -0,0 +1,193 @@
+package updates
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+var (
+	// ErrInvalidBundle is returned for an offline update bundle that can't
+	// be applied: malformed, incomplete, or not signed by an update key
+	ErrInvalidBundle = errors.New("invalid update bundle")
+	// ErrNoUpdateKeys is returned for offline updates on a host with no
+	// update keys to check bundles against
+	ErrNoUpdateKeys = errors.New("no update keys configured")
+)
+
+// bundleSystem matches the NixOS system a bundle names. It's switched to
+// with a shell script, so nothing else gets through.
+var bundleSystem = regexp.MustCompile(`^/nix/store/[0-9a-z]{32}-nixos-system-[0-9A-Za-z+._-]+$`)
+
+// bundleManifest is bundle.json, naming the system in the bundle's cache
+type bundleManifest struct {
+	System string `json:"system"`
+}
+
+// ApplyBundle updates from an offline bundle, for hosts that can't reach the
+// Bloud repository. A bundle is a tar (optionally gzipped) of bundle.json,
+// naming a NixOS system, and cache/, a Nix binary cache holding the
+// system's closure. The system must be signed by one of Config.UpdateKeys.
+//
+// The bundle is imported before ApplyBundle returns; switching to it,
+// with the usual snapshot and verification, continues in the background.
+func (u *Updater) ApplyBundle(ctx context.Context, r io.Reader) error {
+	if len(u.cfg.UpdateKeys) == 0 {
+		return ErrNoUpdateKeys
+	}
+	if !u.mu.TryLock() {
+		return ErrBusy
+	}
+	system, err := u.importBundle(ctx, r)
+	if err != nil {
+		u.mu.Unlock()
+		return err
+	}
+
+	st, err := u.store.State()
+	if err != nil {
+		u.mu.Unlock()
+		return err
+	}
+	next := store.UpdateState{LastAttemptAt: u.now(), Status: StatusApplying, Snapshot: st.Snapshot}
+	current, err := os.Readlink(u.currentSystem)
+	if err != nil {
+		defer u.mu.Unlock()
+		return u.fail(next, fmt.Errorf("finding the running system: %w", err))
+	}
+	next.FromSystem = current
+	if system == current {
+		defer u.mu.Unlock()
+		next.Status = StatusUpToDate
+		return u.store.SaveState(next)
+	}
+
+	// Saved before returning, so the status shows the update under way
+	next.ToSystem = system
+	if err := u.store.SaveState(next); err != nil {
+		u.mu.Unlock()
+		return err
+	}
+	go func() {
+		defer u.mu.Unlock()
+		if err := u.switchTo(context.WithoutCancel(ctx), next); err != nil {
+			u.logger.Error("offline update failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// importBundle unpacks a bundle and copies its system into the Nix store
+func (u *Updater) importBundle(ctx context.Context, r io.Reader) (string, error) {
+	dir, err := os.MkdirTemp(u.cfg.DataDir, ".update-bundle-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	manifest, err := extractBundle(r, dir)
+	if err != nil {
+		return "", err
+	}
+	system := manifest.System
+	if !bundleSystem.MatchString(system) {
+		return "", fmt.Errorf("%w: %q isn't a NixOS system", ErrInvalidBundle, system)
+	}
+
+	u.logger.InfoContext(ctx, "importing update bundle", "system", system)
+	if _, err := u.command(ctx, "nix", "copy", "--from", "file://"+filepath.Join(dir, "cache"), system); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+	// nix copy accepts anything signed by a key the store trusts, such as
+	// cache.nixos.org, so the system must also be signed by an update key.
+	// It names its whole closure, so checking it alone is enough.
+	if _, err := u.command(ctx, "nix", "store", "verify", "--no-contents", "--sigs-needed", "1",
+		"--trusted-public-keys", strings.Join(u.cfg.UpdateKeys, " "), system); err != nil {
+		return "", fmt.Errorf("%w: not signed by an update key: %w", ErrInvalidBundle, err)
+	}
+	return system, nil
+}
+
+// extractBundle unpacks a bundle into dir and returns its manifest
+func extractBundle(r io.Reader, dir string) (*bundleManifest, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+		}
+		name := filepath.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name == "." {
+			continue
+		}
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("%w: unsafe path %q", ErrInvalidBundle, hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0700); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				return nil, err
+			}
+			if err := writeFile(path, tr); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: %q isn't a file or directory", ErrInvalidBundle, hdr.Name)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "bundle.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing bundle.json", ErrInvalidBundle)
+	}
+	var manifest bundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: parsing bundle.json: %v", ErrInvalidBundle, err)
+	}
+	return &manifest, nil
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+	return f.Close()
+}
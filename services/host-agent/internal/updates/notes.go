@@ -0,0 +1,182 @@
+package updates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+// notesRepoDir is the bare clone of the Bloud repository release notes are
+// read from, in the data directory. Only commits and trees are fetched.
+const notesRepoDir = ".bloud-update-repo.git"
+
+// maxReleaseChanges bounds the changes kept for an update; breaking
+// changes, migrations and new apps are always kept
+const maxReleaseChanges = 200
+
+// Commit message conventions release notes are parsed from: a "!" before
+// the subject's colon or a BREAKING CHANGE trailer marks a breaking
+// change, and a Migration trailer says what to do about it
+var (
+	breakingSubject  = regexp.MustCompile(`^[a-z]+(\([^)]*\))?!:`)
+	breakingTrailer  = regexp.MustCompile(`^BREAKING[ -]CHANGE:\s*(.+)$`)
+	migrationTrailer = regexp.MustCompile(`^Migration:\s*(.+)$`)
+)
+
+// Separators in the git log format, which commit messages don't contain
+const (
+	logFieldSep  = "\x1f"
+	logCommitSep = "\x1e"
+)
+
+// releaseNotes reads what an update on a channel brings from the commits
+// between the running system's revision and the channel's head. It needs
+// git, the data directory to clone into, and a system built from a clean
+// checkout, which records its revision.
+func (u *Updater) releaseNotes(ctx context.Context, channel string) (*store.ReleaseNotes, error) {
+	if u.cfg.Git == "" || u.cfg.DataDir == "" {
+		return nil, nil
+	}
+	from, err := u.runningRevision(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if from == "" {
+		return nil, errors.New("the running system doesn't record its revision")
+	}
+	to, err := u.channelRevision(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := u.fetchChannel(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	notes := &store.ReleaseNotes{FromRevision: from, ToRevision: to}
+
+	out, err := u.command(ctx, u.cfg.Git, "-C", repo, "log", "--no-merges",
+		"--format=%H"+logFieldSep+"%s"+logFieldSep+"%b"+logCommitSep, from+".."+to)
+	if err != nil {
+		return nil, fmt.Errorf("reading the commit log: %w", err)
+	}
+	for _, entry := range strings.Split(string(out), logCommitSep) {
+		fields := strings.SplitN(strings.TrimSpace(entry), logFieldSep, 3)
+		if len(fields) < 2 {
+			continue
+		}
+		body := ""
+		if len(fields) == 3 {
+			body = fields[2]
+		}
+		addCommit(notes, fields[0], fields[1], body)
+	}
+	if len(notes.Changes) > maxReleaseChanges {
+		notes.Changes = notes.Changes[:maxReleaseChanges]
+		notes.Truncated = true
+	}
+
+	// Apps are new when their catalog metadata was added
+	out, err = u.command(ctx, u.cfg.Git, "-C", repo, "diff", "--name-only", "--diff-filter=A",
+		from, to, "--", "apps/*/metadata.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("listing new apps: %w", err)
+	}
+	for _, file := range strings.Fields(string(out)) {
+		notes.NewApps = append(notes.NewApps, path.Base(path.Dir(file)))
+	}
+	return notes, nil
+}
+
+// runningRevision returns the revision of the Bloud repository the running
+// system was built from, or "" if it wasn't recorded
+func (u *Updater) runningRevision(ctx context.Context) (string, error) {
+	out, err := u.command(ctx, "nixos-version", "--json")
+	if err != nil {
+		return "", fmt.Errorf("finding the running revision: %w", err)
+	}
+	var version struct {
+		ConfigurationRevision string `json:"configurationRevision"`
+	}
+	if err := json.Unmarshal(out, &version); err != nil {
+		return "", fmt.Errorf("parsing nixos-version: %w", err)
+	}
+	return version.ConfigurationRevision, nil
+}
+
+// channelRevision returns the revision at the head of a channel's branch
+func (u *Updater) channelRevision(ctx context.Context, channel string) (string, error) {
+	out, err := u.command(ctx, "nix", "flake", "metadata", "--json", "--refresh", channelRef(u.cfg.Repo, channel))
+	if err != nil {
+		return "", fmt.Errorf("finding the %s channel's revision: %w", channel, err)
+	}
+	var metadata struct {
+		Revision string `json:"revision"`
+	}
+	if err := json.Unmarshal(out, &metadata); err != nil {
+		return "", fmt.Errorf("parsing flake metadata: %w", err)
+	}
+	if metadata.Revision == "" {
+		return "", fmt.Errorf("the %s channel has no revision", channel)
+	}
+	return metadata.Revision, nil
+}
+
+// fetchChannel brings the clone up to date with a channel's branch, cloning
+// the repository the first time, and returns the clone's path
+func (u *Updater) fetchChannel(ctx context.Context, channel string) (string, error) {
+	repo := filepath.Join(u.cfg.DataDir, notesRepoDir)
+	if _, err := os.Stat(repo); os.IsNotExist(err) {
+		_, err := u.command(ctx, u.cfg.Git, "clone", "--bare", "--quiet", "--filter=blob:none", "--no-tags", gitURL(u.cfg.Repo), repo)
+		if err != nil {
+			return "", fmt.Errorf("cloning %s: %w", gitURL(u.cfg.Repo), err)
+		}
+		return repo, nil
+	}
+	ref := "refs/heads/" + channel
+	if _, err := u.command(ctx, u.cfg.Git, "-C", repo, "fetch", "--quiet", "--filter=blob:none", "--no-tags",
+		"origin", "+"+ref+":"+ref); err != nil {
+		return "", fmt.Errorf("fetching the %s channel: %w", channel, err)
+	}
+	return repo, nil
+}
+
+// addCommit records a commit in notes, with its breaking change and
+// migration notes
+func addCommit(notes *store.ReleaseNotes, commit, subject, body string) {
+	change := store.ReleaseChange{Commit: commit, Summary: subject}
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if m := breakingTrailer.FindStringSubmatch(line); m != nil {
+			change.Breaking = true
+			notes.Breaking = append(notes.Breaking, m[1])
+		}
+		if m := migrationTrailer.FindStringSubmatch(line); m != nil {
+			notes.Migrations = append(notes.Migrations, m[1])
+		}
+	}
+	// A "!" alone makes the subject the breaking change
+	if !change.Breaking && breakingSubject.MatchString(subject) {
+		change.Breaking = true
+		notes.Breaking = append(notes.Breaking, subject)
+	}
+	notes.Changes = append(notes.Changes, change)
+}
+
+// gitURL returns the git URL of a flake URL such as DefaultRepo:
+// git+https://host/repo?ref=x is https://host/repo
+func gitURL(flake string) string {
+	flake = strings.TrimPrefix(flake, "git+")
+	if i := strings.Index(flake, "?"); i >= 0 {
+		flake = flake[:i]
+	}
+	return flake
+}
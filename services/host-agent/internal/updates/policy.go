@@ -0,0 +1,119 @@
+// Package updates keeps the host up to date. Inside a maintenance window it
+// builds the next NixOS system from the configured release channel and
+// switches to it, notifying the admin of the outcome.
+package updates
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+// Update modes
+const (
+	// ModeOff never updates automatically
+	ModeOff = "off"
+	// ModeSecurity keeps the installed Bloud release and picks up the fixes
+	// backported to its NixOS release branch
+	ModeSecurity = "security-only"
+	// ModeAll follows the channel to new Bloud releases
+	ModeAll = "all"
+)
+
+// Release channels, branches of the Bloud repository
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+// clockFormat is the format of window times
+const clockFormat = "15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// DefaultPolicy is the policy until one is set: no automatic updates, and a
+// nightly window for when they're turned on
+func DefaultPolicy() store.UpdatePolicy {
+	return store.UpdatePolicy{
+		Mode:    ModeOff,
+		Channel: ChannelStable,
+		Window:  store.UpdateWindow{Start: "03:00", End: "05:00"},
+	}
+}
+
+// Validate checks a policy
+func Validate(p store.UpdatePolicy) error {
+	switch p.Mode {
+	case ModeOff, ModeSecurity, ModeAll:
+	default:
+		return fmt.Errorf("mode must be off, security-only or all")
+	}
+	switch p.Channel {
+	case ChannelStable, ChannelBeta:
+	default:
+		return fmt.Errorf("channel must be stable or beta")
+	}
+
+	start, err := time.Parse(clockFormat, p.Window.Start)
+	if err != nil {
+		return fmt.Errorf("window start must be HH:MM")
+	}
+	end, err := time.Parse(clockFormat, p.Window.End)
+	if err != nil {
+		return fmt.Errorf("window end must be HH:MM")
+	}
+	if start.Equal(end) {
+		return fmt.Errorf("window start and end must differ")
+	}
+	for _, d := range p.Window.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("unknown day %q", d)
+		}
+	}
+	return nil
+}
+
+// InWindow reports whether t is inside the maintenance window, and when the
+// occurrence it's in opened. The window is assumed valid.
+func InWindow(w store.UpdateWindow, t time.Time) (time.Time, bool) {
+	// A window past midnight may have opened the day before
+	for _, day := range []time.Time{t, t.AddDate(0, 0, -1)} {
+		start, end, ok := occurrence(w, day)
+		if ok && !t.Before(start) && t.Before(end) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// occurrence returns the start and end of the window opening on day's date,
+// and whether it opens that weekday
+func occurrence(w store.UpdateWindow, day time.Time) (start, end time.Time, ok bool) {
+	if len(w.Days) > 0 {
+		found := false
+		for _, d := range w.Days {
+			if weekdays[strings.ToLower(d)] == day.Weekday() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	s, _ := time.Parse(clockFormat, w.Start)
+	e, _ := time.Parse(clockFormat, w.End)
+	y, m, d := day.Date()
+	start = time.Date(y, m, d, s.Hour(), s.Minute(), 0, 0, day.Location())
+	end = time.Date(y, m, d, e.Hour(), e.Minute(), 0, 0, day.Location())
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, true
+}
@@ -0,0 +1,141 @@
+package updates
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+// snapshotTimeFormat stamps snapshot names, as database backups are
+const snapshotTimeFormat = "20060102T150405Z"
+
+// btrfsSnapshotDir holds btrfs snapshots, at the root of the filesystem
+const btrfsSnapshotDir = ".bloud-snapshots"
+
+// snapshot saves user data before switching to an update: a database
+// backup, a copy of the secrets file and a read-only snapshot of the data
+// directory's filesystem when it's btrfs or ZFS
+func (u *Updater) snapshot(ctx context.Context) (*store.UpdateSnapshot, error) {
+	snapshot := &store.UpdateSnapshot{TakenAt: u.now()}
+	stamp := snapshot.TakenAt.UTC().Format(snapshotTimeFormat)
+
+	if u.cfg.Database != nil {
+		backup, err := u.cfg.Database.Backup(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("backing up the database: %w", err)
+		}
+		snapshot.Database = backup.Name
+	}
+
+	if u.cfg.SecretsPath != "" {
+		path, err := copySecrets(u.cfg.SecretsPath, stamp)
+		if err != nil {
+			return nil, fmt.Errorf("copying secrets: %w", err)
+		}
+		snapshot.Secrets = path
+	}
+
+	if u.cfg.DataDir != "" {
+		fstype, name, err := u.snapshotFilesystem(ctx, stamp)
+		if err != nil {
+			return nil, fmt.Errorf("snapshotting %s: %w", u.cfg.DataDir, err)
+		}
+		snapshot.Filesystem, snapshot.Name = fstype, name
+	}
+
+	u.logger.InfoContext(ctx, "took pre-update snapshot", "database", snapshot.Database,
+		"secrets", snapshot.Secrets, "filesystem", snapshot.Filesystem, "name", snapshot.Name)
+	return snapshot, nil
+}
+
+// copySecrets copies the secrets file to backups/update beside it. Backends
+// that keep no file, like Vault, have nothing to copy.
+func copySecrets(path, stamp string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(filepath.Dir(path), "backups", "update")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	dest := filepath.Join(dir, "secrets-"+stamp+".json")
+	tmp := dest + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return dest, nil
+}
+
+// snapshotFilesystem takes a read-only snapshot of the filesystem holding
+// the data directory, and returns its type and the snapshot's name. Other
+// filesystems can't be snapshotted and are skipped.
+func (u *Updater) snapshotFilesystem(ctx context.Context, stamp string) (fstype, name string, err error) {
+	out, err := u.command(ctx, "findmnt", "--noheadings", "--raw", "--output", "FSTYPE,SOURCE,TARGET", "--target", u.cfg.DataDir)
+	if err != nil {
+		return "", "", err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 3 {
+		return "", "", fmt.Errorf("unexpected findmnt output %q", strings.TrimSpace(string(out)))
+	}
+	fstype, source, target := fields[0], fields[1], fields[2]
+
+	switch fstype {
+	case "btrfs":
+		dir := filepath.Join(target, btrfsSnapshotDir)
+		name = filepath.Join(dir, "update-"+stamp)
+		if _, err := u.sudo(ctx, "mkdir", "-p", dir); err != nil {
+			return "", "", err
+		}
+		if _, err := u.sudo(ctx, "btrfs", "subvolume", "snapshot", "-r", target, name); err != nil {
+			return "", "", err
+		}
+	case "zfs":
+		// The source is the dataset, e.g. bloud/data
+		name = source + "@bloud-update-" + stamp
+		if _, err := u.sudo(ctx, "zfs", "snapshot", name); err != nil {
+			return "", "", err
+		}
+	default:
+		return "", "", nil
+	}
+	return fstype, name, nil
+}
+
+// removeSnapshot removes the secrets copy and filesystem snapshot of an
+// earlier update once a newer one was taken, so only the latest is kept.
+// Database backups are left to the backup retention.
+func (u *Updater) removeSnapshot(ctx context.Context, snapshot *store.UpdateSnapshot) {
+	if snapshot == nil {
+		return
+	}
+	if snapshot.Secrets != "" {
+		if err := os.Remove(snapshot.Secrets); err != nil && !os.IsNotExist(err) {
+			u.logger.WarnContext(ctx, "failed to remove old secrets copy", "path", snapshot.Secrets, "error", err)
+		}
+	}
+
+	var err error
+	switch snapshot.Filesystem {
+	case "btrfs":
+		_, err = u.sudo(ctx, "btrfs", "subvolume", "delete", snapshot.Name)
+	case "zfs":
+		_, err = u.sudo(ctx, "zfs", "destroy", snapshot.Name)
+	}
+	if err != nil {
+		u.logger.WarnContext(ctx, "failed to remove old update snapshot", "name", snapshot.Name, "error", err)
+	}
+}
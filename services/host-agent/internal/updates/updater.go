@@ -0,0 +1,687 @@
+package updates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/nixgen"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/notify"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+)
+
+// Update statuses
+const (
+	StatusUpToDate    = "up-to-date" // nothing new to switch to
+	StatusApplying    = "applying"   // switching to the new system
+	StatusVerifying   = "verifying"  // waiting for critical components on the new system
+	StatusApplied     = "applied"
+	StatusFailed      = "failed"
+	StatusRollingBack = "rolling-back" // switching back to the system the update replaced
+	StatusRolledBack  = "rolled-back"
+)
+
+var (
+	// ErrNoRollback is returned when there is no applied update to roll back
+	ErrNoRollback = errors.New("no applied update to roll back")
+	// ErrBusy is returned when an update is already under way
+	ErrBusy = errors.New("an update is in progress")
+)
+
+// DefaultVerifyTimeout is how long critical components may fail after
+// switching to an update before it's rolled back
+const DefaultVerifyTimeout = 10 * time.Minute
+
+// verifyInterval is how often critical components are checked while an
+// update is verified
+const verifyInterval = 30 * time.Second
+
+// DefaultRepo is the Bloud repository; release channels are its branches
+const DefaultRepo = "git+https://codeberg.org/d-buckner/bloud-v3"
+
+// switchUnit is the transient systemd unit the new system is switched to
+// in, so the switch outlives the host agent when it restarts the host agent
+const switchUnit = "bloud-update"
+
+// systemProfile is the NixOS system profile; boot entries and rollbacks come
+// from its generations
+const systemProfile = "/nix/var/nix/profiles/system"
+
+// Config configures an Updater
+type Config struct {
+	Repo      string // flake URL of the Bloud repository, usually DefaultRepo
+	SourceDir string // flake of the installed release
+	Target    string // nixosConfigurations attribute, e.g. "bloud"
+	// What is saved before switching to an update, each skipped when unset:
+	// the database, the secrets file, and the filesystem holding DataDir
+	// when it's btrfs or ZFS
+	Database    DatabaseBackup
+	SecretsPath string
+	DataDir     string
+	// Verify checks the critical components once an update is running. The
+	// update is rolled back when they still fail VerifyTimeout after the
+	// switch (DefaultVerifyTimeout if unset). Nil applies updates unverified.
+	Verify        func(ctx context.Context) error
+	VerifyTimeout time.Duration
+	// UpdateKeys are the public keys offline update bundles must be signed
+	// by; none rejects them
+	UpdateKeys []string
+	// Git reads release notes for available updates from the repository's
+	// commit log, cloned into DataDir. Unset skips release notes.
+	Git string
+}
+
+// DatabaseBackup takes a database backup, as db.Maintainer does
+type DatabaseBackup interface {
+	Backup(ctx context.Context) (*db.Backup, error)
+}
+
+// Status is the outcome of the last update and when the next may run
+type Status struct {
+	LastAttemptAt *time.Time `json:"lastAttemptAt,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	FromSystem    string     `json:"fromSystem,omitempty"` // NixOS system the update started from
+	ToSystem      string     `json:"toSystem,omitempty"`   // NixOS system it switched to
+	SwitchedAt    *time.Time `json:"switchedAt,omitempty"` // when ToSystem started running
+	NextWindow    *time.Time `json:"nextWindow,omitempty"` // unset while updates are off
+	// Snapshot is the user data saved before the last switch to an update
+	Snapshot *store.UpdateSnapshot `json:"snapshot,omitempty"`
+	// Available is the update the last check found, until it's running
+	Available *store.AvailableUpdate `json:"available,omitempty"`
+}
+
+// Updater applies updates according to the stored policy
+type Updater struct {
+	cfg           Config
+	store         store.UpdateStoreInterface
+	notifier      notify.Publisher // nil sends no notifications
+	logger        *slog.Logger
+	currentSystem string // symlink to the running system
+	command       func(ctx context.Context, name string, args ...string) ([]byte, error)
+	now           func() time.Time
+	wait          func(ctx context.Context, d time.Duration) error
+
+	mu sync.Mutex // one update at a time
+}
+
+// New creates an updater
+func New(cfg Config, st store.UpdateStoreInterface, notifier notify.Publisher, logger *slog.Logger) *Updater {
+	if cfg.Repo == "" {
+		cfg.Repo = DefaultRepo
+	}
+	if cfg.VerifyTimeout <= 0 {
+		cfg.VerifyTimeout = DefaultVerifyTimeout
+	}
+	return &Updater{
+		cfg:           cfg,
+		store:         st,
+		notifier:      notifier,
+		logger:        logger,
+		currentSystem: "/run/current-system",
+		command:       runCommand,
+		now:           time.Now,
+		wait:          wait,
+	}
+}
+
+// Policy returns the stored policy, or the default
+func (u *Updater) Policy() (store.UpdatePolicy, error) {
+	p, err := u.store.Policy()
+	if err != nil {
+		return store.UpdatePolicy{}, err
+	}
+	if p == nil {
+		return DefaultPolicy(), nil
+	}
+	return *p, nil
+}
+
+// SetPolicy validates and stores a policy
+func (u *Updater) SetPolicy(p store.UpdatePolicy) error {
+	if err := Validate(p); err != nil {
+		return err
+	}
+	return u.store.SetPolicy(p)
+}
+
+// Status returns the outcome of the last update and the next window
+func (u *Updater) Status() (Status, error) {
+	policy, err := u.Policy()
+	if err != nil {
+		return Status{}, err
+	}
+	st, err := u.store.State()
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{Status: st.Status, Error: st.Error, FromSystem: st.FromSystem, ToSystem: st.ToSystem, Snapshot: st.Snapshot}
+	if !st.LastAttemptAt.IsZero() {
+		status.LastAttemptAt = &st.LastAttemptAt
+	}
+	if !st.SwitchedAt.IsZero() {
+		status.SwitchedAt = &st.SwitchedAt
+	}
+	if policy.Mode != ModeOff {
+		if next, ok := nextWindow(policy.Window, u.now()); ok {
+			status.NextWindow = &next
+		}
+	}
+
+	available, err := u.store.Available()
+	if err != nil {
+		return Status{}, err
+	}
+	if current, _ := os.Readlink(u.currentSystem); available != nil && available.System != current {
+		status.Available = available
+	}
+	return status, nil
+}
+
+// Run is the scheduled job. It finishes an update that was still being
+// applied, then updates once per maintenance window while updates are on.
+func (u *Updater) Run(ctx context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	st, err := u.store.State()
+	if err != nil {
+		return err
+	}
+	if done, err := u.resume(ctx, &st); !done || err != nil {
+		return err
+	}
+
+	policy, err := u.Policy()
+	if err != nil {
+		return err
+	}
+	if policy.Mode == ModeOff {
+		return nil
+	}
+	opened, ok := InWindow(policy.Window, u.now())
+	if !ok || !st.LastAttemptAt.Before(opened) {
+		return nil
+	}
+	return u.update(ctx, policy, st)
+}
+
+// Resume finishes an update the host agent was restarted during, rather
+// than waiting for the next scheduled run. It's called at startup.
+func (u *Updater) Resume(ctx context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	st, err := u.store.State()
+	if err != nil {
+		return err
+	}
+	_, err = u.resume(ctx, &st)
+	return err
+}
+
+// resume settles a switch that was under way and verifies the system it
+// switched to. It reports whether the update is finished.
+func (u *Updater) resume(ctx context.Context, st *store.UpdateState) (bool, error) {
+	if st.Status == StatusApplying || st.Status == StatusRollingBack {
+		if err := u.settle(ctx, st, nil); err != nil {
+			return false, err
+		}
+	}
+	if st.Status == StatusVerifying {
+		if err := u.verify(ctx, st); err != nil {
+			return false, err
+		}
+	}
+	switch st.Status {
+	case StatusApplying, StatusRollingBack, StatusVerifying:
+		return false, nil
+	}
+	return true, nil
+}
+
+// Rollback switches back to the system the last applied update replaced.
+// The host agent and web UI are part of the system, so they go back with
+// it. Later updates skip the rolled back system until the channel moves on.
+func (u *Updater) Rollback(ctx context.Context) error {
+	// An update can take a while; don't wait for it
+	if !u.mu.TryLock() {
+		return ErrBusy
+	}
+	defer u.mu.Unlock()
+
+	st, err := u.store.State()
+	if err != nil {
+		return err
+	}
+	if st.Status != StatusApplied || st.FromSystem == "" {
+		return ErrNoRollback
+	}
+	if current, err := os.Readlink(u.currentSystem); err != nil || current != st.ToSystem {
+		return fmt.Errorf("%w: the running system isn't the updated one", ErrNoRollback)
+	}
+
+	// Saved first, as for updates: the switch may restart the host agent
+	st.Status = StatusRollingBack
+	st.Error = ""
+	if err := u.store.SaveState(st); err != nil {
+		return err
+	}
+	u.logger.InfoContext(ctx, "rolling back update", "system", st.FromSystem)
+	if err := u.settle(ctx, &st, u.apply(ctx, st.FromSystem)); err != nil {
+		return err
+	}
+	if st.Status == StatusApplied {
+		return errors.New(st.Error)
+	}
+	return nil
+}
+
+// Check looks for an update without applying it. The system the policy
+// would build is evaluated and, when it isn't the running one, recorded as
+// available, notifying the admin the first time it's found. With updates
+// off it checks the channel, so hosts updated by hand hear of new releases.
+func (u *Updater) Check(ctx context.Context) error {
+	policy, err := u.Policy()
+	if err != nil {
+		return err
+	}
+	if policy.Mode == ModeOff {
+		policy.Mode = ModeAll
+	}
+	st, err := u.store.State()
+	if err != nil {
+		return err
+	}
+	prev, err := u.store.Available()
+	if err != nil {
+		return err
+	}
+	current, err := os.Readlink(u.currentSystem)
+	if err != nil {
+		return fmt.Errorf("finding the running system: %w", err)
+	}
+
+	next, err := u.evaluate(ctx, policy)
+	if err != nil {
+		return fmt.Errorf("evaluating the next system: %w", err)
+	}
+	// Nothing new, or only the system last rolled back from
+	if next == current || (st.Status == StatusRolledBack && next == st.ToSystem) {
+		if prev == nil {
+			return nil
+		}
+		return u.store.SetAvailable(nil)
+	}
+
+	now := u.now()
+	available := store.AvailableUpdate{System: next, FoundAt: now, CheckedAt: now}
+	if policy.Mode == ModeAll {
+		available.Channel = policy.Channel
+	}
+	if prev != nil && prev.System == next {
+		available.FoundAt = prev.FoundAt
+		available.Notes = prev.Notes
+	}
+	// Release notes are read once per system, and only for channels:
+	// security-only updates stay on the installed release
+	if available.Notes == nil && available.Channel != "" {
+		notes, err := u.releaseNotes(ctx, available.Channel)
+		if err != nil {
+			u.logger.WarnContext(ctx, "failed to read release notes", "channel", available.Channel, "error", err)
+		}
+		available.Notes = notes
+	}
+	if err := u.store.SetAvailable(&available); err != nil {
+		return err
+	}
+	if prev == nil || prev.System != next {
+		u.logger.InfoContext(ctx, "update available", "system", next)
+		message := systemName(next) + " is available"
+		if available.Channel != "" {
+			message += " on the " + available.Channel + " channel"
+		}
+		u.publish(notify.KindUpdateAvailable, "Bloud update available", message+".")
+	}
+	return nil
+}
+
+// update builds the system the policy asks for and switches to it, unless
+// it's the one last rolled back from
+func (u *Updater) update(ctx context.Context, policy store.UpdatePolicy, prev store.UpdateState) error {
+	st := store.UpdateState{LastAttemptAt: u.now(), Status: StatusApplying, Snapshot: prev.Snapshot}
+	current, err := os.Readlink(u.currentSystem)
+	if err != nil {
+		return u.fail(st, fmt.Errorf("finding the running system: %w", err))
+	}
+	st.FromSystem = current
+
+	u.logger.InfoContext(ctx, "building update", "mode", policy.Mode, "channel", policy.Channel)
+	next, err := u.build(ctx, policy)
+	if err != nil {
+		return u.fail(st, fmt.Errorf("building the new system: %w", err))
+	}
+	if next == current {
+		u.logger.InfoContext(ctx, "system is up to date")
+		st.Status = StatusUpToDate
+		return u.store.SaveState(st)
+	}
+	if prev.Status == StatusRolledBack && next == prev.ToSystem {
+		u.logger.InfoContext(ctx, "skipping rolled back update", "system", next)
+		prev.LastAttemptAt = st.LastAttemptAt
+		return u.store.SaveState(prev)
+	}
+	st.ToSystem = next
+	return u.switchTo(ctx, st)
+}
+
+// switchTo switches to st.ToSystem: it saves user data, switches, and
+// verifies the new system
+func (u *Updater) switchTo(ctx context.Context, st store.UpdateState) error {
+	// Nothing has changed yet, so this is the point to restore user data to
+	snapshot, err := u.snapshot(ctx)
+	if err != nil {
+		return u.fail(st, fmt.Errorf("taking the pre-update snapshot: %w", err))
+	}
+	u.removeSnapshot(ctx, st.Snapshot)
+	st.Snapshot = snapshot
+
+	// Saved first: the switch may restart the host agent, in which case the
+	// next run settles the update
+	if err := u.store.SaveState(st); err != nil {
+		return err
+	}
+	u.logger.InfoContext(ctx, "switching to updated system", "system", st.ToSystem)
+	if err := u.settle(ctx, &st, u.apply(ctx, st.ToSystem)); err != nil {
+		return err
+	}
+	if st.Status == StatusVerifying {
+		if err := u.verify(ctx, &st); err != nil {
+			return err
+		}
+	}
+	// Failed, rolled back or failed to roll back
+	if st.Error != "" {
+		return errors.New(st.Error)
+	}
+	return nil
+}
+
+// build builds the next system and returns its store path. All updates
+// follow the channel's branch of the Bloud repository; security-only
+// updates rebuild the installed release against the latest of its NixOS
+// release branch.
+func (u *Updater) build(ctx context.Context, policy store.UpdatePolicy) (string, error) {
+	args, installable, err := u.installable(policy)
+	if err != nil {
+		return "", err
+	}
+	args = append([]string{"build", "--no-link", "--print-out-paths"}, args...)
+	return u.nixPath(ctx, append(args, installable)...)
+}
+
+// evaluate returns the store path build would produce, without building it
+func (u *Updater) evaluate(ctx context.Context, policy store.UpdatePolicy) (string, error) {
+	args, installable, err := u.installable(policy)
+	if err != nil {
+		return "", err
+	}
+	args = append([]string{"eval", "--raw"}, args...)
+	return u.nixPath(ctx, append(args, installable+".outPath")...)
+}
+
+// installable returns the flags and installable of the system the policy
+// asks for
+func (u *Updater) installable(policy store.UpdatePolicy) ([]string, string, error) {
+	flake := u.cfg.SourceDir
+	// Refetch branches rather than use a cached copy
+	args := []string{"--impure", "--refresh"}
+	if policy.Mode == ModeAll {
+		flake = channelRef(u.cfg.Repo, policy.Channel)
+	} else {
+		nixpkgs, err := releaseBranch(filepath.Join(u.cfg.SourceDir, "flake.lock"))
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, "--override-input", "nixpkgs", nixpkgs)
+	}
+	return args, fmt.Sprintf("%s#nixosConfigurations.%s.config.system.build.toplevel", flake, u.cfg.Target), nil
+}
+
+// nixPath runs nix and returns the store path it prints last
+func (u *Updater) nixPath(ctx context.Context, args ...string) (string, error) {
+	out, err := u.command(ctx, "nix", args...)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	path := strings.TrimSpace(lines[len(lines)-1])
+	if !strings.HasPrefix(path, "/nix/store/") {
+		return "", fmt.Errorf("unexpected nix %s output %q", args[0], path)
+	}
+	return path, nil
+}
+
+// apply switches to a new system the way nixos-rebuild switch does: it adds
+// the system to the system profile, then activates it
+func (u *Updater) apply(ctx context.Context, system string) error {
+	script := fmt.Sprintf("nix-env --profile %s --set %s && %s/bin/switch-to-configuration switch", systemProfile, system, system)
+	_, err := u.sudo(ctx, "systemd-run", "--unit="+switchUnit, "--collect", "--wait", "--quiet",
+		"--setenv=PATH="+nixgen.NixosSystemPath, "/bin/sh", "-c", script)
+	return err
+}
+
+// sudo runs a command as root with the system's PATH
+func (u *Updater) sudo(ctx context.Context, args ...string) ([]byte, error) {
+	return u.command(ctx, "sudo", append([]string{"-n", "env", "PATH=" + nixgen.NixosSystemPath}, args...)...)
+}
+
+// settle resolves an update being applied: verifying (or applied, without
+// verification) once the running system is the new one, failed once the
+// switch is no longer running. A rollback settles the same way, back to
+// applied when it fails.
+func (u *Updater) settle(ctx context.Context, st *store.UpdateState, applyErr error) error {
+	rollingBack := st.Status == StatusRollingBack
+	target := st.ToSystem
+	if rollingBack {
+		target = st.FromSystem
+	}
+	current, err := os.Readlink(u.currentSystem)
+	switch {
+	case err == nil && current == target && rollingBack:
+		st.Status = StatusRolledBack
+		u.logger.InfoContext(ctx, "update rolled back", "system", current)
+		// Automatic rollbacks keep the reason; requested ones have none
+		if st.Error != "" {
+			u.publish(notify.KindUpdateFailed, "Bloud update rolled back", st.Error+". Now running "+systemName(current)+" again.")
+		}
+	case err == nil && current == target:
+		st.SwitchedAt = u.now()
+		if u.cfg.Verify != nil {
+			st.Status = StatusVerifying
+			u.logger.InfoContext(ctx, "verifying updated system", "system", current)
+			break
+		}
+		u.applied(ctx, st)
+	case applyErr == nil && u.switching(ctx):
+		return nil
+	case rollingBack:
+		st.Status = StatusApplied
+		st.Error = "rolling back the update failed"
+		if applyErr != nil {
+			st.Error += ": " + applyErr.Error()
+		}
+		u.logger.ErrorContext(ctx, "rollback failed", "error", st.Error)
+		u.publish(notify.KindUpdateFailed, "Bloud rollback failed", st.Error)
+	default:
+		st.Status = StatusFailed
+		st.Error = "switching to the new system failed"
+		if applyErr != nil {
+			st.Error += ": " + applyErr.Error()
+		}
+		u.logger.ErrorContext(ctx, "update failed", "error", st.Error)
+		u.publish(notify.KindUpdateFailed, "Bloud update failed", st.Error)
+	}
+	return u.store.SaveState(*st)
+}
+
+// verify waits for the critical components to be healthy on the updated
+// system, and rolls the update back when they still fail VerifyTimeout after
+// the switch
+func (u *Updater) verify(ctx context.Context, st *store.UpdateState) error {
+	deadline := st.SwitchedAt.Add(u.cfg.VerifyTimeout)
+	for u.cfg.Verify != nil {
+		err := u.cfg.Verify(ctx)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !u.now().Before(deadline) {
+			u.logger.ErrorContext(ctx, "rolling back unhealthy update", "error", err)
+			st.Status = StatusRollingBack
+			st.Error = "critical components failed after the update: " + err.Error()
+			if err := u.store.SaveState(*st); err != nil {
+				return err
+			}
+			return u.settle(ctx, st, u.apply(ctx, st.FromSystem))
+		}
+		u.logger.WarnContext(ctx, "updated system isn't healthy yet", "error", err, "deadline", deadline)
+		if err := u.wait(ctx, verifyInterval); err != nil {
+			return err
+		}
+	}
+	u.applied(ctx, st)
+	return u.store.SaveState(*st)
+}
+
+// applied records that the updated system is running and healthy
+func (u *Updater) applied(ctx context.Context, st *store.UpdateState) {
+	st.Status = StatusApplied
+	st.Error = ""
+	u.logger.InfoContext(ctx, "update applied", "system", st.ToSystem)
+	u.publish(notify.KindUpdateApplied, "Bloud updated", "Now running "+systemName(st.ToSystem)+".")
+}
+
+// switching reports whether the switch to a new system is still running
+func (u *Updater) switching(ctx context.Context) bool {
+	_, err := u.command(ctx, "systemctl", "is-active", "--quiet", switchUnit)
+	return err == nil
+}
+
+// fail records and reports a failed update
+func (u *Updater) fail(st store.UpdateState, err error) error {
+	st.Status = StatusFailed
+	st.Error = err.Error()
+	u.logger.Error("update failed", "error", err)
+	u.publish(notify.KindUpdateFailed, "Bloud update failed", st.Error)
+	if saveErr := u.store.SaveState(st); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	return err
+}
+
+func (u *Updater) publish(kind, title, message string) {
+	if u.notifier == nil {
+		return
+	}
+	u.notifier.Publish(notify.Event{Kind: kind, Title: title, Message: message, Time: u.now()})
+}
+
+// channelRef returns the flake URL of a channel's branch
+func channelRef(repo, channel string) string {
+	sep := "?"
+	if strings.Contains(repo, "?") {
+		sep = "&"
+	}
+	return repo + sep + "ref=" + channel
+}
+
+// releaseBranch returns the flake URL of the nixpkgs branch a flake.lock
+// follows, e.g. github:NixOS/nixpkgs/nixos-24.11
+func releaseBranch(lockPath string) (string, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "", fmt.Errorf("reading flake.lock: %w", err)
+	}
+	var lock struct {
+		Nodes map[string]struct {
+			Original struct {
+				Type  string `json:"type"`
+				Owner string `json:"owner"`
+				Repo  string `json:"repo"`
+				Ref   string `json:"ref"`
+			} `json:"original"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return "", fmt.Errorf("parsing flake.lock: %w", err)
+	}
+	original := lock.Nodes["nixpkgs"].Original
+	if original.Type != "github" || original.Ref == "" {
+		return "", errors.New("nixpkgs doesn't follow a GitHub branch")
+	}
+	return fmt.Sprintf("github:%s/%s/%s", original.Owner, original.Repo, original.Ref), nil
+}
+
+// nextWindow returns when the maintenance window next opens after t
+func nextWindow(w store.UpdateWindow, t time.Time) (time.Time, bool) {
+	for i := 0; i <= 7; i++ {
+		start, _, ok := occurrence(w, t.AddDate(0, 0, i))
+		if ok && start.After(t) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// systemName returns a system's name without its store hash, e.g.
+// nixos-system-bloud-24.11.20250101.abcdef0
+func systemName(path string) string {
+	base := filepath.Base(path)
+	if _, name, ok := strings.Cut(base, "-"); ok {
+		return name
+	}
+	return base
+}
+
+// wait sleeps for d, or until ctx is done
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// runCommand runs a command and returns its output, with the end of its
+// stderr in the error
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			lines := strings.Split(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+			if len(lines) > 3 {
+				lines = lines[len(lines)-3:]
+			}
+			return out, fmt.Errorf("%s: %w: %s", name, err, strings.Join(lines, "\n"))
+		}
+		return out, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,659 @@
+package updates
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/db"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/notify"
+	"codeberg.org/d-buckner/bloud-v3/services/host-agent/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	policy    *store.UpdatePolicy
+	state     store.UpdateState
+	available *store.AvailableUpdate
+}
+
+func (f *fakeStore) Policy() (*store.UpdatePolicy, error)        { return f.policy, nil }
+func (f *fakeStore) SetPolicy(p store.UpdatePolicy) error        { f.policy = &p; return nil }
+func (f *fakeStore) State() (store.UpdateState, error)           { return f.state, nil }
+func (f *fakeStore) SaveState(st store.UpdateState) error        { f.state = st; return nil }
+func (f *fakeStore) Available() (*store.AvailableUpdate, error)  { return f.available, nil }
+func (f *fakeStore) SetAvailable(a *store.AvailableUpdate) error { f.available = a; return nil }
+
+type fakeDatabase struct {
+	err error
+}
+
+func (f *fakeDatabase) Backup(ctx context.Context) (*db.Backup, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &db.Backup{Name: "bloud-20260306T030000Z.dump"}, nil
+}
+
+type fakeNotifier struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (f *fakeNotifier) Publish(event notify.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(DefaultPolicy()))
+
+	p := DefaultPolicy()
+	p.Mode = "nightly"
+	assert.Error(t, Validate(p))
+
+	p = DefaultPolicy()
+	p.Channel = "edge"
+	assert.Error(t, Validate(p))
+
+	p = DefaultPolicy()
+	p.Window.End = p.Window.Start
+	assert.Error(t, Validate(p))
+
+	p = DefaultPolicy()
+	p.Window.Days = []string{"someday"}
+	assert.Error(t, Validate(p))
+}
+
+func TestInWindow(t *testing.T) {
+	// Friday nights, past midnight
+	w := store.UpdateWindow{Start: "23:00", End: "01:00", Days: []string{"fri"}}
+	fri := time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)
+
+	opened, ok := InWindow(w, fri.Add(24*time.Hour+30*time.Minute))
+	require.True(t, ok)
+	assert.Equal(t, fri.Add(23*time.Hour), opened)
+
+	_, ok = InWindow(w, fri.Add(22*time.Hour))
+	assert.False(t, ok)
+	_, ok = InWindow(w, fri.Add(24*time.Hour+23*time.Hour+30*time.Minute))
+	assert.False(t, ok, "saturday night isn't in the window")
+
+	next, ok := nextWindow(w, fri.Add(24*time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, fri.AddDate(0, 0, 7).Add(23*time.Hour), next)
+}
+
+// newTestUpdater returns an updater whose running system is a symlink to
+// the "old" system, and which records the commands it runs
+func newTestUpdater(t *testing.T, policy store.UpdatePolicy, now time.Time) (*Updater, *fakeStore, *fakeNotifier, *[]string) {
+	dir := t.TempDir()
+	current := filepath.Join(dir, "current-system")
+	require.NoError(t, os.Symlink("/nix/store/aaa-nixos-system-bloud-old", current))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "flake.lock"), []byte(`{"nodes": {
+		"nixpkgs": {"original": {"owner": "NixOS", "ref": "nixos-24.11", "repo": "nixpkgs", "type": "github"}}
+	}}`), 0644))
+
+	st := &fakeStore{policy: &policy}
+	notifier := &fakeNotifier{}
+	u := New(Config{SourceDir: dir, Target: "bloud", UpdateKeys: []string{"bloud-updates-1:dGVzdA==", "bloud-updates-2:dGVzdDI="}}, st, notifier, slog.New(slog.DiscardHandler))
+	u.currentSystem = current
+	u.now = func() time.Time { return now }
+
+	var commands []string
+	u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		switch {
+		case name == "nix":
+			return []byte("/nix/store/bbb-nixos-system-bloud-new\n"), nil
+		case name == "sudo" && slices.Contains(args, "systemd-run"):
+			// The switch moves the running system to the one in the script,
+			// "nix-env --profile ... --set <system> && ..."
+			system := strings.Fields(args[len(args)-1])[4]
+			os.Remove(current)
+			return nil, os.Symlink(system, current)
+		case name == "sudo":
+			return nil, nil
+		}
+		return nil, errors.New("inactive")
+	}
+	return u, st, notifier, &commands
+}
+
+func TestUpdater_Run(t *testing.T) {
+	policy := store.UpdatePolicy{Mode: ModeAll, Channel: ChannelBeta, Window: store.UpdateWindow{Start: "03:00", End: "05:00"}}
+	at := time.Date(2026, 3, 6, 3, 10, 0, 0, time.Local)
+	u, st, notifier, commands := newTestUpdater(t, policy, at)
+
+	// Outside the window nothing happens
+	u.now = func() time.Time { return at.Add(-time.Hour) }
+	require.NoError(t, u.Run(context.Background()))
+	assert.Empty(t, *commands)
+
+	u.now = func() time.Time { return at }
+	require.NoError(t, u.Run(context.Background()))
+	require.Len(t, *commands, 2)
+	assert.Equal(t, "nix build --no-link --print-out-paths --impure --refresh "+DefaultRepo+"?ref=beta#nixosConfigurations.bloud.config.system.build.toplevel", (*commands)[0])
+	assert.Contains(t, (*commands)[1], "systemd-run --unit=bloud-update")
+	assert.Contains(t, (*commands)[1], "nix-env --profile /nix/var/nix/profiles/system --set /nix/store/bbb-nixos-system-bloud-new")
+
+	assert.Equal(t, StatusApplied, st.state.Status)
+	assert.Equal(t, "/nix/store/aaa-nixos-system-bloud-old", st.state.FromSystem)
+	assert.Equal(t, "/nix/store/bbb-nixos-system-bloud-new", st.state.ToSystem)
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, notify.KindUpdateApplied, notifier.events[0].Kind)
+	assert.Contains(t, notifier.events[0].Message, "nixos-system-bloud-new")
+
+	// Once per window
+	u.now = func() time.Time { return at.Add(time.Hour) }
+	require.NoError(t, u.Run(context.Background()))
+	assert.Len(t, *commands, 2)
+
+	// Nothing new in the next window
+	u.now = func() time.Time { return at.AddDate(0, 0, 1) }
+	require.NoError(t, u.Run(context.Background()))
+	assert.Len(t, *commands, 3)
+	assert.Equal(t, StatusUpToDate, st.state.Status)
+	assert.Len(t, notifier.events, 1)
+}
+
+func TestUpdater_SecurityOnly(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.Mode = ModeSecurity
+	at := time.Date(2026, 3, 6, 4, 0, 0, 0, time.Local)
+	u, _, _, commands := newTestUpdater(t, policy, at)
+
+	require.NoError(t, u.Run(context.Background()))
+	require.NotEmpty(t, *commands)
+	assert.Contains(t, (*commands)[0], "--override-input nixpkgs github:NixOS/nixpkgs/nixos-24.11 "+u.cfg.SourceDir+"#nixosConfigurations.bloud")
+}
+
+func TestUpdater_Failures(t *testing.T) {
+	policy := store.UpdatePolicy{Mode: ModeAll, Channel: ChannelStable, Window: store.UpdateWindow{Start: "03:00", End: "05:00"}}
+	at := time.Date(2026, 3, 6, 3, 0, 0, 0, time.Local)
+
+	t.Run("build", func(t *testing.T) {
+		u, st, notifier, _ := newTestUpdater(t, policy, at)
+		u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, errors.New("nix: exit status 1: error: attribute missing")
+		}
+		require.Error(t, u.Run(context.Background()))
+		assert.Equal(t, StatusFailed, st.state.Status)
+		assert.Contains(t, st.state.Error, "attribute missing")
+		require.Len(t, notifier.events, 1)
+		assert.Equal(t, notify.KindUpdateFailed, notifier.events[0].Kind)
+	})
+
+	t.Run("interrupted switch", func(t *testing.T) {
+		u, st, notifier, _ := newTestUpdater(t, policy, at)
+		// The host agent was restarted mid-switch, which then failed
+		st.state = store.UpdateState{LastAttemptAt: at, Status: StatusApplying, FromSystem: "/nix/store/aaa-nixos-system-bloud-old", ToSystem: "/nix/store/ccc-nixos-system-bloud-new"}
+
+		require.NoError(t, u.Run(context.Background()))
+		assert.Equal(t, StatusFailed, st.state.Status)
+		require.Len(t, notifier.events, 1)
+		assert.Equal(t, notify.KindUpdateFailed, notifier.events[0].Kind)
+	})
+}
+
+func TestUpdater_Rollback(t *testing.T) {
+	policy := store.UpdatePolicy{Mode: ModeAll, Channel: ChannelStable, Window: store.UpdateWindow{Start: "03:00", End: "05:00"}}
+	at := time.Date(2026, 3, 6, 3, 0, 0, 0, time.Local)
+	u, st, _, commands := newTestUpdater(t, policy, at)
+
+	assert.ErrorIs(t, u.Rollback(context.Background()), ErrNoRollback)
+
+	require.NoError(t, u.Run(context.Background()))
+	require.Equal(t, StatusApplied, st.state.Status)
+
+	require.NoError(t, u.Rollback(context.Background()))
+	assert.Contains(t, (*commands)[len(*commands)-1], "--set /nix/store/aaa-nixos-system-bloud-old")
+	current, err := os.Readlink(u.currentSystem)
+	require.NoError(t, err)
+	assert.Equal(t, "/nix/store/aaa-nixos-system-bloud-old", current)
+	assert.Equal(t, StatusRolledBack, st.state.Status)
+	assert.Equal(t, at, st.state.LastAttemptAt)
+
+	// Only once
+	assert.ErrorIs(t, u.Rollback(context.Background()), ErrNoRollback)
+
+	// The next window builds the same system again and leaves it be
+	u.now = func() time.Time { return at.AddDate(0, 0, 1) }
+	n := len(*commands)
+	require.NoError(t, u.Run(context.Background()))
+	assert.Len(t, *commands, n+1, "built, not switched to")
+	assert.Equal(t, StatusRolledBack, st.state.Status)
+	assert.Equal(t, at.AddDate(0, 0, 1), st.state.LastAttemptAt)
+
+	t.Run("failed", func(t *testing.T) {
+		u, st, notifier, _ := newTestUpdater(t, policy, at)
+		require.NoError(t, u.Run(context.Background()))
+		u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, errors.New("switch-to-configuration failed")
+		}
+		require.Error(t, u.Rollback(context.Background()))
+		assert.Equal(t, StatusApplied, st.state.Status)
+		assert.Contains(t, st.state.Error, "rolling back the update failed")
+		assert.Equal(t, notify.KindUpdateFailed, notifier.events[len(notifier.events)-1].Kind)
+	})
+}
+
+func TestUpdater_Snapshot(t *testing.T) {
+	policy := store.UpdatePolicy{Mode: ModeAll, Channel: ChannelStable, Window: store.UpdateWindow{Start: "03:00", End: "05:00"}}
+	at := time.Date(2026, 3, 6, 3, 0, 0, 0, time.UTC)
+	u, st, _, commands := newTestUpdater(t, policy, at)
+
+	dataDir := t.TempDir()
+	secretsPath := filepath.Join(dataDir, "secrets.json")
+	require.NoError(t, os.WriteFile(secretsPath, []byte(`{"postgresPassword":"pw"}`), 0600))
+	u.cfg.Database = &fakeDatabase{}
+	u.cfg.SecretsPath = secretsPath
+	u.cfg.DataDir = dataDir
+
+	// The installer's ZFS data store: dataset bloud/data mounted at the
+	// data dir
+	run := u.command
+	u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "findmnt" {
+			*commands = append(*commands, name)
+			assert.Equal(t, dataDir, args[len(args)-1])
+			return []byte("zfs bloud/data " + dataDir + "\n"), nil
+		}
+		return run(ctx, name, args...)
+	}
+
+	require.NoError(t, u.Run(context.Background()))
+	require.Equal(t, StatusApplied, st.state.Status)
+	snapshot := st.state.Snapshot
+	require.NotNil(t, snapshot)
+	assert.Equal(t, "bloud-20260306T030000Z.dump", snapshot.Database)
+	assert.Equal(t, "zfs", snapshot.Filesystem)
+	assert.Equal(t, "bloud/data@bloud-update-20260306T030000Z", snapshot.Name)
+	data, err := os.ReadFile(snapshot.Secrets)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "postgresPassword")
+
+	// Taken after the build and before the switch
+	require.Len(t, *commands, 4)
+	assert.True(t, strings.HasPrefix((*commands)[0], "nix build"))
+	assert.Equal(t, "findmnt", (*commands)[1])
+	assert.Contains(t, (*commands)[2], "zfs snapshot bloud/data@bloud-update-20260306T030000Z")
+	assert.Contains(t, (*commands)[3], "systemd-run")
+
+	status, err := u.Status()
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, status.Snapshot)
+
+	// The next update replaces it
+	u.now = func() time.Time { return at.AddDate(0, 0, 1) }
+	require.NoError(t, os.Remove(u.currentSystem))
+	require.NoError(t, os.Symlink("/nix/store/aaa-nixos-system-bloud-old", u.currentSystem))
+	require.NoError(t, u.Run(context.Background()))
+	assert.Equal(t, "bloud/data@bloud-update-20260307T030000Z", st.state.Snapshot.Name)
+	assert.Contains(t, strings.Join(*commands, "\n"), "zfs destroy bloud/data@bloud-update-20260306T030000Z")
+	_, err = os.Stat(snapshot.Secrets)
+	assert.True(t, os.IsNotExist(err))
+
+	t.Run("btrfs", func(t *testing.T) {
+		// The installer's btrfs data store (mirrored or not) is mounted at
+		// the data dir, so the snapshot goes inside it
+		u, st, _, commands := newTestUpdater(t, policy, at)
+		dataDir := "/home/bloud/.local/share/bloud"
+		u.cfg.DataDir = dataDir
+		run := u.command
+		u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "findmnt" {
+				return []byte("btrfs /dev/sda4 " + dataDir + "\n"), nil
+			}
+			return run(ctx, name, args...)
+		}
+		require.NoError(t, u.Run(context.Background()))
+		require.NotNil(t, st.state.Snapshot)
+		assert.Equal(t, "btrfs", st.state.Snapshot.Filesystem)
+		assert.Equal(t, dataDir+"/.bloud-snapshots/update-20260306T030000Z", st.state.Snapshot.Name)
+		assert.Contains(t, strings.Join(*commands, "\n"), "btrfs subvolume snapshot -r "+dataDir+" "+dataDir+"/.bloud-snapshots/update-20260306T030000Z")
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		u, st, notifier, commands := newTestUpdater(t, policy, at)
+		u.cfg.Database = &fakeDatabase{err: errors.New("pg_dump failed")}
+		require.Error(t, u.Run(context.Background()))
+		assert.Equal(t, StatusFailed, st.state.Status)
+		assert.Contains(t, st.state.Error, "taking the pre-update snapshot: backing up the database")
+		assert.Len(t, *commands, 1, "never switched")
+		assert.Len(t, notifier.events, 1)
+	})
+}
+
+func TestUpdater_Verify(t *testing.T) {
+	policy := store.UpdatePolicy{Mode: ModeAll, Channel: ChannelStable, Window: store.UpdateWindow{Start: "03:00", End: "05:00"}}
+	at := time.Date(2026, 3, 6, 3, 0, 0, 0, time.Local)
+
+	// newVerifiedUpdater returns an updater whose clock moves on while it
+	// waits, verifying with check
+	newVerifiedUpdater := func(t *testing.T, check func() error) (*Updater, *fakeStore, *fakeNotifier) {
+		u, st, notifier, _ := newTestUpdater(t, policy, at)
+		clock := at
+		u.now = func() time.Time { return clock }
+		u.wait = func(ctx context.Context, d time.Duration) error {
+			clock = clock.Add(d)
+			return nil
+		}
+		u.cfg.Verify = func(ctx context.Context) error { return check() }
+		return u, st, notifier
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		checks := 0
+		u, st, notifier := newVerifiedUpdater(t, func() error {
+			// Traefik takes a minute to come back
+			if checks++; checks < 3 {
+				return errors.New("traefik: connection refused")
+			}
+			return nil
+		})
+		require.NoError(t, u.Run(context.Background()))
+		assert.Equal(t, StatusApplied, st.state.Status)
+		assert.Equal(t, at, st.state.SwitchedAt)
+		assert.Equal(t, 3, checks)
+		require.Len(t, notifier.events, 1)
+		assert.Equal(t, notify.KindUpdateApplied, notifier.events[0].Kind)
+	})
+
+	t.Run("unhealthy", func(t *testing.T) {
+		u, st, notifier := newVerifiedUpdater(t, func() error {
+			return errors.New("authentik: health check returned 502")
+		})
+		err := u.Run(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "critical components failed after the update: authentik")
+		assert.Equal(t, StatusRolledBack, st.state.Status)
+		current, err := os.Readlink(u.currentSystem)
+		require.NoError(t, err)
+		assert.Equal(t, "/nix/store/aaa-nixos-system-bloud-old", current)
+		require.Len(t, notifier.events, 1)
+		assert.Equal(t, notify.KindUpdateFailed, notifier.events[0].Kind)
+		assert.Equal(t, "Bloud update rolled back", notifier.events[0].Title)
+	})
+
+	t.Run("resumed after restart", func(t *testing.T) {
+		u, st, notifier := newVerifiedUpdater(t, func() error { return nil })
+		// The host agent restarted while the system switched
+		require.NoError(t, os.Remove(u.currentSystem))
+		require.NoError(t, os.Symlink("/nix/store/bbb-nixos-system-bloud-new", u.currentSystem))
+		st.state = store.UpdateState{LastAttemptAt: at, Status: StatusApplying, FromSystem: "/nix/store/aaa-nixos-system-bloud-old", ToSystem: "/nix/store/bbb-nixos-system-bloud-new"}
+
+		require.NoError(t, u.Resume(context.Background()))
+		assert.Equal(t, StatusApplied, st.state.Status)
+		require.Len(t, notifier.events, 1)
+		assert.Equal(t, notify.KindUpdateApplied, notifier.events[0].Kind)
+	})
+}
+
+// bundleTar returns a gzipped bundle tar with the given files
+func bundleTar(t *testing.T, files map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func TestUpdater_ApplyBundle(t *testing.T) {
+	const system = "/nix/store/0123456789abcdfghijklmnpqrsvwxyz-nixos-system-bloud-offline"
+	at := time.Date(2026, 3, 6, 14, 0, 0, 0, time.Local)
+
+	u, st, notifier, commands := newTestUpdater(t, DefaultPolicy(), at)
+	u.cfg.DataDir = t.TempDir()
+	run := u.command
+	u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "findmnt" {
+			return []byte("ext4 /dev/sda2 /\n"), nil
+		}
+		return run(ctx, name, args...)
+	}
+	bundle := bundleTar(t, map[string]string{
+		"bundle.json":                `{"system": "` + system + `"}`,
+		"./cache/nix-cache-info":     "StoreDir: /nix/store\n",
+		"cache/0123456789ab.narinfo": "StorePath: " + system + "\n",
+	})
+	require.NoError(t, u.ApplyBundle(context.Background(), bundle))
+	// The switch continues in the background, holding the lock
+	u.mu.Lock()
+	u.mu.Unlock()
+
+	require.Len(t, *commands, 3)
+	assert.Regexp(t, `^nix copy --from file://.*/\.update-bundle-\d+/cache `+system+`$`, (*commands)[0])
+	assert.Equal(t, "nix store verify --no-contents --sigs-needed 1 --trusted-public-keys bloud-updates-1:dGVzdA== bloud-updates-2:dGVzdDI= "+system, (*commands)[1])
+	assert.Contains(t, (*commands)[2], "--set "+system)
+	assert.Equal(t, StatusApplied, st.state.Status)
+	assert.Equal(t, "/nix/store/aaa-nixos-system-bloud-old", st.state.FromSystem)
+	assert.Equal(t, system, st.state.ToSystem)
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, notify.KindUpdateApplied, notifier.events[0].Kind)
+	entries, err := os.ReadDir(u.cfg.DataDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "bundle removed once imported")
+
+	// The same bundle again is already running
+	require.NoError(t, u.ApplyBundle(context.Background(), bundleTar(t, map[string]string{"bundle.json": `{"system": "` + system + `"}`})))
+	assert.Equal(t, StatusUpToDate, st.state.Status)
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, files := range map[string]map[string]string{
+			"no manifest":    {"cache/nix-cache-info": ""},
+			"not a system":   {"bundle.json": `{"system": "/nix/store/0123456789abcdfghijklmnpqrsvwxyz-hello-2.12"}`},
+			"shell":          {"bundle.json": `{"system": "/nix/store/0123456789abcdfghijklmnpqrsvwxyz-nixos-system-x;reboot"}`},
+			"escaping paths": {"bundle.json": `{"system": "` + system + `"}`, "../evil": ""},
+		} {
+			u, st, _, commands := newTestUpdater(t, DefaultPolicy(), at)
+			err := u.ApplyBundle(context.Background(), bundleTar(t, files))
+			assert.ErrorIs(t, err, ErrInvalidBundle, name)
+			assert.Empty(t, *commands, name)
+			assert.Empty(t, st.state.Status, name)
+		}
+
+		// Not a tar at all
+		u, _, _, _ := newTestUpdater(t, DefaultPolicy(), at)
+		assert.ErrorIs(t, u.ApplyBundle(context.Background(), strings.NewReader("not a bundle")), ErrInvalidBundle)
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		u, st, _, _ := newTestUpdater(t, DefaultPolicy(), at)
+		u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, errors.New("nix: exit status 1: error: cannot add path '" + system + "' because it lacks a signature by a trusted key")
+		}
+		err := u.ApplyBundle(context.Background(), bundleTar(t, map[string]string{"bundle.json": `{"system": "` + system + `"}`}))
+		assert.ErrorIs(t, err, ErrInvalidBundle)
+		assert.ErrorContains(t, err, "lacks a signature by a trusted key")
+		assert.Empty(t, st.state.Status)
+
+		// Imported, being signed by another key the store trusts, but not
+		// by an update key
+		u, st, _, _ = newTestUpdater(t, DefaultPolicy(), at)
+		u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if slices.Contains(args, "verify") {
+				return nil, errors.New("nix: exit status 1: path '" + system + "' is untrusted")
+			}
+			return nil, nil
+		}
+		err = u.ApplyBundle(context.Background(), bundleTar(t, map[string]string{"bundle.json": `{"system": "` + system + `"}`}))
+		assert.ErrorIs(t, err, ErrInvalidBundle)
+		assert.ErrorContains(t, err, "not signed by an update key")
+		assert.Empty(t, st.state.Status)
+	})
+
+	t.Run("no update keys", func(t *testing.T) {
+		u, st, _, commands := newTestUpdater(t, DefaultPolicy(), at)
+		u.cfg.UpdateKeys = nil
+		err := u.ApplyBundle(context.Background(), bundleTar(t, map[string]string{"bundle.json": `{"system": "` + system + `"}`}))
+		assert.ErrorIs(t, err, ErrNoUpdateKeys)
+		assert.Empty(t, *commands)
+		assert.Empty(t, st.state.Status)
+	})
+
+	t.Run("busy", func(t *testing.T) {
+		u, _, _, _ := newTestUpdater(t, DefaultPolicy(), at)
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		assert.ErrorIs(t, u.ApplyBundle(context.Background(), bundleTar(t, nil)), ErrBusy)
+	})
+}
+
+func TestUpdater_Check(t *testing.T) {
+	at := time.Date(2026, 3, 6, 14, 0, 0, 0, time.Local)
+	u, st, notifier, commands := newTestUpdater(t, DefaultPolicy(), at)
+
+	// With updates off the channel is checked, without building anything
+	require.NoError(t, u.Check(context.Background()))
+	require.Len(t, *commands, 1)
+	assert.Equal(t, "nix eval --raw --impure --refresh "+DefaultRepo+"?ref=stable#nixosConfigurations.bloud.config.system.build.toplevel.outPath", (*commands)[0])
+	require.NotNil(t, st.available)
+	assert.Equal(t, store.AvailableUpdate{System: "/nix/store/bbb-nixos-system-bloud-new", Channel: ChannelStable, FoundAt: at, CheckedAt: at}, *st.available)
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, notify.KindUpdateAvailable, notifier.events[0].Kind)
+	assert.Equal(t, "nixos-system-bloud-new is available on the stable channel.", notifier.events[0].Message)
+
+	status, err := u.Status()
+	require.NoError(t, err)
+	assert.Equal(t, st.available, status.Available)
+
+	// Found once, notified once
+	u.now = func() time.Time { return at.AddDate(0, 0, 1) }
+	require.NoError(t, u.Check(context.Background()))
+	assert.Equal(t, at, st.available.FoundAt)
+	assert.Equal(t, at.AddDate(0, 0, 1), st.available.CheckedAt)
+	assert.Len(t, notifier.events, 1)
+
+	// Once it's running it's no longer available
+	require.NoError(t, os.Remove(u.currentSystem))
+	require.NoError(t, os.Symlink("/nix/store/bbb-nixos-system-bloud-new", u.currentSystem))
+	status, err = u.Status()
+	require.NoError(t, err)
+	assert.Nil(t, status.Available)
+	require.NoError(t, u.Check(context.Background()))
+	assert.Nil(t, st.available)
+	assert.Len(t, notifier.events, 1)
+
+	t.Run("rolled back", func(t *testing.T) {
+		u, st, notifier, _ := newTestUpdater(t, DefaultPolicy(), at)
+		st.state = store.UpdateState{Status: StatusRolledBack, FromSystem: "/nix/store/aaa-nixos-system-bloud-old", ToSystem: "/nix/store/bbb-nixos-system-bloud-new"}
+		require.NoError(t, u.Check(context.Background()))
+		assert.Nil(t, st.available)
+		assert.Empty(t, notifier.events)
+	})
+
+	t.Run("security-only", func(t *testing.T) {
+		policy := DefaultPolicy()
+		policy.Mode = ModeSecurity
+		u, st, notifier, commands := newTestUpdater(t, policy, at)
+		require.NoError(t, u.Check(context.Background()))
+		assert.Contains(t, (*commands)[0], "--override-input nixpkgs github:NixOS/nixpkgs/nixos-24.11")
+		require.NotNil(t, st.available)
+		assert.Empty(t, st.available.Channel)
+		assert.Equal(t, "nixos-system-bloud-new is available.", notifier.events[0].Message)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		u, st, notifier, _ := newTestUpdater(t, DefaultPolicy(), at)
+		u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, errors.New("nix: exit status 1: unable to download")
+		}
+		assert.ErrorContains(t, u.Check(context.Background()), "evaluating the next system")
+		assert.Nil(t, st.available)
+		assert.Empty(t, notifier.events)
+		assert.Empty(t, st.state.Status, "not an update attempt")
+	})
+}
+
+func TestUpdater_CheckReleaseNotes(t *testing.T) {
+	at := time.Date(2026, 3, 6, 14, 0, 0, 0, time.Local)
+	u, st, _, commands := newTestUpdater(t, DefaultPolicy(), at)
+	u.cfg.Git = "git"
+	u.cfg.DataDir = t.TempDir()
+
+	log := strings.Join([]string{
+		"c3\x1ffeat(apps)!: move media to /srv\x1fMigration: stop Jellyfin before updating\n\x1e",
+		"c2\x1ffeat: add Immich\x1f\x1e",
+		"c1\x1ffix: postgres upgrade\x1fBREAKING CHANGE: Postgres 16 data is upgraded in place\n\x1e",
+	}, "\n")
+	next := u.command
+	u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name != "nix" || args[0] != "eval" {
+			*commands = append(*commands, name+" "+strings.Join(args, " "))
+		}
+		switch {
+		case name == "nixos-version":
+			return []byte(`{"configurationRevision": "aaa111", "nixosVersion": "24.11"}`), nil
+		case name == "nix" && args[0] == "flake":
+			return []byte(`{"revision": "ccc333"}`), nil
+		case name == "git" && args[0] == "clone":
+			return nil, os.Mkdir(args[len(args)-1], 0755)
+		case name == "git" && slices.Contains(args, "log"):
+			return []byte(log), nil
+		case name == "git" && slices.Contains(args, "diff"):
+			return []byte("apps/immich/metadata.yaml\n"), nil
+		case name == "git":
+			return nil, nil
+		}
+		return next(ctx, name, args...)
+	}
+
+	require.NoError(t, u.Check(context.Background()))
+	require.NotNil(t, st.available)
+	notes := st.available.Notes
+	require.NotNil(t, notes)
+	assert.Equal(t, "aaa111", notes.FromRevision)
+	assert.Equal(t, "ccc333", notes.ToRevision)
+	assert.Equal(t, []string{"feat(apps)!: move media to /srv", "Postgres 16 data is upgraded in place"}, notes.Breaking)
+	assert.Equal(t, []string{"stop Jellyfin before updating"}, notes.Migrations)
+	assert.Equal(t, []string{"immich"}, notes.NewApps)
+	assert.Equal(t, []store.ReleaseChange{
+		{Commit: "c3", Summary: "feat(apps)!: move media to /srv", Breaking: true},
+		{Commit: "c2", Summary: "feat: add Immich"},
+		{Commit: "c1", Summary: "fix: postgres upgrade", Breaking: true},
+	}, notes.Changes)
+	assert.Contains(t, *commands, "git clone --bare --quiet --filter=blob:none --no-tags https://codeberg.org/d-buckner/bloud-v3 "+filepath.Join(u.cfg.DataDir, notesRepoDir))
+
+	// The notes are kept with the available update rather than read again
+	*commands = nil
+	u.now = func() time.Time { return at.AddDate(0, 0, 1) }
+	require.NoError(t, u.Check(context.Background()))
+	assert.Equal(t, notes, st.available.Notes)
+	assert.Len(t, *commands, 1, "only the next system is evaluated")
+
+	t.Run("unreadable", func(t *testing.T) {
+		u, st, _, _ := newTestUpdater(t, DefaultPolicy(), at)
+		u.cfg.Git = "git"
+		u.cfg.DataDir = t.TempDir()
+		next := u.command
+		u.command = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "nixos-version" {
+				// Installed from a copy of the source, not a checkout
+				return []byte(`{"nixosVersion": "24.11"}`), nil
+			}
+			return next(ctx, name, args...)
+		}
+		require.NoError(t, u.Check(context.Background()), "notes are best effort")
+		require.NotNil(t, st.available)
+		assert.Nil(t, st.available.Notes)
+	})
+}
@@ -0,0 +1,168 @@
+// Package watchdog holds the settings of the host agent's app watchdog: the
+// background loop that health checks running apps, marks failing ones as in
+// error, and fails apps stuck installing or starting.
+package watchdog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Config tunes the watchdog
+type Config struct {
+	// CheckIntervalSeconds is the time between health check rounds
+	CheckIntervalSeconds int `json:"checkIntervalSeconds"`
+	// FailuresBeforeError is how many checks in a row a running app must
+	// fail before it is marked as in error
+	FailuresBeforeError int `json:"failuresBeforeError"`
+	// InstallingTimeoutMinutes is how long an app may stay installing
+	// before it is marked as in error
+	InstallingTimeoutMinutes int `json:"installingTimeoutMinutes"`
+	// StartingTimeoutMinutes is how long an app may stay starting before it
+	// is marked as in error
+	StartingTimeoutMinutes int `json:"startingTimeoutMinutes"`
+	// StartGraceSeconds is how long after an app starts running its failed
+	// checks don't count towards FailuresBeforeError
+	StartGraceSeconds int `json:"startGraceSeconds"`
+	// ResumeGraceSeconds is how long after the host wakes from a scheduled
+	// sleep health checks and alerts stay paused
+	ResumeGraceSeconds int `json:"resumeGraceSeconds"`
+	// Exclude lists apps the watchdog leaves alone
+	Exclude []string `json:"exclude"`
+}
+
+// DefaultConfig returns the settings used until some are saved
+func DefaultConfig() Config {
+	return Config{
+		CheckIntervalSeconds:     60,
+		FailuresBeforeError:      3,
+		InstallingTimeoutMinutes: 60,
+		StartingTimeoutMinutes:   15,
+		StartGraceSeconds:        120,
+		ResumeGraceSeconds:       120,
+		Exclude:                  []string{},
+	}
+}
+
+// Validate checks the settings are within sensible bounds
+func (c Config) Validate() error {
+	switch {
+	case c.CheckIntervalSeconds < 10 || c.CheckIntervalSeconds > 3600:
+		return fmt.Errorf("checkIntervalSeconds must be between 10 and 3600")
+	case c.FailuresBeforeError < 1 || c.FailuresBeforeError > 100:
+		return fmt.Errorf("failuresBeforeError must be between 1 and 100")
+	case c.InstallingTimeoutMinutes < 5:
+		return fmt.Errorf("installingTimeoutMinutes must be at least 5")
+	case c.StartingTimeoutMinutes < 1:
+		return fmt.Errorf("startingTimeoutMinutes must be at least 1")
+	case c.StartGraceSeconds < 0 || c.ResumeGraceSeconds < 0:
+		return fmt.Errorf("grace periods can't be negative")
+	}
+	return nil
+}
+
+// CheckInterval is the time between health check rounds
+func (c Config) CheckInterval() time.Duration {
+	return time.Duration(c.CheckIntervalSeconds) * time.Second
+}
+
+// InstallingTimeout is how long an app may stay installing
+func (c Config) InstallingTimeout() time.Duration {
+	return time.Duration(c.InstallingTimeoutMinutes) * time.Minute
+}
+
+// StartingTimeout is how long an app may stay starting
+func (c Config) StartingTimeout() time.Duration {
+	return time.Duration(c.StartingTimeoutMinutes) * time.Minute
+}
+
+// StartGrace is how long failed checks of a newly running app don't count
+func (c Config) StartGrace() time.Duration {
+	return time.Duration(c.StartGraceSeconds) * time.Second
+}
+
+// ResumeGrace is how long checks stay paused after the host wakes
+func (c Config) ResumeGrace() time.Duration {
+	return time.Duration(c.ResumeGraceSeconds) * time.Second
+}
+
+// Excluded reports whether the watchdog leaves app alone
+func (c Config) Excluded(app string) bool {
+	return slices.Contains(c.Exclude, app)
+}
+
+// Settings holds the watchdog config, persisted as JSON
+type Settings struct {
+	path string
+	mu   sync.Mutex
+	cfg  Config
+}
+
+// NewSettings creates settings persisted at path, starting from the defaults
+func NewSettings(path string) *Settings {
+	return &Settings{path: path, cfg: DefaultConfig()}
+}
+
+// Load reads the settings file; a missing file keeps the defaults
+func (s *Settings) Load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading watchdog config: %w", err)
+	}
+
+	// Settings added later keep their defaults
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("parsing watchdog config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid watchdog config: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	return nil
+}
+
+// Config returns the current settings
+func (s *Settings) Config() Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+// Set validates and persists new settings
+func (s *Settings) Set(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.Exclude == nil {
+		cfg.Exclude = []string{}
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling watchdog config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("writing watchdog config: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	return nil
+}
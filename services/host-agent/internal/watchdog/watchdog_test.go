@@ -0,0 +1,53 @@
+package watchdog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, DefaultConfig().Validate())
+
+	for _, mutate := range []func(*Config){
+		func(c *Config) { c.CheckIntervalSeconds = 5 },
+		func(c *Config) { c.FailuresBeforeError = 0 },
+		func(c *Config) { c.InstallingTimeoutMinutes = 1 },
+		func(c *Config) { c.StartingTimeoutMinutes = 0 },
+		func(c *Config) { c.StartGraceSeconds = -1 },
+	} {
+		cfg := DefaultConfig()
+		mutate(&cfg)
+		assert.Error(t, cfg.Validate(), cfg)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchdog.json")
+	s := NewSettings(path)
+	require.NoError(t, s.Load())
+	assert.Equal(t, DefaultConfig(), s.Config())
+
+	cfg := DefaultConfig()
+	cfg.FailuresBeforeError = 5
+	cfg.Exclude = []string{"immich"}
+	require.NoError(t, s.Set(cfg))
+	assert.True(t, s.Config().Excluded("immich"))
+	assert.False(t, s.Config().Excluded("miniflux"))
+
+	reloaded := NewSettings(path)
+	require.NoError(t, reloaded.Load())
+	assert.Equal(t, cfg, reloaded.Config())
+
+	// Settings missing from the file keep their defaults
+	require.NoError(t, os.WriteFile(path, []byte(`{"failuresBeforeError": 2}`), 0644))
+	require.NoError(t, reloaded.Load())
+	assert.Equal(t, 2, reloaded.Config().FailuresBeforeError)
+	assert.Equal(t, 60, reloaded.Config().CheckIntervalSeconds)
+
+	cfg.CheckIntervalSeconds = 1
+	assert.Error(t, s.Set(cfg))
+}
@@ -0,0 +1,147 @@
+// Package apiclient is a Go client for the Bloud host-agent HTTP API.
+//
+// Types and methods in client_gen.go are generated from the OpenAPI document
+// served at /api/openapi.json (a copy lives in openapi.json). Regenerate after
+// changing host-agent routes or request/response types.
+package apiclient
+
+//go:generate go run ../../cmd/apigen -out .
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the host-agent API
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+
+	// Header is added to every request, e.g. a Cookie carrying a session
+	Header http.Header
+}
+
+// New creates a client for the host agent at baseURL (e.g. http://localhost:3000)
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		Header:     http.Header{},
+	}
+}
+
+// SetToken authenticates requests with a personal access token
+func (c *Client) SetToken(token string) {
+	if c.Header == nil {
+		c.Header = http.Header{}
+	}
+	c.Header.Set("Authorization", "Bearer "+token)
+}
+
+// Upload is a raw, non-JSON request body such as an image
+type Upload struct {
+	ContentType string
+	Body        io.Reader
+}
+
+// Error is returned when the API responds with a non-2xx status
+type Error struct {
+	StatusCode int
+	Message    string
+	RequestID  string // host-agent request ID, for finding the call in its logs
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("host-agent API error (status %d)", e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" [request %s]", e.RequestID)
+	}
+	return msg
+}
+
+// withQuery appends encoded query parameters to path, if any
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// doJSON sends a request and decodes a JSON response into out
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.doRaw(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// doRaw sends a request and returns the response if the status is 2xx.
+// The caller must close the response body.
+func (c *Client) doRaw(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	contentType := ""
+	if upload, ok := body.(Upload); ok {
+		reader, contentType = upload.Body, upload.ContentType
+	} else if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader, contentType = bytes.NewReader(data), "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range c.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		apiErr := &Error{StatusCode: resp.StatusCode, Body: data, RequestID: resp.Header.Get("X-Request-ID")}
+		var errBody ErrorResponse
+		if json.Unmarshal(data, &errBody) == nil {
+			apiErr.Message = errBody.Error
+			if errBody.RequestID != "" {
+				apiErr.RequestID = errBody.RequestID
+			}
+		}
+		return nil, apiErr
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,1763 @@
+// Code generated by apigen from the host-agent OpenAPI document. DO NOT EDIT.
+
+package apiclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APIToken is generated from the APIToken schema
+type APIToken struct {
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ID         string    `json:"id"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+	Name       string    `json:"name"`
+	Scopes     []string  `json:"scopes"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+}
+
+// AbsolutePath is generated from the AbsolutePath schema
+type AbsolutePath struct {
+	Headers  map[string]string `json:"headers,omitempty"`
+	Priority int               `json:"priority"`
+	Rule     string            `json:"rule"`
+}
+
+// AlertsConfig is generated from the AlertsConfig schema
+type AlertsConfig struct {
+	Rules []Rule `json:"rules"`
+}
+
+// AlertsResponse is generated from the AlertsResponse schema
+type AlertsResponse struct {
+	Firing []Firing `json:"firing"`
+	Rules  []Rule   `json:"rules"`
+}
+
+// App is generated from the App schema
+type App struct {
+	Bootstrap     *BootstrapConfig `json:"bootstrap,omitempty"`
+	Category      string           `json:"category"`
+	DefaultConfig map[string]any   `json:"defaultConfig"`
+	Dependencies  []string         `json:"dependencies"`
+	Description   string           `json:"description"`
+	DisplayName   string           `json:"displayName"`
+	Docs          Docs             `json:"docs"`
+	HealthCheck   HealthCheck      `json:"healthCheck"`
+	Icon          string           `json:"icon"`
+	ImagePin      string           `json:"imagePin,omitempty"`
+	IsSystem      bool             `json:"isSystem"`
+	Name          string           `json:"name"`
+	Port          int              `json:"port"`
+	Resources     Resources        `json:"resources"`
+	Routing       *Routing         `json:"routing,omitempty"`
+	Screenshots   []string         `json:"screenshots"`
+	Settings      []Setting        `json:"settings,omitempty"`
+	SSO           SSO              `json:"sso"`
+	Tags          []string         `json:"tags"`
+	Version       string           `json:"version"`
+}
+
+// AppEvent is generated from the AppEvent schema
+type AppEvent struct {
+	App        string    `json:"app"`
+	CreatedAt  time.Time `json:"created_at"`
+	FromStatus string    `json:"from_status"`
+	ID         int64     `json:"id"`
+	Reason     string    `json:"reason,omitempty"`
+	ToStatus   string    `json:"to_status"`
+}
+
+// AppHealthResult is generated from the AppHealthResult schema
+type AppHealthResult struct {
+	LastCheck *HealthResult `json:"lastCheck,omitempty"`
+	Name      string        `json:"name"`
+	Status    string        `json:"status"`
+}
+
+// AppHistoryResponse is generated from the AppHistoryResponse schema
+type AppHistoryResponse struct {
+	App    string         `json:"app"`
+	Counts map[string]int `json:"counts"`
+	Events []AppEvent     `json:"events"`
+}
+
+// AppListResponse is generated from the AppListResponse schema
+type AppListResponse struct {
+	Apps  []App `json:"apps"`
+	Total int   `json:"total"`
+}
+
+// AppSearchResponse is generated from the AppSearchResponse schema
+type AppSearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+}
+
+// AppSecretInfo is generated from the AppSecretInfo schema
+type AppSecretInfo struct {
+	Fingerprint string `json:"fingerprint"`
+	Key         string `json:"key"`
+	Managed     bool   `json:"managed,omitempty"`
+}
+
+// AppSecretsResponse is generated from the AppSecretsResponse schema
+type AppSecretsResponse struct {
+	App     string          `json:"app"`
+	Secrets []AppSecretInfo `json:"secrets"`
+}
+
+// AppSettingsResponse is generated from the AppSettingsResponse schema
+type AppSettingsResponse struct {
+	App        string         `json:"app"`
+	Restarting bool           `json:"restarting,omitempty"`
+	Schema     []Setting      `json:"schema"`
+	Values     map[string]any `json:"values"`
+}
+
+// AppStatsResponse is generated from the AppStatsResponse schema
+type AppStatsResponse struct {
+	App   string    `json:"app"`
+	Usage *AppUsage `json:"usage,omitempty"`
+}
+
+// AppUpdatesResponse is generated from the AppUpdatesResponse schema
+type AppUpdatesResponse struct {
+	Available int           `json:"available"`
+	Updates   []ImageUpdate `json:"updates"`
+}
+
+// AppUptimeResponse is generated from the AppUptimeResponse schema
+type AppUptimeResponse struct {
+	App    string `json:"app"`
+	Days   int    `json:"days"`
+	Uptime Uptime `json:"uptime"`
+}
+
+// AppUsage is generated from the AppUsage schema
+type AppUsage struct {
+	CPU         float64   `json:"cpu"`
+	Memory      float64   `json:"memory"`
+	MemoryBytes int64     `json:"memory_bytes"`
+	NetRx       int64     `json:"net_rx"`
+	NetRxRate   float64   `json:"net_rx_rate"`
+	NetTx       int64     `json:"net_tx"`
+	NetTxRate   float64   `json:"net_tx_rate"`
+	SampledAt   time.Time `json:"sampled_at"`
+}
+
+// AuditEntry is generated from the AuditEntry schema
+type AuditEntry struct {
+	CreatedAt  time.Time `json:"created_at"`
+	DurationMs int64     `json:"duration_ms"`
+	ID         int64     `json:"id"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Route      string    `json:"route"`
+	Status     int       `json:"status"`
+	Summary    string    `json:"summary"`
+	Username   string    `json:"username"`
+}
+
+// AuditLogResponse is generated from the AuditLogResponse schema
+type AuditLogResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// AvailableUpdate is generated from the AvailableUpdate schema
+type AvailableUpdate struct {
+	Channel   string        `json:"channel,omitempty"`
+	CheckedAt time.Time     `json:"checkedAt"`
+	FoundAt   time.Time     `json:"foundAt"`
+	Notes     *ReleaseNotes `json:"notes,omitempty"`
+	System    string        `json:"system"`
+}
+
+// Backup is generated from the Backup schema
+type Backup struct {
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+}
+
+// BootstrapConfig is generated from the BootstrapConfig schema
+type BootstrapConfig struct {
+	IndexedDB    *IndexedDBConfig    `json:"indexedDB,omitempty"`
+	LocalStorage *LocalStorageConfig `json:"localStorage,omitempty"`
+}
+
+// Bundle is generated from the Bundle schema
+type Bundle struct {
+	Apps      []BundleApp   `json:"apps"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Routing   BundleRouting `json:"routing"`
+	Signature string        `json:"signature,omitempty"`
+	Version   int           `json:"version"`
+}
+
+// BundleApp is generated from the BundleApp schema
+type BundleApp struct {
+	Choices     map[string]string `json:"choices,omitempty"`
+	DisplayName string            `json:"displayName"`
+	Name        string            `json:"name"`
+	Port        int               `json:"port,omitempty"`
+	Secrets     []string          `json:"secrets,omitempty"`
+	System      bool              `json:"system,omitempty"`
+	Version     string            `json:"version,omitempty"`
+}
+
+// BundleRouting is generated from the BundleRouting schema
+type BundleRouting struct {
+	AuthentikURL string `json:"authentikUrl"`
+	BaseURL      string `json:"baseUrl"`
+}
+
+// ChannelConfig is generated from the ChannelConfig schema
+type ChannelConfig struct {
+	Discord  *DiscordSettings  `json:"discord,omitempty"`
+	Email    *EmailSettings    `json:"email,omitempty"`
+	Enabled  bool              `json:"enabled"`
+	Events   []string          `json:"events,omitempty"`
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Ntfy     *NtfySettings     `json:"ntfy,omitempty"`
+	Telegram *TelegramSettings `json:"telegram,omitempty"`
+	Type     string            `json:"type"`
+}
+
+// ChoiceOption is generated from the ChoiceOption schema
+type ChoiceOption struct {
+	App      string `json:"app"`
+	Category string `json:"category,omitempty"`
+	Default  bool   `json:"default"`
+}
+
+// ClearDataResponse is generated from the ClearDataResponse schema
+type ClearDataResponse struct {
+	App    string `json:"app"`
+	Status string `json:"status"`
+}
+
+// Config is generated from the Config schema
+type Config struct {
+	Enabled bool     `json:"enabled"`
+	Windows []Window `json:"windows"`
+}
+
+// ConfigTask is generated from the ConfigTask schema
+type ConfigTask struct {
+	Integration string `json:"integration"`
+	Source      string `json:"source"`
+	Target      string `json:"target"`
+}
+
+// CreateTokenRequest is generated from the CreateTokenRequest schema
+type CreateTokenRequest struct {
+	ExpiresInDays int      `json:"expiresInDays,omitempty"`
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+}
+
+// CreateTokenResponse is generated from the CreateTokenResponse schema
+type CreateTokenResponse struct {
+	APIToken *APIToken `json:"apiToken,omitempty"`
+	Token    string    `json:"token"`
+}
+
+// CreateUserRequest is generated from the CreateUserRequest schema
+type CreateUserRequest struct {
+	Password string `json:"password"`
+	Username string `json:"username"`
+}
+
+// CreateUserResponse is generated from the CreateUserResponse schema
+type CreateUserResponse struct {
+	Error    string `json:"error,omitempty"`
+	LoginURL string `json:"loginUrl,omitempty"`
+	Success  bool   `json:"success"`
+}
+
+// CurrentUserResponse is generated from the CurrentUserResponse schema
+type CurrentUserResponse struct {
+	ID       string `json:"id"`
+	Role     string `json:"role"`
+	Username string `json:"username"`
+}
+
+// DatabaseBackupsResponse is generated from the DatabaseBackupsResponse schema
+type DatabaseBackupsResponse struct {
+	Backups []Backup `json:"backups"`
+}
+
+// DatabaseCheckFinding is generated from the DatabaseCheckFinding schema
+type DatabaseCheckFinding struct {
+	Action     string `json:"action"`
+	App        string `json:"app"`
+	Check      string `json:"check"`
+	Detail     string `json:"detail"`
+	Error      string `json:"error,omitempty"`
+	Repairable bool   `json:"repairable"`
+	Repaired   bool   `json:"repaired,omitempty"`
+}
+
+// DatabaseCheckResponse is generated from the DatabaseCheckResponse schema
+type DatabaseCheckResponse struct {
+	Findings []DatabaseCheckFinding `json:"findings"`
+	Ok       bool                   `json:"ok"`
+	Repaired int                    `json:"repaired"`
+}
+
+// DiscordSettings is generated from the DiscordSettings schema
+type DiscordSettings struct {
+	WebhookURL string `json:"webhookUrl"`
+}
+
+// Docs is generated from the Docs schema
+type Docs struct {
+	Homepage string `json:"homepage"`
+	Source   string `json:"source"`
+}
+
+// EmailSettings is generated from the EmailSettings schema
+type EmailSettings struct {
+	From     string   `json:"from"`
+	Host     string   `json:"host"`
+	Password string   `json:"password,omitempty"`
+	Port     int      `json:"port"`
+	To       []string `json:"to"`
+	Username string   `json:"username,omitempty"`
+}
+
+// ErrorResponse is generated from the ErrorResponse schema
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Firing is generated from the Firing schema
+type Firing struct {
+	Fired    bool      `json:"fired"`
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	Since    time.Time `json:"since"`
+	Subject  string    `json:"subject,omitempty"`
+	Type     string    `json:"type"`
+	Value    float64   `json:"value"`
+}
+
+// Generation is generated from the Generation schema
+type Generation struct {
+	Current      bool   `json:"current"`
+	Date         string `json:"date"`
+	NixosVersion string `json:"nixosVersion,omitempty"`
+	Number       int    `json:"number"`
+}
+
+// GenerationsResponse is generated from the GenerationsResponse schema
+type GenerationsResponse struct {
+	Generations []Generation `json:"generations"`
+}
+
+// GridElement is generated from the GridElement schema
+type GridElement struct {
+	Col     int    `json:"col"`
+	Colspan int    `json:"colspan"`
+	ID      string `json:"id"`
+	Row     int    `json:"row"`
+	Rowspan int    `json:"rowspan"`
+	Type    string `json:"type"`
+}
+
+// HealthCheck is generated from the HealthCheck schema
+type HealthCheck struct {
+	Interval int    `json:"interval"`
+	Path     string `json:"path"`
+	Timeout  int    `json:"timeout"`
+}
+
+// HealthCheckResult is generated from the HealthCheckResult schema
+type HealthCheckResult struct {
+	Critical  bool   `json:"critical"`
+	Detail    string `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+}
+
+// HealthResult is generated from the HealthResult schema
+type HealthResult struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	Healthy   bool      `json:"healthy"`
+}
+
+// HealthSummaryResponse is generated from the HealthSummaryResponse schema
+type HealthSummaryResponse struct {
+	Apps      []AppHealthResult   `json:"apps"`
+	CheckedAt time.Time           `json:"checkedAt"`
+	Checks    []HealthCheckResult `json:"checks"`
+	Status    string              `json:"status"`
+}
+
+// ImageUpdate is generated from the ImageUpdate schema
+type ImageUpdate struct {
+	App             string    `json:"app"`
+	CheckedAt       time.Time `json:"checked_at"`
+	CurrentDigest   string    `json:"current_digest"`
+	Error           string    `json:"error,omitempty"`
+	Image           string    `json:"image"`
+	LatestDigest    string    `json:"latest_digest,omitempty"`
+	Pinned          bool      `json:"pinned"`
+	UpdateAvailable bool      `json:"update_available"`
+}
+
+// ImportConfigResponse is generated from the ImportConfigResponse schema
+type ImportConfigResponse struct {
+	DryRun   bool     `json:"dryRun"`
+	Install  []string `json:"install"`
+	Skipped  []string `json:"skipped"`
+	Warnings []string `json:"warnings"`
+}
+
+// Incident is generated from the Incident schema
+type Incident struct {
+	Checks   int       `json:"checks"`
+	Duration float64   `json:"duration"`
+	End      time.Time `json:"end,omitempty"`
+	Start    time.Time `json:"start"`
+}
+
+// IndexedDBConfig is generated from the IndexedDBConfig schema
+type IndexedDBConfig struct {
+	Database   string           `json:"database"`
+	Intercepts []IndexedDBEntry `json:"intercepts,omitempty"`
+	Writes     []IndexedDBEntry `json:"writes,omitempty"`
+}
+
+// IndexedDBEntry is generated from the IndexedDBEntry schema
+type IndexedDBEntry struct {
+	Key   string `json:"key"`
+	Store string `json:"store"`
+	Value string `json:"value"`
+}
+
+// InstallAppRequest is generated from the InstallAppRequest schema
+type InstallAppRequest struct {
+	Choices map[string]string `json:"choices,omitempty"`
+	Restore bool              `json:"restore,omitempty"`
+}
+
+// InstallPlan is generated from the InstallPlan schema
+type InstallPlan struct {
+	App        string              `json:"app"`
+	AutoConfig []ConfigTask        `json:"autoConfig"`
+	Blockers   []string            `json:"blockers"`
+	CanInstall bool                `json:"canInstall"`
+	Choices    []IntegrationChoice `json:"choices"`
+	Dependents []ConfigTask        `json:"dependents"`
+}
+
+// InstallResult is generated from the InstallResult schema
+type InstallResult struct {
+	App            string   `json:"app"`
+	AppsInstalled  []string `json:"appsInstalled,omitempty"`
+	ConfigErrors   []string `json:"configErrors,omitempty"`
+	Configured     []string `json:"configured,omitempty"`
+	Error          string   `json:"error,omitempty"`
+	GenerationInfo string   `json:"generationInfo,omitempty"`
+	RebuildOutput  string   `json:"rebuildOutput,omitempty"`
+	Success        bool     `json:"success"`
+}
+
+// InstalledApp is generated from the InstalledApp schema
+type InstalledApp struct {
+	DisplayName         string            `json:"display_name"`
+	ID                  int               `json:"id"`
+	Image               string            `json:"image,omitempty"`
+	ImageDigest         string            `json:"image_digest,omitempty"`
+	InstalledAt         time.Time         `json:"installed_at"`
+	IntegrationConfig   map[string]string `json:"integration_config,omitempty"`
+	IsSystem            bool              `json:"is_system"`
+	Name                string            `json:"name"`
+	Port                int               `json:"port,omitempty"`
+	PreviousImageDigest string            `json:"previous_image_digest,omitempty"`
+	Status              string            `json:"status"`
+	UninstalledAt       time.Time         `json:"uninstalled_at,omitempty"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+	Usage               *AppUsage         `json:"usage,omitempty"`
+	Version             string            `json:"version"`
+}
+
+// IntegrationChoice is generated from the IntegrationChoice schema
+type IntegrationChoice struct {
+	Available   []ChoiceOption `json:"available"`
+	Installed   []ChoiceOption `json:"installed"`
+	Integration string         `json:"integration"`
+	Recommended string         `json:"recommended,omitempty"`
+	Required    bool           `json:"required"`
+}
+
+// InterfaceStats is generated from the InterfaceStats schema
+type InterfaceStats struct {
+	Drops    int64   `json:"drops"`
+	Errors   int64   `json:"errors"`
+	Name     string  `json:"name"`
+	Physical bool    `json:"physical"`
+	RxBytes  int64   `json:"rxBytes"`
+	RxRate   float64 `json:"rxRate"`
+	TxBytes  int64   `json:"txBytes"`
+	TxRate   float64 `json:"txRate"`
+}
+
+// LocalStorageConfig is generated from the LocalStorageConfig schema
+type LocalStorageConfig struct {
+	Intercepts []LocalStorageEntry `json:"intercepts,omitempty"`
+}
+
+// LocalStorageEntry is generated from the LocalStorageEntry schema
+type LocalStorageEntry struct {
+	JSONPatch map[string]string `json:"jsonPatch,omitempty"`
+	Key       string            `json:"key"`
+	Value     string            `json:"value,omitempty"`
+}
+
+// LoggingResponse is generated from the LoggingResponse schema
+type LoggingResponse struct {
+	Config LogshipConfig `json:"config"`
+	Status LogshipStatus `json:"status"`
+}
+
+// LogshipConfig is generated from the LogshipConfig schema
+type LogshipConfig struct {
+	Enabled  bool              `json:"enabled"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Password string            `json:"password,omitempty"`
+	Sink     string            `json:"sink"`
+	URL      string            `json:"url,omitempty"`
+	Username string            `json:"username,omitempty"`
+}
+
+// LogshipStatus is generated from the LogshipStatus schema
+type LogshipStatus struct {
+	LastError   string    `json:"lastError,omitempty"`
+	LastShipped time.Time `json:"lastShipped,omitempty"`
+	Running     bool      `json:"running"`
+	Shipped     int64     `json:"shipped"`
+}
+
+// MaintenanceReport is generated from the MaintenanceReport schema
+type MaintenanceReport struct {
+	Backup          *Backup   `json:"backup,omitempty"`
+	DurationMs      int64     `json:"duration_ms"`
+	IntegrityErrors []string  `json:"integrity_errors,omitempty"`
+	IntegrityOk     bool      `json:"integrity_ok"`
+	Pruned          []string  `json:"pruned,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	Vacuumed        bool      `json:"vacuumed"`
+}
+
+// NetworkResponse is generated from the NetworkResponse schema
+type NetworkResponse struct {
+	Apps        []AppStatsResponse `json:"apps"`
+	Connections int                `json:"connections"`
+	Interfaces  []InterfaceStats   `json:"interfaces"`
+}
+
+// NotificationTestResponse is generated from the NotificationTestResponse schema
+type NotificationTestResponse struct {
+	Results []NotificationTestResult `json:"results"`
+}
+
+// NotificationTestResult is generated from the NotificationTestResult schema
+type NotificationTestResult struct {
+	Error   string `json:"error,omitempty"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+}
+
+// NotifyConfig is generated from the NotifyConfig schema
+type NotifyConfig struct {
+	Channels []ChannelConfig `json:"channels"`
+}
+
+// NtfySettings is generated from the NtfySettings schema
+type NtfySettings struct {
+	Token string `json:"token,omitempty"`
+	URL   string `json:"url"`
+}
+
+// PowerActionRequest is generated from the PowerActionRequest schema
+type PowerActionRequest struct {
+	Confirm string `json:"confirm,omitempty"`
+}
+
+// PowerActionResponse is generated from the PowerActionResponse schema
+type PowerActionResponse struct {
+	Action       string    `json:"action"`
+	ConfirmToken string    `json:"confirmToken,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt,omitempty"`
+	Status       string    `json:"status"`
+}
+
+// PowerScheduleResponse is generated from the PowerScheduleResponse schema
+type PowerScheduleResponse struct {
+	Config Config `json:"config"`
+	Status Status `json:"status"`
+}
+
+// Preferences is generated from the Preferences schema
+type Preferences struct {
+	Layout     []GridElement `json:"layout"`
+	Locale     string        `json:"locale,omitempty"`
+	PinnedApps []string      `json:"pinned_apps"`
+	Theme      string        `json:"theme"`
+}
+
+// ReleaseChange is generated from the ReleaseChange schema
+type ReleaseChange struct {
+	Breaking bool   `json:"breaking,omitempty"`
+	Commit   string `json:"commit"`
+	Summary  string `json:"summary"`
+}
+
+// ReleaseNotes is generated from the ReleaseNotes schema
+type ReleaseNotes struct {
+	Breaking     []string        `json:"breaking,omitempty"`
+	Changes      []ReleaseChange `json:"changes"`
+	FromRevision string          `json:"fromRevision"`
+	Migrations   []string        `json:"migrations,omitempty"`
+	NewApps      []string        `json:"newApps,omitempty"`
+	ToRevision   string          `json:"toRevision"`
+	Truncated    bool            `json:"truncated,omitempty"`
+}
+
+// RemovePlan is generated from the RemovePlan schema
+type RemovePlan struct {
+	App             string   `json:"app"`
+	Blockers        []string `json:"blockers"`
+	CanRemove       bool     `json:"canRemove"`
+	WillUnconfigure []string `json:"willUnconfigure"`
+}
+
+// RenameAppRequest is generated from the RenameAppRequest schema
+type RenameAppRequest struct {
+	DisplayName string `json:"displayName"`
+}
+
+// RenameAppResponse is generated from the RenameAppResponse schema
+type RenameAppResponse struct {
+	App         string `json:"app"`
+	DisplayName string `json:"displayName"`
+	Status      string `json:"status"`
+}
+
+// Resources is generated from the Resources schema
+type Resources struct {
+	GPU     bool `json:"gpu"`
+	MinDisk int  `json:"minDisk"`
+	MinRam  int  `json:"minRam"`
+}
+
+// RestoreBackupResponse is generated from the RestoreBackupResponse schema
+type RestoreBackupResponse struct {
+	Restored     string `json:"restored"`
+	SafetyBackup string `json:"safetyBackup"`
+}
+
+// RevokeTokensResponse is generated from the RevokeTokensResponse schema
+type RevokeTokensResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// RollbackResponse is generated from the RollbackResponse schema
+type RollbackResponse struct {
+	Changes      []string `json:"changes"`
+	Duration     string   `json:"duration"`
+	ErrorMessage string   `json:"errorMessage"`
+	Output       string   `json:"output"`
+	Success      bool     `json:"success"`
+}
+
+// Rotation is generated from the Rotation schema
+type Rotation struct {
+	Apps      []string  `json:"apps"`
+	Completed []string  `json:"completed"`
+	StartedAt time.Time `json:"startedAt"`
+	Version   int       `json:"version"`
+}
+
+// Routing is generated from the Routing schema
+type Routing struct {
+	AbsolutePaths []AbsolutePath    `json:"absolutePaths,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	StripPrefix   bool              `json:"stripPrefix,omitempty"`
+}
+
+// Rule is generated from the Rule schema
+type Rule struct {
+	ForMinutes int     `json:"forMinutes"`
+	ID         string  `json:"id"`
+	Muted      bool    `json:"muted"`
+	Severity   string  `json:"severity"`
+	Threshold  float64 `json:"threshold"`
+	Type       string  `json:"type"`
+}
+
+// SSO is generated from the SSO schema
+type SSO struct {
+	CallbackPath string `json:"callbackPath"`
+	Env          SSOEnv `json:"env"`
+	ProviderName string `json:"providerName"`
+	Strategy     string `json:"strategy"`
+	UserCreation bool   `json:"userCreation"`
+}
+
+// SSOEnv is generated from the SSOEnv schema
+type SSOEnv struct {
+	ClientID       string `json:"clientId"`
+	ClientSecret   string `json:"clientSecret"`
+	DiscoveryURL   string `json:"discoveryUrl"`
+	Issuer         string `json:"issuer"`
+	Provider       string `json:"provider"`
+	ProviderName   string `json:"providerName"`
+	RedirectURL    string `json:"redirectUrl"`
+	ServerHostname string `json:"serverHostname"`
+	UserCreation   string `json:"userCreation"`
+}
+
+// SchedulerStatus is generated from the SchedulerStatus schema
+type SchedulerStatus struct {
+	DefaultSpec    string    `json:"defaultSpec"`
+	Description    string    `json:"description"`
+	Enabled        bool      `json:"enabled"`
+	LastDurationMs int64     `json:"lastDurationMs,omitempty"`
+	LastError      string    `json:"lastError,omitempty"`
+	LastRun        time.Time `json:"lastRun,omitempty"`
+	Name           string    `json:"name"`
+	NextRun        time.Time `json:"nextRun,omitempty"`
+	Running        bool      `json:"running"`
+	Spec           string    `json:"spec"`
+}
+
+// SchedulesResponse is generated from the SchedulesResponse schema
+type SchedulesResponse struct {
+	Schedules []SchedulerStatus `json:"schedules"`
+}
+
+// SearchResult is generated from the SearchResult schema
+type SearchResult struct {
+	App     *App     `json:"app,omitempty"`
+	Matched []string `json:"matched"`
+	Score   float64  `json:"score"`
+}
+
+// SecretAccessEntry is generated from the SecretAccessEntry schema
+type SecretAccessEntry struct {
+	Action    string    `json:"action"`
+	App       string    `json:"app"`
+	Caller    string    `json:"caller"`
+	Component string    `json:"component"`
+	CreatedAt time.Time `json:"created_at"`
+	ID        int64     `json:"id"`
+	Secret    string    `json:"secret"`
+}
+
+// SecretAccessLogResponse is generated from the SecretAccessLogResponse schema
+type SecretAccessLogResponse struct {
+	Entries []SecretAccessEntry `json:"entries"`
+}
+
+// SecretRotationResponse is generated from the SecretRotationResponse schema
+type SecretRotationResponse struct {
+	KeyVersion int       `json:"keyVersion"`
+	Resumed    bool      `json:"resumed,omitempty"`
+	Rotation   *Rotation `json:"rotation,omitempty"`
+	Running    bool      `json:"running"`
+}
+
+// SetAppSecretRequest is generated from the SetAppSecretRequest schema
+type SetAppSecretRequest struct {
+	Secret string `json:"secret"`
+}
+
+// Setting is generated from the Setting schema
+type Setting struct {
+	Default     any      `json:"default,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Env         string   `json:"env,omitempty"`
+	Key         string   `json:"key"`
+	Label       string   `json:"label"`
+	Max         int      `json:"max,omitempty"`
+	Min         int      `json:"min,omitempty"`
+	Options     []string `json:"options,omitempty"`
+	Type        string   `json:"type"`
+}
+
+// SetupStatusResponse is generated from the SetupStatusResponse schema
+type SetupStatusResponse struct {
+	AuthentikReady bool `json:"authentikReady"`
+	SetupRequired  bool `json:"setupRequired"`
+}
+
+// Stats is generated from the Stats schema
+type Stats struct {
+	CPU         int   `json:"cpu"`
+	Disk        int   `json:"disk"`
+	Memory      int   `json:"memory"`
+	Temperature int   `json:"temperature,omitempty"`
+	Throttles   int64 `json:"throttles,omitempty"`
+}
+
+// StatsHistoryResponse is generated from the StatsHistoryResponse schema
+type StatsHistoryResponse struct {
+	App        string        `json:"app,omitempty"`
+	Range      string        `json:"range"`
+	Resolution int           `json:"resolution"`
+	Samples    []StatsSample `json:"samples"`
+}
+
+// StatsSample is generated from the StatsSample schema
+type StatsSample struct {
+	App         string    `json:"app,omitempty"`
+	CPU         float64   `json:"cpu"`
+	Disk        float64   `json:"disk,omitempty"`
+	Memory      float64   `json:"memory"`
+	T           time.Time `json:"t"`
+	Temperature float64   `json:"temperature,omitempty"`
+}
+
+// Status is generated from the Status schema
+type Status struct {
+	LastError string    `json:"lastError,omitempty"`
+	LastSleep time.Time `json:"lastSleep,omitempty"`
+	LastWake  time.Time `json:"lastWake,omitempty"`
+	NextMode  string    `json:"nextMode,omitempty"`
+	NextSleep time.Time `json:"nextSleep,omitempty"`
+	NextWake  time.Time `json:"nextWake,omitempty"`
+	Sleeping  bool      `json:"sleeping"`
+}
+
+// StatusResponse is generated from the StatusResponse schema
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// StorageBreakdown is generated from the StorageBreakdown schema
+type StorageBreakdown struct {
+	Categories []StorageCategory `json:"categories"`
+	Disk       *StorageStats     `json:"disk,omitempty"`
+	DurationMs int64             `json:"durationMs,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	ScannedAt  time.Time         `json:"scannedAt,omitempty"`
+	Scanning   bool              `json:"scanning"`
+	Unreadable int               `json:"unreadable,omitempty"`
+}
+
+// StorageCategory is generated from the StorageCategory schema
+type StorageCategory struct {
+	Bytes int64         `json:"bytes"`
+	Items []StorageItem `json:"items,omitempty"`
+	Name  string        `json:"name"`
+}
+
+// StorageItem is generated from the StorageItem schema
+type StorageItem struct {
+	Bytes int64  `json:"bytes"`
+	Name  string `json:"name"`
+}
+
+// StorageStats is generated from the StorageStats schema
+type StorageStats struct {
+	Free       int64  `json:"free"`
+	Path       string `json:"path"`
+	Percentage int    `json:"percentage"`
+	Total      int64  `json:"total"`
+	Used       int64  `json:"used"`
+}
+
+// TelegramSettings is generated from the TelegramSettings schema
+type TelegramSettings struct {
+	BotToken string `json:"botToken"`
+	ChatID   string `json:"chatId"`
+}
+
+// TokenListResponse is generated from the TokenListResponse schema
+type TokenListResponse struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+// TopAppsResponse is generated from the TopAppsResponse schema
+type TopAppsResponse struct {
+	Apps []AppStatsResponse `json:"apps"`
+	Sort string             `json:"sort"`
+}
+
+// UninstallAppRequest is generated from the UninstallAppRequest schema
+type UninstallAppRequest struct {
+	ClearData bool `json:"clearData,omitempty"`
+}
+
+// UninstallResult is generated from the UninstallResult schema
+type UninstallResult struct {
+	App          string   `json:"app"`
+	Error        string   `json:"error,omitempty"`
+	Success      bool     `json:"success"`
+	Unconfigured []string `json:"unconfigured,omitempty"`
+}
+
+// Update is generated from the Update schema
+type Update struct {
+	Enabled bool   `json:"enabled,omitempty"`
+	Spec    string `json:"spec,omitempty"`
+}
+
+// UpdatePolicy is generated from the UpdatePolicy schema
+type UpdatePolicy struct {
+	Channel string       `json:"channel"`
+	Mode    string       `json:"mode"`
+	Window  UpdateWindow `json:"window"`
+}
+
+// UpdatePreferencesRequest is generated from the UpdatePreferencesRequest schema
+type UpdatePreferencesRequest struct {
+	Layout     []GridElement `json:"layout,omitempty"`
+	Locale     string        `json:"locale,omitempty"`
+	PinnedApps []string      `json:"pinned_apps,omitempty"`
+	Theme      string        `json:"theme,omitempty"`
+}
+
+// UpdateSnapshot is generated from the UpdateSnapshot schema
+type UpdateSnapshot struct {
+	Database   string    `json:"database,omitempty"`
+	Filesystem string    `json:"filesystem,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Secrets    string    `json:"secrets,omitempty"`
+	TakenAt    time.Time `json:"takenAt"`
+}
+
+// UpdateWindow is generated from the UpdateWindow schema
+type UpdateWindow struct {
+	Days  []string `json:"days,omitempty"`
+	End   string   `json:"end"`
+	Start string   `json:"start"`
+}
+
+// UpdatesResponse is generated from the UpdatesResponse schema
+type UpdatesResponse struct {
+	Policy UpdatePolicy  `json:"policy"`
+	Status UpdatesStatus `json:"status"`
+}
+
+// UpdatesStatus is generated from the UpdatesStatus schema
+type UpdatesStatus struct {
+	Available     *AvailableUpdate `json:"available,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	FromSystem    string           `json:"fromSystem,omitempty"`
+	LastAttemptAt time.Time        `json:"lastAttemptAt,omitempty"`
+	NextWindow    time.Time        `json:"nextWindow,omitempty"`
+	Snapshot      *UpdateSnapshot  `json:"snapshot,omitempty"`
+	Status        string           `json:"status,omitempty"`
+	SwitchedAt    time.Time        `json:"switchedAt,omitempty"`
+	ToSystem      string           `json:"toSystem,omitempty"`
+}
+
+// Uptime is generated from the Uptime schema
+type Uptime struct {
+	Checks    int         `json:"checks"`
+	Daily     []UptimeDay `json:"daily"`
+	Failed    int         `json:"failed"`
+	Incidents []Incident  `json:"incidents"`
+	Percent   float64     `json:"percent,omitempty"`
+}
+
+// UptimeDay is generated from the UptimeDay schema
+type UptimeDay struct {
+	Checks  int     `json:"checks"`
+	Date    string  `json:"date"`
+	Percent float64 `json:"percent,omitempty"`
+}
+
+// WatchdogConfig is generated from the WatchdogConfig schema
+type WatchdogConfig struct {
+	CheckIntervalSeconds     int      `json:"checkIntervalSeconds"`
+	Exclude                  []string `json:"exclude"`
+	FailuresBeforeError      int      `json:"failuresBeforeError"`
+	InstallingTimeoutMinutes int      `json:"installingTimeoutMinutes"`
+	ResumeGraceSeconds       int      `json:"resumeGraceSeconds"`
+	StartGraceSeconds        int      `json:"startGraceSeconds"`
+	StartingTimeoutMinutes   int      `json:"startingTimeoutMinutes"`
+}
+
+// Window is generated from the Window schema
+type Window struct {
+	Days  []string `json:"days,omitempty"`
+	Mode  string   `json:"mode,omitempty"`
+	Sleep string   `json:"sleep"`
+	Wake  string   `json:"wake"`
+}
+
+// ListApps calls GET /api/v1/apps: list catalog apps
+func (c *Client) ListApps(ctx context.Context, query url.Values) (*AppListResponse, error) {
+	var out AppListResponse
+	if err := c.doJSON(ctx, "GET", withQuery("/api/v1/apps", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StreamAppEvents calls GET /api/v1/apps/events: stream installed app state (SSE)
+// The caller must close the response body.
+func (c *Client) StreamAppEvents(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, "GET", "/api/v1/apps/events", nil)
+}
+
+// ListInstalledApps calls GET /api/v1/apps/installed: list installed apps
+func (c *Client) ListInstalledApps(ctx context.Context, query url.Values) ([]InstalledApp, error) {
+	var out []InstalledApp
+	if err := c.doJSON(ctx, "GET", withQuery("/api/v1/apps/installed", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RefreshCatalog calls POST /api/v1/apps/refresh-catalog: reload the app catalog
+func (c *Client) RefreshCatalog(ctx context.Context) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/apps/refresh-catalog", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchApps calls GET /api/v1/apps/search: search the catalog, ranked by relevance
+func (c *Client) SearchApps(ctx context.Context, query url.Values) (*AppSearchResponse, error) {
+	var out AppSearchResponse
+	if err := c.doJSON(ctx, "GET", withQuery("/api/v1/apps/search", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListUninstalledApps calls GET /api/v1/apps/uninstalled: list previously installed apps and their saved configuration
+func (c *Client) ListUninstalledApps(ctx context.Context) ([]InstalledApp, error) {
+	var out []InstalledApp
+	if err := c.doJSON(ctx, "GET", "/api/v1/apps/uninstalled", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListAppUpdates calls GET /api/v1/apps/updates: list the latest image update check of each installed app
+func (c *Client) ListAppUpdates(ctx context.Context) (*AppUpdatesResponse, error) {
+	var out AppUpdatesResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/apps/updates", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClearAppData calls POST /api/v1/apps/{name}/clear-data: uninstall an app and delete its data
+func (c *Client) ClearAppData(ctx context.Context, name string) (*ClearDataResponse, error) {
+	var out ClearDataResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/apps/"+url.PathEscape(name)+"/clear-data", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAppHistory calls GET /api/v1/apps/{name}/history: list an app's status transitions
+func (c *Client) GetAppHistory(ctx context.Context, name string, query url.Values) (*AppHistoryResponse, error) {
+	var out AppHistoryResponse
+	if err := c.doJSON(ctx, "GET", withQuery("/api/v1/apps/"+url.PathEscape(name)+"/history", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAppIcon calls DELETE /api/v1/apps/{name}/icon: remove an app's custom icon
+func (c *Client) DeleteAppIcon(ctx context.Context, name string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "DELETE", "/api/v1/apps/"+url.PathEscape(name)+"/icon", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAppIcon calls GET /api/v1/apps/{name}/icon: get an app's icon
+// The caller must close the response body.
+func (c *Client) GetAppIcon(ctx context.Context, name string) (*http.Response, error) {
+	return c.doRaw(ctx, "GET", "/api/v1/apps/"+url.PathEscape(name)+"/icon", nil)
+}
+
+// SetAppIcon calls PUT /api/v1/apps/{name}/icon: upload a custom icon for an app
+func (c *Client) SetAppIcon(ctx context.Context, name string, body Upload) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "PUT", "/api/v1/apps/"+url.PathEscape(name)+"/icon", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// InstallApp calls POST /api/v1/apps/{name}/install: install an app
+func (c *Client) InstallApp(ctx context.Context, name string, body InstallAppRequest) (*InstallResult, error) {
+	var out InstallResult
+	if err := c.doJSON(ctx, "POST", "/api/v1/apps/"+url.PathEscape(name)+"/install", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StreamAppLogs calls GET /api/v1/apps/{name}/logs: stream app logs (SSE)
+// The caller must close the response body.
+func (c *Client) StreamAppLogs(ctx context.Context, name string) (*http.Response, error) {
+	return c.doRaw(ctx, "GET", "/api/v1/apps/"+url.PathEscape(name)+"/logs", nil)
+}
+
+// GetAppMetadata calls GET /api/v1/apps/{name}/metadata: get catalog metadata for an app
+func (c *Client) GetAppMetadata(ctx context.Context, name string) (*App, error) {
+	var out App
+	if err := c.doJSON(ctx, "GET", "/api/v1/apps/"+url.PathEscape(name)+"/metadata", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PlanInstall calls GET /api/v1/apps/{name}/plan-install: preview what installing an app will do
+func (c *Client) PlanInstall(ctx context.Context, name string) (*InstallPlan, error) {
+	var out InstallPlan
+	if err := c.doJSON(ctx, "GET", "/api/v1/apps/"+url.PathEscape(name)+"/plan-install", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PlanRemove calls GET /api/v1/apps/{name}/plan-remove: preview what removing an app will do
+func (c *Client) PlanRemove(ctx context.Context, name string) (*RemovePlan, error) {
+	var out RemovePlan
+	if err := c.doJSON(ctx, "GET", "/api/v1/apps/"+url.PathEscape(name)+"/plan-remove", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RenameApp calls PATCH /api/v1/apps/{name}/rename: change an app's display name
+func (c *Client) RenameApp(ctx context.Context, name string, body RenameAppRequest) (*RenameAppResponse, error) {
+	var out RenameAppResponse
+	if err := c.doJSON(ctx, "PATCH", "/api/v1/apps/"+url.PathEscape(name)+"/rename", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAppSecrets calls GET /api/v1/apps/{name}/secrets: list an app's secrets by key and fingerprint
+func (c *Client) ListAppSecrets(ctx context.Context, name string) (*AppSecretsResponse, error) {
+	var out AppSecretsResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/apps/"+url.PathEscape(name)+"/secrets", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAppSecret calls DELETE /api/v1/apps/{name}/secrets/{key}: delete a secret stored for an app
+func (c *Client) DeleteAppSecret(ctx context.Context, name string, key string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "DELETE", "/api/v1/apps/"+url.PathEscape(name)+"/secrets/"+url.PathEscape(key), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetAppSecret calls PUT /api/v1/apps/{name}/secrets/{key}: store a secret for an app
+func (c *Client) SetAppSecret(ctx context.Context, name string, key string, body SetAppSecretRequest) (*AppSecretInfo, error) {
+	var out AppSecretInfo
+	if err := c.doJSON(ctx, "PUT", "/api/v1/apps/"+url.PathEscape(name)+"/secrets/"+url.PathEscape(key), body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAppSettings calls GET /api/v1/apps/{name}/settings: get an app's settings schema and values
+func (c *Client) GetAppSettings(ctx context.Context, name string) (*AppSettingsResponse, error) {
+	var out AppSettingsResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/apps/"+url.PathEscape(name)+"/settings", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetAppSettings calls PUT /api/v1/apps/{name}/settings: update an app's settings
+func (c *Client) SetAppSettings(ctx context.Context, name string, body map[string]any) (*AppSettingsResponse, error) {
+	var out AppSettingsResponse
+	if err := c.doJSON(ctx, "PUT", "/api/v1/apps/"+url.PathEscape(name)+"/settings", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAppStats calls GET /api/v1/apps/{name}/stats: get an app's current CPU, memory and network usage
+func (c *Client) GetAppStats(ctx context.Context, name string) (*AppStatsResponse, error) {
+	var out AppStatsResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/apps/"+url.PathEscape(name)+"/stats", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UninstallApp calls POST /api/v1/apps/{name}/uninstall: uninstall an app
+func (c *Client) UninstallApp(ctx context.Context, name string, body UninstallAppRequest) (*UninstallResult, error) {
+	var out UninstallResult
+	if err := c.doJSON(ctx, "POST", "/api/v1/apps/"+url.PathEscape(name)+"/uninstall", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAppUptime calls GET /api/v1/apps/{name}/uptime: get an app's uptime and downtime incidents over 30 or 90 days
+func (c *Client) GetAppUptime(ctx context.Context, name string, query url.Values) (*AppUptimeResponse, error) {
+	var out AppUptimeResponse
+	if err := c.doJSON(ctx, "GET", withQuery("/api/v1/apps/"+url.PathEscape(name)+"/uptime", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCurrentUser calls GET /api/v1/auth/me: get the authenticated user
+func (c *Client) GetCurrentUser(ctx context.Context) (*CurrentUserResponse, error) {
+	var out CurrentUserResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/auth/me", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTokens calls GET /api/v1/auth/tokens: list your personal access tokens
+func (c *Client) ListTokens(ctx context.Context) (*TokenListResponse, error) {
+	var out TokenListResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/auth/tokens", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateToken calls POST /api/v1/auth/tokens: create a personal access token
+func (c *Client) CreateToken(ctx context.Context, body CreateTokenRequest) (*CreateTokenResponse, error) {
+	var out CreateTokenResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/auth/tokens", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteToken calls DELETE /api/v1/auth/tokens/{id}: revoke a personal access token
+func (c *Client) DeleteToken(ctx context.Context, id string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "DELETE", "/api/v1/auth/tokens/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Health calls GET /api/v1/health: health check
+func (c *Client) Health(ctx context.Context) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/health", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOpenAPISpec calls GET /api/v1/openapi.json: get this OpenAPI document
+// The caller must close the response body.
+func (c *Client) GetOpenAPISpec(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, "GET", "/api/v1/openapi.json", nil)
+}
+
+// ProvisioningWebhook calls POST /api/v1/provisioning/webhook: receive an Authentik notification webhook
+// The caller must close the response body.
+func (c *Client) ProvisioningWebhook(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, "POST", "/api/v1/provisioning/webhook", nil)
+}
+
+// CreateUser calls POST /api/v1/setup/create-user: create the first admin user
+func (c *Client) CreateUser(ctx context.Context, body CreateUserRequest) (*CreateUserResponse, error) {
+	var out CreateUserResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/setup/create-user", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSetupStatus calls GET /api/v1/setup/status: check whether initial setup is required
+func (c *Client) GetSetupStatus(ctx context.Context) (*SetupStatusResponse, error) {
+	var out SetupStatusResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/setup/status", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAlerts calls GET /api/v1/system/alerts: get alert rules and the alerts currently firing
+func (c *Client) GetAlerts(ctx context.Context) (*AlertsResponse, error) {
+	var out AlertsResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/alerts", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetAlerts calls PUT /api/v1/system/alerts: replace alert rules
+func (c *Client) SetAlerts(ctx context.Context, body AlertsConfig) (*AlertsResponse, error) {
+	var out AlertsResponse
+	if err := c.doJSON(ctx, "PUT", "/api/v1/system/alerts", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAuditLog calls GET /api/v1/system/audit: list audited state-changing requests
+func (c *Client) ListAuditLog(ctx context.Context, query url.Values) (*AuditLogResponse, error) {
+	var out AuditLogResponse
+	if err := c.doJSON(ctx, "GET", withQuery("/api/v1/system/audit", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListDatabaseBackups calls GET /api/v1/system/database/backups: list host-agent database backups
+func (c *Client) ListDatabaseBackups(ctx context.Context) (*DatabaseBackupsResponse, error) {
+	var out DatabaseBackupsResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/database/backups", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RestoreDatabaseBackup calls POST /api/v1/system/database/backups/{name}/restore: restore the host-agent database from a backup
+func (c *Client) RestoreDatabaseBackup(ctx context.Context, name string) (*RestoreBackupResponse, error) {
+	var out RestoreBackupResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/database/backups/"+url.PathEscape(name)+"/restore", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RunDatabaseMaintenance calls POST /api/v1/system/database/maintenance: check, vacuum and back up the host-agent database now
+func (c *Client) RunDatabaseMaintenance(ctx context.Context) (*MaintenanceReport, error) {
+	var out MaintenanceReport
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/database/maintenance", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CheckDatabase calls GET /api/v1/system/db/check: check apps, integrations and secrets for inconsistencies
+func (c *Client) CheckDatabase(ctx context.Context) (*DatabaseCheckResponse, error) {
+	var out DatabaseCheckResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/db/check", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RepairDatabase calls POST /api/v1/system/db/repair: check for inconsistencies and fix the repairable ones
+func (c *Client) RepairDatabase(ctx context.Context) (*DatabaseCheckResponse, error) {
+	var out DatabaseCheckResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/db/repair", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateDiagnosticsBundle calls POST /api/v1/system/diagnostics: download a support bundle of recent journals, app statuses, apps.nix, Traefik config, versions and the last rebuild output, with secrets scrubbed
+// The caller must close the response body.
+func (c *Client) CreateDiagnosticsBundle(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, "POST", "/api/v1/system/diagnostics", nil)
+}
+
+// ExportConfig calls GET /api/v1/system/export: export a signed bundle of the system configuration
+func (c *Client) ExportConfig(ctx context.Context) (*Bundle, error) {
+	var out Bundle
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/export", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetHealthSummary calls GET /api/v1/system/health/summary: get the overall health of the host agent, its dependencies and installed apps
+func (c *Client) GetHealthSummary(ctx context.Context) (*HealthSummaryResponse, error) {
+	var out HealthSummaryResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/health/summary", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImportConfig calls POST /api/v1/system/import: replay a configuration bundle through the orchestrator
+func (c *Client) ImportConfig(ctx context.Context, query url.Values, body Bundle) (*ImportConfigResponse, error) {
+	var out ImportConfigResponse
+	if err := c.doJSON(ctx, "POST", withQuery("/api/v1/system/import", query), body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLogging calls GET /api/v1/system/logging: get log shipping settings (password redacted) and status
+func (c *Client) GetLogging(ctx context.Context) (*LoggingResponse, error) {
+	var out LoggingResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/logging", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetLogging calls PUT /api/v1/system/logging: configure forwarding of host-agent and app logs to Loki or Vector
+func (c *Client) SetLogging(ctx context.Context, body LogshipConfig) (*LoggingResponse, error) {
+	var out LoggingResponse
+	if err := c.doJSON(ctx, "PUT", "/api/v1/system/logging", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetScrapeConfig calls GET /api/v1/system/metrics/scrape-config: get a Prometheus scrape config for the host agent's metrics
+// The caller must close the response body.
+func (c *Client) GetScrapeConfig(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, "GET", "/api/v1/system/metrics/scrape-config", nil)
+}
+
+// GetNetwork calls GET /api/v1/system/network: get per-interface throughput, TCP connections and the apps using the most network
+func (c *Client) GetNetwork(ctx context.Context) (*NetworkResponse, error) {
+	var out NetworkResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/network", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNotifications calls GET /api/v1/system/notifications: get notification channels (credentials redacted)
+func (c *Client) GetNotifications(ctx context.Context) (*NotifyConfig, error) {
+	var out NotifyConfig
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/notifications", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetNotifications calls PUT /api/v1/system/notifications: replace notification channels
+func (c *Client) SetNotifications(ctx context.Context, body NotifyConfig) (*NotifyConfig, error) {
+	var out NotifyConfig
+	if err := c.doJSON(ctx, "PUT", "/api/v1/system/notifications", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TestNotifications calls POST /api/v1/system/notifications/test: send a test notification
+func (c *Client) TestNotifications(ctx context.Context, query url.Values) (*NotificationTestResponse, error) {
+	var out NotificationTestResponse
+	if err := c.doJSON(ctx, "POST", withQuery("/api/v1/system/notifications/test", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPowerSchedule calls GET /api/v1/system/power: get the power schedule and its next and last sleep
+func (c *Client) GetPowerSchedule(ctx context.Context) (*PowerScheduleResponse, error) {
+	var out PowerScheduleResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/power", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetPowerSchedule calls PUT /api/v1/system/power: replace the windows the host suspends or powers off through
+func (c *Client) SetPowerSchedule(ctx context.Context, body Config) (*PowerScheduleResponse, error) {
+	var out PowerScheduleResponse
+	if err := c.doJSON(ctx, "PUT", "/api/v1/system/power", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SyncProvisioning calls POST /api/v1/system/provisioning/sync: sync Authentik users into apps
+func (c *Client) SyncProvisioning(ctx context.Context) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/provisioning/sync", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Reboot calls POST /api/v1/system/reboot: reboot the host (call twice: the first call returns a confirmation token)
+func (c *Client) Reboot(ctx context.Context, body PowerActionRequest) (*PowerActionResponse, error) {
+	var out PowerActionResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/reboot", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StreamRebuild calls GET /api/v1/system/rebuild/stream: stream NixOS rebuild events (SSE)
+// The caller must close the response body.
+func (c *Client) StreamRebuild(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, "GET", "/api/v1/system/rebuild/stream", nil)
+}
+
+// Rollback calls POST /api/v1/system/rollback: roll back to the previous NixOS generation
+func (c *Client) Rollback(ctx context.Context) (*RollbackResponse, error) {
+	var out RollbackResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/rollback", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSchedules calls GET /api/v1/system/schedules: list scheduled jobs with their last and next runs
+func (c *Client) ListSchedules(ctx context.Context) (*SchedulesResponse, error) {
+	var out SchedulesResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/schedules", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateSchedule calls PUT /api/v1/system/schedules/{name}: change a scheduled job's cron schedule or turn it on or off
+func (c *Client) UpdateSchedule(ctx context.Context, name string, body Update) (*SchedulerStatus, error) {
+	var out SchedulerStatus
+	if err := c.doJSON(ctx, "PUT", "/api/v1/system/schedules/"+url.PathEscape(name), body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RunSchedule calls POST /api/v1/system/schedules/{name}/run: run a scheduled job now
+func (c *Client) RunSchedule(ctx context.Context, name string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/schedules/"+url.PathEscape(name)+"/run", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSecretAccess calls GET /api/v1/system/secrets/access: list secret reads and writes, newest first
+func (c *Client) ListSecretAccess(ctx context.Context, query url.Values) (*SecretAccessLogResponse, error) {
+	var out SecretAccessLogResponse
+	if err := c.doJSON(ctx, "GET", withQuery("/api/v1/system/secrets/access", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RotateSecrets calls POST /api/v1/system/secrets/rotate: rotate per-app OAuth client secrets to a new key version, or resume an interrupted rotation
+func (c *Client) RotateSecrets(ctx context.Context) (*SecretRotationResponse, error) {
+	var out SecretRotationResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/secrets/rotate", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSecretRotation calls GET /api/v1/system/secrets/rotation: get the per-app secret key version and any rotation in progress
+func (c *Client) GetSecretRotation(ctx context.Context) (*SecretRotationResponse, error) {
+	var out SecretRotationResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/secrets/rotation", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Shutdown calls POST /api/v1/system/shutdown: power off the host (call twice: the first call returns a confirmation token)
+func (c *Client) Shutdown(ctx context.Context, body PowerActionRequest) (*PowerActionResponse, error) {
+	var out PowerActionResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/shutdown", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTopApps calls GET /api/v1/system/stats/apps: list the apps using the most CPU, memory or network
+func (c *Client) GetTopApps(ctx context.Context, query url.Values) (*TopAppsResponse, error) {
+	var out TopAppsResponse
+	if err := c.doJSON(ctx, "GET", withQuery("/api/v1/system/stats/apps", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStatsHistory calls GET /api/v1/system/stats/history: get CPU, memory and disk usage over time
+func (c *Client) GetStatsHistory(ctx context.Context, query url.Values) (*StatsHistoryResponse, error) {
+	var out StatsHistoryResponse
+	if err := c.doJSON(ctx, "GET", withQuery("/api/v1/system/stats/history", query), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSystemStatus calls GET /api/v1/system/status: get CPU, memory and disk usage
+func (c *Client) GetSystemStatus(ctx context.Context) (*Stats, error) {
+	var out Stats
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/status", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StreamSystemStatus calls GET /api/v1/system/status/stream: stream system usage (SSE)
+// The caller must close the response body.
+func (c *Client) StreamSystemStatus(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, "GET", "/api/v1/system/status/stream", nil)
+}
+
+// GetStorage calls GET /api/v1/system/storage: get storage usage
+func (c *Client) GetStorage(ctx context.Context) (*StorageStats, error) {
+	var out StorageStats
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/storage", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStorageBreakdown calls GET /api/v1/system/storage/breakdown: get disk usage by category (media, downloads, app data, backups, Nix store) from the last storage scan
+func (c *Client) GetStorageBreakdown(ctx context.Context) (*StorageBreakdown, error) {
+	var out StorageBreakdown
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/storage/breakdown", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RefreshStorageBreakdown calls POST /api/v1/system/storage/breakdown/refresh: start a new storage scan
+func (c *Client) RefreshStorageBreakdown(ctx context.Context) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/storage/breakdown/refresh", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUpdates calls GET /api/v1/system/updates: get the automatic update policy and the outcome of the last update
+func (c *Client) GetUpdates(ctx context.Context) (*UpdatesResponse, error) {
+	var out UpdatesResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/updates", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetUpdates calls PUT /api/v1/system/updates: replace the automatic update policy: mode (off, security-only or all), channel (stable or beta) and maintenance window
+func (c *Client) SetUpdates(ctx context.Context, body UpdatePolicy) (*UpdatesResponse, error) {
+	var out UpdatesResponse
+	if err := c.doJSON(ctx, "PUT", "/api/v1/system/updates", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RollbackUpdate calls POST /api/v1/system/updates/rollback: switch back to the system the last applied update replaced
+func (c *Client) RollbackUpdate(ctx context.Context) (*UpdatesResponse, error) {
+	var out UpdatesResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/updates/rollback", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadUpdate calls POST /api/v1/system/updates/upload: update from a signed offline bundle
+func (c *Client) UploadUpdate(ctx context.Context, body Upload) (*UpdatesResponse, error) {
+	var out UpdatesResponse
+	if err := c.doJSON(ctx, "POST", "/api/v1/system/updates/upload", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListGenerations calls GET /api/v1/system/versions: list NixOS generations
+func (c *Client) ListGenerations(ctx context.Context) (*GenerationsResponse, error) {
+	var out GenerationsResponse
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/versions", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetWatchdog calls GET /api/v1/system/watchdog: get the app watchdog settings
+func (c *Client) GetWatchdog(ctx context.Context) (*WatchdogConfig, error) {
+	var out WatchdogConfig
+	if err := c.doJSON(ctx, "GET", "/api/v1/system/watchdog", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetWatchdog calls PUT /api/v1/system/watchdog: replace the app watchdog settings: health check interval, failure threshold, stuck-state timeouts, grace periods and excluded apps
+func (c *Client) SetWatchdog(ctx context.Context, body WatchdogConfig) (*WatchdogConfig, error) {
+	var out WatchdogConfig
+	if err := c.doJSON(ctx, "PUT", "/api/v1/system/watchdog", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLayout calls GET /api/v1/user/layout: get the home screen layout
+func (c *Client) GetLayout(ctx context.Context) ([]GridElement, error) {
+	var out []GridElement
+	if err := c.doJSON(ctx, "GET", "/api/v1/user/layout", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetLayout calls PUT /api/v1/user/layout: save the home screen layout
+func (c *Client) SetLayout(ctx context.Context, body []GridElement) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.doJSON(ctx, "PUT", "/api/v1/user/layout", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPreferences calls GET /api/v1/users/me/preferences: get the current user's preferences
+func (c *Client) GetPreferences(ctx context.Context) (*Preferences, error) {
+	var out Preferences
+	if err := c.doJSON(ctx, "GET", "/api/v1/users/me/preferences", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePreferences calls PUT /api/v1/users/me/preferences: update the current user's preferences
+func (c *Client) UpdatePreferences(ctx context.Context, body UpdatePreferencesRequest) (*Preferences, error) {
+	var out Preferences
+	if err := c.doJSON(ctx, "PUT", "/api/v1/users/me/preferences", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeUserTokens calls DELETE /api/v1/users/{username}/tokens: revoke all of a user's personal access tokens
+func (c *Client) RevokeUserTokens(ctx context.Context, username string) (*RevokeTokensResponse, error) {
+	var out RevokeTokensResponse
+	if err := c.doJSON(ctx, "DELETE", "/api/v1/users/"+url.PathEscape(username)+"/tokens", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMetrics calls GET /metrics: prometheus metrics
+// The caller must close the response body.
+func (c *Client) GetMetrics(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, "GET", "/metrics", nil)
+}
+
+// GetAppMetrics calls GET /metrics/apps: prometheus metrics for installed apps and the host
+// The caller must close the response body.
+func (c *Client) GetAppMetrics(ctx context.Context) (*http.Response, error) {
+	return c.doRaw(ctx, "GET", "/metrics/apps", nil)
+}
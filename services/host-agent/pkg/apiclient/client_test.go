@@ -0,0 +1,71 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallApp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/api/v1/apps/miniflux/install", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "bloud_session=abc", r.Header.Get("Cookie"))
+
+		var body InstallAppRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "postgres", body.Choices["database"])
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(InstallResult{App: "miniflux", Success: true, AppsInstalled: []string{"postgres", "miniflux"}})
+	}))
+	defer server.Close()
+
+	client := New(server.URL + "/")
+	client.Header.Set("Cookie", "bloud_session=abc")
+
+	result, err := client.InstallApp(context.Background(), "miniflux", InstallAppRequest{
+		Choices: map[string]string{"database": "postgres"},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, []string{"postgres", "miniflux"}, result.AppsInstalled)
+}
+
+func TestClient_ErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"app not found","requestId":"abc123"}`))
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL).GetAppMetadata(context.Background(), "missing")
+	require.Error(t, err)
+
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "app not found", apiErr.Message)
+	assert.Equal(t, "abc123", apiErr.RequestID)
+	assert.Contains(t, err.Error(), "[request abc123]")
+}
+
+func TestClient_PathEscaping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/apps/a%2Fb/plan-remove", r.URL.RawPath)
+		json.NewEncoder(w).Encode(RemovePlan{App: "a/b", CanRemove: true})
+	}))
+	defer server.Close()
+
+	plan, err := New(server.URL).PlanRemove(context.Background(), "a/b")
+	require.NoError(t, err)
+	assert.Equal(t, "a/b", plan.App)
+}
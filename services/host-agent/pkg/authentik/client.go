@@ -334,6 +334,10 @@ const (
 	ldapOutpostName      = "Bloud LDAP Outpost"
 	ldapServiceUsername  = "ldap-service"
 	ldapServiceTokenID   = "ldap-service-bind-token"
+
+	// ldapStagedTokenID holds the next bind key during rotation so that both
+	// the old and new keys authenticate until every app has switched over.
+	ldapStagedTokenID = "ldap-service-bind-token-next"
 )
 
 // EnsureLDAPInfrastructure creates the LDAP provider, application, outpost, and service account
@@ -659,6 +663,122 @@ func (c *Client) GetLDAPServiceTokenKey() (string, error) {
 	return result.Key, nil
 }
 
+// StageLDAPServiceToken creates a second app password for the LDAP service account
+// with the given key. While staged, both the current and the new key are accepted
+// for LDAP binds, so apps can be moved to the new key one at a time.
+// Any previously staged token is replaced.
+func (c *Client) StageLDAPServiceToken(key string) error {
+	userID, err := c.findUserID(ldapServiceUsername)
+	if err != nil {
+		return err
+	}
+	if userID == 0 {
+		return fmt.Errorf("LDAP service account %s not found", ldapServiceUsername)
+	}
+
+	if err := c.deleteToken(ldapStagedTokenID); err != nil {
+		return fmt.Errorf("removing stale staged token: %w", err)
+	}
+
+	payload := map[string]interface{}{
+		"identifier": ldapStagedTokenID,
+		"user":       userID,
+		"intent":     "app_password",
+		"expiring":   false,
+		"key":        key,
+	}
+	payloadBytes, _ := json.Marshal(payload)
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/api/v3/core/tokens/", bytes.NewReader(payloadBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("creating staged service token: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// PromoteLDAPServiceToken sets the canonical LDAP service token to the given key
+// and removes the staged token. After this only the new key is accepted.
+func (c *Client) PromoteLDAPServiceToken(key string) error {
+	if err := c.setTokenKey(ldapServiceTokenID, key); err != nil {
+		return err
+	}
+	if err := c.deleteToken(ldapStagedTokenID); err != nil {
+		return fmt.Errorf("removing staged token: %w", err)
+	}
+	return nil
+}
+
+// DiscardStagedLDAPServiceToken removes the staged token, leaving the current key
+// as the only valid bind credential. Used to abort a rotation.
+func (c *Client) DiscardStagedLDAPServiceToken() error {
+	return c.deleteToken(ldapStagedTokenID)
+}
+
+// setTokenKey replaces the key of an existing token in place
+func (c *Client) setTokenKey(identifier, key string) error {
+	payloadBytes, _ := json.Marshal(map[string]string{"key": key})
+
+	reqURL := fmt.Sprintf("%s/api/v3/core/tokens/%s/set_key/", c.baseURL, url.PathEscape(identifier))
+	req, err := http.NewRequest(http.MethodPost, reqURL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("setting token key: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// deleteToken deletes a token by identifier. A missing token is not an error.
+func (c *Client) deleteToken(identifier string) error {
+	reqURL := fmt.Sprintf("%s/api/v3/core/tokens/%s/", c.baseURL, url.PathEscape(identifier))
+	req, err := http.NewRequest(http.MethodDelete, reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("deleting token %s: status %d: %s", identifier, resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
 // GetLDAPOutpostToken returns the auto-generated token for the LDAP outpost
 func (c *Client) GetLDAPOutpostToken() (string, error) {
 	// Find the LDAP outpost
@@ -1019,6 +1139,111 @@ func (c *Client) DeleteUser(username string) error {
 	return nil
 }
 
+// User represents an Authentik user account
+type User struct {
+	PK       int         `json:"pk"`
+	Username string      `json:"username"`
+	Name     string      `json:"name"`
+	Email    string      `json:"email"`
+	IsActive bool        `json:"is_active"`
+	Groups   []UserGroup `json:"groups_obj,omitempty"`
+}
+
+// UserGroup is a group a user belongs to
+type UserGroup struct {
+	Name string `json:"name"`
+}
+
+// InGroup reports whether the user belongs to the named group
+func (u *User) InGroup(name string) bool {
+	for _, g := range u.Groups {
+		if g.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FindUser returns the user with username, groups included, or nil if
+// there is none
+func (c *Client) FindUser(username string) (*User, error) {
+	reqURL := fmt.Sprintf("%s/api/v3/core/users/?username=%s", c.baseURL, url.QueryEscape(username))
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("finding user: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Results []User `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	for i := range result.Results {
+		if result.Results[i].Username == username {
+			return &result.Results[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ListUsers returns all internal (human) users, following pagination.
+// Service accounts such as the LDAP bind user are excluded.
+func (c *Client) ListUsers() ([]User, error) {
+	var users []User
+
+	for page := 1; page != 0; {
+		reqURL := fmt.Sprintf("%s/api/v3/core/users/?type=internal&page_size=100&page=%d", c.baseURL, page)
+		req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("listing users: status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var result struct {
+			Pagination struct {
+				Next int `json:"next"`
+			} `json:"pagination"`
+			Results []User `json:"results"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, result.Results...)
+		page = result.Pagination.Next
+	}
+
+	return users, nil
+}
+
 // OIDC constants for Bloud's own OAuth2 application
 const (
 	bloudAppSlug         = "bloud"
@@ -1277,6 +1502,123 @@ func (c *Client) AddRedirectURI(providerID int, redirectURI string) error {
 	return nil
 }
 
+// GetRedirectURIs returns the redirect URIs registered on an OAuth2 provider
+func (c *Client) GetRedirectURIs(providerID int) ([]string, error) {
+	reqURL := fmt.Sprintf("%s/api/v3/providers/oauth2/%d/", c.baseURL, providerID)
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("fetching provider: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var provider struct {
+		RedirectURIs []struct {
+			URL string `json:"url"`
+		} `json:"redirect_uris"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&provider); err != nil {
+		return nil, fmt.Errorf("decoding provider: %w", err)
+	}
+
+	uris := make([]string, 0, len(provider.RedirectURIs))
+	for _, uri := range provider.RedirectURIs {
+		uris = append(uris, uri.URL)
+	}
+	return uris, nil
+}
+
+// SetRedirectURIs replaces the redirect URIs on an OAuth2 provider.
+// All URIs use strict matching.
+func (c *Client) SetRedirectURIs(providerID int, redirectURIs []string) error {
+	return c.updateBloudOAuth2ProviderRedirectURIs(providerID, redirectURIs)
+}
+
+// GetOAuth2ProviderClientSecret returns the client secret of an OAuth2
+// provider by name, or "" if the provider doesn't exist
+func (c *Client) GetOAuth2ProviderClientSecret(providerName string) (string, error) {
+	providerID, err := c.findProviderID("oauth2", providerName)
+	if err != nil {
+		return "", err
+	}
+	if providerID == 0 {
+		return "", nil
+	}
+
+	reqURL := fmt.Sprintf("%s/api/v3/providers/oauth2/%d/", c.baseURL, providerID)
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("fetching provider: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var provider struct {
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&provider); err != nil {
+		return "", fmt.Errorf("decoding provider: %w", err)
+	}
+	return provider.ClientSecret, nil
+}
+
+// SetOAuth2ProviderClientSecret replaces the client secret of an OAuth2 provider by name
+func (c *Client) SetOAuth2ProviderClientSecret(providerName, clientSecret string) error {
+	providerID, err := c.findProviderID("oauth2", providerName)
+	if err != nil {
+		return err
+	}
+	if providerID == 0 {
+		return fmt.Errorf("OAuth2 provider %q not found", providerName)
+	}
+
+	payloadBytes, _ := json.Marshal(map[string]string{"client_secret": clientSecret})
+
+	reqURL := fmt.Sprintf("%s/api/v3/providers/oauth2/%d/", c.baseURL, providerID)
+	req, err := http.NewRequest(http.MethodPatch, reqURL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("updating client secret: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
 // updateBloudOAuth2ProviderRedirectURIs patches the redirect URIs on an existing provider
 func (c *Client) updateBloudOAuth2ProviderRedirectURIs(providerID int, redirectURIs []string) error {
 	var uriEntries []map[string]string
@@ -245,3 +245,295 @@ func TestIsAvailable(t *testing.T) {
 		})
 	}
 }
+
+func TestStageLDAPServiceToken(t *testing.T) {
+	var created map[string]interface{}
+	var deletedStale bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/core/users/":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"results": []map[string]interface{}{{"pk": 42, "username": "ldap-service"}},
+			})
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v3/core/tokens/ldap-service-bind-token-next/":
+			deletedStale = true
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/core/tokens/":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	if err := client.StageLDAPServiceToken("new-key"); err != nil {
+		t.Fatalf("StageLDAPServiceToken() error = %v", err)
+	}
+
+	if !deletedStale {
+		t.Error("StageLDAPServiceToken() should remove any stale staged token")
+	}
+	if created["identifier"] != "ldap-service-bind-token-next" {
+		t.Errorf("identifier = %v, want ldap-service-bind-token-next", created["identifier"])
+	}
+	if created["key"] != "new-key" {
+		t.Errorf("key = %v, want new-key", created["key"])
+	}
+	if created["user"] != float64(42) {
+		t.Errorf("user = %v, want 42", created["user"])
+	}
+}
+
+func TestStageLDAPServiceToken_NoServiceAccount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []interface{}{}})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	if err := client.StageLDAPServiceToken("new-key"); err == nil {
+		t.Error("StageLDAPServiceToken() expected error when service account is missing")
+	}
+}
+
+func TestPromoteLDAPServiceToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		setKeyStatus int
+		wantErr      bool
+		wantDeleted  bool
+	}{
+		{
+			name:         "successful promotion",
+			setKeyStatus: http.StatusNoContent,
+			wantErr:      false,
+			wantDeleted:  true,
+		},
+		{
+			name:         "set_key fails",
+			setKeyStatus: http.StatusForbidden,
+			wantErr:      true,
+			wantDeleted:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotKey string
+			var deleted bool
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/api/v3/core/tokens/ldap-service-bind-token/set_key/":
+					var body map[string]string
+					json.NewDecoder(r.Body).Decode(&body)
+					gotKey = body["key"]
+					w.WriteHeader(tt.setKeyStatus)
+				case r.Method == http.MethodDelete && r.URL.Path == "/api/v3/core/tokens/ldap-service-bind-token-next/":
+					deleted = true
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "test-token")
+			err := client.PromoteLDAPServiceToken("new-key")
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PromoteLDAPServiceToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotKey != "new-key" {
+				t.Errorf("set_key key = %q, want new-key", gotKey)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("staged token deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("type") != "internal" {
+			t.Errorf("expected type=internal filter, got %q", r.URL.RawQuery)
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "1":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"pagination": map[string]int{"next": 2},
+				"results": []map[string]interface{}{
+					{"pk": 1, "username": "alice", "email": "alice@example.com", "is_active": true},
+				},
+			})
+		case "2":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"pagination": map[string]int{"next": 0},
+				"results": []map[string]interface{}{
+					{"pk": 2, "username": "bob", "is_active": false},
+				},
+			})
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	users, err := client.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("ListUsers() returned %d users, want 2", len(users))
+	}
+	if users[0].Username != "alice" || !users[0].IsActive || users[0].Email != "alice@example.com" {
+		t.Errorf("unexpected first user: %+v", users[0])
+	}
+	if users[1].Username != "bob" || users[1].IsActive {
+		t.Errorf("unexpected second user: %+v", users[1])
+	}
+}
+
+func TestFindUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/core/users/" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		results := []map[string]interface{}{}
+		if r.URL.Query().Get("username") == "alice" {
+			results = append(results, map[string]interface{}{
+				"pk": 1, "username": "alice", "is_active": true,
+				"groups_obj": []map[string]interface{}{{"pk": "g1", "name": "authentik Admins"}},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	user, err := client.FindUser("alice")
+	if err != nil {
+		t.Fatalf("FindUser() error = %v", err)
+	}
+	if user == nil || !user.IsActive || !user.InGroup("authentik Admins") {
+		t.Errorf("unexpected user: %+v", user)
+	}
+
+	user, err = client.FindUser("bob")
+	if err != nil {
+		t.Fatalf("FindUser() error = %v", err)
+	}
+	if user != nil {
+		t.Errorf("FindUser() for a missing user = %+v, want nil", user)
+	}
+}
+
+func TestGetRedirectURIs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/providers/oauth2/7/" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"redirect_uris": []map[string]string{
+				{"matching_mode": "strict", "url": "http://bloud.local/auth/callback"},
+				{"matching_mode": "strict", "url": "http://192.168.1.20/auth/callback"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	uris, err := client.GetRedirectURIs(7)
+	if err != nil {
+		t.Fatalf("GetRedirectURIs() error = %v", err)
+	}
+
+	want := []string{"http://bloud.local/auth/callback", "http://192.168.1.20/auth/callback"}
+	if len(uris) != len(want) {
+		t.Fatalf("GetRedirectURIs() = %v, want %v", uris, want)
+	}
+	for i := range want {
+		if uris[i] != want[i] {
+			t.Errorf("uris[%d] = %q, want %q", i, uris[i], want[i])
+		}
+	}
+}
+
+func TestSetRedirectURIs(t *testing.T) {
+	var payload struct {
+		RedirectURIs []map[string]string `json:"redirect_uris"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH, got %s", r.Method)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	if err := client.SetRedirectURIs(7, []string{"http://bloud.local/auth/callback"}); err != nil {
+		t.Fatalf("SetRedirectURIs() error = %v", err)
+	}
+
+	if len(payload.RedirectURIs) != 1 || payload.RedirectURIs[0]["url"] != "http://bloud.local/auth/callback" {
+		t.Errorf("unexpected payload: %+v", payload.RedirectURIs)
+	}
+	if payload.RedirectURIs[0]["matching_mode"] != "strict" {
+		t.Errorf("matching_mode = %q, want strict", payload.RedirectURIs[0]["matching_mode"])
+	}
+}
+
+func TestOAuth2ProviderClientSecret(t *testing.T) {
+	secret := "old-secret"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v3/providers/oauth2/" && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"results": []map[string]interface{}{{"pk": 7, "name": "Miniflux OAuth2 Provider"}},
+			})
+		case r.URL.Path == "/api/v3/providers/oauth2/7/" && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]string{"client_secret": secret})
+		case r.URL.Path == "/api/v3/providers/oauth2/7/" && r.Method == http.MethodPatch:
+			var payload map[string]string
+			json.NewDecoder(r.Body).Decode(&payload)
+			secret = payload["client_secret"]
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	got, err := client.GetOAuth2ProviderClientSecret("Miniflux OAuth2 Provider")
+	if err != nil || got != "old-secret" {
+		t.Fatalf("GetOAuth2ProviderClientSecret() = %q, %v; want old-secret", got, err)
+	}
+
+	if err := client.SetOAuth2ProviderClientSecret("Miniflux OAuth2 Provider", "new-secret"); err != nil {
+		t.Fatalf("SetOAuth2ProviderClientSecret() error = %v", err)
+	}
+	if secret != "new-secret" {
+		t.Errorf("client secret = %q, want new-secret", secret)
+	}
+
+	if got, err := client.GetOAuth2ProviderClientSecret("Missing OAuth2 Provider"); err != nil || got != "" {
+		t.Errorf("GetOAuth2ProviderClientSecret(missing) = %q, %v; want empty", got, err)
+	}
+	if err := client.SetOAuth2ProviderClientSecret("Missing OAuth2 Provider", "x"); err == nil {
+		t.Error("expected an error for a missing provider")
+	}
+}
@@ -5,6 +5,10 @@ package configurator
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 // Configurator handles app-specific configuration.
@@ -32,6 +36,25 @@ type Configurator interface {
 	PostStart(ctx context.Context, state *AppState) error
 }
 
+// LDAPBindUpdater is implemented by configurators whose app binds to the
+// Authentik LDAP outpost with the shared service account. It is called during
+// bind token rotation; the app is restarted afterwards to pick up the change.
+type LDAPBindUpdater interface {
+	// UpdateLDAPBindPassword writes the new bind password into the app's config.
+	// Returns nil if the app has no LDAP configuration yet.
+	UpdateLDAPBindPassword(ctx context.Context, state *AppState, password string) error
+}
+
+// SettingsConfigurator is implemented by configurators that apply the
+// user-editable settings declared in the app's metadata.yaml. It is called
+// when settings change through the API; the same values are available in
+// AppState.Settings on every PreStart and PostStart.
+type SettingsConfigurator interface {
+	// Configure applies settings to the running app. Every declared setting
+	// is present, with defaults filled in for ones the user hasn't changed.
+	Configure(ctx context.Context, state *AppState, settings map[string]any) error
+}
+
 // AppState contains everything a configurator needs to configure an app.
 type AppState struct {
 	// Name is the app name (e.g., "qbittorrent", "radarr")
@@ -53,4 +76,28 @@ type AppState struct {
 
 	// Options contains app-specific configuration options
 	Options map[string]any
+
+	// Settings contains the user-editable settings declared in metadata.yaml,
+	// with defaults filled in
+	Settings map[string]any
+
+	// CredentialsDir is $CREDENTIALS_DIRECTORY when running as a hook of the
+	// app's unit, empty otherwise
+	CredentialsDir string
+}
+
+// Credential returns a secret the app's unit loads as a systemd credential
+// (e.g. "oauth-client-secret"). Outside the unit it is read from the file
+// host-agent writes under BloudDataPath/credentials/<app>/.
+func (s *AppState) Credential(id string) (string, error) {
+	path := filepath.Join(s.BloudDataPath, "credentials", s.Name, id)
+	if s.CredentialsDir != "" {
+		path = filepath.Join(s.CredentialsDir, id)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading credential %s: %w", id, err)
+	}
+	return strings.TrimSpace(string(data)), nil
 }
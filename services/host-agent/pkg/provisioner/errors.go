@@ -0,0 +1,7 @@
+package provisioner
+
+import "errors"
+
+// ErrNotReady is returned by a provisioner when its app is installed but
+// can't be provisioned yet. The sync loop skips the app without logging an error.
+var ErrNotReady = errors.New("provisioner not ready")
@@ -0,0 +1,40 @@
+// Package provisioner provides the interface and registry for per-app user provisioning.
+// Provisioners mirror Authentik users into apps that keep their own user tables,
+// so accounts exist (and are disabled) in the app without waiting for a first login.
+package provisioner
+
+import (
+	"context"
+)
+
+// User is the app-independent view of a user account.
+type User struct {
+	// Username is the login name, shared between Authentik and the app
+	Username string
+
+	// Email is the user's email address (may be empty)
+	Email string
+
+	// Name is the display name (may be empty)
+	Name string
+
+	// Active is false when the account is disabled
+	Active bool
+}
+
+// Provisioner manages user accounts inside a single app.
+// All methods must be idempotent - the sync loop calls them repeatedly.
+type Provisioner interface {
+	// Name returns the app name this provisioner handles
+	Name() string
+
+	// ListUsers returns the users currently known to the app.
+	// Returns ErrNotReady if the app can't be managed yet (e.g., no API credentials).
+	ListUsers(ctx context.Context) ([]User, error)
+
+	// CreateUser creates an account for the user in the app
+	CreateUser(ctx context.Context, user User) error
+
+	// SetUserDisabled disables or re-enables an existing account
+	SetUserDisabled(ctx context.Context, username string, disabled bool) error
+}
@@ -0,0 +1,23 @@
+package provisioner
+
+// RegistryInterface defines the interface for the provisioner registry.
+// This interface enables mocking for testing.
+type RegistryInterface interface {
+	// Get returns the provisioner for an app, or nil if none exists.
+	Get(appName string) Provisioner
+
+	// Has returns true if a provisioner exists for the given app.
+	Has(appName string) bool
+
+	// All returns all registered provisioners.
+	All() []Provisioner
+
+	// Names returns the names of all registered provisioners.
+	Names() []string
+
+	// Register adds a provisioner to the registry.
+	Register(p Provisioner)
+}
+
+// Compile-time assertion
+var _ RegistryInterface = (*Registry)(nil)
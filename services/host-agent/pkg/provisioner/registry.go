@@ -0,0 +1,74 @@
+package provisioner
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Registry manages user provisioners for all apps.
+// Provisioners register themselves and can be looked up by app name.
+type Registry struct {
+	provisioners map[string]Provisioner
+	mu           sync.RWMutex
+	logger       *slog.Logger
+}
+
+// NewRegistry creates a new provisioner registry.
+func NewRegistry(logger *slog.Logger) *Registry {
+	return &Registry{
+		provisioners: make(map[string]Provisioner),
+		logger:       logger,
+	}
+}
+
+// Register adds a provisioner to the registry.
+// If a provisioner for the same app already exists, it will be replaced.
+func (r *Registry) Register(p Provisioner) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := p.Name()
+	r.provisioners[name] = p
+	r.logger.Debug("registered provisioner", "app", name)
+}
+
+// Get returns the provisioner for an app, or nil if none exists.
+func (r *Registry) Get(appName string) Provisioner {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.provisioners[appName]
+}
+
+// Has returns true if a provisioner exists for the given app.
+func (r *Registry) Has(appName string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.provisioners[appName]
+	return ok
+}
+
+// All returns all registered provisioners.
+func (r *Registry) All() []Provisioner {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]Provisioner, 0, len(r.provisioners))
+	for _, p := range r.provisioners {
+		result = append(result, p)
+	}
+	return result
+}
+
+// Names returns the names of all registered provisioners.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]string, 0, len(r.provisioners))
+	for name := range r.provisioners {
+		result = append(result, name)
+	}
+	return result
+}
@@ -12,6 +12,8 @@ export interface SSECallbacks {
 
 let eventSource: EventSource | null = null;
 let reconnectTimeout: ReturnType<typeof setTimeout> | null = null;
+// ID of the last event received, so a reconnect replays what was missed
+let lastEventId: string | null = null;
 
 /**
  * Connect to the SSE endpoint for real-time app state updates
@@ -20,9 +22,13 @@ export function connectSSE(callbacks: SSECallbacks): void {
 	// Clean up any existing connection
 	disconnectSSE();
 
-	eventSource = new EventSource('/api/apps/events');
+	const url = lastEventId
+		? `/api/apps/events?lastEventId=${encodeURIComponent(lastEventId)}`
+		: '/api/apps/events';
+	eventSource = new EventSource(url);
 
 	eventSource.onmessage = (e) => {
+		if (e.lastEventId) lastEventId = e.lastEventId;
 		try {
 			const apps: App[] = JSON.parse(e.data);
 			callbacks.onApps(apps);
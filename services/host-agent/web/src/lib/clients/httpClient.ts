@@ -13,6 +13,19 @@ export interface RequestOptions extends Omit<RequestInit, 'body'> {
 	body?: Record<string, any>;
 }
 
+const CSRF_COOKIE = 'bloud_csrf';
+const SAFE_METHODS = ['GET', 'HEAD', 'OPTIONS'];
+
+/**
+ * Headers carrying the CSRF token issued at login, required by the host agent
+ * on state-changing requests made with the session cookie
+ */
+export function csrfHeaders(): Record<string, string> {
+	if (typeof document === 'undefined') return {};
+	const match = document.cookie.split('; ').find((c) => c.startsWith(`${CSRF_COOKIE}=`));
+	return match ? { 'X-CSRF-Token': decodeURIComponent(match.slice(CSRF_COOKIE.length + 1)) } : {};
+}
+
 /**
  * Make an HTTP request with standard error handling
  *
@@ -25,6 +38,7 @@ export async function request<T>(url: string, options: RequestOptions = {}): Pro
 		...rest,
 		headers: {
 			...(body !== undefined && { 'Content-Type': 'application/json' }),
+			...(!SAFE_METHODS.includes((rest.method ?? 'GET').toUpperCase()) && csrfHeaders()),
 			...headers
 		},
 		body: body !== undefined ? JSON.stringify(body) : undefined
@@ -0,0 +1,47 @@
+/**
+ * Preferences Client - HTTP transport layer for per-user preferences
+ *
+ * Preferences are stored by the host agent against the signed-in user, so
+ * they follow the user across browsers and devices.
+ */
+
+import { get, put, isUnauthorized } from './httpClient';
+import type { GridElement } from '$lib/stores/layout';
+
+export type Theme = 'system' | 'light' | 'dark';
+
+export interface Preferences {
+	layout: GridElement[];
+	pinned_apps: string[];
+	theme: Theme;
+	locale?: string;
+}
+
+/**
+ * Fetch the current user's preferences
+ * Returns null if unauthorized (401) or on error
+ */
+export async function fetchPreferences(): Promise<Preferences | null> {
+	try {
+		return await get<Preferences>('/api/users/me/preferences');
+	} catch (err) {
+		if (isUnauthorized(err)) return null;
+		console.error('Failed to fetch preferences from API:', err);
+		return null;
+	}
+}
+
+/**
+ * Update some of the current user's preferences; omitted fields are unchanged
+ * Returns the saved preferences, or null if unauthorized or on error
+ */
+export async function savePreferences(update: Partial<Preferences>): Promise<Preferences | null> {
+	try {
+		return await put<Preferences>('/api/users/me/preferences', update);
+	} catch (err) {
+		if (!isUnauthorized(err)) {
+			console.error('Failed to save preferences to API:', err);
+		}
+		return null;
+	}
+}
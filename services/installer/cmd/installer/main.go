@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/answers"
 	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/api"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/console"
 	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/installer"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/setuptoken"
 )
 
 func main() {
@@ -18,8 +22,35 @@ func main() {
 		port = "3001"
 	}
 
+	// `bloud-installer console` is the terminal frontend on tty1; it drives
+	// the running installer service over its API like the web UI does
+	if len(os.Args) > 1 && os.Args[1] == "console" {
+		token := func() string {
+			if os.Getenv("INSTALLER_MOCK") == "1" {
+				return mockToken()
+			}
+			token, _ := setuptoken.Read()
+			return token
+		}
+		if err := console.Run(context.Background(), "http://localhost:"+port, token, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "console: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	token, err := setupToken(logger)
+	if err != nil {
+		logger.Error("setup code not created", "error", err)
+		os.Exit(1)
+	}
+
 	inst := installer.New()
-	server := api.NewServer(inst)
+	server := api.NewServer(inst, token)
+
+	if os.Getenv("INSTALLER_MOCK") != "1" {
+		startUnattended(inst, logger)
+	}
 
 	addr := fmt.Sprintf("0.0.0.0:%s", port)
 	logger.Info("installer starting", "addr", addr)
@@ -29,3 +60,47 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// setupToken makes this boot's setup code. In mock mode it comes from
+// INSTALLER_SETUP_TOKEN, or is logged, since /run isn't writable.
+func setupToken(logger *slog.Logger) (string, error) {
+	if os.Getenv("INSTALLER_MOCK") == "1" {
+		token := mockToken()
+		logger.Info("mock setup code", "token", token)
+		return token, nil
+	}
+	token, err := setuptoken.Generate()
+	if err != nil {
+		return "", err
+	}
+	return token, setuptoken.Write(token)
+}
+
+func mockToken() string {
+	if token := os.Getenv("INSTALLER_SETUP_TOKEN"); token != "" {
+		return token
+	}
+	return "MOCK-CODE"
+}
+
+// startUnattended runs the install from a bloud-install.yaml when there is
+// one. A bad answer file fails the install visibly rather than falling back
+// to the web UI, since nobody may be watching for it.
+func startUnattended(inst *installer.Installer, logger *slog.Logger) {
+	ctx := context.Background()
+	data, source, err := answers.Find(ctx)
+	if err == nil && source == "" {
+		return
+	}
+	if err == nil {
+		logger.Info("answer file found", "source", source)
+		var a answers.Answers
+		if a, err = answers.Parse(data); err == nil {
+			err = answers.Start(ctx, inst, a, logger)
+		}
+	}
+	if err != nil {
+		logger.Error("unattended install not started", "source", source, "error", err)
+		inst.Emit(installer.PhaseFailed, fmt.Sprintf("answer file %s: %v", source, err))
+	}
+}
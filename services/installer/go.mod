@@ -2,4 +2,7 @@ module codeberg.org/d-buckner/bloud-v3/services/installer
 
 go 1.24.0
 
-require github.com/go-chi/chi/v5 v5.2.1
+require (
+	github.com/go-chi/chi/v5 v5.2.1
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,189 @@
+package answers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/disks"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/installer"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/partition"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/restore"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FileName is the answer file looked for on the boot medium and on a
+	// filesystem labelled Label
+	FileName = "bloud-install.yaml"
+	Label    = "BLOUD-INSTALL"
+
+	// cmdlineKey on the kernel command line points at the answer file: an
+	// http(s) URL or an absolute path
+	cmdlineKey = "bloud.install="
+
+	// isoMount is where NixOS mounts the boot medium
+	isoMount = "/iso"
+
+	// labelMount is where a Label filesystem is mounted read-only
+	labelMount = "/run/bloud-install"
+)
+
+// Answers is an install request written ahead of time, in YAML with the
+// same keys as POST /api/install, plus what to do once it finishes
+type Answers struct {
+	installer.InstallRequest
+	// Reboot into the installed system when done; defaults to true
+	Reboot *bool `json:"reboot"`
+}
+
+// Find looks for an answer file: the kernel command line first, then the
+// boot medium, then a filesystem labelled BLOUD-INSTALL (e.g. a second USB
+// stick). The source is "" when there is none.
+func Find(ctx context.Context) (data []byte, source string, err error) {
+	if source = cmdlineSource(); source != "" {
+		data, err = read(ctx, source)
+		return data, source, err
+	}
+
+	source = filepath.Join(isoMount, FileName)
+	if data, err = os.ReadFile(source); err == nil {
+		return data, source, nil
+	}
+
+	device := "/dev/disk/by-label/" + Label
+	if _, err := os.Stat(device); err != nil {
+		return nil, "", nil
+	}
+	if err := os.MkdirAll(labelMount, 0755); err != nil {
+		return nil, "", err
+	}
+	if out, err := exec.CommandContext(ctx, "mount", "-o", "ro", device, labelMount).CombinedOutput(); err != nil {
+		return nil, "", fmt.Errorf("mounting %s: %w\n%s", device, err, string(out))
+	}
+	source = filepath.Join(labelMount, FileName)
+	data, err = os.ReadFile(source)
+	return data, source, err
+}
+
+func cmdlineSource() string {
+	cmdline, err := os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return ""
+	}
+	for _, field := range strings.Fields(string(cmdline)) {
+		if value, ok := strings.CutPrefix(field, cmdlineKey); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func read(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", source, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Parse reads an answer file strictly, so a misspelt key fails the install
+// instead of being ignored
+func Parse(data []byte) (Answers, error) {
+	var a Answers
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return a, err
+	}
+	// Go through JSON so the keys and validation are exactly the API's
+	asJSON, err := json.Marshal(raw)
+	if err != nil {
+		return a, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(asJSON))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&a); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// Start validates the answers against this machine and starts the install.
+// Disk "auto" (or none) takes the disk the web UI would suggest, unless
+// that choice is ambiguous. Unless Reboot is false, the machine reboots
+// into the installed system when the install completes.
+func Start(ctx context.Context, inst *installer.Installer, a Answers, logger *slog.Logger) error {
+	all, err := disks.Enumerate()
+	if err != nil {
+		return err
+	}
+	if a.Disk == "" || a.Disk == "auto" {
+		if disks.AreAmbiguous(all) {
+			return fmt.Errorf("disk: auto can't choose between disks of similar size; name one")
+		}
+		selected := disks.AutoSelect(all, disks.BootDevice())
+		if selected == nil {
+			return fmt.Errorf("disk: auto found no disk to install to")
+		}
+		a.Disk = selected.Device
+	}
+	if err := installer.Validate(&a.InstallRequest, all); err != nil {
+		return err
+	}
+	if a.Encryption {
+		if err := partition.CheckTPM(a.LUKS); err != nil {
+			return err
+		}
+	}
+	if a.Restore != "" {
+		if err := restore.Check(ctx, a.Restore); err != nil {
+			return fmt.Errorf("backup not readable: %w", err)
+		}
+	}
+
+	events, unsub := inst.Subscribe()
+	if err := inst.Start(ctx, a.InstallRequest); err != nil {
+		unsub()
+		return err
+	}
+	logger.Info("unattended install started", "disk", a.Disk)
+
+	go func() {
+		defer unsub()
+		for event := range events {
+			switch event.Phase {
+			case installer.PhaseFailed:
+				logger.Error("unattended install failed", "error", event.Message)
+				return
+			case installer.PhaseComplete:
+				logger.Info("unattended install complete")
+				if a.Reboot == nil || *a.Reboot {
+					if err := exec.Command("systemctl", "reboot").Run(); err != nil {
+						logger.Error("reboot failed", "error", err)
+					}
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
@@ -11,8 +12,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/compat"
 	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/disks"
 	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/installer"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/netconfig"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/partition"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/restore"
 	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/sse"
 )
 
@@ -23,6 +28,8 @@ type StatusResponse struct {
 	IPAddresses []string        `json:"ipAddresses"`
 	CPU         string          `json:"cpu"`
 	MemoryGB    int             `json:"memoryGB"`
+	// TPM is whether a key can be sealed to the machine's TPM 2.0
+	TPM bool `json:"tpm"`
 }
 
 // DiskInfo is the API representation of a disk, with frontend-friendly field names.
@@ -30,6 +37,8 @@ type DiskInfo struct {
 	Device          string  `json:"device"`
 	SizeGB          float64 `json:"sizeGB"`
 	Model           string  `json:"model"`
+	Removable       bool    `json:"removable"`
+	Transport       string  `json:"transport"`
 	HasExistingData bool    `json:"hasExistingData"`
 }
 
@@ -44,6 +53,8 @@ func toDiskInfo(d disks.Disk) DiskInfo {
 		Device:          d.Device,
 		SizeGB:          float64(d.SizeBytes) / 1e9,
 		Model:           d.Model,
+		Removable:       d.IsRemovable,
+		Transport:       d.Transport,
 		HasExistingData: diskHasPartitions(d.Device),
 	}
 }
@@ -63,10 +74,26 @@ func diskHasPartitions(device string) bool {
 	return false
 }
 
+// InstallRequestBody is POST /api/install. Everything but Disk is
+// optional; without the rest the root partition fills the disk, the system
+// uses DHCP and starts empty, and the first-boot wizard creates the admin.
 type InstallRequestBody struct {
-	Disk       string `json:"disk"`
-	Encryption bool   `json:"encryption"`
-	FlakePath  string `json:"flakePath"`
+	Disk       string           `json:"disk"`
+	Encryption bool             `json:"encryption"`
+	FlakePath  string           `json:"flakePath"`
+	Layout     partition.Layout `json:"layout"`
+	Network    netconfig.Config `json:"network"`
+	LUKS       partition.LUKS   `json:"luks"`
+	Restore    string           `json:"restore"`
+	Admin      *installer.Admin `json:"admin"`
+	Apps       []string         `json:"apps"`
+}
+
+// mockDisks stand in for lsblk in mock mode
+var mockDisks = []disks.Disk{
+	{Device: "/dev/sda", SizeBytes: 500_107_862_016, Model: "Samsung 870 EVO", Transport: "sata"},
+	{Device: "/dev/sdb", SizeBytes: 120_034_123_776, Model: "Kingston SSD", Transport: "sata"},
+	{Device: "/dev/sdc", SizeBytes: 15_376_000_000, Model: "SanDisk Ultra", IsRemovable: true, Transport: "usb"},
 }
 
 type InstallResponse struct {
@@ -86,6 +113,7 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 			IPAddresses: []string{"192.168.1.42"},
 			CPU:         "Intel Core i5-8250U (mock)",
 			MemoryGB:    16,
+			TPM:         true,
 		})
 		return
 	}
@@ -102,17 +130,19 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		IPAddresses: ips,
 		CPU:         cpu,
 		MemoryGB:    memGB,
+		TPM:         partition.HasTPM(),
 	})
 }
 
 func (s *Server) handleDisks(w http.ResponseWriter, r *http.Request) {
 	if s.mock {
-		mockDisks := []DiskInfo{
-			{Device: "/dev/sda", SizeGB: 465.8, Model: "Samsung 870 EVO", HasExistingData: true},
-			{Device: "/dev/sdb", SizeGB: 111.8, Model: "Kingston SSD", HasExistingData: false},
+		diskInfos := make([]DiskInfo, len(mockDisks))
+		for i, d := range mockDisks {
+			diskInfos[i] = toDiskInfo(d)
 		}
+		diskInfos[0].HasExistingData = true
 		respondJSON(w, http.StatusOK, DisksResponse{
-			Disks:        mockDisks,
+			Disks:        diskInfos,
 			AutoSelected: "/dev/sda",
 			Ambiguous:    false,
 		})
@@ -125,7 +155,7 @@ func (s *Server) handleDisks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bootDev := bootDevice()
+	bootDev := disks.BootDevice()
 	selected := disks.AutoSelect(all, bootDev)
 
 	autoSelectedPath := ""
@@ -145,6 +175,48 @@ func (s *Server) handleDisks(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// mockHardware is a small, GPU-less machine so the mock report has
+// something to warn about
+var mockHardware = compat.Hardware{
+	CPU:            "Intel Core i5-8250U (mock)",
+	Cores:          8,
+	Virtualization: true,
+	AVX2:           true,
+	MemoryMB:       16 * 1024,
+	Disks:          []compat.Disk{{Device: "/dev/sda", ReadMBps: 520}, {Device: "/dev/sdb", Rotational: true, ReadMBps: 160}},
+	NICs:           []compat.NIC{{Name: "enp1s0", Driver: "e1000e", SpeedMbps: 1000}},
+}
+
+// handleCompat reports how well Bloud's features and apps will run here,
+// for the user to see before committing to the install
+func (s *Server) handleCompat(w http.ResponseWriter, r *http.Request) {
+	s.compatOnce.Do(func() {
+		appsDir := "../../apps" // running from services/installer in development
+		if flakePath := os.Getenv("INSTALLER_FLAKE_PATH"); flakePath != "" {
+			appsDir = filepath.Join(flakePath, "apps")
+		}
+		apps, err := compat.LoadApps(appsDir)
+		if err != nil {
+			slog.Warn("compat: app catalog not loaded", "dir", appsDir, "error", err)
+		}
+
+		hw := mockHardware
+		if !s.mock {
+			all, _ := disks.Enumerate()
+			var candidates []disks.Disk
+			bootDev := disks.BootDevice()
+			for _, d := range all {
+				if d.Device != bootDev {
+					candidates = append(candidates, d)
+				}
+			}
+			hw = compat.Probe(candidates)
+		}
+		s.compat = compat.Evaluate(hw, apps)
+	})
+	respondJSON(w, http.StatusOK, s.compat)
+}
+
 func (s *Server) handleInstall(w http.ResponseWriter, r *http.Request) {
 	var body InstallRequestBody
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -161,25 +233,52 @@ func (s *Server) handleInstall(w http.ResponseWriter, r *http.Request) {
 		Disk:       body.Disk,
 		Encryption: body.Encryption,
 		FlakePath:  body.FlakePath,
+		Layout:     body.Layout,
+		Network:    body.Network,
+		LUKS:       body.LUKS,
+		Restore:    body.Restore,
+		Admin:      body.Admin,
+		Apps:       body.Apps,
 	}
 
-	if s.mock {
-		if err := s.installer.StartMock(req); err != nil {
-			respondError(w, http.StatusConflict, err.Error())
+	all := mockDisks
+	if !s.mock {
+		var err error
+		if all, err = disks.Enumerate(); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to enumerate disks")
 			return
 		}
-		respondJSON(w, http.StatusOK, InstallResponse{Started: true})
-		return
 	}
 
-	all, err := disks.Enumerate()
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "failed to enumerate disks")
+	if s.mock && (req.Network.Address != "" || req.Network.VLAN != 0) && req.Network.Interface == "" {
+		req.Network.Interface = "enp1s0"
+	}
+	if err := installer.Validate(&req, all); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if !diskExists(all, body.Disk) {
-		respondError(w, http.StatusBadRequest, "disk not found: "+body.Disk)
+	if !s.mock && req.Encryption {
+		if err := partition.CheckTPM(req.LUKS); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// Check the backup now, before the disk is wiped for it
+	if req.Restore != "" && !s.mock {
+		if err := restore.Check(r.Context(), req.Restore); err != nil {
+			respondError(w, http.StatusBadRequest, "backup not readable: "+err.Error())
+			return
+		}
+	}
+
+	if s.mock {
+		if err := s.installer.StartMock(req); err != nil {
+			respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, InstallResponse{Started: true})
 		return
 	}
 
@@ -219,6 +318,46 @@ func (s *Server) handleProgress(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleInstallStream is /api/progress with progress: it starts with the
+// current state, so a client that connects or reconnects mid-install can
+// draw the bar straight away, then sends each event with its step and
+// overall percentage until the install completes or fails.
+func (s *Server) handleInstallStream(w http.ResponseWriter, r *http.Request) {
+	sw, err := sse.NewWriter(w)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	ch, unsub := s.installer.Subscribe()
+	defer unsub()
+
+	current := s.installer.Current()
+	if err := sw.Send(current); err != nil {
+		return
+	}
+	if current.Phase == installer.PhaseComplete || current.Phase == installer.PhaseFailed {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := sw.Send(event); err != nil {
+				return
+			}
+			if event.Phase == installer.PhaseComplete || event.Phase == installer.PhaseFailed {
+				return
+			}
+		}
+	}
+}
+
 func (s *Server) handleReboot(w http.ResponseWriter, r *http.Request) {
 	if s.installer.Phase() != installer.PhaseComplete {
 		respondError(w, http.StatusConflict, "installation not complete")
@@ -238,15 +377,6 @@ func (s *Server) handleReboot(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]bool{"rebooting": true})
 }
 
-func diskExists(all []disks.Disk, device string) bool {
-	for _, d := range all {
-		if d.Device == device {
-			return true
-		}
-	}
-	return false
-}
-
 func localIPs() []string {
 	ifaces, err := net.Interfaces()
 	if err != nil {
@@ -317,39 +447,3 @@ func memoryGB() int {
 	}
 	return 0
 }
-
-func bootDevice() string {
-	f, err := os.Open("/proc/cmdline")
-	if err != nil {
-		return ""
-	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-	if !scanner.Scan() {
-		return ""
-	}
-	cmdline := scanner.Text()
-
-	for _, field := range strings.Fields(cmdline) {
-		if !strings.HasPrefix(field, "root=") {
-			continue
-		}
-		val := strings.TrimPrefix(field, "root=")
-		if strings.HasPrefix(val, "/dev/") {
-			return stripPartitionSuffix(val)
-		}
-	}
-	return ""
-}
-
-func stripPartitionSuffix(device string) string {
-	if strings.Contains(device, "nvme") || strings.Contains(device, "mmcblk") {
-		if idx := strings.LastIndex(device, "p"); idx > 0 {
-			return device[:idx]
-		}
-		return device
-	}
-	trimmed := strings.TrimRight(device, "0123456789")
-	return trimmed
-}
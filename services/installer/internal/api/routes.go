@@ -10,9 +10,11 @@ func (s *Server) setupRoutes() {
 	s.router.Get("/api/health", s.handleHealth)
 	s.router.Get("/api/status", s.handleStatus)
 	s.router.Get("/api/disks", s.handleDisks)
-	s.router.Post("/api/install", s.handleInstall)
+	s.router.Get("/api/compat", s.handleCompat)
+	s.router.With(s.requireToken).Post("/api/install", s.handleInstall)
 	s.router.Get("/api/progress", s.handleProgress)
-	s.router.Post("/api/reboot", s.handleReboot)
+	s.router.Get("/api/install/stream", s.handleInstallStream)
+	s.router.With(s.requireToken).Post("/api/reboot", s.handleReboot)
 
 	s.setupFrontend()
 }
@@ -2,10 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"os"
+	"sync"
+	"time"
 
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/compat"
 	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/installer"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/setuptoken"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -14,15 +19,30 @@ type Server struct {
 	installer *installer.Installer
 	router    *chi.Mux
 	mock      bool
+
+	// token must accompany install and reboot requests (setuptoken).
+	// Each client address gets one check a second, so guesses are slow
+	// without one client holding up another.
+	token        string
+	tokenMu      sync.Mutex
+	tokenRetryAt map[string]time.Time // client address → when it may next try
+
+	// compat is probed once; the hardware doesn't change and timing the
+	// disks takes seconds
+	compatOnce sync.Once
+	compat     compat.Report
 }
 
-func NewServer(inst *installer.Installer) *Server {
+func NewServer(inst *installer.Installer, token string) *Server {
 	s := &Server{
-		installer: inst,
-		router:    chi.NewRouter(),
-		mock:      os.Getenv("INSTALLER_MOCK") == "1",
+		installer:    inst,
+		token:        token,
+		tokenRetryAt: map[string]time.Time{},
+		router:       chi.NewRouter(),
+		mock:         os.Getenv("INSTALLER_MOCK") == "1",
 	}
-	s.router.Use(middleware.RealIP)
+	// No RealIP: nothing proxies the installer, and the setup code's rate
+	// limit must key on the connection's address, not a header a client sets
 	s.router.Use(middleware.Recoverer)
 	s.setupRoutes()
 	return s
@@ -32,6 +52,52 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
 
+// tokenRetryDelay is how long a client waits between setup code checks
+const tokenRetryDelay = time.Second
+
+// requireToken rejects requests without the setup code
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if !s.allowTokenCheck(client) {
+			w.Header().Set("Retry-After", "1")
+			respondError(w, http.StatusTooManyRequests, "too many setup code attempts; try again in a second")
+			return
+		}
+
+		if !setuptoken.Equal(r.Header.Get(setuptoken.Header), s.token) {
+			respondError(w, http.StatusUnauthorized, "wrong setup code; it's shown on the server's screen")
+			return
+		}
+		s.tokenMu.Lock()
+		delete(s.tokenRetryAt, client)
+		s.tokenMu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowTokenCheck reports whether client may have its setup code checked
+// now, and if so holds its next check off for tokenRetryDelay
+func (s *Server) allowTokenCheck(client string) bool {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+
+	now := time.Now()
+	if now.Before(s.tokenRetryAt[client]) {
+		return false
+	}
+	for addr, at := range s.tokenRetryAt {
+		if !now.Before(at) {
+			delete(s.tokenRetryAt, addr)
+		}
+	}
+	s.tokenRetryAt[client] = now.Add(tokenRetryDelay)
+	return true
+}
+
 func respondJSON(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,255 @@
+// Package compat reports, before anything is installed, which Bloud
+// features and apps will work well on the machine's hardware.
+package compat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Level is how well something will work
+type Level string
+
+const (
+	LevelGood        Level = "good"
+	LevelLimited     Level = "limited"
+	LevelUnsupported Level = "unsupported"
+)
+
+// Thresholds for the feature checks
+const (
+	minMemoryMB         = 2048 // Bloud's own services: Authentik, PostgreSQL, Traefik
+	recommendedMemoryMB = 4096
+	recommendedCores    = 2
+	mlMemoryMB          = 8192
+	slowDiskMBps        = 100 // below this apps and databases start slowly
+)
+
+// gpuDrivers are the drivers with VA-API video encoding that app
+// containers can use through /dev/dri
+var gpuDrivers = map[string]bool{"i915": true, "xe": true, "amdgpu": true, "radeon": true}
+
+// Check is the verdict on one feature or app
+type Check struct {
+	Name   string `json:"name"`
+	Level  Level  `json:"level"`
+	Detail string `json:"detail"`
+}
+
+// Report is GET /api/compat
+type Report struct {
+	Hardware Hardware `json:"hardware"`
+	// Level is the worst of the feature checks; apps don't lower it
+	Level    Level   `json:"level"`
+	Features []Check `json:"features"`
+	Apps     []Check `json:"apps"`
+}
+
+// App is the part of an app's metadata.yaml the report needs
+type App struct {
+	Name        string `yaml:"name"`
+	DisplayName string `yaml:"displayName"`
+	IsSystem    bool   `yaml:"isSystem"`
+	Resources   struct {
+		MinRAM int  `yaml:"minRam"` // MB
+		GPU    bool `yaml:"gpu"`    // uses a GPU for hardware acceleration
+	} `yaml:"resources"`
+}
+
+// LoadApps reads the catalog from appsDir (apps/ in the bloud flake),
+// skipping system apps
+func LoadApps(appsDir string) ([]App, error) {
+	files, err := filepath.Glob(filepath.Join(appsDir, "*", "metadata.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var apps []App
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var app App
+		if err := yaml.Unmarshal(data, &app); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if !app.IsSystem && app.Name != "" {
+			apps = append(apps, app)
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+	return apps, nil
+}
+
+// Evaluate judges hw for Bloud's features and each app
+func Evaluate(hw Hardware, apps []App) Report {
+	r := Report{Hardware: hw, Level: LevelGood}
+	r.Features = []Check{
+		checkBase(hw),
+		checkStorage(hw),
+		checkNetwork(hw),
+		checkTranscoding(hw),
+		checkML(hw),
+		checkVirtualization(hw),
+	}
+	for _, c := range r.Features {
+		r.Level = worse(r.Level, c.Level)
+	}
+	for _, app := range apps {
+		r.Apps = append(r.Apps, checkApp(hw, app))
+	}
+	return r
+}
+
+func checkBase(hw Hardware) Check {
+	c := Check{Name: "bloud", Level: LevelGood, Detail: fmt.Sprintf("%s of memory and %d cores", formatMB(hw.MemoryMB), hw.Cores)}
+	switch {
+	case hw.MemoryMB < minMemoryMB:
+		c.Level = LevelUnsupported
+		c.Detail = fmt.Sprintf("Bloud needs at least %s of memory; this machine has %s", formatMB(minMemoryMB), formatMB(hw.MemoryMB))
+	case hw.MemoryMB < recommendedMemoryMB:
+		c.Level = LevelLimited
+		c.Detail = fmt.Sprintf("%s of memory runs Bloud but few apps; %s or more is recommended", formatMB(hw.MemoryMB), formatMB(recommendedMemoryMB))
+	case hw.Cores < recommendedCores:
+		c.Level = LevelLimited
+		c.Detail = "a single CPU core makes installs and app startup slow"
+	}
+	return c
+}
+
+func checkStorage(hw Hardware) Check {
+	if len(hw.Disks) == 0 {
+		return Check{Name: "storage", Level: LevelUnsupported, Detail: "no disk to install to"}
+	}
+	best := hw.Disks[0]
+	for _, d := range hw.Disks[1:] {
+		if d.ReadMBps > best.ReadMBps {
+			best = d
+		}
+	}
+	c := Check{Name: "storage", Level: LevelGood, Detail: fmt.Sprintf("%s reads at %d MB/s", best.Device, best.ReadMBps)}
+	switch {
+	case best.ReadMBps == 0:
+		c.Detail = "disk speed could not be measured"
+	case best.Rotational:
+		c.Level = LevelLimited
+		c.Detail = fmt.Sprintf("%s is a spinning disk (%d MB/s); databases and app startup will be slow, an SSD is recommended", best.Device, best.ReadMBps)
+	case best.ReadMBps < slowDiskMBps:
+		c.Level = LevelLimited
+		c.Detail = fmt.Sprintf("%s reads at only %d MB/s; apps will be slow to start", best.Device, best.ReadMBps)
+	}
+	return c
+}
+
+func checkNetwork(hw Hardware) Check {
+	var wired, wireless, missing []string
+	for _, nic := range hw.NICs {
+		switch {
+		case nic.Driver == "":
+			missing = append(missing, nic.Name)
+		case nic.Wireless:
+			wireless = append(wireless, nic.Name)
+		default:
+			wired = append(wired, fmt.Sprintf("%s (%s)", nic.Name, nic.Driver))
+		}
+	}
+	switch {
+	case len(wired) > 0:
+		c := Check{Name: "network", Level: LevelGood, Detail: "wired: " + strings.Join(wired, ", ")}
+		if len(missing) > 0 {
+			c.Detail += fmt.Sprintf("; no driver for %s", strings.Join(missing, ", "))
+		}
+		return c
+	case len(wireless) > 0:
+		return Check{Name: "network", Level: LevelLimited, Detail: "Wi-Fi only; the installed system expects a wired connection"}
+	case len(missing) > 0:
+		return Check{Name: "network", Level: LevelUnsupported, Detail: fmt.Sprintf("no driver for the network controller %s", strings.Join(missing, ", "))}
+	}
+	return Check{Name: "network", Level: LevelUnsupported, Detail: "no network controller found"}
+}
+
+// usableGPU is the first GPU app containers can encode video with
+func usableGPU(hw Hardware) *GPU {
+	for i, gpu := range hw.GPUs {
+		if gpuDrivers[gpu.Driver] {
+			return &hw.GPUs[i]
+		}
+	}
+	return nil
+}
+
+func checkTranscoding(hw Hardware) Check {
+	if gpu := usableGPU(hw); gpu != nil {
+		return Check{Name: "transcoding", Level: LevelGood, Detail: fmt.Sprintf("hardware transcoding on the %s GPU (%s)", gpu.Vendor, gpu.Driver)}
+	}
+	for _, gpu := range hw.GPUs {
+		if gpu.Vendor == "nvidia" {
+			return Check{Name: "transcoding", Level: LevelLimited, Detail: "NVIDIA GPUs need the proprietary driver, which Bloud doesn't set up; video is transcoded on the CPU"}
+		}
+	}
+	detail := "no GPU; video is transcoded on the CPU, which manages one or two streams"
+	if hw.VirtualMachine {
+		detail = "no GPU passed through to this VM; video is transcoded on the CPU"
+	}
+	return Check{Name: "transcoding", Level: LevelLimited, Detail: detail}
+}
+
+func checkML(hw Hardware) Check {
+	switch {
+	case !hw.AVX2:
+		return Check{Name: "ml", Level: LevelUnsupported, Detail: "the CPU lacks AVX2, which machine learning apps need"}
+	case hw.MemoryMB < mlMemoryMB:
+		return Check{Name: "ml", Level: LevelLimited, Detail: fmt.Sprintf("machine learning apps want %s of memory or more", formatMB(mlMemoryMB))}
+	case usableGPU(hw) == nil:
+		return Check{Name: "ml", Level: LevelLimited, Detail: "machine learning apps run on the CPU, slowly"}
+	}
+	return Check{Name: "ml", Level: LevelGood, Detail: "AVX2, enough memory and a GPU"}
+}
+
+func checkVirtualization(hw Hardware) Check {
+	switch {
+	case hw.Virtualization:
+		return Check{Name: "virtualization", Level: LevelGood, Detail: "VT-x/AMD-V available"}
+	case hw.VirtualMachine:
+		return Check{Name: "virtualization", Level: LevelLimited, Detail: "running in a VM without nested virtualization; apps that run VMs won't work"}
+	}
+	return Check{Name: "virtualization", Level: LevelLimited, Detail: "VT-x/AMD-V is off or missing (check the firmware settings); apps that run VMs won't work"}
+}
+
+func checkApp(hw Hardware, app App) Check {
+	name := app.DisplayName
+	if name == "" {
+		name = app.Name
+	}
+	c := Check{Name: app.Name, Level: LevelGood, Detail: name + " will run well"}
+	if min := app.Resources.MinRAM; min > 0 && hw.MemoryMB < min {
+		c.Level = LevelUnsupported
+		c.Detail = fmt.Sprintf("%s needs %s of memory", name, formatMB(min))
+		return c
+	}
+	if app.Resources.GPU && usableGPU(hw) == nil {
+		c.Level = LevelLimited
+		c.Detail = name + " works, but without a usable GPU it can't use hardware acceleration"
+	}
+	return c
+}
+
+func worse(a, b Level) Level {
+	rank := map[Level]int{LevelGood: 0, LevelLimited: 1, LevelUnsupported: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+func formatMB(mb int) string {
+	if mb >= 1024 {
+		return fmt.Sprintf("%d GB", (mb+512)/1024)
+	}
+	return fmt.Sprintf("%d MB", mb)
+}
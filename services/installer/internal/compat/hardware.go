@@ -0,0 +1,227 @@
+package compat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/disks"
+)
+
+// diskSampleBytes is how much of each disk Probe reads to time it
+const diskSampleBytes = 128 << 20
+
+// Hardware is what Probe found on the machine
+type Hardware struct {
+	CPU   string `json:"cpu"`
+	Cores int    `json:"cores"`
+	// Virtualization is whether the CPU has VT-x or AMD-V
+	Virtualization bool `json:"virtualization"`
+	// VirtualMachine is whether the machine is itself a VM
+	VirtualMachine bool `json:"virtualMachine"`
+	// AVX2 is needed by most ML runtimes' CPU backends
+	AVX2     bool   `json:"avx2"`
+	MemoryMB int    `json:"memoryMB"`
+	Disks    []Disk `json:"disks"`
+	NICs     []NIC  `json:"nics"`
+	GPUs     []GPU  `json:"gpus"`
+}
+
+// Disk is a candidate install disk and how fast it reads
+type Disk struct {
+	Device     string `json:"device"`
+	Rotational bool   `json:"rotational"`
+	// ReadMBps is sequential read speed; 0 if it couldn't be measured
+	ReadMBps int `json:"readMBps"`
+}
+
+// NIC is a network controller. Driver is empty when no kernel driver
+// claimed it, so it won't work.
+type NIC struct {
+	Name     string `json:"name"` // interface name, or PCI address without a driver
+	Driver   string `json:"driver"`
+	Wireless bool   `json:"wireless"`
+	// SpeedMbps is the negotiated link speed; 0 without a link
+	SpeedMbps int `json:"speedMbps"`
+}
+
+// GPU is a display controller with a render node
+type GPU struct {
+	Vendor     string `json:"vendor"` // intel, amd, nvidia or the PCI vendor ID
+	Driver     string `json:"driver"`
+	RenderNode string `json:"renderNode"`
+}
+
+// PCI vendor IDs of GPUs with hardware video encoders
+var gpuVendors = map[string]string{"0x8086": "intel", "0x1002": "amd", "0x10de": "nvidia"}
+
+// Probe inspects the machine. Reading the disks takes a few seconds.
+func Probe(candidates []disks.Disk) Hardware {
+	hw := Hardware{}
+	probeCPU(&hw)
+	hw.MemoryMB = memoryMB()
+	for _, d := range candidates {
+		hw.Disks = append(hw.Disks, probeDisk(d.Device))
+	}
+	hw.NICs = probeNICs()
+	hw.GPUs = probeGPUs()
+	return hw
+}
+
+func probeCPU(hw *Hardware) {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "model name":
+			if hw.CPU == "" {
+				hw.CPU = value
+			}
+		case "processor":
+			hw.Cores++
+		case "flags":
+			for _, flag := range strings.Fields(value) {
+				switch flag {
+				case "vmx", "svm":
+					hw.Virtualization = true
+				case "hypervisor":
+					hw.VirtualMachine = true
+				case "avx2":
+					hw.AVX2 = true
+				}
+			}
+		}
+	}
+}
+
+func memoryMB() int {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "MemTotal:") {
+			var kb int
+			fmt.Sscanf(strings.TrimPrefix(line, "MemTotal:"), "%d", &kb)
+			return kb / 1024
+		}
+	}
+	return 0
+}
+
+// probeDisk times a sequential read from the start of device. The live
+// system has never read these disks, so the page cache doesn't flatter them.
+func probeDisk(device string) Disk {
+	d := Disk{Device: device}
+	d.Rotational = readSysfs(filepath.Join("/sys/block", filepath.Base(device), "queue", "rotational")) == "1"
+
+	f, err := os.Open(device)
+	if err != nil {
+		return d
+	}
+	defer f.Close()
+
+	start := time.Now()
+	n, _ := io.Copy(io.Discard, io.LimitReader(f, diskSampleBytes))
+	if elapsed := time.Since(start); n > 0 && elapsed > 0 {
+		d.ReadMBps = int(float64(n) / (1 << 20) / elapsed.Seconds())
+	}
+	return d
+}
+
+func probeNICs() []NIC {
+	var nics []NIC
+	claimed := map[string]bool{}
+	entries, _ := os.ReadDir("/sys/class/net")
+	for _, e := range entries {
+		dir := filepath.Join("/sys/class/net", e.Name())
+		device, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+		if err != nil {
+			continue // virtual: lo, bridges, VLANs
+		}
+		claimed[filepath.Base(device)] = true
+		nic := NIC{Name: e.Name(), Driver: linkBase(filepath.Join(dir, "device", "driver"))}
+		if _, err := os.Stat(filepath.Join(dir, "wireless")); err == nil {
+			nic.Wireless = true
+		}
+		if readSysfs(filepath.Join(dir, "carrier")) == "1" {
+			nic.SpeedMbps, _ = strconv.Atoi(readSysfs(filepath.Join(dir, "speed")))
+		}
+		nics = append(nics, nic)
+	}
+
+	// Network controllers (PCI class 0x02) with no interface have no driver
+	for _, dev := range pciDevices("0x02") {
+		if !claimed[filepath.Base(dev)] {
+			nics = append(nics, NIC{Name: filepath.Base(dev), Driver: linkBase(filepath.Join(dev, "driver"))})
+		}
+	}
+	return nics
+}
+
+func probeGPUs() []GPU {
+	var gpus []GPU
+	nodes, _ := filepath.Glob("/sys/class/drm/renderD*")
+	for _, node := range nodes {
+		device := filepath.Join(node, "device")
+		vendor := readSysfs(filepath.Join(device, "vendor"))
+		if name, ok := gpuVendors[vendor]; ok {
+			vendor = name
+		}
+		gpus = append(gpus, GPU{
+			Vendor:     vendor,
+			Driver:     linkBase(filepath.Join(device, "driver")),
+			RenderNode: "/dev/dri/" + filepath.Base(node),
+		})
+	}
+	return gpus
+}
+
+// pciDevices lists PCI devices whose class starts with prefix
+func pciDevices(prefix string) []string {
+	var devices []string
+	entries, _ := os.ReadDir("/sys/bus/pci/devices")
+	for _, e := range entries {
+		dev := filepath.Join("/sys/bus/pci/devices", e.Name())
+		if strings.HasPrefix(readSysfs(filepath.Join(dev, "class")), prefix) {
+			devices = append(devices, dev)
+		}
+	}
+	return devices
+}
+
+func readSysfs(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// linkBase is the name a sysfs symlink points at, such as a driver
+func linkBase(path string) string {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
@@ -0,0 +1,130 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/api"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/compat"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/installer"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/setuptoken"
+)
+
+// client talks to the installer's own HTTP API, so the console and the web
+// UI drive the same install and get the same validation
+type client struct {
+	baseURL string
+	// token reads the setup code for each request, since a restarted
+	// installer service has a new one
+	token func() string
+}
+
+func (c *client) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *client) post(ctx context.Context, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(setuptoken.Header, c.token())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// statusError is a non-200 answer, carrying the API's error message
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+func apiError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) != nil || body.Error == "" {
+		body.Error = resp.Status
+	}
+	return &statusError{status: resp.StatusCode, message: body.Error}
+}
+
+func (c *client) status(ctx context.Context) (api.StatusResponse, error) {
+	var status api.StatusResponse
+	return status, c.get(ctx, "/api/status", &status)
+}
+
+func (c *client) disks(ctx context.Context) (api.DisksResponse, error) {
+	var disks api.DisksResponse
+	return disks, c.get(ctx, "/api/disks", &disks)
+}
+
+func (c *client) compat(ctx context.Context) (compat.Report, error) {
+	var report compat.Report
+	return report, c.get(ctx, "/api/compat", &report)
+}
+
+// stream calls fn with each /api/install/stream event until the install
+// completes or fails
+func (c *client) stream(ctx context.Context, fn func(installer.LogEvent)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/install/stream", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event installer.LogEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("bad event: %w", err)
+		}
+		fn(event)
+	}
+	return scanner.Err()
+}
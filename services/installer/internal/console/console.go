@@ -0,0 +1,374 @@
+// Package console is the installer's terminal frontend, for machines with
+// a screen and keyboard but no other device to open the web UI on. It
+// walks the same steps as the web flow (welcome, installing, restarting)
+// against the same HTTP API, so either can start the install and both
+// follow it.
+package console
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/api"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/compat"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/installer"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/partition"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorDim    = "\033[2m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	clearScreen = "\033[H\033[2J"
+	clearLine   = "\033[2K"
+	cursorUp    = "\033[1A"
+
+	barWidth = 40
+)
+
+// friendlyMessages match the web UI's Installing step
+var friendlyMessages = map[installer.Phase]string{
+	installer.PhaseValidating:   "Getting started…",
+	installer.PhasePartitioning: "Preparing your drive…",
+	installer.PhaseFormatting:   "Preparing your drive…",
+	installer.PhaseInstalling:   "Installing Bloud…",
+	installer.PhaseRestoring:    "Restoring your backup…",
+	installer.PhaseConfiguring:  "Almost there…",
+	installer.PhaseComplete:     "All done.",
+}
+
+type console struct {
+	client *client
+	in     *bufio.Reader
+	out    io.Writer
+}
+
+// Run shows the console installer on in and out until the machine
+// restarts into the installed system. baseURL is the installer's API and
+// token reads its setup code.
+func Run(ctx context.Context, baseURL string, token func() string, in io.Reader, out io.Writer) error {
+	c := &console{client: &client{baseURL: baseURL, token: token}, in: bufio.NewReader(in), out: out}
+	for {
+		status, err := c.client.status(ctx)
+		if err != nil {
+			c.printf("%sWaiting for the installer service…%s\n", colorDim, colorReset)
+			if err := sleep(ctx, 2*time.Second); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// An install started from the web UI or an answer file is
+		// followed here rather than offered again
+		if status.Phase == installer.PhaseIdle || status.Phase == installer.PhaseFailed {
+			if err := c.welcome(ctx, status); err != nil {
+				if errors.Is(err, io.EOF) || ctx.Err() != nil {
+					return err
+				}
+				c.printf("\n%s%v%s\n", colorRed, err, colorReset)
+				c.pause()
+				continue
+			}
+		}
+
+		event, err := c.installing(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			c.printf("\n%sLost the installer's progress: %v%s\n", colorRed, err, colorReset)
+			c.pause()
+			continue
+		}
+		if event.Phase == installer.PhaseComplete {
+			return c.restarting(ctx)
+		}
+		c.printf("\n%sSomething went wrong.%s Setup didn't complete:\n  %s\n", colorRed, colorReset, event.Message)
+		c.pause()
+	}
+}
+
+// welcome is the web UI's Welcome step: system details, the drive (picked
+// for the user unless it's ambiguous) and encryption, then starting the
+// install. It returns nil once an install is running, whoever started it.
+func (c *console) welcome(ctx context.Context, status api.StatusResponse) error {
+	disks, err := c.client.disks(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to load system information: %w", err)
+	}
+	if len(disks.Disks) == 0 {
+		return fmt.Errorf("No drives found to install to.")
+	}
+
+	c.printf("%s%sbloud%s\n\n", clearScreen, colorBold, colorReset)
+	c.printf("Your server is ready to set up.\n\n")
+	c.printf("  CPU     %s\n", status.CPU)
+	c.printf("  Memory  %d GB\n", status.MemoryGB)
+	if len(status.IPAddresses) > 0 {
+		c.printf("  IP      %s\n", status.IPAddresses[0])
+		c.printf("\n%sYou can also set up from another device at http://bloud.local or http://%s%s\n",
+			colorDim, status.IPAddresses[0], colorReset)
+		c.printf("%sSetup code:%s %s%s%s\n", colorDim, colorReset, colorBold, c.client.token(), colorReset)
+	}
+
+	// The report times the disks; it's worth the wait before committing
+	if report, err := c.client.compat(ctx); err == nil {
+		for _, check := range report.Features {
+			switch check.Level {
+			case compat.LevelLimited:
+				c.printf("  %s%-14s %s%s\n", colorDim, check.Name, check.Detail, colorReset)
+			case compat.LevelUnsupported:
+				c.printf("  %s%-14s %s%s\n", colorYellow, check.Name, check.Detail, colorReset)
+			}
+		}
+	}
+
+	c.printf("\nDrives:\n")
+	selected := -1
+	for i, d := range disks.Disks {
+		if d.Device == disks.AutoSelected {
+			selected = i
+		}
+		notes := []string{}
+		if d.Removable {
+			notes = append(notes, "removable")
+		}
+		if d.HasExistingData {
+			notes = append(notes, "has data")
+		}
+		note := ""
+		if len(notes) > 0 {
+			note = fmt.Sprintf(" %s(%s)%s", colorDim, strings.Join(notes, ", "), colorReset)
+		}
+		c.printf("  %d) %-24s %8s  %s%s\n", i+1, d.Model, formatSize(d.SizeGB), d.Device, note)
+	}
+
+	if disks.Ambiguous || selected < 0 {
+		c.printf("\nMultiple drives detected — choose one.\n")
+		selected = -1
+	}
+	for {
+		prompt := "Drive"
+		if selected >= 0 {
+			prompt = fmt.Sprintf("Drive [%d]", selected+1)
+		}
+		answer, err := c.ask(prompt + ": ")
+		if err != nil {
+			return err
+		}
+		if answer == "" && selected >= 0 {
+			break
+		}
+		n, err := strconv.Atoi(answer)
+		if err == nil && n >= 1 && n <= len(disks.Disks) {
+			selected = n - 1
+			break
+		}
+		c.printf("%sEnter a number from 1 to %d.%s\n", colorYellow, len(disks.Disks), colorReset)
+	}
+	disk := disks.Disks[selected]
+
+	encryption, err := c.confirm("Encrypt drive? Recommended; protects data if the machine is lost or stolen.", true)
+	if err != nil {
+		return err
+	}
+
+	var luks partition.LUKS
+	if encryption {
+		if luks, err = c.encryption(status); err != nil {
+			return err
+		}
+	}
+
+	c.printf("\n")
+	if disk.HasExistingData {
+		c.printf("%sAll existing data on %s (%s) will be erased.%s\n", colorYellow, disk.Device, disk.Model, colorReset)
+	}
+	install, err := c.confirm(fmt.Sprintf("Install Bloud on %s?", disk.Device), false)
+	if err != nil {
+		return err
+	}
+	if !install {
+		return fmt.Errorf("Installation cancelled.")
+	}
+
+	err = c.client.post(ctx, "/api/install", api.InstallRequestBody{Disk: disk.Device, Encryption: encryption, LUKS: luks})
+	var se *statusError
+	if errors.As(err, &se) && se.status == http.StatusConflict {
+		return nil // started elsewhere in the meantime
+	}
+	return err
+}
+
+// encryption asks for the drive passphrase, and whether the TPM should
+// unlock the drive when the machine has one
+func (c *console) encryption(status api.StatusResponse) (partition.LUKS, error) {
+	luks := partition.LUKS{}
+	if status.TPM {
+		tpm, err := c.confirm("Unlock automatically with this machine's TPM?", true)
+		if err != nil {
+			return luks, err
+		}
+		luks.TPM = tpm
+	}
+	if luks.TPM {
+		c.printf("%sThe passphrase unlocks the drive if the TPM can't. Keep it safe.%s\n", colorDim, colorReset)
+	} else {
+		c.printf("%sYou'll type the passphrase each time the server starts. It can't be recovered.%s\n", colorDim, colorReset)
+	}
+	for {
+		passphrase, err := c.askSecret("Drive passphrase: ")
+		if err != nil {
+			return luks, err
+		}
+		if utf8.RuneCountInString(passphrase) < partition.MinPassphraseLength {
+			c.printf("%sUse at least %d characters.%s\n", colorYellow, partition.MinPassphraseLength, colorReset)
+			continue
+		}
+		confirm, err := c.askSecret("Confirm passphrase: ")
+		if err != nil {
+			return luks, err
+		}
+		if confirm != passphrase {
+			c.printf("%sPassphrases do not match.%s\n", colorYellow, colorReset)
+			continue
+		}
+		luks.Passphrase = passphrase
+		return luks, nil
+	}
+}
+
+// installing is the web UI's Installing step: a progress bar and the
+// latest log line, until the install completes or fails
+func (c *console) installing(ctx context.Context) (installer.LogEvent, error) {
+	c.printf("%s%sbloud%s\n\nSetting up your server.\n", clearScreen, colorBold, colorReset)
+	c.printf("%sThis takes a few minutes. Don't turn off your computer.%s\n\n\n\n", colorDim, colorReset)
+
+	var last installer.LogEvent
+	err := c.client.stream(ctx, func(event installer.LogEvent) {
+		last = event
+		if event.Phase == installer.PhaseFailed {
+			return
+		}
+		message := friendlyMessages[event.Phase]
+		if message == "" {
+			message = friendlyMessages[installer.PhaseValidating]
+		}
+		filled := event.Percent * barWidth / 100
+		detail := event.Message
+		if len(detail) > 76 {
+			detail = detail[:73] + "..."
+		}
+		c.printf("%s%s\r%s[%s%s] %3d%%  %s\n", cursorUp, cursorUp, clearLine,
+			strings.Repeat("#", filled), strings.Repeat("-", barWidth-filled), event.Percent, message)
+		c.printf("%s%s%s%s\n", clearLine, colorDim, detail, colorReset)
+	})
+	if err != nil {
+		return last, err
+	}
+	if last.Phase != installer.PhaseComplete && last.Phase != installer.PhaseFailed {
+		return last, fmt.Errorf("stream ended during %s", last.Phase)
+	}
+	return last, nil
+}
+
+// restarting is the web UI's Restarting step
+func (c *console) restarting(ctx context.Context) error {
+	c.printf("\n%sAll done.%s Restarting into Bloud…\n", colorGreen, colorReset)
+	c.printf("Once it's back, open http://bloud.local from any device on this network to finish setup.\n")
+	if err := c.client.post(ctx, "/api/reboot", nil); err != nil {
+		// The reboot can cut the connection before the response
+		c.printf("%s%v%s\n", colorDim, err, colorReset)
+	}
+	return nil
+}
+
+func (c *console) printf(format string, args ...any) {
+	fmt.Fprintf(c.out, format, args...)
+}
+
+// ask reads one trimmed line
+func (c *console) ask(prompt string) (string, error) {
+	c.printf("%s", prompt)
+	line, err := c.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// askSecret reads a line without echoing it. Outside a terminal stty
+// fails and the line is read as is.
+func (c *console) askSecret(prompt string) (string, error) {
+	c.printf("%s", prompt)
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		return cmd.Run()
+	}
+	if stty("-echo") == nil {
+		defer func() {
+			stty("echo") //nolint:errcheck
+			c.printf("\n")
+		}()
+	}
+	line, err := c.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *console) confirm(question string, def bool) (bool, error) {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	for {
+		answer, err := c.ask(fmt.Sprintf("%s %s ", question, hint))
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+func (c *console) pause() {
+	c.ask("Press Enter to try again. ") //nolint:errcheck
+}
+
+func formatSize(gb float64) string {
+	if gb >= 1000 {
+		return fmt.Sprintf("%.1f TB", gb/1000)
+	}
+	return fmt.Sprintf("%.0f GB", gb)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -11,6 +11,7 @@ type Disk struct {
 	SizeBytes   int64
 	Model       string
 	IsRemovable bool
+	Transport   string // usb, sata, nvme, ... as reported by lsblk
 }
 
 type lsblkOutput struct {
@@ -23,10 +24,11 @@ type lsblkDevice struct {
 	Model string `json:"model"`
 	Type  string `json:"type"`
 	Tran  string `json:"tran"`
+	RM    bool   `json:"rm"`
 }
 
 func Enumerate() ([]Disk, error) {
-	out, err := exec.Command("lsblk", "-J", "-b", "-d", "-o", "NAME,SIZE,MODEL,TYPE,TRAN").Output()
+	out, err := exec.Command("lsblk", "-J", "-b", "-d", "-o", "NAME,SIZE,MODEL,TYPE,TRAN,RM").Output()
 	if err != nil {
 		return nil, fmt.Errorf("lsblk failed: %w", err)
 	}
@@ -45,7 +47,8 @@ func Enumerate() ([]Disk, error) {
 			Device:      "/dev/" + dev.Name,
 			SizeBytes:   dev.Size,
 			Model:       dev.Model,
-			IsRemovable: dev.Tran == "usb",
+			IsRemovable: dev.RM || dev.Tran == "usb",
+			Transport:   dev.Tran,
 		})
 	}
 	return disks, nil
@@ -1,6 +1,11 @@
 package disks
 
-import "sort"
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+)
 
 // AutoSelect returns the largest disk, excluding the boot device.
 // bootDevice may be empty, in which case no exclusion is applied.
@@ -41,3 +46,41 @@ func sortedBySize(disks []Disk) []Disk {
 	})
 	return out
 }
+
+// BootDevice is the disk the live system booted from, from root= on the
+// kernel command line, or "" when it can't tell
+func BootDevice() string {
+	f, err := os.Open("/proc/cmdline")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return ""
+	}
+	cmdline := scanner.Text()
+
+	for _, field := range strings.Fields(cmdline) {
+		if !strings.HasPrefix(field, "root=") {
+			continue
+		}
+		val := strings.TrimPrefix(field, "root=")
+		if strings.HasPrefix(val, "/dev/") {
+			return stripPartitionSuffix(val)
+		}
+	}
+	return ""
+}
+
+func stripPartitionSuffix(device string) string {
+	if strings.Contains(device, "nvme") || strings.Contains(device, "mmcblk") {
+		if idx := strings.LastIndex(device, "p"); idx > 0 {
+			return device[:idx]
+		}
+		return device
+	}
+	trimmed := strings.TrimRight(device, "0123456789")
+	return trimmed
+}
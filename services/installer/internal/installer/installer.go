@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/netconfig"
 	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/nixinstall"
 	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/partition"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/restore"
 )
 
 type Phase string
@@ -19,26 +22,44 @@ const (
 	PhasePartitioning Phase = "partitioning"
 	PhaseFormatting   Phase = "formatting"
 	PhaseInstalling   Phase = "installing"
+	PhaseRestoring    Phase = "restoring"
 	PhaseConfiguring  Phase = "configuring"
 	PhaseComplete     Phase = "complete"
 	PhaseFailed       Phase = "failed"
 )
 
+// LogEvent is one line of install output. Step and Percent say how far the
+// install is overall; a failed install keeps the step it failed in.
 type LogEvent struct {
 	Phase   Phase  `json:"phase"`
 	Message string `json:"message"`
+	Step    Step   `json:"step"`
+	Percent int    `json:"percent"`
 }
 
 type InstallRequest struct {
-	Disk       string `json:"disk"`
-	Encryption bool   `json:"encryption"`
-	FlakePath  string `json:"flakePath"`
+	Disk       string           `json:"disk"`
+	Encryption bool             `json:"encryption"`
+	FlakePath  string           `json:"flakePath"`
+	Layout     partition.Layout `json:"layout"`
+	Network    netconfig.Config `json:"network"`
+	// LUKS is how to encrypt when Encryption is set; it needs at least a
+	// passphrase
+	LUKS partition.LUKS `json:"luks"`
+	// Restore is a ./bloud backup archive to seed the new system with: an
+	// absolute path on the live system or an http(s) URL
+	Restore string `json:"restore"`
+	// Admin and Apps are set up through the host-agent on first boot
+	Admin *Admin   `json:"admin"`
+	Apps  []string `json:"apps"`
 }
 
 type Installer struct {
 	mu          sync.Mutex
 	phase       Phase
 	lastMessage string
+	step        Step
+	percent     int
 	subscribers []chan LogEvent
 }
 
@@ -60,15 +81,25 @@ func (inst *Installer) LastMessage() string {
 	return inst.lastMessage
 }
 
+// Current is the latest event, for subscribers joining mid-install
+func (inst *Installer) Current() LogEvent {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return LogEvent{Phase: inst.phase, Message: inst.lastMessage, Step: inst.step, Percent: inst.percent}
+}
+
 func (inst *Installer) Emit(phase Phase, message string) {
 	inst.mu.Lock()
 	inst.phase = phase
 	inst.lastMessage = message
+	if step, ok := phaseSteps[phase]; ok {
+		inst.advance(step, 0)
+	}
+	event := LogEvent{Phase: phase, Message: message, Step: inst.step, Percent: inst.percent}
 	subs := make([]chan LogEvent, len(inst.subscribers))
 	copy(subs, inst.subscribers)
 	inst.mu.Unlock()
 
-	event := LogEvent{Phase: phase, Message: message}
 	for _, ch := range subs {
 		select {
 		case ch <- event:
@@ -108,20 +139,54 @@ func (inst *Installer) StartMock(req InstallRequest) error {
 		return fmt.Errorf("installation already in progress (phase: %s)", inst.phase)
 	}
 	inst.phase = PhaseValidating
+	inst.step, inst.percent = StepPreparing, 0
 	inst.subscribers = nil // reset subscribers so stale channels don't accumulate
 	inst.mu.Unlock()
 
+	type mockStep struct {
+		phase   Phase
+		message string
+		delay   time.Duration
+	}
+	var layoutSteps []mockStep
+	if req.Layout.RootGB > 0 {
+		layoutSteps = append(layoutSteps, mockStep{PhasePartitioning, fmt.Sprintf("Limiting root to %d GiB; the rest is the data store", req.Layout.RootGB), time.Second})
+	}
+	if req.Layout.SwapGB > 0 {
+		layoutSteps = append(layoutSteps, mockStep{PhasePartitioning, fmt.Sprintf("Creating %d GiB swap partition", req.Layout.SwapGB), time.Second})
+	}
+	var restoreSteps []mockStep
+	if req.Restore != "" {
+		restoreSteps = []mockStep{
+			{PhaseRestoring, "Restoring backup " + req.Restore, time.Second},
+			{PhaseRestoring, "Unpacking " + req.Restore + " into " + restore.DataDir, 2 * time.Second},
+			{PhaseRestoring, "Database dump queued for replay on first boot", 500 * time.Millisecond},
+		}
+	}
+	if req.Encryption {
+		layoutSteps = append(layoutSteps, mockStep{PhaseFormatting, "Encrypting root partition with LUKS2 (label: nixos-crypt)", time.Second})
+		if req.LUKS.TPM {
+			layoutSteps = append(layoutSteps, mockStep{PhaseFormatting, "Sealing a key for the root partition to the TPM", time.Second})
+		}
+	}
+	if req.Layout.Filesystem != "" || req.Layout.MirrorDisk != "" {
+		msg := fmt.Sprintf("Creating %s data store", req.Layout.Filesystem)
+		if req.Layout.MirrorDisk != "" {
+			msg += " mirrored on " + req.Layout.MirrorDisk
+		}
+		layoutSteps = append(layoutSteps, mockStep{PhaseFormatting, msg, time.Second})
+	}
+
 	go func() {
-		steps := []struct {
-			phase   Phase
-			message string
-			delay   time.Duration
-		}{
+		steps := []mockStep{
 			{PhaseValidating, "Validating parameters", 800 * time.Millisecond},
 			{PhasePartitioning, "Clearing existing signatures from " + req.Disk, time.Second},
 			{PhasePartitioning, "Creating GPT partition table", time.Second},
 			{PhasePartitioning, "Creating EFI partition (1MiB–513MiB)", time.Second},
 			{PhasePartitioning, "Creating root partition (513MiB–100%)", time.Second},
+		}
+		steps = append(steps, layoutSteps...)
+		steps = append(steps, []mockStep{
 			{PhaseFormatting, "Formatting EFI partition as FAT32", time.Second},
 			{PhaseFormatting, "Formatting root partition as ext4", time.Second},
 			{PhaseFormatting, "Mounting partitions", 500 * time.Millisecond},
@@ -130,15 +195,33 @@ func (inst *Installer) StartMock(req InstallRequest) error {
 			{PhaseInstalling, "copying path '/nix/store/...-linux-6.6.66' to '/mnt'...", 2 * time.Second},
 			{PhaseInstalling, "copying path '/nix/store/...-glibc-2.39' to '/mnt'...", 2 * time.Second},
 			{PhaseInstalling, "copying path '/nix/store/...-bloud-host-agent-0.1.0' to '/mnt'...", 2 * time.Second},
+			{PhaseInstalling, "installing the boot loader...", time.Second},
 			{PhaseInstalling, "activating the configuration...", time.Second},
+		}...)
+		steps = append(steps, restoreSteps...)
+		steps = append(steps, []mockStep{
 			{PhaseConfiguring, "Applying post-install configuration", time.Second},
-			{PhaseConfiguring, fmt.Sprintf("Encryption: %v", req.Encryption), 300 * time.Millisecond},
-		{PhaseConfiguring, "User account setup deferred to post-reboot wizard", 500 * time.Millisecond},
+			{PhaseConfiguring, encryptionSummary(req), 300 * time.Millisecond},
+			{PhaseConfiguring, "Network: " + networkSummary(req.Network), 300 * time.Millisecond},
+			{PhaseConfiguring, provisionSummary(req), 500 * time.Millisecond},
 			{PhaseComplete, "Installation complete — ready to reboot", 0},
-		}
+		}...)
 
+		copies, copied := 0, 0
+		for _, step := range steps {
+			if strings.HasPrefix(step.message, "copying path ") {
+				copies++
+			}
+		}
 		for _, step := range steps {
 			time.Sleep(step.delay)
+			switch {
+			case strings.HasPrefix(step.message, "copying path "):
+				copied++
+				inst.Advance(StepCopying, float64(copied)/float64(copies))
+			case strings.HasPrefix(step.message, "installing the boot loader"):
+				inst.Advance(StepBootloader, 0)
+			}
 			inst.Emit(step.phase, step.message)
 		}
 	}()
@@ -153,6 +236,7 @@ func (inst *Installer) Start(_ context.Context, req InstallRequest) error {
 		return fmt.Errorf("installation already in progress (phase: %s)", inst.phase)
 	}
 	inst.phase = PhaseValidating
+	inst.step, inst.percent = StepPreparing, 0
 	inst.mu.Unlock()
 
 	// Use context.Background() so the installation continues even after
@@ -173,18 +257,37 @@ func (inst *Installer) run(ctx context.Context, req InstallRequest) {
 		inst.Emit(inst.Phase(), message)
 	}
 
-	inst.Emit(PhasePartitioning, "Partitioning disk "+req.Disk)
+	if req.Layout != (partition.Layout{}) {
+		inst.Emit(PhasePartitioning, fmt.Sprintf("Partitioning disk %s (root: %s, swap: %d GiB)", req.Disk, rootSize(req.Layout), req.Layout.SwapGB))
+	} else {
+		inst.Emit(PhasePartitioning, "Partitioning disk "+req.Disk)
+	}
 	emitPartition := func(msg string) {
 		inst.Emit(PhasePartitioning, msg)
 	}
 
-	if err := partition.Prepare(ctx, req.Disk, emitPartition); err != nil {
+	var luks *partition.LUKS
+	if req.Encryption {
+		luks = &req.LUKS
+	}
+	if err := partition.Prepare(ctx, req.Disk, req.Layout, luks, emitPartition); err != nil {
 		inst.Emit(PhaseFailed, "partitioning failed: "+err.Error())
 		return
 	}
 
 	inst.Emit(PhaseFormatting, "Formatting complete")
 
+	if err := writeDiskConfig(req.Layout, luks); err != nil {
+		inst.Emit(PhaseFailed, "writing disk configuration failed: "+err.Error())
+		return
+	}
+	if !req.Network.IsZero() {
+		if err := netconfig.Write("/mnt", req.Network); err != nil {
+			inst.Emit(PhaseFailed, "writing network configuration failed: "+err.Error())
+			return
+		}
+	}
+
 	inst.Emit(PhaseInstalling, "Starting NixOS installation")
 	emitInstall := func(msg string) {
 		inst.Emit(PhaseInstalling, msg)
@@ -198,13 +301,136 @@ func (inst *Installer) run(ctx context.Context, req InstallRequest) {
 		flakePath = "/etc/bloud"
 	}
 
-	if err := nixinstall.Install(ctx, flakePath, emitInstall); err != nil {
+	progress := func(p nixinstall.Progress) {
+		switch {
+		case p.Bootloader:
+			inst.Advance(StepBootloader, 0)
+		case p.Total > 0:
+			inst.Advance(StepCopying, float64(p.Copied)/float64(p.Total))
+		default:
+			// Unknown closure: approach the end of the step without reaching it
+			inst.Advance(StepCopying, float64(p.Copied)/float64(p.Copied+500))
+		}
+	}
+
+	if err := nixinstall.Install(ctx, flakePath, luks != nil, emitInstall, progress); err != nil {
 		inst.Emit(PhaseFailed, "nixos-install failed: "+err.Error())
 		return
 	}
 
+	if req.Restore != "" {
+		inst.Emit(PhaseRestoring, "Restoring backup "+req.Restore)
+		emitRestore := func(msg string) {
+			inst.Emit(PhaseRestoring, msg)
+		}
+		progress := func(fraction float64) {
+			inst.Advance(StepRestoring, fraction)
+		}
+		if err := restore.Seed(ctx, req.Restore, "/mnt", emitRestore, progress); err != nil {
+			inst.Emit(PhaseFailed, "restoring backup failed: "+err.Error())
+			return
+		}
+	}
+
 	inst.Emit(PhaseConfiguring, "Applying post-install configuration")
-	emit("User account setup deferred to post-reboot wizard")
+	if req.Admin != nil || len(req.Apps) > 0 {
+		if err := writeProvision(req); err != nil {
+			inst.Emit(PhaseFailed, "writing first-boot provisioning failed: "+err.Error())
+			return
+		}
+		emit(provisionSummary(req))
+	} else {
+		emit("User account setup deferred to post-reboot wizard")
+	}
+	if luks != nil {
+		if err := configureEncryption(ctx, req, emit); err != nil {
+			inst.Emit(PhaseFailed, "configuring encryption failed: "+err.Error())
+			return
+		}
+	}
+	if err := partition.Finish(ctx, req.Layout, emit); err != nil {
+		inst.Emit(PhaseFailed, "releasing data store failed: "+err.Error())
+		return
+	}
 
 	inst.Emit(PhaseComplete, "Installation complete — ready to reboot")
 }
+
+func rootSize(layout partition.Layout) string {
+	if layout.RootGB == 0 {
+		return "whole disk"
+	}
+	return fmt.Sprintf("%d GiB", layout.RootGB)
+}
+
+// writeDiskConfig records the layout and encryption as NixOS configuration
+// for installed.nix to import
+func writeDiskConfig(layout partition.Layout, luks *partition.LUKS) error {
+	if err := os.MkdirAll("/mnt/etc/nixos", 0755); err != nil {
+		return err
+	}
+	return os.WriteFile("/mnt/etc/nixos/bloud-disks.nix", []byte(partition.NixConfig(layout, luks)), 0644)
+}
+
+// configureEncryption leaves what the pre-built system can't do itself:
+// the initrd's SSH host key for remote unlock, and a rebuild on first boot
+// to pick up bloud-disks.nix's encrypted swap and remote unlock
+func configureEncryption(ctx context.Context, req InstallRequest, emit func(string)) error {
+	emit(encryptionSummary(req))
+	if len(req.LUKS.SSHKeys) > 0 {
+		fingerprint, err := partition.WriteInitrdHostKey(ctx, "/mnt")
+		if err != nil {
+			return err
+		}
+		emit(fmt.Sprintf("Remote unlock host key: %s", fingerprint))
+	}
+	if len(req.LUKS.SSHKeys) == 0 && req.Layout.SwapGB == 0 {
+		return nil
+	}
+	if err := os.MkdirAll("/mnt/etc/bloud", 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(rebuildPendingFile, nil, 0644)
+}
+
+func encryptionSummary(req InstallRequest) string {
+	if !req.Encryption {
+		return "Encryption: off"
+	}
+	summary := "Encryption: LUKS2, unlocked with the passphrase"
+	if req.LUKS.TPM {
+		summary += " or the TPM"
+	}
+	if len(req.LUKS.SSHKeys) > 0 {
+		summary += fmt.Sprintf(", remotely over SSH on %s port %d", req.LUKS.Interface, partition.UnlockPort)
+	}
+	return summary
+}
+
+func networkSummary(c netconfig.Config) string {
+	summary := "DHCP"
+	if c.Address != "" {
+		summary = fmt.Sprintf("%s via %s", c.Address, c.Gateway)
+	}
+	if c.VLAN != 0 {
+		summary += fmt.Sprintf(" on VLAN %d", c.VLAN)
+	}
+	if c.Hostname != "" {
+		summary += ", hostname " + c.Hostname
+	}
+	return summary
+}
+
+func provisionSummary(req InstallRequest) string {
+	if req.Admin == nil && len(req.Apps) == 0 {
+		return "User account setup deferred to post-reboot wizard"
+	}
+	var parts []string
+	if req.Admin != nil {
+		parts = append(parts, "create admin "+req.Admin.Username)
+	}
+	if len(req.Apps) > 0 {
+		parts = append(parts, "install "+strings.Join(req.Apps, ", "))
+	}
+	return "First boot will " + strings.Join(parts, " and ")
+}
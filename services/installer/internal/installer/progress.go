@@ -0,0 +1,70 @@
+package installer
+
+// Step is a stage of the install as /api/install/stream reports it; finer
+// than Phase where the work is long (copying the system) and coarser where
+// it isn't
+type Step string
+
+const (
+	StepPreparing    Step = "preparing"
+	StepPartitioning Step = "partitioning"
+	StepFormatting   Step = "formatting"
+	StepCopying      Step = "copying"
+	StepBootloader   Step = "bootloader"
+	StepRestoring    Step = "restoring"
+	StepConfiguring  Step = "configuring"
+	StepDone         Step = "done"
+)
+
+// stepRanges is the share of the overall percentage each step covers,
+// weighted by how long it takes on typical hardware
+var stepRanges = map[Step][2]int{
+	StepPreparing:    {0, 2},
+	StepPartitioning: {2, 8},
+	StepFormatting:   {8, 15},
+	StepCopying:      {15, 85},
+	StepBootloader:   {85, 90},
+	StepRestoring:    {90, 95},
+	StepConfiguring:  {95, 99},
+	StepDone:         {100, 100},
+}
+
+// phaseSteps is the step each phase starts in
+var phaseSteps = map[Phase]Step{
+	PhaseValidating:   StepPreparing,
+	PhasePartitioning: StepPartitioning,
+	PhaseFormatting:   StepFormatting,
+	PhaseInstalling:   StepCopying,
+	PhaseRestoring:    StepRestoring,
+	PhaseConfiguring:  StepConfiguring,
+	PhaseComplete:     StepDone,
+}
+
+// Advance records that the install is fraction (0–1) of the way through
+// step. Progress only moves forward, so a late or repeated report never
+// makes the bar jump back.
+func (inst *Installer) Advance(step Step, fraction float64) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	inst.advance(step, fraction)
+}
+
+func (inst *Installer) advance(step Step, fraction float64) {
+	r, ok := stepRanges[step]
+	if !ok {
+		return
+	}
+	fraction = min(max(fraction, 0), 1)
+	percent := r[0] + int(fraction*float64(r[1]-r[0]))
+	if percent < inst.percent {
+		return
+	}
+	inst.step, inst.percent = step, percent
+}
+
+// Progress is the current step and overall percentage
+func (inst *Installer) Progress() (Step, int) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.step, inst.percent
+}
@@ -0,0 +1,41 @@
+package installer
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// provisionFile is read by bloud-provision.service on first boot. It holds
+// the admin's password, so it is root-only and removed once used.
+const provisionFile = "/mnt/etc/bloud/provision.json"
+
+// rebuildPendingFile asks bloud-rebuild-pending.service for a rebuild on
+// first boot, for generated configuration the pre-built system lacks
+const rebuildPendingFile = "/mnt/etc/bloud/rebuild-pending"
+
+// Admin is the first user, created on first boot in place of the setup
+// wizard
+type Admin struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// provision is what the installed system does through the host-agent API
+// once it is up
+type provision struct {
+	Admin *Admin   `json:"admin,omitempty"`
+	Apps  []string `json:"apps,omitempty"`
+}
+
+// writeProvision leaves the admin account and initial apps for the
+// installed system
+func writeProvision(req InstallRequest) error {
+	data, err := json.Marshal(provision{Admin: req.Admin, Apps: req.Apps})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll("/mnt/etc/bloud", 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(provisionFile, data, 0600)
+}
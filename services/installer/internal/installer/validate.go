@@ -0,0 +1,92 @@
+package installer
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/disks"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/netconfig"
+	"codeberg.org/d-buckner/bloud-v3/services/installer/internal/nixinstall"
+)
+
+var (
+	// usernamePattern and appNamePattern match what the host-agent accepts
+	// for the first user and for app names
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]{3,30}$`)
+	appNamePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+// Validate checks req against the machine's disks before anything is
+// touched, filling in the network interface when one is needed but not
+// given. Errors are meant for whoever wrote the request.
+func Validate(req *InstallRequest, all []disks.Disk) error {
+	if req.Disk == "" {
+		return fmt.Errorf("disk is required")
+	}
+	disk := findDisk(all, req.Disk)
+	if disk == nil {
+		return fmt.Errorf("disk not found: %s", req.Disk)
+	}
+	if err := req.Layout.Validate(disk.SizeBytes); err != nil {
+		return fmt.Errorf("invalid layout: %w", err)
+	}
+	if mirror := req.Layout.MirrorDisk; mirror != "" {
+		if mirror == req.Disk {
+			return fmt.Errorf("invalid layout: mirrorDisk must differ from disk")
+		}
+		if findDisk(all, mirror) == nil {
+			return fmt.Errorf("mirror disk not found: %s", mirror)
+		}
+	}
+
+	if (req.Network.Address != "" || req.Network.VLAN != 0) && req.Network.Interface == "" {
+		if iface, err := netconfig.DefaultInterface(); err == nil {
+			req.Network.Interface = iface
+		}
+	}
+	if err := req.Network.Validate(); err != nil {
+		return fmt.Errorf("invalid network: %w", err)
+	}
+
+	if req.Encryption {
+		if len(req.LUKS.SSHKeys) > 0 && req.LUKS.Interface == "" {
+			req.LUKS.Interface = req.Network.Interface
+			if req.LUKS.Interface == "" {
+				req.LUKS.Interface, _ = netconfig.DefaultInterface()
+			}
+		}
+		if err := req.LUKS.Validate(); err != nil {
+			return fmt.Errorf("invalid encryption: %w", err)
+		}
+		if err := nixinstall.CheckEncrypted(); err != nil {
+			return fmt.Errorf("invalid encryption: %w", err)
+		}
+	} else if !req.LUKS.IsZero() {
+		return fmt.Errorf("invalid encryption: luks options need encryption: true")
+	}
+
+	if req.Admin != nil {
+		if !usernamePattern.MatchString(req.Admin.Username) {
+			return fmt.Errorf("invalid admin: username must be 3–30 letters, numbers or underscores")
+		}
+		if utf8.RuneCountInString(req.Admin.Password) < 8 {
+			return fmt.Errorf("invalid admin: password must be at least 8 characters")
+		}
+	}
+	for _, app := range req.Apps {
+		if !appNamePattern.MatchString(app) {
+			return fmt.Errorf("invalid app name %q", app)
+		}
+	}
+	return nil
+}
+
+func findDisk(all []disks.Disk, device string) *disks.Disk {
+	for i := range all {
+		if all[i].Device == device {
+			return &all[i]
+		}
+	}
+	return nil
+}
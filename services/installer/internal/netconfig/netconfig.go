@@ -0,0 +1,194 @@
+package netconfig
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// hostnamePattern is a single RFC 1123 label; the host is also
+	// <hostname>.local over mDNS
+	hostnamePattern  = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	interfacePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,15}$`)
+)
+
+// Config is the network the installed system comes up with. The zero value
+// keeps the defaults: DHCP on every interface and hostname bloud.
+type Config struct {
+	Hostname string `json:"hostname"`
+	// Interface carries the address and VLAN; defaults to the interface of
+	// the installer's default route
+	Interface string `json:"interface"`
+	// Address is a static address in CIDR form, e.g. 192.168.1.20/24;
+	// empty keeps DHCP
+	Address string   `json:"address"`
+	Gateway string   `json:"gateway"`
+	DNS     []string `json:"dns"`
+	// VLAN tags Interface's traffic with this ID (1–4094)
+	VLAN int `json:"vlan"`
+}
+
+// IsZero reports whether c changes nothing
+func (c Config) IsZero() bool {
+	return c.Hostname == "" && c.Interface == "" && c.Address == "" && c.Gateway == "" && len(c.DNS) == 0 && c.VLAN == 0
+}
+
+// Validate checks c is complete and well-formed; Interface must already be
+// filled in when it's needed
+func (c Config) Validate() error {
+	if c.Hostname != "" && !hostnamePattern.MatchString(c.Hostname) {
+		return fmt.Errorf("invalid hostname %q (lowercase letters, digits and -)", c.Hostname)
+	}
+	if c.Interface != "" && !interfacePattern.MatchString(c.Interface) {
+		return fmt.Errorf("invalid interface %q", c.Interface)
+	}
+	if c.VLAN < 0 || c.VLAN > 4094 {
+		return fmt.Errorf("vlan must be between 1 and 4094")
+	}
+	if (c.Address != "" || c.VLAN != 0) && c.Interface == "" {
+		return fmt.Errorf("interface is required for a static address or VLAN")
+	}
+
+	if c.Address == "" {
+		if c.Gateway != "" || len(c.DNS) > 0 {
+			return fmt.Errorf("gateway and dns need a static address")
+		}
+		return nil
+	}
+	prefix, err := netip.ParsePrefix(c.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q (use CIDR form, e.g. 192.168.1.20/24)", c.Address)
+	}
+	if c.Gateway == "" {
+		return fmt.Errorf("gateway is required with a static address")
+	}
+	gateway, err := netip.ParseAddr(c.Gateway)
+	if err != nil {
+		return fmt.Errorf("invalid gateway %q", c.Gateway)
+	}
+	if !prefix.Masked().Contains(gateway) {
+		return fmt.Errorf("gateway %s is outside %s", gateway, prefix.Masked())
+	}
+	if len(c.DNS) == 0 {
+		return fmt.Errorf("dns is required with a static address")
+	}
+	for _, ns := range c.DNS {
+		if _, err := netip.ParseAddr(ns); err != nil {
+			return fmt.Errorf("invalid dns server %q", ns)
+		}
+	}
+	return nil
+}
+
+// device is the interface the address goes on: the VLAN interface when
+// there is one
+func (c Config) device() string {
+	if c.VLAN != 0 {
+		return fmt.Sprintf("%s.%d", c.Interface, c.VLAN)
+	}
+	return c.Interface
+}
+
+// DefaultInterface is the interface of the IPv4 default route
+func DefaultInterface() (string, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Iface Destination Gateway ...; a default route has destination 0
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[1] == "00000000" {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no default route")
+}
+
+// Write records c under root for the installed system: etc/nixos/bloud-network.nix
+// for installed.nix to import on rebuild, and etc/bloud/network.env, which
+// the pre-built system applies at boot until then.
+func Write(root string, c Config) error {
+	files := map[string]string{
+		"etc/nixos/bloud-network.nix": NixConfig(c),
+		"etc/bloud/network.env":       Env(c),
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NixConfig is c as NixOS configuration
+func NixConfig(c Config) string {
+	var b strings.Builder
+	b.WriteString("# Generated by the Bloud installer from the install's network settings.\n")
+	b.WriteString("{ lib, ... }:\n\n{\n")
+	b.WriteString("  # Declared here now; the boot-time fallback is no longer needed\n")
+	b.WriteString("  systemd.services.bloud-network-setup.enable = false;\n")
+
+	if c.Hostname != "" {
+		host := "http://" + c.Hostname + ".local"
+		fmt.Fprintf(&b, "\n  networking.hostName = lib.mkForce %q;\n", c.Hostname)
+		fmt.Fprintf(&b, "  bloud.externalHost = lib.mkForce %q;\n", host)
+		fmt.Fprintf(&b, "  bloud.authentikExternalHost = lib.mkForce %q;\n", host)
+	}
+
+	dev := c.device()
+	if c.VLAN != 0 {
+		fmt.Fprintf(&b, "\n  networking.vlans.%q = { id = %d; interface = %q; };\n", dev, c.VLAN, c.Interface)
+		if c.Address == "" {
+			fmt.Fprintf(&b, "  networking.interfaces.%q.useDHCP = true;\n", dev)
+		}
+	}
+
+	if c.Address != "" {
+		prefix := netip.MustParsePrefix(c.Address)
+		family, gateway := "ipv4", "defaultGateway"
+		if prefix.Addr().Is6() {
+			family, gateway = "ipv6", "defaultGateway6"
+		}
+		b.WriteString("\n  networking.useDHCP = lib.mkForce false;\n")
+		fmt.Fprintf(&b, "  networking.interfaces.%q.%s.addresses = [\n    { address = %q; prefixLength = %d; }\n  ];\n",
+			dev, family, prefix.Addr().String(), prefix.Bits())
+		fmt.Fprintf(&b, "  networking.%s = { address = %q; interface = %q; };\n", gateway, c.Gateway, dev)
+		b.WriteString("  networking.nameservers = [")
+		for _, ns := range c.DNS {
+			fmt.Fprintf(&b, " %q", ns)
+		}
+		b.WriteString(" ];\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Env is c as the shell variables bloud-network-setup reads. Values are
+// validated, so they need no quoting.
+func Env(c Config) string {
+	vlan := ""
+	if c.VLAN != 0 {
+		vlan = fmt.Sprint(c.VLAN)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "BLOUD_HOSTNAME=%s\n", c.Hostname)
+	fmt.Fprintf(&b, "BLOUD_INTERFACE=%s\n", c.Interface)
+	fmt.Fprintf(&b, "BLOUD_VLAN=%s\n", vlan)
+	fmt.Fprintf(&b, "BLOUD_ADDRESS=%s\n", c.Address)
+	fmt.Fprintf(&b, "BLOUD_GATEWAY=%s\n", c.Gateway)
+	fmt.Fprintf(&b, "BLOUD_DNS=\"%s\"\n", strings.Join(c.DNS, " "))
+	return b.String()
+}
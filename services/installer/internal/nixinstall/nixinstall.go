@@ -6,20 +6,59 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 )
 
-func Install(ctx context.Context, flakePath string, emit func(string)) error {
+// Progress is how far nixos-install has got: Copied of the system's Total
+// store paths (Total is 0 when the closure isn't known up front), then
+// Bootloader once it starts installing the boot loader
+type Progress struct {
+	Copied     int
+	Total      int
+	Bootloader bool
+}
+
+// target returns the flake target to install and the environment variable
+// holding its pre-built system. Encrypted installs get the bloud-encrypted
+// system, whose initrd unlocks the installer's LUKS containers; the
+// pre-built system can't read bloud-disks.nix, which declares them for
+// later rebuilds.
+func target(encrypted bool) (name, systemEnv string) {
+	if encrypted {
+		return "bloud-encrypted", "INSTALLER_ENCRYPTED_SYSTEM_PATH"
+	}
+	return "bloud", "INSTALLER_SYSTEM_PATH"
+}
+
+// CheckEncrypted fails when this installer image can't install an
+// encrypted system: it has a pre-built system, but no encrypted one
+func CheckEncrypted() error {
+	if os.Getenv("INSTALLER_SYSTEM_PATH") != "" && os.Getenv("INSTALLER_ENCRYPTED_SYSTEM_PATH") == "" {
+		return fmt.Errorf("this installer image has no encrypted system")
+	}
+	return nil
+}
+
+func Install(ctx context.Context, flakePath string, encrypted bool, emit func(string), progress func(Progress)) error {
 	if err := writeConfigStub(); err != nil {
 		return fmt.Errorf("writing nixos config stub: %w", err)
 	}
+	if encrypted {
+		if err := CheckEncrypted(); err != nil {
+			return err
+		}
+	}
+	name, systemEnv := target(encrypted)
 
 	var args []string
+	var p Progress
 	// Prefer a pre-built system path when available. The bundled flake evaluates
 	// to a different store hash than what's baked into the ISO squashfs, so
 	// --flake re-evaluation would fail with a missing store path.
-	if systemPath := os.Getenv("INSTALLER_SYSTEM_PATH"); systemPath != "" {
+	if systemPath := os.Getenv(systemEnv); systemPath != "" {
 		emit("Running nixos-install --no-root-passwd --system " + systemPath + " --root /mnt")
 		args = []string{"--no-root-passwd", "--system", systemPath, "--root", "/mnt"}
+		p.Total = closureSize(ctx, systemPath)
 	} else {
 		if flakePath == "" {
 			flakePath = os.Getenv("INSTALLER_FLAKE_PATH")
@@ -27,8 +66,8 @@ func Install(ctx context.Context, flakePath string, emit func(string)) error {
 		if flakePath == "" {
 			flakePath = "/etc/bloud"
 		}
-		emit("Running nixos-install --no-root-passwd --flake " + flakePath + "#bloud --root /mnt")
-		args = []string{"--no-root-passwd", "--flake", flakePath + "#bloud", "--root", "/mnt"}
+		emit("Running nixos-install --no-root-passwd --flake " + flakePath + "#" + name + " --root /mnt")
+		args = []string{"--no-root-passwd", "--flake", flakePath + "#" + name, "--root", "/mnt"}
 	}
 
 	cmd := exec.CommandContext(ctx, "/run/current-system/sw/bin/nixos-install", args...)
@@ -45,7 +84,16 @@ func Install(ctx context.Context, flakePath string, emit func(string)) error {
 
 	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
-		emit(scanner.Text())
+		line := scanner.Text()
+		emit(line)
+		switch {
+		case strings.HasPrefix(line, "copying path "):
+			p.Copied++
+			progress(p)
+		case strings.Contains(line, "installing the boot loader"):
+			p.Bootloader = true
+			progress(p)
+		}
 	}
 
 	if err := cmd.Wait(); err != nil {
@@ -55,6 +103,16 @@ func Install(ctx context.Context, flakePath string, emit func(string)) error {
 	return nil
 }
 
+// closureSize is the number of store paths nixos-install will copy to a
+// blank disk, or 0 if nix-store can't tell
+func closureSize(ctx context.Context, systemPath string) int {
+	out, err := exec.CommandContext(ctx, "nix-store", "--query", "--requisites", systemPath).Output()
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(out), "\n")
+}
+
 func writeConfigStub() error {
 	if err := os.MkdirAll("/mnt/etc/nixos", 0755); err != nil {
 		return err
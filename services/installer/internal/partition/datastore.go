@@ -0,0 +1,211 @@
+package partition
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Data store filesystems
+const (
+	FilesystemExt4  = "ext4"
+	FilesystemBtrfs = "btrfs"
+	FilesystemZFS   = "zfs"
+)
+
+const (
+	// DataDir is Bloud's data directory on the installed system (dataDir in
+	// nixos/modules/host-agent.nix), where apps, the database and secrets
+	// are kept. The data store is mounted there.
+	DataDir = "/home/bloud/.local/share/bloud"
+
+	// dataLabel is the ext4 or btrfs label of the data store
+	dataLabel = "bloud-data"
+
+	// dataPool and dataDataset are the ZFS pool and the dataset mounted at
+	// DataDir
+	dataPool    = "bloud"
+	dataDataset = dataPool + "/data"
+
+	// bloudUID and usersGID own DataDir: the installed system's first normal
+	// user and the users group
+	bloudUID = 1000
+	usersGID = 100
+)
+
+// createDataStore formats the data partition, and the mirror disk when
+// there is one, and mounts the result at DataDir under /mnt. data is
+// already opened when encrypting; the mirror is encrypted here.
+func createDataStore(ctx context.Context, data string, layout Layout, luks *LUKS, emit func(string)) error {
+	target := filepath.Join("/mnt", DataDir)
+	devices := []string{data}
+	if layout.MirrorDisk != "" {
+		mirror, err := prepareMirror(ctx, layout.MirrorDisk, emit)
+		if err != nil {
+			return err
+		}
+		if luks != nil {
+			if mirror, err = luks.encrypt(ctx, mirror, mirrorLabel, mirrorMapping, emit); err != nil {
+				return err
+			}
+		}
+		devices = append(devices, mirror)
+	}
+
+	var steps [][]string
+	switch layout.Filesystem {
+	case FilesystemBtrfs:
+		args := []string{"mkfs.btrfs", "-f", "-L", dataLabel}
+		if len(devices) > 1 {
+			args = append(args, "-m", "raid1", "-d", "raid1")
+		}
+		steps = append(steps, append(args, devices...))
+		mount := []string{"mount", "-t", "btrfs"}
+		for _, dev := range devices[1:] {
+			mount = append(mount, "-o", "device="+dev)
+		}
+		steps = append(steps, append(mount, devices[0], target))
+	case FilesystemZFS:
+		// -R /mnt mounts the dataset under the install root; the pool is
+		// exported again by Finish so the installed system imports it cleanly
+		create := []string{"zpool", "create", "-f", "-R", "/mnt",
+			"-o", "ashift=12",
+			"-O", "mountpoint=none", "-O", "compression=lz4", "-O", "acltype=posixacl", "-O", "xattr=sa",
+			dataPool}
+		if len(devices) > 1 {
+			create = append(create, "mirror")
+		}
+		steps = append(steps,
+			append(create, devices...),
+			[]string{"zfs", "create", "-o", "mountpoint=" + DataDir, dataDataset},
+		)
+	default:
+		steps = append(steps,
+			[]string{"mkfs.ext4", "-F", "-L", dataLabel, data},
+			[]string{"mount", data, target},
+		)
+	}
+
+	fs := layout.Filesystem
+	if fs == "" {
+		fs = FilesystemExt4
+	}
+	if len(devices) > 1 {
+		emit(fmt.Sprintf("Creating mirrored %s data store on %s", fs, strings.Join(devices, " and ")))
+	} else {
+		emit(fmt.Sprintf("Creating %s data store on %s", fs, data))
+	}
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", target, err)
+	}
+	for _, args := range steps {
+		emit("Running " + strings.Join(args, " "))
+		if out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w\n%s", args[0], err, string(out))
+		}
+	}
+	if err := checkDataStore(ctx, target, fs); err != nil {
+		return err
+	}
+
+	// The home dir is created before the bloud user exists, and the new
+	// filesystem's root belongs to root, so hand the whole path to it;
+	// NixOS leaves existing homes alone
+	for dir := target; dir != "/mnt/home"; dir = filepath.Dir(dir) {
+		if err := os.Chown(dir, bloudUID, usersGID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDataStore makes sure the data store is what's mounted at target, so
+// Bloud's data lands on it rather than on the root filesystem beneath
+func checkDataStore(ctx context.Context, target, fstype string) error {
+	out, err := exec.CommandContext(ctx, "findmnt", "--noheadings", "--raw", "--output", "FSTYPE", "--mountpoint", target).Output()
+	if err != nil {
+		return fmt.Errorf("data store isn't mounted at %s: %w", target, err)
+	}
+	if got := strings.TrimSpace(string(out)); got != fstype {
+		return fmt.Errorf("%s is mounted at %s, not the %s data store", got, target, fstype)
+	}
+	return nil
+}
+
+// prepareMirror gives the mirror disk a single partition spanning it
+func prepareMirror(ctx context.Context, disk string, emit func(string)) (string, error) {
+	for _, step := range []struct {
+		description string
+		args        []string
+	}{
+		{"Clearing existing signatures from " + disk, []string{"wipefs", "-a", disk}},
+		{"Creating GPT partition table on " + disk, []string{"parted", "-s", disk, "mklabel", "gpt"}},
+		{"Creating mirror partition on " + disk, []string{"parted", "-s", disk, "mkpart", "mirror", "1MiB", "100%"}},
+	} {
+		emit(step.description)
+		if out, err := exec.CommandContext(ctx, step.args[0], step.args[1:]...).CombinedOutput(); err != nil {
+			return "", fmt.Errorf("%s: %w\n%s", step.description, err, string(out))
+		}
+	}
+	// Let udev create the partition's device node before mkfs uses it
+	exec.CommandContext(ctx, "udevadm", "settle").Run() //nolint:errcheck
+	return partitionDevice(disk, "1"), nil
+}
+
+// Finish releases the data store after nixos-install. A ZFS pool is
+// exported so the installed system, with its own host ID, can import it
+// without forcing.
+func Finish(ctx context.Context, layout Layout, emit func(string)) error {
+	if layout.Filesystem != FilesystemZFS {
+		return nil
+	}
+	emit("Exporting ZFS pool " + dataPool)
+	if out, err := exec.CommandContext(ctx, "zpool", "export", dataPool).CombinedOutput(); err != nil {
+		return fmt.Errorf("zpool export %s: %w\n%s", dataPool, err, string(out))
+	}
+	return nil
+}
+
+// NixConfig is the NixOS configuration for layout's data store, written
+// to /etc/nixos/bloud-disks.nix on the installed system. installed.nix
+// imports it so later rebuilds mount exactly what was created; without it
+// the installed system still finds the data store by label or pool name.
+// Encryption is only declared here, for encrypted installs; the pre-built
+// system they start from is bloud-encrypted (nixos/encrypted.nix).
+// Encrypted swap and remote unlock only exist from the first rebuild.
+func NixConfig(layout Layout, luks *LUKS) string {
+	var b strings.Builder
+	b.WriteString("# Generated by the Bloud installer from the install's partition layout.\n")
+	b.WriteString("{ lib, ... }:\n\n{\n")
+	switch {
+	case layout.SwapGB > 0 && luks != nil:
+		b.WriteString("  swapDevices = lib.mkForce [ { device = \"/dev/disk/by-partlabel/swap\"; randomEncryption.enable = true; } ];\n")
+	case layout.SwapGB > 0:
+		b.WriteString("  swapDevices = lib.mkForce [ { device = \"/dev/disk/by-label/swap\"; } ];\n")
+	}
+	if layout.RootGB > 0 {
+		// zfsutil mounts the dataset despite its non-legacy mountpoint; NixOS
+		// imports the pools its zfs fileSystems name
+		device, fsType, options := "/dev/disk/by-label/"+dataLabel, layout.Filesystem, ""
+		switch layout.Filesystem {
+		case "":
+			fsType = FilesystemExt4
+		case FilesystemZFS:
+			device, options = dataDataset, "    options = [ \"zfsutil\" ];\n"
+		}
+		fmt.Fprintf(&b, "  fileSystems.%q = lib.mkForce {\n    device = %q;\n    fsType = %q;\n%s  };\n", DataDir, device, fsType, options)
+		// Nothing that writes to the data dir starts without the data store,
+		// or it would write to the root filesystem beneath
+		for _, service := range []string{"bloud-user-services", "bloud-restore", "bloud-host-agent"} {
+			fmt.Fprintf(&b, "  systemd.services.%s.unitConfig.RequiresMountsFor = [ %q ];\n", service, DataDir)
+		}
+	}
+	if luks != nil {
+		b.WriteString(luks.nixConfig(layout))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
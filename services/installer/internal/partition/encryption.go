@@ -0,0 +1,199 @@
+package partition
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// LUKS containers Prepare creates when encrypting. The labels match the
+// boot.initrd.luks.devices in nixos/encrypted.nix and bloud-disks.nix; the
+// mapped devices carry the usual filesystem labels.
+const (
+	rootMapping   = "cryptroot"
+	rootLUKSLabel = "nixos-crypt"
+	dataMapping   = "cryptdata"
+	dataLUKSLabel = "bloud-data-crypt"
+	mirrorMapping = "cryptmirror"
+	mirrorLabel   = "bloud-mirror-crypt"
+)
+
+const (
+	// MinPassphraseLength is the shortest passphrase LUKS.Validate accepts
+	MinPassphraseLength = 8
+
+	// UnlockPort is where the initrd's SSH server listens for remote unlock,
+	// clear of the installed system's own SSH on 22
+	UnlockPort = 2222
+
+	// initrdHostKey is the initrd SSH server's host key on the installed
+	// system; NixOS copies it into the initrd when the boot loader is installed
+	initrdHostKey = "/etc/secrets/initrd/ssh_host_ed25519_key"
+
+	// tpmDevice is present when the machine has a TPM 2.0 systemd can use
+	tpmDevice = "/dev/tpmrm0"
+)
+
+// LUKS is how Prepare encrypts the root and data partitions, and the mirror
+// disk, with LUKS2. Swap gets a new random key every boot.
+type LUKS struct {
+	// Passphrase unlocks the disk at the boot prompt, and is the recovery
+	// key when a TPM unlocks it
+	Passphrase string `json:"passphrase"`
+	// TPM also seals a key to this machine's TPM (PCR 7, the Secure Boot
+	// state) so it boots without the passphrase
+	TPM bool `json:"tpm"`
+	// SSHKeys are public keys allowed to unlock the disk over SSH on
+	// UnlockPort while the machine waits at the passphrase prompt
+	SSHKeys []string `json:"sshKeys"`
+	// Interface is the network interface the initrd brings up with DHCP for
+	// remote unlock
+	Interface string `json:"interface"`
+}
+
+// IsZero reports whether no encryption options were given
+func (l LUKS) IsZero() bool {
+	return l.Passphrase == "" && !l.TPM && len(l.SSHKeys) == 0 && l.Interface == ""
+}
+
+// Validate checks the options without looking at the machine
+func (l LUKS) Validate() error {
+	if utf8.RuneCountInString(l.Passphrase) < MinPassphraseLength {
+		return fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+	}
+	for _, key := range l.SSHKeys {
+		fields := strings.Fields(key)
+		if len(fields) < 2 || !(strings.HasPrefix(fields[0], "ssh-") || strings.HasPrefix(fields[0], "ecdsa-") || strings.HasPrefix(fields[0], "sk-")) {
+			return fmt.Errorf("sshKeys: %q is not an SSH public key", key)
+		}
+	}
+	if len(l.SSHKeys) > 0 && l.Interface == "" {
+		return fmt.Errorf("remote unlock needs an interface")
+	}
+	return nil
+}
+
+// HasTPM reports whether the machine has a TPM 2.0 to seal a key to
+func HasTPM() bool {
+	_, err := os.Stat(tpmDevice)
+	return err == nil
+}
+
+// CheckTPM fails when l asks for a TPM the machine doesn't have
+func CheckTPM(l LUKS) error {
+	if l.TPM && !HasTPM() {
+		return fmt.Errorf("tpm: no TPM 2.0 found on this machine")
+	}
+	return nil
+}
+
+// encrypt formats device as a LUKS2 container and opens it as
+// /dev/mapper/<mapping>, returning the mapped device
+func (l *LUKS) encrypt(ctx context.Context, device, label, mapping string, emit func(string)) (string, error) {
+	emit(fmt.Sprintf("Encrypting %s with LUKS2 (label: %s)", device, label))
+	format := exec.CommandContext(ctx, "cryptsetup", "luksFormat", "--type", "luks2", "--batch-mode",
+		"--label", label, "--key-file", "-", device)
+	format.Stdin = strings.NewReader(l.Passphrase)
+	if out, err := format.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("cryptsetup luksFormat %s: %w\n%s", device, err, string(out))
+	}
+
+	if l.TPM {
+		emit("Sealing a key for " + device + " to the TPM")
+		enroll := exec.CommandContext(ctx, "systemd-cryptenroll", "--tpm2-device=auto", "--tpm2-pcrs=7", device)
+		enroll.Env = append(os.Environ(), "PASSWORD="+l.Passphrase)
+		if out, err := enroll.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("systemd-cryptenroll %s: %w\n%s", device, err, string(out))
+		}
+	}
+
+	emit(fmt.Sprintf("Opening %s as /dev/mapper/%s", device, mapping))
+	open := exec.CommandContext(ctx, "cryptsetup", "open", "--key-file", "-", device, mapping)
+	open.Stdin = strings.NewReader(l.Passphrase)
+	if out, err := open.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("cryptsetup open %s: %w\n%s", device, err, string(out))
+	}
+	return "/dev/mapper/" + mapping, nil
+}
+
+// closeMappings closes the LUKS containers of a previous install attempt.
+// Errors are intentionally ignored — these may not be open.
+func closeMappings(ctx context.Context) {
+	for _, mapping := range []string{dataMapping, mirrorMapping, rootMapping} {
+		exec.CommandContext(ctx, "cryptsetup", "close", mapping).Run() //nolint:errcheck
+	}
+}
+
+// WriteInitrdHostKey creates the host key of the initrd's SSH server under
+// root and returns its fingerprint, for checking on first connect
+func WriteInitrdHostKey(ctx context.Context, root string) (string, error) {
+	key := filepath.Join(root, initrdHostKey)
+	if err := os.MkdirAll(filepath.Dir(key), 0700); err != nil {
+		return "", err
+	}
+	os.Remove(key)          //nolint:errcheck
+	os.Remove(key + ".pub") //nolint:errcheck
+	if out, err := exec.CommandContext(ctx, "ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "bloud-initrd", "-f", key).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("ssh-keygen: %w\n%s", err, string(out))
+	}
+	out, err := exec.CommandContext(ctx, "ssh-keygen", "-l", "-f", key+".pub").Output()
+	if err != nil {
+		return "", fmt.Errorf("ssh-keygen -l: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// nixConfig is the encryption part of bloud-disks.nix: the systemd initrd
+// unlocking the containers layout has, and for remote unlock, DHCP on the
+// interface and an SSH server whose root shell answers the passphrase
+// prompt.
+func (l *LUKS) nixConfig(layout Layout) string {
+	var b strings.Builder
+	// systemd asks for the passphrase once and tries it on each container;
+	// a key sealed to the TPM unlocks them without asking
+	b.WriteString("  boot.initrd.systemd.enable = true;\n  boot.initrd.luks.devices = {\n")
+	devices := [][2]string{{rootMapping, rootLUKSLabel}}
+	if layout.RootGB > 0 {
+		devices = append(devices, [2]string{dataMapping, dataLUKSLabel})
+	}
+	if layout.MirrorDisk != "" {
+		devices = append(devices, [2]string{mirrorMapping, mirrorLabel})
+	}
+	for _, d := range devices {
+		fmt.Fprintf(&b, "    %s.device = \"/dev/disk/by-label/%s\";\n", d[0], d[1])
+		if l.TPM {
+			fmt.Fprintf(&b, "    %s.crypttabExtraOpts = [ \"tpm2-device=auto\" ];\n", d[0])
+		}
+	}
+	b.WriteString("  };\n")
+
+	if len(l.SSHKeys) == 0 {
+		return b.String()
+	}
+	b.WriteString("  boot.initrd.network = {\n    enable = true;\n    ssh = {\n      enable = true;\n")
+	fmt.Fprintf(&b, "      port = %d;\n      hostKeys = [ %q ];\n      authorizedKeys = [\n", UnlockPort, initrdHostKey)
+	for _, key := range l.SSHKeys {
+		fmt.Fprintf(&b, "        %q\n", strings.TrimSpace(key))
+	}
+	b.WriteString("      ];\n    };\n  };\n")
+	fmt.Fprintf(&b, "  boot.initrd.systemd.network.networks.\"10-bloud-unlock\" = {\n    matchConfig.Name = %q;\n    networkConfig.DHCP = \"yes\";\n  };\n", l.Interface)
+	b.WriteString("  boot.initrd.systemd.users.root.shell = \"/bin/systemd-tty-ask-password-agent\";\n")
+	if module := interfaceModule(l.Interface); module != "" {
+		fmt.Fprintf(&b, "  boot.initrd.availableKernelModules = [ %q ];\n", module)
+	}
+	return b.String()
+}
+
+// interfaceModule is the kernel driver behind a network interface, which
+// the initrd needs to bring it up
+func interfaceModule(iface string) string {
+	target, err := os.Readlink(filepath.Join("/sys/class/net", iface, "device", "driver", "module"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
@@ -9,17 +9,81 @@ import (
 	"strings"
 )
 
-func Prepare(ctx context.Context, device string, emit func(string)) error {
+// bootEndMiB is where the BIOS boot and EFI partitions end
+const bootEndMiB = 514
+
+// MinRootGB is the smallest root partition Layout accepts; the Nix store and
+// the app images live there
+const MinRootGB = 16
+
+// Layout is how Prepare divides the disk after the BIOS boot and EFI
+// partitions. The zero value is a single root partition filling the disk.
+type Layout struct {
+	// SwapGB adds a swap partition (label "swap") of this size at the end of
+	// the disk
+	SwapGB int `json:"swapGB"`
+	// RootGB caps the root partition; the rest of the disk becomes the data
+	// store mounted at DataDir
+	RootGB int `json:"rootGB"`
+	// Filesystem of the data store: ext4 (the default), btrfs or zfs
+	Filesystem string `json:"filesystem"`
+	// MirrorDisk is wiped and mirrors the data store; btrfs and zfs only
+	MirrorDisk string `json:"mirrorDisk"`
+}
+
+// Validate checks the layout fits a disk of sizeBytes
+func (l Layout) Validate(sizeBytes int64) error {
+	if l.SwapGB < 0 || l.RootGB < 0 {
+		return fmt.Errorf("partition sizes must not be negative")
+	}
+	if l.RootGB != 0 && l.RootGB < MinRootGB {
+		return fmt.Errorf("rootGB must be at least %d", MinRootGB)
+	}
+	switch l.Filesystem {
+	case "", FilesystemExt4:
+	case FilesystemBtrfs, FilesystemZFS:
+		if l.RootGB == 0 {
+			return fmt.Errorf("filesystem %s is for the data store; set rootGB to split it from root", l.Filesystem)
+		}
+	default:
+		return fmt.Errorf("unknown filesystem %q (use ext4, btrfs or zfs)", l.Filesystem)
+	}
+	if l.MirrorDisk != "" && (l.Filesystem == "" || l.Filesystem == FilesystemExt4) {
+		return fmt.Errorf("mirroring needs filesystem btrfs or zfs")
+	}
+
+	needMiB := int64(bootEndMiB) + int64(l.SwapGB)*1024
+	if l.RootGB != 0 {
+		// Leave at least as much for the data store as a minimal root
+		needMiB += int64(l.RootGB+MinRootGB) * 1024
+	} else {
+		needMiB += MinRootGB * 1024
+	}
+	if sizeMiB := sizeBytes / (1024 * 1024); needMiB > sizeMiB {
+		return fmt.Errorf("layout needs %d GiB but the disk has %d GiB", (needMiB+1023)/1024, sizeMiB/1024)
+	}
+	return nil
+}
+
+// Prepare partitions device with layout, formats it and mounts the result
+// on /mnt for nixos-install. Labels match the fileSystems and swapDevices in
+// nixos/installed.nix. With luks the root and data partitions are encrypted
+// and swap is left for the installed system to key at boot.
+func Prepare(ctx context.Context, device string, layout Layout, luks *LUKS, emit func(string)) error {
 	// Unmount any partitions left over from a previous install attempt.
 	// Errors are intentionally ignored — these may not be mounted.
 	emit("Unmounting any existing mounts on " + device)
-	exec.CommandContext(ctx, "umount", "-f", "/mnt/boot").Run() //nolint:errcheck
-	exec.CommandContext(ctx, "umount", "-f", "/mnt").Run()       //nolint:errcheck
+	exec.CommandContext(ctx, "umount", "-f", "/mnt"+DataDir).Run()    //nolint:errcheck
+	exec.CommandContext(ctx, "zpool", "export", "-f", dataPool).Run() //nolint:errcheck
+	exec.CommandContext(ctx, "umount", "-f", "/mnt/boot").Run()       //nolint:errcheck
+	exec.CommandContext(ctx, "umount", "-f", "/mnt").Run()            //nolint:errcheck
+	closeMappings(ctx)
 
-	steps := []struct {
+	type step struct {
 		description string
 		args        []string
-	}{
+	}
+	steps := []step{
 		{
 			"Clearing existing signatures from " + device,
 			[]string{"wipefs", "-a", device},
@@ -47,11 +111,39 @@ func Prepare(ctx context.Context, device string, emit func(string)) error {
 			"Setting ESP flag on partition 2",
 			[]string{"parted", "-s", device, "set", "2", "esp", "on"},
 		},
-		// Partition 3: root filesystem.
-		{
-			"Creating root partition (514MiB–100%)",
-			[]string{"parted", "-s", device, "mkpart", "root", "ext4", "514MiB", "100%"},
-		},
+	}
+
+	// Partition 3: root filesystem, then the optional data store and swap
+	// partitions. Swap is placed from the end of the disk so the others
+	// can run up to it.
+	end := "100%"
+	if layout.SwapGB > 0 {
+		end = fmt.Sprintf("-%dMiB", layout.SwapGB*1024)
+	}
+	rootEnd := end
+	if layout.RootGB > 0 {
+		rootEnd = fmt.Sprintf("%dMiB", bootEndMiB+layout.RootGB*1024)
+	}
+	steps = append(steps, step{
+		fmt.Sprintf("Creating root partition (%dMiB–%s)", bootEndMiB, rootEnd),
+		[]string{"parted", "-s", device, "--", "mkpart", "root", "ext4", fmt.Sprintf("%dMiB", bootEndMiB), rootEnd},
+	})
+	next := 4
+	data, swap := "", ""
+	if layout.RootGB > 0 {
+		data = partitionDevice(device, fmt.Sprint(next))
+		next++
+		steps = append(steps, step{
+			fmt.Sprintf("Creating data store partition (%s–%s)", rootEnd, end),
+			[]string{"parted", "-s", device, "--", "mkpart", "data", "ext4", rootEnd, end},
+		})
+	}
+	if layout.SwapGB > 0 {
+		swap = partitionDevice(device, fmt.Sprint(next))
+		steps = append(steps, step{
+			fmt.Sprintf("Creating %d GiB swap partition", layout.SwapGB),
+			[]string{"parted", "-s", device, "--", "mkpart", "swap", "linux-swap", end, "100%"},
+		})
 	}
 
 	for _, step := range steps {
@@ -70,11 +162,34 @@ func Prepare(ctx context.Context, device string, emit func(string)) error {
 		return fmt.Errorf("mkfs.vfat %s: %w\n%s", efi, err, string(out))
 	}
 
+	if luks != nil {
+		mapped, err := luks.encrypt(ctx, root, rootLUKSLabel, rootMapping, emit)
+		if err != nil {
+			return err
+		}
+		root = mapped
+		if data != "" {
+			if data, err = luks.encrypt(ctx, data, dataLUKSLabel, dataMapping, emit); err != nil {
+				return err
+			}
+		}
+	}
+
 	emit("Formatting root partition " + root + " as ext4 (label: nixos)")
 	if out, err := exec.CommandContext(ctx, "mkfs.ext4", "-F", "-L", "nixos", root).CombinedOutput(); err != nil {
 		return fmt.Errorf("mkfs.ext4 %s: %w\n%s", root, err, string(out))
 	}
 
+	// Encrypted swap is formatted with a random key on every boot
+	// (bloud-disks.nix); an unlabelled partition keeps the plain swap in
+	// installed.nix from using it before then
+	if swap != "" && luks == nil {
+		emit("Formatting swap partition " + swap + " (label: swap)")
+		if out, err := exec.CommandContext(ctx, "mkswap", "-L", "swap", swap).CombinedOutput(); err != nil {
+			return fmt.Errorf("mkswap %s: %w\n%s", swap, err, string(out))
+		}
+	}
+
 	emit("Creating mount point /mnt")
 	if err := os.MkdirAll("/mnt", 0755); err != nil {
 		return fmt.Errorf("mkdir /mnt: %w", err)
@@ -95,6 +210,9 @@ func Prepare(ctx context.Context, device string, emit func(string)) error {
 		return fmt.Errorf("mounting EFI: %w\n%s", err, string(out))
 	}
 
+	if data != "" {
+		return createDataStore(ctx, data, layout, luks, emit)
+	}
 	return nil
 }
 
@@ -0,0 +1,167 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DataDir is the bloud data dir on the installed system, where
+	// ./bloud backup archives are taken from
+	DataDir = "/home/bloud/.local/share/bloud"
+
+	// DumpName is the database dump inside an archive
+	DumpName = "postgres.sql"
+
+	// PendingDump is where Seed leaves the dump for bloud-restore.service to
+	// replay once PostgreSQL is up on first boot
+	PendingDump = DataDir + "/restore/" + DumpName
+
+	// bloudUID and usersGID own the data dir: the installed system's first
+	// normal user and the users group
+	bloudUID = 1000
+	usersGID = 100
+)
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// Check makes sure source can be read before the disk is wiped: an
+// absolute path to an archive on the live system (e.g. a mounted USB
+// stick) or an http(s) URL
+func Check(ctx context.Context, source string) error {
+	if isURL(source) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, source, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: %s", source, resp.Status)
+		}
+		return nil
+	}
+
+	if !filepath.IsAbs(source) {
+		return fmt.Errorf("backup must be an absolute path or an http(s) URL")
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a file", source)
+	}
+	return nil
+}
+
+// open returns source's contents and size, -1 when unknown
+func open(ctx context.Context, source string) (io.ReadCloser, int64, error) {
+	if !isURL(source) {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, 0, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, info.Size(), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("downloading %s: %s", source, resp.Status)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// countingReader reports how much of the archive has been read
+type countingReader struct {
+	r        io.Reader
+	read     int64
+	progress func(read int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	c.progress(c.read)
+	return n, err
+}
+
+// Seed unpacks a ./bloud backup archive into the data dir under root, the
+// installed system's mount point. The data dir, app data and secrets.json
+// included, is restored as is; the database dump is set aside for
+// bloud-restore.service, since PostgreSQL only runs once the system boots.
+// progress gets the fraction of the archive read, when its size is known.
+func Seed(ctx context.Context, source, root string, emit func(string), progress func(float64)) error {
+	r, size, err := open(ctx, source)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	dataDir := filepath.Join(root, DataDir)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return err
+	}
+	// The home dir is created before the bloud user exists, so hand the
+	// whole path to it; NixOS leaves existing homes alone
+	for dir := dataDir; dir != filepath.Join(root, "/home"); dir = filepath.Dir(dir) {
+		if err := os.Chown(dir, bloudUID, usersGID); err != nil {
+			return err
+		}
+	}
+
+	emit("Unpacking " + source + " into " + DataDir)
+	counter := &countingReader{r: r, progress: func(read int64) {
+		if size > 0 {
+			progress(float64(read) / float64(size))
+		}
+	}}
+	// --numeric-owner keeps the bloud user's and containers' subordinate
+	// IDs; the live system has none of those names
+	cmd := exec.CommandContext(ctx, "tar", "-xzf", "-", "--numeric-owner", "-C", dataDir)
+	cmd.Stdin = counter
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("unpacking backup: %w\n%s", err, string(out))
+	}
+
+	dump := filepath.Join(dataDir, DumpName)
+	if _, err := os.Stat(dump); err != nil {
+		return fmt.Errorf("not a bloud backup: %s is missing", DumpName)
+	}
+	pending := filepath.Join(root, PendingDump)
+	if err := os.MkdirAll(filepath.Dir(pending), 0700); err != nil {
+		return err
+	}
+	if err := os.Chown(filepath.Dir(pending), bloudUID, usersGID); err != nil {
+		return err
+	}
+	if err := os.Rename(dump, pending); err != nil {
+		return err
+	}
+	emit("Database dump queued for replay on first boot")
+	return nil
+}
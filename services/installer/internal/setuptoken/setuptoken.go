@@ -0,0 +1,91 @@
+// Package setuptoken is the one-time code that guards the installer's
+// destructive endpoints. The installer listens on every interface with no
+// accounts, so without it anyone on the LAN could wipe a machine that
+// booted the ISO. The code is made fresh each boot and shown only on the
+// machine's own screen.
+package setuptoken
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Header carries the code on POST /api/install and /api/reboot
+	Header = "X-Setup-Token"
+
+	// File holds the code for the console and for tools driving the
+	// installer over SSH; root-only
+	File = "/run/bloud-installer/setup-token"
+
+	// IssueFile is the code as a line for the login banner, which
+	// services.getty reads after /etc/issue
+	IssueFile = "/run/bloud-installer/issue"
+)
+
+// alphabet leaves out 0/O, 1/I/L and U so the code is easy to read off a
+// screen and type
+const alphabet = "ABCDEFGHJKMNPQRSTVWXYZ23456789"
+
+// length gives about 39 bits, which the installer's one-attempt-a-second
+// limit puts well out of reach
+const length = 8
+
+// Generate returns a new code formatted as XXXX-XXXX
+func Generate() (string, error) {
+	var b strings.Builder
+	for i := 0; i < length; i++ {
+		if i == length/2 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(alphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// Write saves the code to File and IssueFile
+func Write(token string) error {
+	if err := os.MkdirAll(filepath.Dir(File), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(File, []byte(token+"\n"), 0600); err != nil {
+		return err
+	}
+	issue := fmt.Sprintf("  Setup code: %s  (enter it in the browser to install)\n\n", token)
+	return os.WriteFile(IssueFile, []byte(issue), 0644)
+}
+
+// Read loads the code from File
+func Read() (string, error) {
+	data, err := os.ReadFile(File)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Equal compares a code as typed with the real one, ignoring case, spaces
+// and dashes, in constant time
+func Equal(typed, token string) bool {
+	a, b := normalize(typed), normalize(token)
+	return b != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(s)))
+}